@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/avscan"
+)
+
+// scanOutputs hands a decrypt job's results to the configured antivirus and
+// records the verdicts in sum before the summary shows. Called from the job goroutine.
+func (s *AppState) scanOutputs(sum *OperationSummary) {
+	if !s.config.AVScan || len(sum.Outputs) == 0 { return }
+	scanner, err := avscan.Find(s.config.AVScannerPath)
+	if err != nil {
+		sum.AVError = err.Error()
+		s.setStatus("⚠️ Antivirus scan not run: " + err.Error())
+		return
+	}
+	sum.AVScanner = scanner.Name()
+	for i, p := range sum.Outputs {
+		s.setStatus(fmt.Sprintf("🦠 Scanning %d/%d with %s: %s", i+1, len(sum.Outputs), sum.AVScanner, filepath.Base(p)))
+		sum.AVResults = append(sum.AVResults, scanner.Scan(context.Background(), p))
+	}
+	switch {
+	case sum.threatFound():
+		s.setStatus("🦠 " + sum.AVScanner + " found a threat in the decrypted output — see the summary")
+	case sum.scanFailed():
+		s.setStatus("⚠️ Antivirus scan incomplete — see the summary")
+	default:
+		s.setStatus(fmt.Sprintf("✅ Decrypted — %s found no threats", sum.AVScanner))
+	}
+}
+
+func (sum *OperationSummary) threatFound() bool { return sum.countVerdict(avscan.Infected) > 0 }
+func (sum *OperationSummary) scanFailed() bool  { return sum.countVerdict(avscan.Failed) > 0 }
+
+func (sum *OperationSummary) countVerdict(v avscan.Verdict) int {
+	n := 0
+	for _, r := range sum.AVResults { if r.Verdict == v { n++ } }
+	return n
+}
+
+// avReport is the summary's antivirus section; empty when no scan was asked for
+func avReport(sum *OperationSummary) string {
+	if sum.AVError != "" { return "\nAntivirus: not run — " + sum.AVError }
+	if sum.AVScanner == "" { return "" }
+	if !sum.threatFound() && !sum.scanFailed() { return fmt.Sprintf("\nAntivirus (%s): ✅ no threats in %d result(s)", sum.AVScanner, len(sum.AVResults)) }
+	text := fmt.Sprintf("\nAntivirus (%s):", sum.AVScanner)
+	for _, r := range sum.AVResults {
+		switch r.Verdict {
+		case avscan.Infected:
+			text += fmt.Sprintf("\n  🦠 %s: %s", filepath.Base(r.Path), strings.Join(r.Threats, ", "))
+		case avscan.Failed:
+			text += fmt.Sprintf("\n  ⚠️ %s: %v", filepath.Base(r.Path), r.Err)
+		}
+	}
+	if n := sum.countVerdict(avscan.Clean); n > 0 { text += fmt.Sprintf("\n  ✅ %d clean", n) }
+	return text
+}
+
+// buildAntivirusControls returns the Settings row turning the post-decryption scan on and choosing the scanner
+func (s *AppState) buildAntivirusControls() fyne.CanvasObject {
+	path := widget.NewEntry()
+	path.SetPlaceHolder("auto: Defender, clamdscan, clamscan")
+	path.SetText(s.config.AVScannerPath)
+	path.OnChanged = func(text string) {
+		text = strings.TrimSpace(text)
+		if text == s.config.AVScannerPath { return }
+		s.config.AVScannerPath = text
+		s.config.Save()
+	}
+	check := widget.NewCheck("Scan decrypted files", func(checked bool) {
+		if checked { path.Enable() } else { path.Disable() }
+		if s.config.AVScan != checked {
+			s.config.AVScan = checked
+			s.config.Save()
+		}
+	})
+	check.SetChecked(s.config.AVScan)
+	if !s.config.AVScan { path.Disable() }
+	return container.NewBorder(nil, nil, check, nil, path)
+}
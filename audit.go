@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/audit"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+// doSecurityAudit scans the selection for containers using legacy or weak parameters
+func (s *AppState) doSecurityAudit(w fyne.Window) {
+	var paths []string
+	if s.selectedPath != "" { paths = []string{s.selectedPath} } else { paths = s.selectedPaths }
+	if len(paths) == 0 {
+		dialog.ShowInformation("Security Audit", "Select encrypted files or folders to audit.", w)
+		return
+	}
+
+	finalPassword := []byte(s.password)
+	if s.keyfileManager.HasKeyfiles() { finalPassword = s.keyfileManager.GetCombinedKey([]byte(s.password)) }
+	if len(finalPassword) == 0 { finalPassword = nil }
+	findings := audit.Scan(paths, finalPassword)
+	if len(findings) == 0 {
+		dialog.ShowInformation("Security Audit", "No HadesCrypt containers found in the selection.", w)
+		return
+	}
+
+	var flagged []audit.Finding
+	var sb strings.Builder
+	for _, f := range findings {
+		icon := "✅"
+		switch f.Severity {
+		case audit.SeverityInfo: icon = "ℹ️"
+		case audit.SeverityWarning: icon = "⚠️"
+		case audit.SeverityCritical: icon = "❌"
+		}
+		mode := "?"
+		if f.Header != nil { mode = cryptoengine.GetEncryptionModeName(f.Header.Mode) }
+		fmt.Fprintf(&sb, "%s %s — %s\n", icon, filepath.Base(f.Path), mode)
+		for _, issue := range f.Issues { fmt.Fprintf(&sb, "     • %s\n", issue) }
+		if f.NeedsReencryption() { flagged = append(flagged, f) }
+	}
+
+	report := widget.NewLabel(sb.String())
+	report.Wrapping = fyne.TextWrapWord
+	summary := widget.NewLabel(fmt.Sprintf("%d file(s) scanned, %d need re-encryption", len(findings), len(flagged)))
+
+	var d dialog.Dialog
+	reencBtn := widget.NewButton(fmt.Sprintf("Re-encrypt %d flagged file(s) to current defaults", len(flagged)), func() {
+		d.Hide()
+		s.reencryptFindings(w, flagged)
+	})
+	reencBtn.Importance = widget.HighImportance
+	if len(flagged) == 0 { reencBtn.Disable() }
+
+	content := container.NewBorder(summary, reencBtn, nil, nil, container.NewScroll(report))
+	d = dialog.NewCustom("Security Audit", "Close", content, w)
+	d.Resize(fyne.NewSize(620, 460))
+	d.Show()
+}
+
+// reencryptFindings converts flagged containers with the current password to AES-256-GCM
+func (s *AppState) reencryptFindings(w fyne.Window, flagged []audit.Finding) {
+	opts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM, Compliance: s.config.ComplianceMode, Argon2: cryptoengine.Argon2Preset(s.opt().Argon2Preset)}
+	s.convertContainers(w, flagged, opts)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/throttle"
+)
+
+// beginBackgroundJob applies low-priority mode to the calling job goroutine and
+// returns the job's rate limiter (nil when unlimited or the mode is off).
+// The goroutine must end with the job: its OS thread stays deprioritized.
+func (s *AppState) beginBackgroundJob() *throttle.Limiter {
+	if !s.config.LowPriority { return nil }
+	if err := throttle.LowerPriority(); err != nil {
+		s.setStatus("🐢 Low priority: " + err.Error() + " (rate limit only)")
+	}
+	return throttle.NewLimiter(throttle.MBps(s.config.RateLimitMBps))
+}
+
+// buildBackgroundControls returns the low-priority checkbox and its MB/s limit row
+func (s *AppState) buildBackgroundControls() (*widget.Check, fyne.CanvasObject) {
+	rateEntry := widget.NewEntry()
+	rateEntry.SetPlaceHolder("0 = no limit")
+	if s.config.RateLimitMBps > 0 { rateEntry.SetText(strconv.FormatFloat(s.config.RateLimitMBps, 'f', -1, 64)) }
+	rateEntry.Validator = func(text string) error {
+		text = strings.TrimSpace(text)
+		if text == "" { return nil }
+		if v, err := strconv.ParseFloat(text, 64); err != nil || v < 0 { return fmt.Errorf("enter MB/s, e.g. 50") }
+		return nil
+	}
+	rateEntry.OnChanged = func(text string) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if strings.TrimSpace(text) == "" { v, err = 0, nil }
+		if err != nil || v < 0 || v == s.config.RateLimitMBps { return }
+		s.config.RateLimitMBps = v
+		s.config.Save()
+	}
+	check := widget.NewCheck("Low priority (background thread, IO rate limit)", func(checked bool) {
+		if checked { rateEntry.Enable() } else { rateEntry.Disable() }
+		if s.config.LowPriority != checked {
+			s.config.LowPriority = checked
+			s.config.Save()
+		}
+	})
+	check.SetChecked(s.config.LowPriority)
+	if !s.config.LowPriority { rateEntry.Disable() }
+	row := container.NewBorder(nil, nil, widget.NewLabel("Limit:"), widget.NewLabel("MB/s"), rateEntry)
+	return check, row
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/bench"
+)
+
+// benchSizes are the data sizes offered per measurement, in MiB
+var benchSizes = []string{"16 MiB", "64 MiB", "256 MiB"}
+
+// showBenchmarkDialog measures the modes, KDF presets and compression levels on
+// this machine and exports the figures as CSV; closing the dialog stops a run
+func (s *AppState) showBenchmarkDialog(w fyne.Window) {
+	sizeSelect := widget.NewSelect(benchSizes, nil)
+	sizeSelect.SetSelected("64 MiB")
+	modesCheck, kdfCheck, compCheck := widget.NewCheck("Encryption modes", nil), widget.NewCheck("KDF presets", nil), widget.NewCheck("Compression levels", nil)
+	modesCheck.SetChecked(true); kdfCheck.SetChecked(true); compCheck.SetChecked(true)
+	info := widget.NewLabel("Runs in memory, so the figures show the CPU rather than the disk. Modes are timed after the key is derived; the KDF rows are the wait per file. The Maximum KDF preset needs 1 GiB of memory.")
+	info.Wrapping = fyne.TextWrapWord
+	report := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	activity := widget.NewActivity()
+	activity.Hide()
+
+	var results []bench.Result
+	ctx, cancel := context.WithCancel(context.Background())
+	var runBtn, exportBtn *widget.Button
+	runBtn = widget.NewButton("Run", func() {
+		opts, all := bench.Options{}, bench.Defaults()
+		var mib int64
+		fmt.Sscanf(sizeSelect.Selected, "%d", &mib)
+		opts.Size = mib << 20
+		if modesCheck.Checked { opts.Modes = all.Modes }
+		if kdfCheck.Checked { opts.KDFs = all.KDFs }
+		if compCheck.Checked { opts.Levels = all.Levels }
+		runBtn.Disable(); exportBtn.Disable(); activity.Show(); activity.Start()
+		report.SetText("")
+		go func() {
+			var sb strings.Builder
+			res, err := bench.Run(ctx, opts, func(r bench.Result) {
+				sb.WriteString(r.String() + "\n")
+				text := sb.String()
+				s.ui(func() { report.SetText(text) })
+			})
+			s.ui(func() {
+				activity.Stop(); activity.Hide(); runBtn.Enable()
+				results = res
+				if len(results) > 0 { exportBtn.Enable() }
+				if err != nil && ctx.Err() == nil { dialog.ShowError(err, w) }
+			})
+		}()
+	})
+	runBtn.Importance = widget.HighImportance
+	exportBtn = widget.NewButton("Export CSV…", func() {
+		var buf bytes.Buffer
+		if err := bench.WriteCSV(&buf, results); err != nil { dialog.ShowError(err, w); return }
+		save := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil || wc == nil { return }
+			path := wc.URI().Path()
+			wc.Close()
+			if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil { dialog.ShowError(err, w) }
+		}, w)
+		save.SetFileName("hadescrypt-benchmark.csv")
+		save.Show()
+	})
+	exportBtn.Disable()
+
+	controls := container.NewHBox(widget.NewLabel("Data:"), sizeSelect, modesCheck, kdfCheck, compCheck)
+	top := container.NewVBox(info, controls)
+	bottom := container.NewBorder(nil, nil, activity, container.NewHBox(runBtn, exportBtn))
+	d := dialog.NewCustom("⏱ Benchmark", "Close", container.NewBorder(top, bottom, nil, nil, container.NewScroll(report)), w)
+	d.SetOnClosed(cancel)
+	d.Resize(fyne.NewSize(760, 520))
+	d.Show()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/bench"
+)
+
+func benchCmd(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	sizeMiB := fs.Int64("size", bench.DefaultSize>>20, "MiB of data per mode and compression level")
+	only := fs.String("only", "modes,kdf,compression", "comma-separated parts to measure")
+	samplePath := fs.String("sample", "", "compress the start of this file instead of generated data")
+	output := fs.String("o", "", "write the CSV here (default: stdout)")
+	workers := fs.Int("workers", 0, "chunks sealed concurrently (default: the CPU count)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 || *sizeMiB <= 0 {
+		usage()
+		return exitUsage
+	}
+	opts := bench.Options{Size: *sizeMiB << 20, Workers: *workers}
+	all := bench.Defaults()
+	for _, part := range strings.Split(*only, ",") {
+		switch strings.TrimSpace(part) {
+		case "modes":
+			opts.Modes = all.Modes
+		case "kdf":
+			opts.KDFs = all.KDFs
+		case "compression":
+			opts.Levels = all.Levels
+		default:
+			fmt.Fprintf(os.Stderr, "error: unknown -only part %q; use modes, kdf or compression\n", part)
+			return exitUsage
+		}
+	}
+	if *samplePath != "" {
+		f, err := os.Open(*samplePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return apperr.ExitCode(err)
+		}
+		defer f.Close()
+		opts.Sample = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	results, err := bench.Run(ctx, opts, func(r bench.Result) { fmt.Fprintln(os.Stderr, r) })
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return apperr.ExitCode(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := bench.WriteCSV(out, results); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	return 0
+}
@@ -0,0 +1,586 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/archiver"
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/fetch"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+	"github.com/bangundwir/HadesCrypt/internal/keyfiles"
+	"github.com/bangundwir/HadesCrypt/internal/media"
+	"github.com/bangundwir/HadesCrypt/internal/operations"
+	"github.com/bangundwir/HadesCrypt/internal/pgppacket"
+	"github.com/bangundwir/HadesCrypt/internal/policy"
+	"github.com/bangundwir/HadesCrypt/internal/progress"
+	"github.com/bangundwir/HadesCrypt/internal/safename"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+	"github.com/bangundwir/HadesCrypt/internal/splitter"
+	"github.com/bangundwir/HadesCrypt/internal/units"
+)
+
+// keyfileFlag collects repeated -keyfile flags in order
+type keyfileFlag []string
+
+func (k *keyfileFlag) String() string { return strings.Join(*k, ",") }
+
+func (k *keyfileFlag) Set(path string) error {
+	*k = append(*k, path)
+	return nil
+}
+
+// withKeyfiles combines password with the keyfiles the way the app does, so
+// containers made by either open in the other
+func withKeyfiles(password []byte, paths []string) ([]byte, error) {
+	if len(paths) == 0 {
+		return password, nil
+	}
+	km := keyfiles.NewKeyfileManager()
+	for _, p := range paths {
+		if err := keyfiles.ValidateKeyfile(p); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		if err := km.AddKeyfile(p); err != nil {
+			return nil, err
+		}
+	}
+	return km.GetCombinedKey(password), nil
+}
+
+// loadPQKey reads the post-quantum key file at path, if any, and with decrypt
+// set loads it for decryption
+func loadPQKey(path string, decrypt bool) (*cryptoengine.KEMKey, error) {
+	if path == "" {
+		return nil, nil
+	}
+	k, err := cryptoengine.LoadKEMKey(path)
+	if err != nil {
+		return nil, err
+	}
+	if decrypt {
+		cryptoengine.AddKEMKey(k)
+	}
+	return k, nil
+}
+
+// progressLine draws one updating percentage line on stderr; a nil
+// progressLine stays silent
+type progressLine struct {
+	label string
+	last  int64
+}
+
+func newProgressLine(on bool, label string) *progressLine {
+	if !on {
+		return nil
+	}
+	return &progressLine{label: label, last: -1}
+}
+
+func (p *progressLine) update(done, total int64) {
+	if p == nil || total <= 0 {
+		return
+	}
+	pct := min(done*100/total, 100)
+	if pct == p.last {
+		return
+	}
+	p.last = pct
+	fmt.Fprintf(os.Stderr, "\r%s %3d%%  %s of %s", p.label, pct, units.Bytes(done), units.Bytes(total))
+}
+
+// end finishes the line so later output starts on its own
+func (p *progressLine) end() {
+	if p != nil && p.last >= 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// splitArgs moves the positional arguments before the flags out of args, so
+// paths can come before or after the flags
+func splitArgs(args []string) (paths, rest []string) {
+	for len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		paths, args = append(paths, args[0]), args[1:]
+	}
+	return paths, args
+}
+
+func encryptCmd(args []string) int {
+	fs := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	output := fs.String("o", "", "write the container here (default: input name plus the mode's extension)")
+	modeName := fs.String("mode", cryptoengine.GetEncryptionModeName(cryptoengine.ModeAES256GCM), "encryption mode, e.g. ChaCha20-Poly1305 or GnuPG/OpenPGP")
+	kdf := fs.String("kdf", "Balanced", "Argon2id preset: "+strings.Join(cryptoengine.Argon2PresetNames, ", "))
+	comment := fs.String("comment", "", "comment stored in the header, readable without the password")
+	hint := fs.String("hint", "", "password hint stored in the header, readable without the password")
+	compress := fs.Bool("compress", false, "deflate a file before it is encrypted; folder archives are compressed already")
+	adaptive := fs.Bool("adaptive", false, "grow chunks from 64 KiB to 4 MiB while the input keeps up; faster from spinning disks and network shares")
+	ecc := fs.Bool("ecc", false, "add Reed-Solomon parity, about a quarter more, that repairs damaged blocks on decryption")
+	deny := fs.Bool("deny", false, "Deniability Mode: wrap the whole container, header included, so it reads as random bytes")
+	var kf keyfileFlag
+	fs.Var(&kf, "keyfile", "keyfile to combine with the password; repeat for more, in the same order when decrypting")
+	pqKeyFile := fs.String("pqkey", "", "post-quantum key file the Kyber768 and Hybrid PQ modes encrypt to; see pqkey gen")
+	passwordFile := fs.String("password-file", "", "read the password from the first line of this file")
+	showProgress := fs.Bool("progress", false, "show progress on stderr")
+	overwrite := fs.Bool("overwrite", false, "replace an existing output")
+	names := fs.String("names", "keep", "default output name: keep, transliterate (ASCII) or percent (encode other bytes); the exact name is kept in the header")
+	paths, args := splitArgs(args)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	paths = append(paths, fs.Args()...)
+	if len(paths) != 1 {
+		usage()
+		return exitUsage
+	}
+	input := paths[0]
+	style, err := safename.ParseStyle(*names)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitUsage
+	}
+	mode, ok := cryptoengine.ModeByName(*modeName)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown -mode %q\n", *modeName)
+		return exitUsage
+	}
+	if !slices.Contains(cryptoengine.Argon2PresetNames, *kdf) {
+		fmt.Fprintf(os.Stderr, "error: unknown -kdf preset %q\n", *kdf)
+		return exitUsage
+	}
+	if cryptoengine.NeedsKEMKey(mode) != (*pqKeyFile != "") {
+		fmt.Fprintln(os.Stderr, "error: -pqkey goes with the Kyber768 and Hybrid PQ modes, which need it; create one with pqkey gen")
+		return exitUsage
+	}
+	pqKey, err := loadPQKey(*pqKeyFile, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "warning: config:", err)
+	}
+	opts := cryptoengine.EncryptionOptions{
+		Mode:       mode,
+		Compliance: cfg.ComplianceMode,
+		Argon2:     cryptoengine.Argon2Preset(*kdf),
+		GPGPath:    cfg.GPGPath,
+		Metadata:   cryptoengine.Metadata{Comment: *comment, Hint: *hint},
+		KEMKey:     pqKey,
+	}
+	if err := opts.Metadata.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitUsage
+	}
+	if mode == cryptoengine.ModeGnuPG && !opts.Metadata.IsZero() {
+		fmt.Fprintln(os.Stderr, "warning: GnuPG containers do not store a comment or hint")
+	}
+	pol, err := policy.Load()
+	if err == nil {
+		err = pol.CheckEncrypt(opts)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	info, err := os.Stat(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	if info.IsDir() && mode == cryptoengine.ModeGnuPG {
+		fmt.Fprintln(os.Stderr, "error: GnuPG mode cannot encrypt folders; pick another mode")
+		return apperr.Unsupported.ExitCode()
+	}
+	// a folder archive is gzip compressed already
+	opts.UseCompression = *compress && !info.IsDir()
+	opts.AdaptiveChunks = *adaptive
+	opts.UseReedSolomon = *ecc
+	opts.UseDeniability = *deny
+	ops := &operations.Controller{Settings: operations.Settings{Mode: mode, Names: style}}
+	out := *output
+	if out == "" {
+		out = ops.EncryptedPath(filepath.Clean(input))
+		opts.Metadata.Name = ops.OriginalName(filepath.Clean(input))
+	}
+	if _, err := os.Lstat(out); err == nil && !*overwrite {
+		fmt.Fprintf(os.Stderr, "error: %s already exists; pass -overwrite or choose -o\n", out)
+		return exitUsage
+	}
+	// refuse now rather than gigabytes into the run; compressed output has no known size,
+	// and adaptive output is at least as large as with fixed chunks
+	fixed := opts
+	fixed.AdaptiveChunks = false
+	if size, err := cryptoengine.EncryptedSize(info.Size(), fixed); err == nil && !info.IsDir() && !opts.UseCompression {
+		if limit := media.MaxFileSize(filepath.Dir(out)); limit.Size > 0 && size > limit.Size {
+			fmt.Fprintf(os.Stderr, "error: the output will be %s, but %s takes files of at most %s; choose another -o\n", units.Bytes(size), limit.Name, units.Bytes(limit.Size))
+			return exitUsage
+		}
+	}
+
+	password, err := newPassword(*passwordFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitUsage
+	}
+	defer secret.Wipe(password)
+	key, err := withKeyfiles(password, kf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	defer secret.Wipe(key)
+
+	line := newProgressLine(*showProgress, "encrypting")
+	err = encryptPath(input, out, info, key, opts, ops, line.update)
+	line.end()
+	if err == nil {
+		err = pol.Escrow(out, key, len(kf) > 0)
+	}
+	if err != nil {
+		os.Remove(out)
+		fmt.Fprintln(os.Stderr, "error:", secret.ScrubError(err, password, key))
+		return apperr.ExitCode(err)
+	}
+	fmt.Println(out)
+	return 0
+}
+
+// encryptPath encrypts a file, or a folder packed into a tar.gz archive first as
+// the app does, with progress over both phases
+func encryptPath(input, out string, info os.FileInfo, key []byte, opts cryptoengine.EncryptionOptions, ops *operations.Controller, onProgress cryptoengine.ProgressCallback) error {
+	if !info.IsDir() {
+		return cryptoengine.EncryptFileWithOptions(input, out, key, opts, onProgress)
+	}
+	tmp, err := securetemp.TempPath(filepath.Dir(out), filepath.Base(out)+".*.temp.tar.gz")
+	if err != nil {
+		return fmt.Errorf("create temp archive: %w", err)
+	}
+	defer securetemp.Remove(tmp)
+	var total int64
+	walkOpts := ops.WalkOptions(input, false)
+	files, err := ops.ScanWith(input, walkOpts, func(e fswalk.Entry) bool { return e.Info.Mode().IsRegular() })
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		total += f.Info.Size()
+	}
+	track := progress.New(total, progress.Func(onProgress))
+	archivePhase, encryptPhase := track.Phase(total), track.Phase(total)
+	if err := archiver.CreateTarGzWithOptions(input, tmp, walkOpts, archivePhase.Update); err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+	archivePhase.Complete()
+	if fi, err := os.Stat(tmp); err == nil {
+		encryptPhase.SetWeight(fi.Size())
+	}
+	if err := cryptoengine.EncryptFileWithOptions(tmp, out, key, opts, encryptPhase.Update); err != nil {
+		return fmt.Errorf("encrypt archive: %w", err)
+	}
+	encryptPhase.Complete()
+	return nil
+}
+
+func decryptCmd(args []string) int {
+	fs := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	output := fs.String("o", "", "write the result here (default: the name without the container extension)")
+	var kf keyfileFlag
+	fs.Var(&kf, "keyfile", "keyfile used when encrypting; repeat for more, in the same order")
+	pqKeyFile := fs.String("pqkey", "", "post-quantum key file a Kyber768 or Hybrid PQ container was encrypted to")
+	passwordFile := fs.String("password-file", "", "read the password from the first line of this file")
+	showProgress := fs.Bool("progress", false, "show progress on stderr")
+	overwrite := fs.Bool("overwrite", false, "replace an existing output")
+	sum := fs.String("sha256", "", "with a link: the SHA-256 the download must match")
+	sig := fs.String("sig", "", "with a link: the minisign signature of the download, as a .minisig path or https:// link")
+	pubkey := fs.String("pubkey", "", "with -sig: the publisher's minisign public key, or the path of its .pub file")
+	paths, args := splitArgs(args)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	paths = append(paths, fs.Args()...)
+	if len(paths) != 1 {
+		usage()
+		return exitUsage
+	}
+	input := paths[0]
+	remote := fetch.IsURL(input)
+	if !remote && (*sum != "" || *sig != "" || *pubkey != "") {
+		fmt.Fprintln(os.Stderr, "error: -sha256, -sig and -pubkey check a download; pass an https:// link")
+		return exitUsage
+	}
+	if base, parts := splitter.Volumes(input); parts != nil && !remote {
+		// a volume stands for the container it was split from; DecryptFile joins them
+		input = base
+	} else if _, err := os.Stat(input); err != nil && !remote {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	out := *output
+	if out == "" && !remote {
+		out = (&operations.Controller{}).DecryptedPath(input)
+	}
+	if _, err := os.Lstat(out); err == nil && !*overwrite && !remote {
+		fmt.Fprintf(os.Stderr, "error: %s already exists; pass -overwrite or choose -o\n", out)
+		return exitUsage
+	}
+	if _, err := loadPQKey(*pqKeyFile, true); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "warning: config:", err)
+	}
+
+	password, err := filePassword(*passwordFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitUsage
+	}
+	defer secret.Wipe(password)
+	key, err := withKeyfiles(password, kf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	defer secret.Wipe(key)
+
+	line := newProgressLine(*showProgress, "decrypting")
+	if remote {
+		opts := fetch.Options{Limits: archiver.Limits{MaxBytes: int64(cfg.MaxExtractGB * 1e9), MaxFiles: cfg.MaxExtractFiles}}
+		out, err = decryptURL(input, out, key, *sum, *sig, *pubkey, opts, line.update)
+	} else {
+		err = decryptPath(input, out, key, cfg, line.update)
+	}
+	line.end()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", secret.ScrubError(err, password, key))
+		return apperr.ExitCode(err)
+	}
+	fmt.Println(out)
+	return 0
+}
+
+// decryptURL decrypts the container at an https:// link into the folder dir
+// (default: the current one) as it downloads, checked against sum and sig when
+// given, and returns the output path
+func decryptURL(link, dir string, key []byte, sum, sig, pubkey string, opts fetch.Options, onProgress cryptoengine.ProgressCallback) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	sig, err := fileOrText(sig)
+	if err != nil {
+		return "", fmt.Errorf("-sig: %w", err)
+	}
+	if pubkey, err = fileOrText(pubkey); err != nil {
+		return "", fmt.Errorf("-pubkey: %w", err)
+	}
+	if opts.Expect, err = fetch.ParseExpect(ctx, nil, sum, sig, pubkey); err != nil {
+		return "", apperr.Wrap(apperr.Usage, err)
+	}
+	res, err := fetch.Decrypt(ctx, nil, link, dir, key, opts, onProgress)
+	if err != nil {
+		return "", err
+	}
+	if res.Signed {
+		fmt.Fprintf(os.Stderr, "signature verified: %s\n", res.Trusted)
+	} else if !res.Checked {
+		fmt.Fprintln(os.Stderr, "note: the server sent no checksum; the container's chunks were authenticated")
+	}
+	return res.Path, nil
+}
+
+// fileOrText returns the contents of the file s names, or s itself when it is
+// text or a link rather than a path
+func fileOrText(s string) (string, error) {
+	if s == "" || fetch.IsURL(s) || strings.ContainsAny(s, "\n") {
+		return s, nil
+	}
+	data, err := os.ReadFile(s)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	return string(data), err
+}
+
+// decryptPath decrypts input to out. A HadesCrypt container holding a folder
+// archive is extracted into the folder out within the configured limits.
+func decryptPath(input, out string, key []byte, cfg *config.Config, onProgress cryptoengine.ProgressCallback) error {
+	if !format.IsHadesCrypt(input) && cryptoengine.IsGnuPGFile(input) {
+		return cryptoengine.DecryptFileWithGnuPGAt(cfg.GPGPath, input, out, key, onProgress)
+	}
+	tmp, err := securetemp.TempPath(filepath.Dir(out), filepath.Base(out)+".*.__dec_tmp__")
+	if err != nil {
+		return err
+	}
+	defer securetemp.Remove(tmp)
+	var size int64
+	if fi, err := os.Stat(input); err == nil {
+		size = fi.Size()
+	} else if _, n, err := splitter.GetChunkInfo(input); err == nil {
+		size = n
+	}
+	track := progress.New(size, progress.Func(onProgress))
+	decryptPhase, extractPhase := track.Phase(size), track.Phase(size)
+	if err := cryptoengine.DecryptFile(input, tmp, key, false, decryptPhase.Update); err != nil {
+		return err
+	}
+	decryptPhase.Complete()
+	if !archiver.IsArchive(tmp) {
+		return os.Rename(tmp, out)
+	}
+	if fi, err := os.Stat(tmp); err == nil {
+		extractPhase.SetWeight(fi.Size())
+	}
+	limits := archiver.Limits{MaxBytes: int64(cfg.MaxExtractGB * 1e9), MaxFiles: cfg.MaxExtractFiles}
+	if err := archiver.ExtractTarGzWithLimits(tmp, out, limits, extractPhase.Update); err != nil {
+		return fmt.Errorf("extract archive: %w", err)
+	}
+	extractPhase.Complete()
+	return nil
+}
+
+func infoCmd(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitUsage
+	}
+	code := 0
+	for i, path := range args {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := printInfo(path); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			code = apperr.ExitCode(err)
+		}
+	}
+	return code
+}
+
+// printInfo prints what a container reveals without the password
+func printInfo(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	h, err := cryptoengine.ReadHeaderFromFile(path)
+	if errors.Is(err, cryptoengine.ErrNotContainer) && cryptoengine.IsGnuPGFile(path) {
+		msg, err := pgppacket.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n  format:   GnuPG/OpenPGP\n  size:     %s\n  contents: %s\n", path, units.Bytes(fi.Size()), msg.Summary())
+		for _, p := range msg.Packets {
+			if p.Warning != "" {
+				fmt.Printf("  warning:  %s\n", p.Warning)
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	kdf := h.KDFParams().String()
+	if h.Compliance() {
+		kdf = "PBKDF2-HMAC-SHA256 (compliance mode)"
+	}
+	fmt.Printf("%s\n  format:   HadesCrypt v%d\n  mode:     %s\n  kdf:      %s\n", path, h.Version, cryptoengine.GetEncryptionModeName(h.Mode), kdf)
+	fmt.Printf("  size:     %s encrypted, %s original\n  chunks:   %s\n", units.Bytes(fi.Size()), units.Bytes(h.OriginalSize), units.Bytes(int64(h.ChunkSize)))
+	if fp := h.KEMFingerprint(); fp != "" {
+		fmt.Printf("  pq key:   %s\n", fp)
+	}
+	// compressed containers are framed too, with chunks of one size
+	if h.Flags&cryptoengine.FlagFramed != 0 && h.MinChunkSize != h.ChunkSize {
+		fmt.Printf("  adaptive: %s to %s per chunk\n", units.Bytes(int64(h.MinChunkSize)), units.Bytes(int64(h.ChunkSize)))
+	}
+	if h.Flags&cryptoengine.FlagReedSolomon != 0 {
+		fmt.Printf("  parity:   Reed-Solomon, %d data and %d parity shards per block\n", h.DataShards, h.ParityShards)
+	}
+	if h.Flags&cryptoengine.FlagCompressed != 0 {
+		fmt.Printf("  deflate:  %s compressed\n", units.Bytes(h.CompressedSize))
+	}
+	for _, e := range h.Extensions {
+		fmt.Printf("  extension: %#04x, %s\n", e.Type, units.Bytes(int64(len(e.Value))))
+	}
+	if m := h.Metadata; !m.IsZero() {
+		if m.Name != "" {
+			fmt.Printf("  name:     %s\n", m.Name)
+		}
+		if m.Hint != "" {
+			fmt.Printf("  hint:     %s\n", m.Hint)
+		}
+		if m.Comment != "" {
+			fmt.Printf("  comment:  %s\n", strings.ReplaceAll(m.Comment, "\n", "\n            "))
+		}
+	}
+	return nil
+}
+
+func keyfileCmd(args []string) int {
+	if len(args) == 0 || args[0] != "gen" {
+		usage()
+		return exitUsage
+	}
+	fs := flag.NewFlagSet("keyfile gen", flag.ContinueOnError)
+	sizeKB := fs.Int("size", 1, "keyfile size in KiB")
+	paths, rest := splitArgs(args[1:])
+	if err := fs.Parse(rest); err != nil {
+		return exitUsage
+	}
+	paths = append(paths, fs.Args()...)
+	if len(paths) != 1 || *sizeKB <= 0 {
+		usage()
+		return exitUsage
+	}
+	if _, err := os.Lstat(paths[0]); err == nil {
+		fmt.Fprintf(os.Stderr, "error: %s already exists; a keyfile is never overwritten\n", paths[0])
+		return exitUsage
+	}
+	if err := keyfiles.GenerateKeyfile(paths[0], *sizeKB); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	fmt.Println(paths[0])
+	return 0
+}
+
+func pqkeyCmd(args []string) int {
+	if len(args) != 2 || args[0] != "gen" {
+		usage()
+		return exitUsage
+	}
+	path := args[1]
+	k, err := cryptoengine.GenerateKEMKey(rand.Reader)
+	if err == nil {
+		err = cryptoengine.WriteKEMKey(path, k)
+	}
+	if errors.Is(err, os.ErrExist) {
+		fmt.Fprintf(os.Stderr, "error: %s already exists; a key file is never overwritten\n", path)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	fmt.Fprintln(os.Stderr, "fingerprint:", k.Fingerprint())
+	fmt.Println(path)
+	return 0
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+func TestEncryptDecryptFolder(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	pwFile := filepath.Join(dir, "pw")
+	if err := os.WriteFile(pwFile, []byte("correct horse\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "docs")
+	os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("alpha"), 0644)
+	os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("beta"), 0644)
+
+	key := filepath.Join(dir, "key.bin")
+	if code := keyfileCmd([]string{"gen", key, "-size", "2"}); code != 0 {
+		t.Fatalf("keyfile gen exit %d", code)
+	}
+	if fi, err := os.Stat(key); err != nil || fi.Size() != 2048 {
+		t.Fatalf("keyfile %v %v", fi, err)
+	}
+	if code := keyfileCmd([]string{"gen", key}); code != exitUsage {
+		t.Errorf("keyfile gen over an existing file: exit %d", code)
+	}
+
+	enc := filepath.Join(dir, "docs.hadescrypt")
+	if code := encryptCmd([]string{src, "-kdf", "Fast", "-keyfile", key, "-comment", "quarterly", "-password-file", pwFile, "-o", enc}); code != 0 {
+		t.Fatalf("encrypt exit %d", code)
+	}
+	h, err := cryptoengine.ReadHeaderFromFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Metadata.Comment != "quarterly" {
+		t.Errorf("comment %q", h.Metadata.Comment)
+	}
+	if code := infoCmd([]string{enc}); code != 0 {
+		t.Errorf("info exit %d", code)
+	}
+
+	out := filepath.Join(dir, "restored")
+	if code := decryptCmd([]string{enc, "-password-file", pwFile, "-o", out}); code == 0 {
+		t.Fatal("decrypted without the keyfile")
+	}
+	if code := decryptCmd([]string{enc, "-keyfile", key, "-password-file", pwFile, "-o", out}); code != 0 {
+		t.Fatalf("decrypt exit %d", code)
+	}
+	if b, err := os.ReadFile(filepath.Join(out, "sub", "b.txt")); err != nil || string(b) != "beta" {
+		t.Errorf("restored b.txt %q %v", b, err)
+	}
+}
+
+func TestEncryptDecryptPQKey(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	pwFile := filepath.Join(dir, "pw")
+	if err := os.WriteFile(pwFile, []byte("correct horse\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "plan.txt")
+	os.WriteFile(src, []byte("quantum-safe"), 0644)
+
+	key := filepath.Join(dir, "pq.key")
+	if code := pqkeyCmd([]string{"gen", key}); code != 0 {
+		t.Fatalf("pqkey gen exit %d", code)
+	}
+	if code := pqkeyCmd([]string{"gen", key}); code != exitUsage {
+		t.Errorf("pqkey gen over an existing file: exit %d", code)
+	}
+	mode := cryptoengine.GetEncryptionModeName(cryptoengine.ModeHybridPQ)
+	enc := filepath.Join(dir, "plan.hadescrypt")
+	if code := encryptCmd([]string{src, "-kdf", "Fast", "-mode", mode, "-password-file", pwFile, "-o", enc}); code != exitUsage {
+		t.Errorf("encrypt without -pqkey: exit %d", code)
+	}
+	if code := encryptCmd([]string{src, "-kdf", "Fast", "-mode", mode, "-pqkey", key, "-password-file", pwFile, "-o", enc}); code != 0 {
+		t.Fatalf("encrypt exit %d", code)
+	}
+
+	out := filepath.Join(dir, "restored.txt")
+	if code := decryptCmd([]string{enc, "-password-file", pwFile, "-o", out}); code != apperr.WrongPassword.ExitCode() {
+		t.Fatalf("decrypt with the password alone: exit %d", code)
+	}
+	if code := decryptCmd([]string{enc, "-pqkey", key, "-password-file", pwFile, "-o", out}); code != 0 {
+		t.Fatalf("decrypt exit %d", code)
+	}
+	if b, err := os.ReadFile(out); err != nil || string(b) != "quantum-safe" {
+		t.Errorf("restored %q %v", b, err)
+	}
+}
@@ -0,0 +1,524 @@
+// Command hadescrypt-cli runs HadesCrypt operations without the GUI.
+//
+//	hadescrypt-cli encrypt file-or-folder [-o output] [-mode name] [-keyfile path]... [-pqkey path] [-comment text] [-progress]
+//	hadescrypt-cli decrypt file.hadescrypt [-o output] [-keyfile path]... [-pqkey path] [-progress]
+//	hadescrypt-cli info file...
+//	hadescrypt-cli keyfile gen path [-size KiB]
+//	hadescrypt-cli pqkey gen path
+//	hadescrypt-cli run jobs.yaml [-report report.json] [-parallel N] [-password-file path] [-every 1h] [-metrics 127.0.0.1:9464]
+//	hadescrypt-cli repair file.hadescrypt [-o repaired.hadescrypt] [-password-file path]
+//	hadescrypt-cli convert file-or-folder... [-mode name] [-kdf preset] [-pqkey path] [-password-file path]
+//	hadescrypt-cli bench [-size MiB] [-only modes,kdf,compression] [-sample path] [-o results.csv]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/audit"
+	"github.com/bangundwir/HadesCrypt/internal/batch"
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/metrics"
+	"github.com/bangundwir/HadesCrypt/internal/notify"
+	"github.com/bangundwir/HadesCrypt/internal/policy"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+	"github.com/bangundwir/HadesCrypt/internal/store"
+)
+
+// Exit codes are defined by apperr so the GUI and scripts share one taxonomy
+var (
+	exitFailed = apperr.Internal.ExitCode()
+	exitUsage  = apperr.Usage.ExitCode()
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  hadescrypt-cli encrypt <file|folder> [-o output] [-mode name] [-kdf preset] [-keyfile path]... [-pqkey path]
+                     [-comment text] [-hint text] [-compress] [-adaptive] [-ecc] [-deny] [-password-file path] [-progress] [-overwrite]
+  hadescrypt-cli decrypt <file|https-url> [-o output] [-keyfile path]... [-pqkey path] [-password-file path] [-progress] [-overwrite]
+                     [-sha256 hex] [-sig file|https-url] [-pubkey key|file]
+  hadescrypt-cli info <file>...
+  hadescrypt-cli keyfile gen <path> [-size KiB]
+  hadescrypt-cli pqkey gen <path>
+  hadescrypt-cli run <jobs.yaml|jobs.json> [-report path] [-parallel N] [-password-file path]
+                     [-every interval] [-metrics 127.0.0.1:9464]
+  hadescrypt-cli repair <file.hadescrypt> [-o output] [-password-file path]
+  hadescrypt-cli convert <file|folder>... [-mode name] [-kdf preset] [-pqkey path] [-password-file path]
+  hadescrypt-cli bench [-size MiB] [-only modes,kdf,compression] [-sample path] [-o results.csv] [-workers N]
+
+encrypt and decrypt work on one file or folder like the app: folders are packed
+into an archive first and unpacked on decryption within the extraction limits of
+the app settings. Keyfiles are combined with the password as in the app, so
+containers open in either. -progress draws a percentage line on stderr; the
+output path is printed on stdout. info shows what a container reveals without
+the password. keyfile gen writes a new random keyfile (default 1 KiB).
+The Kyber768 and Hybrid PQ modes encrypt to a post-quantum key file as well as
+the password: pqkey gen writes a new one, encrypt takes it with -pqkey and
+decrypt needs it again with -pqkey. Keep it apart from the containers; without
+it they cannot be opened, and info shows the fingerprint of the key it takes.
+-compress deflates a file before it is encrypted; decryption inflates it again.
+-adaptive starts with 64 KiB chunks and doubles them up to 4 MiB while the input
+keeps up, which suits spinning disks and network shares. -ecc adds Reed-Solomon
+parity: every 64 KiB of the container gets 16 KiB more, and decryption rebuilds
+up to four damaged 4 KiB pieces of each. -deny writes Deniability Mode output:
+no magic, header or fields readable without the password; decrypt unwraps it
+with the password, and info reports it as no container. decrypt takes any
+volume of a container the app split into parts (name.000…) and joins the set
+first.
+
+decrypt also takes the https:// link of a container, including Dropbox, Google
+Drive and OneDrive share links. The download is decrypted as it arrives into
+the folder -o (default: the current one) under the file's original name,
+resumed when the connection drops and checked against the server's checksum.
+A folder archive is extracted as it arrives. -sha256, and -sig with the
+publisher's -pubkey, check the download against a published checksum and
+minisign signature; nothing is kept when either does not match.
+
+The job file lists sources, destinations, profiles and post-hooks.
+A JSON report is written to -report (or the job file's "report", or stdout).
+A summary goes to the notification targets configured in the app settings.
+
+With -every the command keeps running as a service and repeats the job file at
+that interval. -metrics serves Prometheus metrics (jobs, bytes, failures, queue
+depth, durations) at http://<addr>/metrics on a loopback address.
+
+The password is taken from, in order: -password-file, $HADESCRYPT_PASSWORD_FILE,
+the job file's password_file or password_env, or a no-echo terminal prompt.
+Passwords on the command line are visible to other users and are refused unless
+-allow-argv-password or HADESCRYPT_ALLOW_ARGV_PASSWORD=1 is given.
+
+repair cuts a truncated or damaged container after its last complete,
+authenticated chunk and writes the result to -o (default name.repaired.ext),
+reporting how many bytes were lost. The original is left untouched. The
+password comes from -password-file, $HADESCRYPT_PASSWORD_FILE or a prompt.
+
+convert re-encrypts containers in place with a new -mode (default AES-256-GCM)
+and -kdf preset (default Balanced), keeping the password. Folders are searched
+for containers; files that already use the settings are skipped. Each file is
+decrypted and re-encrypted in one streamed pass, so no plaintext is written to
+disk, and is only replaced once its new version is complete.
+
+bench measures this machine: encryption and decryption throughput of every
+mode, the time each KDF preset takes to derive a key, and the speed and ratio of
+every compression level, on -size MiB in memory (default 64). Compression runs
+on generated text unless -sample names a file of your own. Results are printed
+on stderr as they come and written as CSV to -o (default: stdout).
+
+Exit codes:
+  0 ok               all jobs succeeded
+  1 internal         unexpected or mixed failures
+  2 usage            invalid arguments or job file
+  3 wrong_password   wrong password, keyfile or post-quantum key
+  4 corrupt_file     corrupted, truncated or foreign file
+  5 io_error         a file could not be read or written
+  6 canceled         interrupted before finishing
+  7 partial_success  some jobs succeeded, others failed
+  8 unsupported      format or mode not supported`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	if err := config.SecureDir(); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: restrict config folder:", err)
+	}
+	switch os.Args[1] {
+	case "run":
+		// Job files only encrypt; a viewer build has nothing to run
+		if policy.ViewerBuild {
+			fmt.Fprintln(os.Stderr, "error:", policy.ErrViewer)
+			os.Exit(exitUsage)
+		}
+		os.Exit(runCmd(os.Args[2:]))
+	case "repair":
+		os.Exit(repairCmd(os.Args[2:]))
+	case "convert":
+		if policy.ViewerBuild {
+			fmt.Fprintln(os.Stderr, "error:", policy.ErrViewer)
+			os.Exit(exitUsage)
+		}
+		os.Exit(convertCmd(os.Args[2:]))
+	case "encrypt":
+		if policy.ViewerBuild {
+			fmt.Fprintln(os.Stderr, "error:", policy.ErrViewer)
+			os.Exit(exitUsage)
+		}
+		os.Exit(encryptCmd(os.Args[2:]))
+	case "decrypt":
+		os.Exit(decryptCmd(os.Args[2:]))
+	case "info":
+		os.Exit(infoCmd(os.Args[2:]))
+	case "keyfile":
+		os.Exit(keyfileCmd(os.Args[2:]))
+	case "pqkey":
+		os.Exit(pqkeyCmd(os.Args[2:]))
+	case "bench":
+		os.Exit(benchCmd(os.Args[2:]))
+	case "-h", "--help", "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(exitUsage)
+	}
+}
+
+func runCmd(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	reportPath := fs.String("report", "", "write the JSON report to this file")
+	parallel := fs.Int("parallel", 0, "number of jobs to run concurrently (overrides the job file)")
+	passwordFile := fs.String("password-file", "", "read the password from the first line of this file")
+	argvPassword := fs.String("password", "", "password on the command line (insecure; needs -allow-argv-password)")
+	allowArgv := fs.Bool("allow-argv-password", false, "accept -password despite the exposure in the process list")
+	every := fs.Duration("every", 0, "service mode: run the job file again at this interval until interrupted")
+	metricsAddr := fs.String("metrics", "", "serve Prometheus metrics on this loopback address, e.g. "+metrics.DefaultAddr)
+	// Allow the job file before or after the flags
+	var file string
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		file, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if file == "" && fs.NArg() > 0 {
+		file = fs.Arg(0)
+	}
+	if file == "" {
+		usage()
+		return exitUsage
+	}
+
+	load := func() (*batch.JobFile, error) {
+		jf, err := batch.Load(file)
+		if err != nil {
+			return nil, err
+		}
+		if *parallel > 0 {
+			jf.Parallel = *parallel
+		}
+		if *reportPath != "" {
+			jf.Report = *reportPath
+		}
+		return jf, nil
+	}
+	jf, err := load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitUsage
+	}
+	password, err := resolvePassword(jf, *passwordFile, *argvPassword, *allowArgv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitUsage
+	}
+	defer secret.Wipe(password)
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "warning: config:", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var obs batch.Observer
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		obs = batch.NewMetrics(reg)
+		srv, err := metrics.Serve(*metricsAddr, reg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return exitUsage
+		}
+		defer srv.Close()
+		fmt.Fprintf(os.Stderr, "metrics at http://%s/metrics\n", *metricsAddr)
+	}
+	if *every <= 0 {
+		return runOnce(ctx, file, jf, password, cfg, obs)
+	}
+
+	// Service mode: the job file and settings are reloaded before every run so edits
+	// apply without a restart; a broken file keeps the previous version
+	for {
+		code := runOnce(ctx, file, jf, password, cfg, obs)
+		if ctx.Err() != nil {
+			return code
+		}
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-time.After(*every):
+		}
+		if next, err := load(); err != nil {
+			fmt.Fprintln(os.Stderr, "warning: keeping the previous job file:", err)
+		} else {
+			jf = next
+		}
+		if next, err := config.Load(); err == nil {
+			cfg = next
+		}
+	}
+}
+
+// runOnce runs the job file once, reports and notifies, and returns the exit code
+func runOnce(ctx context.Context, file string, jf *batch.JobFile, password []byte, cfg *config.Config, obs batch.Observer) int {
+	rep := batch.RunObserved(ctx, jf, password, cfg, obs)
+
+	var lines []string
+	for _, j := range rep.Jobs {
+		line := fmt.Sprintf("[%s] %s", j.Status, j.Name)
+		if j.Error != "" {
+			line += fmt.Sprintf(": %s (%s)", j.Error, j.Code)
+		}
+		fmt.Fprintln(os.Stderr, line)
+		lines = append(lines, line)
+	}
+	if t := rep.Transaction; t != nil && !t.Committed {
+		for _, b := range t.BlockedBy {
+			line := "rolled back all outputs: " + b
+			fmt.Fprintln(os.Stderr, line)
+			lines = append(lines, line)
+		}
+	}
+	msg := notify.Message{
+		Title:  fmt.Sprintf("HadesCrypt %s: %d succeeded, %d failed, %d skipped", filepath.Base(file), rep.Succeeded, rep.Failed, rep.Skipped),
+		Body:   strings.Join(lines, "\n"),
+		Failed: !rep.OK(),
+	}
+	if err := notify.Notify(context.Background(), cfg.Notifications, msg); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: notification:", err)
+	}
+	if err := recordRun(file, rep, lines); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: job journal:", err)
+	}
+	if err := rep.Write(jf.Report); err != nil {
+		fmt.Fprintln(os.Stderr, "error: write report:", err)
+		return apperr.ExitCode(err)
+	}
+	if rep.Code == apperr.OK && !rep.OK() {
+		return exitFailed
+	}
+	return rep.Code.ExitCode()
+}
+
+// recordRun adds the run to the job journal shown in the app's History view
+func recordRun(file string, rep *batch.Report, lines []string) error {
+	st, err := store.OpenDefault()
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		abs = file
+	}
+	return st.AddJobRun(store.JobRun{
+		File:      abs,
+		Started:   rep.Started.Unix(),
+		Finished:  rep.Finished.Unix(),
+		Succeeded: rep.Succeeded,
+		Failed:    rep.Failed,
+		Skipped:   rep.Skipped,
+		Lines:     lines,
+	})
+}
+
+func repairCmd(args []string) int {
+	fs := flag.NewFlagSet("repair", flag.ContinueOnError)
+	output := fs.String("o", "", "write the repaired container here (default: name.repaired.ext next to the input)")
+	passwordFile := fs.String("password-file", "", "read the password from the first line of this file")
+	var file string
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		file, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if file == "" && fs.NArg() > 0 {
+		file = fs.Arg(0)
+	}
+	if file == "" {
+		usage()
+		return exitUsage
+	}
+	out := *output
+	if out == "" {
+		out = format.RepairedPathFor(file)
+	}
+
+	password, err := filePassword(*passwordFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitUsage
+	}
+	defer secret.Wipe(password)
+
+	res, err := cryptoengine.Repair(file, out, password, nil)
+	if errors.Is(err, cryptoengine.ErrIntact) {
+		fmt.Fprintln(os.Stderr, file+":", err)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", secret.ScrubError(err, password))
+		return apperr.ExitCode(err)
+	}
+	fmt.Printf("repaired: %s\n", out)
+	fmt.Printf("kept:     %d of %d chunks, %d bytes\n", res.Chunks, res.TotalChunks, res.Recovered)
+	fmt.Printf("lost:     %d bytes (%s)\n", res.Lost, res.Cause)
+	return 0
+}
+
+func convertCmd(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	modeName := fs.String("mode", cryptoengine.GetEncryptionModeName(cryptoengine.ModeAES256GCM), "encryption mode to convert to, e.g. ChaCha20-Poly1305")
+	kdf := fs.String("kdf", "Balanced", "Argon2id preset: "+strings.Join(cryptoengine.Argon2PresetNames, ", "))
+	passwordFile := fs.String("password-file", "", "read the password from the first line of this file")
+	pqKeyFile := fs.String("pqkey", "", "post-quantum key file to convert to, and to open Kyber768 and Hybrid PQ containers with")
+	// Allow the paths before or after the flags
+	var paths []string
+	for len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		paths, args = append(paths, args[0]), args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	paths = append(paths, fs.Args()...)
+	if len(paths) == 0 {
+		usage()
+		return exitUsage
+	}
+	mode, ok := cryptoengine.ModeByName(*modeName)
+	if !ok || mode == cryptoengine.ModeGnuPG {
+		fmt.Fprintf(os.Stderr, "error: cannot convert to mode %q\n", *modeName)
+		return exitUsage
+	}
+	if !slices.Contains(cryptoengine.Argon2PresetNames, *kdf) {
+		fmt.Fprintf(os.Stderr, "error: unknown -kdf preset %q\n", *kdf)
+		return exitUsage
+	}
+	pqKey, err := loadPQKey(*pqKeyFile, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+	if cryptoengine.NeedsKEMKey(mode) && pqKey == nil {
+		fmt.Fprintf(os.Stderr, "error: %s encrypts to a post-quantum key; pass -pqkey\n", *modeName)
+		return exitUsage
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "warning: config:", err)
+	}
+	opts := cryptoengine.EncryptionOptions{Mode: mode, Compliance: cfg.ComplianceMode, Argon2: cryptoengine.Argon2Preset(*kdf)}
+	if cryptoengine.NeedsKEMKey(mode) {
+		opts.KEMKey = pqKey
+	}
+	pol, err := policy.Load()
+	if err == nil {
+		err = pol.CheckEncrypt(opts)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return apperr.ExitCode(err)
+	}
+
+	password, err := filePassword(*passwordFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return exitUsage
+	}
+	defer secret.Wipe(password)
+
+	var converted, skipped, failed int
+	var firstErr error
+	for _, f := range audit.Scan(paths, password) {
+		switch {
+		case f.Header == nil:
+			fmt.Fprintf(os.Stderr, "failed:    %s: %s\n", f.Path, strings.Join(f.Issues, "; "))
+		case f.Header.Matches(opts):
+			skipped++
+			fmt.Printf("current:   %s\n", f.Path)
+			continue
+		default:
+			err = audit.Reencrypt(f.Path, password, opts, nil)
+			if err == nil {
+				converted++
+				fmt.Printf("converted: %s\n", f.Path)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "failed:    %s: %v\n", f.Path, secret.ScrubError(err, password))
+		}
+		failed++
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	fmt.Printf("%d converted, %d already current, %d failed\n", converted, skipped, failed)
+	switch {
+	case failed == 0:
+		return 0
+	case converted > 0:
+		return apperr.PartialSuccess.ExitCode()
+	case firstErr != nil:
+		return apperr.ExitCode(firstErr)
+	}
+	return exitFailed
+}
+
+// filePassword reads the password for repair and convert from path,
+// $HADESCRYPT_PASSWORD_FILE or a no-echo prompt, in that order
+func filePassword(path string) ([]byte, error) {
+	if path != "" {
+		return secret.FromFile(path)
+	}
+	if env := os.Getenv(secret.EnvPasswordFile); env != "" {
+		return secret.FromFile(env)
+	}
+	return secret.Prompt("Password: ", false)
+}
+
+// newPassword reads the password for encrypt like filePassword, but a prompt
+// asks twice so a typo cannot lock the output away
+func newPassword(path string) ([]byte, error) {
+	if path == "" && os.Getenv(secret.EnvPasswordFile) == "" {
+		return secret.Prompt("Password: ", true)
+	}
+	return filePassword(path)
+}
+
+// resolvePassword picks the password source for a run; see usage for the order
+func resolvePassword(jf *batch.JobFile, file, argv string, allowArgv bool) ([]byte, error) {
+	if argv != "" {
+		if !allowArgv && os.Getenv(secret.EnvAllowArgvPassword) != "1" {
+			return nil, errors.New("refusing -password: command-line arguments are visible to other users; use -password-file, $" + secret.EnvPasswordFile + " or the prompt, or pass -allow-argv-password")
+		}
+		fmt.Fprintln(os.Stderr, "warning: password passed on the command line")
+		return []byte(argv), nil
+	}
+	if file != "" {
+		return secret.FromFile(file)
+	}
+	if env := os.Getenv(secret.EnvPasswordFile); env != "" {
+		return secret.FromFile(env)
+	}
+	pw, err := jf.Password()
+	if !errors.Is(err, batch.ErrNoPassword) {
+		return pw, err
+	}
+	pw, err = secret.Prompt("Password: ", true)
+	if errors.Is(err, secret.ErrNoTerminal) {
+		return nil, fmt.Errorf("no password source: set password_file in the job file, -password-file or $%s", secret.EnvPasswordFile)
+	}
+	return pw, err
+}
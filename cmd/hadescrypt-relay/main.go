@@ -0,0 +1,34 @@
+// Command hadescrypt-relay is the rendezvous server for code-phrase transfers.
+// It pairs a sender and a receiver by the number in their code and copies
+// bytes between them; the files stay end-to-end encrypted.
+//
+//	hadescrypt-relay [-listen :4747] [-wait 10m] [-per-addr 4] [-quiet]
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+
+	"github.com/bangundwir/HadesCrypt/internal/wormhole"
+)
+
+func main() {
+	listen := flag.String("listen", ":"+wormhole.DefaultRelayPort, "address to listen on")
+	wait := flag.Duration("wait", wormhole.DefaultWaitTimeout, "how long a sender may wait for its receiver")
+	perAddr := flag.Int("per-addr", wormhole.DefaultMaxPerAddr, "how many senders from one IP address may wait at once")
+	quiet := flag.Bool("quiet", false, "do not log connections")
+	flag.Parse()
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv := &wormhole.Server{WaitTimeout: *wait, MaxPerAddr: *perAddr}
+	if !*quiet {
+		srv.Logger = log.New(os.Stderr, "relay: ", log.LstdFlags)
+	}
+	log.Printf("hadescrypt-relay listening on %s", ln.Addr())
+	log.Fatal(srv.Serve(ln))
+}
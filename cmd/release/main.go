@@ -0,0 +1,257 @@
+// Command release builds the HadesCrypt release artifacts.
+//
+//	go run ./cmd/release [-version 2.1.0] [-targets windows/amd64,linux/amd64] [-gui host|all|none] [-sign minisign.key] [-out dist/release]
+//
+// Every target gets a portable zip with the app, hadescrypt-cli and
+// hadescrypt-relay plus the README and changelog. SHA256SUMS lists the zips
+// and, with -sign, every zip and the list get a minisign signature.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/minisign"
+	"github.com/bangundwir/HadesCrypt/internal/release"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+)
+
+// program is one binary in a release zip
+type program struct {
+	name, pkg string
+	gui       bool // the Fyne app: needs cgo, so a C compiler for the target
+}
+
+var programs = []program{
+	{"HadesCrypt", ".", true},
+	{"hadescrypt-cli", "./cmd/hadescrypt-cli", false},
+	{"hadescrypt-relay", "./cmd/hadescrypt-relay", false},
+}
+
+// docs are copied into every zip when present
+var docs = []string{"README.md", "CHANGELOG.md", "LICENSE"}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  go run ./cmd/release [-version X.Y.Z] [-targets os/arch,...] [-gui host|all|none]
+                       [-sign minisign.key] [-key-password-file path] [-out dir] [-test]
+
+Run from the repository root. The version defaults to the VERSION file and is
+embedded with -X main.version. Builds use -trimpath, no VCS stamping and an
+empty build ID; zip entries carry the source date ($SOURCE_DATE_EPOCH, else
+the last commit time) and fixed permissions, so the same tree and Go version
+give byte-identical artifacts.
+
+The GUI needs cgo. -gui host (the default) builds it only for the platform
+running the builder; -gui all builds it for every target and expects a C
+cross-compiler in $CC_<os>_<arch> (e.g. CC_windows_amd64=x86_64-w64-mingw32-gcc)
+or $CC; -gui none ships the command-line tools only.
+
+-test runs the test suite with the race detector first.
+
+-sign takes a minisign secret key (minisign -G). An encrypted key's password
+comes from -key-password-file or a no-echo prompt. Check a download with:
+  minisign -Vm HadesCrypt-X.Y.Z-windows-amd64.zip -P <public key>
+  sha256sum -c --ignore-missing SHA256SUMS`)
+}
+
+func main() {
+	fs := flag.NewFlagSet("release", flag.ExitOnError)
+	fs.Usage = usage
+	version := fs.String("version", "", "version to embed (default: VERSION file)")
+	targetList := fs.String("targets", "", "comma-separated os/arch list (default: all release platforms)")
+	gui := fs.String("gui", "host", "build the GUI for: host, all or none")
+	keyPath := fs.String("sign", "", "minisign secret key to sign the artifacts with")
+	keyPasswordFile := fs.String("key-password-file", "", "file holding the secret key's password")
+	out := fs.String("out", filepath.Join("dist", "release"), "output directory")
+	runTests := fs.Bool("test", false, "run go test -race ./... before building")
+	fs.Parse(os.Args[1:])
+	if fs.NArg() > 0 || !slices.Contains([]string{"host", "all", "none"}, *gui) {
+		usage()
+		os.Exit(2)
+	}
+	if err := run(*version, *targetList, *gui, *keyPath, *keyPasswordFile, *out, *runTests); err != nil {
+		fmt.Fprintln(os.Stderr, "release:", err)
+		os.Exit(1)
+	}
+}
+
+func run(version, targetList, gui, keyPath, keyPasswordFile, out string, runTests bool) error {
+	if version == "" {
+		data, err := os.ReadFile("VERSION")
+		if err != nil {
+			return fmt.Errorf("no -version and no VERSION file: %w", err)
+		}
+		version = strings.TrimSpace(string(data))
+	}
+	targets := release.DefaultTargets
+	if targetList != "" {
+		var err error
+		if targets, err = release.ParseTargets(targetList); err != nil {
+			return err
+		}
+	}
+	var key *minisign.PrivateKey
+	if keyPath != "" {
+		k, err := loadKey(keyPath, keyPasswordFile)
+		if err != nil {
+			return err
+		}
+		key = &k
+	}
+	epoch, err := sourceDate()
+	if err != nil {
+		return err
+	}
+	if runTests {
+		if err := goCmd([]string{"CGO_ENABLED=1"}, "test", "-race", "./...").Run(); err != nil {
+			return fmt.Errorf("tests: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+	work, err := os.MkdirTemp("", "hadescrypt-release-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+
+	var artifacts []string
+	for _, t := range targets {
+		name, err := buildTarget(t, version, gui, work, out, epoch)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t, err)
+		}
+		artifacts = append(artifacts, name)
+		fmt.Println("built", name)
+	}
+	sums, err := release.WriteChecksums(out, artifacts)
+	if err != nil {
+		return err
+	}
+	fmt.Println("wrote", sums)
+	if key == nil {
+		fmt.Println("not signed: pass -sign with a minisign secret key to sign the artifacts")
+		return nil
+	}
+	for _, name := range append(artifacts, release.ChecksumsFile) {
+		if err := signFile(*key, filepath.Join(out, name), epoch); err != nil {
+			return err
+		}
+	}
+	fmt.Println("signed with minisign key", key.Public())
+	return nil
+}
+
+// buildTarget compiles the programs for t and packs them into the target's zip in out
+func buildTarget(t release.Target, version, gui, work, out string, epoch time.Time) (string, error) {
+	name := release.ArtifactName(version, t)
+	dir := strings.TrimSuffix(name, ".zip")
+	host := t.OS == runtime.GOOS && t.Arch == runtime.GOARCH
+	var files []release.File
+	for _, p := range programs {
+		if p.gui && (gui == "none" || gui == "host" && !host) {
+			fmt.Printf("%s: skipping the GUI (built only with -gui all or on %s itself)\n", t, t)
+			continue
+		}
+		bin := filepath.Join(work, t.OS+"-"+t.Arch, t.Exe(p.name))
+		ldflags := "-s -w -buildid= -X main.version=" + version
+		if p.gui && t.OS == "windows" {
+			ldflags += " -H windowsgui"
+		}
+		env := []string{"GOOS=" + t.OS, "GOARCH=" + t.Arch, "CGO_ENABLED=0"}
+		if p.gui {
+			env[2] = "CGO_ENABLED=1"
+			if cc := os.Getenv("CC_" + t.OS + "_" + t.Arch); cc != "" {
+				env = append(env, "CC="+cc)
+			}
+		}
+		cmd := goCmd(env, "build", "-trimpath", "-buildvcs=false", "-ldflags", ldflags, "-o", bin, p.pkg)
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("build %s: %w", p.name, err)
+		}
+		files = append(files, release.File{Name: dir + "/" + t.Exe(p.name), Source: bin, Exec: true})
+	}
+	for _, d := range docs {
+		if _, err := os.Stat(d); err == nil {
+			files = append(files, release.File{Name: dir + "/" + d, Source: d})
+		}
+	}
+	return name, release.WriteZip(filepath.Join(out, name), files, epoch)
+}
+
+// goCmd runs the go tool with env added, its output going to ours
+func goCmd(env []string, args ...string) *exec.Cmd {
+	cmd := exec.Command("go", args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd
+}
+
+// sourceDate is the time stamped into the zips and signatures: $SOURCE_DATE_EPOCH,
+// else the last commit, else the zip format's earliest date
+func sourceDate() (time.Time, error) {
+	s := os.Getenv("SOURCE_DATE_EPOCH")
+	if s == "" {
+		if out, err := exec.Command("git", "log", "-1", "--format=%ct").Output(); err == nil {
+			s = strings.TrimSpace(string(out))
+		}
+	}
+	if s == "" {
+		return time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("source date %q: %w", s, err)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// loadKey reads the minisign secret key, asking for its password only when it is encrypted
+func loadKey(path, passwordFile string) (minisign.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return minisign.PrivateKey{}, err
+	}
+	k, err := minisign.ParsePrivateKey(data, nil)
+	if !errors.Is(err, minisign.ErrPasswordRequired) {
+		return k, err
+	}
+	var pw []byte
+	if passwordFile != "" {
+		pw, err = secret.FromFile(passwordFile)
+	} else {
+		pw, err = secret.Prompt("Password for "+filepath.Base(path)+": ", false)
+	}
+	if err != nil {
+		return minisign.PrivateKey{}, err
+	}
+	defer secret.Wipe(pw)
+	return minisign.ParsePrivateKey(data, pw)
+}
+
+// signFile writes path.minisig; the trusted comment matches minisign's own
+func signFile(k minisign.PrivateKey, path string, epoch time.Time) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	name := filepath.Base(path)
+	sig, err := k.Sign(f, "signature from HadesCrypt release key", fmt.Sprintf("timestamp:%d\tfile:%s\thashed", epoch.Unix(), name))
+	if err != nil {
+		return fmt.Errorf("sign %s: %w", name, err)
+	}
+	return os.WriteFile(path+".minisig", sig, 0o644)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/compare"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// compareReportFiles caps how many differing files the report lists
+const compareReportFiles = 50
+
+// restoredGuess is the decrypted output of the selected container, if it exists
+func (s *AppState) restoredGuess() string {
+	if s.selectedPath == "" || !format.IsEncryptedArtifact(s.selectedPath) { return "" }
+	out := s.defaultOutputPathForDecrypt(s.selectedPath)
+	if _, err := os.Stat(out); err != nil { return "" }
+	return out
+}
+
+// showCompareDialog compares a decrypted output with a reference copy, or with the SHA-256 of the original
+func (s *AppState) showCompareDialog(w fyne.Window) {
+	restored := widget.NewEntry()
+	restored.SetPlaceHolder("Decrypted file or folder")
+	restored.SetText(s.restoredGuess())
+	reference := widget.NewEntry()
+	reference.SetPlaceHolder("Original file or folder, or its SHA-256")
+	browse := func(e *widget.Entry) fyne.CanvasObject {
+		file := widget.NewButton("File…", func() {
+			dialog.ShowFileOpen(func(rc fyne.URIReadCloser, err error) {
+				if err != nil || rc == nil { return }
+				e.SetText(rc.URI().Path()); rc.Close()
+			}, w)
+		})
+		folder := widget.NewButton("Folder…", func() {
+			dialog.ShowFolderOpen(func(u fyne.ListableURI, err error) {
+				if err != nil || u == nil { return }
+				e.SetText(u.Path())
+			}, w)
+		})
+		return container.NewBorder(nil, nil, nil, container.NewHBox(file, folder), e)
+	}
+	info := widget.NewLabel("Checks a restore byte by byte against the original and lists the offsets that differ. Paste a SHA-256 instead of a path when only the original's hash is known.")
+	info.Wrapping = fyne.TextWrapWord
+
+	var d dialog.Dialog
+	run := widget.NewButton("🟰 Compare", func() {
+		a, b := strings.TrimSpace(restored.Text), strings.TrimSpace(reference.Text)
+		if a == "" || b == "" { dialog.ShowInformation("Compare", "Choose the decrypted output and a reference.", w); return }
+		d.Hide()
+		s.runCompare(w, a, b)
+	})
+	run.Importance = widget.HighImportance
+	form := widget.NewForm(widget.NewFormItem("Restored", browse(restored)), widget.NewFormItem("Reference", browse(reference)))
+	d = dialog.NewCustom("Compare", "Close", container.NewVBox(info, form, run), w)
+	d.Resize(fyne.NewSize(620, 300))
+	d.Show()
+}
+
+// runCompare compares in the background; the main Cancel button stops it
+func (s *AppState) runCompare(w fyne.Window, restored, reference string) {
+	s.cancelRequested.Store(false)
+	s.statusLabel.SetText("🟰 Comparing…")
+	s.setProgressFraction(0)
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		res, err := compare.Compare(ctx, restored, reference, func(done, total int64) {
+			if s.cancelRequested.Load() { cancel(); return }
+			s.reportProgress(done, total)
+		})
+		s.ui(func() {
+			switch {
+			case errors.Is(err, context.Canceled):
+				s.statusLabel.SetText("⏹️ Canceled")
+			case err != nil:
+				s.statusLabel.SetText("❌ " + err.Error())
+				dialog.ShowError(err, w)
+			default:
+				s.setProgressFraction(1)
+				s.showCompareReport(w, res)
+			}
+		})
+	}()
+}
+
+// showCompareReport lists the differing files with their differing byte ranges
+func (s *AppState) showCompareReport(w fyne.Window, res *compare.Result) {
+	var sb strings.Builder
+	diffs := res.Differences()
+	if len(diffs) == 0 {
+		s.statusLabel.SetText("✅ Identical")
+		if len(res.Files) == 1 {
+			f := res.Files[0]
+			fmt.Fprintf(&sb, "✅ Identical: %s\nSHA-256 %s\n", uiutil.HumanBytes(f.RestoredSize), f.RestoredSHA256)
+		} else {
+			fmt.Fprintf(&sb, "✅ All %d file(s) are identical (%s compared)\n", len(res.Files), uiutil.HumanBytes(res.Bytes))
+		}
+	} else {
+		s.statusLabel.SetText(fmt.Sprintf("⚠️ %d of %d file(s) differ", len(diffs), len(res.Files)))
+		fmt.Fprintf(&sb, "⚠️ %d of %d file(s) differ\n", len(diffs), len(res.Files))
+		for i, f := range diffs {
+			if i == compareReportFiles { fmt.Fprintf(&sb, "\n… and %d more\n", len(diffs)-i); break }
+			sb.WriteString("\n")
+			if f.Name != "" { sb.WriteString(f.Name + "\n") }
+			writeFileDifference(&sb, f)
+		}
+	}
+	lbl := widget.NewLabel(sb.String())
+	lbl.Wrapping = fyne.TextWrapWord
+	lbl.TextStyle = fyne.TextStyle{Monospace: true}
+	d := dialog.NewCustom("Comparison", "Close", container.NewScroll(lbl), w)
+	d.Resize(fyne.NewSize(620, 420))
+	d.Show()
+}
+
+// writeFileDifference describes how one file differs from its reference
+func writeFileDifference(sb *strings.Builder, f compare.FileResult) {
+	switch {
+	case f.MissingRestored:
+		fmt.Fprintf(sb, "  missing from the restore (%s)\n", uiutil.HumanBytes(f.ReferenceSize))
+		return
+	case f.MissingReference:
+		fmt.Fprintf(sb, "  not in the reference (%s)\n", uiutil.HumanBytes(f.RestoredSize))
+		return
+	case f.DigestOnly:
+		fmt.Fprintf(sb, "  SHA-256 %s\n  expected %s\n", f.RestoredSHA256, f.ReferenceSHA256)
+		return
+	}
+	if f.RestoredSize != f.ReferenceSize {
+		fmt.Fprintf(sb, "  size %d bytes, reference %d bytes\n", f.RestoredSize, f.ReferenceSize)
+	}
+	if f.DifferentBytes > 0 {
+		fmt.Fprintf(sb, "  %d byte(s) differ within the first %d:\n", f.DifferentBytes, min(f.RestoredSize, f.ReferenceSize))
+	}
+	for _, r := range f.Ranges {
+		fmt.Fprintf(sb, "    offset 0x%08x (%d), %d byte(s)\n", r.Offset, r.Offset, r.Length)
+	}
+	if f.MoreRanges { fmt.Fprintf(sb, "    … more ranges after the first %d\n", compare.MaxRanges) }
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+	"github.com/bangundwir/HadesCrypt/internal/units"
+)
+
+// startWithConfigCheck opens the main window with cfg, first asking whether to
+// restore the last good backup when loading config.json failed with loadErr
+func startWithConfigCheck(application fyne.App, cfg *config.Config, loadErr error) {
+	backup, _, err := config.LastGoodBackup()
+	if !config.IsDamaged(loadErr) || err != nil || backup == "" {
+		openMain(application, cfg)
+		return
+	}
+	msg := "Your settings file is damaged and could not be read, so default settings were loaded.\n\n"
+	keep := "Use defaults"
+	if errors.Is(loadErr, config.ErrChecksum) {
+		msg = "Your settings file does not match its checksum: it was edited outside HadesCrypt or is damaged.\n\n"
+		keep = "Keep current file"
+	}
+	msg += "Restore the last good backup (" + filepath.Base(backup) + ")? The current file is kept as config.json.corrupt."
+
+	w := application.NewWindow("HadesCrypt — Settings recovery")
+	w.Resize(fyne.NewSize(520, 260))
+	d := dialog.NewConfirm("Settings recovery", msg, func(ok bool) {
+		var restoreErr error
+		if ok {
+			if restored, err := config.RestoreBackup(backup); err != nil { restoreErr = err } else { cfg = restored }
+		}
+		mw := openMain(application, cfg)
+		w.Close()
+		if restoreErr != nil { dialog.ShowError(restoreErr, mw) }
+	}, w)
+	d.SetConfirmText("Restore backup")
+	d.SetDismissText(keep)
+	d.Show()
+	w.Show()
+}
+
+// openMain opens the record store and shows the primary window for cfg
+func openMain(application fyne.App, cfg *config.Config) fyne.Window {
+	openRecords(cfg)
+	application.Settings().SetTheme(uiutil.NewTheme(cfg.Theme, cfg.UIScale))
+	units.SetDecimal(cfg.DecimalUnits)
+	w := newMainWindow(application, cfg, true)
+	w.SetMaster()
+	w.Show()
+	return w
+}
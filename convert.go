@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/audit"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+// showConvertDialog re-encrypts the selected containers, or every container in the
+// selected folders, with a new mode and key derivation without writing plaintext
+func (s *AppState) showConvertDialog(w fyne.Window) {
+	if s.denyInViewer(w) { return }
+	var paths []string
+	if s.selectedPath != "" { paths = []string{s.selectedPath} } else { paths = s.selectedPaths }
+	scanPassword := []byte(s.password)
+	if s.keyfileManager.HasKeyfiles() { scanPassword = s.keyfileManager.GetCombinedKey([]byte(s.password)) }
+	if len(scanPassword) == 0 { scanPassword = nil }
+	var found []audit.Finding
+	for _, f := range audit.Scan(paths, scanPassword) {
+		if f.Header != nil && f.Header.Mode != cryptoengine.ModeGnuPG { found = append(found, f) }
+	}
+	if len(found) == 0 {
+		dialog.ShowInformation("Convert", "Select HadesCrypt containers, or folders containing them. GnuPG files cannot be converted.", w)
+		return
+	}
+
+	modes := allModeOptions
+	if s.config.ComplianceMode { modes = complianceModeOptions }
+	modes = slices.DeleteFunc(s.allowedModeOptions(modes), func(opt string) bool {
+		m, _ := modeForOption(opt)
+		return m == cryptoengine.ModeGnuPG
+	})
+	if len(modes) == 0 { dialog.ShowInformation("Convert", "No encryption mode that can be converted to is allowed.", w); return }
+	modeSel := widget.NewSelect(modes, nil)
+	modeSel.SetSelected(modes[0])
+	if s.encryptionModeSelect != nil && slices.Contains(modes, s.encryptionModeSelect.Selected) { modeSel.SetSelected(s.encryptionModeSelect.Selected) }
+	kdfSel := widget.NewSelect(cryptoengine.Argon2PresetNames, nil)
+	kdfSel.SetSelected(s.opt().Argon2Preset)
+	s.restrictArgon2Select(kdfSel)
+	if s.config.ComplianceMode { kdfSel.Disable() }
+
+	options := func() cryptoengine.EncryptionOptions {
+		m, _ := modeForOption(modeSel.Selected)
+		return cryptoengine.EncryptionOptions{Mode: m, Compliance: s.config.ComplianceMode, Argon2: cryptoengine.Argon2Preset(kdfSel.Selected), KEMKey: s.pqKey}
+	}
+	pending := func() []audit.Finding {
+		opts := options()
+		return slices.DeleteFunc(slices.Clone(found), func(f audit.Finding) bool { return f.Header.Matches(opts) })
+	}
+	summary := widget.NewLabel("")
+	var d dialog.Dialog
+	run := widget.NewButton("", func() {
+		todo := pending()
+		if len(todo) == 0 { return }
+		d.Hide()
+		s.convertContainers(w, todo, options())
+	})
+	run.Importance = widget.HighImportance
+	refresh := func() {
+		n := len(pending())
+		summary.SetText(fmt.Sprintf("%d container(s) found, %d already use these settings", len(found), len(found)-n))
+		run.SetText(fmt.Sprintf("🔁 Convert %d file(s)", n))
+		if n == 0 { run.Disable() } else { run.Enable() }
+	}
+	modeSel.OnChanged = func(string) { refresh() }
+	kdfSel.OnChanged = func(string) { refresh() }
+	refresh()
+
+	info := widget.NewLabel("Each file is decrypted with the entered password and re-encrypted with the new settings in one streamed pass; no plaintext is written to disk. A file is only replaced once its new version is complete. The password, comment and other details stay the same.")
+	info.Wrapping = fyne.TextWrapWord
+	form := widget.NewForm(widget.NewFormItem("Encryption", modeSel), widget.NewFormItem("Key derivation", kdfSel))
+	d = dialog.NewCustom("Convert", "Close", container.NewVBox(info, form, summary, run), w)
+	d.Resize(fyne.NewSize(560, 340))
+	d.Show()
+}
+
+// convertContainers re-encrypts files in place with opts and the entered password
+func (s *AppState) convertContainers(w fyne.Window, files []audit.Finding, opts cryptoengine.EncryptionOptions) {
+	if s.denyInViewer(w) { return }
+	if s.password == "" && !s.keyfileManager.HasKeyfiles() {
+		dialog.ShowInformation("Password required", "Enter the password of the files to convert first.", w)
+		return
+	}
+	finalPassword := []byte(s.password)
+	if s.keyfileManager.HasKeyfiles() { finalPassword = s.keyfileManager.GetCombinedKey([]byte(s.password)) }
+	if err := s.checkPolicy(opts); err != nil { dialog.ShowError(err, w); return }
+	if cryptoengine.NeedsKEMKey(opts.Mode) && opts.KEMKey == nil { dialog.ShowInformation("Post-quantum key required", "Load or generate a post-quantum key in the PQ key row to convert to this mode.", w); return }
+
+	s.cancelRequested.Store(false)
+	go func() {
+		s.startOpSummary("convert")
+		for i, f := range files {
+			if s.cancelRequested.Load() { s.markCanceled(); break }
+			name := filepath.Base(f.Path)
+			s.setStatus(fmt.Sprintf("🔁 Converting %d/%d %s", i+1, len(files), name))
+			err := audit.Reencrypt(f.Path, finalPassword, opts, func(done, total int64) {
+				if total > 0 { s.ui(func() { s.setProgressFraction((float64(i) + float64(done)/float64(total)) / float64(len(files))) }) }
+			})
+			if err != nil { s.noteError(fmt.Errorf("%s: %w", name, err)); continue }
+			// Older files may predate the recovery policy; escrow without removing the converted file
+			if err := s.adminPolicy.Escrow(f.Path, finalPassword, s.keyfileManager.HasKeyfiles()); err != nil { s.noteError(fmt.Errorf("%s: %w", name, err)) }
+			s.addFile(f.Header.OriginalSize)
+		}
+		sum := s.finishSummary()
+		s.ui(func() {
+			s.setProgressFraction(1)
+			s.statusLabel.SetText("✅ Conversion finished")
+			s.updateFileInfo()
+			if sum != nil { s.showSummaryDialog(w, sum) }
+		})
+	}()
+}
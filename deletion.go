@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/operations"
+	"github.com/bangundwir/HadesCrypt/internal/safedelete"
+)
+
+// confirmDeletionPlan shows exactly which paths delete-after will remove and runs proceed
+// once the user agrees; large plans and whole folders need the confirmation word typed.
+// proceed runs with mediaAcknowledged set so the re-entered action does not ask again.
+func (s *AppState) confirmDeletionPlan(w fyne.Window, decrypt bool, proceed func()) {
+	run := func() { s.mediaAcknowledged = true; proceed(); s.mediaAcknowledged = false }
+	if !s.deleteAfter { run(); return }
+	go func() {
+		plan, err := s.ops().DeletionPlan(s.selection(), decrypt)
+		s.ui(func() {
+			s.statusLabel.SetText("Status: Ready")
+			if err != nil { dialog.ShowError(fmt.Errorf("list files to delete: %w", err), w); return }
+			if len(plan) == 0 { run(); return }
+			s.showDeletionPlan(w, plan, run)
+		})
+	}()
+}
+
+// removeEncryptedSource deletes src once out is verified to hold all of it; a failed check
+// is reported and the source kept
+func (s *AppState) removeEncryptedSource(src, out string, password []byte) error {
+	err := safedelete.AfterEncrypt(src, out, password)
+	if err != nil { s.noteError(err) }
+	return err
+}
+
+// removeDecryptedSource deletes an encrypted src once out is verified. Force Decrypt may
+// have written partial output, so its sources are always kept.
+func (s *AppState) removeDecryptedSource(src, out string) error {
+	var err error
+	if s.opt().ForceDecrypt { err = fmt.Errorf("%s: %w: Force Decrypt may have skipped damaged data", src, safedelete.ErrUnverified) } else { err = safedelete.AfterDecrypt(src, out) }
+	if err != nil { s.noteError(err) }
+	return err
+}
+
+// showDeletionPlan lists plan and runs run when the user confirms
+func (s *AppState) showDeletionPlan(w fyne.Window, plan []operations.DeletionTarget, run func()) {
+	folders := 0
+	lines := make([]string, len(plan))
+	for i, t := range plan {
+		lines[i] = "🗑 " + t.Path
+		if t.Folder { lines[i] = "📁 " + t.Path + "  (entire folder)"; folders++ }
+	}
+	summary := fmt.Sprintf("%d file(s)", len(plan)-folders)
+	if folders > 0 { summary += fmt.Sprintf(" and %d whole folder(s) with everything inside", folders) }
+	summary += " will be deleted:"
+	list := widget.NewLabel(strings.Join(lines, "\n"))
+	list.TextStyle = fyne.TextStyle{Monospace: true}
+	scroll := container.NewScroll(list)
+	scroll.SetMinSize(fyne.NewSize(560, 220))
+
+	typed := operations.NeedsTypedConfirmation(plan)
+	var d *dialog.CustomDialog
+	confirm := widget.NewButton("Continue and delete", func() { d.Hide(); run() })
+	confirm.Importance = widget.DangerImportance
+	content := container.NewVBox(widget.NewLabel(summary), scroll)
+	if typed {
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder("Type " + operations.ConfirmWord + " to confirm")
+		entry.OnChanged = func(v string) {
+			if v == operations.ConfirmWord { confirm.Enable() } else { confirm.Disable() }
+		}
+		confirm.Disable()
+		content.Add(entry)
+	}
+	cancel := widget.NewButton("Cancel", func() { d.Hide() })
+	d = dialog.NewCustomWithoutButtons("Delete source files?", content, w)
+	d.SetButtons([]fyne.CanvasObject{cancel, confirm})
+	d.Show()
+}
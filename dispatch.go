@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+)
+
+// Widgets belong to the Fyne event goroutine. Background work never touches them
+// directly: it hands changes to ui, or to the helpers below built on it. Button
+// handlers and other callbacks already run on the event goroutine and call widgets
+// as usual; they must not use uiWait.
+
+// ui queues fn on the event goroutine and returns at once. Changes for a window
+// that has been closed are dropped.
+func (s *AppState) ui(fn func()) {
+	fyne.Do(func() {
+		if s.closed.Load() { return }
+		fn()
+	})
+}
+
+// uiWait runs fn on the event goroutine and returns once it has finished, for
+// background work that needs what fn reads or decides
+func (s *AppState) uiWait(fn func()) {
+	fyne.DoAndWait(func() {
+		if s.closed.Load() { return }
+		fn()
+	})
+}
+
+// setStatus shows text on the status line from any goroutine
+func (s *AppState) setStatus(text string) { s.ui(func() { s.statusLabel.SetText(text) }) }
+
+// reportProgress moves the progress bar to done/total from any goroutine; an unknown total is ignored
+func (s *AppState) reportProgress(done, total int64) {
+	if total <= 0 { return }
+	if m := s.perfMeter.Load(); m != nil { m.Update(done, total) }
+	s.ui(func() { s.setProgressFraction(float64(done) / float64(total)) })
+}
+
+// showSummary shows the summary of a finished job from any goroutine
+func (s *AppState) showSummary(w fyne.Window, sum *OperationSummary) {
+	if sum == nil { return }
+	s.ui(func() { s.showSummaryDialog(w, sum) })
+}
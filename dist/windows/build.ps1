@@ -55,7 +55,16 @@ try {
     exit 1
 }
 
-Write-Host "Step 2: Building executable..." -ForegroundColor Blue
+Write-Host "Step 2: Running tests with the race detector..." -ForegroundColor Blue
+go test -race ./...
+if ($LASTEXITCODE -ne 0) {
+    Write-Host "Error: Tests failed" -ForegroundColor Red
+    Read-Host "Press Enter to exit"
+    exit 1
+}
+Write-Host "Tests passed" -ForegroundColor Green
+
+Write-Host "Step 3: Building executable..." -ForegroundColor Blue
 try {
     $buildFlags = "-s -w -H windowsgui -X main.version=$version"
     go build -ldflags $buildFlags -o "dist\windows\HadesCrypt.exe" .
@@ -76,7 +85,7 @@ try {
 }
 
 if ($rsrcAvailable) {
-    Write-Host "Step 3: Embedding Windows resources..." -ForegroundColor Blue
+    Write-Host "Step 4: Embedding Windows resources..." -ForegroundColor Blue
     Push-Location "dist\windows"
     try {
         if (Test-Path "icon.ico") {
@@ -101,7 +110,7 @@ if ($rsrcAvailable) {
         Pop-Location
     }
 } else {
-    Write-Host "Step 3: Skipping resource embedding (rsrc not found)" -ForegroundColor Yellow
+    Write-Host "Step 4: Skipping resource embedding (rsrc not found)" -ForegroundColor Yellow
     Write-Host "To embed resources, install: go install github.com/akavel/rsrc@latest" -ForegroundColor Cyan
 }
 
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/editsession"
+)
+
+// doOpenForEditing decrypts the selected container to a private temp copy, opens it in the
+// default application and re-encrypts every saved change until the user finishes editing.
+func (s *AppState) doOpenForEditing(w fyne.Window) {
+	if s.denyInViewer(w) { return }
+	if s.selectedPath == "" || !s.isHadesCryptFile(s.selectedPath) {
+		dialog.ShowInformation("Open for Editing", "Select a single HadesCrypt file to edit.", w)
+		return
+	}
+	if s.password == "" {
+		dialog.ShowInformation("Password required", "Please enter a password.", w)
+		return
+	}
+
+	path := s.selectedPath
+	name := filepath.Base(path)
+	finalPassword := []byte(s.password)
+	if s.keyfileManager.HasKeyfiles() { finalPassword = s.keyfileManager.GetCombinedKey([]byte(s.password)) }
+
+	s.statusLabel.SetText("✏️ Decrypting working copy…")
+	go func() {
+		sess, err := editsession.Start(path, finalPassword)
+		if err == nil { err = sess.Open() }
+		if err != nil {
+			if sess != nil { sess.Close() }
+			s.ui(func() {
+				s.statusLabel.SetText("❌ " + err.Error())
+				dialog.ShowError(err, w)
+			})
+			return
+		}
+		sess.Watch(time.Second, func(err error) {
+			s.ui(func() {
+				if err != nil {
+					s.statusLabel.SetText(fmt.Sprintf("❌ Re-encrypt %s failed: %v", name, err))
+					return
+				}
+				s.statusLabel.SetText(fmt.Sprintf("🔒 %s re-encrypted at %s", name, time.Now().Format("15:04:05")))
+			})
+		})
+
+		s.ui(func() {
+			s.editSessions = append(s.editSessions, sess)
+			s.statusLabel.SetText("✏️ Editing " + name)
+			msg := widget.NewLabel(fmt.Sprintf("%s is open in your default application.\n\nEvery save is re-encrypted automatically.\nClose the document, then press Finish to shred the temporary copy.", name))
+			var d dialog.Dialog
+			finishBtn := widget.NewButton("Finish & Shred", func() {
+				d.Hide()
+				s.finishEditSession(sess)
+			})
+			finishBtn.Importance = widget.HighImportance
+			d = dialog.NewCustomWithoutButtons("Editing "+name, container.NewVBox(msg, finishBtn), w)
+			d.Show()
+		})
+	}()
+}
+
+// finishEditSession runs the final re-encryption and shreds the working copy
+func (s *AppState) finishEditSession(sess *editsession.Session) {
+	for i, es := range s.editSessions {
+		if es == sess {
+			s.editSessions = append(s.editSessions[:i], s.editSessions[i+1:]...)
+			break
+		}
+	}
+	name := filepath.Base(sess.Container)
+	go func() {
+		err := sess.Close()
+		s.ui(func() {
+			if err != nil {
+				s.statusLabel.SetText(fmt.Sprintf("❌ Final re-encrypt of %s failed: %v", name, err))
+				return
+			}
+			s.statusLabel.SetText(fmt.Sprintf("✅ Finished editing %s • temp copy shredded", name))
+		})
+	}()
+}
+
+// closeEditSessions finalizes every open editing session (used on exit)
+func (s *AppState) closeEditSessions() {
+	for _, sess := range s.editSessions { sess.Close() }
+	s.editSessions = nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/elevate"
+)
+
+// elevatedFlag hands an operation that was denied access to an instance started
+// with administrator rights. It carries the selection and settings, never the
+// password: the elevated window asks for it again.
+const elevatedFlag = "--elevated="
+
+// elevatedRequest is the operation an elevated instance opens with
+type elevatedRequest struct {
+	Operation   string   `json:"operation"` // "encrypt" or "decrypt"
+	Paths       []string `json:"paths"`
+	Mode        string   `json:"mode,omitempty"`
+	DeleteAfter bool     `json:"delete_after,omitempty"`
+	Options     Options  `json:"options"`
+}
+
+// elevatedRequested is set by the --elevated= command-line switch
+var elevatedRequested *elevatedRequest
+
+// parseElevatedRequest finds and decodes the --elevated= switch; nil when absent or unreadable
+func parseElevatedRequest(args []string) *elevatedRequest {
+	for _, a := range args {
+		enc, ok := strings.CutPrefix(a, elevatedFlag)
+		if !ok { continue }
+		data, err := base64.RawURLEncoding.DecodeString(enc)
+		if err != nil { return nil }
+		var r elevatedRequest
+		if json.Unmarshal(data, &r) != nil || len(r.Paths) == 0 { return nil }
+		return &r
+	}
+	return nil
+}
+
+// canRetryElevated reports whether a finished job can be repeated with administrator rights
+func canRetryElevated(sum *OperationSummary) bool {
+	return sum.AccessDenied && (sum.Operation == "encrypt" || sum.Operation == "decrypt")
+}
+
+// relaunchElevated starts an elevated instance set up for op on the current selection
+func (s *AppState) relaunchElevated(w fyne.Window, op string) {
+	exe, err := os.Executable()
+	if err != nil { dialog.ShowError(err, w); return }
+	data, err := json.Marshal(elevatedRequest{Operation: op, Paths: s.selection(), Mode: cryptoengine.GetEncryptionModeName(s.encryptionMode), DeleteAfter: s.deleteAfter, Options: s.opt()})
+	if err != nil { dialog.ShowError(err, w); return }
+	args := []string{elevatedFlag + base64.RawURLEncoding.EncodeToString(data)}
+	if s.viewer { args = append(args, "--viewer") }
+	if err := elevate.Relaunch(exe, args); err != nil {
+		if !errors.Is(err, apperr.ErrCanceled) { dialog.ShowError(err, w) }
+		return
+	}
+	recordAudit("elevated-relaunch", op)
+	s.statusLabel.SetText("🛡 " + op + " continues in an administrator window")
+}
+
+// applyElevatedRequest restores the handed-over selection and settings; the user
+// re-enters the password and starts the operation
+func (s *AppState) applyElevatedRequest(r *elevatedRequest) {
+	s.options.set(r.Options)
+	if opt := modeOptionFor(r.Mode); opt != "" && r.Mode != "" { s.encryptionModeSelect.SetSelected(opt) }
+	s.deleteAfter = s.deleteAfter && r.DeleteAfter
+	if len(r.Paths) == 1 { s.setSelectedFile(r.Paths[0]) } else { s.setSelectedFiles(r.Paths) }
+	verb := "Encrypt"
+	if r.Operation == "decrypt" { verb = "Decrypt" }
+	s.statusLabel.SetText("🛡 Running as administrator: enter the password and press " + verb + " to retry")
+}
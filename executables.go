@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/filetype"
+)
+
+// flaggedShown caps the programs listed in a summary
+const flaggedShown = 10
+
+// flaggedOutput is a decrypted file that may run code when opened
+type flaggedOutput struct {
+	Path string
+	Info filetype.Info
+}
+
+// String is the summary line for the file
+func (f flaggedOutput) String() string {
+	line := filepath.Base(f.Path) + ": " + f.Info.Kind
+	if f.Info.Disguised { line += fmt.Sprintf(" named as %s", strings.ToLower(filepath.Ext(f.Path))) }
+	return line
+}
+
+// flagExecutables sniffs the files among outputs and returns the programs and
+// scripts; folders restored from archives are not searched
+func flagExecutables(outputs []string) []flaggedOutput {
+	var flagged []flaggedOutput
+	for _, p := range outputs {
+		if fi, err := os.Stat(p); err != nil || !fi.Mode().IsRegular() { continue }
+		if info, err := filetype.Detect(p); err == nil && info.Executable() { flagged = append(flagged, flaggedOutput{p, info}) }
+	}
+	return flagged
+}
+
+// withExecutableWarning adds the list of flagged results below content and, on
+// Windows, a button giving them the mark of the web
+func withExecutableWarning(w fyne.Window, content fyne.CanvasObject, flagged []flaggedOutput) fyne.CanvasObject {
+	if len(flagged) == 0 { return content }
+	lines := make([]string, 0, flaggedShown)
+	for _, f := range flagged[:min(len(flagged), flaggedShown)] { lines = append(lines, f.String()) }
+	text := "⚠️ Programs or scripts among the results — open them only if you trust where they came from:\n  " + strings.Join(lines, "\n  ")
+	if n := len(flagged); n > flaggedShown { text += fmt.Sprintf("\n  …and %d more", n-flaggedShown) }
+	warning := widget.NewLabel(text)
+	warning.Wrapping = fyne.TextWrapWord
+	box := container.NewVBox(content, widget.NewSeparator(), warning)
+	if !filetype.Supported() { return box }
+	var mark *widget.Button
+	mark = widget.NewButton("🛡 Mark as downloaded from the internet", func() {
+		var failed []string
+		for _, f := range flagged {
+			if err := filetype.Quarantine(f.Path); err != nil { failed = append(failed, fmt.Sprintf("%s: %v", filepath.Base(f.Path), err)) }
+		}
+		if len(failed) > 0 { dialog.ShowError(fmt.Errorf("could not mark %d file(s):\n%s", len(failed), strings.Join(failed, "\n")), w); return }
+		mark.SetText("🛡 Marked — Windows will warn before they run")
+		mark.Disable()
+	})
+	box.Add(mark)
+	return box
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/archiver"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+)
+
+// errNotFolderArchive is returned when a container does not hold an archived folder
+var errNotFolderArchive = errors.New("this container does not hold an archived folder")
+
+// Export formats offered for folder containers
+const (
+	exportTarGz = "tar.gz (as stored)"
+	exportZip   = "zip"
+)
+
+// showExportDialog writes the archive inside the selected folder container to a
+// file of the user's choice, without extracting it
+func (s *AppState) showExportDialog(w fyne.Window) {
+	path := s.selectedPath
+	if path == "" || !s.isHadesCryptFile(path) {
+		dialog.ShowInformation("Export Archive", "Select an encrypted folder (.hadescrypt).", w)
+		return
+	}
+	if hdr, err := cryptoengine.ReadHeaderFromFile(path); err != nil { dialog.ShowError(err, w); return } else if hdr.Mode == cryptoengine.ModeGnuPG {
+		dialog.ShowInformation("Export Archive", "GnuPG containers cannot be exported this way; decrypt them instead.", w)
+		return
+	}
+	if s.password == "" && !s.keyfileManager.HasKeyfiles() {
+		dialog.ShowInformation("Password required", "Enter the container's password first.", w)
+		return
+	}
+
+	formatRadio := widget.NewRadioGroup([]string{exportTarGz, exportZip}, nil)
+	formatRadio.SetSelected(exportTarGz)
+	info := widget.NewLabel("Writes the folder as one standard archive for other tools, instead of extracting every file. tar.gz is the archive exactly as stored; zip is converted on the fly. The exported archive is NOT encrypted.")
+	info.Wrapping = fyne.TextWrapWord
+	d := dialog.NewCustomConfirm("Export Archive", "Choose destination…", "Cancel", container.NewVBox(info, formatRadio), func(ok bool) {
+		if !ok { return }
+		asZip := formatRadio.Selected == exportZip
+		ext := ".tar.gz"
+		if asZip { ext = ".zip" }
+		save := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil || wc == nil { return }
+			out := wc.URI().Path()
+			wc.Close()
+			s.runExport(w, path, out, asZip)
+		}, w)
+		save.SetFileName(strings.TrimSuffix(filepath.Base(format.DecryptedPathFor(path)), ".tar.gz") + ext)
+		if dir, err := storage.ListerForURI(storage.NewFileURI(filepath.Dir(path))); err == nil { save.SetLocation(dir) }
+		save.Show()
+	}, w)
+	d.Resize(fyne.NewSize(520, 260))
+	d.Show()
+}
+
+// runExport exports src to out in the background
+func (s *AppState) runExport(w fyne.Window, src, out string, asZip bool) {
+	finalPassword := []byte(s.password)
+	if s.keyfileManager.HasKeyfiles() { finalPassword = s.keyfileManager.GetCombinedKey([]byte(s.password)) }
+	s.statusLabel.SetText("📦 Exporting " + filepath.Base(src) + "…")
+	s.setProgressFraction(0)
+	go func() {
+		err := exportArchive(src, out, finalPassword, asZip, func(done, total int64) {
+			s.reportProgress(done, total)
+		})
+		err = secret.ScrubError(err, finalPassword, []byte(s.password))
+		s.ui(func() {
+			if err != nil { s.statusLabel.SetText("❌ Export failed: " + err.Error()); dialog.ShowError(withPasswordHint(err, src), w); return }
+			s.setProgressFraction(1)
+			s.statusLabel.SetText("✅ Exported → " + filepath.Base(out))
+		})
+	}()
+}
+
+// exportArchive writes the tar.gz held by the folder container src to out, or
+// converts it to zip on the fly. It goes through a temp file next to out that
+// only replaces out once the whole container has authenticated.
+func exportArchive(src, out string, password []byte, asZip bool, onProgress cryptoengine.ProgressCallback) error {
+	tmp, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+".*.export")
+	if err != nil { return err }
+	defer os.Remove(tmp.Name())
+	if asZip {
+		open := func() (io.ReadCloser, error) {
+			pr, pw := io.Pipe()
+			go func() { pw.CloseWithError(cryptoengine.DecryptFileToWriter(src, pw, password, onProgress)) }()
+			return pr, nil
+		}
+		err = archiver.TarGzToZip(open, tmp)
+		if errors.Is(err, archiver.ErrNotTarGz) { err = errNotFolderArchive }
+	} else {
+		sniff := &gzipSniffer{w: tmp}
+		err = cryptoengine.DecryptFileToWriter(src, sniff, password, onProgress)
+		if err == nil && !sniff.checked { err = errNotFolderArchive }
+	}
+	if err == nil { err = tmp.Sync() }
+	if cerr := tmp.Close(); err == nil { err = cerr }
+	if err != nil { return err }
+	return os.Rename(tmp.Name(), out)
+}
+
+// gzipSniffer refuses plaintext that does not start like a gzip stream
+type gzipSniffer struct {
+	w       io.Writer
+	checked bool
+}
+
+func (g *gzipSniffer) Write(p []byte) (int, error) {
+	if !g.checked {
+		if len(p) < 2 || p[0] != 0x1f || p[1] != 0x8b { return 0, errNotFolderArchive }
+		g.checked = true
+	}
+	return g.w.Write(p)
+}
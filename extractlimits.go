@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/archiver"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// extractLimits returns the configured decompression bomb guard; crossing a bound
+// asks whether to go on. Called from job goroutines.
+func (s *AppState) extractLimits() archiver.Limits {
+	return archiver.Limits{
+		MaxBytes: int64(s.config.MaxExtractGB * 1e9),
+		MaxFiles: s.config.MaxExtractFiles,
+		Continue: s.askExtractPastLimit,
+	}
+}
+
+// askExtractPastLimit blocks the job until the user answers; a closed window stops the extraction
+func (s *AppState) askExtractPastLimit(e archiver.Exceeded) bool {
+	what := fmt.Sprintf("more than %s (next entry %q is %s)", uiutil.HumanBytes(e.Limit), e.Entry, uiutil.HumanBytes(e.EntrySize))
+	if e.Files { what = fmt.Sprintf("more than %d files", e.Limit) }
+	answer := make(chan bool, 1)
+	shown := false
+	s.uiWait(func() {
+		w := openWindows[s]
+		if w == nil { return }
+		shown = true
+		dialog.ShowConfirm("⚠️ Extraction limit reached",
+			"This archive expands to "+what+".\nIt may be a decompression bomb meant to fill the disk.\n\nContinue extracting?",
+			func(ok bool) { answer <- ok }, w)
+	})
+	if !shown { return false }
+	s.setStatus("⚠️ Extraction paused at the limit — waiting for an answer")
+	for {
+		select {
+		case ok := <-answer:
+			return ok
+		case <-time.After(time.Second):
+			if s.closed.Load() { return false }
+		}
+	}
+}
+
+// buildExtractLimitControls returns the Settings entries for the extraction size and file limits
+func (s *AppState) buildExtractLimitControls() (size, files *widget.Entry) {
+	size = widget.NewEntry()
+	size.SetPlaceHolder("0 = no limit")
+	if s.config.MaxExtractGB > 0 { size.SetText(strconv.FormatFloat(s.config.MaxExtractGB, 'f', -1, 64)) }
+	size.Validator = func(text string) error {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(text), 64); strings.TrimSpace(text) != "" && (err != nil || v < 0) { return fmt.Errorf("enter GB, e.g. 100") }
+		return nil
+	}
+	size.OnChanged = func(text string) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if strings.TrimSpace(text) == "" { v, err = 0, nil }
+		if err != nil || v < 0 || v == s.config.MaxExtractGB { return }
+		s.config.MaxExtractGB = v
+		s.config.Save()
+	}
+
+	files = widget.NewEntry()
+	files.SetPlaceHolder("0 = no limit")
+	if s.config.MaxExtractFiles > 0 { files.SetText(strconv.Itoa(s.config.MaxExtractFiles)) }
+	files.Validator = func(text string) error {
+		if v, err := strconv.Atoi(strings.TrimSpace(text)); strings.TrimSpace(text) != "" && (err != nil || v < 0) { return fmt.Errorf("enter a file count, e.g. 1000000") }
+		return nil
+	}
+	files.OnChanged = func(text string) {
+		v, err := strconv.Atoi(strings.TrimSpace(text))
+		if strings.TrimSpace(text) == "" { v, err = 0, nil }
+		if err != nil || v < 0 || v == s.config.MaxExtractFiles { return }
+		s.config.MaxExtractFiles = v
+		s.config.Save()
+	}
+	return size, files
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/pgppacket"
+	"github.com/bangundwir/HadesCrypt/internal/timestamp"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// beginFileInfo starts loading the details of a new selection and returns its
+// generation; results carrying an older one are dropped
+func (s *AppState) beginFileInfo() uint64 {
+	s.fileInfoGen++
+	s.setSizeBasis(nil)
+	s.fileInfoActivity.Show()
+	s.fileInfoActivity.Start()
+	return s.fileInfoGen
+}
+
+// endFileInfo stops the spinner once the details of generation gen are complete
+func (s *AppState) endFileInfo(gen uint64) {
+	if gen != s.fileInfoGen { return }
+	s.fileInfoActivity.Stop()
+	s.fileInfoActivity.Hide()
+}
+
+// showFileInfo runs fn on the UI goroutine if gen is still the current selection;
+// done ends the loading
+func (s *AppState) showFileInfo(gen uint64, done bool, fn func()) {
+	s.ui(func() {
+		if gen != s.fileInfoGen { return }
+		fn()
+		if done { s.endFileInfo(gen) }
+	})
+}
+
+// loadSelectionSummary counts the files and folders of a multi selection in the background
+func (s *AppState) loadSelectionSummary(gen uint64, paths []string) {
+	go func() {
+		var totalSize int64
+		var sizes []int64
+		files, folders := 0, 0
+		for _, p := range paths {
+			if fi, err := os.Stat(p); err == nil {
+				if fi.IsDir() { folders++ } else if fi.Mode().IsRegular() { files++; totalSize += fi.Size(); sizes = append(sizes, fi.Size()) }
+			}
+		}
+		icon := "📄"
+		if folders > 0 { icon = "📂" }
+		// folders of a mixed selection are not counted, so only plain file selections get a preview
+		var basis *sizeBasis
+		if folders == 0 && files > 0 { basis = &sizeBasis{files: sizes, ratio: sampleRatio(paths)} }
+		s.showFileInfo(gen, true, func() {
+			s.dragDropLabel.SetText(fmt.Sprintf("%s %d item(s) (%d file(s), %d folder(s))", icon, len(paths), files, folders))
+			s.fileInfoLabel.SetText(fmt.Sprintf("Regular file bytes (pre-archive): %s", uiutil.HumanBytes(totalSize)))
+			s.setSizeBasis(basis)
+		})
+	}()
+}
+
+// loadFileDetails reads what the selected file or folder shows off the event
+// goroutine: the size first, then a folder estimate, or the header of an
+// encrypted file and its comments. Headers are cached by path, size and mtime
+// (see cryptoengine.GetFileInfo), so going back to a file is instant; Deniability
+// Mode output is only described when password unwraps it, which is not cached.
+func (s *AppState) loadFileDetails(gen uint64, path, modeText string, password []byte) {
+	ops := s.ops()
+	name := filepath.Base(path)
+	go func() {
+		info, err := os.Stat(path)
+		if err != nil {
+			s.showFileInfo(gen, true, func() {
+				s.dragDropLabel.SetText("[ Drag & Drop your files here ]")
+				s.fileInfoLabel.SetText("Error: " + err.Error())
+				s.commentsEntry.SetText("")
+			})
+			return
+		}
+		if info.IsDir() {
+			// Don't clear comments for directories, user might want to add them
+			s.showFileInfo(gen, false, func() {
+				s.dragDropLabel.SetText("📁 " + name)
+				s.fileInfoLabel.SetText("Folder: estimating size… | Mode: " + modeText)
+			})
+			text, est, err := folderEstimate(ops, path)
+			var basis *sizeBasis
+			if err == nil { basis = &sizeBasis{folder: true, folderFiles: est.Files, folderBytes: est.Bytes, approx: !est.Exact, ratio: sampleRatio([]string{path})} }
+			s.showFileInfo(gen, true, func() {
+				s.fileInfoLabel.SetText(text + " | Mode: " + modeText)
+				s.setSizeBasis(basis)
+			})
+			return
+		}
+		if meta, err := readFolderMeta(path + ".meta"); err == nil {
+			original := meta.OriginalFolder
+			if original == "" { original = name }
+			s.showFileInfo(gen, true, func() {
+				s.dragDropLabel.SetText("📦 " + original)
+				s.fileInfoLabel.SetText(fmt.Sprintf("Archived Folder (files: %d)", meta.FileCount))
+			})
+			return
+		}
+		sizeText := uiutil.HumanBytes(info.Size())
+		s.showFileInfo(gen, false, func() {
+			s.dragDropLabel.SetText("📄 " + name)
+			s.fileInfoLabel.SetText("Size: " + sizeText)
+		})
+		details, comments := fileDetails(path, sizeText, password)
+		var basis *sizeBasis
+		if details == "" { basis = &sizeBasis{files: []int64{info.Size()}, ratio: sampleRatio([]string{path})} }
+		s.showFileInfo(gen, true, func() {
+			if details == "" { s.setSizeBasis(basis); return } // regular file: the size and its preview are all there is
+			s.fileInfoLabel.SetText(details)
+			if comments != nil {
+				s.commentsEntry.SetText(*comments)
+				s.comments = *comments
+			}
+		})
+	}()
+}
+
+// fileDetails describes an encrypted file from its header; comments is nil when
+// the comments field should be left alone. Both are empty for other files. Only
+// files named like containers are unwrapped with password.
+func fileDetails(path, sizeText string, password []byte) (details string, comments *string) {
+	if !format.IsHadesCrypt(path) { password = nil }
+	fileInfo, err := cryptoengine.GetFileInfoWithPassword(path, password)
+	if err != nil { return "", nil }
+	switch fileInfo["format"] {
+	case "HadesCrypt":
+		modeName, _ := fileInfo["encryption_mode_name"].(string)
+		if compliant, _ := fileInfo["compliance"].(bool); compliant { modeName += " • FIPS compliance" }
+		if deniable, _ := fileInfo["deniable"].(bool); deniable { modeName += " • 🙈 Deniability Mode" }
+		if timestamp.HasToken(path) { modeName += " • ⏱ timestamped" }
+		details = fmt.Sprintf("🔒 Size: %s - %s", sizeText, modeName)
+		if n, _ := fileInfo["original_name"].(string); n != "" { details += "\nOriginal name: " + n }
+		if h, _ := fileInfo["hint"].(string); h != "" { details += "\n💡 Password hint: " + h }
+		c, _ := fileInfo["comments"].(string) // an encrypted file without comments clears the field
+		return details, &c
+	case "GnuPG/OpenPGP":
+		// GnuPG files don't store comments, but don't clear existing ones
+		details = fmt.Sprintf("🔐 Size: %s - GnuPG/OpenPGP", sizeText)
+		if msg, err := pgppacket.ParseFile(path); err == nil { details += "\n" + msg.Summary() + " (🔍 Inspect for details)" }
+		return details, nil
+	}
+	return "", nil
+}
+
+// folderMeta is the sidecar written next to an encrypted folder archive
+type folderMeta struct {
+	OriginalFolder string `json:"original_folder"`
+	FileCount      int    `json:"file_count"`
+}
+
+func readFolderMeta(path string) (*folderMeta, error) {
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, err }
+	var m folderMeta
+	if err := json.Unmarshal(data, &m); err != nil { return nil, err }
+	return &m, nil
+}
+
+// limitEntry keeps e's text within limit bytes, cutting at a character boundary,
+// for fields whose size the container format bounds. onTrim runs after a cut.
+// Set e.OnChanged before calling; it then only sees text within the limit.
+func limitEntry(e *widget.Entry, limit int, onTrim func()) {
+	next := e.OnChanged
+	e.OnChanged = func(text string) {
+		if len(text) > limit {
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(text[cut]) { cut-- }
+			e.SetText(text[:cut]) // runs OnChanged again with the cut text
+			if onTrim != nil { onTrim() }
+			return
+		}
+		if next != nil { next(text) }
+	}
+}
+
+// commentTrimmedNote is the status after a comment was cut to the header limit
+func commentTrimmedNote() string {
+	return fmt.Sprintf("✂️ Comments are limited to %s; the rest was cut", uiutil.HumanBytes(cryptoengine.MaxCommentBytes))
+}
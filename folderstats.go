@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/ignore"
+	"github.com/bangundwir/HadesCrypt/internal/operations"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// mediaKinds are the kinds "Exclude media" adds to .hadesignore
+var mediaKinds = []string{"Video", "Audio", "Images", "Disk images"}
+
+// showFolderStats counts the selected folder in full and shows what it is made of,
+// so bulky media can be excluded or the output split before encrypting.
+// Closing the dialog stops the count.
+func (s *AppState) showFolderStats(w fyne.Window) {
+	root := s.selectedPath
+	if info, err := os.Stat(root); root == "" || err != nil || !info.IsDir() {
+		dialog.ShowInformation("Folder statistics", "Select a single folder to see what it contains before encrypting it.", w)
+		return
+	}
+	var stop atomic.Bool
+	ops := s.ops()
+	ops.Canceled = stop.Load
+	text := widget.NewLabel("🔎 Counting every file…")
+	text.TextStyle = fyne.TextStyle{Monospace: true}
+	excludeBtn := widget.NewButton("🚫 Exclude media", nil)
+	excludeBtn.Hide()
+	scroll := container.NewVScroll(text)
+	scroll.SetMinSize(fyne.NewSize(600, 400))
+	d := dialog.NewCustom("📊 "+filepath.Base(root), "Close", container.NewBorder(nil, excludeBtn, nil, nil, scroll), w)
+	d.SetOnClosed(func() { stop.Store(true) })
+	d.Show()
+
+	go func() {
+		st, err := ops.Stats(root)
+		s.ui(func() {
+			if st == nil { text.SetText("❌ " + err.Error()); return }
+			report := folderStatsReport(root, st)
+			if err != nil { report += "\n⚠️ Some folders could not be read: " + err.Error() }
+			text.SetText(report)
+			rules := mediaRules(st)
+			if len(rules) == 0 { return }
+			excludeBtn.SetText(fmt.Sprintf("🚫 Exclude media (%d type(s)) via %s", len(rules), ignore.FileName))
+			excludeBtn.OnTapped = func() {
+				n, err := ignore.Append(root, rules)
+				if err != nil { dialog.ShowError(err, w); return }
+				excludeBtn.Disable()
+				s.statusLabel.SetText(fmt.Sprintf("🚫 %d rule(s) added to %s", n, filepath.Join(filepath.Base(root), ignore.FileName)))
+				d.Hide()
+				s.updateFileInfo()
+			}
+			excludeBtn.Show()
+		})
+	}()
+}
+
+// folderStatsReport is the dialog text: totals, kinds, extensions, largest files, containers
+func folderStatsReport(root string, st *operations.FolderStats) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d file(s), %s to encrypt (excluded paths left out)\n", st.Files, uiutil.HumanBytes(st.Bytes))
+	share := func(n int64) string {
+		if st.Bytes == 0 { return "" }
+		return fmt.Sprintf("%5.1f%%", float64(n)*100/float64(st.Bytes))
+	}
+	if len(st.Types) > 0 {
+		b.WriteString("\nBy type\n")
+		for _, k := range st.KindTotals() {
+			fmt.Fprintf(&b, "  %-12s %7d file(s) %10s %s\n", k.Kind, k.Files, uiutil.HumanBytes(k.Bytes), share(k.Bytes))
+		}
+		b.WriteString("\nBy extension\n")
+		for _, t := range st.Types[:min(len(st.Types), 15)] {
+			ext := t.Ext
+			if ext == "" { ext = "(none)" }
+			fmt.Fprintf(&b, "  %-12s %7d file(s) %10s %s\n", ext, t.Files, uiutil.HumanBytes(t.Bytes), share(t.Bytes))
+		}
+		if rest := len(st.Types) - 15; rest > 0 { fmt.Fprintf(&b, "  … %d more\n", rest) }
+	}
+	if len(st.Largest) > 0 {
+		fmt.Fprintf(&b, "\nLargest %d\n", len(st.Largest))
+		for _, e := range st.Largest {
+			rel, err := filepath.Rel(root, e.Path)
+			if err != nil { rel = e.Path }
+			fmt.Fprintf(&b, "  %10s  %s\n", uiutil.HumanBytes(e.Info.Size()), rel)
+		}
+	}
+	if st.Encrypted > 0 {
+		fmt.Fprintf(&b, "\nAlready encrypted: %d file(s), %s. Per-file encryption skips them; an archive encrypts them again.\n", st.Encrypted, uiutil.HumanBytes(st.EncryptedBytes))
+	}
+	return b.String()
+}
+
+// mediaRules returns .hadesignore rules for the media extensions found
+func mediaRules(st *operations.FolderStats) []string {
+	var rules []string
+	for _, t := range st.Types {
+		if t.Ext != "" && slices.Contains(mediaKinds, t.Kind) { rules = append(rules, ignore.ExtensionPattern(t.Ext)) }
+	}
+	return rules
+}
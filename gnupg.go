@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/gnupg"
+)
+
+// gpgPath is the gpg binary for GnuPG mode: the active profile's override,
+// else the global setting (empty lets gnupg search the system)
+func (s *AppState) gpgPath() string {
+	if s.profileGPGPath != "" { return s.profileGPGPath }
+	return s.config.GPGPath
+}
+
+// refreshGnuPGStatus shows which gpg binary GnuPG mode will use; recheck
+// bypasses the cached lookup (after installing or upgrading gpg)
+func (s *AppState) refreshGnuPGStatus(recheck bool) {
+	if s.gpgStatusLabel == nil { return }
+	path, override := s.gpgPath(), s.profileGPGPath != ""
+	s.gpgStatusLabel.SetText("GnuPG: checking…")
+	go func() {
+		locate := gnupg.Locate
+		if recheck { locate = gnupg.Probe }
+		bin, err := locate(path)
+		text := ""
+		switch {
+		case err != nil:
+			text = "⚠️ GnuPG: " + err.Error()
+		case override:
+			text = "✅ GnuPG: " + bin.String() + " (profile)"
+		default:
+			text = "✅ GnuPG: " + bin.String()
+		}
+		s.ui(func() { s.gpgStatusLabel.SetText(text) })
+	}()
+}
+
+// buildGnuPGControls returns the gpg binary setting with its diagnostics line
+func (s *AppState) buildGnuPGControls(w fyne.Window) fyne.CanvasObject {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("Search PATH")
+	entry.SetText(s.config.GPGPath)
+	entry.OnChanged = func(text string) {
+		text = strings.TrimSpace(text)
+		if text == s.config.GPGPath { return }
+		s.config.GPGPath = text
+		s.config.Save()
+	}
+	// Probe once editing settles rather than on every keystroke
+	entry.OnSubmitted = func(string) { s.refreshGnuPGStatus(true) }
+	browse := widget.NewButton("…", func() {
+		dialog.ShowFileOpen(func(rc fyne.URIReadCloser, err error) {
+			if err != nil || rc == nil { return }
+			rc.Close()
+			entry.SetText(rc.URI().Path())
+			s.refreshGnuPGStatus(true)
+		}, w)
+	})
+	check := widget.NewButton("Check", func() { s.refreshGnuPGStatus(true) })
+
+	s.gpgStatusLabel = widget.NewLabel("")
+	s.gpgStatusLabel.Wrapping = fyne.TextWrapWord
+	s.refreshGnuPGStatus(false)
+	return container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("GnuPG binary:"), container.NewHBox(browse, check), entry),
+		s.gpgStatusLabel,
+		s.buildRecipientsRow(w),
+	)
+}
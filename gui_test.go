@@ -0,0 +1,860 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/avscan"
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/ignore"
+	"github.com/bangundwir/HadesCrypt/internal/operations"
+	"github.com/bangundwir/HadesCrypt/internal/pgppacket"
+	"github.com/bangundwir/HadesCrypt/internal/progress"
+	"github.com/bangundwir/HadesCrypt/internal/safename"
+	"github.com/bangundwir/HadesCrypt/internal/searchindex"
+	"github.com/bangundwir/HadesCrypt/internal/splitter"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// jobTimeout bounds how long one encrypt or decrypt may take in the smoke tests
+const jobTimeout = time.Minute
+
+// newTestWindow builds a main window the way the app does, on the in-memory test
+// driver with config and records in a throwaway home folder
+func newTestWindow(t *testing.T) (*AppState, fyne.Window) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	app := test.NewTempApp(t)
+
+	w := newMainWindow(app, config.DefaultConfig(), false)
+	var s *AppState
+	for st, ow := range openWindows {
+		if ow == w {
+			s = st
+		}
+	}
+	if s == nil {
+		t.Fatal("main window not registered")
+	}
+	// the plan dialog shown before deleting sources is out of scope here
+	s.deleteAfter = false
+	t.Cleanup(func() { s.closeWindow(w) })
+	return s, w
+}
+
+// findButton returns the laid-out button labelled text
+func findButton(t *testing.T, w fyne.Window, text string) *widget.Button {
+	t.Helper()
+	for _, o := range test.LaidOutObjects(w.Content()) {
+		if b, ok := o.(*widget.Button); ok && b.Text == text {
+			return b
+		}
+	}
+	t.Fatalf("no %q button", text)
+	return nil
+}
+
+// runJob taps the button and waits for the job it starts to finish. The job sets
+// busy first thing and clears it once its status is final; the KDF alone keeps
+// it busy long enough to be seen.
+func runJob(t *testing.T, s *AppState, b *widget.Button) {
+	t.Helper()
+	test.Tap(b)
+	deadline := time.Now().Add(jobTimeout)
+	for !s.busy.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("job did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for s.busy.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("job did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func typePasswords(s *AppState, password, confirm string) {
+	test.Type(s.passwordEntry, password)
+	test.Type(s.confirmPasswordEntry, confirm)
+}
+
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// assertDialog fails unless a dialog is showing over the window
+func assertDialog(t *testing.T, w fyne.Window) {
+	t.Helper()
+	if w.Canvas().Overlays().Top() == nil {
+		t.Error("expected a dialog")
+	}
+}
+
+func TestGUIEncryptDecrypt(t *testing.T) {
+	s, w := newTestWindow(t)
+	plain := bytes.Repeat([]byte("smoke test "), 10000)
+	path := writeTempFile(t, "report.txt", plain)
+	s.setSelectedFile(path)
+	if !strings.Contains(s.dragDropLabel.Text, "report.txt") {
+		t.Errorf("selection label %q does not name the file", s.dragDropLabel.Text)
+	}
+	typePasswords(s, "correct horse battery", "correct horse battery")
+
+	runJob(t, s, findButton(t, w, "🔒 Encrypt"))
+	if !strings.HasPrefix(s.statusLabel.Text, "✅") {
+		t.Fatalf("encrypt status %q", s.statusLabel.Text)
+	}
+	out := s.defaultOutputPathForEncrypt(path)
+	if _, err := os.Stat(out); err != nil {
+		t.Fatalf("encrypted output: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("source removed although delete-after is off: %v", err)
+	}
+	if sum := s.opSummary; sum == nil || len(sum.Outputs) != 1 || sum.Outputs[0] != out {
+		t.Errorf("summary %+v, want output %s", sum, out)
+	}
+
+	os.Remove(path)
+	s.setSelectedFile(out)
+	runJob(t, s, findButton(t, w, "🔓 Decrypt"))
+	if !strings.HasPrefix(s.statusLabel.Text, "✅ Decrypted") {
+		t.Fatalf("decrypt status %q", s.statusLabel.Text)
+	}
+	got, err := os.ReadFile(s.defaultOutputPathForDecrypt(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Error("decrypted content differs from the original")
+	}
+}
+
+func TestGUIDecryptWrongPassword(t *testing.T) {
+	s, w := newTestWindow(t)
+	path := writeTempFile(t, "notes.txt", []byte("secret notes"))
+	s.setSelectedFile(path)
+	typePasswords(s, "right password", "right password")
+	runJob(t, s, findButton(t, w, "🔒 Encrypt"))
+	out := s.defaultOutputPathForEncrypt(path)
+	os.Remove(path)
+
+	s.passwordEntry.SetText("")
+	s.confirmPasswordEntry.SetText("")
+	typePasswords(s, "wrong password", "")
+	s.setSelectedFile(out)
+	runJob(t, s, findButton(t, w, "🔓 Decrypt"))
+	if !strings.HasPrefix(s.statusLabel.Text, "❌") {
+		t.Errorf("decrypt status %q, want an error", s.statusLabel.Text)
+	}
+	assertDialog(t, w)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("wrong password left output behind: %v", err)
+	}
+}
+
+func TestGUIEncryptWritesComments(t *testing.T) {
+	s, w := newTestWindow(t)
+	path := writeTempFile(t, "notes.txt", []byte("notes"))
+	s.setSelectedFile(path)
+	waitForText(t, s.fileInfoLabel, "Size:")
+	s.commentsEntry.SetText("for the auditors")
+	typePasswords(s, "correct horse battery", "correct horse battery")
+	runJob(t, s, findButton(t, w, "🔒 Encrypt"))
+	hdr, err := cryptoengine.ReadHeaderFromFile(s.defaultOutputPathForEncrypt(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Metadata.Comment != "for the auditors" {
+		t.Errorf("header comment %q", hdr.Metadata.Comment)
+	}
+}
+
+func TestGUIEncryptRefusesBadInput(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		selected          bool
+		password, confirm string
+	}{
+		{"no selection", false, "password", "password"},
+		{"no password", true, "", ""},
+		{"mismatch", true, "password", "passw0rd"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, w := newTestWindow(t)
+			path := writeTempFile(t, "data.bin", []byte{1, 2, 3})
+			if tc.selected {
+				s.setSelectedFile(path)
+			}
+			typePasswords(s, tc.password, tc.confirm)
+			test.Tap(findButton(t, w, "🔒 Encrypt"))
+			assertDialog(t, w)
+			if s.statusLabel.Text != "Status: Ready" {
+				t.Errorf("status %q, want it unchanged", s.statusLabel.Text)
+			}
+			if _, err := os.Stat(s.defaultOutputPathForEncrypt(path)); !os.IsNotExist(err) {
+				t.Errorf("output written: %v", err)
+			}
+		})
+	}
+}
+
+// openAdvanced expands the Advanced Options panel so its controls are laid out
+func openAdvanced(t *testing.T, w fyne.Window) {
+	t.Helper()
+	for _, o := range test.LaidOutObjects(w.Content()) {
+		if a, ok := o.(*widget.Accordion); ok {
+			a.OpenAll()
+			return
+		}
+	}
+	t.Fatal("no Advanced Options panel")
+}
+
+// findCheck returns the laid-out check whose label starts with prefix
+func findCheck(t *testing.T, w fyne.Window, prefix string) *widget.Check {
+	t.Helper()
+	for _, o := range test.LaidOutObjects(w.Content()) {
+		if c, ok := o.(*widget.Check); ok && strings.HasPrefix(c.Text, prefix) {
+			return c
+		}
+	}
+	t.Fatalf("no %q check", prefix)
+	return nil
+}
+
+func TestGUIProfileDrivesControls(t *testing.T) {
+	s, w := newTestWindow(t)
+	openAdvanced(t, w)
+	recursive := findCheck(t, w, "Recursive Mode")
+	split := findCheck(t, w, "Split into chunks")
+
+	p := config.Profile{Name: "archive", RecursiveMode: true, SplitOutput: true, CompressionLevel: 9,
+		Argon2Preset: "Strong", OutputDir: "vault", SymlinkPolicy: "follow"}
+	s.applyProfile(&p)
+	if !recursive.Checked || !split.Checked {
+		t.Errorf("profile not shown: recursive %v, split %v", recursive.Checked, split.Checked)
+	}
+	var dirShown, symlinksShown bool
+	for _, o := range test.LaidOutObjects(w.Content()) {
+		switch o := o.(type) {
+		case *widget.Entry:
+			dirShown = dirShown || o.Text == "vault"
+		case *widget.Select:
+			symlinksShown = symlinksShown || o.Selected == "Follow"
+		}
+	}
+	if !dirShown || !symlinksShown {
+		t.Errorf("profile not shown: output folder %v, symlinks %v", dirShown, symlinksShown)
+	}
+	got := s.currentProfile("archive")
+	if got.RecursiveMode != p.RecursiveMode || got.CompressionLevel != p.CompressionLevel || got.Argon2Preset != p.Argon2Preset ||
+		got.OutputDir != p.OutputDir || got.SymlinkPolicy != p.SymlinkPolicy {
+		t.Errorf("currentProfile = %+v, want the applied %+v", got, p)
+	}
+
+	test.Tap(recursive)
+	if s.opt().Recursive {
+		t.Error("unticking Recursive Mode left the option set")
+	}
+	s.options.set(defaultOptions())
+	if recursive.Checked || split.Checked {
+		t.Error("controls kept the profile after resetting to defaults")
+	}
+}
+
+func TestHistoryTimeLabel(t *testing.T) {
+	test.NewTempApp(t)
+	l := newTimeLabel()
+	at := time.Now().Add(-2 * time.Hour)
+	l.SetTime(at)
+	if l.Text != "2 hours ago" {
+		t.Errorf("relative text %q", l.Text)
+	}
+	l.MouseIn(&desktop.MouseEvent{})
+	if l.Text == "2 hours ago" || !strings.Contains(l.Text, at.Format("04")) {
+		t.Errorf("hover text %q, want the absolute time", l.Text)
+	}
+	l.MouseOut()
+	if l.Text != "2 hours ago" {
+		t.Errorf("text after hover %q", l.Text)
+	}
+	l.SetTime(time.Time{})
+	l.MouseIn(&desktop.MouseEvent{})
+	if l.Text != "" {
+		t.Errorf("zero time shows %q", l.Text)
+	}
+}
+
+func TestGUIFolderInfoEstimate(t *testing.T) {
+	s, _ := newTestWindow(t)
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("12345"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.setSelectedFile(dir)
+	waitForText(t, s.fileInfoLabel, "2 file(s), 10 B")
+}
+
+func TestGUISelectionSummaryInBackground(t *testing.T) {
+	s, _ := newTestWindow(t)
+	a := writeTempFile(t, "a.txt", []byte("12345"))
+	b := writeTempFile(t, "b.txt", []byte("67890"))
+	s.setSelectedFiles([]string{a, b})
+	if !s.fileInfoActivity.Visible() {
+		t.Error("no spinner while the selection loads")
+	}
+	waitForText(t, s.dragDropLabel, "2 item(s) (2 file(s), 0 folder(s))")
+	waitForText(t, s.fileInfoLabel, "10 B")
+	deadline := time.Now().Add(jobTimeout)
+	for s.fileInfoActivity.Visible() {
+		if time.Now().After(deadline) {
+			t.Fatal("spinner still shown after the details loaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGUIFolderStatsExcludeMedia(t *testing.T) {
+	s, w := newTestWindow(t)
+	dir := t.TempDir()
+	for name, size := range map[string]int{"clip.MP4": 3000, "notes.txt": 10, "old.txt.hadescrypt": 5} {
+		if err := os.WriteFile(filepath.Join(dir, name), bytes.Repeat([]byte("x"), size), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st, err := (&operations.Controller{}).Stats(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := folderStatsReport(dir, st)
+	for _, want := range []string{"2 file(s)", "Video", ".mp4", "clip.MP4", "Already encrypted: 1 file(s)"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+
+	s.setSelectedFile(dir)
+	test.Tap(findButton(t, w, "📊 Stats"))
+	deadline := time.Now().Add(jobTimeout)
+	var exclude *widget.Button
+	for exclude == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("no exclude button")
+		}
+		time.Sleep(10 * time.Millisecond)
+		for _, o := range test.LaidOutObjects(w.Canvas().Overlays().Top()) {
+			if b, ok := o.(*widget.Button); ok && b.Visible() && strings.HasPrefix(b.Text, "🚫 Exclude media") {
+				exclude = b
+			}
+		}
+	}
+	test.Tap(exclude)
+	data, err := os.ReadFile(filepath.Join(dir, ignore.FileName))
+	if err != nil || string(data) != "*.[mM][pP]4\n" {
+		t.Fatalf("ignore file %q, %v", data, err)
+	}
+	waitForText(t, s.fileInfoLabel, "Folder: 1 file(s), 10 B")
+}
+
+func TestSizePreviewText(t *testing.T) {
+	opts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM}
+	o := defaultOptions()
+	o.Split, o.SplitSize, o.SplitUnit = true, 1, "MiB"
+	exact, err := cryptoengine.EncryptedSize(3<<20, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := sizePreviewText(&sizeBasis{files: []int64{3 << 20}}, o, opts)
+	if want := "📦 Output: " + uiutil.HumanBytes(exact); !strings.HasPrefix(got, want) || !strings.HasSuffix(got, "• 4 part(s) of 1 MiB") {
+		t.Errorf("file preview %q, want %q… and 4 parts", got, want)
+	}
+
+	folder := &sizeBasis{folder: true, folderFiles: 4, folderBytes: 4 << 20, ratio: 0.5}
+	if got := sizePreviewText(folder, o, opts); !strings.Contains(got, "compressed to ~50%") || !strings.Contains(got, "~3 part(s)") {
+		t.Errorf("archive preview %q", got)
+	}
+	o.Recursive = true
+	if got := sizePreviewText(folder, o, opts); !strings.Contains(got, "in 4 files") || !strings.Contains(got, "~8 part(s)") {
+		t.Errorf("per-file preview %q", got)
+	}
+	if got := sizePreviewText(folder, o, cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeGnuPG}); !strings.Contains(got, "gpg") {
+		t.Errorf("GnuPG preview %q", got)
+	}
+	adaptive := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM, AdaptiveChunks: true}
+	if got := sizePreviewText(&sizeBasis{files: []int64{3 << 20}}, o, adaptive); !strings.HasPrefix(got, "📦 Output: ≤") {
+		t.Errorf("adaptive preview %q, want an upper bound", got)
+	}
+}
+
+func TestGUITransliteratedNameRestored(t *testing.T) {
+	s, w := newTestWindow(t)
+	path := writeTempFile(t, "Ärger über Öl.txt", []byte("umlauts"))
+	s.options.names.Set(safename.Transliterate.Label())
+	s.setSelectedFile(path)
+	typePasswords(s, "correct horse battery", "correct horse battery")
+	runJob(t, s, findButton(t, w, "🔒 Encrypt"))
+	out := filepath.Join(filepath.Dir(path), "Aerger ueber Oel.txt.hadescrypt")
+	hdr, err := cryptoengine.ReadHeaderFromFile(out)
+	if err != nil {
+		t.Fatalf("transliterated output: %v (status %q)", err, s.statusLabel.Text)
+	}
+	if hdr.Metadata.Name != filepath.Base(path) {
+		t.Errorf("stored name %q", hdr.Metadata.Name)
+	}
+
+	os.Remove(path)
+	s.setSelectedFile(out)
+	runJob(t, s, findButton(t, w, "🔓 Decrypt"))
+	if data, err := os.ReadFile(path); err != nil || string(data) != "umlauts" {
+		t.Errorf("not restored under the original name: %v (status %q)", err, s.statusLabel.Text)
+	}
+}
+
+func TestGUIOutputLimitOffersSplit(t *testing.T) {
+	s, w := newTestWindow(t)
+	dir := filepath.Join(t.TempDir(), "OneDrive")
+	os.Mkdir(dir, 0700)
+	path := filepath.Join(dir, "data.bin")
+	os.WriteFile(path, []byte("small"), 0600)
+	s.setSelectedFile(path)
+	waitForText(t, s.outputEstimateLabel, "📦 Output")
+	// as if the file held 300 GB, over OneDrive's 250 GB per file
+	s.setSizeBasis(&sizeBasis{files: []int64{300_000_000_000}})
+	typePasswords(s, "password", "password")
+	test.Tap(findButton(t, w, "🔒 Encrypt"))
+	assertDialog(t, w)
+	if _, err := os.Stat(s.defaultOutputPathForEncrypt(path)); !os.IsNotExist(err) {
+		t.Fatalf("encryption started before the warning was answered: %v", err)
+	}
+	var split *widget.Button
+	for _, o := range test.LaidOutObjects(w.Canvas().Overlays().Top()) {
+		if b, ok := o.(*widget.Button); ok && b.Text == "Split into 232 GiB parts" {
+			split = b
+		}
+	}
+	if split == nil {
+		t.Fatal("no split suggestion in the warning")
+	}
+	runJob(t, s, split)
+	if o := s.opt(); !o.Split || o.SplitSize != 232 || o.SplitUnit != "GiB" {
+		t.Errorf("split options %v %d %s", o.Split, o.SplitSize, o.SplitUnit)
+	}
+	if _, err := os.Stat(s.defaultOutputPathForEncrypt(path)); err != nil {
+		t.Errorf("no output after choosing to split: %v", err)
+	}
+}
+
+func TestGUISplitOutput(t *testing.T) {
+	s, w := newTestWindow(t)
+	plain := bytes.Repeat([]byte("volume "), 500)
+	path := writeTempFile(t, "notes.txt", plain)
+	s.options.splitUnit.Set("KiB")
+	s.options.splitSize.Set(1)
+	s.options.split.Set(true)
+	s.setSelectedFile(path)
+	typePasswords(s, "correct horse battery", "correct horse battery")
+	runJob(t, s, findButton(t, w, "🔒 Encrypt"))
+	out := s.defaultOutputPathForEncrypt(path)
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("the whole container is still there: %v (status %q)", err, s.statusLabel.Text)
+	}
+	m, err := splitter.ReadManifest(out)
+	if err != nil || len(m.Parts) != 4 {
+		t.Fatalf("manifest %+v: %v", m, err)
+	}
+
+	os.Remove(path)
+	s.setSelectedFile(out + ".000")
+	runJob(t, s, findButton(t, w, "🔓 Decrypt"))
+	if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("not restored from the volumes: %v (status %q)", err, s.statusLabel.Text)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("the joined container was left behind: %v", err)
+	}
+}
+
+func TestGUISizePreviewFollowsOptions(t *testing.T) {
+	s, _ := newTestWindow(t)
+	path := writeTempFile(t, "data.bin", bytes.Repeat([]byte{7}, 3<<20))
+	s.setSelectedFile(path)
+	waitForText(t, s.outputEstimateLabel, "📦 Output: 3")
+	s.options.splitSize.Set(1)
+	s.options.splitUnit.Set("MiB")
+	s.options.split.Set(true)
+	waitForText(t, s.outputEstimateLabel, "4 part(s) of 1 MiB")
+	s.setSelectedFile("")
+	if s.outputEstimateLabel.Visible() {
+		t.Errorf("preview %q shown without a selection", s.outputEstimateLabel.Text)
+	}
+}
+
+func TestGUIParanoidModeOption(t *testing.T) {
+	s, _ := newTestWindow(t)
+	s.options.paranoid.Set(true)
+	if mode := s.encryptOptions().Mode; mode != cryptoengine.ModeParanoidSerpent {
+		t.Errorf("paranoid option encrypts with %s", cryptoengine.GetEncryptionModeName(mode))
+	}
+	s.encryptionMode = cryptoengine.ModeGnuPG
+	if mode := s.encryptOptions().Mode; mode != cryptoengine.ModeGnuPG {
+		t.Errorf("paranoid option replaced GnuPG with %s", cryptoengine.GetEncryptionModeName(mode))
+	}
+	s.encryptionMode = cryptoengine.ModeAES256GCM
+	s.config.ComplianceMode = true
+	if mode := s.encryptOptions().Mode; mode != cryptoengine.ModeAES256GCM {
+		t.Errorf("compliance mode encrypts with %s", cryptoengine.GetEncryptionModeName(mode))
+	}
+}
+
+func TestGUICompressOption(t *testing.T) {
+	s, _ := newTestWindow(t)
+	if s.encryptOptions().UseCompression {
+		t.Error("compression is on by default")
+	}
+	s.options.compress.Set(true)
+	s.options.compression.Set("Best")
+	if opts := s.encryptOptions(); !opts.UseCompression || opts.CompressionLevel != 9 {
+		t.Errorf("compress option gives compression %v at level %d", opts.UseCompression, opts.CompressionLevel)
+	}
+}
+
+func TestGUIAdaptiveOption(t *testing.T) {
+	s, _ := newTestWindow(t)
+	if s.encryptOptions().AdaptiveChunks {
+		t.Error("adaptive chunks are on by default")
+	}
+	s.config.AdaptiveChunks = true
+	if !s.encryptOptions().AdaptiveChunks {
+		t.Error("the adaptive chunk setting is not passed to the engine")
+	}
+}
+
+func TestGUIReedSolomonOption(t *testing.T) {
+	s, _ := newTestWindow(t)
+	if s.encryptOptions().UseReedSolomon {
+		t.Error("Reed-Solomon is on by default")
+	}
+	s.options.reedSolomon.Set(true)
+	if !s.encryptOptions().UseReedSolomon {
+		t.Error("the Reed-Solomon option is not passed to the engine")
+	}
+}
+
+func TestGUIDeniabilityOption(t *testing.T) {
+	s, _ := newTestWindow(t)
+	if s.encryptOptions().UseDeniability {
+		t.Error("Deniability Mode is on by default")
+	}
+	s.options.deniability.Set(true)
+	if !s.encryptOptions().UseDeniability {
+		t.Error("the Deniability Mode option is not passed to the engine")
+	}
+}
+
+func TestGUIPerfPanel(t *testing.T) {
+	busy := cryptoengine.PipelineStats{Workers: 8, Active: 8, Queued: 4}
+	ev := progress.Event{Done: 1 << 30, Total: 3 << 30, Remaining: 2 << 30, Rate: 100 << 20, ETA: 20 * time.Second}
+	for _, c := range []struct {
+		p    perfSample
+		want string
+	}{
+		{perfSample{ev: ev, stats: busy, usage: 0.95, cpu: 0.9}, "every worker is busy"},
+		{perfSample{ev: ev, stats: cryptoengine.PipelineStats{Workers: 1, Active: 1}, usage: 0.9}, "one chunk at a time"},
+		{perfSample{ev: ev, stats: busy, usage: 0.2}, "disk or network"},
+		{perfSample{ev: ev, stats: busy, usage: 0.2, limited: 100 << 20}, "Low priority limit"},
+		{perfSample{ev: ev}, "archiving"},
+		{perfSample{ev: ev, stats: busy, usage: 0.7}, ""},
+	} {
+		if got := c.p.hint(); c.want == "" && got != "" || !strings.Contains(got, c.want) {
+			t.Errorf("hint for %+v = %q, want %q", c.p, got, c.want)
+		}
+	}
+	text := perfSample{ev: ev, stats: busy, cpu: 0.9}.text()
+	for _, want := range []string{"CPU ~90%", "workers 8/8 busy", "queue 4", "left (~20s)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("panel text %q lacks %q", text, want)
+		}
+	}
+
+	s, _ := newTestWindow(t)
+	s.startOpSummary("encrypt")
+	if s.perfMeter.Load() == nil {
+		t.Fatal("no meter while a job runs")
+	}
+	s.reportProgress(10, 100)
+	if ev := s.perfMeter.Load().Event(); ev.Done != 10 || ev.Total != 100 {
+		t.Errorf("meter saw %d/%d", ev.Done, ev.Total)
+	}
+	s.finishSummary()
+	if s.perfMeter.Load() != nil || s.perfPanel.Visible() {
+		t.Error("performance panel left running after the job")
+	}
+}
+
+// waitForText waits until a label updated in the background contains want
+func waitForText(t *testing.T, l *widget.Label, want string) {
+	t.Helper()
+	deadline := time.Now().Add(jobTimeout)
+	for !strings.Contains(l.Text, want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("label %q does not contain %q", l.Text, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGUIFileDetailsInBackground(t *testing.T) {
+	s, _ := newTestWindow(t)
+	src := writeTempFile(t, "plan.txt", []byte("plan"))
+	enc := src + ".hadescrypt"
+	opts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeChaCha20, Argon2: cryptoengine.Argon2Preset("Fast"), Metadata: cryptoengine.Metadata{Comment: "for the board", Hint: "usual"}}
+	if err := cryptoengine.EncryptFileWithOptions(src, enc, []byte("pw"), opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	s.setSelectedFile(enc)
+	waitForText(t, s.fileInfoLabel, "💡 Password hint: usual")
+	if s.commentsEntry.Text != "for the board" || s.comments != "for the board" {
+		t.Errorf("comments %q / %q", s.commentsEntry.Text, s.comments)
+	}
+
+	s.setSelectedFile(src)
+	waitForText(t, s.fileInfoLabel, "Size: 4 B")
+	if s.fileInfoLabel.Text != "Size: 4 B" {
+		t.Errorf("plain file info %q", s.fileInfoLabel.Text)
+	}
+	s.commentsEntry.SetText(strings.Repeat("é", cryptoengine.MaxCommentBytes)) // two bytes each
+	if n := len(s.comments); n != cryptoengine.MaxCommentBytes {
+		t.Errorf("comment of %d bytes kept, limit %d", n, cryptoengine.MaxCommentBytes)
+	}
+	if !strings.HasPrefix(s.statusLabel.Text, "✂️") {
+		t.Errorf("status %q", s.statusLabel.Text)
+	}
+}
+
+func TestSummaryOutputs(t *testing.T) {
+	_, w := newTestWindow(t)
+	text := widget.NewLabel("summary")
+	if withOutputs(w, text, nil) != text {
+		t.Error("a job without results grew a results section")
+	}
+	var outputs []string
+	for i := range outputsShown + 2 {
+		outputs = append(outputs, filepath.Join(t.TempDir(), fmt.Sprintf("f%d.hc", i)))
+	}
+	var buttons, labels []string
+	for _, o := range test.LaidOutObjects(withOutputs(w, text, outputs)) {
+		switch o := o.(type) {
+		case *widget.Button:
+			buttons = append(buttons, o.Text)
+		case *widget.Label:
+			labels = append(labels, o.Text)
+		}
+	}
+	if n := strings.Count(strings.Join(buttons, " "), "📂"); n != outputsShown {
+		t.Errorf("%d reveal buttons, want %d", n, outputsShown)
+	}
+	if !slices.Contains(buttons, fmt.Sprintf("📋 Copy all %d as files", len(outputs))) || !slices.Contains(labels, "…and 2 more") {
+		t.Errorf("buttons %q, labels %q", buttons, labels)
+	}
+}
+
+func TestGUIFlagsExecutableOutput(t *testing.T) {
+	s, w := newTestWindow(t)
+	path := writeTempFile(t, "invoice.pdf", []byte("#!/bin/sh\necho hello\n"))
+	s.setSelectedFile(path)
+	typePasswords(s, "correct horse battery", "correct horse battery")
+	runJob(t, s, findButton(t, w, "🔒 Encrypt"))
+	out := s.defaultOutputPathForEncrypt(path)
+	if sum := s.opSummary; sum == nil || len(sum.Executables) != 0 {
+		t.Errorf("encrypt summary flagged %+v", sum)
+	}
+
+	os.Remove(path)
+	s.setSelectedFile(out)
+	runJob(t, s, findButton(t, w, "🔓 Decrypt"))
+	sum := s.opSummary
+	if sum == nil || len(sum.Executables) != 1 {
+		t.Fatalf("decrypt summary %+v, want one flagged output", sum)
+	}
+	if f := sum.Executables[0]; f.String() != "invoice.pdf: script (sh) named as .pdf" {
+		t.Errorf("flagged %q", f.String())
+	}
+	var warned bool
+	for _, o := range test.LaidOutObjects(withExecutableWarning(w, widget.NewLabel("summary"), sum.Executables)) {
+		if l, ok := o.(*widget.Label); ok && strings.Contains(l.Text, "invoice.pdf: script (sh)") {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Error("summary does not list the script")
+	}
+}
+
+func TestAntivirusReport(t *testing.T) {
+	s, _ := newTestWindow(t)
+	sum := &OperationSummary{Operation: "decrypt", Outputs: []string{"/tmp/out.txt"}}
+	s.scanOutputs(sum)
+	if sum.AVScanner != "" || sum.AVError != "" || avReport(sum) != "" {
+		t.Errorf("scan ran while switched off: %+v", sum)
+	}
+	s.config.AVScan, s.config.AVScannerPath = true, filepath.Join(t.TempDir(), "clamdscan")
+	s.scanOutputs(sum)
+	if !strings.HasPrefix(avReport(sum), "\nAntivirus: not run") {
+		t.Errorf("missing scanner reported as %q", avReport(sum))
+	}
+
+	sum = &OperationSummary{AVScanner: "clamdscan", AVResults: []avscan.Result{
+		{Path: "/tmp/a.txt", Verdict: avscan.Clean},
+		{Path: "/tmp/b.exe", Verdict: avscan.Infected, Threats: []string{"Win.Test.EICAR_HDB-1"}},
+	}}
+	if !sum.threatFound() || sum.scanFailed() {
+		t.Errorf("verdicts misread: %+v", sum.AVResults)
+	}
+	if got, want := avReport(sum), "\nAntivirus (clamdscan):\n  🦠 b.exe: Win.Test.EICAR_HDB-1\n  ✅ 1 clean"; got != want {
+		t.Errorf("report %q, want %q", got, want)
+	}
+}
+
+func TestGUIInspectOpenPGP(t *testing.T) {
+	s, _ := newTestWindow(t)
+	// gpg --symmetric output: password session key (AES-256, iterated S2K), then encrypted data
+	skesk := []byte{0xc3, 13, 4, 9, 3, 8, 1, 2, 3, 4, 5, 6, 7, 8, 0xff}
+	seipd := append([]byte{0xd2, 41, 1}, bytes.Repeat([]byte{0xaa}, 40)...)
+	path := writeTempFile(t, "letter.gpg", append(skesk, seipd...))
+	s.setSelectedFile(path)
+	waitForText(t, s.fileInfoLabel, "AES-256 • password (iterated and salted, SHA-256)")
+	msg, err := pgppacket.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := packetReport(msg)
+	for _, want := range []string{"Binary\n", "• Password encrypted session key (13 B)", "    S2K bytes hashed: 65011712", "• Encrypted data (integrity protected)"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestElevatedRequestRoundTrip(t *testing.T) {
+	s, _ := newTestWindow(t)
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+	opts := defaultOptions()
+	opts.Recursive, opts.OutputDir = true, dir
+	data, err := json.Marshal(elevatedRequest{Operation: "decrypt", Paths: paths, Options: opts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"--viewer", elevatedFlag + base64.RawURLEncoding.EncodeToString(data)}
+	r := parseElevatedRequest(args)
+	if r == nil {
+		t.Fatal("request not parsed")
+	}
+	if parseElevatedRequest([]string{elevatedFlag + "!!"}) != nil || parseElevatedRequest(nil) != nil {
+		t.Error("invalid or missing switch parsed")
+	}
+
+	s.applyElevatedRequest(r)
+	if len(s.selectedPaths) != 2 || !s.opt().Recursive || s.opt().OutputDir != dir {
+		t.Errorf("selection %v, options %+v", s.selectedPaths, s.opt())
+	}
+	if !strings.Contains(s.statusLabel.Text, "press Decrypt") {
+		t.Errorf("status %q", s.statusLabel.Text)
+	}
+}
+
+func TestGUILeftovers(t *testing.T) {
+	s, w := newTestWindow(t)
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	orphan := filepath.Join(dir, "report.pdf.hadescrypt.42.__dec_tmp__")
+	if err := os.WriteFile(orphan, []byte("plaintext"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(orphan, old, old)
+	s.setSelectedFile(dir)
+
+	s.scanLeftovers(w, true)
+	var del *widget.Button
+	deadline := time.Now().Add(jobTimeout)
+	for del == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("no leftovers dialog")
+		}
+		time.Sleep(10 * time.Millisecond)
+		if top := w.Canvas().Overlays().Top(); top != nil {
+			for _, o := range test.LaidOutObjects(top) {
+				if b, ok := o.(*widget.Button); ok && b.Text == "Securely delete 1" {
+					del = b
+				}
+			}
+		}
+	}
+	test.Tap(del)
+	for {
+		if _, err := os.Stat(orphan); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("leftover not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGUIIndexOutputFollowsIgnoreFile(t *testing.T) {
+	s, _ := newTestWindow(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".hadesignore"), []byte("*.log\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "report.txt"), []byte("report"), 0600)
+	os.WriteFile(filepath.Join(dir, "debug.log"), []byte("noise"), 0600)
+	s.index = &searchindex.Index{}
+	s.indexEnabled = true
+
+	// encryptions add from their own goroutines while the index may be locked
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() { defer wg.Done(); s.indexOutput(fmt.Sprintf("docs%d.hadescrypt", i), dir) }()
+	}
+	wg.Wait()
+	if got := s.index.Search("debug.log"); len(got) != 0 {
+		t.Errorf("ignored file indexed: %v", got)
+	}
+	if got := s.index.Search("report.txt"); len(got) != 4 {
+		t.Errorf("report.txt indexed in %d containers, want 4", len(got))
+	}
+	s.lockIndex()
+	s.indexOutput("late.hadescrypt", dir)
+	if !s.indexLocked() {
+		t.Error("indexOutput unlocked the index")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/store"
+	"github.com/bangundwir/HadesCrypt/internal/timefmt"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// records holds history, job runs and the audit log; nil when it could not be opened
+var records *store.Store
+
+// historyPageSize caps how many entries the History view lists at once
+const historyPageSize = 500
+
+// openRecords opens the record store and moves any history still kept in
+// config.json into it, rewriting config.json without it once that succeeded
+func openRecords(cfg *config.Config) {
+	st, err := store.OpenDefault()
+	if err != nil { fmt.Fprintln(os.Stderr, "warning: history store:", err); return }
+	records = st
+	if len(cfg.History) == 0 { return }
+	if _, err := st.ImportHistory(cfg.History); err != nil { fmt.Fprintln(os.Stderr, "warning: history migration:", err); return }
+	cfg.History = nil
+	cfg.Save()
+}
+
+// addHistory records one finished operation; a successful one's output joins the job summary
+func (s *AppState) addHistory(e config.HistoryEntry) {
+	if e.Result == "success" && e.Output != "" { s.addOutput(e.Output) }
+	if records == nil { return }
+	if err := records.AddHistory(e); err != nil { fmt.Fprintln(os.Stderr, "warning: history:", err) }
+}
+
+// recordAudit appends a security-relevant event to the audit log
+func recordAudit(event, detail string) {
+	if records == nil { return }
+	if err := records.Audit(event, detail); err != nil { fmt.Fprintln(os.Stderr, "warning: audit log:", err) }
+}
+
+// showHistoryDialog lists past operations, CLI job runs and the audit log, newest first
+func (s *AppState) showHistoryDialog(w fyne.Window) {
+	if records == nil {
+		dialog.ShowInformation("History", "The history store could not be opened; see the terminal output for details.", w)
+		return
+	}
+	const all = "All"
+	var entries []config.HistoryEntry
+	summary := widget.NewLabel("")
+	list := widget.NewList(
+		func() int { return len(entries) },
+		newOutputRow,
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(entries) { return }
+			e := entries[id]
+			line := e.Operation + "  " + e.FileName
+			if e.Size > 0 { line += "  (" + uiutil.HumanBytes(e.Size) + ")" }
+			if e.Result == "error" { line = "❌ " + line + ": " + e.Error }
+			setOutputRow(w, obj, timedLine{At: time.Unix(e.Timestamp, 0), Text: line}, e.Output)
+		},
+	)
+	query := widget.NewEntry()
+	query.SetPlaceHolder("File name or error…")
+	opSelect := widget.NewSelect([]string{all, "encrypt", "encrypt-folder", "decrypt"}, nil)
+	opSelect.SetSelected(all)
+	resultSelect := widget.NewSelect([]string{all, "success", "error"}, nil)
+	resultSelect.SetSelected(all)
+	refresh := func() {
+		f := store.HistoryFilter{Text: query.Text}
+		if opSelect.Selected != all { f.Operation = opSelect.Selected }
+		if resultSelect.Selected != all { f.Result = resultSelect.Selected }
+		var err error
+		if entries, err = records.History(f, historyPageSize); err != nil { summary.SetText("❌ " + err.Error()) } else if len(entries) == historyPageSize {
+			summary.SetText(fmt.Sprintf("Newest %d matches", historyPageSize))
+		} else {
+			summary.SetText(fmt.Sprintf("%d match(es)", len(entries)))
+		}
+		list.Refresh()
+	}
+	query.OnChanged = func(string) { refresh() }
+	opSelect.OnChanged = func(string) { refresh() }
+	resultSelect.OnChanged = func(string) { refresh() }
+	refresh()
+
+	clearBtn := widget.NewButton("Clear history", func() {
+		dialog.ShowConfirm("Clear history", "Remove every history entry? Job runs and the audit log are kept.", func(ok bool) {
+			if !ok { return }
+			if err := records.ClearHistory(); err != nil { dialog.ShowError(err, w); return }
+			recordAudit("history-cleared", "")
+			refresh()
+		}, w)
+	})
+	if s.viewer { clearBtn.Hide() }
+	historyTab := container.NewBorder(
+		container.NewVBox(query, container.NewHBox(widget.NewLabel("Operation:"), opSelect, widget.NewLabel("Result:"), resultSelect), summary),
+		container.NewHBox(clearBtn), nil, nil, list)
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Operations", historyTab),
+		container.NewTabItem("Job runs", recordLines(func() ([]timedLine, error) {
+			runs, err := records.JobRuns(historyPageSize)
+			var lines []timedLine
+			for _, r := range runs {
+				lines = append(lines, timedLine{At: time.Unix(r.Started, 0), Text: fmt.Sprintf("%s: %d succeeded, %d failed, %d skipped", r.File, r.Succeeded, r.Failed, r.Skipped)})
+				for _, l := range r.Lines { lines = append(lines, timedLine{Text: "      " + l}) }
+			}
+			return lines, err
+		})),
+		container.NewTabItem("Audit log", recordLines(func() ([]timedLine, error) {
+			log, err := records.AuditLog(historyPageSize)
+			var lines []timedLine
+			for _, e := range log {
+				line := e.Event
+				if e.Detail != "" { line += ": " + e.Detail }
+				lines = append(lines, timedLine{At: time.Unix(e.Timestamp, 0), Text: line})
+			}
+			return lines, err
+		})),
+	)
+	d := dialog.NewCustom("🕘 History", "Close", tabs, w)
+	d.Resize(fyne.NewSize(680, 480))
+	d.Show()
+}
+
+// timedLine is one History row; a zero At leaves the time column empty
+type timedLine struct {
+	At   time.Time
+	Text string
+}
+
+// recordLines is a read-only list of the lines load returns
+func recordLines(load func() ([]timedLine, error)) fyne.CanvasObject {
+	lines, err := load()
+	if err != nil { lines = []timedLine{{Text: "❌ " + err.Error()}} }
+	if len(lines) == 0 { lines = []timedLine{{Text: "Nothing recorded yet."}} }
+	return widget.NewList(
+		func() int { return len(lines) },
+		newTimedRow,
+		func(id widget.ListItemID, obj fyne.CanvasObject) { setTimedRow(obj, lines[id]) },
+	)
+}
+
+// newTimedRow creates a list row: when, then what
+func newTimedRow() fyne.CanvasObject {
+	return container.NewBorder(nil, nil, newTimeLabel(), nil, widget.NewLabel("entry"))
+}
+
+// setTimedRow fills a row made by newTimedRow
+func setTimedRow(obj fyne.CanvasObject, l timedLine) {
+	row := obj.(*fyne.Container)
+	row.Objects[1].(*timeLabel).SetTime(l.At)
+	row.Objects[0].(*widget.Label).SetText(l.Text)
+}
+
+// timeLabel shows how long ago something happened, and the full local date and
+// time in the user's locale while the pointer rests on it
+type timeLabel struct {
+	widget.Label
+	at time.Time
+}
+
+func newTimeLabel() *timeLabel {
+	l := &timeLabel{}
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+// SetTime shows t relative to now; the zero time shows nothing
+func (l *timeLabel) SetTime(t time.Time) {
+	l.at = t
+	l.MouseOut()
+}
+
+func (l *timeLabel) MouseIn(*desktop.MouseEvent) {
+	if l.at.IsZero() { return }
+	l.SetText(timefmt.Absolute(l.at, string(lang.SystemLocale())))
+}
+
+func (l *timeLabel) MouseMoved(*desktop.MouseEvent) {}
+
+func (l *timeLabel) MouseOut() {
+	if l.at.IsZero() { l.SetText(""); return }
+	l.SetText(timefmt.Relative(l.at, time.Now()))
+}
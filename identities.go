@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"rsc.io/qr"
+
+	"github.com/bangundwir/HadesCrypt/internal/contacts"
+	"github.com/bangundwir/HadesCrypt/internal/sshrecipient"
+)
+
+// encryptionKeysTab manages the own X25519 keypairs used to receive .age files
+func (s *AppState) encryptionKeysTab(w fyne.Window) fyne.CanvasObject {
+	dir, err := contacts.IdentitiesDir()
+	if err != nil { return widget.NewLabel("⚠️ " + err.Error()) }
+
+	var keys []*contacts.Keypair
+	selected := -1
+	reload := func() {
+		keys, err = contacts.ListKeypairs(dir)
+		if err != nil { dialog.ShowError(err, w) }
+	}
+	reload()
+
+	list := widget.NewList(
+		func() int { return len(keys) },
+		func() fyne.CanvasObject { return widget.NewLabel("key") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(keys) { return }
+			k := keys[id]
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s  •  %s  •  %s…", k.Name, k.Fingerprint(), k.Recipient[:16]))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) { selected = id }
+	refresh := func() { reload(); selected = -1; list.UnselectAll(); list.Refresh() }
+	current := func() *contacts.Keypair {
+		if selected < 0 || selected >= len(keys) { return nil }
+		return keys[selected]
+	}
+
+	generateBtn := widget.NewButton("Generate…", func() {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetPlaceHolder("e.g. personal")
+		passEntry := widget.NewPasswordEntry()
+		confirmEntry := widget.NewPasswordEntry()
+		dialog.ShowForm("Generate Encryption Key", "Generate", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Name", nameEntry),
+			widget.NewFormItem("Passphrase", passEntry),
+			widget.NewFormItem("Confirm", confirmEntry),
+		}, func(ok bool) {
+			if !ok { return }
+			if passEntry.Text != confirmEntry.Text { dialog.ShowError(errors.New("passphrases do not match"), w); return }
+			k, err := contacts.Generate(dir, strings.TrimSpace(nameEntry.Text), passEntry.Text)
+			if err != nil { dialog.ShowError(err, w); return }
+			refresh()
+			s.statusLabel.SetText("🔐 Encryption key " + k.Name + " generated (" + k.Fingerprint() + ")")
+		}, w)
+	})
+	copyBtn := widget.NewButton("Copy public key", func() {
+		k := current()
+		if k == nil { return }
+		w.Clipboard().SetContent(k.Recipient)
+		s.statusLabel.SetText("📋 Public key " + k.Name + " copied")
+	})
+	qrBtn := widget.NewButton("Show QR", func() {
+		if k := current(); k != nil { showKeyQR(w, k.Name, k.Recipient) }
+	})
+	deleteBtn := widget.NewButton("Delete", func() {
+		k := current()
+		if k == nil { return }
+		dialog.ShowConfirm("Delete Key", fmt.Sprintf("Delete encryption key %s?\nFiles encrypted to it can no longer be decrypted.", k.Name), func(ok bool) {
+			if !ok { return }
+			if err := contacts.DeleteKeypair(dir, k.Name); err != nil { dialog.ShowError(err, w); return }
+			refresh()
+		}, w)
+	})
+
+	hint := widget.NewLabel("X25519 keys in the age format. Share the public key (age1…); files encrypted to it under 🔑 SSH decrypt with the key's passphrase in the password field.")
+	hint.Wrapping = fyne.TextWrapWord
+	return container.NewBorder(hint, container.NewHBox(generateBtn, copyBtn, qrBtn, deleteBtn), nil, nil, list)
+}
+
+// contactsTab manages the contact book of recipients
+func (s *AppState) contactsTab(w fyne.Window) fyne.CanvasObject {
+	path, err := contacts.BookPath()
+	if err != nil { return widget.NewLabel("⚠️ " + err.Error()) }
+	book, err := contacts.LoadBook(path)
+	if err != nil { return widget.NewLabel("⚠️ " + err.Error()) }
+
+	selected := -1
+	list := widget.NewList(
+		func() int { return len(book.Contacts) },
+		func() fyne.CanvasObject { return widget.NewLabel("contact") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(book.Contacts) { return }
+			c := book.Contacts[id]
+			kind, _, _ := strings.Cut(c.Recipient, " ")
+			if strings.HasPrefix(kind, "age1") { kind = "X25519" }
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s  •  %s  •  %s", c.Name, c.Fingerprint(), kind))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) { selected = id }
+	current := func() *contacts.Contact {
+		if selected < 0 || selected >= len(book.Contacts) { return nil }
+		return &book.Contacts[selected]
+	}
+	save := func() {
+		if err := book.Save(); err != nil { dialog.ShowError(err, w) }
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	}
+
+	addBtn := widget.NewButton("Add…", func() {
+		nameEntry := widget.NewEntry()
+		keyEntry := widget.NewMultiLineEntry()
+		keyEntry.SetPlaceHolder("age1… or ssh-ed25519 AAAA…")
+		keyEntry.SetMinRowsVisible(3)
+		noteEntry := widget.NewEntry()
+		dialog.ShowForm("Add Contact", "Add", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Name", nameEntry),
+			widget.NewFormItem("Public key", keyEntry),
+			widget.NewFormItem("Note", noteEntry),
+		}, func(ok bool) {
+			if !ok { return }
+			c := contacts.Contact{Name: nameEntry.Text, Recipient: keyEntry.Text, Note: noteEntry.Text}
+			if err := book.Add(c, func(recipient string) error { _, err := sshrecipient.ParseRecipient(recipient); return err }); err != nil { dialog.ShowError(err, w); return }
+			save()
+		}, w)
+	})
+	copyBtn := widget.NewButton("Copy key", func() {
+		c := current()
+		if c == nil { return }
+		w.Clipboard().SetContent(c.Recipient)
+		s.statusLabel.SetText("📋 Key of " + c.Name + " copied")
+	})
+	qrBtn := widget.NewButton("Show QR", func() {
+		if c := current(); c != nil { showKeyQR(w, c.Name, c.Recipient) }
+	})
+	removeBtn := widget.NewButton("Remove", func() {
+		c := current()
+		if c == nil { return }
+		name := c.Name
+		dialog.ShowConfirm("Remove Contact", "Remove "+name+" from the contact book?", func(ok bool) {
+			if !ok { return }
+			book.Remove(name)
+			save()
+		}, w)
+	})
+
+	hint := widget.NewLabel("People you encrypt to. Compare fingerprints with the owner before trusting a key; pick contacts under 🔑 SSH.")
+	hint.Wrapping = fyne.TextWrapWord
+	return container.NewBorder(hint, container.NewHBox(addBtn, copyBtn, qrBtn, removeBtn), nil, nil, list)
+}
+
+// showKeyQR shows a public key as a QR code with its fingerprint for verification
+func showKeyQR(w fyne.Window, name, key string) {
+	code, err := qr.Encode(key, qr.M)
+	if err != nil { dialog.ShowError(fmt.Errorf("QR code: %w", err), w); return }
+	img := canvas.NewImageFromImage(code.Image())
+	img.FillMode = canvas.ImageFillContain
+	img.ScaleMode = canvas.ImageScalePixels
+	img.SetMinSize(fyne.NewSize(280, 280))
+	fp := widget.NewLabelWithStyle("Fingerprint: "+contacts.Fingerprint(key), fyne.TextAlignCenter, fyne.TextStyle{Monospace: true})
+	dialog.ShowCustom(name, "Close", container.NewVBox(img, fp), w)
+}
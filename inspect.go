@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/pgppacket"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// showInspectDialog lists the OpenPGP packets of the selected .gpg/.pgp/.asc file
+// without decrypting it, so the user sees what was received before entering a password
+func (s *AppState) showInspectDialog(w fyne.Window) {
+	path := s.selectedPath
+	if path == "" || s.isHadesCryptFile(path) {
+		dialog.ShowInformation("Inspect", "Select a single OpenPGP file (.gpg, .pgp or .asc). HadesCrypt containers show their details below the selection.", w)
+		return
+	}
+	msg, err := pgppacket.ParseFile(path)
+	if err != nil { dialog.ShowError(fmt.Errorf("%s: %w", filepath.Base(path), err), w); return }
+	text := widget.NewLabel(packetReport(msg))
+	text.TextStyle = fyne.TextStyle{Monospace: true}
+	text.Wrapping = fyne.TextWrapWord
+	note := widget.NewLabel("Read without decrypting: only what OpenPGP leaves in the clear is shown. The name of an encrypted file is inside the ciphertext.")
+	note.Wrapping = fyne.TextWrapWord
+	scroll := container.NewVScroll(text)
+	scroll.SetMinSize(fyne.NewSize(560, 360))
+	dialog.NewCustom("🔍 Inspect — "+filepath.Base(path), "Close", container.NewBorder(nil, note, nil, nil, scroll), w).Show()
+}
+
+// packetReport is the Inspect dialog text: the encoding, then one block per packet
+func packetReport(msg *pgppacket.Message) string {
+	var b strings.Builder
+	if msg.Armored {
+		fmt.Fprintf(&b, "ASCII armored (%s)\n", msg.ArmorType)
+		for _, h := range msg.ArmorHeaders { fmt.Fprintf(&b, "  %s\n", h) }
+	} else {
+		b.WriteString("Binary\n")
+	}
+	for _, p := range msg.Packets {
+		indent := strings.Repeat("    ", p.Depth)
+		size := "streamed"
+		if p.Length >= 0 { size = uiutil.HumanBytes(p.Length) }
+		fmt.Fprintf(&b, "\n%s• %s (%s)\n", indent, p.Name, size)
+		for _, d := range p.Details { fmt.Fprintf(&b, "%s    %s\n", indent, d) }
+		if p.Warning != "" { fmt.Fprintf(&b, "%s    ⚠️ %s\n", indent, p.Warning) }
+	}
+	return b.String()
+}
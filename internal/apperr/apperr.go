@@ -0,0 +1,151 @@
+// Package apperr defines the error taxonomy shared by the GUI and the CLI:
+// stable string codes for reports and stable process exit codes for scripts.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+// Code is a stable, machine-readable failure class
+type Code string
+
+// Codes are part of the CLI contract; never renumber or rename them
+const (
+	OK             Code = "ok"
+	Internal       Code = "internal"
+	Usage          Code = "usage"
+	WrongPassword  Code = "wrong_password"
+	CorruptFile    Code = "corrupt_file"
+	IOError        Code = "io_error"
+	Canceled       Code = "canceled"
+	PartialSuccess Code = "partial_success"
+	Unsupported    Code = "unsupported"
+)
+
+var exitCodes = map[Code]int{
+	OK:             0,
+	Internal:       1,
+	Usage:          2,
+	WrongPassword:  3,
+	CorruptFile:    4,
+	IOError:        5,
+	Canceled:       6,
+	PartialSuccess: 7,
+	Unsupported:    8,
+}
+
+var descriptions = map[Code]string{
+	OK:             "completed successfully",
+	Internal:       "unexpected error",
+	Usage:          "invalid arguments or job file",
+	WrongPassword:  "wrong password or keyfile",
+	CorruptFile:    "file is corrupted, truncated or not a HadesCrypt file",
+	IOError:        "file could not be read or written",
+	Canceled:       "operation was canceled",
+	PartialSuccess: "some items failed",
+	Unsupported:    "format or mode not supported by this build",
+}
+
+// ExitCode returns the process exit code for c
+func (c Code) ExitCode() int {
+	if n, ok := exitCodes[c]; ok {
+		return n
+	}
+	return exitCodes[Internal]
+}
+
+// Description returns a short human-readable explanation of c
+func (c Code) Description() string {
+	if d, ok := descriptions[c]; ok {
+		return d
+	}
+	return descriptions[Internal]
+}
+
+// ErrCanceled is returned when the user or a signal stops an operation
+var ErrCanceled = errors.New("canceled")
+
+// Error attaches a code to an underlying error
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Code.Description()
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Wrap tags err with code; a nil err stays nil
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Classify maps err to a code. Explicit *Error tags win, then known sentinels.
+func Classify(err error) Code {
+	if err == nil {
+		return OK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	switch {
+	case errors.Is(err, ErrCanceled), errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.Is(err, cryptoengine.ErrAuthFailed), errors.Is(err, cryptoengine.ErrKEMKeyMissing):
+		return WrongPassword
+	case errors.Is(err, cryptoengine.ErrCorrupt), errors.Is(err, cryptoengine.ErrNotContainer):
+		return CorruptFile
+	case errors.Is(err, cryptoengine.ErrUnsupported):
+		return Unsupported
+	}
+	var pe *fs.PathError
+	if errors.As(err, &pe) || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return IOError
+	}
+	return Internal
+}
+
+// ExitCode returns the process exit code for err
+func ExitCode(err error) int { return Classify(err).ExitCode() }
+
+// Combine reduces per-item codes to one: all OK is OK, a mix of successes and
+// failures is PartialSuccess, and uniform failures keep their shared code.
+func Combine(codes []Code) Code {
+	if len(codes) == 0 {
+		return OK
+	}
+	succeeded, first, uniform := 0, Code(""), true
+	for _, c := range codes {
+		if c == OK {
+			succeeded++
+			continue
+		}
+		if first == "" {
+			first = c
+		} else if c != first {
+			uniform = false
+		}
+	}
+	switch {
+	case first == "":
+		return OK
+	case succeeded > 0:
+		return PartialSuccess
+	case uniform:
+		return first
+	}
+	return Internal
+}
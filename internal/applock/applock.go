@@ -0,0 +1,44 @@
+package applock
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters for the master password verifier
+const (
+	argonTime    = uint32(2)
+	argonMemory  = uint32(64 * 1024)
+	argonThreads = uint8(4)
+	hashLen      = uint32(32)
+	saltLen      = 16
+)
+
+// Hash derives a verifier for password. Both results are base64 encoded for the config file.
+func Hash(password string) (hash, salt string, err error) {
+	s := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, s); err != nil {
+		return "", "", fmt.Errorf("generate salt: %w", err)
+	}
+	h := argon2.IDKey([]byte(password), s, argonTime, argonMemory, argonThreads, hashLen)
+	return base64.StdEncoding.EncodeToString(h), base64.StdEncoding.EncodeToString(s), nil
+}
+
+// Verify reports whether password matches a verifier produced by Hash
+func Verify(password, hash, salt string) bool {
+	want, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil {
+		return false
+	}
+	s, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return false
+	}
+	got := argon2.IDKey([]byte(password), s, argonTime, argonMemory, argonThreads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
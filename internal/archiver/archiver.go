@@ -2,21 +2,35 @@ package archiver
 
 import (
 	"archive/tar"
+	"bufio"
 	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"bytes"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+	"github.com/bangundwir/HadesCrypt/internal/sparse"
 )
 
 // ProgressCallback reports processed and total bytes during archiving
 type ProgressCallback func(processed int64, total int64)
 
-// CreateTarGz creates a compressed tar archive from a directory
+// copyBufferSize is the read and write buffer size; large buffers keep network
+// shares and USB sticks from being hammered with small requests
+const copyBufferSize = 1 << 20
+
+// CreateTarGz creates a compressed tar archive from a directory; symbolic links are left out
 func CreateTarGz(sourceDir, targetFile string, onProgress ProgressCallback) error {
+	return CreateTarGzWithOptions(sourceDir, targetFile, fswalk.Options{}, onProgress)
+}
+
+// CreateTarGzWithOptions is CreateTarGz with an exclusion filter and symlink policy
+func CreateTarGzWithOptions(sourceDir, targetFile string, opts fswalk.Options, onProgress ProgressCallback) error {
 	// Calculate total size first for progress reporting
-	totalSize, err := calculateDirSize(sourceDir)
+	totalSize, err := calculateDirSize(sourceDir, opts)
 	if err != nil {
 		return fmt.Errorf("calculate directory size: %w", err)
 	}
@@ -29,7 +43,8 @@ func CreateTarGz(sourceDir, targetFile string, onProgress ProgressCallback) erro
 	defer file.Close()
 
 	// Create gzip writer
-	gzipWriter := gzip.NewWriter(file)
+	buffered := bufio.NewWriterSize(file, copyBufferSize)
+	gzipWriter := gzip.NewWriter(buffered)
 	defer gzipWriter.Close()
 
 	// Create tar writer
@@ -37,15 +52,27 @@ func CreateTarGz(sourceDir, targetFile string, onProgress ProgressCallback) erro
 	defer tarWriter.Close()
 
 	processed := int64(0)
+	buf := make([]byte, copyBufferSize)
+	hardLinks := map[fileKey]string{} // file identity → first archived name
 
 	// Walk through the source directory
-	err = filepath.Walk(sourceDir, func(filePath string, fileInfo os.FileInfo, err error) error {
+	err = fswalk.Walk(sourceDir, opts, func(filePath string, fileInfo os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
 		// Create tar header
-		header, err := tar.FileInfoHeader(fileInfo, "")
+		link := ""
+		if fswalk.IsLink(fileInfo) {
+			if link, err = os.Readlink(filePath); err != nil {
+				return fmt.Errorf("read link %s: %w", filePath, err)
+			}
+			// Extraction refuses such links, so fail now rather than at decryption time
+			if !linkStaysInside(sourceDir, filePath, link) {
+				return fmt.Errorf("link %s -> %s points outside the folder; choose Skip or Follow for symlinks", filePath, link)
+			}
+		}
+		header, err := tar.FileInfoHeader(fileInfo, link)
 		if err != nil {
 			return fmt.Errorf("create tar header for %s: %w", filePath, err)
 		}
@@ -57,49 +84,109 @@ func CreateTarGz(sourceDir, targetFile string, onProgress ProgressCallback) erro
 		}
 		header.Name = filepath.ToSlash(relPath)
 
-		// Write header
-		if err := tarWriter.WriteHeader(header); err != nil {
-			return fmt.Errorf("write tar header: %w", err)
+		if !fileInfo.Mode().IsRegular() {
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return fmt.Errorf("write tar header: %w", err)
+			}
+			return nil
 		}
 
-		// If it's a regular file, write its content
-		if fileInfo.Mode().IsRegular() {
-			srcFile, err := os.Open(filePath)
-			if err != nil {
-				return fmt.Errorf("open source file %s: %w", filePath, err)
+		// Further names of a hard-linked file become link entries instead of copies
+		if key, ok := hardLinkKey(filePath, fileInfo); ok {
+			if first, seen := hardLinks[key]; seen {
+				header.Typeflag, header.Linkname, header.Size = tar.TypeLink, first, 0
+				if err := tarWriter.WriteHeader(header); err != nil {
+					return fmt.Errorf("write tar header: %w", err)
+				}
+				processed += fileInfo.Size()
+				if onProgress != nil {
+					onProgress(processed, totalSize)
+				}
+				return nil
 			}
-			defer srcFile.Close()
+			hardLinks[key] = header.Name
+		}
 
-			// Copy file content with progress reporting
-			buf := make([]byte, 32*1024) // 32KB buffer
-			for {
-				n, err := srcFile.Read(buf)
-				if n > 0 {
-					if _, writeErr := tarWriter.Write(buf[:n]); writeErr != nil {
-						return fmt.Errorf("write to tar: %w", writeErr)
-					}
-					processed += int64(n)
-					if onProgress != nil {
-						onProgress(processed, totalSize)
-					}
+		srcFile, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("open source file %s: %w", filePath, err)
+		}
+		defer srcFile.Close()
+
+		// Files with holes (disk images, VM disks) are stored sparsely
+		if extents, err := sparse.DataExtents(srcFile, fileInfo.Size()); err == nil && sparse.IsSparse(extents, fileInfo.Size()) {
+			if err := tarWriter.Flush(); err != nil {
+				return fmt.Errorf("write to tar: %w", err)
+			}
+			start := processed
+			err := writeSparseEntry(gzipWriter, header.Name, fileInfo, srcFile, extents, buf, func(n int64) {
+				processed += n
+				if onProgress != nil {
+					onProgress(processed, totalSize)
 				}
-				if err == io.EOF {
-					break
+			})
+			if err != nil {
+				return fmt.Errorf("write sparse entry %s: %w", header.Name, err)
+			}
+			processed = start + fileInfo.Size() // holes count as done
+			if onProgress != nil {
+				onProgress(processed, totalSize)
+			}
+			return nil
+		}
+
+		// Write header
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("write tar header: %w", err)
+		}
+
+		// Copy file content with progress reporting
+		for {
+			n, err := srcFile.Read(buf)
+			if n > 0 {
+				if _, writeErr := tarWriter.Write(buf[:n]); writeErr != nil {
+					return fmt.Errorf("write to tar: %w", writeErr)
 				}
-				if err != nil {
-					return fmt.Errorf("read from source file: %w", err)
+				processed += int64(n)
+				if onProgress != nil {
+					onProgress(processed, totalSize)
 				}
 			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("read from source file: %w", err)
+			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Close explicitly so a failed final write (e.g. a dropped network share) is reported
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("finish tar: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("finish gzip: %w", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return file.Close()
 }
 
 // ExtractTarGz extracts a compressed tar archive to a directory
 func ExtractTarGz(sourceFile, targetDir string, onProgress ProgressCallback) error {
+	return ExtractTarGzWithLimits(sourceFile, targetDir, Limits{}, onProgress)
+}
+
+// ExtractTarGzWithLimits is ExtractTarGz stopping with ErrLimit once the archive
+// expands beyond limits (unless limits.Continue lifts them)
+func ExtractTarGzWithLimits(sourceFile, targetDir string, limits Limits, onProgress ProgressCallback) error {
 	// Open the source file
 	file, err := os.Open(sourceFile)
 	if err != nil {
@@ -112,10 +199,17 @@ func ExtractTarGz(sourceFile, targetDir string, onProgress ProgressCallback) err
 	if err != nil {
 		return fmt.Errorf("stat source file: %w", err)
 	}
-	totalSize := fileInfo.Size()
+	return ExtractTarGzFrom(file, fileInfo.Size(), targetDir, limits, onProgress)
+}
 
-	// Create gzip reader
-	gzipReader, err := gzip.NewReader(file)
+// ExtractTarGzFrom extracts the compressed tar archive read from r, which is
+// totalSize bytes long (-1 if unknown), so an archive can be unpacked as it is
+// decrypted or downloaded. Reading may stop past the end of the tar stream
+// but before the end of r.
+func ExtractTarGzFrom(r io.Reader, totalSize int64, targetDir string, limits Limits, onProgress ProgressCallback) error {
+	// Progress counts compressed bytes consumed so it matches totalSize
+	counter := &countingReader{r: bufio.NewReaderSize(r, copyBufferSize)}
+	gzipReader, err := gzip.NewReader(counter)
 	if err != nil {
 		return fmt.Errorf("create gzip reader: %w", err)
 	}
@@ -123,8 +217,10 @@ func ExtractTarGz(sourceFile, targetDir string, onProgress ProgressCallback) err
 
 	// Create tar reader
 	tarReader := tar.NewReader(gzipReader)
-
-	processed := int64(0)
+	buf := make([]byte, copyBufferSize)
+	g := &guard{limits: limits, root: targetDir}
+	// Links are created after everything else so no file is ever written through one
+	var links []*tar.Header
 
 	// Extract files
 	for {
@@ -135,6 +231,9 @@ func ExtractTarGz(sourceFile, targetDir string, onProgress ProgressCallback) err
 		if err != nil {
 			return fmt.Errorf("read tar header: %w", err)
 		}
+		if err := g.check(header); err != nil {
+			return err
+		}
 
 		targetPath := filepath.Join(targetDir, header.Name)
 
@@ -149,25 +248,30 @@ func ExtractTarGz(sourceFile, targetDir string, onProgress ProgressCallback) err
 			if err := os.MkdirAll(targetPath, os.FileMode(header.Mode)); err != nil {
 				return fmt.Errorf("create directory %s: %w", targetPath, err)
 			}
-		case tar.TypeReg:
+		case tar.TypeReg, tar.TypeGNUSparse:
 			// Create regular file
 			targetFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
 			if err != nil {
 				return fmt.Errorf("create target file %s: %w", targetPath, err)
 			}
+			// Sparse entries are restored with holes again
+			var out io.Writer = targetFile
+			var holes *sparse.Writer
+			if isSparseEntry(header) {
+				holes = sparse.NewWriter(targetFile)
+				out = holes
+			}
 
 			// Copy file content with progress reporting
-			buf := make([]byte, 32*1024) // 32KB buffer
 			for {
 				n, err := tarReader.Read(buf)
 				if n > 0 {
-					if _, writeErr := targetFile.Write(buf[:n]); writeErr != nil {
+					if _, writeErr := out.Write(buf[:n]); writeErr != nil {
 						targetFile.Close()
 						return fmt.Errorf("write to target file: %w", writeErr)
 					}
-					processed += int64(n)
 					if onProgress != nil {
-						onProgress(processed, totalSize)
+						onProgress(counter.n, totalSize)
 					}
 				}
 				if err == io.EOF {
@@ -178,18 +282,113 @@ func ExtractTarGz(sourceFile, targetDir string, onProgress ProgressCallback) err
 					return fmt.Errorf("read from tar: %w", err)
 				}
 			}
+			if holes != nil {
+				if err := holes.Finish(); err != nil {
+					targetFile.Close()
+					return fmt.Errorf("write to target file: %w", err)
+				}
+			}
 			targetFile.Close()
+		case tar.TypeLink:
+			if err := restoreHardLink(targetDir, targetPath, header.Linkname); err != nil {
+				return fmt.Errorf("create hard link %s: %w", targetPath, err)
+			}
+		case tar.TypeSymlink:
+			links = append(links, header)
+		}
+	}
+
+	for _, header := range links {
+		targetPath := filepath.Join(targetDir, header.Name)
+		if !linkStaysInside(targetDir, targetPath, header.Linkname) {
+			return fmt.Errorf("refusing link %s -> %s: points outside the archive", header.Name, header.Linkname)
+		}
+		if err := os.Symlink(header.Linkname, targetPath); err != nil {
+			return fmt.Errorf("create link %s: %w", targetPath, err)
+		}
+	}
+	// A chain of links can still climb out even when each one looks local
+	if len(links) > 0 {
+		realRoot, err := filepath.EvalSymlinks(targetDir)
+		if err != nil {
+			return fmt.Errorf("resolve target directory: %w", err)
+		}
+		for _, header := range links {
+			targetPath := filepath.Join(targetDir, header.Name)
+			resolved, err := filepath.EvalSymlinks(targetPath)
+			if err == nil && !within(realRoot, resolved) {
+				os.Remove(targetPath)
+				return fmt.Errorf("refusing link %s -> %s: resolves outside the archive", header.Name, header.Linkname)
+			}
 		}
 	}
 
 	return nil
 }
 
+// restoreHardLink links targetPath to the already extracted linkname, copying
+// the file instead where the file system has no hard links (FAT, some shares)
+func restoreHardLink(targetDir, targetPath, linkname string) error {
+	source := filepath.Join(targetDir, filepath.FromSlash(linkname))
+	if !within(targetDir, source) {
+		return fmt.Errorf("link target %s is outside the archive", linkname)
+	}
+	os.Remove(targetPath) // never write through an existing name
+	if err := os.Link(source, targetPath); err == nil {
+		return nil
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// isSparseEntry reports whether a tar entry was stored as a sparse file
+func isSparseEntry(h *tar.Header) bool {
+	if h.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for k := range h.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// linkStaysInside reports whether a relative link at linkPath resolves within root
+func linkStaysInside(root, linkPath, target string) bool {
+	if target == "" || filepath.IsAbs(target) || filepath.VolumeName(target) != "" {
+		return false
+	}
+	return within(root, filepath.Join(filepath.Dir(linkPath), target))
+}
+
+// within reports whether path is root or below it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // calculateDirSize calculates the total size of all files in a directory
-func calculateDirSize(dirPath string) (int64, error) {
+func calculateDirSize(dirPath string, opts fswalk.Options) (int64, error) {
 	var totalSize int64
 
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+	err := fswalk.Walk(dirPath, opts, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -207,13 +406,18 @@ func IsArchive(filename string) bool {
 	f, err := os.Open(filename)
 	if err != nil { return false }
 	defer f.Close()
+	return IsTarGz(f)
+}
+
+// IsTarGz reports whether r starts like a tar.gz archive; only the first
+// compressed bytes up to the first tar header are read
+func IsTarGz(r io.Reader) bool {
+	br := bufio.NewReader(r)
 	// Read first few bytes for gzip magic 1F 8B
-	hdr := make([]byte, 3)
-	if _, err := io.ReadFull(f, hdr); err != nil { return false }
+	hdr, err := br.Peek(3)
+	if err != nil { return false }
 	if hdr[0] != 0x1F || hdr[1] != 0x8B { return false }
-	// Reset and try to create gzip reader
-	f.Seek(0,0)
-	gz, err := gzip.NewReader(f)
+	gz, err := gzip.NewReader(br)
 	if err != nil { return false }
 	defer gz.Close()
 	// Read first tar header block (512 bytes)
@@ -225,3 +429,15 @@ func IsArchive(filename string) bool {
 	}
 	return false
 }
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package archiver
+
+import "os"
+
+type fileKey struct{}
+
+func hardLinkKey(string, os.FileInfo) (fileKey, bool) { return fileKey{}, false }
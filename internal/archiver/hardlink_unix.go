@@ -0,0 +1,22 @@
+//go:build unix
+
+package archiver
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileKey identifies a file independent of its name
+type fileKey struct {
+	dev, ino uint64
+}
+
+// hardLinkKey returns the identity of a regular file that has further hard links
+func hardLinkKey(_ string, info os.FileInfo) (fileKey, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Nlink < 2 {
+		return fileKey{}, false
+	}
+	return fileKey{uint64(st.Dev), uint64(st.Ino)}, true
+}
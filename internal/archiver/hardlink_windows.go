@@ -0,0 +1,34 @@
+//go:build windows
+
+package archiver
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// fileKey identifies a file independent of its name
+type fileKey struct {
+	volume    uint32
+	indexHigh uint32
+	indexLow  uint32
+}
+
+// hardLinkKey returns the identity of a regular file that has further hard links
+func hardLinkKey(path string, _ os.FileInfo) (fileKey, bool) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return fileKey{}, false
+	}
+	h, err := windows.CreateFile(p, 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return fileKey{}, false
+	}
+	defer windows.CloseHandle(h)
+	var fi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &fi); err != nil || fi.NumberOfLinks < 2 {
+		return fileKey{}, false
+	}
+	return fileKey{fi.VolumeSerialNumber, fi.FileIndexHigh, fi.FileIndexLow}, true
+}
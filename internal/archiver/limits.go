@@ -0,0 +1,78 @@
+package archiver
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// ErrLimit is returned when an extraction stops at one of its Limits
+var ErrLimit = errors.New("archive exceeds the extraction limit")
+
+// Limits bounds what an extraction may write, so a tiny archive that expands
+// to terabytes or millions of files (a decompression bomb) stops early
+type Limits struct {
+	MaxBytes int64 // total size of the extracted files; 0 means unlimited
+	MaxFiles int   // number of extracted entries; 0 means unlimited
+	// Continue is asked before an entry would cross a bound; returning true
+	// lifts that bound for the rest of the archive. Nil stops at the bound.
+	Continue func(Exceeded) bool
+}
+
+// Exceeded describes the bound an entry would cross
+type Exceeded struct {
+	Files     bool   // the file count, not the total size, reached its bound
+	Limit     int64  // the bound: bytes or entries
+	Entry     string // name of the entry that would cross it
+	EntrySize int64
+}
+
+func (e Exceeded) String() string {
+	if e.Files {
+		return fmt.Sprintf("more than %d files (at %s)", e.Limit, e.Entry)
+	}
+	return fmt.Sprintf("more than %d bytes (at %s, %d bytes)", e.Limit, e.Entry, e.EntrySize)
+}
+
+// guard applies Limits and the per-entry sanity checks to each tar header
+type guard struct {
+	limits Limits
+	root   string
+	bytes  int64
+	files  int
+}
+
+// check runs before an entry is written; it fails entries that are malformed or
+// escape the target directory whether or not limits are set
+func (g *guard) check(h *tar.Header) error {
+	if h.Size < 0 {
+		return fmt.Errorf("entry %s has negative size %d", h.Name, h.Size)
+	}
+	if filepath.IsAbs(h.Name) || filepath.VolumeName(h.Name) != "" || !within(g.root, filepath.Join(g.root, h.Name)) {
+		return fmt.Errorf("refusing entry %s: path leaves the archive", h.Name)
+	}
+	g.files++
+	if g.limits.MaxFiles > 0 && g.files > g.limits.MaxFiles {
+		if !g.ask(Exceeded{Files: true, Limit: int64(g.limits.MaxFiles), Entry: h.Name}) {
+			return fmt.Errorf("%w: more than %d files", ErrLimit, g.limits.MaxFiles)
+		}
+		g.limits.MaxFiles = 0
+	}
+	if h.Typeflag != tar.TypeReg && h.Typeflag != tar.TypeGNUSparse {
+		return nil
+	}
+	// Compare with what is left so a huge size cannot overflow the running total
+	if g.limits.MaxBytes > 0 && h.Size > g.limits.MaxBytes-g.bytes {
+		if !g.ask(Exceeded{Limit: g.limits.MaxBytes, Entry: h.Name, EntrySize: h.Size}) {
+			return fmt.Errorf("%w: more than %d bytes", ErrLimit, g.limits.MaxBytes)
+		}
+		g.limits.MaxBytes = 0
+	}
+	g.bytes += h.Size
+	return nil
+}
+
+func (g *guard) ask(e Exceeded) bool {
+	return g.limits.Continue != nil && g.limits.Continue(e)
+}
@@ -0,0 +1,95 @@
+package archiver
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testEntry struct {
+	name string
+	size int64
+}
+
+// writeTestArchive writes entries of zero bytes, which gzip shrinks to almost nothing
+func writeTestArchive(t *testing.T, entries ...testEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	zeros := make([]byte, 64<<10)
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Typeflag: tar.TypeReg, Mode: 0600, Size: e.size}); err != nil {
+			t.Fatal(err)
+		}
+		for left := e.size; left > 0; {
+			n := min(left, int64(len(zeros)))
+			if _, err := tw.Write(zeros[:n]); err != nil {
+				t.Fatal(err)
+			}
+			left -= n
+		}
+	}
+	for _, c := range []interface{ Close() error }{tw, gz, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestExtractStopsAtByteLimit(t *testing.T) {
+	archive := writeTestArchive(t, testEntry{"a", 1 << 20}, testEntry{"b", 8 << 20})
+	out := t.TempDir()
+	var asked []Exceeded
+	limits := Limits{MaxBytes: 4 << 20, Continue: func(e Exceeded) bool { asked = append(asked, e); return false }}
+	err := ExtractTarGzWithLimits(archive, out, limits, nil)
+	if !errors.Is(err, ErrLimit) {
+		t.Fatalf("err = %v, want ErrLimit", err)
+	}
+	if len(asked) != 1 || asked[0].Files || asked[0].Entry != "b" || asked[0].EntrySize != 8<<20 {
+		t.Fatalf("asked %+v", asked)
+	}
+	if _, err := os.Stat(filepath.Join(out, "b")); !os.IsNotExist(err) {
+		t.Fatalf("entry past the limit was written: %v", err)
+	}
+}
+
+func TestExtractContinuesPastLimit(t *testing.T) {
+	archive := writeTestArchive(t, testEntry{"a", 10}, testEntry{"b", 10}, testEntry{"c", 10})
+	out := t.TempDir()
+	calls := 0
+	limits := Limits{MaxFiles: 1, Continue: func(e Exceeded) bool { calls++; return e.Files }}
+	if err := ExtractTarGzWithLimits(archive, out, limits, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("Continue called %d times, want once", calls)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExtractRefusesEscapingEntry(t *testing.T) {
+	archive := writeTestArchive(t, testEntry{"../escaped", 1})
+	parent := t.TempDir()
+	out := filepath.Join(parent, "out")
+	err := ExtractTarGz(archive, out, nil)
+	if err == nil || !strings.Contains(err.Error(), "leaves the archive") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "escaped")); !os.IsNotExist(err) {
+		t.Fatal("entry was written outside the target directory")
+	}
+}
@@ -0,0 +1,140 @@
+package archiver
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/sparse"
+)
+
+// archive/tar reads sparse entries but cannot write them, so sparse files are
+// emitted by hand in the PAX 1.0 sparse format GNU tar uses: an extended
+// header carrying GNU.sparse.* records, then a regular entry whose data is
+// the sparse map followed by the data extents.
+
+const blockSize = 512
+
+// writeSparseEntry writes a sparse file entry to w, which must be positioned
+// on a tar block boundary (i.e. right after tar.Writer.Flush)
+func writeSparseEntry(w io.Writer, name string, info os.FileInfo, f *os.File, extents []sparse.Extent, buf []byte, onData func(n int64)) error {
+	// Sparse map: entry count, then offset/length pairs, one number per line
+	var m strings.Builder
+	entries := extents
+	if n := len(entries); n == 0 || entries[n-1].Offset+entries[n-1].Length < info.Size() {
+		// GNU tar only restores a trailing hole when the map ends with an empty extent
+		entries = append(entries[:n:n], sparse.Extent{Offset: info.Size(), Length: 0})
+	}
+	fmt.Fprintf(&m, "%d\n", len(entries))
+	var dataLen int64
+	for _, e := range entries {
+		fmt.Fprintf(&m, "%d\n%d\n", e.Offset, e.Length)
+		dataLen += e.Length
+	}
+	sparseMap := padBlock([]byte(m.String()))
+	size := int64(len(sparseMap)) + dataLen
+
+	records := paxRecord("GNU.sparse.major", "1") + paxRecord("GNU.sparse.minor", "0") +
+		paxRecord("GNU.sparse.name", name) + paxRecord("GNU.sparse.realsize", strconv.FormatInt(info.Size(), 10)) +
+		paxRecord("size", strconv.FormatInt(size, 10)) + paxRecord("mtime", strconv.FormatInt(info.ModTime().Unix(), 10))
+
+	dir, file := path.Split(name)
+	stored := path.Join(dir, "GNUSparseFile.0", file)
+	mode := int64(info.Mode().Perm())
+	mtime := info.ModTime().Unix()
+	if _, err := w.Write(ustarHeader(path.Join(dir, "PaxHeaders.0", file), 'x', int64(len(records)), mode, mtime)); err != nil {
+		return err
+	}
+	if _, err := w.Write(padBlock([]byte(records))); err != nil {
+		return err
+	}
+	if _, err := w.Write(ustarHeader(stored, '0', size, mode, mtime)); err != nil {
+		return err
+	}
+	if _, err := w.Write(sparseMap); err != nil {
+		return err
+	}
+
+	written := int64(0)
+	for _, e := range extents {
+		if _, err := f.Seek(e.Offset, io.SeekStart); err != nil {
+			return fmt.Errorf("seek %s: %w", name, err)
+		}
+		n, err := io.CopyBuffer(w, io.LimitReader(f, e.Length), buf)
+		written += n
+		if onData != nil {
+			onData(n)
+		}
+		if err != nil {
+			return fmt.Errorf("copy %s: %w", name, err)
+		}
+		if n != e.Length {
+			return fmt.Errorf("copy %s: file shrank while archiving", name)
+		}
+	}
+	if rem := written % blockSize; rem != 0 {
+		if _, err := w.Write(make([]byte, blockSize-rem)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// paxRecord formats "<len> key=value\n" where len counts the whole record
+func paxRecord(k, v string) string {
+	body := " " + k + "=" + v + "\n"
+	n := len(body) + 1
+	for n != len(strconv.Itoa(n))+len(body) {
+		n = len(strconv.Itoa(n)) + len(body)
+	}
+	return strconv.Itoa(n) + body
+}
+
+// ustarHeader encodes a minimal ustar header block. The name field is cut to
+// fit; readers take the real name from the GNU.sparse.name record.
+func ustarHeader(name string, typeflag byte, size, mode, mtime int64) []byte {
+	b := make([]byte, blockSize)
+	if len(name) > 99 {
+		name = name[len(name)-99:]
+	}
+	copy(b[0:100], name)
+	octal(b[100:108], mode)
+	octal(b[108:116], 0) // uid
+	octal(b[116:124], 0) // gid
+	if size < 1<<33 {
+		octal(b[124:136], size)
+	} else {
+		octal(b[124:136], 0) // the PAX size record carries it
+	}
+	octal(b[136:148], mtime)
+	b[156] = typeflag
+	copy(b[257:263], "ustar\x00")
+	copy(b[263:265], "00")
+
+	// Checksum is computed with its own field set to spaces
+	copy(b[148:156], "        ")
+	var sum int64
+	for _, c := range b {
+		sum += int64(c)
+	}
+	copy(b[148:156], fmt.Sprintf("%06o\x00 ", sum))
+	return b
+}
+
+// octal writes v as a NUL-terminated, zero-padded octal field
+func octal(field []byte, v int64) {
+	s := strconv.FormatInt(v, 8)
+	s = strings.Repeat("0", len(field)-1-len(s)) + s
+	copy(field, s)
+	field[len(field)-1] = 0
+}
+
+func padBlock(b []byte) []byte {
+	if rem := len(b) % blockSize; rem != 0 {
+		b = append(b, make([]byte, blockSize-rem)...)
+	}
+	return b
+}
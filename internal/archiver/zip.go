@@ -0,0 +1,150 @@
+package archiver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotTarGz is returned by TarGzToZip when the stream is not gzip-compressed
+var ErrNotTarGz = errors.New("not a tar.gz archive")
+
+// TarGzToZip rewrites the tar.gz stream returned by open as a zip archive on w,
+// entry by entry, without extracting anything. Zip has no hard links, so every
+// hard link is stored as a copy of its target; those copies need a second read
+// of the archive, and open is called again only when the archive has links.
+func TarGzToZip(open func() (io.ReadCloser, error), w io.Writer) error {
+	bw := bufio.NewWriterSize(w, copyBufferSize)
+	zw := zip.NewWriter(bw)
+	links := map[string][]*tar.Header{} // target name → links to it
+	err := eachTarEntry(open, func(h *tar.Header, r io.Reader) error {
+		if h.Typeflag == tar.TypeLink {
+			target := path.Clean(h.Linkname)
+			links[target] = append(links[target], h)
+			return nil
+		}
+		return writeZipEntry(zw, h, r)
+	})
+	if err != nil {
+		return err
+	}
+	// Each pass copies a target into one of its links
+	for len(links) > 0 {
+		copied := 0
+		err = eachTarEntry(open, func(h *tar.Header, r io.Reader) error {
+			name := path.Clean(h.Name)
+			pending := links[name]
+			if len(pending) == 0 || !isRegularEntry(h) {
+				return nil
+			}
+			if links[name] = pending[1:]; len(links[name]) == 0 {
+				delete(links, name)
+			}
+			c := *pending[0]
+			c.Typeflag, c.Size = tar.TypeReg, h.Size
+			copied++
+			return writeZipEntry(zw, &c, r)
+		})
+		if err != nil {
+			return err
+		}
+		if copied == 0 {
+			for target, l := range links {
+				return fmt.Errorf("hard link %s: target %s is not in the archive", l[0].Name, target)
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// eachTarEntry calls fn for every entry of the tar.gz stream from open
+func eachTarEntry(open func() (io.ReadCloser, error), fn func(*tar.Header, io.Reader) error) error {
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	gz, err := gzip.NewReader(bufio.NewReaderSize(rc, copyBufferSize))
+	if errors.Is(err, gzip.ErrHeader) || errors.Is(err, io.EOF) {
+		return ErrNotTarGz
+	}
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			// Read to the end so the gzip checksum and the source's own errors surface
+			_, err = io.Copy(io.Discard, gz)
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("read tar header: %w", err)
+		}
+		if err := fn(h, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func isRegularEntry(h *tar.Header) bool {
+	return h.Typeflag == tar.TypeReg || h.Typeflag == tar.TypeGNUSparse
+}
+
+// zipHeader describes a tar entry as a zip entry; ok is false for entries zip cannot hold
+func zipHeader(h *tar.Header) (zh *zip.FileHeader, ok bool, err error) {
+	if !isRegularEntry(h) && h.Typeflag != tar.TypeDir && h.Typeflag != tar.TypeSymlink {
+		return nil, false, nil
+	}
+	zh, err = zip.FileInfoHeader(h.FileInfo())
+	if err != nil {
+		return nil, false, err
+	}
+	zh.Name = strings.TrimPrefix(path.Clean(h.Name), "/")
+	if zh.Name == "." || zh.Name == "" {
+		return nil, false, nil // the archive root needs no entry
+	}
+	if h.Typeflag == tar.TypeDir {
+		zh.Name += "/"
+		zh.Method = zip.Store
+	} else if h.Typeflag == tar.TypeSymlink {
+		zh.Method = zip.Store
+	} else {
+		zh.Method = zip.Deflate
+	}
+	return zh, true, nil
+}
+
+// writeZipEntry adds one tar entry to zw; devices and other special files are skipped
+func writeZipEntry(zw *zip.Writer, h *tar.Header, r io.Reader) error {
+	zh, ok, err := zipHeader(h)
+	if err != nil || !ok {
+		return err
+	}
+	w, err := zw.CreateHeader(zh)
+	if err != nil {
+		return err
+	}
+	switch h.Typeflag {
+	case tar.TypeSymlink:
+		_, err = io.WriteString(w, h.Linkname)
+	case tar.TypeDir:
+	default:
+		_, err = io.CopyBuffer(w, r, make([]byte, copyBufferSize))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", h.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+)
+
+// Severity ranks audit findings
+type Severity int
+
+const (
+	SeverityOK Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityCritical
+)
+
+// String returns a short label for the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityOK:
+		return "OK"
+	case SeverityInfo:
+		return "Info"
+	case SeverityWarning:
+		return "Warning"
+	case SeverityCritical:
+		return "Critical"
+	default:
+		return "Unknown"
+	}
+}
+
+// defaultChunkSize is the chunk size written by current releases
+const defaultChunkSize = 1 << 20
+
+// tempSuffix ends the names of containers being re-encrypted next to their originals
+const tempSuffix = ".reencrypt"
+
+// Finding is the audit result of one file
+type Finding struct {
+	Path     string
+	Severity Severity
+	Issues   []string
+	Header   *cryptoengine.Header // nil when the header could not be read
+}
+
+// NeedsReencryption reports whether re-encrypting to current defaults is recommended
+func (f Finding) NeedsReencryption() bool {
+	return f.Header != nil && f.Severity >= SeverityWarning
+}
+
+// Scan audits every HadesCrypt container in paths; folders are walked
+// recursively. Deniability Mode output is audited when password unwraps it;
+// password may be nil.
+func Scan(paths []string, password []byte) []Finding {
+	var findings []Finding
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			findings = append(findings, Finding{Path: p, Severity: SeverityCritical, Issues: []string{err.Error()}})
+			continue
+		}
+		if !fi.IsDir() {
+			findings = append(findings, CheckFile(p, password))
+			continue
+		}
+		filepath.Walk(p, func(sp string, info os.FileInfo, err error) error {
+			if err != nil || info == nil || info.IsDir() {
+				return nil
+			}
+			if format.IsHadesCrypt(sp) {
+				findings = append(findings, CheckFile(sp, password))
+			}
+			return nil
+		})
+	}
+	return findings
+}
+
+// CheckFile inspects the header of a single container, unwrapping Deniability
+// Mode output with password unless it is nil
+func CheckFile(path string, password []byte) Finding {
+	f := Finding{Path: path}
+	hdr, err := cryptoengine.ReadHeaderWithPassword(path, password)
+	if err != nil {
+		f.Severity = SeverityCritical
+		f.Issues = append(f.Issues, "unreadable header: "+err.Error())
+		return f
+	}
+	f.Header = hdr
+
+	raise := func(s Severity, issue string) {
+		if s > f.Severity {
+			f.Severity = s
+		}
+		f.Issues = append(f.Issues, issue)
+	}
+
+	switch hdr.Mode {
+	case cryptoengine.ModePostQuantumKyber768, cryptoengine.ModePostQuantumDilithium3, cryptoengine.ModePostQuantumSPHINCS:
+		if hdr.Mode != cryptoengine.ModePostQuantumKyber768 || hdr.Flags&cryptoengine.FlagKEM == 0 {
+			raise(SeverityCritical, fmt.Sprintf("%s written by the simulated post-quantum cipher (SHA-256 keystream) of earlier releases; it still decrypts, but re-encrypt it to a current mode", cryptoengine.GetEncryptionModeName(hdr.Mode)))
+		}
+	case cryptoengine.ModeAES256GCM, cryptoengine.ModeChaCha20, cryptoengine.ModeParanoid, cryptoengine.ModeHybridPQ, cryptoengine.ModeParanoidSerpent:
+	default:
+		raise(SeverityCritical, fmt.Sprintf("unknown encryption mode %d", hdr.Mode))
+	}
+	if kdf := hdr.KDFParams(); !hdr.Compliance() && kdf.Memory < cryptoengine.DefaultArgon2.Memory {
+		raise(SeverityWarning, "weak KDF: "+kdf.String())
+	}
+	if hdr.ChunkSize != defaultChunkSize {
+		raise(SeverityInfo, fmt.Sprintf("non-default chunk size %d", hdr.ChunkSize))
+	}
+	return f
+}
+
+// Reencrypt re-encrypts path in place using opts and the same password. The
+// conversion is streamed (see cryptoengine.Convert), so no plaintext reaches the
+// disk; the new container is written next to path and only replaces the original
+// once it is complete. onProgress may be nil.
+func Reencrypt(path string, password []byte, opts cryptoengine.EncryptionOptions, onProgress cryptoengine.ProgressCallback) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*"+tempSuffix)
+	if err != nil {
+		return err
+	}
+	tmpOut := f.Name()
+	f.Close()
+	if err := cryptoengine.Convert(path, tmpOut, password, opts, onProgress); err != nil {
+		os.Remove(tmpOut)
+		return err
+	}
+	if err := os.Chmod(tmpOut, fi.Mode().Perm()); err != nil {
+		os.Remove(tmpOut)
+		return err
+	}
+	if err := os.Rename(tmpOut, path); err != nil {
+		os.Remove(tmpOut)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+var password = []byte("correct horse battery staple")
+
+// encrypted writes a container of a small file to dir
+func encrypted(t *testing.T, dir, name string, opts cryptoengine.EncryptionOptions) string {
+	t.Helper()
+	src := filepath.Join(dir, name)
+	if err := os.WriteFile(src, []byte("audit me"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := src + ".hadescrypt"
+	if err := cryptoengine.EncryptFileWithOptions(src, out, password, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(src)
+	return out
+}
+
+func TestCheckFile(t *testing.T) {
+	dir := t.TempDir()
+	weak := cryptoengine.Argon2Params{Time: 1, Memory: 64, Threads: 1}
+	plain := encrypted(t, dir, "plain", cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM, Argon2: weak})
+	hidden := encrypted(t, dir, "hidden", cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM, Argon2: weak, UseDeniability: true})
+
+	if f := CheckFile(plain, nil); f.Header == nil || f.Severity != SeverityWarning || !f.NeedsReencryption() {
+		t.Errorf("weak KDF: %+v", f)
+	}
+	if f := CheckFile(hidden, nil); f.Header != nil || f.Severity != SeverityCritical {
+		t.Errorf("Deniability Mode without a password: %+v", f)
+	}
+	if f := CheckFile(hidden, password); f.Header == nil || !f.Header.Deniable || f.Severity != SeverityWarning {
+		t.Errorf("Deniability Mode with the password: %+v", f)
+	}
+
+	findings := Scan([]string{dir}, password)
+	if len(findings) != 2 {
+		t.Fatalf("Scan found %d containers, want 2", len(findings))
+	}
+	for _, f := range findings {
+		if f.Header == nil {
+			t.Errorf("Scan: %+v", f)
+		}
+	}
+}
+
+func TestReencryptDeniable(t *testing.T) {
+	hidden := encrypted(t, t.TempDir(), "hidden", cryptoengine.EncryptionOptions{
+		Mode: cryptoengine.ModeAES256GCM, Argon2: cryptoengine.Argon2Params{Time: 1, Memory: 64, Threads: 1}, UseDeniability: true,
+	})
+	opts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeChaCha20, Argon2: cryptoengine.Argon2Params{Time: 1, Memory: 128, Threads: 1}}
+	if err := Reencrypt(hidden, password, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	f := CheckFile(hidden, password)
+	if f.Header == nil || !f.Header.Deniable || f.Header.Mode != cryptoengine.ModeChaCha20 {
+		t.Fatalf("re-encrypted container: %+v", f)
+	}
+	if got, err := cryptoengine.DecryptFileToMemory(hidden, password, 0); err != nil || string(got) != "audit me" {
+		t.Errorf("decrypt: %q, %v", got, err)
+	}
+}
+
+func TestReencryptLegacyPQ(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "cryptoengine", "testdata", "legacy-sphincs.hadescrypt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "legacy.hadescrypt")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	f := CheckFile(path, nil)
+	if f.Severity != SeverityCritical || !f.NeedsReencryption() {
+		t.Fatalf("legacy container: %+v", f)
+	}
+	opts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM, Argon2: cryptoengine.Argon2Params{Time: 1, Memory: 64, Threads: 1}}
+	if err := Reencrypt(path, password, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if f := CheckFile(path, nil); f.Header == nil || f.Header.Mode != cryptoengine.ModeAES256GCM || f.Severity == SeverityCritical {
+		t.Errorf("re-encrypted container: %+v", f)
+	}
+	if got, err := cryptoengine.DecryptFileToMemory(path, password, 0); err != nil || len(got) == 0 {
+		t.Errorf("decrypt: %q, %v", got, err)
+	}
+}
@@ -0,0 +1,186 @@
+// Package avscan hands decrypted files to the platform antivirus before they
+// are opened: Microsoft Defender's command-line scanner (MpCmdRun) on Windows,
+// ClamAV elsewhere, through the clamd daemon (clamdscan) or standalone clamscan.
+// Encrypted files are opaque to scanners, so this is the first point where a
+// scanner can see what a container held.
+package avscan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// ErrNoScanner is returned by Find when no supported scanner is installed
+var ErrNoScanner = errors.New("no antivirus scanner found (Microsoft Defender on Windows, clamdscan or clamscan elsewhere)")
+
+// Verdict is the outcome of scanning one path
+type Verdict int
+
+const (
+	Clean    Verdict = iota
+	Infected         // the scanner reported at least one threat
+	Failed           // the scanner could not finish; see Result.Err
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Clean:
+		return "clean"
+	case Infected:
+		return "infected"
+	}
+	return "scan failed"
+}
+
+// Result is what the scanner said about one file or folder
+type Result struct {
+	Path    string
+	Verdict Verdict
+	Threats []string // names of what was found, for Infected
+	Err     error    // why the scan failed, for Failed
+}
+
+type engine int
+
+const (
+	defender engine = iota
+	clamd
+	clamscan
+)
+
+// Scanner is a located scanner executable
+type Scanner struct {
+	Path   string
+	engine engine
+}
+
+// Name returns the scanner's executable name, e.g. "clamdscan"
+func (s *Scanner) Name() string { return filepath.Base(s.Path) }
+
+// Find returns the scanner at path, which must be MpCmdRun, clamdscan or
+// clamscan, or when path is empty the first one installed
+func Find(path string) (*Scanner, error) {
+	if path != "" {
+		resolved, err := exec.LookPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("antivirus scanner not usable at %s: %w", path, err)
+		}
+		e, ok := engineFor(resolved)
+		if !ok {
+			return nil, fmt.Errorf("%s is not MpCmdRun, clamdscan or clamscan", path)
+		}
+		return &Scanner{Path: resolved, engine: e}, nil
+	}
+	for _, candidate := range candidates(runtime.GOOS) {
+		if resolved, err := exec.LookPath(candidate); err == nil {
+			e, _ := engineFor(resolved)
+			return &Scanner{Path: resolved, engine: e}, nil
+		}
+	}
+	return nil, ErrNoScanner
+}
+
+// candidates lists where to look on goos, preferred first. Defender updates
+// install newer copies below ProgramData\...\Platform; the newest wins.
+func candidates(goos string) []string {
+	if goos != "windows" {
+		return []string{"clamdscan", "clamscan"}
+	}
+	var list []string
+	platform := filepath.Join(os.Getenv("ProgramData"), "Microsoft", "Windows Defender", "Platform")
+	if versions, err := filepath.Glob(filepath.Join(platform, "*", "MpCmdRun.exe")); err == nil {
+		sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+		list = append(list, versions...)
+	}
+	return append(list, filepath.Join(os.Getenv("ProgramFiles"), "Windows Defender", "MpCmdRun.exe"), "clamdscan", "clamscan")
+}
+
+func engineFor(path string) (engine, bool) {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(path)), ".exe")
+	switch name {
+	case "mpcmdrun":
+		return defender, true
+	case "clamdscan":
+		return clamd, true
+	case "clamscan":
+		return clamscan, true
+	}
+	return 0, false
+}
+
+// args returns the command line scanning path without changing it; Defender
+// and ClamAV are told not to quarantine or delete, so the user decides
+func (s *Scanner) args(path string) []string {
+	switch s.engine {
+	case defender:
+		return []string{"-Scan", "-ScanType", "3", "-File", path, "-DisableRemediation"}
+	case clamd:
+		return []string{"--no-summary", "--infected", "--fdpass", path}
+	}
+	return []string{"--no-summary", "--infected", "--recursive", path}
+}
+
+// Scan checks the file or folder at path
+func (s *Scanner) Scan(ctx context.Context, path string) Result {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Result{Path: path, Verdict: Failed, Err: err}
+	}
+	out, err := exec.CommandContext(ctx, s.Path, s.args(abs)...).CombinedOutput()
+	code := 0
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		code = exit.ExitCode()
+	} else if err != nil {
+		return Result{Path: path, Verdict: Failed, Err: err}
+	}
+	r := s.verdict(code, string(out))
+	r.Path = path
+	return r
+}
+
+// verdict reads the exit code and output: Defender exits 2 and lists
+// "Threat : name" lines, ClamAV exits 1 and prints "path: name FOUND"
+func (s *Scanner) verdict(code int, output string) Result {
+	infected := 1
+	if s.engine == defender {
+		infected = 2
+	}
+	switch code {
+	case 0:
+		return Result{Verdict: Clean}
+	case infected:
+		return Result{Verdict: Infected, Threats: threats(s.engine, output)}
+	}
+	msg := strings.TrimSpace(output)
+	if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+		msg = strings.TrimSpace(msg[i+1:])
+	}
+	return Result{Verdict: Failed, Err: fmt.Errorf("%s exited with %d: %s", s.Name(), code, msg)}
+}
+
+func threats(e engine, output string) []string {
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if e == defender {
+			if key, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) == "Threat" {
+				names = append(names, strings.TrimSpace(value))
+			}
+			continue
+		}
+		if rest, ok := strings.CutSuffix(line, " FOUND"); ok {
+			if i := strings.LastIndex(rest, ": "); i >= 0 {
+				names = append(names, rest[i+2:])
+			}
+		}
+	}
+	return names
+}
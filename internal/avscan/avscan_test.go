@@ -0,0 +1,67 @@
+package avscan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestVerdict(t *testing.T) {
+	def := &Scanner{Path: `C:\Program Files\Windows Defender\MpCmdRun.exe`, engine: defender}
+	r := def.verdict(2, "Scan starting...\r\nScan finished.\r\nScanning C:\\x\\eicar.com found 1 threats.\r\n\r\n<===========================LIST OF DETECTED THREATS==========================>\r\n----------------------------- Threat information ------------------------------\r\nThreat                  : Virus:DOS/EICAR_Test_File\r\nResources               : 1 total\r\n")
+	if r.Verdict != Infected || !slices.Equal(r.Threats, []string{"Virus:DOS/EICAR_Test_File"}) {
+		t.Errorf("defender threat: %+v", r)
+	}
+	if r := def.verdict(0, "Scan finished."); r.Verdict != Clean {
+		t.Errorf("defender clean: %+v", r)
+	}
+
+	clam := &Scanner{Path: "/usr/bin/clamdscan", engine: clamd}
+	r = clam.verdict(1, "/home/me/a b/eicar.txt: Win.Test.EICAR_HDB-1 FOUND\n")
+	if r.Verdict != Infected || !slices.Equal(r.Threats, []string{"Win.Test.EICAR_HDB-1"}) {
+		t.Errorf("clamav threat: %+v", r)
+	}
+	r = clam.verdict(2, "ERROR: Could not connect to clamd on LocalSocket /run/clamav/clamd.ctl\n")
+	if r.Verdict != Failed || r.Err == nil {
+		t.Errorf("clamav error: %+v", r)
+	}
+}
+
+func TestEngineFor(t *testing.T) {
+	for path, want := range map[string]engine{"C:/x/MpCmdRun.exe": defender, "/usr/bin/clamdscan": clamd, "clamscan": clamscan} {
+		if e, ok := engineFor(path); !ok || e != want {
+			t.Errorf("engineFor(%q) = %v, %v", path, e, ok)
+		}
+	}
+	if _, ok := engineFor("/usr/bin/rm"); ok {
+		t.Error("an unknown program was taken for a scanner")
+	}
+}
+
+func TestScanRunsScanner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake scanner is a shell script")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "clamdscan")
+	script := "#!/bin/sh\nfor f; do :; done\ncase \"$f\" in *bad*) echo \"$f: Test.Virus FOUND\"; exit 1;; esac\n"
+	if err := os.WriteFile(fake, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Find(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := s.Scan(context.Background(), filepath.Join(dir, "good.txt")); r.Verdict != Clean {
+		t.Errorf("good: %+v", r)
+	}
+	if r := s.Scan(context.Background(), filepath.Join(dir, "bad.txt")); r.Verdict != Infected || !slices.Equal(r.Threats, []string{"Test.Virus"}) {
+		t.Errorf("bad: %+v", r)
+	}
+	if _, err := Find(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Find accepted a missing scanner")
+	}
+}
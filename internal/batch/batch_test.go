@@ -0,0 +1,268 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+var password = []byte("batch password")
+
+const manifestYAML = `# nightly backup
+parallel: 2
+password_env: "BACKUP_PW"   # never in the file itself
+report: reports/nightly.json
+stop_on_error: true
+jobs:
+  - name: documents
+    source: docs
+    destination: /srv/backup/
+    mode: ChaCha20-Poly1305
+    argon2_preset: 'Fast'
+    post_hooks: ["sync", 'echo "done: #1"']
+  - source: photos/2024
+    delete_source: yes
+    rate_limit_mbps: 12.5
+    post_hooks:
+      - ls -l
+`
+
+const manifestJSON = `{
+  "parallel": 2,
+  "password_env": "BACKUP_PW",
+  "report": "reports/nightly.json",
+  "stop_on_error": true,
+  "jobs": [
+    {"name": "documents", "source": "docs", "destination": "/srv/backup/", "mode": "ChaCha20-Poly1305",
+     "argon2_preset": "Fast", "post_hooks": ["sync", "echo \"done: #1\""]},
+    {"source": "photos/2024", "delete_source": true, "rate_limit_mbps": 12.5, "post_hooks": ["ls -l"]}
+  ]
+}`
+
+// writeJobFile stores data as name in a new folder and returns its path
+func writeJobFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	yamlPath := writeJobFile(t, "nightly.yaml", manifestYAML)
+	jf, err := Load(yamlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Dir(yamlPath)
+	want := &JobFile{
+		Parallel:    2,
+		PasswordEnv: "BACKUP_PW",
+		Report:      filepath.Join(base, "reports", "nightly.json"),
+		StopOnError: true,
+		Jobs: []Job{
+			{Name: "documents", Source: filepath.Join(base, "docs"), Destination: "/srv/backup/", Mode: "ChaCha20-Poly1305", Argon2Preset: "Fast", PostHooks: []string{"sync", `echo "done: #1"`}},
+			{Name: "2024", Source: filepath.Join(base, "photos", "2024"), DeleteSource: true, RateLimit: 12.5, PostHooks: []string{"ls -l"}},
+		},
+	}
+	if !reflect.DeepEqual(jf, want) {
+		t.Errorf("YAML manifest:\n got %+v\nwant %+v", jf, want)
+	}
+
+	// the JSON form of the same manifest loads the same, relative to its own folder
+	jsonPath := writeJobFile(t, "nightly.json", manifestJSON)
+	fromJSON, err := Load(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON.Report = strings.Replace(fromJSON.Report, filepath.Dir(jsonPath), base, 1)
+	for i := range fromJSON.Jobs {
+		fromJSON.Jobs[i].Source = strings.Replace(fromJSON.Jobs[i].Source, filepath.Dir(jsonPath), base, 1)
+	}
+	if !reflect.DeepEqual(fromJSON, want) {
+		t.Errorf("JSON manifest:\n got %+v\nwant %+v", fromJSON, want)
+	}
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown field": "jobs:\n  - source: a\n    destinaton: b\n",
+		"no jobs":       "parallel: 2\n",
+		"no source":     "jobs:\n  - name: a\n",
+		"bad syntax":    "jobs:\n  - source: [a\n",
+		"wrong type":    "parallel: many\njobs:\n  - source: a\n",
+		"empty":         "",
+	} {
+		if _, err := Load(writeJobFile(t, "jobs.yml", data)); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+}
+
+// recorder is an Observer that tracks how many jobs run at once
+type recorder struct {
+	mu              sync.Mutex
+	running, most   int
+	started, ended  int
+	finishedReports int
+}
+
+func (r *recorder) RunStarted(int) {}
+
+func (r *recorder) JobStarted(Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started++
+	r.running++
+	r.most = max(r.most, r.running)
+}
+
+func (r *recorder) JobFinished(JobResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ended++
+	r.running--
+}
+
+func (r *recorder) RunFinished(*Report) { r.finishedReports++ }
+
+// newJobs writes n source files to a new folder and returns jobs encrypting them;
+// each job's post-hook waits so that parallel jobs overlap
+func newJobs(t *testing.T, n int) []Job {
+	t.Helper()
+	dir := t.TempDir()
+	var jobs []Job
+	for i := range n {
+		src := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := os.WriteFile(src, []byte(strings.Repeat("x", 100*(i+1))), 0600); err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, Job{Name: filepath.Base(src), Source: src, Argon2Preset: "Fast", PostHooks: []string{"sleep 0.2"}})
+	}
+	return jobs
+}
+
+func TestRunSequentialAndParallel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("post-hooks use sh")
+	}
+	for _, parallel := range []int{0, 3} {
+		jobs := newJobs(t, 4)
+		obs := &recorder{}
+		rep := RunObserved(context.Background(), &JobFile{Parallel: parallel, Jobs: jobs}, password, config.DefaultConfig(), obs)
+		if !rep.OK() || rep.Succeeded != 4 || rep.Code != apperr.OK {
+			t.Fatalf("parallel %d: %+v", parallel, rep)
+		}
+		want := 1
+		if parallel > 1 {
+			want = parallel
+		}
+		if obs.most != want || obs.started != 4 || obs.ended != 4 || obs.finishedReports != 1 {
+			t.Errorf("parallel %d: %d jobs at once, %d started, %d ended, %d reports", parallel, obs.most, obs.started, obs.ended, obs.finishedReports)
+		}
+		// results keep the job file order whatever order the jobs finished in
+		for i, r := range rep.Jobs {
+			if r.Name != jobs[i].Name || r.Status != StatusOK {
+				t.Errorf("parallel %d: result %d is %+v, want job %s", parallel, i, r, jobs[i].Name)
+				continue
+			}
+			want, _ := os.ReadFile(jobs[i].Source)
+			got, err := cryptoengine.DecryptFileToMemory(r.Output, password, 0)
+			if err != nil || string(got) != string(want) {
+				t.Errorf("parallel %d: %s decrypts to %d bytes, %v", parallel, r.Output, len(got), err)
+			}
+		}
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	missing := Job{Name: "missing", Source: filepath.Join(t.TempDir(), "missing.txt")}
+	badMode := Job{Name: "bad mode", Source: missing.Source, Mode: "ROT13"}
+	for _, tc := range []struct {
+		name        string
+		jf          func() *JobFile
+		code        apperr.Code
+		ok, skipped int
+	}{
+		{"all succeed", func() *JobFile { return &JobFile{Jobs: newJobs(t, 2)} }, apperr.OK, 2, 0},
+		{"some fail", func() *JobFile { return &JobFile{Jobs: append(newJobs(t, 2), missing)} }, apperr.PartialSuccess, 2, 0},
+		{"all fail alike", func() *JobFile { return &JobFile{Jobs: []Job{missing, missing}} }, apperr.IOError, 0, 0},
+		{"all fail differently", func() *JobFile { return &JobFile{Jobs: []Job{missing, badMode}} }, apperr.Internal, 0, 0},
+		{"stop on error", func() *JobFile { return &JobFile{StopOnError: true, Jobs: append([]Job{badMode}, newJobs(t, 2)...)} }, apperr.Usage, 0, 2},
+	} {
+		jf := tc.jf()
+		for i := range jf.Jobs {
+			jf.Jobs[i].PostHooks = nil
+		}
+		rep := Run(context.Background(), jf, password, config.DefaultConfig())
+		if rep.Code != tc.code || rep.Succeeded != tc.ok || rep.Skipped != tc.skipped || rep.Failed != len(jf.Jobs)-tc.ok-tc.skipped {
+			t.Errorf("%s: code %s, %d ok, %d failed, %d skipped", tc.name, rep.Code, rep.Succeeded, rep.Failed, rep.Skipped)
+		}
+		if got := rep.Code.ExitCode(); got != tc.code.ExitCode() {
+			t.Errorf("%s: exit code %d", tc.name, got)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if rep := Run(ctx, &JobFile{Jobs: newJobs(t, 2)}, password, config.DefaultConfig()); rep.Code != apperr.Canceled || rep.Skipped != 2 {
+		t.Errorf("canceled run: code %s, %d skipped", rep.Code, rep.Skipped)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("post-hooks use sh")
+	}
+	jobs := newJobs(t, 1)
+	// a hook that echoes the password must not leak it into the report
+	jobs[0].PostHooks = []string{"echo " + string(password)}
+	failing := Job{Name: "missing", Source: filepath.Join(t.TempDir(), "missing.txt")}
+	rep := Run(context.Background(), &JobFile{Jobs: append(jobs, failing)}, password, config.DefaultConfig())
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := rep.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), string(password)) {
+		t.Error("the report contains the password")
+	}
+	var got struct {
+		Succeeded, Failed, Skipped int
+		Code                       apperr.Code
+		Jobs                       []struct {
+			Name, Source, Output, Status, Error string
+			Code                                apperr.Code
+			Bytes                               int64
+			Hooks                               []HookResult
+		}
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Succeeded != 1 || got.Failed != 1 || got.Skipped != 0 || got.Code != apperr.PartialSuccess || len(got.Jobs) != 2 {
+		t.Fatalf("report: %s", data)
+	}
+	ok, failed := got.Jobs[0], got.Jobs[1]
+	if ok.Status != StatusOK || ok.Code != apperr.OK || ok.Output == "" || ok.Bytes != 100 || len(ok.Hooks) != 1 || ok.Hooks[0].ExitCode != 0 {
+		t.Errorf("successful job: %+v", ok)
+	}
+	if failed.Status != StatusFailed || failed.Code != apperr.IOError || failed.Error == "" || failed.Output != "" {
+		t.Errorf("failed job: %+v", failed)
+	}
+}
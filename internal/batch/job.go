@@ -0,0 +1,109 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+)
+
+// JobFile is a batch description loaded from JSON or YAML
+type JobFile struct {
+	Parallel     int    `json:"parallel" yaml:"parallel"`           // concurrent jobs; 0 or 1 runs them sequentially
+	PasswordFile string `json:"password_file" yaml:"password_file"` // file holding the password (first line)
+	PasswordEnv  string `json:"password_env" yaml:"password_env"`   // environment variable holding the password
+	Report       string `json:"report" yaml:"report"`               // JSON report path; empty prints to stdout
+	StopOnError  bool   `json:"stop_on_error" yaml:"stop_on_error"` // skip remaining jobs after the first failure
+	// Transactional makes the run all-or-nothing: outputs are staged and only
+	// published, with post-hooks and source deletion, once every job succeeded
+	Transactional bool  `json:"transactional" yaml:"transactional"`
+	Jobs          []Job `json:"jobs" yaml:"jobs"`
+}
+
+// Job encrypts one source (file or folder) to a destination
+type Job struct {
+	Name         string   `json:"name" yaml:"name"`
+	Source       string   `json:"source" yaml:"source"`
+	Destination  string   `json:"destination" yaml:"destination"`     // output file, or an existing folder to write into
+	Profile      string   `json:"profile" yaml:"profile"`             // name of a saved profile
+	Mode         string   `json:"mode" yaml:"mode"`                   // overrides the profile's mode, e.g. "ChaCha20-Poly1305"
+	Argon2Preset string   `json:"argon2_preset" yaml:"argon2_preset"` // overrides the profile's preset
+	DeleteSource bool     `json:"delete_source" yaml:"delete_source"`
+	PostHooks    []string `json:"post_hooks" yaml:"post_hooks"`           // shell commands run after a successful job
+	LowPriority  bool     `json:"low_priority" yaml:"low_priority"`       // run the job on a deprioritized thread
+	RateLimit    float64  `json:"rate_limit_mbps" yaml:"rate_limit_mbps"` // cap read/write throughput; 0 is unlimited
+	Symlinks     string   `json:"symlinks" yaml:"symlinks"`               // symlink policy for folders: skip, follow or link; overrides the profile's
+	Names        string   `json:"names" yaml:"names"`                     // output name style: keep, transliterate or percent; overrides the profile's
+}
+
+// Load reads a job file; .yaml/.yml files are parsed as YAML, everything else as JSON.
+// Relative paths in jobs are resolved against the job file's folder.
+func Load(path string) (*JobFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var jf JobFile
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&jf)
+		if errors.Is(err, io.EOF) {
+			err = errors.New("empty document")
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&jf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(jf.Jobs) == 0 {
+		return nil, fmt.Errorf("%s: no jobs defined", path)
+	}
+
+	base := filepath.Dir(path)
+	abs := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(base, p)
+	}
+	jf.PasswordFile = abs(jf.PasswordFile)
+	jf.Report = abs(jf.Report)
+	for i := range jf.Jobs {
+		j := &jf.Jobs[i]
+		if j.Source == "" {
+			return nil, fmt.Errorf("%s: job %d has no source", path, i+1)
+		}
+		if j.Name == "" {
+			j.Name = filepath.Base(j.Source)
+		}
+		j.Source = abs(j.Source)
+		j.Destination = abs(j.Destination)
+	}
+	return &jf, nil
+}
+
+// ErrNoPassword is returned by Password when the job file names no password source
+var ErrNoPassword = errors.New("job file sets neither password_file nor password_env")
+
+// Password reads the batch password from PasswordFile or PasswordEnv
+func (jf *JobFile) Password() ([]byte, error) {
+	switch {
+	case jf.PasswordFile != "":
+		return secret.FromFile(jf.PasswordFile)
+	case jf.PasswordEnv != "":
+		return secret.FromEnv(jf.PasswordEnv)
+	}
+	return nil, ErrNoPassword
+}
@@ -0,0 +1,92 @@
+package batch
+
+import (
+	"sync"
+
+	"github.com/bangundwir/HadesCrypt/internal/metrics"
+)
+
+// Metrics is an Observer that exports job counts, bytes, failures, queue depth
+// and durations to a metrics registry
+type Metrics struct {
+	jobs      *metrics.Vec
+	bytes     *metrics.Vec
+	failures  *metrics.Vec
+	queue     *metrics.Vec
+	running   *metrics.Vec
+	durations *metrics.Vec
+	runs      *metrics.Vec
+	lastRun   *metrics.Vec
+	lastOK    *metrics.Vec
+
+	mu      sync.Mutex
+	waiting int
+	active  int
+}
+
+// NewMetrics registers the batch metrics in reg
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{
+		jobs:      reg.Counter("hadescrypt_jobs_total", "Jobs finished, by status.", "status"),
+		bytes:     reg.Counter("hadescrypt_bytes_processed_total", "Plaintext bytes encrypted by successful jobs."),
+		failures:  reg.Counter("hadescrypt_job_failures_total", "Failed jobs, by error code.", "code"),
+		queue:     reg.Gauge("hadescrypt_queue_depth", "Jobs of the current run waiting to start."),
+		running:   reg.Gauge("hadescrypt_jobs_running", "Jobs currently running."),
+		durations: reg.Histogram("hadescrypt_job_duration_seconds", "Job durations, by status.", metrics.DurationBuckets, "status"),
+		runs:      reg.Counter("hadescrypt_runs_total", "Job file runs completed."),
+		lastRun:   reg.Gauge("hadescrypt_last_run_timestamp_seconds", "Unix time the last run finished."),
+		lastOK:    reg.Gauge("hadescrypt_last_run_success", "1 if every job of the last run succeeded, else 0."),
+	}
+}
+
+func (m *Metrics) gauges() {
+	m.queue.Set(float64(m.waiting))
+	m.running.Set(float64(m.active))
+}
+
+// RunStarted implements Observer
+func (m *Metrics) RunStarted(jobs int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.waiting, m.active = jobs, 0
+	m.gauges()
+}
+
+// JobStarted implements Observer
+func (m *Metrics) JobStarted(Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.waiting--
+	m.active++
+	m.gauges()
+}
+
+// JobFinished implements Observer
+func (m *Metrics) JobFinished(res JobResult) {
+	m.mu.Lock()
+	m.active--
+	m.gauges()
+	m.mu.Unlock()
+
+	m.jobs.Inc(res.Status)
+	switch res.Status {
+	case StatusOK:
+		m.bytes.Add(float64(res.Bytes))
+	case StatusFailed:
+		m.failures.Inc(string(res.Code))
+	}
+	if res.Status != StatusSkipped {
+		m.durations.Observe(float64(res.DurationMS)/1000, res.Status)
+	}
+}
+
+// RunFinished implements Observer
+func (m *Metrics) RunFinished(rep *Report) {
+	m.runs.Inc()
+	m.lastRun.Set(float64(rep.Finished.Unix()))
+	ok := 0.0
+	if rep.OK() {
+		ok = 1
+	}
+	m.lastOK.Set(ok)
+}
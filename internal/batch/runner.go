@@ -0,0 +1,474 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/archiver"
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+	"github.com/bangundwir/HadesCrypt/internal/media"
+	"github.com/bangundwir/HadesCrypt/internal/operations"
+	"github.com/bangundwir/HadesCrypt/internal/policy"
+	"github.com/bangundwir/HadesCrypt/internal/safedelete"
+	"github.com/bangundwir/HadesCrypt/internal/safename"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+	"github.com/bangundwir/HadesCrypt/internal/throttle"
+	"github.com/bangundwir/HadesCrypt/internal/txn"
+)
+
+// Job statuses in the report
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+	// StatusRolledBack marks a job of a transactional run whose output was discarded
+	// because another job failed
+	StatusRolledBack = "rolled_back"
+)
+
+// HookResult is the outcome of one post-hook
+type HookResult struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output,omitempty"`
+}
+
+// JobResult is the outcome of one job
+type JobResult struct {
+	Name       string       `json:"name"`
+	Source     string       `json:"source"`
+	Output     string       `json:"output,omitempty"`
+	Status     string       `json:"status"`
+	Code       apperr.Code  `json:"code"`
+	Error      string       `json:"error,omitempty"`
+	Bytes      int64        `json:"bytes"`
+	DurationMS int64        `json:"duration_ms"`
+	Hooks      []HookResult `json:"hooks,omitempty"`
+}
+
+// Report is the consolidated result of a run
+type Report struct {
+	Started   time.Time   `json:"started"`
+	Finished  time.Time   `json:"finished"`
+	Succeeded int         `json:"succeeded"`
+	Failed    int         `json:"failed"`
+	Skipped   int         `json:"skipped"`
+	Code      apperr.Code `json:"code"`
+	Jobs      []JobResult `json:"jobs"`
+	// Transaction is set for transactional runs
+	Transaction *TxnReport `json:"transaction,omitempty"`
+}
+
+// TxnReport explains the outcome of a transactional run
+type TxnReport struct {
+	Committed bool     `json:"committed"`
+	BlockedBy []string `json:"blocked_by,omitempty"` // why the outputs were rolled back
+}
+
+// OK reports whether every job succeeded
+func (r *Report) OK() bool { return r.Failed == 0 && r.Skipped == 0 }
+
+// Write stores the report as indented JSON at path, or prints it when path is empty
+func (r *Report) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Observer is told how a run progresses, e.g. to export metrics. Every job is
+// started and finished once, skipped jobs included. Calls may come from several
+// goroutines when jobs run in parallel.
+type Observer interface {
+	RunStarted(jobs int)
+	JobStarted(job Job)
+	JobFinished(res JobResult)
+	RunFinished(rep *Report)
+}
+
+// Run executes the jobs of jf with password and profiles from cfg. Jobs run
+// sequentially unless jf.Parallel > 1; results keep the job file order.
+func Run(ctx context.Context, jf *JobFile, password []byte, cfg *config.Config) *Report {
+	return RunObserved(ctx, jf, password, cfg, nil)
+}
+
+// RunObserved is Run reporting to obs, which may be nil. The administrator
+// policy is read at the start of every run; if it exists but is invalid, every
+// job fails. Transactional runs stop at the first failure and roll back.
+func RunObserved(ctx context.Context, jf *JobFile, password []byte, cfg *config.Config, obs Observer) *Report {
+	rep := &Report{Started: time.Now(), Jobs: make([]JobResult, len(jf.Jobs))}
+	pol, polErr := policy.Load()
+	if obs != nil {
+		obs.RunStarted(len(jf.Jobs))
+	}
+	workers := max(jf.Parallel, 1)
+	var tx *txn.Txn
+	if jf.Transactional {
+		tx = txn.New()
+	}
+
+	var mu sync.Mutex
+	failed := false
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, job := range jf.Jobs {
+		sem <- struct{}{}
+		mu.Lock()
+		skip := ctx.Err() != nil || ((jf.StopOnError || tx != nil) && failed)
+		mu.Unlock()
+		if skip {
+			<-sem
+			rep.Jobs[i] = JobResult{Name: job.Name, Source: job.Source, Status: StatusSkipped}
+			if ctx.Err() != nil {
+				rep.Jobs[i].Code = apperr.Canceled
+			}
+			if obs != nil {
+				obs.JobStarted(job)
+				obs.JobFinished(rep.Jobs[i])
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if obs != nil {
+				obs.JobStarted(job)
+			}
+			var res JobResult
+			if polErr != nil {
+				res = JobResult{Name: job.Name, Source: job.Source, Status: StatusFailed, Code: apperr.Usage, Error: polErr.Error()}
+			} else {
+				res = runJob(ctx, job, password, cfg, pol, tx)
+			}
+			if obs != nil {
+				res.Error = secret.Scrub(res.Error, password)
+				obs.JobFinished(res)
+			}
+			mu.Lock()
+			if res.Status == StatusFailed {
+				failed = true
+			}
+			mu.Unlock()
+			rep.Jobs[i] = res
+		}()
+	}
+	wg.Wait()
+	if tx != nil {
+		rep.Transaction = finishTxn(ctx, tx, jf, rep, password)
+	}
+
+	// Hooks and underlying libraries may echo their input; keep the password out of the report
+	if rep.Transaction != nil {
+		for i, b := range rep.Transaction.BlockedBy {
+			rep.Transaction.BlockedBy[i] = secret.Scrub(b, password)
+		}
+	}
+	for i := range rep.Jobs {
+		j := &rep.Jobs[i]
+		j.Error = secret.Scrub(j.Error, password)
+		for k := range j.Hooks {
+			j.Hooks[k].Command = secret.Scrub(j.Hooks[k].Command, password)
+			j.Hooks[k].Output = secret.Scrub(j.Hooks[k].Output, password)
+		}
+	}
+
+	var codes []apperr.Code
+	for _, r := range rep.Jobs {
+		if r.Code != "" {
+			codes = append(codes, r.Code)
+		}
+		switch r.Status {
+		case StatusOK:
+			rep.Succeeded++
+		case StatusFailed:
+			rep.Failed++
+		default:
+			rep.Skipped++
+		}
+	}
+	// Jobs skipped by stop_on_error carry no code; the failure that stopped the run does
+	rep.Code = apperr.Combine(codes)
+	if ctx.Err() != nil {
+		rep.Code = apperr.Canceled
+	}
+	rep.Finished = time.Now()
+	if obs != nil {
+		obs.RunFinished(rep)
+	}
+	return rep
+}
+
+// finishTxn publishes the staged outputs of a transactional run if every job
+// succeeded, then runs the deferred post-hooks and source deletions. Otherwise
+// every staged output is discarded and the report names what blocked it.
+func finishTxn(ctx context.Context, tx *txn.Txn, jf *JobFile, rep *Report, password []byte) *TxnReport {
+	tr := &TxnReport{}
+	for _, r := range rep.Jobs {
+		switch {
+		case r.Status == StatusFailed:
+			tr.BlockedBy = append(tr.BlockedBy, fmt.Sprintf("%s: %s", r.Name, r.Error))
+		case r.Status == StatusSkipped && r.Code == apperr.Canceled:
+			tr.BlockedBy = append(tr.BlockedBy, r.Name+": canceled")
+		}
+	}
+	if len(tr.BlockedBy) == 0 {
+		if err := tx.Commit(); err != nil {
+			tr.BlockedBy = append(tr.BlockedBy, err.Error())
+		}
+	} else if err := tx.Rollback(); err != nil {
+		tr.BlockedBy = append(tr.BlockedBy, "clean up staged outputs: "+err.Error())
+	}
+	if len(tr.BlockedBy) > 0 {
+		for i := range rep.Jobs {
+			if r := &rep.Jobs[i]; r.Status == StatusOK {
+				r.Status, r.Code = StatusRolledBack, ""
+			}
+		}
+		return tr
+	}
+	tr.Committed = true
+	for i := range rep.Jobs {
+		finishJob(ctx, jf.Jobs[i], password, &rep.Jobs[i])
+	}
+	return tr
+}
+
+// optionsFor resolves the encryption options and output extension of a job
+func optionsFor(job Job, cfg *config.Config) (cryptoengine.EncryptionOptions, string, string, error) {
+	opts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM, Compliance: cfg.ComplianceMode, GPGPath: cfg.GPGPath}
+	ext, outDir := "", ""
+	modeName, preset := job.Mode, job.Argon2Preset
+	if job.Profile != "" {
+		p := cfg.GetProfile(job.Profile)
+		if p == nil {
+			return opts, "", "", fmt.Errorf("unknown profile %q", job.Profile)
+		}
+		if modeName == "" {
+			modeName = p.EncryptionMode
+		}
+		if preset == "" {
+			preset = p.Argon2Preset
+		}
+		if p.OutputExtension != "" {
+			ext = p.OutputExtension
+		}
+		outDir = p.OutputDir
+		if p.GPGPath != "" {
+			opts.GPGPath = p.GPGPath
+		}
+	}
+	if modeName != "" {
+		mode, ok := cryptoengine.ModeByName(modeName)
+		if !ok {
+			return opts, "", "", fmt.Errorf("unknown encryption mode %q", modeName)
+		}
+		opts.Mode = mode
+	}
+	ext = cryptoengine.ExtensionFor(opts.Mode, ext)
+	if preset != "" {
+		opts.Argon2 = cryptoengine.Argon2Preset(preset)
+	}
+	return opts, ext, outDir, nil
+}
+
+// symlinkPolicyFor resolves the symlink policy of a folder job
+func symlinkPolicyFor(job Job, cfg *config.Config) (fswalk.Policy, error) {
+	name := job.Symlinks
+	if name == "" && job.Profile != "" {
+		if p := cfg.GetProfile(job.Profile); p != nil {
+			name = p.SymlinkPolicy
+		}
+	}
+	return fswalk.ParsePolicy(name)
+}
+
+// nameStyleFor resolves how a job's output name is rewritten
+func nameStyleFor(job Job, cfg *config.Config) (safename.Style, error) {
+	name := job.Names
+	if name == "" && job.Profile != "" {
+		if p := cfg.GetProfile(job.Profile); p != nil {
+			name = p.OutputNames
+		}
+	}
+	return safename.ParseStyle(name)
+}
+
+// runJob encrypts one job. With tx the output is staged and the post-hooks and
+// source deletion are left to finishTxn.
+func runJob(ctx context.Context, job Job, password []byte, cfg *config.Config, pol *policy.Policy, tx *txn.Txn) (res JobResult) {
+	start := time.Now()
+	res = JobResult{Name: job.Name, Source: job.Source, Status: StatusFailed}
+	defer func() { res.DurationMS = time.Since(start).Milliseconds() }()
+	fail := func(err error) JobResult {
+		res.Code, res.Error = apperr.Classify(err), err.Error()
+		return res
+	}
+
+	opts, ext, outDir, err := optionsFor(job, cfg)
+	if err != nil {
+		return fail(apperr.Wrap(apperr.Usage, err))
+	}
+	if err := pol.CheckEncrypt(opts); err != nil {
+		return fail(apperr.Wrap(apperr.Usage, err))
+	}
+	if job.DeleteSource {
+		if err := pol.CheckDeleteAfter(); err != nil {
+			return fail(apperr.Wrap(apperr.Usage, err))
+		}
+	}
+	symlinks, err := symlinkPolicyFor(job, cfg)
+	if err != nil {
+		return fail(apperr.Wrap(apperr.Usage, err))
+	}
+	names, err := nameStyleFor(job, cfg)
+	if err != nil {
+		return fail(apperr.Wrap(apperr.Usage, err))
+	}
+	info, err := os.Stat(job.Source)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Naming and folder walks follow the same rules as the app
+	ops := &operations.Controller{Settings: operations.Settings{Mode: opts.Mode, Extension: ext, OutputDir: outDir, Symlinks: symlinks, Names: names}}
+	out := ops.EncryptedPath(job.Source)
+	opts.Metadata.Name = ops.OriginalName(job.Source)
+	if job.Destination != "" {
+		out = job.Destination
+		if fi, err := os.Stat(out); err == nil && fi.IsDir() {
+			ops.OutputDir = out
+			out = ops.EncryptedPath(job.Source)
+		}
+	}
+	res.Output = out
+
+	if ctx.Err() != nil {
+		res.Status = StatusSkipped
+		return fail(apperr.ErrCanceled)
+	}
+	dst := out
+	if tx != nil {
+		if dst, err = tx.Stage(out); err != nil {
+			return fail(err)
+		}
+	}
+
+	// Each job runs on its own goroutine, so lowering its thread's priority is contained
+	if job.LowPriority {
+		throttle.LowerPriority()
+	}
+	paced := throttle.Progress(throttle.NewLimiter(throttle.MBps(job.RateLimit)), nil)
+
+	input := job.Source
+	if info.IsDir() {
+		if opts.Mode == cryptoengine.ModeGnuPG {
+			return fail(apperr.Wrap(apperr.Unsupported, errors.New("GnuPG mode cannot encrypt folders; pick another mode")))
+		}
+		// Stage the archive locally when the destination is a network or removable drive
+		tmpDir := filepath.Dir(out)
+		if media.Detect(tmpDir).Slow() {
+			tmpDir = ""
+		}
+		tmp, err := securetemp.TempPath(tmpDir, filepath.Base(out)+".*.temp.tar.gz")
+		if err != nil {
+			return fail(err)
+		}
+		defer securetemp.Remove(tmp)
+		if err := archiver.CreateTarGzWithOptions(job.Source, tmp, ops.WalkOptions(job.Source, false), paced); err != nil {
+			return fail(fmt.Errorf("create archive: %w", err))
+		}
+		input = tmp
+	}
+	encrypt := func() error { return cryptoengine.EncryptFileWithOptions(input, dst, password, opts, paced) }
+	if media.Detect(input).Slow() || media.Detect(out).Slow() {
+		err = media.Retry(3, encrypt)
+	} else {
+		err = encrypt()
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fail(err)
+	}
+	// An output whose password could not be escrowed must not survive
+	if err := pol.Escrow(dst, password, false); err != nil {
+		os.Remove(dst)
+		return fail(err)
+	}
+	if fi, err := os.Stat(input); err == nil {
+		res.Bytes = fi.Size()
+	}
+	res.Status, res.Code = StatusOK, apperr.OK
+	if tx == nil {
+		finishJob(ctx, job, password, &res)
+	}
+	return res
+}
+
+// finishJob runs the post-hooks of a successful job and deletes its source,
+// marking res failed if either does not succeed
+func finishJob(ctx context.Context, job Job, password []byte, res *JobResult) {
+	fail := func(err error) {
+		res.Status, res.Code, res.Error = StatusFailed, apperr.Classify(err), err.Error()
+	}
+	for _, hook := range job.PostHooks {
+		hr := runHook(ctx, hook, job, res.Output)
+		res.Hooks = append(res.Hooks, hr)
+		if hr.ExitCode != 0 {
+			fail(fmt.Errorf("post-hook failed: %s", hook))
+			return
+		}
+	}
+	if job.DeleteSource {
+		if err := safedelete.AfterEncrypt(job.Source, res.Output, password); err != nil {
+			fail(fmt.Errorf("delete source: %w", err))
+		}
+	}
+}
+
+// runHook runs a post-hook through the system shell with the job's paths in the environment
+func runHook(ctx context.Context, command string, job Job, output string) HookResult {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(),
+		"HADESCRYPT_JOB="+job.Name,
+		"HADESCRYPT_SOURCE="+job.Source,
+		"HADESCRYPT_OUTPUT="+output,
+	)
+	var buf bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &buf, &buf
+	hr := HookResult{Command: command}
+	if err := cmd.Run(); err != nil {
+		hr.ExitCode = -1
+		if ee, ok := err.(*exec.ExitError); ok {
+			hr.ExitCode = ee.ExitCode()
+		}
+	}
+	hr.Output = strings.TrimSpace(buf.String())
+	if len(hr.Output) > 4096 {
+		hr.Output = hr.Output[:4096] + "…"
+	}
+	return hr
+}
@@ -0,0 +1,307 @@
+// Package bench measures how fast this machine encrypts and decrypts in each
+// mode, derives keys with each KDF preset and compresses at each level, so
+// settings for large datasets can be chosen from numbers rather than guessed.
+package bench
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/bangundwir/HadesCrypt/internal/compression"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+// Kinds of measurement, the first CSV column
+const (
+	KindMode        = "mode"
+	KindKDF         = "kdf"
+	KindCompression = "compression"
+)
+
+// DefaultSize is the data per mode and compression level when Options.Size is 0
+const DefaultSize = 64 << 20
+
+// Result is one measurement
+type Result struct {
+	Kind      string // KindMode, KindKDF or KindCompression
+	Name      string // mode name, KDF preset or compression level
+	Operation string // encrypt, decrypt, derive, compress or decompress
+	Bytes     int64  // data processed; 0 for key derivation
+	Duration  time.Duration
+	Ratio     float64 // output size / input size; compression only
+}
+
+// MBps is the throughput in MB/s, 0 for key derivation
+func (r Result) MBps() float64 {
+	if r.Bytes == 0 || r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / 1e6 / r.Duration.Seconds()
+}
+
+// String formats r for a terminal or a status line
+func (r Result) String() string {
+	switch {
+	case r.Kind == KindKDF:
+		return fmt.Sprintf("%-12s %-38s %-10s %10s", r.Kind, r.Name, r.Operation, r.Duration.Round(time.Millisecond))
+	case r.Ratio > 0:
+		return fmt.Sprintf("%-12s %-38s %-10s %8.1f MB/s  ratio %.2f", r.Kind, r.Name, r.Operation, r.MBps(), r.Ratio)
+	}
+	return fmt.Sprintf("%-12s %-38s %-10s %8.1f MB/s", r.Kind, r.Name, r.Operation, r.MBps())
+}
+
+// DefaultModes are the modes the engine encrypts itself; GnuPG depends on gpg
+var DefaultModes = []cryptoengine.EncryptionMode{
+	cryptoengine.ModeAES256GCM,
+	cryptoengine.ModeChaCha20,
+	cryptoengine.ModeParanoid,
+	cryptoengine.ModePostQuantumKyber768,
+	cryptoengine.ModeHybridPQ,
+	cryptoengine.ModeParanoidSerpent,
+}
+
+// DefaultLevels are the compression levels the app offers
+var DefaultLevels = []compression.CompressionLevel{compression.BestSpeed, compression.DefaultCompression, compression.BestCompression}
+
+// Options select what Run measures. Empty lists measure nothing of their kind;
+// Defaults fills them all in.
+type Options struct {
+	Size    int64     // bytes per mode and compression level; 0 uses DefaultSize
+	Sample  io.Reader // data to compress, up to Size bytes; nil uses a mix of text and random bytes
+	Modes   []cryptoengine.EncryptionMode
+	KDFs    []string // Argon2id preset names
+	Levels  []compression.CompressionLevel
+	Workers int // as in cryptoengine.EncryptionOptions
+}
+
+// Defaults returns Options measuring every mode, KDF preset and compression level
+func Defaults() Options {
+	return Options{Modes: DefaultModes, KDFs: cryptoengine.Argon2PresetNames, Levels: DefaultLevels}
+}
+
+// LevelName names a compression level for results
+func LevelName(level compression.CompressionLevel) string {
+	switch level {
+	case compression.BestSpeed:
+		return "Fast (flate 1)"
+	case compression.DefaultCompression:
+		return "Default (flate 6)"
+	case compression.BestCompression:
+		return "Best (flate 9)"
+	}
+	return fmt.Sprintf("flate %d", level)
+}
+
+// password is what the benchmark encrypts with; the key is never kept
+var password = []byte("HadesCrypt benchmark")
+
+// Run measures what opts selects, in the order modes, KDF presets, compression
+// levels, and passes each result to onResult (which may be nil) as it comes.
+// Everything happens in memory, so the figures exclude the disk.
+func Run(ctx context.Context, opts Options, onResult func(Result)) ([]Result, error) {
+	size := opts.Size
+	if size <= 0 {
+		size = DefaultSize
+	}
+	var results []Result
+	add := func(r Result) {
+		results = append(results, r)
+		if onResult != nil {
+			onResult(r)
+		}
+	}
+	if len(opts.Modes) > 0 {
+		plain := randomData(size)
+		for _, mode := range opts.Modes {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			enc, dec, err := measureMode(ctx, mode, plain, opts.Workers)
+			if err != nil {
+				return results, fmt.Errorf("%s: %w", cryptoengine.GetEncryptionModeName(mode), err)
+			}
+			add(enc)
+			add(dec)
+		}
+	}
+	for _, name := range opts.KDFs {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		add(measureKDF(name))
+	}
+	if len(opts.Levels) > 0 {
+		sample, err := sampleData(opts.Sample, size)
+		if err != nil {
+			return results, fmt.Errorf("read sample: %w", err)
+		}
+		for _, level := range opts.Levels {
+			if err := ctx.Err(); err != nil {
+				return results, err
+			}
+			comp, decomp, err := measureCompression(level, sample)
+			if err != nil {
+				return results, fmt.Errorf("%s: %w", LevelName(level), err)
+			}
+			add(comp)
+			add(decomp)
+		}
+	}
+	return results, nil
+}
+
+// measureMode encrypts plain into memory and decrypts it again. The clock
+// starts once the key is derived, so only the cipher is measured.
+func measureMode(ctx context.Context, mode cryptoengine.EncryptionMode, plain []byte, workers int) (enc, dec Result, err error) {
+	name := cryptoengine.GetEncryptionModeName(mode)
+	var sealed bytes.Buffer
+	sealed.Grow(len(plain) + len(plain)/256 + 4096)
+	opts := cryptoengine.EncryptionOptions{Mode: mode, Argon2: cryptoengine.Argon2Preset("Fast"), Workers: workers}
+	if cryptoengine.NeedsKEMKey(mode) {
+		// the post-quantum modes encrypt to a key file; a throwaway one will do
+		key, err := cryptoengine.GenerateKEMKey(crand.Reader)
+		if err != nil {
+			return enc, dec, err
+		}
+		defer cryptoengine.AddKEMKey(key)()
+		opts.KEMKey = key
+	}
+	w, err := cryptoengine.NewEncryptingWriter(&sealed, password, opts)
+	if err != nil {
+		return enc, dec, err
+	}
+	start := time.Now()
+	if err := writeChunked(ctx, w, plain); err != nil {
+		w.Close()
+		return enc, dec, err
+	}
+	if err := w.Close(); err != nil {
+		return enc, dec, err
+	}
+	enc = Result{Kind: KindMode, Name: name, Operation: "encrypt", Bytes: int64(len(plain)), Duration: time.Since(start)}
+
+	r, err := cryptoengine.NewDecryptingReader(&sealed, password)
+	if err != nil {
+		return enc, dec, err
+	}
+	defer r.Close()
+	start = time.Now()
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return enc, dec, err
+	}
+	if n != int64(len(plain)) {
+		return enc, dec, fmt.Errorf("decrypted %d of %d bytes", n, len(plain))
+	}
+	dec = Result{Kind: KindMode, Name: name, Operation: "decrypt", Bytes: n, Duration: time.Since(start)}
+	return enc, dec, nil
+}
+
+// measureKDF derives one key with the Argon2id preset name
+func measureKDF(name string) Result {
+	p := cryptoengine.Argon2Preset(name)
+	salt := make([]byte, 16)
+	start := time.Now()
+	key := argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, 32)
+	d := time.Since(start)
+	cryptoengine.Wipe(key)
+	return Result{Kind: KindKDF, Name: fmt.Sprintf("%s (%s)", name, p), Operation: "derive", Duration: d}
+}
+
+// measureCompression compresses sample at level and decompresses it again
+func measureCompression(level compression.CompressionLevel, sample []byte) (comp, decomp Result, err error) {
+	c := compression.NewCompressor(level)
+	name := LevelName(level)
+	start := time.Now()
+	packed, err := c.CompressBytes(sample)
+	if err != nil {
+		return comp, decomp, err
+	}
+	comp = Result{Kind: KindCompression, Name: name, Operation: "compress", Bytes: int64(len(sample)), Duration: time.Since(start), Ratio: float64(len(packed)) / float64(max(len(sample), 1))}
+	start = time.Now()
+	if _, err := c.DecompressBytes(packed); err != nil {
+		return comp, decomp, err
+	}
+	decomp = Result{Kind: KindCompression, Name: name, Operation: "decompress", Bytes: int64(len(sample)), Duration: time.Since(start), Ratio: comp.Ratio}
+	return comp, decomp, nil
+}
+
+// writeChunked writes data in 1 MiB pieces so a cancel is noticed promptly
+func writeChunked(ctx context.Context, w io.Writer, data []byte) error {
+	for len(data) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := min(len(data), 1<<20)
+		if _, err := w.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// randomData returns size pseudo-random bytes; ciphers run at the same speed
+// on any input, so they need not be cryptographically random
+func randomData(size int64) []byte {
+	data := make([]byte, size)
+	rand.NewChaCha8([32]byte{}).Read(data)
+	return data
+}
+
+// sampleData reads up to size bytes of r, or without r builds a mix of
+// repetitive text and random bytes, roughly what a folder of documents holds
+func sampleData(r io.Reader, size int64) ([]byte, error) {
+	if r != nil {
+		return io.ReadAll(io.LimitReader(r, size))
+	}
+	data := make([]byte, 0, size)
+	noise := rand.NewChaCha8([32]byte{1})
+	for i := 0; int64(len(data)) < size; i++ {
+		if i%16 == 15 {
+			block := make([]byte, min(256, size-int64(len(data))))
+			noise.Read(block)
+			data = append(data, block...)
+			continue
+		}
+		line := fmt.Sprintf("%08d\tinvoice\t2024-%02d-%02d\tACME Corporation\t%d.%02d EUR\tpaid\n", i, i%12+1, i%28+1, i*37%10000, i%100)
+		data = append(data, line[:min(int64(len(line)), size-int64(len(data)))]...)
+	}
+	return data, nil
+}
+
+// csvHeader is the first row WriteCSV writes
+var csvHeader = []string{"kind", "name", "operation", "bytes", "seconds", "mb_per_s", "ratio"}
+
+// WriteCSV writes results as CSV with a header row
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		ratio := ""
+		if r.Kind == KindCompression {
+			ratio = strconv.FormatFloat(r.Ratio, 'f', 4, 64)
+		}
+		mbps := ""
+		if r.Bytes > 0 {
+			mbps = strconv.FormatFloat(r.MBps(), 'f', 2, 64)
+		}
+		row := []string{r.Kind, r.Name, r.Operation, strconv.FormatInt(r.Bytes, 10), strconv.FormatFloat(r.Duration.Seconds(), 'f', 6, 64), mbps, ratio}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,73 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/compression"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+func TestRun(t *testing.T) {
+	opts := Options{
+		Size:   3<<20 + 17,
+		Modes:  DefaultModes,
+		KDFs:   []string{"Fast"},
+		Levels: []compression.CompressionLevel{compression.BestSpeed},
+	}
+	var seen int
+	results, err := Run(context.Background(), opts, func(Result) { seen++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2*len(DefaultModes) + 1 + 2; len(results) != want || seen != want {
+		t.Fatalf("%d results, %d reported; want %d", len(results), seen, want)
+	}
+	for _, r := range results {
+		if r.Duration <= 0 {
+			t.Errorf("%s: duration %v", r, r.Duration)
+		}
+		if r.Kind != KindKDF && (r.Bytes != opts.Size || r.MBps() <= 0) {
+			t.Errorf("%s: %d bytes", r, r.Bytes)
+		}
+		if r.Kind == KindCompression && (r.Ratio <= 0 || r.Ratio >= 1) {
+			t.Errorf("%s: the sample should compress somewhat", r)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(results)+1 || strings.Join(rows[0], ",") != "kind,name,operation,bytes,seconds,mb_per_s,ratio" {
+		t.Fatalf("CSV header %v, %d rows", rows[0], len(rows))
+	}
+	if rows[1][1] != cryptoengine.GetEncryptionModeName(cryptoengine.ModeAES256GCM) || rows[1][2] != "encrypt" {
+		t.Errorf("first row %v", rows[1])
+	}
+}
+
+func TestRunSampleAndCancel(t *testing.T) {
+	sample := strings.NewReader(strings.Repeat("a", 1000))
+	results, err := Run(context.Background(), Options{Size: 1 << 20, Sample: sample, Levels: DefaultLevels}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2*len(DefaultLevels) || results[0].Bytes != 1000 {
+		t.Fatalf("results %v", results)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, Defaults(), nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled run: %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+// Package compare checks a restored file or folder against a reference copy,
+// byte by byte, and reports the offsets where they differ. A reference given
+// as a SHA-256 hex digest is compared by hash only.
+package compare
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// MaxRanges caps the differing ranges recorded per file
+const MaxRanges = 100
+
+const blockSize = 1 << 20
+
+// Range is a run of differing bytes within the length both files share
+type Range struct {
+	Offset int64
+	Length int64
+}
+
+// FileResult compares one restored file with its reference
+type FileResult struct {
+	Name             string // slash-separated path inside compared folders; empty for a file pair
+	RestoredSize     int64
+	ReferenceSize    int64
+	RestoredSHA256   string
+	ReferenceSHA256  string
+	Ranges           []Range // the first MaxRanges differing ranges
+	DifferentBytes   int64   // differing bytes within the shared length
+	MoreRanges       bool    // Ranges was cut at MaxRanges
+	MissingRestored  bool    // only the reference has this file
+	MissingReference bool    // only the restored side has this file
+	DigestOnly       bool    // the reference was a digest; no offsets or size are known
+}
+
+// Equal reports whether both sides exist and hold the same bytes
+func (f *FileResult) Equal() bool {
+	return !f.MissingRestored && !f.MissingReference && f.RestoredSize == f.ReferenceSize &&
+		f.RestoredSHA256 == f.ReferenceSHA256
+}
+
+// Result is the outcome of a comparison
+type Result struct {
+	Files []FileResult
+	Bytes int64 // bytes read from both sides
+}
+
+// Equal reports whether every file matched
+func (r *Result) Equal() bool {
+	for i := range r.Files {
+		if !r.Files[i].Equal() {
+			return false
+		}
+	}
+	return true
+}
+
+// Differences returns the files that did not match
+func (r *Result) Differences() []FileResult {
+	var out []FileResult
+	for _, f := range r.Files {
+		if !f.Equal() {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// IsDigest reports whether reference is a SHA-256 hex digest rather than a path
+func IsDigest(reference string) bool {
+	b, err := hex.DecodeString(strings.TrimSpace(reference))
+	return err == nil && len(b) == sha256.Size
+}
+
+// Compare compares restored with reference: two files, two folders (matched by
+// relative path, links skipped), or a file and a SHA-256 digest. onProgress,
+// which may be nil, receives bytes read over the total of both sides.
+func Compare(ctx context.Context, restored, reference string, onProgress func(done, total int64)) (*Result, error) {
+	c := &comparer{ctx: ctx, onProgress: onProgress}
+	ri, err := os.Stat(restored)
+	if err != nil {
+		return nil, err
+	}
+	if IsDigest(reference) {
+		if ri.IsDir() {
+			return nil, errors.New("a SHA-256 digest can only be compared with a file")
+		}
+		c.total = ri.Size()
+		f, err := c.digest(restored, reference)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Files: []FileResult{*f}, Bytes: c.done}, nil
+	}
+	fi, err := os.Stat(reference)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case ri.IsDir() && fi.IsDir():
+		return c.folders(restored, reference)
+	case ri.IsDir() || fi.IsDir():
+		return nil, errors.New("compare a file with a file, or a folder with a folder")
+	}
+	c.total = ri.Size() + fi.Size()
+	f, err := c.files(restored, reference)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Files: []FileResult{*f}, Bytes: c.done}, nil
+}
+
+type comparer struct {
+	ctx         context.Context
+	onProgress  func(done, total int64)
+	done, total int64
+}
+
+func (c *comparer) advance(n int64) error {
+	c.done += n
+	if c.onProgress != nil {
+		c.onProgress(c.done, c.total)
+	}
+	return c.ctx.Err()
+}
+
+// files compares two regular files block by block, recording differing ranges and both hashes
+func (c *comparer) files(restored, reference string) (*FileResult, error) {
+	a, err := os.Open(restored)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+	b, err := os.Open(reference)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+
+	res := &FileResult{}
+	ha, hb := sha256.New(), sha256.New()
+	bufA, bufB := make([]byte, blockSize), make([]byte, blockSize)
+	var off int64
+	inRun := false // the previous byte differed and its range is the last one recorded
+	for {
+		na, errA := readBlock(a, bufA)
+		nb, errB := readBlock(b, bufB)
+		if errA != nil {
+			return nil, fmt.Errorf("%s: %w", restored, errA)
+		}
+		if errB != nil {
+			return nil, fmt.Errorf("%s: %w", reference, errB)
+		}
+		ha.Write(bufA[:na])
+		hb.Write(bufB[:nb])
+		res.RestoredSize += int64(na)
+		res.ReferenceSize += int64(nb)
+		n := min(na, nb)
+		if bytes.Equal(bufA[:n], bufB[:n]) {
+			inRun = false
+		} else {
+			for i := 0; i < n; i++ {
+				if bufA[i] == bufB[i] {
+					inRun = false
+					continue
+				}
+				res.DifferentBytes++
+				switch {
+				case inRun:
+					res.Ranges[len(res.Ranges)-1].Length++
+				case len(res.Ranges) < MaxRanges:
+					res.Ranges = append(res.Ranges, Range{Offset: off + int64(i), Length: 1})
+					inRun = true
+				default:
+					res.MoreRanges = true
+				}
+			}
+		}
+		off += int64(n)
+		if err := c.advance(int64(na + nb)); err != nil {
+			return nil, err
+		}
+		if na < len(bufA) || nb < len(bufB) {
+			// one side ended; hash whatever remains of the other
+			if err := c.drain(ha, a, bufA, &res.RestoredSize); err != nil {
+				return nil, fmt.Errorf("%s: %w", restored, err)
+			}
+			if err := c.drain(hb, b, bufB, &res.ReferenceSize); err != nil {
+				return nil, fmt.Errorf("%s: %w", reference, err)
+			}
+			break
+		}
+	}
+	res.RestoredSHA256 = hex.EncodeToString(ha.Sum(nil))
+	res.ReferenceSHA256 = hex.EncodeToString(hb.Sum(nil))
+	return res, nil
+}
+
+// digest hashes restored and compares it with a hex digest
+func (c *comparer) digest(restored, want string) (*FileResult, error) {
+	f, err := os.Open(restored)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res := &FileResult{ReferenceSHA256: strings.ToLower(strings.TrimSpace(want)), DigestOnly: true}
+	h := sha256.New()
+	if err := c.drain(h, f, make([]byte, blockSize), &res.RestoredSize); err != nil {
+		return nil, err
+	}
+	res.RestoredSHA256 = hex.EncodeToString(h.Sum(nil))
+	res.ReferenceSize = res.RestoredSize
+	return res, nil
+}
+
+func (c *comparer) drain(h hash.Hash, r io.Reader, buf []byte, size *int64) error {
+	for {
+		n, err := readBlock(r, buf)
+		if err != nil {
+			return err
+		}
+		h.Write(buf[:n])
+		*size += int64(n)
+		if err := c.advance(int64(n)); err != nil {
+			return err
+		}
+		if n < len(buf) {
+			return nil
+		}
+	}
+}
+
+// readBlock fills buf unless the reader ends first
+func readBlock(r io.Reader, buf []byte) (int, error) {
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return n, err
+}
+
+// folders compares the regular files of two folders by relative path
+func (c *comparer) folders(restored, reference string) (*Result, error) {
+	a, err := listFiles(restored)
+	if err != nil {
+		return nil, err
+	}
+	b, err := listFiles(reference)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name, size := range a {
+		names = append(names, name)
+		c.total += size
+	}
+	for name, size := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+		c.total += size
+	}
+	slices.Sort(names)
+
+	res := &Result{}
+	for _, name := range names {
+		sa, inA := a[name]
+		sb, inB := b[name]
+		var f *FileResult
+		switch {
+		case !inA:
+			f = &FileResult{MissingRestored: true, ReferenceSize: sb}
+			err = c.advance(sb)
+		case !inB:
+			f = &FileResult{MissingReference: true, RestoredSize: sa}
+			err = c.advance(sa)
+		default:
+			f, err = c.files(filepath.Join(restored, filepath.FromSlash(name)), filepath.Join(reference, filepath.FromSlash(name)))
+		}
+		if err != nil {
+			return nil, err
+		}
+		f.Name = name
+		res.Files = append(res.Files, *f)
+	}
+	res.Bytes = c.done
+	return res, nil
+}
+
+// listFiles maps the slash-separated relative paths of the regular files below root to their sizes
+func listFiles(root string) (map[string]int64, error) {
+	files := map[string]int64{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	return files, err
+}
@@ -2,19 +2,137 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
 // Config represents the application configuration
 type Config struct {
-	Theme           string           `json:"theme"`           // "dark" or "light"
+	Theme           string           `json:"theme"`           // "dark", "light" or "high-contrast"
+	UIScale         int              `json:"ui_scale,omitempty"` // text and control size in percent; 0 means 100
+	DecimalUnits    bool             `json:"decimal_units,omitempty"` // show sizes in kB/MB (powers of 1000) instead of KiB/MiB
 	WindowWidth     float32          `json:"window_width"`
 	WindowHeight    float32          `json:"window_height"`
 	Argon2Defaults  Argon2Config     `json:"argon2_defaults"`
 	LastUsedProfile string           `json:"last_used_profile"`
-	History         []HistoryEntry   `json:"history"`
+	History         []HistoryEntry   `json:"history,omitempty"` // legacy; moved into the record store on start
 	Profiles        []Profile        `json:"profiles"`
+
+	// App lock (empty hash means no master password)
+	MasterPasswordHash string `json:"master_password_hash,omitempty"`
+	MasterPasswordSalt string `json:"master_password_salt,omitempty"`
+	AutoLockMinutes    int    `json:"auto_lock_minutes"` // 0 disables idle locking
+
+	// Compliance mode: only FIPS-approved algorithms (AES-256-GCM + PBKDF2)
+	ComplianceMode bool `json:"compliance_mode"`
+
+	// RFC 3161 trusted timestamping of encrypted outputs
+	TimestampEnabled bool   `json:"timestamp_enabled"`
+	TimestampURL     string `json:"timestamp_url,omitempty"` // empty uses the built-in default TSA
+
+	// Collect passwords through pinentry / the native OS dialog instead of the GUI entries
+	UseSystemPrompt bool `json:"use_system_prompt"`
+
+	// Low-priority mode: deprioritized worker thread and an optional read/write cap
+	LowPriority   bool    `json:"low_priority"`
+	RateLimitMBps float64 `json:"rate_limit_mbps,omitempty"` // 0 means unlimited
+
+	// Grow chunks from 64 KiB to 4 MiB while the input keeps up; faster on spinning disks and network shares
+	AdaptiveChunks bool `json:"adaptive_chunks"`
+
+	// Decompression bomb guard: extraction asks before writing more than this; 0 means unlimited
+	MaxExtractGB    float64 `json:"max_extract_gb"`
+	MaxExtractFiles int     `json:"max_extract_files"`
+
+	// Scan decrypted results with the platform antivirus before the summary; empty path finds one
+	AVScan        bool   `json:"av_scan"`
+	AVScannerPath string `json:"av_scanner_path,omitempty"`
+
+	// gpg binary for GnuPG mode; empty searches PATH and the usual install locations
+	GPGPath string `json:"gpg_path,omitempty"`
+
+	// SSH key encryption: private key used to decrypt .age files, and the last recipient list
+	SSHIdentity   string `json:"ssh_identity,omitempty"`
+	SSHRecipients string `json:"ssh_recipients,omitempty"`
+
+	// hadescrypt-relay server (host or host:port) for code-phrase transfers over the internet
+	RelayAddr string `json:"relay_addr,omitempty"`
+
+	// Two-way sync folders; passwords are asked for when a pair is started
+	SyncFolders []SyncFolder `json:"sync_folders,omitempty"`
+
+	// S3-compatible upload destinations; secret keys are never saved
+	Destinations []Destination `json:"destinations,omitempty"`
+
+	// Summaries of unattended work (CLI job runs, sync folders) sent to the configured sinks
+	Notifications Notifications `json:"notifications"`
+
+	// Workspace restored on the next start (never contains passwords)
+	Session *Session `json:"session,omitempty"`
+
+	// SHA-256 of the rest of the file, set by Save; a mismatch on load means damage or a hand edit
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// Session is the persisted workspace: selection, mode and any unfinished batch
+type Session struct {
+	SelectedPath   string        `json:"selected_path,omitempty"`
+	SelectedPaths  []string      `json:"selected_paths,omitempty"`
+	EncryptionMode string        `json:"encryption_mode,omitempty"`
+	Pending        *PendingBatch `json:"pending,omitempty"`
+}
+
+// SyncFolder pairs a plaintext folder with its encrypted mirror
+type SyncFolder struct {
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+}
+
+// Destination is an S3-compatible bucket encrypted files can be uploaded to
+type Destination struct {
+	Name        string `json:"name"`
+	Endpoint    string `json:"endpoint,omitempty"` // empty uses AWS for Region
+	Region      string `json:"region,omitempty"`
+	Bucket      string `json:"bucket"`
+	Prefix      string `json:"prefix,omitempty"` // key prefix, e.g. "backups/"
+	AccessKeyID string `json:"access_key_id,omitempty"`
+	PathStyle   bool   `json:"path_style,omitempty"` // bucket in the path instead of the host name
+
+	// Server-side safety
+	StorageClass string `json:"storage_class,omitempty"` // e.g. "STANDARD_IA", "GLACIER"; empty is the bucket default
+	LockMode     string `json:"lock_mode,omitempty"`     // Object Lock "GOVERNANCE" or "COMPLIANCE"; empty disables
+	LockDays     int    `json:"lock_days,omitempty"`     // retention period for LockMode
+	Checksum     bool   `json:"checksum,omitempty"`      // send x-amz-checksum-sha256 so the provider verifies and keeps it
+
+	RateLimitMBps float64 `json:"rate_limit_mbps,omitempty"` // upload bandwidth cap; 0 means unlimited
+}
+
+// Notifications configures where job summaries are sent. Empty sinks are skipped.
+type Notifications struct {
+	OnSuccess bool `json:"on_success"`
+	OnFailure bool `json:"on_failure"`
+
+	// SMTP email; the password comes from SMTPPasswordFile or $HADESCRYPT_SMTP_PASSWORD, never from here
+	SMTPHost         string `json:"smtp_host,omitempty"`
+	SMTPPort         int    `json:"smtp_port,omitempty"` // 465 uses implicit TLS, anything else STARTTLS; 0 means 587
+	SMTPUser         string `json:"smtp_user,omitempty"`
+	SMTPPasswordFile string `json:"smtp_password_file,omitempty"`
+	SMTPFrom         string `json:"smtp_from,omitempty"`
+	SMTPTo           string `json:"smtp_to,omitempty"` // comma-separated
+
+	WebhookURL  string `json:"webhook_url,omitempty"`  // receives a JSON POST
+	NtfyURL     string `json:"ntfy_url,omitempty"`     // server and topic, e.g. https://ntfy.sh/my-backups
+	NtfyToken   string `json:"ntfy_token,omitempty"`   // optional access token
+	GotifyURL   string `json:"gotify_url,omitempty"`   // server base URL
+	GotifyToken string `json:"gotify_token,omitempty"` // application token (can only post messages)
+}
+
+// PendingBatch is a multi-item operation that had not finished when it was last saved
+type PendingBatch struct {
+	Operation string   `json:"operation"` // "encrypt" or "decrypt"
+	Remaining []string `json:"remaining"`
+	Started   int64    `json:"started"` // Unix timestamp
 }
 
 // Argon2Config holds Argon2id parameters
@@ -32,6 +150,7 @@ type HistoryEntry struct {
 	Timestamp int64  `json:"timestamp"` // Unix timestamp
 	Result    string `json:"result"`    // "success" or "error"
 	Error     string `json:"error,omitempty"`
+	Output    string `json:"output,omitempty"` // full path of what was written, for revealing it later
 }
 
 // Profile represents a saved configuration preset
@@ -45,6 +164,18 @@ type Profile struct {
 	CompressFiles   bool   `json:"compress_files"`
 	DeniabilityMode bool   `json:"deniability_mode"`
 	RecursiveMode   bool   `json:"recursive_mode"`
+
+	EncryptionMode   string `json:"encryption_mode,omitempty"`   // mode name, e.g. "AES-256-GCM"
+	OutputExtension  string `json:"output_extension,omitempty"`  // ".hadescrypt" or ".heistcrypt"
+	CompressionLevel int    `json:"compression_level,omitempty"` // flate level; 0 means default
+	Argon2Preset     string `json:"argon2_preset,omitempty"`     // "Fast", "Balanced", "Strong" or "Maximum"
+	OutputDir        string `json:"output_dir,omitempty"`        // empty writes next to the input
+	SymlinkPolicy    string `json:"symlink_policy,omitempty"`    // "skip" (default), "follow" or "link"
+	OutputNames      string `json:"output_names,omitempty"`      // "keep" (default), "transliterate" or "percent"
+	GPGPath          string `json:"gpg_path,omitempty"`          // overrides the global gpg binary
+
+	// AbortOnUnreadable stops recursive encryption at a file it cannot read instead of skipping it
+	AbortOnUnreadable bool `json:"abort_on_unreadable,omitempty"`
 }
 
 // DefaultConfig returns a configuration with sensible defaults
@@ -53,24 +184,28 @@ func DefaultConfig() *Config {
 		Theme:        "dark",
 		WindowWidth:  800,
 		WindowHeight: 600,
+		MaxExtractGB:    100,
+		MaxExtractFiles: 1000000,
 		Argon2Defaults: Argon2Config{
 			Memory:      64 * 1024, // 64 MiB
 			Iterations:  1,
 			Parallelism: 4,
 		},
 		LastUsedProfile: "",
-		History:         []HistoryEntry{},
 		Profiles: []Profile{
 			{
-				Name:            "Fast Archive",
-				UseKeyfiles:     false,
-				ParanoidMode:    false,
-				ReedSolomon:     false,
-				ForceDecrypt:    false,
-				SplitOutput:     false,
-				CompressFiles:   true,
-				DeniabilityMode: false,
-				RecursiveMode:   true,
+				Name:             "Fast Archive",
+				UseKeyfiles:      false,
+				ParanoidMode:     false,
+				ReedSolomon:      false,
+				ForceDecrypt:     false,
+				SplitOutput:      false,
+				CompressFiles:    true,
+				DeniabilityMode:  false,
+				RecursiveMode:    true,
+				EncryptionMode:   "AES-256-GCM",
+				Argon2Preset:     "Fast",
+				CompressionLevel: 1,
 			},
 			{
 				Name:            "Ultra Secure",
@@ -82,17 +217,22 @@ func DefaultConfig() *Config {
 				CompressFiles:   false,
 				DeniabilityMode: true,
 				RecursiveMode:   false,
+				EncryptionMode:  "Paranoid (AES-256 + ChaCha20)",
+				Argon2Preset:    "Strong",
 			},
 			{
-				Name:            "Cloud Upload",
-				UseKeyfiles:     false,
-				ParanoidMode:    false,
-				ReedSolomon:     true,
-				ForceDecrypt:    false,
-				SplitOutput:     true,
-				CompressFiles:   true,
-				DeniabilityMode: false,
-				RecursiveMode:   false,
+				Name:             "Cloud Upload",
+				UseKeyfiles:      false,
+				ParanoidMode:     false,
+				ReedSolomon:      true,
+				ForceDecrypt:     false,
+				SplitOutput:      true,
+				CompressFiles:    true,
+				DeniabilityMode:  false,
+				RecursiveMode:    false,
+				EncryptionMode:   "ChaCha20-Poly1305",
+				Argon2Preset:     "Balanced",
+				CompressionLevel: 9,
 			},
 		},
 	}
@@ -116,7 +256,9 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(configDir, "config.json"), nil
 }
 
-// Load reads the configuration from disk
+// Load reads the configuration from disk. It always returns a usable
+// configuration: defaults when the file cannot be read or parsed (ErrCorrupt),
+// or the parsed contents when only the checksum is off (ErrChecksum).
 func Load() (*Config, error) {
 	configPath, err := GetConfigPath()
 	if err != nil {
@@ -133,23 +275,22 @@ func Load() (*Config, error) {
 		return DefaultConfig(), err
 	}
 
-	config := DefaultConfig()
-	if err := json.Unmarshal(data, config); err != nil {
-		return DefaultConfig(), err
-	}
-
-	return config, nil
+	return parse(configPath, data)
 }
 
-// Save writes the configuration to disk
+// Save writes the configuration to disk atomically, keeping the previous
+// version as a backup
 func (c *Config) Save() error {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return err
 	}
 
 	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(configDir, 0700); err != nil {
 		return err
 	}
 
@@ -158,27 +299,18 @@ func (c *Config) Save() error {
 		return err
 	}
 
+	if c.Checksum, err = c.sum(); err != nil {
+		return err
+	}
 	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(configPath, data, 0644)
-}
-
-// AddHistoryEntry adds a new entry to the history
-func (c *Config) AddHistoryEntry(entry HistoryEntry) {
-	c.History = append(c.History, entry)
-	
-	// Keep only the last 100 entries
-	if len(c.History) > 100 {
-		c.History = c.History[len(c.History)-100:]
+	if err := keepPrevious(configPath); err != nil {
+		return fmt.Errorf("back up %s: %w", configPath, err)
 	}
-}
-
-// ClearHistory removes all history entries
-func (c *Config) ClearHistory() {
-	c.History = []HistoryEntry{}
+	return writeFileAtomic(configPath, data, 0600)
 }
 
 // GetProfile returns a profile by name, or nil if not found
@@ -211,3 +343,13 @@ func (c *Config) DeleteProfile(name string) {
 		}
 	}
 }
+
+// GetDestination returns the destination called name, or nil
+func (c *Config) GetDestination(name string) *Destination {
+	for i := range c.Destinations {
+		if c.Destinations[i].Name == name {
+			return &c.Destinations[i]
+		}
+	}
+	return nil
+}
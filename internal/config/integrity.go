@@ -0,0 +1,162 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Backups are config.json.bak.1 (the previously saved version) up to
+// config.json.bak.<backupCount>; the older ones shift along at most once per backupInterval
+const (
+	backupCount    = 3
+	backupInterval = 24 * time.Hour
+	corruptSuffix  = ".corrupt"
+	checksumPrefix = "sha256:"
+)
+
+var (
+	// ErrCorrupt is returned by Load when config.json cannot be parsed; defaults are used instead
+	ErrCorrupt = errors.New("config file is damaged")
+	// ErrChecksum is returned by Load when config.json parses but does not match its
+	// checksum, because it was edited by hand or damaged; its contents are still used
+	ErrChecksum = errors.New("config file does not match its checksum")
+)
+
+// saveMu serializes writers within this process
+var saveMu sync.Mutex
+
+// sum returns the checksum of c's contents, ignoring the checksum field itself
+func (c *Config) sum() (string, error) {
+	cp := *c
+	cp.Checksum = ""
+	data, err := json.Marshal(&cp)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(data)
+	return checksumPrefix + hex.EncodeToString(h[:]), nil
+}
+
+// parse decodes a config file. A file without a checksum, as written by older
+// versions, is accepted.
+func parse(path string, data []byte) (*Config, error) {
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return DefaultConfig(), fmt.Errorf("%s: %w: %v", path, ErrCorrupt, err)
+	}
+	if cfg.Checksum == "" {
+		return cfg, nil
+	}
+	want, err := cfg.sum()
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.Checksum != want {
+		return cfg, fmt.Errorf("%s: %w", path, ErrChecksum)
+	}
+	return cfg, nil
+}
+
+// IsDamaged reports whether err from Load means the file needs attention
+func IsDamaged(err error) bool {
+	return errors.Is(err, ErrCorrupt) || errors.Is(err, ErrChecksum)
+}
+
+// backupPath returns the path of backup n (1 is the newest)
+func backupPath(configPath string, n int) string {
+	return configPath + ".bak." + strconv.Itoa(n)
+}
+
+// LastGoodBackup returns the newest backup that loads without errors, or an
+// empty path when there is none
+func LastGoodBackup() (string, *Config, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", nil, err
+	}
+	for n := 1; n <= backupCount; n++ {
+		p := backupPath(configPath, n)
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		if cfg, err := parse(p, data); err == nil {
+			return p, cfg, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// RestoreBackup makes the backup at path the current configuration and returns
+// it. The file it replaces is kept as config.json.corrupt if it was damaged.
+func RestoreBackup(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parse(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Save(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// writeFileAtomic replaces path with data through a synced temp file in the same
+// folder, so a crash leaves either the old or the new contents
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// keepPrevious backs up the config file about to be replaced. A file that loads
+// cleanly becomes backup 1; backup 1 first moves to 2 (and 2 to 3) when backup 2
+// is missing or at least backupInterval old. A damaged one is set aside as
+// config.json.corrupt and never enters the rotation.
+func keepPrevious(configPath string) error {
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := parse(configPath, data); err != nil {
+		return writeFileAtomic(configPath+corruptSuffix, data, 0600)
+	}
+	if fi, err := os.Stat(backupPath(configPath, 2)); err != nil || time.Since(fi.ModTime()) >= backupInterval {
+		for n := backupCount; n > 1; n-- {
+			if err := os.Rename(backupPath(configPath, n-1), backupPath(configPath, n)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return writeFileAtomic(backupPath(configPath, 1), data, 0600)
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tempConfig points the config folder at a new home and returns the config path
+func tempConfig(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	path, err := GetConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// saveTheme saves the defaults with theme, which tells the versions apart
+func saveTheme(t *testing.T, theme string) *Config {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Theme = theme
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// themeOf returns the theme of the config file at path, or "" when it is missing
+func themeOf(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := parse(path, data)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return cfg.Theme
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := tempConfig(t)
+	want := DefaultConfig()
+	want.Theme = "light"
+	want.RelayAddr = "relay.example.com"
+	want.SyncFolders = []SyncFolder{{Local: "/home/me/docs", Remote: "/mnt/backup/docs"}}
+	if err := want.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(want.Checksum, checksumPrefix) {
+		t.Fatalf("Save set checksum %q", want.Checksum)
+	}
+	got, err := Load()
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Load: %+v, %v\nwant %+v", got, err, want)
+	}
+
+	// files written before checksums were added still load
+	if err := os.WriteFile(path, []byte(`{"theme": "dark"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Load(); err != nil || got.Theme != "dark" {
+		t.Errorf("Load without a checksum: %q, %v", got.Theme, err)
+	}
+}
+
+func TestLoadHandEdited(t *testing.T) {
+	path := tempConfig(t)
+	saveTheme(t, "light")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(data), `"theme": "light"`, `"theme": "dark"`, 1)
+	if edited == string(data) {
+		t.Fatalf("theme not found in %s", data)
+	}
+	if err := os.WriteFile(path, []byte(edited), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// the edited contents are used, but flagged
+	got, err := Load()
+	if !errors.Is(err, ErrChecksum) || !IsDamaged(err) || got.Theme != "dark" {
+		t.Errorf("Load: %q, %v; want the edited theme and ErrChecksum", got.Theme, err)
+	}
+}
+
+func TestLoadGarbage(t *testing.T) {
+	path := tempConfig(t)
+	saveTheme(t, "v1")
+	saveTheme(t, "v2")
+	garbage := []byte("{\"theme\": \"v\x00\x00\x00")
+	if err := os.WriteFile(path, garbage, 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load()
+	if !errors.Is(err, ErrCorrupt) || !IsDamaged(err) || !reflect.DeepEqual(got, DefaultConfig()) {
+		t.Fatalf("Load: %+v, %v; want the defaults and ErrCorrupt", got, err)
+	}
+
+	// saving over the damaged file sets it aside instead of rotating it into the backups
+	saveTheme(t, "v3")
+	if kept, err := os.ReadFile(path + corruptSuffix); err != nil || string(kept) != string(garbage) {
+		t.Errorf("%s: %q, %v", corruptSuffix, kept, err)
+	}
+	if got := themeOf(t, backupPath(path, 1)); got != "v1" {
+		t.Errorf("backup 1 has theme %q, want the last good version", got)
+	}
+	if got := themeOf(t, path); got != "v3" {
+		t.Errorf("config has theme %q after saving", got)
+	}
+}
+
+func TestBackupRotation(t *testing.T) {
+	path := tempConfig(t)
+	backups := func() []string {
+		var themes []string
+		for n := 1; n <= backupCount; n++ {
+			themes = append(themes, themeOf(t, backupPath(path, n)))
+		}
+		return themes
+	}
+	check := func(step string, want ...string) {
+		t.Helper()
+		if got := backups(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: backups %q, want %q", step, got, want)
+		}
+	}
+
+	saveTheme(t, "v1")
+	check("first save", "", "", "")
+	saveTheme(t, "v2")
+	check("second save", "v1", "", "")
+	saveTheme(t, "v3")
+	check("backup 2 missing", "v2", "v1", "")
+	// backup 2 is fresh, so only backup 1 is replaced
+	saveTheme(t, "v4")
+	check("within the interval", "v3", "v1", "")
+
+	old := time.Now().Add(-backupInterval - time.Hour)
+	if err := os.Chtimes(backupPath(path, 2), old, old); err != nil {
+		t.Fatal(err)
+	}
+	saveTheme(t, "v5")
+	check("after the interval", "v4", "v3", "v1")
+	saveTheme(t, "v6")
+	check("fresh again", "v5", "v3", "v1")
+	if got := themeOf(t, path); got != "v6" {
+		t.Errorf("config has theme %q", got)
+	}
+}
+
+func TestLastGoodBackup(t *testing.T) {
+	path := tempConfig(t)
+	if p, cfg, err := LastGoodBackup(); p != "" || cfg != nil || err != nil {
+		t.Fatalf("LastGoodBackup without backups: %q, %v, %v", p, cfg, err)
+	}
+
+	saveTheme(t, "v1")
+	saveTheme(t, "v2")
+	saveTheme(t, "v3")
+	// backup 1 is damaged, so the newest good one is backup 2
+	if err := os.WriteFile(backupPath(path, 1), []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, cfg, err := LastGoodBackup()
+	if err != nil || p != backupPath(path, 2) || cfg == nil || cfg.Theme != "v1" {
+		t.Fatalf("LastGoodBackup: %q, %+v, %v", p, cfg, err)
+	}
+
+	garbage := []byte("\x00\x01garbage")
+	if err := os.WriteFile(path, garbage, 0600); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := RestoreBackup(p)
+	if err != nil || restored.Theme != "v1" {
+		t.Fatalf("RestoreBackup: %+v, %v", restored, err)
+	}
+	if got, err := Load(); err != nil || got.Theme != "v1" {
+		t.Errorf("Load after RestoreBackup: %q, %v", got.Theme, err)
+	}
+	if kept, err := os.ReadFile(path + corruptSuffix); err != nil || string(kept) != string(garbage) {
+		t.Errorf("the replaced file was not kept: %q, %v", kept, err)
+	}
+
+	if _, err := RestoreBackup(backupPath(path, 1)); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("RestoreBackup of a damaged backup: got %v, want ErrCorrupt", err)
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+)
+
+// SecureDir restricts the config directory and everything in it to the current
+// user (0700/0600 on Unix, an owner-only DACL on Windows). Earlier versions
+// created config.json world-readable, exposing history and file names to other
+// local users, so this runs on every start. Public keys (*.pub) keep their mode.
+func SecureDir() error {
+	dir, err := GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return securetemp.Restrict(path, true)
+		case d.Type().IsRegular() && !strings.HasSuffix(d.Name(), ".pub"):
+			return securetemp.Restrict(path, false)
+		}
+		return nil
+	})
+}
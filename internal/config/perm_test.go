@@ -0,0 +1,80 @@
+//go:build !windows
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Mode().Perm()
+}
+
+func TestSecureDirFixesExistingInstall(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".hadescrypt")
+	if err := os.MkdirAll(filepath.Join(dir, "sync"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]os.FileMode{
+		"config.json":       0600,
+		"sync/state.json":   0600,
+		"identity.pub":      0644,
+		"index.hadesidx":    0600,
+		"config.json.bak.1": 0600,
+	}
+	for name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := SecureDir(); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{dir, filepath.Join(dir, "sync")} {
+		if got := mode(t, d); got != 0700 {
+			t.Errorf("%s: mode %04o, want 0700", d, got)
+		}
+	}
+	for name, want := range files {
+		if got := mode(t, filepath.Join(dir, name)); got != want {
+			t.Errorf("%s: mode %04o, want %04o", name, got, want)
+		}
+	}
+}
+
+func TestSecureDirWithoutInstall(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := SecureDir(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveWritesPrivateFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfg := DefaultConfig()
+	for i := 0; i < 2; i++ {
+		if err := cfg.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, err := GetConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]os.FileMode{filepath.Dir(path): 0700, path: 0600, path + ".bak.1": 0600} {
+		if got := mode(t, p); got != want {
+			t.Errorf("%s: mode %04o, want %04o", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package contacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+)
+
+const contactsFileName = "contacts.json"
+
+// Contact is a person and the public key files are encrypted to for them
+type Contact struct {
+	Name      string `json:"name"`
+	Recipient string `json:"recipient"` // age1… X25519 key or an ssh-ed25519/ssh-rsa line
+	Note      string `json:"note,omitempty"`
+	Added     int64  `json:"added"` // Unix timestamp
+}
+
+// Fingerprint returns the contact's short key fingerprint
+func (c Contact) Fingerprint() string { return Fingerprint(c.Recipient) }
+
+// Book is the contact list stored in the config directory
+type Book struct {
+	path     string
+	Contacts []Contact `json:"contacts"`
+}
+
+// BookPath returns the contact book location inside the config directory
+func BookPath() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, contactsFileName), nil
+}
+
+// LoadBook reads the contact book at path; a missing file is an empty book
+func LoadBook(path string) (*Book, error) {
+	b := &Book{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("invalid contact book: %w", err)
+	}
+	return b, nil
+}
+
+// Save writes the book back to its file
+func (b *Book) Save() error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(b.path, data, 0600)
+}
+
+// Add stores a contact, replacing one with the same name. validate checks the
+// recipient before anything is stored.
+func (b *Book) Add(c Contact, validate func(recipient string) error) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Recipient = strings.TrimSpace(c.Recipient)
+	if c.Name == "" {
+		return errors.New("a contact needs a name")
+	}
+	if validate != nil {
+		if err := validate(c.Recipient); err != nil {
+			return err
+		}
+	}
+	if c.Added == 0 {
+		c.Added = time.Now().Unix()
+	}
+	for i := range b.Contacts {
+		if b.Contacts[i].Name == c.Name {
+			b.Contacts[i] = c
+			return nil
+		}
+	}
+	b.Contacts = append(b.Contacts, c)
+	sort.Slice(b.Contacts, func(i, j int) bool { return strings.ToLower(b.Contacts[i].Name) < strings.ToLower(b.Contacts[j].Name) })
+	return nil
+}
+
+// Remove deletes the contact name
+func (b *Book) Remove(name string) {
+	for i := range b.Contacts {
+		if b.Contacts[i].Name == name {
+			b.Contacts = append(b.Contacts[:i], b.Contacts[i+1:]...)
+			return
+		}
+	}
+}
+
+// Get returns the contact name
+func (b *Book) Get(name string) (Contact, bool) {
+	for _, c := range b.Contacts {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Contact{}, false
+}
@@ -0,0 +1,219 @@
+// Package contacts stores personal X25519 keypairs and the contact book of
+// recipients used when encrypting to other people's keys. Keypairs are age
+// X25519 identities; the secret half is kept passphrase-encrypted at rest.
+package contacts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"filippo.io/age"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+)
+
+const identitiesDirName = "identities"
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ErrWrongPassphrase means the passphrase does not unlock the keypair
+var ErrWrongPassphrase = errors.New("wrong passphrase for the keypair")
+
+// Keypair is an own X25519 key; the secret stays encrypted until Unlock
+type Keypair struct {
+	Name      string
+	Recipient string // age1… public key to hand out
+	dir       string
+}
+
+// Fingerprint returns the short fingerprint of the public key
+func (k *Keypair) Fingerprint() string { return Fingerprint(k.Recipient) }
+
+// Fingerprint returns a short, human-comparable fingerprint of a recipient
+// string: the first 64 bits of its SHA-256 as four hex groups
+func Fingerprint(recipient string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(recipient)))
+	h := fmt.Sprintf("%X", sum[:8])
+	return h[0:4] + " " + h[4:8] + " " + h[8:12] + " " + h[12:16]
+}
+
+// IdentitiesDir returns the keypair store inside the config directory
+func IdentitiesDir() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, identitiesDirName), nil
+}
+
+// Generate creates a keypair named name in dir with its secret encrypted under passphrase
+func Generate(dir, name, passphrase string) (*Keypair, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid key name %q", name)
+	}
+	if passphrase == "" {
+		return nil, errors.New("a passphrase is required to protect the keypair")
+	}
+	if _, err := os.Stat(filepath.Join(dir, name+".pub")); err == nil {
+		return nil, fmt.Errorf("a keypair named %q already exists", name)
+	}
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, err
+	}
+	sealer, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var sealed bytes.Buffer
+	w, err := age.Encrypt(&sealed, sealer)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, id.String()+"\n"); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".age"), sealed.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to store keypair: %w", err)
+	}
+	recipient := id.Recipient().String()
+	if err := os.WriteFile(filepath.Join(dir, name+".pub"), []byte(recipient+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to store public key: %w", err)
+	}
+	return &Keypair{Name: name, Recipient: recipient, dir: dir}, nil
+}
+
+// ListKeypairs returns the keypairs in dir sorted by name
+func ListKeypairs(dir string) ([]*Keypair, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []*Keypair
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".pub")
+		if !ok || e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		recipient := strings.TrimSpace(string(data))
+		if _, err := age.ParseX25519Recipient(recipient); err != nil {
+			continue
+		}
+		keys = append(keys, &Keypair{Name: name, Recipient: recipient, dir: dir})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys, nil
+}
+
+// DeleteKeypair removes the keypair name from dir
+func DeleteKeypair(dir, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid key name %q", name)
+	}
+	if err := os.Remove(filepath.Join(dir, name+".age")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(filepath.Join(dir, name+".pub"))
+}
+
+// Unlock decrypts the secret half with passphrase
+func (k *Keypair) Unlock(passphrase string) (*age.X25519Identity, error) {
+	sealed, err := os.Open(filepath.Join(k.dir, k.Name+".age"))
+	if err != nil {
+		return nil, err
+	}
+	defer sealed.Close()
+	opener, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(sealed, opener)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, ErrWrongPassphrase
+		}
+		return nil, fmt.Errorf("unlock %s: %w", k.Name, err)
+	}
+	text, err := io.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return nil, fmt.Errorf("unlock %s: %w", k.Name, err)
+	}
+	id, err := age.ParseX25519Identity(strings.TrimSpace(string(text)))
+	if err != nil {
+		return nil, fmt.Errorf("unlock %s: %w", k.Name, err)
+	}
+	if id.Recipient().String() != k.Recipient {
+		return nil, fmt.Errorf("private and public key for %q do not match", k.Name)
+	}
+	return id, nil
+}
+
+// Identity returns one age identity for all keypairs. Their secrets are
+// unlocked with passphrase only when a file has X25519 recipients; if none of
+// them unlocks, decryption fails with ErrWrongPassphrase.
+func Identity(keys []*Keypair, passphrase func() (string, error)) age.Identity {
+	return &lazyIdentity{keys: keys, passphrase: passphrase}
+}
+
+type lazyIdentity struct {
+	keys       []*Keypair
+	passphrase func() (string, error)
+	unlocked   []*age.X25519Identity
+	tried      bool
+}
+
+func (l *lazyIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	x25519 := false
+	for _, s := range stanzas {
+		x25519 = x25519 || s.Type == "X25519"
+	}
+	if !x25519 || len(l.keys) == 0 {
+		return nil, age.ErrIncorrectIdentity
+	}
+	if !l.tried {
+		pass, err := l.passphrase()
+		if err != nil {
+			return nil, err
+		}
+		l.tried = true
+		for _, k := range l.keys {
+			if id, err := k.Unlock(pass); err == nil {
+				l.unlocked = append(l.unlocked, id)
+			}
+		}
+	}
+	if len(l.unlocked) == 0 {
+		return nil, ErrWrongPassphrase
+	}
+	for _, id := range l.unlocked {
+		key, err := id.Unwrap(stanzas)
+		if errors.Is(err, age.ErrIncorrectIdentity) {
+			continue
+		}
+		return key, err
+	}
+	return nil, age.ErrIncorrectIdentity
+}
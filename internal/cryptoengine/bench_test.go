@@ -0,0 +1,76 @@
+package cryptoengine
+
+// Chunk-loop throughput, 64 MiB random input, go test -bench . -benchtime 10x
+// (Intel Xeon, linux/amd64). "before" allocated a fresh sealed/ciphertext slice
+// per chunk; "after" reuses pooled buffers and opens in place. The Encrypt
+// benchmarks seal on one worker; EncryptAESGCMParallel uses every core.
+//
+//	                   before                          after
+//	EncryptAESGCM      500 MB/s  68.8 MB/op   93 allocs   669 MB/s  0.39 MB/op  31 allocs
+//	EncryptChaCha20    399 MB/s  68.7 MB/op   92 allocs   469 MB/s  0.38 MB/op  29 allocs
+//	EncryptParanoid    288 MB/s 136.5 MB/op  181 allocs   353 MB/s  0.77 MB/op  56 allocs
+//	DecryptAESGCM      492 MB/s 134.8 MB/op  157 allocs   646 MB/s  0.07 MB/op  30 allocs
+//	DecryptChaCha20    403 MB/s 134.8 MB/op  156 allocs   471 MB/s  0.07 MB/op  29 allocs
+//	DecryptParanoid    333 MB/s 202.5 MB/op  245 allocs   417 MB/s  0.14 MB/op  54 allocs
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// benchKDF keeps key derivation negligible so the benchmarks measure the chunk loop
+var benchKDF = Argon2Params{Time: 1, Memory: 64, Threads: 1}
+
+const benchSize = 64 << 20
+
+func benchInput(b *testing.B) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "plain.bin")
+	data := make([]byte, benchSize)
+	rand.Read(data)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func benchmarkEncrypt(b *testing.B, mode EncryptionMode, workers int) {
+	in := benchInput(b)
+	out := in + ".hadescrypt"
+	opts := EncryptionOptions{Mode: mode, Argon2: benchKDF, Workers: workers, KEMKey: testKEMKey}
+	b.SetBytes(benchSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		if err := EncryptFileWithOptions(in, out, []byte("bench"), opts, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkDecrypt(b *testing.B, mode EncryptionMode) {
+	in := benchInput(b)
+	enc := in + ".hadescrypt"
+	if err := EncryptFileWithOptions(in, enc, []byte("bench"), EncryptionOptions{Mode: mode, Argon2: benchKDF, KEMKey: testKEMKey}, nil); err != nil {
+		b.Fatal(err)
+	}
+	out := in + ".out"
+	b.SetBytes(benchSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		if err := DecryptFile(enc, out, []byte("bench"), false, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncryptAESGCM(b *testing.B)         { benchmarkEncrypt(b, ModeAES256GCM, 1) }
+func BenchmarkEncryptChaCha20(b *testing.B)       { benchmarkEncrypt(b, ModeChaCha20, 1) }
+func BenchmarkEncryptParanoid(b *testing.B)       { benchmarkEncrypt(b, ModeParanoid, 1) }
+func BenchmarkEncryptAESGCMParallel(b *testing.B) { benchmarkEncrypt(b, ModeAES256GCM, 0) }
+func BenchmarkDecryptAESGCM(b *testing.B)         { benchmarkDecrypt(b, ModeAES256GCM) }
+func BenchmarkDecryptChaCha20(b *testing.B)       { benchmarkDecrypt(b, ModeChaCha20) }
+func BenchmarkDecryptParanoid(b *testing.B)       { benchmarkDecrypt(b, ModeParanoid) }
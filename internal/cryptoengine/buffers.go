@@ -0,0 +1,22 @@
+package cryptoengine
+
+import "sync"
+
+// chunkPool recycles chunk-sized buffers between files so batch runs do not
+// allocate two fresh megabytes per file. Buffers are wiped before reuse.
+var chunkPool sync.Pool
+
+// getBuffer returns a zero-length slice with capacity of at least n
+func getBuffer(n int) []byte {
+	if p, ok := chunkPool.Get().(*[]byte); ok && cap(*p) >= n {
+		return (*p)[:0]
+	}
+	return make([]byte, 0, n)
+}
+
+// putBuffer wipes b and hands it back to the pool
+func putBuffer(b []byte) {
+	b = b[:cap(b)]
+	Wipe(b)
+	chunkPool.Put(&b)
+}
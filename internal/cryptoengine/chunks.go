@@ -0,0 +1,216 @@
+package cryptoengine
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// chunkSealer seals the chunks of one container. Chunks are independent, their
+// nonces coming from the counter, so any number of goroutines may seal at once.
+type chunkSealer struct {
+	chunkSize   int
+	noncePrefix []byte
+	aead        cipher.AEAD
+	outer       cipher.AEAD    // second layer of ModeParanoid and ModeParanoidSerpent
+	key         []byte         // to seal the header
+	stream      bool           // end with a short chunk, empty if the input fills the last one
+	framed      bool           // put the frame word in front of every chunk
+	sizer       *adaptiveSizer // sizes framed chunks; nil seals chunkSize bytes each
+}
+
+// newChunkSealer sets up the ciphers of the container hdr describes. A KEM
+// header gets its file key here, drawn from random.
+func newChunkSealer(password []byte, hdr *Header, random io.Reader) (*chunkSealer, error) {
+	key, err := newFileKey(password, hdr, random)
+	if err != nil {
+		return nil, err
+	}
+	o, err := newChunkOpenerKey(key, password, hdr)
+	if err != nil {
+		Wipe(key)
+		return nil, err
+	}
+	return &chunkSealer{
+		chunkSize:   hdr.ChunkSize,
+		noncePrefix: hdr.NoncePrefix,
+		aead:        o.aead,
+		outer:       o.aead2,
+		key:         o.key,
+		stream:      hdr.endsShort() || hdr.Flags&FlagFramed != 0,
+		framed:      hdr.Flags&FlagFramed != 0,
+	}, nil
+}
+
+// chunkJob is one chunk on its way through encryptChunks
+type chunkJob struct {
+	counter  uint32
+	plain    []byte // as long as the chunk; n bytes are read
+	n        int
+	last     bool // the input ended in this chunk
+	sealBuf  []byte
+	outerBuf []byte
+	sealed   []byte
+	err      error
+	done     chan struct{}
+}
+
+// seal encrypts j.plain[:j.n] into j.sealed, reusing the job's buffers
+func (s *chunkSealer) seal(j *chunkJob) {
+	plain := j.plain[:j.n]
+	// XChaCha20 takes a longer nonce; it is zero past the counter
+	nonce := make([]byte, s.aead.NonceSize())
+	copy(nonce[:noncePrefixLen], s.noncePrefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixLen:], j.counter)
+	// a framed chunk is sealed behind room for its frame word, which both
+	// layers authenticate
+	prefix := 0
+	var word []byte
+	if s.framed {
+		prefix, word = frameLenSize, frameWord(j.n, j.last)
+	}
+	j.sealBuf = fitBuffer(j.sealBuf, prefix+len(plain)+s.aead.Overhead())
+	j.sealed = s.aead.Seal(j.sealBuf[:prefix], nonce, plain, word)
+	if s.outer != nil {
+		j.outerBuf = fitBuffer(j.outerBuf, len(j.sealed)+s.outer.Overhead())
+		nonce2 := make([]byte, s.outer.NonceSize())
+		copy(nonce2, nonce[:min(len(nonce2), len(nonce))])
+		j.sealed = s.outer.Seal(j.outerBuf[:prefix], nonce2, j.sealed[prefix:], word)
+	}
+	copy(j.sealed, word)
+}
+
+// fitBuffer returns b, or a pooled buffer in its place when b holds less than n bytes
+func fitBuffer(b []byte, n int) []byte {
+	if cap(b) >= n {
+		return b
+	}
+	if b != nil {
+		putBuffer(b)
+	}
+	return getBuffer(n)
+}
+
+// release hands the job's buffers back to the pool
+func (j *chunkJob) release() {
+	for _, b := range [][]byte{j.plain, j.sealBuf, j.outerBuf} {
+		if b != nil {
+			putBuffer(b)
+		}
+	}
+}
+
+// encryptChunks reads in until EOF, seals it chunk by chunk on workers
+// goroutines (0 uses the CPU count) and writes the sealed chunks to out in
+// order, so the output does not depend on workers. It returns the plaintext
+// bytes written. At most two chunks per worker are held in memory.
+func (s *chunkSealer) encryptChunks(in io.Reader, out io.Writer, workers int, total int64, onProgress ProgressCallback) (int64, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	inFlight := 2 * workers
+	free := make(chan *chunkJob, inFlight)
+	for range inFlight {
+		free <- &chunkJob{}
+	}
+	// ordered has room for every job, so the reader never waits on the writer
+	ordered := make(chan *chunkJob, inFlight)
+	work := make(chan *chunkJob)
+	quit := make(chan struct{})
+
+	pipeline.workers.Add(int64(workers))
+	defer pipeline.workers.Add(-int64(workers))
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for j := range work {
+				pipeline.queued.Add(-1)
+				start := beginChunk()
+				s.seal(j)
+				endChunk(start)
+				close(j.done)
+			}
+		})
+	}
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(ordered)
+		defer close(work)
+		readErr <- s.readChunks(in, free, ordered, work, quit)
+	}()
+
+	var processed int64
+	var err error
+	for j := range ordered {
+		<-j.done
+		if err == nil {
+			if err = j.err; err == nil {
+				if _, err = out.Write(j.sealed); err == nil {
+					processed += int64(j.n)
+					if onProgress != nil {
+						onProgress(processed, total)
+					}
+				}
+			}
+			if err != nil {
+				close(quit)
+			}
+		}
+		free <- j
+	}
+	wg.Wait()
+	if rerr := <-readErr; err == nil {
+		err = rerr
+	}
+	for range inFlight {
+		(<-free).release()
+	}
+	return processed, err
+}
+
+// readChunks fills free jobs from in and queues them in order until EOF or quit
+func (s *chunkSealer) readChunks(in io.Reader, free chan *chunkJob, ordered, work chan<- *chunkJob, quit <-chan struct{}) error {
+	var j *chunkJob
+	defer func() {
+		// a job taken but not queued goes back, so every job is released
+		if j != nil {
+			free <- j
+		}
+	}()
+	for counter := uint32(0); ; counter++ {
+		select {
+		case j = <-free:
+		case <-quit:
+			return nil
+		}
+		size := s.chunkSize
+		if s.sizer != nil {
+			size = s.sizer.size
+		}
+		j.plain = fitBuffer(j.plain, size)[:size]
+		start := time.Now()
+		n, err := io.ReadFull(in, j.plain)
+		if s.sizer != nil {
+			s.sizer.observe(n, time.Since(start))
+		}
+		if errors.Is(err, io.EOF) && !s.stream {
+			return nil
+		}
+		last := err != nil
+		if last && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		j.counter, j.n, j.last, j.sealed, j.err, j.done = counter, n, last, nil, nil, make(chan struct{})
+		ordered <- j
+		pipeline.queued.Add(1)
+		work <- j
+		j = nil
+		if last {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cryptoengine
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Compliance mode restricts containers to FIPS 140 approved primitives:
+// AES-256-GCM for data and PBKDF2-HMAC-SHA256 (NIST SP 800-132) for key derivation.
+const compliancePBKDF2Iterations = 600000
+
+// ComplianceModes lists the encryption modes allowed in compliance mode
+var ComplianceModes = []EncryptionMode{ModeAES256GCM}
+
+// IsComplianceMode reports whether mode may be used in compliance mode
+func IsComplianceMode(mode EncryptionMode) bool {
+	for _, m := range ComplianceModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// deriveKey derives the file key using the KDF selected by the header flags
+func deriveKey(password []byte, h *Header) ([]byte, error) {
+	if h.Compliance() {
+		key, err := pbkdf2.Key(sha256.New, string(password), h.Salt, compliancePBKDF2Iterations, int(keyLen))
+		if err != nil {
+			return nil, fmt.Errorf("derive key: %w", err)
+		}
+		return key, nil
+	}
+	p := h.KDFParams()
+	return argon2.IDKey(password, h.Salt, p.Time, p.Memory, p.Threads, keyLen), nil
+}
@@ -0,0 +1,131 @@
+package cryptoengine
+
+import (
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bangundwir/HadesCrypt/internal/compression"
+)
+
+// Containers written with UseCompression carry FlagCompressed: the plaintext
+// is deflated before it is split into chunks, and ORIGINAL_SIZE keeps the size
+// before compression, or is 0 in a stream. The size of the Deflate stream is
+// not known until it ends, so its chunks are framed and end with the last
+// frame; containers from before framing end with a short chunk as in streams.
+// Decryption inflates transparently and checks the result against
+// ORIGINAL_SIZE. Compressed containers cannot be read at random.
+
+// compressionLevel maps EncryptionOptions.CompressionLevel to a flate level
+func compressionLevel(level int) compression.CompressionLevel {
+	if level == 0 {
+		return compression.DefaultCompression
+	}
+	return compression.CompressionLevel(level)
+}
+
+// deflating returns a reader of the Deflate stream of in. Closing it stops
+// the compressing goroutine.
+func deflating(in io.Reader, level int) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(compression.NewCompressor(compressionLevel(level)).CompressStream(in, pw))
+	}()
+	return pr
+}
+
+// inflatedSize returns the size the Deflate stream of h must inflate to, or -1
+// for a compressed stream, whose size is not recorded
+func (h *Header) inflatedSize() int64 {
+	if h.Flags&FlagStream != 0 {
+		return -1
+	}
+	return h.OriginalSize
+}
+
+// inflatingWriter inflates what is written to it into out on its own
+// goroutine. Close reports whether exactly size bytes, or any number if size
+// is negative, came out of a complete Deflate stream.
+type inflatingWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func newInflatingWriter(out io.Writer, size int64) *inflatingWriter {
+	pr, pw := io.Pipe()
+	w := &inflatingWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := inflate(pr, out, size)
+		// writes after the end of the stream or a failure must not block
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w
+}
+
+func (w *inflatingWriter) Write(p []byte) (int, error) { return w.pw.Write(p) }
+
+func (w *inflatingWriter) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
+
+// inflate writes the Deflate stream read from src to out
+func inflate(src io.Reader, out io.Writer, size int64) error {
+	_, err := io.Copy(out, newInflatingReader(src, size))
+	return err
+}
+
+// inflatingReader reads the plaintext of the Deflate stream in src, failing
+// with ErrCorrupt unless it holds exactly the recorded size, if that is not
+// negative, and nothing follows it
+type inflatingReader struct {
+	src       io.Reader
+	fr        io.ReadCloser
+	remaining int64
+}
+
+func newInflatingReader(src io.Reader, size int64) *inflatingReader {
+	return &inflatingReader{src: src, fr: flate.NewReader(src), remaining: size}
+}
+
+func (r *inflatingReader) Read(p []byte) (int, error) {
+	n, err := r.fr.Read(p)
+	if r.remaining >= 0 {
+		if int64(n) > r.remaining {
+			return 0, fmt.Errorf("%w: the compressed stream inflates past its recorded size", ErrCorrupt)
+		}
+		r.remaining -= int64(n)
+	}
+	switch {
+	case err == io.EOF && r.remaining > 0:
+		err = fmt.Errorf("%w: the compressed stream ends %d bytes short", ErrCorrupt, r.remaining)
+	case err == io.EOF:
+		if m, _ := io.ReadFull(r.src, make([]byte, 1)); m != 0 {
+			err = fmt.Errorf("%w: data after the compressed stream", ErrCorrupt)
+		}
+	case err != nil:
+		var corrupt flate.CorruptInputError
+		if errors.As(err, &corrupt) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+	}
+	return n, err
+}
+
+// progressReader reports the bytes read through it to onProgress
+type progressReader struct {
+	r          io.Reader
+	n, total   int64
+	onProgress ProgressCallback
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if n > 0 && r.onProgress != nil {
+		r.onProgress(r.n, r.total)
+	}
+	return n, err
+}
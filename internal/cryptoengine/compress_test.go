@@ -0,0 +1,139 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// encryptCompressed deflates and encrypts data with mode and returns the container
+func encryptCompressed(t *testing.T, mode EncryptionMode, data []byte) string {
+	t.Helper()
+	in := filepath.Join(t.TempDir(), "plain.txt")
+	if err := os.WriteFile(in, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := in + ".hadescrypt"
+	opts := EncryptionOptions{Mode: mode, Argon2: testKDF, UseCompression: true, CompressionLevel: 1, KEMKey: testKEMKey}
+	var last int64
+	err := EncryptFileWithOptions(in, out, testPassword, opts, func(done, total int64) {
+		if total != int64(len(data)) || done < last {
+			t.Errorf("progress %d/%d after %d", done, total, last)
+		}
+		last = done
+	})
+	if err != nil {
+		t.Fatalf("encrypt %s, %d bytes: %v", GetEncryptionModeName(mode), len(data), err)
+	}
+	if last != int64(len(data)) {
+		t.Errorf("progress ended at %d of %d", last, len(data))
+	}
+	return out
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog\n", 3*testChunk/44+1))
+	for _, mode := range []EncryptionMode{ModeAES256GCM, ModeParanoidSerpent} {
+		for _, size := range []int{0, 1, testChunk, len(text)} {
+			t.Run(fmt.Sprintf("%s/%d", GetEncryptionModeName(mode), size), func(t *testing.T) {
+				t.Parallel()
+				want := text[:size]
+				enc := encryptCompressed(t, mode, want)
+
+				hdr, err := ReadHeaderFromFile(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.Flags&(FlagCompressed|FlagFramed|FlagStream) != FlagCompressed|FlagFramed || hdr.OriginalSize != int64(size) {
+					t.Errorf("header: flags %#x, size %d", hdr.Flags, hdr.OriginalSize)
+				}
+				fi, err := os.Stat(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				opts := EncryptionOptions{Mode: mode, Argon2: testKDF, UseCompression: true, KEMKey: testKEMKey}
+				if est, err := EncryptedSize(hdr.CompressedSize, opts); err != nil || est != fi.Size() {
+					t.Errorf("EncryptedSize(%d) = %d, %v; the container is %d bytes", hdr.CompressedSize, est, err, fi.Size())
+				}
+				if size > testChunk && fi.Size() >= int64(size)/10 {
+					t.Errorf("%d bytes of text took %d bytes", size, fi.Size())
+				}
+
+				got, err := decryptBytes(enc, testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("decrypted %d bytes differ from the %d bytes encrypted", len(got), len(want))
+				}
+				f, err := os.Open(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				r, err := NewDecryptingReader(f, testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, want) {
+					t.Fatalf("DecryptingReader: %d bytes, %v", len(got), err)
+				}
+			})
+		}
+	}
+}
+
+func TestCompressedTruncated(t *testing.T) {
+	// random data does not compress, so the Deflate stream spans several chunks
+	_, noise := writePlain(t, 2*testChunk+100)
+	enc := encryptCompressed(t, ModeChaCha20, noise)
+	data, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cut := range []int{gcmOverhead, 100} {
+		path := filepath.Join(t.TempDir(), "short.hadescrypt")
+		if err := os.WriteFile(path, data[:len(data)-cut], 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decryptBytes(path, testPassword); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("cut %d: got %v, want ErrCorrupt", cut, err)
+		}
+	}
+}
+
+func TestCompressedSizeMismatch(t *testing.T) {
+	enc := encryptCompressed(t, ModeAES256GCM, []byte(strings.Repeat("a", 5000)))
+	hdr, err := ReadHeaderFromFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the size is not authenticated, but the inflated data must match it
+	data, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, sizeAt := headerOffsets(t, enc)
+	data[sizeAt+7]++
+	if err := os.WriteFile(enc, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptBytes(enc, testPassword); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("header raised to %d+1 bytes: got %v, want ErrCorrupt", hdr.OriginalSize, err)
+	}
+}
+
+func TestCompressedUnsupported(t *testing.T) {
+	enc := encryptCompressed(t, ModeAES256GCM, []byte("hello"))
+	if _, err := OpenReader(enc, testPassword); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("OpenReader: got %v, want ErrUnsupported", err)
+	}
+	if _, err := Repair(enc, enc+".fixed", testPassword, nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Repair: got %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,88 @@
+package cryptoengine
+
+import (
+	"io"
+	"os"
+)
+
+// containerFile is the container stored in a file: the file itself, or the
+// container its Deniability Mode wrapping holds, unwrapped as it is read
+type containerFile struct {
+	*io.SectionReader
+	f    *os.File
+	wrap *deniableKey // nil unless the file is wrapped
+}
+
+// openContainer opens the container at path. With a password, a file without
+// the magic that the password unwraps is read through its wrapping; any other
+// file is read as it is, so parsing its header fails with ErrNotContainer.
+func openContainer(path string, password []byte) (*containerFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	c := &containerFile{SectionReader: io.NewSectionReader(f, 0, fi.Size()), f: f}
+	if password == nil {
+		return c, nil
+	}
+	k, err := unwrapKey(f, fi.Size(), password)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if k != nil {
+		start := int64(deniablePrefixLen(password))
+		c.SectionReader = io.NewSectionReader(&deniableReaderAt{k: k, r: f, start: start}, 0, fi.Size()-start)
+		c.wrap = k
+	}
+	return c, nil
+}
+
+// Close wipes the key of the wrapping and closes the file
+func (c *containerFile) Close() error {
+	if c.wrap != nil {
+		c.wrap.wipe()
+	}
+	return c.f.Close()
+}
+
+// readHeader parses the header as readFileHeader does, noting a wrapping in
+// Header.Deniable
+func (c *containerFile) readHeader() (*Header, error) {
+	h, err := readFileHeader(io.NewSectionReader(c, 0, c.Size()))
+	if err == nil {
+		h.Deniable = c.wrap != nil
+	}
+	return h, err
+}
+
+// decoded returns the container as decryption reads it from the start: c
+// itself, or with Reed-Solomon parity the container without it, decoded and
+// repaired block by block as it is read
+func (c *containerFile) decoded() (*io.SectionReader, error) {
+	r := io.NewSectionReader(c, 0, c.Size())
+	h, err := ReadHeader(r)
+	if err != nil || h.Flags&FlagReedSolomon == 0 {
+		// decryption reports a bad header itself
+		return io.NewSectionReader(c, 0, c.Size()), nil
+	}
+	return newParityReaderAt(c, c.Size(), h)
+}
+
+// ReadHeaderWithPassword parses the header of the container at path like
+// ReadHeaderFromFile, and also of Deniability Mode output that password
+// unwraps, which then has Header.Deniable set. Unwrapping costs an Argon2id
+// run for files that do not start with the magic.
+func ReadHeaderWithPassword(path string, password []byte) (*Header, error) {
+	c, err := openContainer(path, password)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.readHeader()
+}
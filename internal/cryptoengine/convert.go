@@ -0,0 +1,59 @@
+package cryptoengine
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Convert re-encrypts the container at inputPath into outputPath with opts in a
+// single streamed pass: decrypted chunks are handed straight to the encryptor,
+// so no plaintext is written to disk. The password stays the same and the
+// header metadata is carried over, as is Deniability Mode, so a hidden
+// container stays hidden. outputPath only exists afterwards if the whole input
+// authenticated; GnuPG containers cannot be converted either way.
+func Convert(inputPath, outputPath string, password []byte, opts EncryptionOptions, onProgress ProgressCallback) (err error) {
+	hdr, err := ReadHeaderWithPassword(inputPath, password)
+	if err != nil {
+		return err
+	}
+	if hdr.Mode == ModeGnuPG || opts.Mode == ModeGnuPG {
+		return fmt.Errorf("%w: GnuPG containers cannot be converted", ErrUnsupported)
+	}
+	opts.Metadata = hdr.Metadata
+	opts.UseDeniability = opts.UseDeniability || hdr.Deniable
+
+	pr, pw := io.Pipe()
+	decrypted := make(chan error, 1)
+	go func() {
+		err := DecryptFileToWriter(inputPath, pw, password, nil)
+		pw.CloseWithError(err)
+		decrypted <- err
+	}()
+	err = EncryptReaderWithOptions(pr, hdr.OriginalSize, outputPath, password, opts, onProgress)
+	pr.CloseWithError(err)
+	// The decryption error is the cause; the encryptor only saw the pipe break
+	if derr := <-decrypted; derr != nil && err != nil {
+		err = derr
+	}
+	if err != nil {
+		os.Remove(outputPath)
+	}
+	return err
+}
+
+// Matches reports whether the container was written with the mode, compliance
+// setting and key derivation of opts, i.e. converting it would change nothing
+func (h *Header) Matches(opts EncryptionOptions) bool {
+	if h.Mode != opts.Mode || h.Compliance() != opts.Compliance {
+		return false
+	}
+	if opts.Compliance {
+		return true
+	}
+	want := opts.Argon2
+	if want.IsZero() {
+		want = DefaultArgon2
+	}
+	return h.KDFParams() == want
+}
@@ -3,7 +3,6 @@ package cryptoengine
 import (
     "crypto/aes"
     "crypto/cipher"
-    "crypto/rand"
     "encoding/binary"
     "errors"
     "fmt"
@@ -12,8 +11,6 @@ import (
 
     "golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/chacha20poly1305"
-	
-	"github.com/bangundwir/HadesCrypt/internal/postquantum"
 )
 
 // min returns the minimum of two integers
@@ -34,10 +31,12 @@ const (
 	ModeAES256GCM EncryptionMode = iota
 	ModeChaCha20
 	ModeParanoid // AES-256-GCM + ChaCha20-Poly1305
-	ModePostQuantumKyber768
-	ModePostQuantumDilithium3
-	ModePostQuantumSPHINCS
+	ModePostQuantumKyber768   // AES-256-GCM with the file key wrapped under the password and ML-KEM-768 + X25519, see kem.go; decrypt-only without FlagKEM, see legacypq.go
+	ModePostQuantumDilithium3 // decrypt-only, see legacypq.go: signature schemes cannot encrypt
+	ModePostQuantumSPHINCS    // decrypt-only, see legacypq.go: signature schemes cannot encrypt
 	ModeGnuPG // GnuPG/OpenPGP encryption
+	ModeHybridPQ // AES-256-GCM under the Argon2id key combined with an ML-KEM-768 secret, see kem.go
+	ModeParanoidSerpent // XChaCha20-Poly1305 + Serpent-CTR/HMAC-SHA256, see serpent.go
 )
 
 const (
@@ -52,13 +51,26 @@ const (
 // EncryptionOptions holds options for encryption
 type EncryptionOptions struct {
 	Mode            EncryptionMode
-	Comments        string
-	UseCompression  bool
-	UseReedSolomon  bool
-	UseDeniability  bool
+	Comments        string // stored as Metadata.Comment when that is empty
+	UseCompression  bool // deflate the plaintext before it is sealed; see compress.go
+	CompressionLevel int // flate level with UseCompression; 0 is the library default
+	AdaptiveChunks  bool // grow chunks with the speed of the input; see framing.go
+	UseReedSolomon  bool // add Reed-Solomon parity to the encrypted data; see parity.go
+	UseDeniability  bool // wrap the whole container so it reads as random bytes; see deniable.go
 	SplitSize       int64 // 0 means no splitting
+	Compliance      bool  // restrict to FIPS-approved algorithms and stamp FlagCompliance
+	Argon2          Argon2Params // zero value uses DefaultArgon2; ignored in compliance mode
+	GPGPath         string // gpg binary for ModeGnuPG; empty searches the system
+	Recipients      []string // ModeGnuPG public-key recipients; empty encrypts with the password
+	KEMKey          *KEMKey // the post-quantum key ModePostQuantumKyber768 and ModeHybridPQ encrypt to, see kemkey.go
+	Metadata        Metadata // written to the header and sealed with the file key; see metadata.go
+	DeterministicSeed []byte // tests and CI only: derive salt and nonces from this seed; see deterministic.go
+	Workers         int // chunks sealed concurrently; 0 uses the CPU count. The output is the same for any value
 }
 
+// encryptChunkSize is the plaintext per chunk of new containers, balancing memory and speed
+const encryptChunkSize = 1 << 20
+
 // Argon2id parameters (balanced for desktop)
 var (
     argonTime    uint32 = 1
@@ -72,17 +84,23 @@ func EncryptFile(inputPath, outputPath string, password []byte, onProgress Progr
 	return EncryptFileWithMode(inputPath, outputPath, password, ModeAES256GCM, onProgress)
 }
 
-// EncryptFileWithOptions encrypts inputPath -> outputPath using specified options.
-// The output format header:
-// [4]MAGIC "HAD1" | [1]VERSION | [1]MODE | [1]FLAGS | [16]SALT | [8]NONCE_PREFIX | [4]CHUNK_SIZE | [8]ORIGINAL_SIZE | [2]COMMENT_LEN | [..]COMMENT | [..]CIPHERTEXT
-func EncryptFileWithOptions(inputPath, outputPath string, password []byte, opts EncryptionOptions, onProgress ProgressCallback) error {
-	return EncryptFileWithMode(inputPath, outputPath, password, opts.Mode, onProgress)
-}
-
 // EncryptFileWithMode encrypts inputPath -> outputPath using specified encryption mode.
-// The output format header:
-// [4]MAGIC "HAD1" | [1]VERSION | [1]MODE | [16]SALT | [8]NONCE_PREFIX | [4]CHUNK_SIZE | [8]ORIGINAL_SIZE | [..]CIPHERTEXT
 func EncryptFileWithMode(inputPath, outputPath string, password []byte, mode EncryptionMode, onProgress ProgressCallback) error {
+	return EncryptFileWithOptions(inputPath, outputPath, password, EncryptionOptions{Mode: mode}, onProgress)
+}
+
+// EncryptFileWithOptions encrypts inputPath -> outputPath using specified options.
+// The output format header (see Header):
+// [4]MAGIC "HAD1" | [1]VERSION | [1]MODE | ([1]FLAGS [9]KDF, v2 only) | [16]SALT | [8]NONCE_PREFIX | [4]CHUNK_SIZE | [8]ORIGINAL_SIZE |
+// (METADATA [32]MAC, v2 with a comment, hint or name) | [..]CIPHERTEXT
+func EncryptFileWithOptions(inputPath, outputPath string, password []byte, opts EncryptionOptions, onProgress ProgressCallback) error {
+    if opts.Mode == ModeGnuPG && !opts.Compliance {
+        if opts.UseDeniability {
+            return fmt.Errorf("%w: GnuPG output cannot be hidden by Deniability Mode", ErrUnsupported)
+        }
+        // GnuPG mode uses external GPG binary, handled separately
+        return encryptFileWithGnuPG(opts.GPGPath, opts.Recipients, inputPath, outputPath, password, onProgress)
+    }
     in, err := os.Open(inputPath)
     if err != nil {
         return err
@@ -93,72 +111,32 @@ func EncryptFileWithMode(inputPath, outputPath string, password []byte, mode Enc
     if err != nil {
         return err
     }
-    totalSize := st.Size()
+    return EncryptReaderWithOptions(in, st.Size(), outputPath, password, opts, onProgress)
+}
 
-    // Prepare header fields
-    salt := make([]byte, saltLengthBytes)
-    if _, err := io.ReadFull(rand.Reader, salt); err != nil {
-        return fmt.Errorf("generate salt: %w", err)
+// EncryptReaderWithOptions encrypts exactly size bytes read from in -> outputPath,
+// so plaintext can be streamed from another decryption without touching the disk.
+// ModeGnuPG needs a file and is not supported.
+func EncryptReaderWithOptions(in io.Reader, size int64, outputPath string, password []byte, opts EncryptionOptions, onProgress ProgressCallback) (err error) {
+    if opts.Mode == ModeGnuPG {
+        return fmt.Errorf("%w: GnuPG mode encrypts files, not streams", ErrUnsupported)
     }
-    noncePrefix := make([]byte, noncePrefixLen)
-    if _, err := io.ReadFull(rand.Reader, noncePrefix); err != nil {
-        return fmt.Errorf("generate nonce prefix: %w", err)
+    random, err := randomSource(opts)
+    if err != nil {
+        return err
     }
-
-    key := argon2.IDKey(password, salt, argonTime, argonMemory, argonThreads, keyLen)
-
-    // Create cipher based on mode
-    var aead cipher.AEAD
-    var aead2 cipher.AEAD // For paranoid mode
-    var pqCipher *postquantum.PostQuantumCipher // For post-quantum modes
-    
-    switch mode {
-    case ModeAES256GCM:
-        block, err := aes.NewCipher(key)
-        if err != nil {
-            return err
-        }
-        aead, err = cipher.NewGCM(block)
-        if err != nil {
-            return err
-        }
-    case ModeChaCha20:
-        aead, err = chacha20poly1305.New(key)
-        if err != nil {
-            return err
-        }
-    case ModeParanoid:
-        // First layer: AES-256-GCM
-    block, err := aes.NewCipher(key)
+    hdr, err := newHeader(opts, size, random)
     if err != nil {
         return err
     }
-        aead, err = cipher.NewGCM(block)
+    sealer, err := newChunkSealer(password, hdr, random)
     if err != nil {
         return err
-        }
-        
-        // Second layer: ChaCha20-Poly1305 (derive different key)
-        key2 := argon2.IDKey(append(password, []byte("paranoid")...), salt, argonTime*2, argonMemory, argonThreads, keyLen)
-        aead2, err = chacha20poly1305.New(key2)
-        if err != nil {
-            return err
-        }
-    case ModePostQuantumKyber768:
-        pqCipher = postquantum.NewPostQuantumCipher(postquantum.Kyber768)
-    case ModePostQuantumDilithium3:
-        pqCipher = postquantum.NewPostQuantumCipher(postquantum.Dilithium3)
-    case ModePostQuantumSPHINCS:
-        pqCipher = postquantum.NewPostQuantumCipher(postquantum.SPHINCS)
-    case ModeGnuPG:
-        // GnuPG mode uses external GPG binary, handled separately
-        return EncryptFileWithGnuPG(inputPath, outputPath, password, onProgress)
-    default:
-        return fmt.Errorf("unsupported encryption mode: %d", mode)
     }
-
-    // Choose chunk size to balance memory and speed
-    const chunkSize = 1 << 20 // 1 MiB plaintext per chunk
+    sealer.sizer = newAdaptiveSizer(hdr, opts)
+    if err := hdr.seal(sealer.key); err != nil {
+        return err
+    }
 
     out, err := os.Create(outputPath)
     if err != nil {
@@ -170,200 +148,200 @@ func EncryptFileWithMode(inputPath, outputPath string, password []byte, mode Enc
             err = cerr
         }
     }()
-
-    // Write header
-    if _, err := out.Write([]byte(fileMagic)); err != nil {
-        return err
-    }
-    if _, err := out.Write([]byte{fileVersion}); err != nil {
-        return err
+    var dst io.Writer = out
+    if opts.UseDeniability {
+        wrapped, err := newDeniableWriter(out, password, random)
+        if err != nil {
+            return err
+        }
+        defer wrapped.k.wipe()
+        dst = wrapped
     }
-    if _, err := out.Write([]byte{byte(mode)}); err != nil {
+    if _, err := dst.Write(hdr.Bytes()); err != nil {
         return err
     }
-    if _, err := out.Write(salt); err != nil {
-        return err
+    var body io.Writer = dst
+    if hdr.Flags&FlagReedSolomon != 0 {
+        parity, err := newParityWriter(dst, hdr)
+        if err != nil {
+            return err
+        }
+        // the last block is written once everything else succeeded
+        defer func() {
+            if err == nil {
+                err = parity.Close()
+            }
+        }()
+        body = parity
     }
-    if _, err := out.Write(noncePrefix); err != nil {
-        return err
+
+    if opts.UseCompression {
+        // progress follows the plaintext, since the compressed size is not known
+        plain := &progressReader{r: in, total: size, onProgress: onProgress}
+        deflated := deflating(plain, opts.CompressionLevel)
+        defer deflated.Close()
+        if _, err := sealer.encryptChunks(deflated, body, opts.Workers, 0, nil); err != nil {
+            return err
+        }
+        if plain.n != size {
+            return fmt.Errorf("input ended after %d of %d bytes", plain.n, size)
+        }
+        return nil
     }
-    // chunk size uint32
-    var tmp4 [4]byte
-    binary.BigEndian.PutUint32(tmp4[:], uint32(chunkSize))
-    if _, err := out.Write(tmp4[:]); err != nil {
+
+    // Chunks are sealed concurrently into pooled buffers and written in order
+    processed, err := sealer.encryptChunks(in, body, opts.Workers, size, onProgress)
+    if err != nil {
         return err
     }
-    // original size uint64
-    var tmp8 [8]byte
-    binary.BigEndian.PutUint64(tmp8[:], uint64(totalSize))
-    if _, err := out.Write(tmp8[:]); err != nil {
-        return err
+    if processed != size {
+        return fmt.Errorf("input ended after %d of %d bytes", processed, size)
     }
+    return nil
+}
 
-    buf := make([]byte, chunkSize)
-    processed := int64(0)
-    var counter uint32 = 0
-    nonce := make([]byte, gcmNonceLen)
-    copy(nonce[:noncePrefixLen], noncePrefix)
-
-    for {
-        n, readErr := io.ReadFull(in, buf)
-        if errors.Is(readErr, io.ErrUnexpectedEOF) {
-            // last partial chunk
-            if n > 0 {
-                binary.BigEndian.PutUint32(nonce[noncePrefixLen:], counter)
-                
-                var sealed []byte
-                
-                // Choose encryption method based on mode
-                if pqCipher != nil {
-                    // Post-quantum encryption
-                    pqNonce, err := pqCipher.GenerateNonce()
-                    if err != nil {
-                        return fmt.Errorf("generate PQ nonce: %w", err)
-                    }
-                    sealed, err = pqCipher.Encrypt(buf[:n], key, pqNonce)
-                    if err != nil {
-                        return fmt.Errorf("PQ encrypt: %w", err)
-                    }
-                    // Prepend nonce to ciphertext
-                    sealed = append(pqNonce, sealed...)
-                } else {
-                    // Traditional AEAD encryption
-                    sealed = aead.Seal(nil, nonce, buf[:n], nil)
-                    
-                    // Apply second layer encryption for paranoid mode
-                    if mode == ModeParanoid {
-                        nonce2 := make([]byte, aead2.NonceSize())
-                        copy(nonce2, nonce[:min(len(nonce2), len(nonce))])
-                        sealed = aead2.Seal(nil, nonce2, sealed, nil)
-                    }
-                }
-                
-                if _, err := out.Write(sealed); err != nil {
-                    return err
-                }
-                processed += int64(n)
-                if onProgress != nil {
-                    onProgress(processed, totalSize)
-                }
-            }
-            break
+// newHeader validates opts and returns the unsealed header of a container of size
+// plaintext bytes, with the salt and nonce prefix drawn from random
+func newHeader(opts EncryptionOptions, size int64, random io.Reader) (*Header, error) {
+    hdr := &Header{Mode: opts.Mode, Argon2: opts.Argon2, ChunkSize: encryptChunkSize, OriginalSize: size}
+    if opts.Compliance {
+        if !IsComplianceMode(opts.Mode) {
+            return nil, fmt.Errorf("%s is not allowed in compliance mode", GetEncryptionModeName(opts.Mode))
         }
-        if errors.Is(readErr, io.EOF) {
-            break
+        if opts.UseDeniability {
+            return nil, fmt.Errorf("Deniability Mode is not allowed in compliance mode")
         }
-        if readErr != nil && readErr != io.ErrUnexpectedEOF {
-            return readErr
+        hdr.Flags |= FlagCompliance
+    } else if !opts.Argon2.IsZero() && opts.Argon2 != DefaultArgon2 {
+        if err := opts.Argon2.validate(); err != nil {
+            return nil, err
         }
-
-        binary.BigEndian.PutUint32(nonce[noncePrefixLen:], counter)
-        
-        var sealed []byte
-        
-        // Choose encryption method based on mode
-        if pqCipher != nil {
-            // Post-quantum encryption
-            pqNonce, err := pqCipher.GenerateNonce()
-            if err != nil {
-                return fmt.Errorf("generate PQ nonce: %w", err)
-            }
-            sealed, err = pqCipher.Encrypt(buf[:n], key, pqNonce)
-            if err != nil {
-                return fmt.Errorf("PQ encrypt: %w", err)
-            }
-            // Prepend nonce to ciphertext
-            sealed = append(pqNonce, sealed...)
-        } else {
-            // Traditional AEAD encryption
-            sealed = aead.Seal(nil, nonce, buf[:n], nil)
-            
-            // Apply second layer encryption for paranoid mode
-            if mode == ModeParanoid {
-                // Use different nonce for second layer
-                nonce2 := make([]byte, aead2.NonceSize())
-                copy(nonce2, nonce[:min(len(nonce2), len(nonce))])
-                sealed = aead2.Seal(nil, nonce2, sealed, nil)
-            }
+        hdr.Flags |= FlagArgon2Params
+    }
+    // the Deflate stream ends at the last frame, since its size is not known up front
+    if opts.UseCompression {
+        hdr.Flags |= FlagCompressed | FlagFramed
+        hdr.MinChunkSize = hdr.ChunkSize
+    }
+    if opts.AdaptiveChunks {
+        hdr.Flags |= FlagFramed
+        hdr.ChunkSize, hdr.MinChunkSize = adaptiveMaxChunk, adaptiveMinChunk
+    }
+    if opts.UseReedSolomon {
+        hdr.Flags |= FlagReedSolomon
+        hdr.DataShards, hdr.ParityShards = parityDataShards, parityParityShards
+    }
+    switch opts.Mode {
+    case ModePostQuantumKyber768, ModeHybridPQ:
+        if opts.DeterministicSeed != nil {
+            return nil, fmt.Errorf("%w: ML-KEM draws its own randomness, so %s output cannot be deterministic", ErrUnsupported, GetEncryptionModeName(opts.Mode))
         }
-        
-        if _, err := out.Write(sealed); err != nil {
-            return err
+        if opts.KEMKey == nil {
+            return nil, fmt.Errorf("%w: %s encrypts to a post-quantum key", ErrKEMKeyMissing, GetEncryptionModeName(opts.Mode))
         }
-        processed += int64(n)
-        if onProgress != nil {
-            onProgress(processed, totalSize)
-        }
-        counter++
+        hdr.Flags |= FlagKEM
+        hdr.kemKey = opts.KEMKey
+    case ModePostQuantumDilithium3, ModePostQuantumSPHINCS:
+        return nil, fmt.Errorf("%w: %s is a signature scheme and cannot encrypt; use %s", ErrUnsupported, GetEncryptionModeName(opts.Mode), GetEncryptionModeName(ModePostQuantumKyber768))
     }
+    if opts.Metadata.Comment == "" {
+        opts.Metadata.Comment = opts.Comments
+    }
+    if err := opts.Metadata.Validate(); err != nil {
+        return nil, err
+    }
+    hdr.SetMetadata(opts.Metadata)
 
-    return nil
+    hdr.Salt = make([]byte, saltLengthBytes)
+    if _, err := io.ReadFull(random, hdr.Salt); err != nil {
+        return nil, fmt.Errorf("generate salt: %w", err)
+    }
+    hdr.NoncePrefix = make([]byte, noncePrefixLen)
+    if _, err := io.ReadFull(random, hdr.NoncePrefix); err != nil {
+        return nil, fmt.Errorf("generate nonce prefix: %w", err)
+    }
+    return hdr, nil
 }
 
 // DecryptFile decrypts inputPath -> outputPath using the encryption mode stored in the file.
 // If force is true, the function still returns error on auth failure (AEAD cannot bypass),
 // but the flag is provided to align with UI; future modes may try salvage.
+// A container split into volumes is joined first; inputPath may name it or any volume.
 func DecryptFile(inputPath, outputPath string, password []byte, force bool, onProgress ProgressCallback) error {
-    in, err := os.Open(inputPath)
+    inputPath, done, err := joinVolumes(inputPath, outputPath)
     if err != nil {
         return err
     }
-    defer in.Close()
-
-    // Read and validate header
-    header := make([]byte, 4)
-    if _, err := io.ReadFull(in, header); err != nil {
-        return err
-    }
-    if string(header) != fileMagic {
-        return fmt.Errorf("invalid file format")
+    defer done()
+    err = decryptFile(inputPath, password, force, func() (io.WriteCloser, error) {
+        return os.Create(outputPath)
+    }, onProgress)
+    if errors.Is(err, errGnuPGMode) {
+        return DecryptFileWithGnuPG(inputPath, outputPath, password, onProgress)
     }
+    return err
+}
 
-    ver := make([]byte, 1)
-    if _, err := io.ReadFull(in, ver); err != nil {
-        return err
-    }
-    if ver[0] != fileVersion {
-        return fmt.Errorf("unsupported version: %d", ver[0])
+// DecryptFileToWriter decrypts inputPath into w without touching the filesystem.
+// GnuPG containers are not supported because gpg always writes to a file.
+func DecryptFileToWriter(inputPath string, w io.Writer, password []byte, onProgress ProgressCallback) error {
+    err := decryptFile(inputPath, password, false, func() (io.WriteCloser, error) {
+        return nopWriteCloser{w}, nil
+    }, onProgress)
+    if errors.Is(err, errGnuPGMode) {
+        return fmt.Errorf("GnuPG containers cannot be decrypted in memory")
     }
+    return err
+}
 
-    // Read encryption mode
-    modeBytes := make([]byte, 1)
-    if _, err := io.ReadFull(in, modeBytes); err != nil {
-        return err
-    }
-    mode := EncryptionMode(modeBytes[0])
+// errGnuPGMode signals that the container must be handed to the GnuPG backend.
+var errGnuPGMode = errors.New("gnupg mode")
 
-    salt := make([]byte, saltLengthBytes)
-    if _, err := io.ReadFull(in, salt); err != nil {
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// decryptFile holds the HAD1 decryption loop; openOut is only called once the header is valid.
+func decryptFile(inputPath string, password []byte, force bool, openOut func() (io.WriteCloser, error), onProgress ProgressCallback) (err error) {
+    // a Deniability Mode wrapping is removed and Reed-Solomon parity decoded,
+    // repairing what it can, as the container is read
+    c, err := openContainer(inputPath, password)
+    if err != nil {
         return err
     }
-    noncePrefix := make([]byte, noncePrefixLen)
-    if _, err := io.ReadFull(in, noncePrefix); err != nil {
+    defer c.Close()
+    in, err := c.decoded()
+    if err != nil {
         return err
     }
 
-    var tmp4 [4]byte
-    if _, err := io.ReadFull(in, tmp4[:]); err != nil {
+    // Read and validate header
+    hdr, err := readFileHeader(in)
+    if err != nil {
         return err
     }
-    chunkSize := int(binary.BigEndian.Uint32(tmp4[:]))
+    mode := hdr.Mode
+    salt := hdr.Salt
+    noncePrefix := hdr.NoncePrefix
+    chunkSize := hdr.ChunkSize
+    totalSize := hdr.sealedSize()
 
-    var tmp8 [8]byte
-    if _, err := io.ReadFull(in, tmp8[:]); err != nil {
+    key, err := fileKey(password, hdr)
+    if err != nil {
         return err
     }
-    totalSize := int64(binary.BigEndian.Uint64(tmp8[:]))
-
-    key := argon2.IDKey(password, salt, argonTime, argonMemory, argonThreads, keyLen)
+    kdf := hdr.KDFParams()
     
     // Create AEAD cipher based on mode
     var aead cipher.AEAD
     var aead2 cipher.AEAD // For paranoid mode
-    var pqCipher *postquantum.PostQuantumCipher // For post-quantum modes
     
     switch mode {
-    case ModeAES256GCM:
+    case ModeAES256GCM, ModePostQuantumKyber768, ModeHybridPQ:
+        if hdr.legacyPQ() {
+            aead = newLegacyPQ(key, mode)
+            break
+        }
         block, err := aes.NewCipher(key)
         if err != nil {
             return err
@@ -389,72 +367,147 @@ func DecryptFile(inputPath, outputPath string, password []byte, force bool, onPr
         }
         
         // Second layer: ChaCha20-Poly1305
-        key2 := argon2.IDKey(append(password, []byte("paranoid")...), salt, argonTime*2, argonMemory, argonThreads, keyLen)
+        key2 := argon2.IDKey(append(password, []byte("paranoid")...), salt, kdf.Time*2, kdf.Memory, kdf.Threads, keyLen)
         aead2, err = chacha20poly1305.New(key2)
         if err != nil {
             return err
         }
-    case ModePostQuantumKyber768:
-        pqCipher = postquantum.NewPostQuantumCipher(postquantum.Kyber768)
-    case ModePostQuantumDilithium3:
-        pqCipher = postquantum.NewPostQuantumCipher(postquantum.Dilithium3)
-    case ModePostQuantumSPHINCS:
-        pqCipher = postquantum.NewPostQuantumCipher(postquantum.SPHINCS)
+    case ModeParanoidSerpent:
+        aead, aead2, err = newParanoidSerpent(key, password, hdr)
+        if err != nil {
+            return err
+        }
+    case ModePostQuantumDilithium3, ModePostQuantumSPHINCS:
+        // only written by earlier releases, see legacypq.go
+        aead = newLegacyPQ(key, mode)
     case ModeGnuPG:
         // GnuPG mode uses external GPG binary, handled separately
-        return DecryptFileWithGnuPG(inputPath, outputPath, password, onProgress)
+        return errGnuPGMode
     default:
-        return fmt.Errorf("unsupported encryption mode: %d", mode)
+        return fmt.Errorf("%w: encryption mode %d", ErrUnsupported, mode)
     }
 
-    out, err := os.Create(outputPath)
+    dst, err := openOut()
     if err != nil {
         return err
     }
     defer func() {
-        cerr := out.Close()
+        cerr := dst.Close()
         if err == nil && cerr != nil {
             err = cerr
         }
     }()
+    var out io.Writer = dst
+    if hdr.Flags&FlagCompressed != 0 {
+        // chunks carry a Deflate stream; it is inflated as they authenticate
+        inflater := newInflatingWriter(dst, hdr.inflatedSize())
+        defer func() {
+            if cerr := inflater.Close(); err == nil {
+                err = cerr
+            }
+        }()
+        out = inflater
+    }
 
     // Determine number of chunks
     fullChunks := totalSize / int64(chunkSize)
     lastChunkSize := int(totalSize % int64(chunkSize))
-    if totalSize == 0 {
+    if totalSize == 0 || hdr.Flags&FlagFramed != 0 {
+        // framed chunks carry their lengths and are read in their own loop
         fullChunks = 0
         lastChunkSize = 0
     }
 
     processed := int64(0)
     var counter uint32 = 0
-    nonce := make([]byte, gcmNonceLen)
+    nonce := make([]byte, aead.NonceSize())
     copy(nonce[:noncePrefixLen], noncePrefix)
+    var nonce2 []byte
+    if aead2 != nil {
+        nonce2 = make([]byte, aead2.NonceSize())
+    }
+
+    // One ciphertext buffer serves every chunk; AEAD layers open in place into it
+    var cipherBuf []byte
+    defer func() {
+        if cipherBuf != nil {
+            putBuffer(cipherBuf)
+        }
+    }()
 
+    // Every AEAD layer adds its tag (paranoid modes seal twice)
+    overhead, err := hdr.chunkOverhead()
+    if err != nil {
+        return err
+    }
     // Helper to read exactly N ciphertext bytes for a given plaintext length
     readCipher := func(nPlain int) ([]byte, error) {
-        var need int
-        if pqCipher != nil {
-            // Post-quantum: nonce + plaintext + auth tag (32 bytes)
-            nonceSize := pqCipher.GetNonceSize()
-            authTagSize := 32 // From postquantum implementation
-            need = nPlain + nonceSize + authTagSize
-        } else if mode == ModeParanoid {
-            // Two AEAD layers: inner AES-GCM + outer ChaCha20-Poly1305 (each adds its tag)
-            // We used aead.Seal(), then aead2.Seal() during encryption.
-            // So ciphertext = plaintext + gcmOverhead + chachaOverhead (both 16 bytes)
-            need = nPlain + gcmOverhead*2
-        } else {
-            // Single AEAD layer (GCM or Chacha20)
-            need = nPlain + gcmOverhead
-        }
-        buf := make([]byte, need)
+        need := nPlain + overhead
+        if cap(cipherBuf) < need {
+            if cipherBuf != nil {
+                putBuffer(cipherBuf)
+            }
+            cipherBuf = getBuffer(need)
+        }
+        buf := cipherBuf[:need]
         if _, err := io.ReadFull(in, buf); err != nil {
-            return nil, err
+            return nil, truncatedError(err)
         }
         return buf, nil
     }
 
+    // Decrypt a chunk in place with the layers of the mode; word is the frame
+    // word of a framed chunk, nil otherwise
+    openChunk := func(cipherChunk, word []byte) ([]byte, error) {
+        defer endChunk(beginChunk())
+        binary.BigEndian.PutUint32(nonce[noncePrefixLen:], counter)
+        if aead2 != nil {
+            // First decrypt the outer layer
+            copy(nonce2, nonce[:min(len(nonce2), len(nonce))])
+            intermediate, err := aead2.Open(cipherChunk[:0], nonce2, cipherChunk, word)
+            if err != nil {
+                return nil, chunkAuthError(counter)
+            }
+            cipherChunk = intermediate
+        }
+        plain, err := aead.Open(cipherChunk[:0], nonce, cipherChunk, word)
+        if err != nil {
+            return nil, chunkAuthError(counter)
+        }
+        return plain, nil
+    }
+    pipeline.workers.Add(1)
+    defer pipeline.workers.Add(-1)
+
+    // Read framed chunks up to the one marked last; see framing.go
+    remaining := hdr.frameTotal()
+    for last := hdr.Flags&FlagFramed == 0; !last; {
+        n, isLast, err := readFrame(in, hdr, remaining)
+        if err != nil {
+            return err
+        }
+        last = isLast
+        if remaining >= 0 {
+            remaining -= int64(n)
+        }
+        cipherChunk, err := readCipher(n)
+        if err != nil {
+            return err
+        }
+        plain, err := openChunk(cipherChunk, frameWord(n, last))
+        if err != nil {
+            return err
+        }
+        if _, err := out.Write(plain); err != nil {
+            return err
+        }
+        processed += int64(len(plain))
+        if onProgress != nil {
+            onProgress(processed, totalSize)
+        }
+        counter++
+    }
+
     // Read full-size chunks
     if fullChunks > 0 {
         for i := int64(0); i < fullChunks; i++ {
@@ -462,41 +515,9 @@ func DecryptFile(inputPath, outputPath string, password []byte, force bool, onPr
             if err != nil {
                 return err
             }
-            binary.BigEndian.PutUint32(nonce[noncePrefixLen:], counter)
-            
-            // Decrypt with appropriate layers based on mode
-            var plain []byte
-            if pqCipher != nil {
-                // Post-quantum decryption
-                // Extract nonce from beginning of ciphertext
-                nonceSize := pqCipher.GetNonceSize()
-                if len(cipherChunk) < nonceSize {
-                    return fmt.Errorf("PQ ciphertext too short, need at least %d bytes", nonceSize)
-                }
-                pqNonce := cipherChunk[:nonceSize]
-                cipherData := cipherChunk[nonceSize:]
-                plain, err = pqCipher.Decrypt(cipherData, key, pqNonce)
-                if err != nil {
-                    return fmt.Errorf("PQ decrypt: %w", err)
-                }
-            } else if mode == ModeParanoid {
-                // First decrypt with ChaCha20 (outer layer)
-                nonce2 := make([]byte, aead2.NonceSize())
-                copy(nonce2, nonce[:min(len(nonce2), len(nonce))])
-                intermediate, err := aead2.Open(nil, nonce2, cipherChunk, nil)
-                if err != nil {
-                    return err
-                }
-                // Then decrypt with AES-GCM (inner layer)
-                plain, err = aead.Open(nil, nonce, intermediate, nil)
-                if err != nil {
-                    return err
-                }
-            } else {
-                plain, err = aead.Open(nil, nonce, cipherChunk, nil)
+            plain, err := openChunk(cipherChunk, nil)
             if err != nil {
                 return err
-                }
             }
             if _, err := out.Write(plain); err != nil {
                 return err
@@ -509,47 +530,15 @@ func DecryptFile(inputPath, outputPath string, password []byte, force bool, onPr
         }
     }
 
-    // Read last chunk if any
-    if lastChunkSize > 0 {
+    // Read last chunk if any; a stream always ends with one, empty if need be
+    if lastChunkSize > 0 || hdr.endsShort() {
         cipherChunk, err := readCipher(lastChunkSize)
         if err != nil {
             return err
         }
-        binary.BigEndian.PutUint32(nonce[noncePrefixLen:], counter)
-        
-        // Decrypt with appropriate layers based on mode
-        var plain []byte
-        if pqCipher != nil {
-            // Post-quantum decryption
-            // Extract nonce from beginning of ciphertext
-            nonceSize := pqCipher.GetNonceSize()
-            if len(cipherChunk) < nonceSize {
-                return fmt.Errorf("PQ ciphertext too short, need at least %d bytes", nonceSize)
-            }
-            pqNonce := cipherChunk[:nonceSize]
-            cipherData := cipherChunk[nonceSize:]
-            plain, err = pqCipher.Decrypt(cipherData, key, pqNonce)
-            if err != nil {
-                return fmt.Errorf("PQ decrypt: %w", err)
-            }
-        } else if mode == ModeParanoid {
-            // First decrypt with ChaCha20 (outer layer)
-            nonce2 := make([]byte, aead2.NonceSize())
-            copy(nonce2, nonce[:min(len(nonce2), len(nonce))])
-            intermediate, err := aead2.Open(nil, nonce2, cipherChunk, nil)
-            if err != nil {
-                return err
-            }
-            // Then decrypt with AES-GCM (inner layer)
-            plain, err = aead.Open(nil, nonce, intermediate, nil)
-            if err != nil {
-                return err
-            }
-        } else {
-            plain, err = aead.Open(nil, nonce, cipherChunk, nil)
+        plain, err := openChunk(cipherChunk, nil)
         if err != nil {
             return err
-            }
         }
         if _, err := out.Write(plain); err != nil {
             return err
@@ -560,7 +549,13 @@ func DecryptFile(inputPath, outputPath string, password []byte, force bool, onPr
         }
     }
 
-    return nil
+    // Data after the last chunk means the size in the header was lowered
+    if n, _ := io.ReadFull(in, make([]byte, 1)); n != 0 {
+        return fmt.Errorf("%w: data after the last chunk", ErrCorrupt)
+    }
+
+    // Metadata is checked last so a wrong password is still reported by the first chunk
+    return hdr.verifyMAC(key)
 }
 
 
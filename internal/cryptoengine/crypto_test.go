@@ -0,0 +1,502 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testKDF keeps key derivation cheap; the format is the same for any parameters
+var testKDF = Argon2Params{Time: 1, Memory: 64, Threads: 1}
+
+const testChunk = 1 << 20 // chunk size written by EncryptReaderWithOptions
+
+var testPassword = []byte("correct horse battery staple")
+
+// streamModes are the modes handled by the engine itself (GnuPG needs a gpg binary)
+var streamModes = []EncryptionMode{
+	ModeAES256GCM,
+	ModeChaCha20,
+	ModeParanoid,
+	ModePostQuantumKyber768,
+	ModeHybridPQ,
+	ModeParanoidSerpent,
+}
+
+// testSizes covers empty and tiny files and both sides of every chunk boundary
+var testSizes = []int{0, 1, 15, 16, 17, testChunk - 1, testChunk, testChunk + 1, 2 * testChunk, 2*testChunk + 100}
+
+// writePlain writes size random bytes to a new file and returns its path and contents
+func writePlain(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), "plain.bin")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+// encryptTest encrypts size random bytes with mode and returns the container and the plaintext
+func encryptTest(t *testing.T, mode EncryptionMode, size int, meta Metadata) (string, []byte) {
+	t.Helper()
+	in, data := writePlain(t, size)
+	out := in + ".hadescrypt"
+	opts := EncryptionOptions{Mode: mode, Argon2: testKDF, Metadata: meta, KEMKey: testKEMKey}
+	if err := EncryptFileWithOptions(in, out, testPassword, opts, nil); err != nil {
+		t.Fatalf("encrypt %s, %d bytes: %v", GetEncryptionModeName(mode), size, err)
+	}
+	return out, data
+}
+
+// decryptBytes decrypts the container at path into memory
+func decryptBytes(path string, password []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	err := DecryptFileToWriter(path, &buf, password, nil)
+	return buf.Bytes(), err
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, mode := range streamModes {
+		for _, size := range testSizes {
+			t.Run(fmt.Sprintf("%s/%d", GetEncryptionModeName(mode), size), func(t *testing.T) {
+				t.Parallel()
+				enc, want := encryptTest(t, mode, size, Metadata{})
+
+				overhead, err := chunkOverhead(mode)
+				if err != nil {
+					t.Fatal(err)
+				}
+				hdr, err := ReadHeaderFromFile(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				chunks := (size + testChunk - 1) / testChunk
+				fi, err := os.Stat(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if wantSize := int64(len(hdr.Bytes()) + size + chunks*overhead); fi.Size() != wantSize {
+					t.Errorf("container is %d bytes, want %d", fi.Size(), wantSize)
+				}
+				if hdr.Mode != mode || hdr.OriginalSize != int64(size) || hdr.ChunkSize != testChunk {
+					t.Errorf("header: mode %d, size %d, chunk %d", hdr.Mode, hdr.OriginalSize, hdr.ChunkSize)
+				}
+
+				out := enc + ".out"
+				var last int64 = -1
+				err = DecryptFile(enc, out, testPassword, false, func(done, total int64) {
+					if total != int64(size) || done < last {
+						t.Errorf("progress %d/%d after %d", done, total, last)
+					}
+					last = done
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := os.ReadFile(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("decrypted %d bytes differ from the %d bytes encrypted", len(got), len(want))
+				}
+				if size > 0 && last != int64(size) {
+					t.Errorf("progress ended at %d of %d", last, size)
+				}
+			})
+		}
+	}
+}
+
+func TestRoundTripCompliance(t *testing.T) {
+	in, want := writePlain(t, testChunk+1)
+	out := in + ".hadescrypt"
+	if err := EncryptFileWithOptions(in, out, testPassword, EncryptionOptions{Mode: ModeAES256GCM, Compliance: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := ReadHeaderFromFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hdr.Compliance() {
+		t.Error("compliance flag not set")
+	}
+	got, err := decryptBytes(out, testPassword)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	if err := EncryptFileWithOptions(in, out, testPassword, EncryptionOptions{Mode: ModeChaCha20, Compliance: true}, nil); err == nil {
+		t.Error("ChaCha20-Poly1305 accepted in compliance mode")
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	meta := Metadata{Comment: "quarterly\nreport", Hint: "the usual one", Name: "report.pdf"}
+	enc, want := encryptTest(t, ModeAES256GCM, 1000, meta)
+	hdr, err := ReadHeaderFromFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Metadata != meta {
+		t.Errorf("metadata %+v, want %+v", hdr.Metadata, meta)
+	}
+	got, err := decryptBytes(enc, testPassword)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("round trip failed: %v", err)
+	}
+}
+
+func TestCommentsOption(t *testing.T) {
+	dir := t.TempDir()
+	in, enc := filepath.Join(dir, "in"), filepath.Join(dir, "in.hadescrypt")
+	if err := os.WriteFile(in, []byte("payload"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	opts := EncryptionOptions{Mode: ModeChaCha20, Comments: "sent by finance", Argon2: Argon2Preset("Fast")}
+	if err := EncryptFileWithOptions(in, enc, testPassword, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	info, err := GetFileInfo(enc)
+	if err != nil || info["comments"] != "sent by finance" {
+		t.Fatalf("comments %q, %v", info["comments"], err)
+	}
+	got, err := decryptBytes(enc, testPassword)
+	if err != nil || string(got) != "payload" {
+		t.Fatalf("round trip: %q, %v", got, err)
+	}
+}
+
+func TestEncryptedSize(t *testing.T) {
+	for _, mode := range streamModes {
+		for _, size := range []int{0, 17, testChunk, 2*testChunk + 100} {
+			meta := Metadata{Comment: "sized"}
+			enc, _ := encryptTest(t, mode, size, meta)
+			fi, err := os.Stat(enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := EncryptedSize(int64(size), EncryptionOptions{Mode: mode, Argon2: testKDF, Metadata: meta})
+			if err != nil || got != fi.Size() {
+				t.Errorf("%s, %d bytes: EncryptedSize = %d, %v; file has %d", GetEncryptionModeName(mode), size, got, err, fi.Size())
+			}
+		}
+	}
+	if _, err := EncryptedSize(10, EncryptionOptions{Mode: ModeGnuPG}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GnuPG: %v", err)
+	}
+}
+
+func TestFileInfoCache(t *testing.T) {
+	enc, _ := encryptTest(t, ModeChaCha20, 1000, Metadata{Comment: "first"})
+	info, err := GetFileInfo(enc)
+	if err != nil || info["comments"] != "first" {
+		t.Fatalf("info %v, %v", info, err)
+	}
+	info["comments"] = "changed by the caller"
+	if again, _ := GetFileInfo(enc); again["comments"] != "first" {
+		t.Errorf("cached result was shared with the caller: %v", again["comments"])
+	}
+	if err := Restamp(enc, testPassword, Metadata{Comment: "second"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := GetFileInfo(enc); info["comments"] != "second" {
+		t.Errorf("stale comment %v after Restamp", info["comments"])
+	}
+
+	// a file replaced behind the cache's back is recognized by its size and time
+	other, _ := encryptTest(t, ModeChaCha20, 2000, Metadata{Comment: "third"})
+	if err := os.Rename(other, enc); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := GetFileInfo(enc); info["comments"] != "third" {
+		t.Errorf("stale comment %v after replacement", info["comments"])
+	}
+}
+
+func TestOversizedCommentRefused(t *testing.T) {
+	in := filepath.Join(t.TempDir(), "in")
+	if err := os.WriteFile(in, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	meta := Metadata{Comment: strings.Repeat("x", MaxCommentBytes+1)}
+	err := EncryptFileWithOptions(in, in+".hc", testPassword, EncryptionOptions{Mode: ModeChaCha20, Argon2: testKDF, Metadata: meta}, nil)
+	if err == nil || !strings.Contains(err.Error(), "the limit is") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := os.Stat(in + ".hc"); !os.IsNotExist(err) {
+		t.Error("output written for an oversized comment")
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	for _, mode := range streamModes {
+		for _, size := range []int{0, 1, testChunk + 1} {
+			t.Run(fmt.Sprintf("%s/%d", GetEncryptionModeName(mode), size), func(t *testing.T) {
+				t.Parallel()
+				enc, _ := encryptTest(t, mode, size, Metadata{Comment: "c"})
+				got, err := decryptBytes(enc, []byte("wrong password"))
+				if size == 0 && mode != ModePostQuantumKyber768 {
+					// Without a chunk there is nothing to open; the metadata MAC catches the key.
+					// In Kyber mode the file key fails to unwrap first.
+					if !errors.Is(err, ErrHeaderAuth) {
+						t.Fatalf("got %v, want ErrHeaderAuth", err)
+					}
+					return
+				}
+				if !errors.Is(err, ErrAuthFailed) {
+					t.Fatalf("got %v, want ErrAuthFailed", err)
+				}
+				if len(got) != 0 {
+					t.Errorf("%d bytes of output before the failure", len(got))
+				}
+				if _, err := OpenReader(enc, []byte("wrong password")); !errors.Is(err, ErrAuthFailed) {
+					t.Errorf("OpenReader: got %v, want ErrAuthFailed", err)
+				}
+			})
+		}
+	}
+}
+
+// cancelingReader fails with context.Canceled once limit bytes were read, like an
+// input stopped by the user
+type cancelingReader struct {
+	r     io.Reader
+	limit int64
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	if c.limit <= 0 {
+		return 0, context.Canceled
+	}
+	if int64(len(p)) > c.limit {
+		p = p[:c.limit]
+	}
+	n, err := c.r.Read(p)
+	c.limit -= int64(n)
+	return n, err
+}
+
+// cancelingWriter fails with context.Canceled after limit bytes
+type cancelingWriter struct{ limit int }
+
+func (c *cancelingWriter) Write(p []byte) (int, error) {
+	if len(p) > c.limit {
+		n := c.limit
+		c.limit = 0
+		return n, context.Canceled
+	}
+	c.limit -= len(p)
+	return len(p), nil
+}
+
+func TestCancelEncrypt(t *testing.T) {
+	size := 3 * testChunk
+	data := make([]byte, size)
+	rand.Read(data)
+	out := filepath.Join(t.TempDir(), "partial.hadescrypt")
+	in := &cancelingReader{r: bytes.NewReader(data), limit: testChunk + testChunk/2}
+	err := EncryptReaderWithOptions(in, int64(size), out, testPassword, EncryptionOptions{Argon2: testKDF}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	// Whatever was written must never pass for a complete file
+	if _, err := decryptBytes(out, testPassword); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("partial output: got %v, want ErrCorrupt", err)
+	}
+}
+
+func TestCancelDecrypt(t *testing.T) {
+	enc, _ := encryptTest(t, ModeChaCha20, 3*testChunk, Metadata{})
+	err := DecryptFileToWriter(enc, &cancelingWriter{limit: testChunk}, testPassword, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestShortInput(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "short.hadescrypt")
+	err := EncryptReaderWithOptions(bytes.NewReader(make([]byte, 10)), 20, out, testPassword, EncryptionOptions{Argon2: testKDF}, nil)
+	if err == nil {
+		t.Fatal("input shorter than its declared size was accepted")
+	}
+}
+
+// headerOffsets returns where the salt, nonce prefix, chunk size and original size start
+func headerOffsets(t *testing.T, path string) (salt, nonce, chunk, size int) {
+	t.Helper()
+	hdr, err := ReadHeaderFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := hdr.unsealed()
+	size = len(raw) - 8
+	if hdr.Flags&FlagMetadata != 0 {
+		size -= len(hdr.Metadata.appendTo(nil))
+	}
+	chunk = size - 4
+	nonce = chunk - noncePrefixLen
+	salt = nonce - saltLengthBytes
+	return
+}
+
+func TestHeaderTampering(t *testing.T) {
+	const size = 2*testChunk + 100
+	// Each case rewrites a copy of the container and names the error it must produce
+	cases := []struct {
+		name   string
+		meta   Metadata
+		edit   func(t *testing.T, path string, b []byte) []byte
+		wantIs error
+	}{
+		{"magic", Metadata{}, func(t *testing.T, p string, b []byte) []byte { b[0] ^= 0xff; return b }, ErrNotContainer},
+		{"version", Metadata{}, func(t *testing.T, p string, b []byte) []byte { b[4] = 9; return b }, ErrUnsupported},
+		{"unknown mode", Metadata{}, func(t *testing.T, p string, b []byte) []byte { b[5] = 200; return b }, ErrUnsupported},
+		{"mode", Metadata{}, func(t *testing.T, p string, b []byte) []byte { b[5] = byte(ModeChaCha20); return b }, ErrAuthFailed},
+		{"salt", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			off, _, _, _ := headerOffsets(t, p)
+			b[off] ^= 1
+			return b
+		}, ErrAuthFailed},
+		{"nonce prefix", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			_, off, _, _ := headerOffsets(t, p)
+			b[off] ^= 1
+			return b
+		}, ErrAuthFailed},
+		{"zero chunk size", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			_, _, off, _ := headerOffsets(t, p)
+			copy(b[off:], []byte{0, 0, 0, 0})
+			return b
+		}, ErrCorrupt},
+		{"huge chunk size", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			_, _, off, _ := headerOffsets(t, p)
+			copy(b[off:], []byte{0x7f, 0xff, 0xff, 0xff})
+			return b
+		}, ErrCorrupt},
+		{"chunk size", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			_, _, off, _ := headerOffsets(t, p)
+			b[off+3] ^= 1
+			return b
+		}, ErrAuthFailed},
+		{"size lowered", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			_, _, _, off := headerOffsets(t, p)
+			b[off+5] = 0x10 // 2 MiB + 100 → 1 MiB + 100
+			return b
+		}, ErrCorrupt},
+		{"size lowered to a chunk boundary", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			_, _, _, off := headerOffsets(t, p)
+			b[off+7] = 0 // 2 MiB + 100 → 2 MiB: every chunk read still authenticates
+			return b
+		}, ErrCorrupt},
+		{"size raised", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			_, _, _, off := headerOffsets(t, p)
+			b[off+7]++
+			return b
+		}, ErrCorrupt},
+		{"negative size", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			_, _, _, off := headerOffsets(t, p)
+			b[off] = 0x80
+			return b
+		}, ErrCorrupt},
+		// every flag bit is taken; a stray Reed-Solomon flag reads metadata as shard counts
+		{"parity flag added", Metadata{Comment: "x"}, func(t *testing.T, p string, b []byte) []byte { b[6] |= FlagReedSolomon; return b }, ErrCorrupt},
+		{"metadata dropped", Metadata{Comment: "x"}, func(t *testing.T, p string, b []byte) []byte { b[6] &^= FlagMetadata; return b }, ErrAuthFailed},
+		{"comment", Metadata{Comment: "pay alice"}, func(t *testing.T, p string, b []byte) []byte {
+			i := bytes.Index(b, []byte("alice"))
+			copy(b[i:], "mallo")
+			return b
+		}, ErrHeaderAuth},
+		{"MAC", Metadata{Hint: "h"}, func(t *testing.T, p string, b []byte) []byte {
+			hdr, err := ReadHeaderFromFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b[len(hdr.Bytes())-1] ^= 1
+			return b
+		}, ErrHeaderAuth},
+		{"truncated header", Metadata{}, func(t *testing.T, p string, b []byte) []byte { return b[:20] }, ErrCorrupt},
+		{"first chunk", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			hdr, _ := ReadHeaderFromFile(p)
+			b[len(hdr.Bytes())+10] ^= 1
+			return b
+		}, ErrAuthFailed},
+		{"later chunk", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			hdr, _ := ReadHeaderFromFile(p)
+			b[len(hdr.Bytes())+testChunk+gcmOverhead+10] ^= 1
+			return b
+		}, ErrCorrupt},
+		{"chunks swapped", Metadata{}, func(t *testing.T, p string, b []byte) []byte {
+			hdr, _ := ReadHeaderFromFile(p)
+			start, n := len(hdr.Bytes()), testChunk+gcmOverhead
+			first := append([]byte(nil), b[start:start+n]...)
+			copy(b[start:], b[start+n:start+2*n])
+			copy(b[start+n:], first)
+			return b
+		}, ErrAuthFailed},
+		{"truncated last chunk", Metadata{}, func(t *testing.T, p string, b []byte) []byte { return b[:len(b)-1] }, ErrCorrupt},
+		{"last chunk dropped", Metadata{}, func(t *testing.T, p string, b []byte) []byte { return b[:len(b)-(100+gcmOverhead)] }, ErrCorrupt},
+		{"trailing data", Metadata{}, func(t *testing.T, p string, b []byte) []byte { return append(b, 0) }, ErrCorrupt},
+	}
+	originals := map[Metadata]string{}
+	for _, c := range cases {
+		if originals[c.meta] == "" {
+			originals[c.meta], _ = encryptTest(t, ModeAES256GCM, size, c.meta)
+		}
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			orig := originals[c.meta]
+			data, err := os.ReadFile(orig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tampered := filepath.Join(t.TempDir(), "tampered.hadescrypt")
+			if err := os.WriteFile(tampered, c.edit(t, orig, data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := decryptBytes(tampered, testPassword); !errors.Is(err, c.wantIs) {
+				t.Errorf("decrypt: got %v, want %v", err, c.wantIs)
+			}
+			if r, err := OpenReader(tampered, testPassword); err == nil {
+				// Random access may only fail once the bad chunk is read
+				_, err = io.Copy(io.Discard, io.NewSectionReader(r, 0, r.Size()))
+				r.Close()
+				if err == nil {
+					t.Error("OpenReader read the whole tampered file without an error")
+				}
+			}
+		})
+	}
+}
+
+func TestReaderRandomAccess(t *testing.T) {
+	enc, want := encryptTest(t, ModeParanoid, 2*testChunk+100, Metadata{})
+	r, err := OpenReader(enc, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Size() != int64(len(want)) {
+		t.Fatalf("size %d, want %d", r.Size(), len(want))
+	}
+	for _, span := range [][2]int{{0, 1}, {testChunk - 3, 6}, {2*testChunk + 99, 1}, {5, 2 * testChunk}, {0, len(want)}} {
+		got := make([]byte, span[1])
+		if _, err := r.ReadAt(got, int64(span[0])); err != nil && err != io.EOF {
+			t.Fatalf("ReadAt(%d, %d): %v", span[0], span[1], err)
+		}
+		if !bytes.Equal(got, want[span[0]:span[0]+span[1]]) {
+			t.Errorf("ReadAt(%d, %d) returned the wrong bytes", span[0], span[1])
+		}
+	}
+	if n, err := r.ReadAt(make([]byte, 10), int64(len(want))-4); n != 4 || err != io.EOF {
+		t.Errorf("read across the end: %d, %v", n, err)
+	}
+}
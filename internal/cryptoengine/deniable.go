@@ -0,0 +1,248 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+)
+
+// Containers written with UseDeniability are wrapped whole, header included,
+// so the output holds nothing but random-looking bytes: no magic, version or
+// parameters. The wrapped file is
+//
+//	[PAD]RANDOM [16]SALT [24]NONCE [..]XCHACHA20(CONTAINER)
+//
+// The padding length, below deniablePadMax, follows from a hash of the
+// password, so the salt sits at an offset only the password gives away. The
+// key is Argon2id of the password and salt with DefaultArgon2, since no
+// parameters can be stored in the clear. The keystream adds no authentication:
+// the container inside authenticates itself, and a wrong password unwraps to
+// bytes without the magic, reported as ErrNotContainer like any other file
+// that is not a container.
+//
+// The keystream can start at any offset, so wrapped containers are unwrapped
+// as they are read, at random as well as in order; the container inside never
+// reaches the disk.
+const (
+	deniableDomain   = "hadescrypt deniability offset v1\x00"
+	deniablePadMax   = 1 << 10
+	deniableNonceLen = chacha20.NonceSizeX
+	// keystream bytes per nonce, well below the 256 GiB the block counter allows
+	deniableSegment = 1 << 36
+)
+
+// otherFormats are the first bytes of files that are not worth unwrapping,
+// since deriving the key costs a full Argon2id run: containers, and formats
+// commonly passed to decryption by mistake. Wrappings never start with them.
+var otherFormats = []string{
+	fileMagic,
+	"-----BEGIN ",         // OpenPGP and other armor
+	"age-encryption.org/", // age
+	"PK\x03\x04",          // zip
+	"\x1f\x8b\x08",        // gzip
+	"%PDF-",
+	"\x89PNG",
+	"\xff\xd8\xff", // JPEG
+	"\x7fELF",
+}
+
+// notWrapped reports whether a file starting with head cannot be Deniability Mode output
+func notWrapped(head []byte) bool {
+	for _, m := range otherFormats {
+		if bytes.HasPrefix(head, []byte(m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MayBeDeniable reports whether the file at path could be Deniability Mode
+// output: it is long enough and starts with neither the magic nor that of
+// another known format. Only the password tells for sure.
+func MayBeDeniable(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, saltLengthBytes+deniableNonceLen+len(fileMagic))
+	if _, err := io.ReadFull(f, head); err != nil {
+		return false
+	}
+	return !notWrapped(head)
+}
+
+// deniablePad returns the length of the random padding before the salt for password
+func deniablePad(password []byte) int {
+	h := sha256.New()
+	h.Write([]byte(deniableDomain))
+	h.Write(password)
+	return int(binary.BigEndian.Uint16(h.Sum(nil)) % deniablePadMax)
+}
+
+// deniablePrefixLen returns the length of the padding, salt and nonce for password
+func deniablePrefixLen(password []byte) int {
+	return deniablePad(password) + saltLengthBytes + deniableNonceLen
+}
+
+// deniableOverhead returns the bytes the wrapping adds at most
+func deniableOverhead() int64 { return deniablePadMax - 1 + saltLengthBytes + deniableNonceLen }
+
+// deniableKey is the keystream of one wrapping: XChaCha20 that moves to the
+// next nonce every deniableSegment bytes, so wrapped containers have no size
+// limit
+type deniableKey struct {
+	key, nonce []byte
+}
+
+// newDeniableKey derives the key of the wrapping that starts with prefix
+func newDeniableKey(password, prefix []byte) *deniableKey {
+	salt := prefix[len(prefix)-saltLengthBytes-deniableNonceLen : len(prefix)-deniableNonceLen]
+	p := DefaultArgon2
+	return &deniableKey{
+		key:   argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, keyLen),
+		nonce: bytes.Clone(prefix[len(prefix)-deniableNonceLen:]),
+	}
+}
+
+// xorAt XORs b with the keystream from container offset off on. The nonce of
+// each segment adds its index to the last 8 bytes of the stored nonce.
+func (k *deniableKey) xorAt(b []byte, off int64) {
+	for len(b) > 0 {
+		segment, within := off/deniableSegment, off%deniableSegment
+		nonce := bytes.Clone(k.nonce)
+		binary.BigEndian.PutUint64(nonce[16:], binary.BigEndian.Uint64(nonce[16:])+uint64(segment))
+		c, err := chacha20.NewUnauthenticatedCipher(k.key, nonce)
+		if err != nil {
+			panic(err) // the key and nonce have fixed lengths
+		}
+		c.SetCounter(uint32(within / 64))
+		if skip := within % 64; skip > 0 {
+			var discard [64]byte
+			c.XORKeyStream(discard[:skip], discard[:skip])
+		}
+		n := len(b)
+		if rest := deniableSegment - within; int64(n) > rest {
+			n = int(rest)
+		}
+		c.XORKeyStream(b[:n], b[:n])
+		b, off = b[n:], off+int64(n)
+	}
+}
+
+// wipe wipes the key of the wrapping
+func (k *deniableKey) wipe() { Wipe(k.key) }
+
+// deniableWriter wraps the container written to it
+type deniableWriter struct {
+	k   *deniableKey
+	w   io.Writer
+	off int64
+	buf []byte
+}
+
+// newDeniableWriter writes the padding, salt and nonce of a wrapping drawn
+// from random to w and returns the writer for the container
+func newDeniableWriter(w io.Writer, password []byte, random io.Reader) (*deniableWriter, error) {
+	prefix := make([]byte, deniablePrefixLen(password))
+	for {
+		if _, err := io.ReadFull(random, prefix); err != nil {
+			return nil, err
+		}
+		// readers would not try to unwrap it
+		if !notWrapped(prefix) {
+			break
+		}
+	}
+	k := newDeniableKey(password, prefix)
+	if _, err := w.Write(prefix); err != nil {
+		k.wipe()
+		return nil, err
+	}
+	return &deniableWriter{k: k, w: w}, nil
+}
+
+func (d *deniableWriter) Write(b []byte) (int, error) {
+	if cap(d.buf) < len(b) {
+		d.buf = make([]byte, len(b))
+	}
+	out := d.buf[:len(b)]
+	copy(out, b)
+	d.k.xorAt(out, d.off)
+	n, err := d.w.Write(out)
+	d.off += int64(n)
+	return n, err
+}
+
+// deniableReader unwraps a container read in order
+type deniableReader struct {
+	k   *deniableKey
+	r   io.Reader
+	off int64
+}
+
+// newDeniableReader reads the prefix of the wrapping from r, or fails with
+// ErrNotContainer when r is too short to hold one
+func newDeniableReader(r io.Reader, password []byte) (*deniableReader, error) {
+	prefix := make([]byte, deniablePrefixLen(password))
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNotContainer
+		}
+		return nil, err
+	}
+	return &deniableReader{k: newDeniableKey(password, prefix), r: r}, nil
+}
+
+func (d *deniableReader) Read(b []byte) (int, error) {
+	n, err := d.r.Read(b)
+	d.k.xorAt(b[:n], d.off)
+	d.off += int64(n)
+	return n, err
+}
+
+// deniableReaderAt unwraps a container read at random; the wrapped container
+// starts at start in r
+type deniableReaderAt struct {
+	k     *deniableKey
+	r     io.ReaderAt
+	start int64
+}
+
+func (d *deniableReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	n, err := d.r.ReadAt(b, d.start+off)
+	d.k.xorAt(b[:n], off)
+	return n, err
+}
+
+// unwrapKey returns the key that unwraps the size bytes of r with password,
+// or nil when r does not start with a container under it
+func unwrapKey(r io.ReaderAt, size int64, password []byte) (*deniableKey, error) {
+	prefix := make([]byte, deniablePrefixLen(password))
+	if size < int64(len(prefix)+len(fileMagic)) {
+		return nil, nil
+	}
+	if _, err := r.ReadAt(prefix, 0); err != nil {
+		return nil, err
+	}
+	if notWrapped(prefix) {
+		return nil, nil
+	}
+	k := newDeniableKey(password, prefix)
+	magic := make([]byte, len(fileMagic))
+	if _, err := (&deniableReaderAt{k: k, r: r, start: int64(len(prefix))}).ReadAt(magic, 0); err != nil {
+		k.wipe()
+		return nil, err
+	}
+	if string(magic) != fileMagic {
+		k.wipe()
+		return nil, nil
+	}
+	return k, nil
+}
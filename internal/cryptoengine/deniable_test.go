@@ -0,0 +1,224 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+func TestDeniableRoundTrip(t *testing.T) {
+	for name, opts := range map[string]EncryptionOptions{
+		"plain":  {Mode: ModeAES256GCM, Metadata: Metadata{Comment: "hidden", Name: "secret.txt"}},
+		"parity": {Mode: ModeChaCha20, UseReedSolomon: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			enc, want := encryptDeniable(t, 2*testChunk+100, opts)
+			data, err := os.ReadFile(enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.HasPrefix(data, []byte(fileMagic)) || bytes.Contains(data, []byte("secret.txt")) {
+				t.Error("the output shows the magic or the original name")
+			}
+			opts.Argon2, opts.UseDeniability = testKDF, true
+			est, err := EncryptedSize(int64(len(want)), opts)
+			if pad := int64(deniablePad(testPassword)); err != nil || est-(deniablePadMax-1-pad) != int64(len(data)) {
+				t.Errorf("EncryptedSize = %d, %v; the output is %d bytes", est, err, len(data))
+			}
+			if _, err := ReadHeaderFromFile(enc); !errors.Is(err, ErrNotContainer) {
+				t.Errorf("ReadHeaderFromFile: got %v, want ErrNotContainer", err)
+			}
+
+			if got, err := decryptBytes(enc, testPassword); err != nil || !bytes.Equal(got, want) {
+				t.Fatalf("decrypt: %d bytes, %v", len(got), err)
+			}
+			if _, err := decryptBytes(enc, []byte("wrong password")); !errors.Is(err, ErrNotContainer) {
+				t.Errorf("wrong password: got %v, want ErrNotContainer", err)
+			}
+		})
+	}
+}
+
+// encryptDeniable encrypts a file of size bytes in Deniability Mode
+func encryptDeniable(t *testing.T, size int, opts EncryptionOptions) (string, []byte) {
+	t.Helper()
+	opts.Argon2, opts.UseDeniability = testKDF, true
+	in, want := writePlain(t, size)
+	enc := in + ".hadescrypt"
+	if err := EncryptFileWithOptions(in, enc, testPassword, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	return enc, want
+}
+
+func TestDeniableReaders(t *testing.T) {
+	meta := Metadata{Comment: "hidden", Name: "secret.txt"}
+	enc, want := encryptDeniable(t, 2*testChunk+100, EncryptionOptions{Mode: ModeAES256GCM, Metadata: meta})
+
+	hdr, err := ReadHeaderWithPassword(enc, testPassword)
+	if err != nil || !hdr.Deniable || hdr.Metadata != meta || hdr.OriginalSize != int64(len(want)) {
+		t.Fatalf("ReadHeaderWithPassword: %+v, %v", hdr, err)
+	}
+	if _, err := ReadHeaderWithPassword(enc, []byte("wrong password")); !errors.Is(err, ErrNotContainer) {
+		t.Errorf("ReadHeaderWithPassword, wrong password: got %v, want ErrNotContainer", err)
+	}
+	if !MayBeDeniable(enc) {
+		t.Error("MayBeDeniable: false for Deniability Mode output")
+	}
+
+	if got, err := DecryptFileToMemory(enc, testPassword, 0); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("DecryptFileToMemory: %d bytes, %v", len(got), err)
+	}
+
+	r, err := OpenReader(enc, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part := make([]byte, 200)
+	if _, err := r.ReadAt(part, testChunk-100); err != nil || !bytes.Equal(part, want[testChunk-100:testChunk+100]) {
+		t.Errorf("Reader.ReadAt: %v", err)
+	}
+	r.Close()
+
+	info, err := GetFileInfoWithPassword(enc, testPassword)
+	if err != nil || info["format"] != "HadesCrypt" || info["deniable"] != true || info["comments"] != "hidden" {
+		t.Errorf("GetFileInfoWithPassword: %v, %v", info, err)
+	}
+	if info, _ := GetFileInfoWithPassword(enc, []byte("wrong password")); info["format"] == "HadesCrypt" {
+		t.Errorf("GetFileInfoWithPassword, wrong password: format %v", info["format"])
+	}
+
+	converted := enc + ".converted"
+	if err := Convert(enc, converted, testPassword, EncryptionOptions{Mode: ModeChaCha20, Argon2: testKDF}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if hdr, err := ReadHeaderWithPassword(converted, testPassword); err != nil || !hdr.Deniable || hdr.Mode != ModeChaCha20 || hdr.Metadata != meta {
+		t.Errorf("Convert: %+v, %v", hdr, err)
+	}
+	if got, err := decryptBytes(converted, testPassword); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("decrypt converted: %d bytes, %v", len(got), err)
+	}
+}
+
+func TestDeniableRepair(t *testing.T) {
+	enc, want := encryptDeniable(t, 3*testChunk, EncryptionOptions{Mode: ModeAES256GCM})
+	data, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(enc, data[:len(data)-testChunk/2], 0600); err != nil {
+		t.Fatal(err)
+	}
+	repaired := enc + ".repaired"
+	res, err := Repair(enc, repaired, testPassword, nil)
+	if err != nil || res.Chunks != 2 {
+		t.Fatalf("Repair: %+v, %v", res, err)
+	}
+	out, err := os.ReadFile(repaired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.HasPrefix(out, []byte(fileMagic)) {
+		t.Error("the repaired copy is not wrapped")
+	}
+	if got, err := decryptBytes(repaired, testPassword); err != nil || !bytes.Equal(got, want[:2*testChunk]) {
+		t.Errorf("decrypt repaired: %d bytes, %v", len(got), err)
+	}
+}
+
+func TestDeniableRestampRefused(t *testing.T) {
+	enc, want := encryptDeniable(t, testChunk+100, EncryptionOptions{Mode: ModeAES256GCM})
+	before, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Restamp(enc, testPassword, Metadata{Comment: "new"})
+	if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "cannot be restamped") {
+		t.Fatalf("Restamp: got %v, want the Deniability Mode refusal", err)
+	}
+	if after, err := os.ReadFile(enc); err != nil || !bytes.Equal(after, before) {
+		t.Errorf("the refused file changed: %v", err)
+	}
+	if got, err := decryptBytes(enc, testPassword); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("decrypt after Restamp: %d bytes, %v", len(got), err)
+	}
+}
+
+func TestDeniableStream(t *testing.T) {
+	_, want := writePlain(t, testChunk+100)
+	var buf bytes.Buffer
+	w, err := NewEncryptingWriter(&buf, testPassword, EncryptionOptions{Mode: ModeAES256GCM, Argon2: testKDF, UseDeniability: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(want); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewDecryptingReader(bytes.NewReader(buf.Bytes()), testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("DecryptingReader: %d bytes, %v", len(got), err)
+	}
+	if _, err := NewDecryptingReader(bytes.NewReader(buf.Bytes()), []byte("wrong password")); !errors.Is(err, ErrNotContainer) {
+		t.Errorf("wrong password: got %v, want ErrNotContainer", err)
+	}
+	path := filepath.Join(t.TempDir(), "stream.hadescrypt")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := decryptBytes(path, testPassword); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("decrypt: %d bytes, %v", len(got), err)
+	}
+}
+
+func TestDeniableRefused(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	opts := EncryptionOptions{Mode: ModeAES256GCM, Compliance: true, UseDeniability: true}
+	if err := EncryptReaderWithOptions(bytes.NewReader(nil), 0, out, testPassword, opts, nil); err == nil {
+		t.Error("Deniability Mode was accepted in compliance mode")
+	}
+}
+
+func TestDeniableKeystream(t *testing.T) {
+	key, nonce := make([]byte, chacha20.KeySize), make([]byte, deniableNonceLen)
+	nonce[23] = 0xff
+	k := &deniableKey{key: key, nonce: nonce}
+
+	// any offset continues the keystream read from the start
+	whole := make([]byte, 300)
+	k.xorAt(whole, 0)
+	for _, off := range []int64{1, 63, 64, 130} {
+		part := make([]byte, 100)
+		k.xorAt(part, off)
+		if !bytes.Equal(part, whole[off:off+100]) {
+			t.Errorf("keystream at %d differs from the keystream read in order", off)
+		}
+	}
+
+	got := make([]byte, 20)
+	k.xorAt(got, deniableSegment-5)
+	want := make([]byte, 20)
+	c, _ := chacha20.NewUnauthenticatedCipher(key, nonce)
+	c.SetCounter(deniableSegment/64 - 1)
+	var skip [59]byte
+	c.XORKeyStream(skip[:], skip[:])
+	c.XORKeyStream(want[:5], want[:5])
+	next := bytes.Clone(nonce)
+	next[23], next[22] = 0, 1 // the counter carries into the byte before
+	c, _ = chacha20.NewUnauthenticatedCipher(key, next)
+	c.XORKeyStream(want[5:], want[5:])
+	if !bytes.Equal(got, want) {
+		t.Errorf("keystream across a segment boundary:\n got %x\nwant %x", got, want)
+	}
+}
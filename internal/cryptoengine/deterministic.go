@@ -0,0 +1,54 @@
+package cryptoengine
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// EnvAllowDeterministic must be set to "1" for EncryptionOptions.DeterministicSeed
+// to be honoured. It exists for golden-file tests and reproducible CI pipelines:
+// no application entry point sets the seed.
+const EnvAllowDeterministic = "HADESCRYPT_ALLOW_DETERMINISTIC"
+
+// ErrDeterministicDisabled is returned when a seed is given without EnvAllowDeterministic
+var ErrDeterministicDisabled = errors.New("deterministic output requires " + EnvAllowDeterministic + "=1")
+
+// deterministicDomain separates this use of the seed from any other
+const deterministicDomain = "hadescrypt deterministic salt and nonces v1\x00"
+
+// randomSource returns where the salt and nonces of one encryption come from:
+// crypto/rand, or with a seed a ChaCha20 keystream keyed by it. Equal seeds,
+// passwords, options and plaintexts then give byte-identical containers, which
+// also means two different plaintexts encrypted with the same seed and password
+// reuse nonces: a seed must never protect real data.
+func randomSource(opts EncryptionOptions) (io.Reader, error) {
+	if opts.DeterministicSeed == nil {
+		return rand.Reader, nil
+	}
+	if os.Getenv(EnvAllowDeterministic) != "1" {
+		return nil, ErrDeterministicDisabled
+	}
+	if opts.Compliance {
+		return nil, errors.New("deterministic output is not allowed in compliance mode")
+	}
+	key := sha256.Sum256(append([]byte(deterministicDomain), opts.DeterministicSeed...))
+	stream, err := chacha20.NewUnauthenticatedCipher(key[:], make([]byte, chacha20.NonceSize))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamReader{S: stream, R: zeroReader{}}, nil
+}
+
+// zeroReader reads endless zeros, so a StreamReader over it yields the bare keystream
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
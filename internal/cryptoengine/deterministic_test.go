@@ -0,0 +1,127 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testSeed = []byte("golden-file seed")
+
+// seededModes are the modes a seed makes reproducible; ML-KEM draws its own randomness
+var seededModes = []EncryptionMode{ModeAES256GCM, ModeChaCha20, ModeParanoid, ModeParanoidSerpent}
+
+// encryptSeeded encrypts plain with mode and seed and returns the container bytes
+func encryptSeeded(t *testing.T, mode EncryptionMode, plain, seed []byte) ([]byte, error) {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "seeded.hadescrypt")
+	opts := EncryptionOptions{Mode: mode, Argon2: testKDF, DeterministicSeed: seed, Metadata: Metadata{Comment: "golden"}}
+	if err := EncryptReaderWithOptions(bytes.NewReader(plain), int64(len(plain)), out, testPassword, opts, nil); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(out)
+}
+
+// goldenSums are the SHA-256 sums of the containers TestDeterministicGolden writes.
+// A change means the container format changed: old files may no longer open.
+var goldenSums = map[EncryptionMode]string{
+	ModeAES256GCM:       "41f8f28448dacc6312fd82e25e0187817c7c882c6bfa568b2d12db153c9abbca",
+	ModeChaCha20:        "5a5d9e9fbc09a30bcb17234e936d2e25cbf2800de0669c7186c980e83bac3d28",
+	ModeParanoid:        "afdcf5e7ecb162a02660a45b9abef1e3f7cb7d3406f2190cc7e96f9a3255b650",
+	ModeParanoidSerpent: "2f178801f12c5ad63eff12d19ea0c2f71259aa809574069bcaf32718120067bf",
+}
+
+func TestDeterministicGolden(t *testing.T) {
+	t.Setenv(EnvAllowDeterministic, "1")
+	plain := bytes.Repeat([]byte("golden plaintext "), testChunk/8) // three chunks, the last one partial
+	for _, mode := range seededModes {
+		t.Run(GetEncryptionModeName(mode), func(t *testing.T) {
+			a, err := encryptSeeded(t, mode, plain, testSeed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum := sha256.Sum256(a); hex.EncodeToString(sum[:]) != goldenSums[mode] {
+				t.Errorf("container sum %x, want %s", sum, goldenSums[mode])
+			}
+			b, err := encryptSeeded(t, mode, plain, testSeed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(a, b) {
+				t.Error("the same seed gave different containers")
+			}
+			other, err := encryptSeeded(t, mode, plain, []byte("another seed"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(a, other) {
+				t.Error("different seeds gave the same container")
+			}
+
+			path := filepath.Join(t.TempDir(), "golden.hadescrypt")
+			os.WriteFile(path, a, 0600)
+			got, err := decryptBytes(path, testPassword)
+			if err != nil || !bytes.Equal(got, plain) {
+				t.Errorf("decrypt: %v", err)
+			}
+		})
+	}
+}
+
+func TestWorkersSameOutput(t *testing.T) {
+	t.Setenv(EnvAllowDeterministic, "1")
+	plain := make([]byte, 9*encryptChunkSize+123)
+	rand.Read(plain)
+	for _, mode := range seededModes {
+		t.Run(GetEncryptionModeName(mode), func(t *testing.T) {
+			var first []byte
+			for _, workers := range []int{1, 3, 8} {
+				out := filepath.Join(t.TempDir(), "workers.hadescrypt")
+				opts := EncryptionOptions{Mode: mode, Argon2: testKDF, DeterministicSeed: testSeed, Workers: workers}
+				if err := EncryptReaderWithOptions(bytes.NewReader(plain), int64(len(plain)), out, testPassword, opts, nil); err != nil {
+					t.Fatal(err)
+				}
+				got, err := os.ReadFile(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if first == nil {
+					first = got
+					if dec, err := decryptBytes(out, testPassword); err != nil || !bytes.Equal(dec, plain) {
+						t.Fatalf("decrypt: %v", err)
+					}
+				} else if !bytes.Equal(got, first) {
+					t.Errorf("%d workers wrote a different container than 1", workers)
+				}
+			}
+		})
+	}
+}
+
+func TestDeterministicGated(t *testing.T) {
+	t.Setenv(EnvAllowDeterministic, "")
+	if _, err := encryptSeeded(t, ModeAES256GCM, []byte("x"), testSeed); !errors.Is(err, ErrDeterministicDisabled) {
+		t.Errorf("seed without %s: %v", EnvAllowDeterministic, err)
+	}
+	t.Setenv(EnvAllowDeterministic, "1")
+	out := filepath.Join(t.TempDir(), "compliance.hadescrypt")
+	opts := EncryptionOptions{Mode: ModeAES256GCM, Compliance: true, DeterministicSeed: testSeed}
+	if err := EncryptReaderWithOptions(bytes.NewReader([]byte("x")), 1, out, testPassword, opts, nil); err == nil {
+		t.Error("seed accepted in compliance mode")
+	}
+	for _, mode := range []EncryptionMode{ModePostQuantumKyber768, ModeHybridPQ} {
+		if _, err := encryptSeeded(t, mode, []byte("x"), testSeed); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("seed in %s: %v", GetEncryptionModeName(mode), err)
+		}
+	}
+	a, _ := encryptSeeded(t, ModeAES256GCM, []byte("x"), nil)
+	b, _ := encryptSeeded(t, ModeAES256GCM, []byte("x"), nil)
+	if bytes.Equal(a, b) {
+		t.Error("without a seed two encryptions were identical")
+	}
+}
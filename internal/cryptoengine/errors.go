@@ -0,0 +1,36 @@
+package cryptoengine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Sentinel errors returned (wrapped) by decryption, so callers can tell failure kinds apart
+var (
+	// ErrAuthFailed means the first chunk did not authenticate: almost always a wrong password or keyfile
+	ErrAuthFailed = errors.New("wrong password or keyfile")
+	// ErrCorrupt means the container is truncated or a later chunk failed authentication
+	ErrCorrupt = errors.New("file is corrupted or truncated")
+	// ErrNotContainer means the file does not start with the HadesCrypt magic
+	ErrNotContainer = errors.New("not a HadesCrypt file")
+	// ErrUnsupported means the container uses a version or mode this build cannot read
+	ErrUnsupported = errors.New("unsupported container format")
+)
+
+// chunkAuthError classifies an AEAD failure by position: a bad first chunk points to
+// the key, a bad later chunk to damaged data
+func chunkAuthError(counter uint32) error {
+	if counter == 0 {
+		return ErrAuthFailed
+	}
+	return fmt.Errorf("%w: chunk %d failed authentication", ErrCorrupt, counter)
+}
+
+// truncatedError wraps a short read of ciphertext; genuine IO errors pass through
+func truncatedError(err error) error {
+	if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrCorrupt, err)
+}
@@ -0,0 +1,69 @@
+package cryptoengine
+
+import "fmt"
+
+// EncryptedSize returns the size of the container EncryptReaderWithOptions
+// writes for size plaintext bytes with opts: the header with its metadata
+// and KEM block, the plaintext, and the tag of every chunk. Containers are not
+// padded, so the figure is exact. GnuPG output depends on gpg and is not
+// supported. With UseCompression, size is the length of the Deflate stream,
+// which the caller has to estimate. With AdaptiveChunks the number of chunks
+// depends on the speed of the input, and the figure is an upper bound.
+// UseReedSolomon adds the parity of every block and its padding. With
+// UseDeniability the random padding depends on the password, and the figure
+// counts the longest.
+func EncryptedSize(size int64, opts EncryptionOptions) (int64, error) {
+	if opts.Mode == ModeGnuPG && !opts.Compliance {
+		return 0, fmt.Errorf("%w: the size of GnuPG output is decided by gpg", ErrUnsupported)
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("negative size %d", size)
+	}
+	overhead, err := chunkOverhead(opts.Mode)
+	if err != nil {
+		return 0, err
+	}
+	hdr := &Header{Mode: opts.Mode, Argon2: opts.Argon2, Salt: make([]byte, saltLengthBytes), NoncePrefix: make([]byte, noncePrefixLen), ChunkSize: encryptChunkSize, OriginalSize: size}
+	if opts.Compliance {
+		hdr.Flags |= FlagCompliance
+	} else if !opts.Argon2.IsZero() && opts.Argon2 != DefaultArgon2 {
+		hdr.Flags |= FlagArgon2Params
+	}
+	if n := kemLen(opts.Mode); n > 0 {
+		hdr.Flags |= FlagKEM
+		hdr.KEM = make([]byte, n)
+	}
+	meta := opts.Metadata
+	if meta.Comment == "" {
+		meta.Comment = opts.Comments
+	}
+	hdr.SetMetadata(meta)
+	chunks := (size + encryptChunkSize - 1) / encryptChunkSize
+	if opts.UseCompression || opts.AdaptiveChunks {
+		// framed chunks end with the last frame, empty if the input fills the
+		// one before, and each carries its frame word
+		hdr.Flags |= FlagFramed
+		hdr.MinChunkSize = encryptChunkSize
+		overhead += frameLenSize
+		chunks = size/encryptChunkSize + 1
+	}
+	if opts.UseCompression {
+		hdr.Flags |= FlagCompressed
+	}
+	if opts.AdaptiveChunks {
+		// at most every chunk but the last stays at the smallest size
+		hdr.ChunkSize, hdr.MinChunkSize = adaptiveMaxChunk, adaptiveMinChunk
+		chunks = size/adaptiveMinChunk + 1
+	}
+	body := size + chunks*int64(overhead)
+	if opts.UseReedSolomon {
+		hdr.Flags |= FlagReedSolomon
+		hdr.DataShards, hdr.ParityShards = parityDataShards, parityParityShards
+		body = hdr.paritySize(body)
+	}
+	size = int64(len(hdr.Bytes())) + body
+	if opts.UseDeniability {
+		size += deniableOverhead()
+	}
+	return size, nil
+}
@@ -0,0 +1,117 @@
+package cryptoengine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// Version 3 headers follow FLAGS with [1]FLAGS2, reserved and zero so far,
+// and [2]EXT_LEN EXTENSIONS: records of [2]TYPE [2]LEN VALUE in ascending
+// type order, each type at most once. A type with extCritical set changes how
+// the container must be read, so a reader that does not know it fails with
+// ErrUnsupported; other types it does not know are kept, so the header still
+// serializes and authenticates byte for byte, but otherwise ignored. The
+// extensions are covered by the header MAC like everything before it.
+//
+// New header fields go here rather than into another version: FLAGS has no
+// bit left. Headers without extensions are written as version 1 or 2, so
+// older releases keep reading them.
+const (
+	fileVersionExt = byte(3)
+	extCritical    = 0x8000
+	maxExtLen      = 1<<16 - 1
+)
+
+// knownExtensions lists the extension types this build understands
+var knownExtensions = map[uint16]bool{}
+
+// Extension is one record of the extension area of a header
+type Extension struct {
+	Type  uint16
+	Value []byte
+}
+
+// Critical reports whether readers must understand the extension to read the container
+func (e Extension) Critical() bool { return e.Type&extCritical != 0 }
+
+// Extension returns the value of the extension of type t
+func (h *Header) Extension(t uint16) ([]byte, bool) {
+	i, ok := h.findExtension(t)
+	if !ok {
+		return nil, false
+	}
+	return h.Extensions[i].Value, true
+}
+
+// SetExtension sets the extension of type t to value, or removes it when
+// value is nil; the header must be sealed again before it is written
+func (h *Header) SetExtension(t uint16, value []byte) error {
+	i, ok := h.findExtension(t)
+	switch {
+	case value == nil && ok:
+		h.Extensions = slices.Delete(h.Extensions, i, i+1)
+	case value == nil:
+	case ok:
+		h.Extensions[i].Value = value
+	default:
+		h.Extensions = slices.Insert(h.Extensions, i, Extension{Type: t, Value: value})
+	}
+	h.MAC = nil
+	if n := extensionsLen(h.Extensions); n > maxExtLen {
+		return fmt.Errorf("header extensions take %d bytes; the limit is %d", n, maxExtLen)
+	}
+	return nil
+}
+
+// findExtension returns where the extension of type t is, or would go
+func (h *Header) findExtension(t uint16) (int, bool) {
+	return slices.BinarySearchFunc(h.Extensions, t, func(e Extension, t uint16) int { return int(e.Type) - int(t) })
+}
+
+// extensionsLen returns the length of the encoded extension area without EXT_LEN
+func extensionsLen(exts []Extension) int {
+	n := 0
+	for _, e := range exts {
+		n += 4 + len(e.Value)
+	}
+	return n
+}
+
+// appendExtensions appends [1]FLAGS2 and the extension area
+func appendExtensions(out []byte, exts []Extension) []byte {
+	out = append(out, 0)
+	out = binary.BigEndian.AppendUint16(out, uint16(extensionsLen(exts)))
+	for _, e := range exts {
+		out = binary.BigEndian.AppendUint16(out, e.Type)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(e.Value)))
+		out = append(out, e.Value...)
+	}
+	return out
+}
+
+// parseExtensions parses the extension area b strictly: records must fill it
+// exactly, in ascending type order, and critical ones must be known
+func parseExtensions(b []byte) ([]Extension, error) {
+	var exts []Extension
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("%w: %d stray bytes after the header extensions", ErrCorrupt, len(b))
+		}
+		e := Extension{Type: binary.BigEndian.Uint16(b)}
+		n := int(binary.BigEndian.Uint16(b[2:]))
+		if len(b) < 4+n {
+			return nil, fmt.Errorf("%w: header extension %#04x of %d bytes overruns the area", ErrCorrupt, e.Type, n)
+		}
+		if len(exts) > 0 && exts[len(exts)-1].Type >= e.Type {
+			return nil, fmt.Errorf("%w: header extension %#04x out of order", ErrCorrupt, e.Type)
+		}
+		if e.Critical() && !knownExtensions[e.Type] {
+			return nil, fmt.Errorf("%w: critical header extension %#04x", ErrUnsupported, e.Type)
+		}
+		e.Value = b[4 : 4+n : 4+n]
+		exts = append(exts, e)
+		b = b[4+n:]
+	}
+	return exts, nil
+}
@@ -0,0 +1,115 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+// withExtension rewrites the container at path with the extension t set to
+// value and the header sealed again
+func withExtension(t *testing.T, path string, typ uint16, value []byte) *Header {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := ReadHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := data[len(hdr.Bytes()):]
+	if err := hdr.SetExtension(typ, value); err != nil {
+		t.Fatal(err)
+	}
+	key, err := fileKey(testPassword, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hdr.seal(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(hdr.Bytes(), body...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return hdr
+}
+
+func TestHeaderExtensions(t *testing.T) {
+	enc, want := encryptTest(t, ModeAES256GCM, 2*testChunk+100, Metadata{Comment: "ext"})
+	withExtension(t, enc, 0x0042, []byte("from a later release"))
+
+	hdr, err := ReadHeaderFromFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := hdr.Extension(0x0042); hdr.Version != fileVersionExt || !ok || string(v) != "from a later release" {
+		t.Errorf("version %d, extension %q %v", hdr.Version, v, ok)
+	}
+	// an unknown extension that is not critical is ignored
+	if got, err := decryptBytes(enc, testPassword); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("decrypt: %d bytes, %v", len(got), err)
+	}
+
+	// the extensions are authenticated with the rest of the header
+	data, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := bytes.Index(data, []byte("later"))
+	data[i] = 'L'
+	if err := os.WriteFile(enc, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptBytes(enc, testPassword); !errors.Is(err, ErrHeaderAuth) {
+		t.Errorf("changed extension: got %v, want ErrHeaderAuth", err)
+	}
+	data[i] = 'l'
+	if err := os.WriteFile(enc, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// without extensions the header is version 2 again
+	withExtension(t, enc, 0x0042, nil)
+	if hdr, err := ReadHeaderFromFile(enc); err != nil || hdr.Version != fileVersionFlags || hdr.Extensions != nil {
+		t.Errorf("after removing the extension: %+v, %v", hdr, err)
+	}
+	if got, err := decryptBytes(enc, testPassword); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("decrypt without the extension: %d bytes, %v", len(got), err)
+	}
+
+	withExtension(t, enc, extCritical|0x0042, []byte{1})
+	if _, err := decryptBytes(enc, testPassword); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("unknown critical extension: got %v, want ErrUnsupported", err)
+	}
+}
+
+func TestParseExtensionsStrict(t *testing.T) {
+	for name, c := range map[string]struct {
+		area []byte
+		want error
+	}{
+		"stray bytes":  {[]byte{0, 1, 0, 0, 9}, ErrCorrupt},
+		"overrun":      {[]byte{0, 1, 0, 5, 1, 2}, ErrCorrupt},
+		"out of order": {[]byte{0, 2, 0, 0, 0, 1, 0, 0}, ErrCorrupt},
+		"duplicate":    {[]byte{0, 1, 0, 0, 0, 1, 0, 0}, ErrCorrupt},
+		"critical":     {[]byte{0x80, 1, 0, 0}, ErrUnsupported},
+		"unknown":      {[]byte{0, 1, 0, 1, 7, 0, 2, 0, 0}, nil},
+	} {
+		if _, err := parseExtensions(c.area); !errors.Is(err, c.want) || (err == nil) != (c.want == nil) {
+			t.Errorf("%s: got %v, want %v", name, err, c.want)
+		}
+	}
+
+	hdr := &Header{Mode: ModeAES256GCM, Salt: make([]byte, saltLengthBytes), NoncePrefix: make([]byte, noncePrefixLen), ChunkSize: testChunk}
+	hdr.SetExtension(7, []byte("x"))
+	b := hdr.Bytes()
+	b[7] = 1 // FLAGS2
+	if _, err := ReadHeader(bytes.NewReader(b)); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("reserved flags: got %v, want ErrUnsupported", err)
+	}
+	if err := hdr.SetExtension(8, make([]byte, maxExtLen)); err == nil {
+		t.Error("an oversized extension area was accepted")
+	}
+}
@@ -1,149 +1,80 @@
 package cryptoengine
 
 import (
-	"encoding/binary"
-	"fmt"
-	"io"
+	"maps"
 	"os"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/format"
 )
 
-// ExtractCommentsFromFile extracts comments from an encrypted file header
+// ExtractCommentsFromFile returns the comment stored in a container's header metadata
 func ExtractCommentsFromFile(inputPath string) (string, error) {
-	in, err := os.Open(inputPath)
+	hdr, err := ReadHeaderFromFile(inputPath)
 	if err != nil {
 		return "", err
 	}
-	defer in.Close()
-
-	// Read and validate header
-	header := make([]byte, 4)
-	if _, err := io.ReadFull(in, header); err != nil {
-		return "", err
-	}
-	if string(header) != fileMagic {
-		return "", fmt.Errorf("not a HadesCrypt file")
-	}
-
-	ver := make([]byte, 1)
-	if _, err := io.ReadFull(in, ver); err != nil {
-		return "", err
-	}
-	if ver[0] != fileVersion {
-		return "", fmt.Errorf("unsupported version: %d", ver[0])
-	}
-
-	// Read encryption mode (skip it)
-	modeBytes := make([]byte, 1)
-	if _, err := io.ReadFull(in, modeBytes); err != nil {
-		return "", err
-	}
-
-	// Skip salt and nonce prefix
-	salt := make([]byte, saltLengthBytes)
-	if _, err := io.ReadFull(in, salt); err != nil {
-		return "", err
-	}
-	noncePrefix := make([]byte, noncePrefixLen)
-	if _, err := io.ReadFull(in, noncePrefix); err != nil {
-		return "", err
-	}
-
-	// Skip chunk size and total size
-	var tmp4 [4]byte
-	if _, err := io.ReadFull(in, tmp4[:]); err != nil {
-		return "", err
-	}
-	var tmp8 [8]byte
-	if _, err := io.ReadFull(in, tmp8[:]); err != nil {
-		return "", err
-	}
-
-	// Read comment length
-	var commentLen [4]byte
-	if _, err := io.ReadFull(in, commentLen[:]); err != nil {
-		return "", err
-	}
-	commentLength := binary.BigEndian.Uint32(commentLen[:])
-
-	if commentLength == 0 {
-		return "", nil // No comments
-	}
-
-	if commentLength > 1024*1024 { // Sanity check: max 1MB comment
-		return "", fmt.Errorf("comment too large: %d bytes", commentLength)
-	}
-
-	// Read comment
-	commentBytes := make([]byte, commentLength)
-	if _, err := io.ReadFull(in, commentBytes); err != nil {
-		return "", err
-	}
-
-	return string(commentBytes), nil
+	return hdr.Metadata.Comment, nil
 }
 
 // ExtractEncryptionModeFromFile extracts the encryption mode from a HadesCrypt file
 func ExtractEncryptionModeFromFile(inputPath string) (EncryptionMode, error) {
-	in, err := os.Open(inputPath)
+	hdr, err := ReadHeaderFromFile(inputPath)
 	if err != nil {
 		return ModeAES256GCM, err
 	}
-	defer in.Close()
+	return hdr.Mode, nil
+}
 
-	// Read and validate header
-	header := make([]byte, 4)
-	if _, err := io.ReadFull(in, header); err != nil {
-		return ModeAES256GCM, err
-	}
-	if string(header) != fileMagic {
-		return ModeAES256GCM, fmt.Errorf("not a HadesCrypt file")
-	}
+// fileInfoCache keeps GetFileInfo results per path while the file's size and
+// modification time stay the same; it is emptied when it reaches fileInfoCacheSize
+var fileInfoCache = struct {
+	sync.Mutex
+	entries map[string]cachedFileInfo
+}{entries: map[string]cachedFileInfo{}}
 
-	ver := make([]byte, 1)
-	if _, err := io.ReadFull(in, ver); err != nil {
-		return ModeAES256GCM, err
-	}
-	if ver[0] != fileVersion {
-		return ModeAES256GCM, fmt.Errorf("unsupported version: %d", ver[0])
-	}
+const fileInfoCacheSize = 256
 
-	// Read encryption mode
-	modeBytes := make([]byte, 1)
-	if _, err := io.ReadFull(in, modeBytes); err != nil {
-		return ModeAES256GCM, err
-	}
-	
-	return EncryptionMode(modeBytes[0]), nil
+type cachedFileInfo struct {
+	size    int64
+	modTime time.Time
+	info    map[string]interface{}
 }
 
-// GetFileInfo returns information about an encrypted file
+// forgetFileInfo drops the cached result for path after the file was rewritten
+func forgetFileInfo(path string) {
+	fileInfoCache.Lock()
+	delete(fileInfoCache.entries, path)
+	fileInfoCache.Unlock()
+}
+
+// GetFileInfo returns information about an encrypted file. The header is read
+// once per version of the file; later calls are answered from a cache.
 func GetFileInfo(inputPath string) (map[string]interface{}, error) {
-	info := make(map[string]interface{})
-	
 	// Get basic file info
 	fileInfo, err := os.Stat(inputPath)
 	if err != nil {
 		return nil, err
 	}
 	
+	fileInfoCache.Lock()
+	c, ok := fileInfoCache.entries[inputPath]
+	fileInfoCache.Unlock()
+	if ok && c.size == fileInfo.Size() && c.modTime.Equal(fileInfo.ModTime()) {
+		return maps.Clone(c.info), nil
+	}
+	
+	info := make(map[string]interface{})
 	info["size"] = fileInfo.Size()
 	info["modified"] = fileInfo.ModTime()
 	info["name"] = fileInfo.Name()
 	
 	// Try to extract HadesCrypt specific info
-	comments, err := ExtractCommentsFromFile(inputPath)
+	hdr, err := ReadHeaderFromFile(inputPath)
 	if err == nil {
-		info["format"] = "HadesCrypt"
-		info["comments"] = comments
-		
-		// Try to get encryption mode
-		mode, modeErr := ExtractEncryptionModeFromFile(inputPath)
-		if modeErr == nil {
-			info["encryption_mode"] = mode
-			info["encryption_mode_name"] = GetEncryptionModeName(mode)
-		}
+		headerInfo(info, hdr)
 	} else {
 		// Check if it's a GnuPG file
 		if IsGnuPGFile(inputPath) {
@@ -156,9 +87,43 @@ func GetFileInfo(inputPath string) (map[string]interface{}, error) {
 		}
 	}
 	
+	fileInfoCache.Lock()
+	if len(fileInfoCache.entries) >= fileInfoCacheSize {
+		clear(fileInfoCache.entries)
+	}
+	fileInfoCache.entries[inputPath] = cachedFileInfo{size: fileInfo.Size(), modTime: fileInfo.ModTime(), info: info}
+	fileInfoCache.Unlock()
+	return maps.Clone(info), nil
+}
+
+// GetFileInfoWithPassword is GetFileInfo that also unwraps Deniability Mode
+// output with password, adding "deniable". Such results are not cached, and
+// every call on a file that may be wrapped costs an Argon2id run. Wrapped files
+// whose first byte has the high bit set look like OpenPGP to GetFileInfo, so
+// only files named like OpenPGP are left as such.
+func GetFileInfoWithPassword(inputPath string, password []byte) (map[string]interface{}, error) {
+	info, err := GetFileInfo(inputPath)
+	if err != nil || info["format"] == "HadesCrypt" || format.IsOpenPGP(inputPath) || len(password) == 0 || !MayBeDeniable(inputPath) {
+		return info, err
+	}
+	if hdr, err := ReadHeaderWithPassword(inputPath, password); err == nil && hdr.Deniable {
+		headerInfo(info, hdr)
+		info["deniable"] = true
+	}
 	return info, nil
 }
 
+// headerInfo adds what hdr tells to the GetFileInfo result info
+func headerInfo(info map[string]interface{}, hdr *Header) {
+	info["format"] = "HadesCrypt"
+	info["comments"] = hdr.Metadata.Comment
+	info["encryption_mode"] = hdr.Mode
+	info["encryption_mode_name"] = GetEncryptionModeName(hdr.Mode)
+	info["compliance"] = hdr.Compliance()
+	info["hint"] = hdr.Metadata.Hint
+	info["original_name"] = hdr.Metadata.Name
+}
+
 // GetEncryptionModeName returns human-readable name for encryption mode
 func GetEncryptionModeName(mode EncryptionMode) string {
 	switch mode {
@@ -176,16 +141,31 @@ func GetEncryptionModeName(mode EncryptionMode) string {
 		return "Post-Quantum: SPHINCS+"
 	case ModeGnuPG:
 		return "GnuPG/OpenPGP"
+	case ModeHybridPQ:
+		return "Hybrid PQ (Argon2id + ML-KEM-768)"
+	case ModeParanoidSerpent:
+		return "Paranoid (XChaCha20 + Serpent)"
 	default:
 		return "Unknown"
 	}
 }
 
+// ExtensionFor returns the output extension of a mode; containerExt is the
+// configured HadesCrypt extension (empty means the default)
+func ExtensionFor(mode EncryptionMode, containerExt string) string {
+	if mode == ModeGnuPG {
+		return format.OpenPGPExtension
+	}
+	if containerExt == "" {
+		return format.DefaultExtension
+	}
+	return containerExt
+}
+
 // IsGnuPGFile checks if a file is in GnuPG/OpenPGP format
 func IsGnuPGFile(filePath string) bool {
 	// Check by extension first
-	lowerPath := strings.ToLower(filePath)
-	if strings.HasSuffix(lowerPath, ".gpg") || strings.HasSuffix(lowerPath, ".pgp") {
+	if format.IsOpenPGP(filePath) {
 		return true
 	}
 	
@@ -214,30 +194,18 @@ func IsGnuPGFile(filePath string) bool {
 	return false
 }
 
-// FormatFileSize formats file size in human readable format
-func FormatFileSize(size int64) string {
-	const (
-		KB = 1024
-		MB = KB * 1024
-		GB = MB * 1024
-		TB = GB * 1024
-	)
-	
-	switch {
-	case size >= TB:
-		return fmt.Sprintf("%.2f TB", float64(size)/float64(TB))
-	case size >= GB:
-		return fmt.Sprintf("%.2f GB", float64(size)/float64(GB))
-	case size >= MB:
-		return fmt.Sprintf("%.2f MB", float64(size)/float64(MB))
-	case size >= KB:
-		return fmt.Sprintf("%.2f KB", float64(size)/float64(KB))
-	default:
-		return fmt.Sprintf("%d bytes", size)
-	}
-}
 
 // FormatTime formats time in user-friendly format
 func FormatTime(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05")
 }
+
+// ModeByName looks up a mode by the name GetEncryptionModeName returns (case-insensitive)
+func ModeByName(name string) (EncryptionMode, bool) {
+	for m := ModeAES256GCM; m <= ModeParanoidSerpent; m++ {
+		if strings.EqualFold(GetEncryptionModeName(m), name) {
+			return m, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,148 @@
+package cryptoengine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Framed containers (FlagFramed) put a [4] frame word in front of every
+// chunk: its plaintext length in the low 31 bits and frameLast in the top one,
+// set on the final chunk only. The word is the additional data of both AEAD
+// layers, so a changed length, a moved end or chunks dropped after a frame
+// fail authentication. Every chunk but the last holds MIN_CHUNK_SIZE to
+// CHUNK_SIZE bytes and the last at most CHUNK_SIZE, empty when the input
+// filled the one before. When ORIGINAL_SIZE is the length of the chunked data
+// the lengths must add up to it; the Deflate stream of a compressed container
+// and a stream end at the last frame alone. A length outside these bounds is
+// ErrCorrupt.
+//
+// Compressed containers are always framed, with MIN_CHUNK_SIZE equal to
+// CHUNK_SIZE. EncryptionOptions.AdaptiveChunks writes chunks that start at
+// adaptiveMinChunk and grow with the speed of the input.
+const (
+	adaptiveMinChunk = 64 << 10
+	adaptiveMaxChunk = 4 << 20
+	frameLenSize     = 4
+	frameLast        = 1 << 31
+)
+
+// adaptiveSlower is how much slower than the last read a read twice its size
+// may be before the chunk size stops growing; reads from the page cache are
+// noisy, so a little slowdown does not count
+const adaptiveSlower = 0.75
+
+// adaptiveSizer picks the size of each chunk of a framed container. It starts
+// small, so a slow source still shows progress and keeps the workers fed, and
+// doubles the size while the bigger reads arrive about as fast as the smaller
+// ones did, which is what sequential IO on spinning disks and network shares
+// does. Once a bigger read is clearly slower it goes back one step and stays.
+type adaptiveSizer struct {
+	size, max int
+	rate      float64 // bytes per second of the last full read
+	settled   bool
+	timed     bool // false grows on every full read, so the output is deterministic
+}
+
+// newAdaptiveSizer returns the sizer of the container hdr describes, or nil
+// unless opts asks for adaptive chunks
+func newAdaptiveSizer(hdr *Header, opts EncryptionOptions) *adaptiveSizer {
+	if !opts.AdaptiveChunks {
+		return nil
+	}
+	// a seed fixes the sizes as well, or the output would depend on timing
+	return &adaptiveSizer{size: hdr.MinChunkSize, max: hdr.ChunkSize, timed: opts.DeterministicSeed == nil}
+}
+
+// observe records that a read of n bytes for a chunk of a.size took d
+func (a *adaptiveSizer) observe(n int, d time.Duration) {
+	if a.settled || n < a.size || a.size >= a.max {
+		return
+	}
+	if a.timed {
+		rate := float64(n) / max(d.Seconds(), 1e-9)
+		if a.rate > 0 && rate < a.rate*adaptiveSlower {
+			a.size /= 2
+			a.settled = true
+			return
+		}
+		a.rate = rate
+	}
+	a.size = min(2*a.size, a.max)
+}
+
+// frameWord returns the frame word of a chunk of n bytes
+func frameWord(n int, last bool) []byte {
+	w := uint32(n)
+	if last {
+		w |= frameLast
+	}
+	return binary.BigEndian.AppendUint32(nil, w)
+}
+
+// frameTotal returns what the chunk lengths of h add up to, or -1 when only
+// the last frame ends them
+func (h *Header) frameTotal() int64 {
+	if h.Flags&(FlagStream|FlagCompressed) != 0 {
+		return -1
+	}
+	return h.OriginalSize
+}
+
+// readFrame reads the frame word in front of the next chunk of h, with
+// remaining bytes of chunked data still to come, or -1 if that is not known
+func readFrame(r io.Reader, h *Header, remaining int64) (int, bool, error) {
+	var b [frameLenSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, false, truncatedError(err)
+	}
+	return h.parseFrame(b[:], remaining)
+}
+
+// parseFrame checks the frame word b; see readFrame
+func (h *Header) parseFrame(b []byte, remaining int64) (int, bool, error) {
+	w := binary.BigEndian.Uint32(b)
+	n, last := int64(w&^frameLast), w&frameLast != 0
+	if n > int64(h.ChunkSize) || !last && n < int64(h.MinChunkSize) ||
+		remaining >= 0 && (n > remaining || last && n != remaining) {
+		return 0, false, fmt.Errorf("%w: a chunk of %d bytes (last %v) with %d to come", ErrCorrupt, n, last, remaining)
+	}
+	return int(n), last, nil
+}
+
+// frame is where a framed chunk lies
+type frame struct {
+	plain int64 // offset of its data
+	at    int64 // file offset of the sealed chunk, after its frame word
+	n     int   // data length
+	last  bool
+}
+
+// scanFrames reads the frame words of the chunks of h from the data at start
+// in r up to the last one, and returns the frames and the offset past them
+func scanFrames(r io.ReaderAt, h *Header, start int64, overhead int) ([]frame, int64, error) {
+	total := h.frameTotal()
+	var frames []frame
+	var plain int64
+	var b [frameLenSize]byte
+	for at := start; ; {
+		if _, err := r.ReadAt(b[:], at); err != nil {
+			return nil, 0, truncatedError(err)
+		}
+		remaining := int64(-1)
+		if total >= 0 {
+			remaining = total - plain
+		}
+		n, last, err := h.parseFrame(b[:], remaining)
+		if err != nil {
+			return nil, 0, err
+		}
+		frames = append(frames, frame{plain: plain, at: at + frameLenSize, n: n, last: last})
+		at += frameLenSize + int64(n+overhead)
+		plain += int64(n)
+		if last {
+			return frames, at, nil
+		}
+	}
+}
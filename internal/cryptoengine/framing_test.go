@@ -0,0 +1,292 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// encryptAdaptive encrypts plain in a framed container and returns its path.
+// A seed makes the chunk sizes grow on every read.
+func encryptAdaptive(t *testing.T, mode EncryptionMode, plain, seed []byte) string {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "adaptive.hadescrypt")
+	opts := EncryptionOptions{Mode: mode, Argon2: testKDF, AdaptiveChunks: true, DeterministicSeed: seed}
+	if err := EncryptReaderWithOptions(bytes.NewReader(plain), int64(len(plain)), out, testPassword, opts, nil); err != nil {
+		t.Fatalf("encrypt %s, %d bytes: %v", GetEncryptionModeName(mode), len(plain), err)
+	}
+	return out
+}
+
+// frameLens returns the chunk lengths of the framed container at path
+func frameLens(t *testing.T, path string) []int {
+	t.Helper()
+	r, err := OpenReader(path, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var lens []int
+	for _, f := range r.frames {
+		lens = append(lens, f.n)
+	}
+	return lens
+}
+
+func TestAdaptiveRoundTrip(t *testing.T) {
+	t.Setenv(EnvAllowDeterministic, "1")
+	sizes := []int{0, 1, adaptiveMinChunk, adaptiveMinChunk + 1, 3*adaptiveMinChunk + 7, 2*testChunk + 100}
+	for _, mode := range []EncryptionMode{ModeAES256GCM, ModeParanoidSerpent} {
+		for _, size := range sizes {
+			t.Run(fmt.Sprintf("%s/%d", GetEncryptionModeName(mode), size), func(t *testing.T) {
+				_, want := writePlain(t, size)
+				enc := encryptAdaptive(t, mode, want, testSeed)
+
+				hdr, err := ReadHeaderFromFile(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.Flags&FlagFramed == 0 || hdr.MinChunkSize != adaptiveMinChunk || hdr.ChunkSize != adaptiveMaxChunk {
+					t.Errorf("header: flags %#x, chunks %d-%d", hdr.Flags, hdr.MinChunkSize, hdr.ChunkSize)
+				}
+				fi, err := os.Stat(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				opts := EncryptionOptions{Mode: mode, Argon2: testKDF, AdaptiveChunks: true, KEMKey: testKEMKey}
+				if est, err := EncryptedSize(int64(size), opts); err != nil || est < fi.Size() {
+					t.Errorf("EncryptedSize(%d) = %d, %v; the container is %d bytes", size, est, err, fi.Size())
+				}
+
+				got, err := decryptBytes(enc, testPassword)
+				if err != nil || !bytes.Equal(got, want) {
+					t.Fatalf("decrypt: %d bytes, %v", len(got), err)
+				}
+				f, err := os.Open(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				dr, err := NewDecryptingReader(f, testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := io.ReadAll(dr); err != nil || !bytes.Equal(got, want) {
+					t.Fatalf("DecryptingReader: %d bytes, %v", len(got), err)
+				}
+
+				r, err := OpenReader(enc, testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer r.Close()
+				for _, off := range []int{0, adaptiveMinChunk - 1, 3 * adaptiveMinChunk, size - 1} {
+					if off < 0 || off >= size {
+						continue
+					}
+					buf := make([]byte, min(100, size-off))
+					if _, err := r.ReadAt(buf, int64(off)); err != nil && err != io.EOF || !bytes.Equal(buf, want[off:off+len(buf)]) {
+						t.Errorf("ReadAt %d: %v", off, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestAdaptiveGrowth(t *testing.T) {
+	t.Setenv(EnvAllowDeterministic, "1")
+	_, plain := writePlain(t, 9*testChunk)
+	lens := frameLens(t, encryptAdaptive(t, ModeChaCha20, plain, testSeed))
+	want := []int{64 << 10, 128 << 10, 256 << 10, 512 << 10, 1 << 20, 2 << 20, 4 << 20, 9*testChunk - (8<<20 - 64<<10)}
+	if fmt.Sprint(lens) != fmt.Sprint(want) {
+		t.Errorf("chunk lengths %v, want %v", lens, want)
+	}
+}
+
+func TestAdaptiveSizer(t *testing.T) {
+	a := &adaptiveSizer{size: 4, max: 64, timed: true}
+	a.observe(4, time.Second)
+	a.observe(8, 2*time.Second)
+	if a.size != 16 || a.settled {
+		t.Fatalf("steady rate: size %d, settled %v", a.size, a.settled)
+	}
+	a.observe(10, time.Second) // a short read is the end of the input
+	if a.size != 16 {
+		t.Fatalf("short read: size %d", a.size)
+	}
+	a.observe(16, 8*time.Second)
+	if a.size != 8 || !a.settled {
+		t.Fatalf("slower: size %d, settled %v", a.size, a.settled)
+	}
+	a.observe(8, time.Nanosecond)
+	if a.size != 8 {
+		t.Fatalf("settled: size %d", a.size)
+	}
+
+	u := &adaptiveSizer{size: 4, max: 16}
+	for range 4 {
+		u.observe(u.size, time.Hour)
+	}
+	if u.size != 16 {
+		t.Errorf("untimed: size %d, want the maximum", u.size)
+	}
+}
+
+func TestAdaptiveCorruptFrame(t *testing.T) {
+	_, plain := writePlain(t, 3*adaptiveMinChunk)
+	enc := encryptAdaptive(t, ModeAES256GCM, plain, nil)
+	hdr, err := ReadHeaderFromFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := len(hdr.Bytes())
+	for _, n := range []uint32{0, 1, adaptiveMaxChunk + 1, 1 << 31} {
+		bad := bytes.Clone(data)
+		binary.BigEndian.PutUint32(bad[at:], n)
+		path := filepath.Join(t.TempDir(), "bad.hadescrypt")
+		if err := os.WriteFile(path, bad, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decryptBytes(path, testPassword); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("length %d: decrypt got %v, want ErrCorrupt", n, err)
+		}
+		if _, err := OpenReader(path, testPassword); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("length %d: OpenReader got %v, want ErrCorrupt", n, err)
+		}
+	}
+	if _, err := Repair(enc, enc+".fixed", testPassword, nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Repair: got %v, want ErrUnsupported", err)
+	}
+}
+
+// encryptFramedStream encrypts data with NewEncryptingWriter and opts
+func encryptFramedStream(t *testing.T, data []byte, opts EncryptionOptions) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewEncryptingWriter(&buf, testPassword, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFramedStreamRoundTrip(t *testing.T) {
+	t.Setenv(EnvAllowDeterministic, "1")
+	variants := map[string]EncryptionOptions{
+		"compressed":          {UseCompression: true, CompressionLevel: 1},
+		"adaptive":            {AdaptiveChunks: true, DeterministicSeed: testSeed},
+		"compressed+adaptive": {UseCompression: true, AdaptiveChunks: true, DeterministicSeed: testSeed},
+	}
+	for name, opts := range variants {
+		for _, size := range []int{0, 1, adaptiveMinChunk, 2*testChunk + 100} {
+			t.Run(fmt.Sprintf("%s/%d", name, size), func(t *testing.T) {
+				_, want := writePlain(t, size)
+				opts.Mode, opts.Argon2 = ModeChaCha20, testKDF
+				enc := encryptFramedStream(t, want, opts)
+
+				r, err := NewDecryptingReader(bytes.NewReader(enc), testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, want) {
+					t.Fatalf("DecryptingReader: %d bytes, %v", len(got), err)
+				}
+
+				path := filepath.Join(t.TempDir(), "stream.hadescrypt")
+				if err := os.WriteFile(path, enc, 0600); err != nil {
+					t.Fatal(err)
+				}
+				hdr, err := ReadHeaderFromFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.Flags&(FlagStream|FlagFramed) != FlagStream|FlagFramed {
+					t.Errorf("header flags %#x", hdr.Flags)
+				}
+				if got, err := decryptBytes(path, testPassword); err != nil || !bytes.Equal(got, want) {
+					t.Fatalf("DecryptFileToWriter: %d bytes, %v", len(got), err)
+				}
+				if opts.UseCompression {
+					return
+				}
+				if hdr.OriginalSize != int64(size) {
+					t.Errorf("size from the frames is %d, want %d", hdr.OriginalSize, size)
+				}
+				ra, err := OpenReader(path, testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer ra.Close()
+				if got, err := io.ReadAll(ra); err != nil || !bytes.Equal(got, want) {
+					t.Errorf("Reader: %d bytes, %v", len(got), err)
+				}
+			})
+		}
+	}
+}
+
+func TestFrameWordAuthenticated(t *testing.T) {
+	t.Setenv(EnvAllowDeterministic, "1")
+	// chunks of 64 KiB and 128 KiB, then an empty last one
+	_, plain := writePlain(t, 3*adaptiveMinChunk)
+	opts := EncryptionOptions{Mode: ModeParanoidSerpent, Argon2: testKDF, AdaptiveChunks: true, DeterministicSeed: testSeed}
+	enc := encryptFramedStream(t, plain, opts)
+	hdr, err := ReadHeader(bytes.NewReader(enc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	overhead, err := chunkOverhead(hdr.Mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := len(hdr.Bytes())
+	second := first + frameLenSize + adaptiveMinChunk + overhead
+	third := second + frameLenSize + 2*adaptiveMinChunk + overhead
+
+	for name, damage := range map[string]func([]byte) []byte{
+		// the first chunk claims to end the stream
+		"last moved": func(b []byte) []byte { b[first] |= 0x80; return b[:second] },
+		// the empty last chunk is dropped
+		"cut at a frame": func(b []byte) []byte { return b[:third] },
+		// the second chunk claims its last byte is the next frame word
+		"length lowered": func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[second:], 2*adaptiveMinChunk-1)
+			return b
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			bad := damage(bytes.Clone(enc))
+			r, err := NewDecryptingReader(bytes.NewReader(bad), testPassword)
+			if err == nil {
+				_, err = io.ReadAll(r)
+			}
+			if !errors.Is(err, ErrAuthFailed) && !errors.Is(err, ErrCorrupt) {
+				t.Errorf("got %v, want a failure", err)
+			}
+			path := filepath.Join(t.TempDir(), "bad.hadescrypt")
+			if err := os.WriteFile(path, bad, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := decryptBytes(path, testPassword); !errors.Is(err, ErrAuthFailed) && !errors.Is(err, ErrCorrupt) {
+				t.Errorf("DecryptFileToWriter: got %v, want a failure", err)
+			}
+		})
+	}
+}
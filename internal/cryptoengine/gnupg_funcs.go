@@ -1,6 +1,7 @@
 package cryptoengine
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	
@@ -9,10 +10,20 @@ import (
 
 // EncryptFileWithGnuPG encrypts a file using GnuPG
 func EncryptFileWithGnuPG(inputPath, outputPath string, password []byte, onProgress ProgressCallback) error {
+    return EncryptFileWithGnuPGAt("", inputPath, outputPath, password, onProgress)
+}
+
+// EncryptFileWithGnuPGAt encrypts a file using the gpg binary at gpgPath (empty searches the system)
+func EncryptFileWithGnuPGAt(gpgPath, inputPath, outputPath string, password []byte, onProgress ProgressCallback) error {
+    return encryptFileWithGnuPG(gpgPath, nil, inputPath, outputPath, password, onProgress)
+}
+
+// encryptFileWithGnuPG encrypts to recipients when any are given, else with password
+func encryptFileWithGnuPG(gpgPath string, recipients []string, inputPath, outputPath string, password []byte, onProgress ProgressCallback) error {
     // Initialize GnuPG cipher
-    gpgCipher, err := gnupg.NewGnuPGCipher()
+    gpgCipher, err := gnupg.NewGnuPGCipherAt(gpgPath)
     if err != nil {
-        return fmt.Errorf("failed to initialize GnuPG: %w", err)
+        return fmt.Errorf("%w: %v", ErrUnsupported, err)
     }
     defer gpgCipher.Cleanup()
     
@@ -30,8 +41,10 @@ func EncryptFileWithGnuPG(inputPath, outputPath string, password []byte, onProgr
     options := gnupg.DefaultGnuPGOptions()
     options.Cipher = "AES256"
     options.Compression = "ZLIB"
-    options.UseSymmetric = true
+    options.UseSymmetric = len(recipients) == 0
+    options.Recipients = recipients
     options.ArmorOutput = false // Binary output
+    options.Progress = gnupgProgress(totalSize, onProgress)
     
     // Report initial progress
     if onProgress != nil {
@@ -41,7 +54,7 @@ func EncryptFileWithGnuPG(inputPath, outputPath string, password []byte, onProgr
     // Encrypt file
     err = gpgCipher.EncryptFile(inputPath, outputPath, options)
     if err != nil {
-        return fmt.Errorf("GnuPG encryption failed: %w", err)
+        return gnupgError(err)
     }
     
     // Report completion
@@ -54,10 +67,15 @@ func EncryptFileWithGnuPG(inputPath, outputPath string, password []byte, onProgr
 
 // DecryptFileWithGnuPG decrypts a file using GnuPG
 func DecryptFileWithGnuPG(inputPath, outputPath string, password []byte, onProgress ProgressCallback) error {
+    return DecryptFileWithGnuPGAt("", inputPath, outputPath, password, onProgress)
+}
+
+// DecryptFileWithGnuPGAt decrypts a file using the gpg binary at gpgPath (empty searches the system)
+func DecryptFileWithGnuPGAt(gpgPath, inputPath, outputPath string, password []byte, onProgress ProgressCallback) error {
     // Initialize GnuPG cipher
-    gpgCipher, err := gnupg.NewGnuPGCipher()
+    gpgCipher, err := gnupg.NewGnuPGCipherAt(gpgPath)
     if err != nil {
-        return fmt.Errorf("failed to initialize GnuPG: %w", err)
+        return fmt.Errorf("%w: %v", ErrUnsupported, err)
     }
     defer gpgCipher.Cleanup()
     
@@ -74,6 +92,7 @@ func DecryptFileWithGnuPG(inputPath, outputPath string, password []byte, onProgr
     // Configure GnuPG options
     options := gnupg.DefaultGnuPGOptions()
     options.UseSymmetric = true
+    options.Progress = gnupgProgress(totalSize, onProgress)
     
     // Report initial progress
     if onProgress != nil {
@@ -83,7 +102,7 @@ func DecryptFileWithGnuPG(inputPath, outputPath string, password []byte, onProgr
     // Decrypt file
     err = gpgCipher.DecryptFile(inputPath, outputPath, options)
     if err != nil {
-        return fmt.Errorf("GnuPG decryption failed: %w", err)
+        return gnupgError(err)
     }
     
     // Report completion
@@ -93,3 +112,35 @@ func DecryptFileWithGnuPG(inputPath, outputPath string, password []byte, onProgr
     
     return nil
 }
+
+// gnupgProgress scales gpg's own progress (which counts its input in KiB) onto totalSize
+func gnupgProgress(totalSize int64, onProgress ProgressCallback) gnupg.ProgressFunc {
+    if onProgress == nil {
+        return nil
+    }
+    return func(done, total int64) {
+        if total <= 0 {
+            return
+        }
+        n := int64(float64(done) / float64(total) * float64(totalSize))
+        if n < 0 {
+            n = 0
+        } else if n > totalSize {
+            n = totalSize
+        }
+        onProgress(n, totalSize)
+    }
+}
+
+// gnupgError maps gpg's classified failures onto the engine's error kinds
+func gnupgError(err error) error {
+    switch {
+    case errors.Is(err, gnupg.ErrBadPassphrase), errors.Is(err, gnupg.ErrNoSecretKey):
+        return fmt.Errorf("%w: %v", ErrAuthFailed, err)
+    case errors.Is(err, gnupg.ErrCorrupt):
+        return fmt.Errorf("%w: %v", ErrCorrupt, err)
+    case errors.Is(err, gnupg.ErrNoData):
+        return fmt.Errorf("%w: %v", ErrNotContainer, err)
+    }
+    return fmt.Errorf("GnuPG failed: %w", err)
+}
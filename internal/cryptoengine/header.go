@@ -0,0 +1,302 @@
+package cryptoengine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Header flags (only present in version 2 headers)
+const (
+	FlagCompliance   byte = 1 << 0 // AES-256-GCM with PBKDF2-HMAC-SHA256, see compliance.go
+	FlagArgon2Params byte = 1 << 1 // non-default Argon2id parameters follow the FLAGS byte, see kdf.go
+	FlagMetadata     byte = 1 << 2 // a metadata block and its MAC end the header, see metadata.go
+	FlagStream       byte = 1 << 3 // ORIGINAL_SIZE is 0 and a chunk shorter than CHUNK_SIZE, or the last frame, ends the data, see stream.go
+	FlagKEM          byte = 1 << 4 // a hybrid KEM block wrapping the file key follows ORIGINAL_SIZE, see kem.go
+	FlagCompressed   byte = 1 << 5 // the chunks hold a Deflate stream of the ORIGINAL_SIZE plaintext bytes, ended by the last frame or as with FlagStream, see compress.go
+	FlagFramed       byte = 1 << 6 // [4]MIN_CHUNK_SIZE follows ORIGINAL_SIZE and every chunk is preceded by its authenticated [4] frame word, see framing.go
+	FlagReedSolomon  byte = 1 << 7 // [1]DATA_SHARDS [1]PARITY_SHARDS follow and everything after the header is Reed-Solomon coded, see parity.go
+
+	knownFlags = FlagCompliance | FlagArgon2Params | FlagMetadata | FlagStream | FlagKEM | FlagCompressed | FlagFramed | FlagReedSolomon
+)
+
+// maxChunkSize bounds the chunk size a header may declare, so a damaged header
+// cannot make readers allocate huge buffers
+const maxChunkSize = 64 << 20
+
+// fileVersionFlags is written instead of fileVersion when any flag is set.
+// Version 2 inserts a [1]FLAGS byte right after MODE; everything else is unchanged,
+// so files without flags stay readable by older releases. Version 3 adds the
+// extension area, see ext.go.
+const fileVersionFlags = byte(2)
+
+// Header is the parsed fixed-size part of a HadesCrypt container:
+// [4]MAGIC | [1]VERSION | [1]MODE | ([1]FLAGS, v2+) | ([1]FLAGS2 [2]EXT_LEN EXTENSIONS, v3) |
+// ([4]TIME [4]MEMORY [1]THREADS, FlagArgon2Params) |
+// [16]SALT | [8]NONCE_PREFIX | [4]CHUNK_SIZE | [8]ORIGINAL_SIZE | ([4]MIN_CHUNK_SIZE, FlagFramed) |
+// ([1]DATA_SHARDS [1]PARITY_SHARDS, FlagReedSolomon) | ([1176|1096]KEM, FlagKEM) | (METADATA [32]MAC, FlagMetadata)
+type Header struct {
+	Version        byte
+	Mode           EncryptionMode
+	Flags          byte
+	Extensions     []Extension  // in ascending type order, only in version 3
+	Argon2         Argon2Params // only meaningful with FlagArgon2Params; see KDFParams
+	Salt           []byte
+	NoncePrefix    []byte
+	ChunkSize      int // the largest chunk with FlagFramed
+	MinChunkSize   int // the smallest chunk but the last, only with FlagFramed
+	DataShards     int // Reed-Solomon shard counts, only with FlagReedSolomon
+	ParityShards   int
+	OriginalSize   int64    // of a stream, filled in by readFileHeader unless it is compressed
+	CompressedSize int64    // of the Deflate stream, with FlagCompressed; filled in by readFileHeader
+	KEM            []byte   // encapsulated file key, only with FlagKEM
+	Metadata       Metadata // only with FlagMetadata
+	MAC            []byte   // HMAC-SHA256 of the rest of the header, only with FlagMetadata
+	Deniable       bool     // read through a Deniability Mode wrapping; not part of the header
+	kemKey         *KEMKey  // the KEM key a container being written encrypts to
+}
+
+// Compliance reports whether the file was written in compliance mode
+func (h *Header) Compliance() bool { return h.Flags&FlagCompliance != 0 }
+
+// KDFParams returns the Argon2id parameters the file key was derived with
+func (h *Header) KDFParams() Argon2Params {
+	if h.Flags&FlagArgon2Params != 0 {
+		return h.Argon2
+	}
+	return DefaultArgon2
+}
+
+// ReadHeader parses a header from r, leaving r positioned at the first ciphertext byte
+func ReadHeader(r io.Reader) (*Header, error) {
+	var fixed [6]byte
+	if _, err := io.ReadFull(r, fixed[:]); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrCorrupt, err)
+	}
+	if string(fixed[:4]) != fileMagic {
+		return nil, ErrNotContainer
+	}
+	h := &Header{Version: fixed[4], Mode: EncryptionMode(fixed[5])}
+	switch h.Version {
+	case fileVersion:
+	case fileVersionFlags, fileVersionExt:
+		var flags [1]byte
+		if _, err := io.ReadFull(r, flags[:]); err != nil {
+			return nil, truncatedError(err)
+		}
+		h.Flags = flags[0]
+		if h.Version == fileVersionExt {
+			if err := h.readExtensions(r); err != nil {
+				return nil, err
+			}
+		}
+		if h.Flags&^knownFlags != 0 {
+			return nil, fmt.Errorf("%w: header flags %#x", ErrUnsupported, h.Flags)
+		}
+		if h.Flags&(FlagStream|FlagCompressed|FlagFramed) == FlagStream|FlagCompressed {
+			return nil, fmt.Errorf("%w: a compressed stream must be framed", ErrCorrupt)
+		}
+		if h.Flags&FlagArgon2Params != 0 {
+			var kdf [9]byte
+			if _, err := io.ReadFull(r, kdf[:]); err != nil {
+				return nil, truncatedError(err)
+			}
+			h.Argon2 = Argon2Params{
+				Time:    binary.BigEndian.Uint32(kdf[0:4]),
+				Memory:  binary.BigEndian.Uint32(kdf[4:8]),
+				Threads: kdf[8],
+			}
+			if err := h.Argon2.validate(); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupported, h.Version)
+	}
+
+	rest := make([]byte, saltLengthBytes+noncePrefixLen+4+8)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, truncatedError(err)
+	}
+	h.Salt = rest[:saltLengthBytes]
+	h.NoncePrefix = rest[saltLengthBytes : saltLengthBytes+noncePrefixLen]
+	h.ChunkSize = int(binary.BigEndian.Uint32(rest[saltLengthBytes+noncePrefixLen:]))
+	h.OriginalSize = int64(binary.BigEndian.Uint64(rest[saltLengthBytes+noncePrefixLen+4:]))
+	if h.ChunkSize <= 0 || h.ChunkSize > maxChunkSize || h.OriginalSize < 0 {
+		return nil, fmt.Errorf("%w: chunk size %d, size %d", ErrCorrupt, h.ChunkSize, h.OriginalSize)
+	}
+	if h.Flags&FlagFramed != 0 {
+		var minSize [4]byte
+		if _, err := io.ReadFull(r, minSize[:]); err != nil {
+			return nil, truncatedError(err)
+		}
+		h.MinChunkSize = int(binary.BigEndian.Uint32(minSize[:]))
+		if h.MinChunkSize <= 0 || h.MinChunkSize > h.ChunkSize {
+			return nil, fmt.Errorf("%w: chunk sizes %d to %d", ErrCorrupt, h.MinChunkSize, h.ChunkSize)
+		}
+	}
+	if h.Flags&FlagReedSolomon != 0 {
+		var shards [2]byte
+		if _, err := io.ReadFull(r, shards[:]); err != nil {
+			return nil, truncatedError(err)
+		}
+		h.DataShards, h.ParityShards = int(shards[0]), int(shards[1])
+		if h.DataShards == 0 || h.ParityShards == 0 || h.DataShards+h.ParityShards > 256 {
+			return nil, fmt.Errorf("%w: %d data and %d parity shards", ErrCorrupt, h.DataShards, h.ParityShards)
+		}
+	}
+	if h.Flags&FlagKEM != 0 {
+		h.KEM = make([]byte, kemLen(h.Mode))
+		if _, err := io.ReadFull(r, h.KEM); err != nil {
+			return nil, truncatedError(err)
+		}
+	}
+	if h.Flags&FlagMetadata != 0 {
+		if err := h.readMetadata(r); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// readExtensions reads [1]FLAGS2 and the extension area of a version 3 header
+func (h *Header) readExtensions(r io.Reader) error {
+	var fixed [3]byte
+	if _, err := io.ReadFull(r, fixed[:]); err != nil {
+		return truncatedError(err)
+	}
+	if fixed[0] != 0 {
+		return fmt.Errorf("%w: reserved header flags %#x", ErrUnsupported, fixed[0])
+	}
+	area := make([]byte, binary.BigEndian.Uint16(fixed[1:]))
+	if _, err := io.ReadFull(r, area); err != nil {
+		return truncatedError(err)
+	}
+	exts, err := parseExtensions(area)
+	if err != nil {
+		return err
+	}
+	h.Extensions = exts
+	return nil
+}
+
+// ReadHeaderFromFile opens path and parses its header; Deniability Mode
+// output needs ReadHeaderWithPassword
+func ReadHeaderFromFile(path string) (*Header, error) {
+	return ReadHeaderWithPassword(path, nil)
+}
+
+// endsShort reports whether the chunks run until one shorter than the chunk
+// size rather than for a size in the header or up to the last frame
+func (h *Header) endsShort() bool {
+	return h.Flags&FlagFramed == 0 && h.Flags&(FlagStream|FlagCompressed) != 0
+}
+
+// sealedSize is the length of the data split into chunks: the plaintext, or
+// the Deflate stream of a compressed container
+func (h *Header) sealedSize() int64 {
+	if h.Flags&FlagCompressed != 0 {
+		return h.CompressedSize
+	}
+	return h.OriginalSize
+}
+
+// readFileHeader parses the header of the container f, leaving f positioned at
+// the first ciphertext byte. The size of a stream, or of the Deflate stream of
+// a compressed container, is worked out from the frame words or the length
+// of f; in a Reed-Solomon container it is only known once the parity is
+// decoded, and left as it is.
+func readFileHeader(f *io.SectionReader) (*Header, error) {
+	h, err := ReadHeader(f)
+	if err != nil || h.Flags&(FlagStream|FlagCompressed) == 0 || h.Flags&FlagReedSolomon != 0 {
+		return h, err
+	}
+	overhead, err := h.chunkOverhead()
+	if err != nil {
+		return nil, err
+	}
+	if h.Flags&FlagFramed != 0 {
+		frames, _, err := scanFrames(f, h, int64(len(h.Bytes())), overhead)
+		if err != nil {
+			return nil, err
+		}
+		last := frames[len(frames)-1]
+		h.setChunkedSize(last.plain + int64(last.n))
+		return h, nil
+	}
+	// every chunk is full but the last, which holds at least its overhead
+	data := f.Size() - int64(len(h.Bytes()))
+	sealed := int64(h.ChunkSize + overhead)
+	if data%sealed < int64(overhead) {
+		return nil, fmt.Errorf("%w: the stream does not end with a short chunk", ErrCorrupt)
+	}
+	h.setChunkedSize(data/sealed*int64(h.ChunkSize) + data%sealed - int64(overhead))
+	return h, nil
+}
+
+// setChunkedSize records the length of the chunked data of a stream or a
+// compressed container once it is known
+func (h *Header) setChunkedSize(size int64) {
+	if h.Flags&FlagCompressed != 0 {
+		h.CompressedSize = size
+	} else {
+		h.OriginalSize = size
+	}
+}
+
+// Bytes serializes the header
+func (h *Header) Bytes() []byte {
+	out := h.unsealed()
+	if h.Flags&FlagMetadata != 0 {
+		mac := make([]byte, headerMACLen)
+		copy(mac, h.MAC)
+		out = append(out, mac...)
+	}
+	return out
+}
+
+// unsealed serializes the header without its MAC, which is computed over exactly these bytes
+func (h *Header) unsealed() []byte {
+	version := fileVersion
+	switch {
+	case len(h.Extensions) > 0:
+		version = fileVersionExt
+	case h.Flags != 0:
+		version = fileVersionFlags
+	}
+	out := make([]byte, 0, 16+saltLengthBytes+noncePrefixLen+12)
+	out = append(out, fileMagic...)
+	out = append(out, version, byte(h.Mode))
+	if version != fileVersion {
+		out = append(out, h.Flags)
+	}
+	if version == fileVersionExt {
+		out = appendExtensions(out, h.Extensions)
+	}
+	if h.Flags&FlagArgon2Params != 0 {
+		out = binary.BigEndian.AppendUint32(out, h.Argon2.Time)
+		out = binary.BigEndian.AppendUint32(out, h.Argon2.Memory)
+		out = append(out, h.Argon2.Threads)
+	}
+	out = append(out, h.Salt...)
+	out = append(out, h.NoncePrefix...)
+	out = binary.BigEndian.AppendUint32(out, uint32(h.ChunkSize))
+	if h.Flags&FlagStream != 0 {
+		out = binary.BigEndian.AppendUint64(out, 0)
+	} else {
+		out = binary.BigEndian.AppendUint64(out, uint64(h.OriginalSize))
+	}
+	if h.Flags&FlagFramed != 0 {
+		out = binary.BigEndian.AppendUint32(out, uint32(h.MinChunkSize))
+	}
+	if h.Flags&FlagReedSolomon != 0 {
+		out = append(out, byte(h.DataShards), byte(h.ParityShards))
+	}
+	if h.Flags&FlagKEM != 0 {
+		out = append(out, h.KEM...)
+	}
+	if h.Flags&FlagMetadata != 0 {
+		out = h.Metadata.appendTo(out)
+	}
+	return out
+}
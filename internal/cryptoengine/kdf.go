@@ -0,0 +1,53 @@
+package cryptoengine
+
+import "fmt"
+
+// Argon2Params are the Argon2id cost parameters used to derive a file key
+type Argon2Params struct {
+	Time    uint32
+	Memory  uint32 // in KiB
+	Threads uint8
+}
+
+// Upper bounds accepted when reading a header, so a crafted file cannot
+// make decryption allocate unbounded memory
+const (
+	maxArgon2Time   = 64
+	maxArgon2Memory = 4 << 20 // 4 GiB
+)
+
+// DefaultArgon2 are the parameters implied by headers without FlagArgon2Params
+var DefaultArgon2 = Argon2Params{Time: argonTime, Memory: argonMemory, Threads: argonThreads}
+
+// Argon2PresetNames lists the presets offered in the UI, weakest first
+var Argon2PresetNames = []string{"Fast", "Balanced", "Strong", "Maximum"}
+
+var argon2Presets = map[string]Argon2Params{
+	"Fast":     {Time: 1, Memory: 32 * 1024, Threads: 4},
+	"Balanced": DefaultArgon2,
+	"Strong":   {Time: 3, Memory: 256 * 1024, Threads: 4},
+	"Maximum":  {Time: 4, Memory: 1024 * 1024, Threads: 4},
+}
+
+// Argon2Preset returns the parameters of a named preset; unknown names give the defaults
+func Argon2Preset(name string) Argon2Params {
+	if p, ok := argon2Presets[name]; ok {
+		return p
+	}
+	return DefaultArgon2
+}
+
+// IsZero reports whether no parameters were set
+func (p Argon2Params) IsZero() bool { return p == Argon2Params{} }
+
+// String formats the parameters for display
+func (p Argon2Params) String() string {
+	return fmt.Sprintf("Argon2id t=%d, %d MiB, %d threads", p.Time, p.Memory/1024, p.Threads)
+}
+
+func (p Argon2Params) validate() error {
+	if p.Time == 0 || p.Time > maxArgon2Time || p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory || p.Threads == 0 {
+		return fmt.Errorf("invalid Argon2 parameters (%s)", p)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package cryptoengine
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/mlkem"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// ModePostQuantumKyber768 seals chunks with AES-256-GCM under a random file
+// key. The file key is wrapped with a key derived from the Argon2id key and
+// the secrets of an encapsulation to the KEMKey of EncryptionOptions, a hybrid
+// of ML-KEM-768 (FIPS 203, the standardized Kyber) and X25519; see kemkey.go.
+// Opening the file takes the password and the key file, so a guessed password
+// or a broken KEM alone does not. The encapsulation travels in the header's
+// KEM block:
+//
+//	[8]KEY_ID | [32]X25519_EPHEMERAL | [1088]MLKEM_CIPHERTEXT | [48]WRAPPED_FILE_KEY
+const (
+	x25519KeyLen  = 32
+	wrappedKeyLen = 32 + gcmOverhead
+	kemBlockLen   = kemKeyIDLen + x25519KeyLen + mlkem.CiphertextSize768 + wrappedKeyLen
+)
+
+// ModeHybridPQ seals chunks with AES-256-GCM under a key combined with HKDF
+// from the Argon2id key and the shared secret of an ML-KEM-768 encapsulation
+// to the KEMKey of EncryptionOptions. The KEM key is drawn apart from the
+// password, so decryption needs both the password and the key file, and the
+// file holds as long as either Argon2id with the password or ML-KEM does. Its
+// KEM block is the key ID and the ML-KEM ciphertext; the key is not wrapped
+// but derived from both halves.
+//
+//	[8]KEY_ID | [1088]MLKEM_CIPHERTEXT
+const hybridBlockLen = kemKeyIDLen + mlkem.CiphertextSize768
+
+// kemLen returns the size of the KEM block of mode, 0 for modes without one
+func kemLen(mode EncryptionMode) int {
+	switch mode {
+	case ModePostQuantumKyber768:
+		return kemBlockLen
+	case ModeHybridPQ:
+		return hybridBlockLen
+	}
+	return 0
+}
+
+// keyWrap returns the AEAD that wraps the file key. Its key hashes the
+// password key and both shared secrets with the whole encapsulation and the
+// recipient's X25519 key, so no part can be left out or swapped.
+func keyWrap(passwordKey, mlkemShared, x25519Shared, block, recipient, salt []byte) (cipher.AEAD, error) {
+	secret := append(append(append([]byte{}, passwordKey...), mlkemShared...), x25519Shared...)
+	defer Wipe(secret)
+	info := "HadesCrypt hybrid KEM file key v2\x00" + string(block) + string(recipient)
+	kek, err := hkdf.Key(sha256.New, secret, salt, info, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer Wipe(kek)
+	c, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
+
+// encapsulate draws a file key for h, stores its encapsulation to h.kemKey in
+// h.KEM and returns it. ML-KEM draws its own randomness, so only the X25519
+// half and the file key come from random.
+func (h *Header) encapsulate(passwordKey []byte, random io.Reader) ([]byte, error) {
+	k := h.kemKey
+	mlkemShared, ciphertext := k.dk.EncapsulationKey().Encapsulate()
+	defer Wipe(mlkemShared)
+	ephemeralKey := make([]byte, x25519KeyLen)
+	if _, err := io.ReadFull(random, ephemeralKey); err != nil {
+		return nil, fmt.Errorf("generate X25519 key: %w", err)
+	}
+	ephemeral, err := ecdh.X25519().NewPrivateKey(ephemeralKey)
+	Wipe(ephemeralKey)
+	if err != nil {
+		return nil, err
+	}
+	x25519Shared, err := ephemeral.ECDH(k.xk.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	defer Wipe(x25519Shared)
+	block := append(append(append([]byte{}, k.id...), ephemeral.PublicKey().Bytes()...), ciphertext...)
+	wrap, err := keyWrap(passwordKey, mlkemShared, x25519Shared, block, k.xk.PublicKey().Bytes(), h.Salt)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(random, key); err != nil {
+		return nil, fmt.Errorf("generate file key: %w", err)
+	}
+	// the wrapping key is used once, so the nonce can be fixed
+	h.KEM = wrap.Seal(block, make([]byte, wrap.NonceSize()), key, nil)
+	return key, nil
+}
+
+// decapsulate recovers the file key from h.KEM with the loaded KEM key it
+// names; a wrong password fails to unwrap it
+func (h *Header) decapsulate(passwordKey []byte) ([]byte, error) {
+	if len(h.KEM) != kemBlockLen {
+		return nil, fmt.Errorf("%w: KEM block of %d bytes", ErrCorrupt, len(h.KEM))
+	}
+	k, err := kemKeyByID(h.KEM[:kemKeyIDLen])
+	if err != nil {
+		return nil, err
+	}
+	block := h.KEM[:kemBlockLen-wrappedKeyLen]
+	epk := block[kemKeyIDLen : kemKeyIDLen+x25519KeyLen]
+	ciphertext := block[kemKeyIDLen+x25519KeyLen:]
+	wrapped := h.KEM[len(block):]
+	mlkemShared, err := k.dk.Decapsulate(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer Wipe(mlkemShared)
+	ephemeral, err := ecdh.X25519().NewPublicKey(epk)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	x25519Shared, err := k.xk.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer Wipe(x25519Shared)
+	wrap, err := keyWrap(passwordKey, mlkemShared, x25519Shared, block, k.xk.PublicKey().Bytes(), h.Salt)
+	if err != nil {
+		return nil, err
+	}
+	key, err := wrap.Open(nil, make([]byte, wrap.NonceSize()), wrapped, nil)
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return key, nil
+}
+
+// combineKeys derives the ModeHybridPQ file key from the Argon2id key and the
+// ML-KEM shared secret, bound to the KEM block that carried it
+func combineKeys(passwordKey, shared, block, salt []byte) ([]byte, error) {
+	secret := append(append([]byte{}, passwordKey...), shared...)
+	defer Wipe(secret)
+	return hkdf.Key(sha256.New, secret, salt, "HadesCrypt hybrid PQ file key v2\x00"+string(block), int(keyLen))
+}
+
+// encapsulateHybrid stores a fresh ML-KEM encapsulation to h.kemKey in h.KEM
+// and returns the file key combined from it and passwordKey
+func (h *Header) encapsulateHybrid(passwordKey []byte) ([]byte, error) {
+	k := h.kemKey
+	shared, ciphertext := k.dk.EncapsulationKey().Encapsulate()
+	defer Wipe(shared)
+	h.KEM = append(append([]byte{}, k.id...), ciphertext...)
+	return combineKeys(passwordKey, shared, h.KEM, h.Salt)
+}
+
+// decapsulateHybrid recovers the ModeHybridPQ file key with the loaded KEM key
+// h.KEM names. ML-KEM rejects implicitly, so a wrong password or a KEM key
+// with a colliding ID yields a wrong key that fails the header MAC or the
+// first chunk.
+func (h *Header) decapsulateHybrid(passwordKey []byte) ([]byte, error) {
+	if len(h.KEM) != hybridBlockLen {
+		return nil, fmt.Errorf("%w: KEM block of %d bytes", ErrCorrupt, len(h.KEM))
+	}
+	k, err := kemKeyByID(h.KEM[:kemKeyIDLen])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := k.dk.Decapsulate(h.KEM[kemKeyIDLen:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer Wipe(shared)
+	return combineKeys(passwordKey, shared, h.KEM, h.Salt)
+}
+
+// fileKey returns the key the chunks and header MAC of h are sealed with: the
+// password key, or for a KEM header the file key it wraps or combines
+func fileKey(password []byte, h *Header) ([]byte, error) {
+	if err := checkPQMode(h); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(password, h)
+	if err != nil || h.Flags&FlagKEM == 0 {
+		return key, err
+	}
+	defer Wipe(key)
+	if h.Mode == ModeHybridPQ {
+		return h.decapsulateHybrid(key)
+	}
+	return h.decapsulate(key)
+}
+
+// newFileKey is fileKey for a container being written: a KEM header without
+// its block gets a fresh file key encapsulated to h.kemKey
+func newFileKey(password []byte, h *Header, random io.Reader) ([]byte, error) {
+	if h.Flags&FlagKEM == 0 || h.KEM != nil {
+		return fileKey(password, h)
+	}
+	key, err := deriveKey(password, h)
+	if err != nil {
+		return nil, err
+	}
+	defer Wipe(key)
+	if h.Mode == ModeHybridPQ {
+		return h.encapsulateHybrid(key)
+	}
+	return h.encapsulate(key, random)
+}
+
+// checkPQMode rejects ModeHybridPQ headers without a KEM block and headers
+// of other modes with one; Kyber headers without one are legacy, see
+// legacypq.go
+func checkPQMode(h *Header) error {
+	switch h.Mode {
+	case ModePostQuantumKyber768:
+	case ModeHybridPQ:
+		if h.Flags&FlagKEM == 0 {
+			return fmt.Errorf("%w: %s container without its KEM block", ErrCorrupt, GetEncryptionModeName(h.Mode))
+		}
+	default:
+		if h.Flags&FlagKEM != 0 {
+			return fmt.Errorf("%w: KEM block in a %s container", ErrCorrupt, GetEncryptionModeName(h.Mode))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testKEMKey is the post-quantum key the tests encrypt to; it is loaded for
+// the whole package
+var testKEMKey, removeTestKEMKey = func() (*KEMKey, func()) {
+	k, err := GenerateKEMKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return k, AddKEMKey(k)
+}()
+
+func TestKEMHeader(t *testing.T) {
+	enc, want := encryptTest(t, ModePostQuantumKyber768, testChunk+1, Metadata{Comment: "pq"})
+	hdr, err := ReadHeaderFromFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Flags&FlagKEM == 0 || len(hdr.KEM) != kemBlockLen {
+		t.Fatalf("flags %#x, KEM block of %d bytes", hdr.Flags, len(hdr.KEM))
+	}
+	other, _ := encryptTest(t, ModePostQuantumKyber768, 1, Metadata{})
+	if h2, _ := ReadHeaderFromFile(other); bytes.Equal(h2.KEM, hdr.KEM) {
+		t.Error("two containers share their KEM block")
+	}
+
+	data, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := bytes.Index(data, hdr.KEM)
+	if hdr.KEMFingerprint() != testKEMKey.Fingerprint() {
+		t.Errorf("KEM key %s, want %s", hdr.KEMFingerprint(), testKEMKey.Fingerprint())
+	}
+	// the key ID, the X25519 key, the ML-KEM ciphertext and the wrapped key are all bound
+	for _, off := range []int{0, kemKeyIDLen, kemKeyIDLen + x25519KeyLen + 100, kemBlockLen - 1} {
+		bad := bytes.Clone(data)
+		bad[start+off] ^= 1
+		path := filepath.Join(t.TempDir(), "bad.hadescrypt")
+		if err := os.WriteFile(path, bad, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decryptBytes(path, testPassword); !errors.Is(err, ErrAuthFailed) && !errors.Is(err, ErrCorrupt) && !errors.Is(err, ErrKEMKeyMissing) {
+			t.Errorf("KEM byte %d changed: got %v", off, err)
+		}
+	}
+	if got, err := decryptBytes(enc, testPassword); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("decrypt: %v", err)
+	}
+}
+
+func TestHybridPQ(t *testing.T) {
+	enc, want := encryptTest(t, ModeHybridPQ, testChunk+1, Metadata{Comment: "hybrid"})
+	hdr, err := ReadHeaderFromFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Flags&FlagKEM == 0 || len(hdr.KEM) != hybridBlockLen {
+		t.Fatalf("flags %#x, KEM block of %d bytes", hdr.Flags, len(hdr.KEM))
+	}
+
+	data, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the key needs both the salt (Argon2id) and the ML-KEM ciphertext
+	for name, off := range map[string]int{"salt": bytes.Index(data, hdr.Salt), "ciphertext": bytes.Index(data, hdr.KEM) + kemKeyIDLen + 500} {
+		bad := bytes.Clone(data)
+		bad[off] ^= 1
+		path := filepath.Join(t.TempDir(), "bad.hadescrypt")
+		if err := os.WriteFile(path, bad, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decryptBytes(path, testPassword); !errors.Is(err, ErrAuthFailed) && !errors.Is(err, ErrHeaderAuth) {
+			t.Errorf("%s changed: got %v", name, err)
+		}
+	}
+	if got, err := decryptBytes(enc, testPassword); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("decrypt: %v", err)
+	}
+}
+
+// withKEMKeys runs f with only keys loaded
+func withKEMKeys(t *testing.T, f func(), keys ...*KEMKey) {
+	t.Helper()
+	removeTestKEMKey()
+	defer func() { removeTestKEMKey = AddKEMKey(testKEMKey) }()
+	for _, k := range keys {
+		defer AddKEMKey(k)()
+	}
+	f()
+}
+
+func TestKEMNeedsPasswordAndKey(t *testing.T) {
+	other, err := GenerateKEMKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []EncryptionMode{ModePostQuantumKyber768, ModeHybridPQ} {
+		t.Run(GetEncryptionModeName(mode), func(t *testing.T) {
+			in, want := writePlain(t, 100)
+			if err := EncryptFileWithOptions(in, in+".x", testPassword, EncryptionOptions{Mode: mode, Argon2: testKDF}, nil); !errors.Is(err, ErrKEMKeyMissing) {
+				t.Errorf("encrypt without a KEM key: got %v, want ErrKEMKeyMissing", err)
+			}
+			enc, _ := encryptTest(t, mode, 0, Metadata{})
+			if err := os.WriteFile(in, want, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := EncryptFileWithOptions(in, enc, testPassword, EncryptionOptions{Mode: mode, Argon2: testKDF, KEMKey: testKEMKey}, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			// the password alone, or with another key, opens nothing
+			withKEMKeys(t, func() {
+				if _, err := decryptBytes(enc, testPassword); !errors.Is(err, ErrKEMKeyMissing) {
+					t.Errorf("password alone: got %v, want ErrKEMKeyMissing", err)
+				}
+				if _, err := OpenReader(enc, testPassword); !errors.Is(err, ErrKEMKeyMissing) {
+					t.Errorf("OpenReader, password alone: got %v, want ErrKEMKeyMissing", err)
+				}
+			})
+			withKEMKeys(t, func() {
+				if _, err := decryptBytes(enc, testPassword); !errors.Is(err, ErrKEMKeyMissing) {
+					t.Errorf("another key: got %v, want ErrKEMKeyMissing", err)
+				}
+			}, other)
+
+			// a key that claims the ID of the right one derives another file key
+			impostor := *other
+			impostor.id = testKEMKey.id
+			withKEMKeys(t, func() {
+				if _, err := decryptBytes(enc, testPassword); !errors.Is(err, ErrAuthFailed) && !errors.Is(err, ErrHeaderAuth) {
+					t.Errorf("another key with the same ID: got %v, want an authentication failure", err)
+				}
+			}, &impostor)
+
+			// nor does the key with a wrong password
+			if _, err := decryptBytes(enc, []byte("wrong password")); !errors.Is(err, ErrAuthFailed) && !errors.Is(err, ErrHeaderAuth) {
+				t.Errorf("key with a wrong password: got %v, want an authentication failure", err)
+			}
+			withKEMKeys(t, func() {
+				if got, err := decryptBytes(enc, testPassword); err != nil || !bytes.Equal(got, want) {
+					t.Errorf("password and key: %d bytes, %v", len(got), err)
+				}
+			}, other, testKEMKey)
+		})
+	}
+}
+
+func TestKEMKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pq.key")
+	if err := WriteKEMKey(path, testKEMKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteKEMKey(path, testKEMKey); err == nil {
+		t.Error("WriteKEMKey overwrote a key file")
+	}
+	if st, err := os.Stat(path); err != nil || st.Mode().Perm()&0077 != 0 {
+		t.Errorf("key file mode: %v, %v", st, err)
+	}
+	k, err := LoadKEMKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Fingerprint() != testKEMKey.Fingerprint() || !bytes.Equal(k.seed, testKEMKey.seed) {
+		t.Errorf("loaded key %s, want %s", k.Fingerprint(), testKEMKey.Fingerprint())
+	}
+	data, _ := os.ReadFile(path)
+	if !bytes.Contains(data, []byte(testKEMKey.Fingerprint())) {
+		t.Error("the key file does not name its fingerprint")
+	}
+	for name, bad := range map[string]string{
+		"empty":    "",
+		"keyfile":  "random bytes of an ordinary keyfile",
+		"short":    kemKeyMagic + "\nAAAA\n",
+		"no magic": base64.StdEncoding.EncodeToString(testKEMKey.seed),
+	} {
+		if _, err := ParseKEMKey([]byte(bad)); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}
+
+// legacyPlain is the plaintext of the testdata containers an earlier release
+// wrote with the simulated post-quantum cipher
+const legacyPlain = "Written by the simulated post-quantum cipher of HadesCrypt 2.x, kept to test that it still decrypts.\n"
+
+func TestLegacyPQ(t *testing.T) {
+	for _, mode := range []EncryptionMode{ModePostQuantumDilithium3, ModePostQuantumSPHINCS} {
+		in, _ := writePlain(t, 10)
+		if err := EncryptFileWithOptions(in, in+".hadescrypt", testPassword, EncryptionOptions{Mode: mode, Argon2: testKDF}, nil); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: encrypt got %v, want ErrUnsupported", GetEncryptionModeName(mode), err)
+		}
+	}
+	for name, mode := range map[string]EncryptionMode{"kyber768": ModePostQuantumKyber768, "dilithium3": ModePostQuantumDilithium3, "sphincs": ModePostQuantumSPHINCS} {
+		t.Run(name, func(t *testing.T) {
+			fixture := filepath.Join("testdata", "legacy-"+name+".hadescrypt")
+			hdr, err := ReadHeaderFromFile(fixture)
+			if err != nil || hdr.Mode != mode || !hdr.legacyPQ() {
+				t.Fatalf("header: %+v, %v", hdr, err)
+			}
+			if got, err := decryptBytes(fixture, testPassword); err != nil || string(got) != legacyPlain {
+				t.Errorf("decrypt: %q, %v", got, err)
+			}
+			if _, err := decryptBytes(fixture, []byte("wrong password")); err == nil {
+				t.Error("decrypted with a wrong password")
+			}
+			r, err := OpenReader(fixture, testPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			part := make([]byte, 20)
+			if _, err := r.ReadAt(part, 40); err != nil || string(part) != legacyPlain[40:60] {
+				t.Errorf("Reader.ReadAt: %q, %v", part, err)
+			}
+			r.Close()
+
+			data, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[len(data)-40] ^= 1
+			tampered := filepath.Join(t.TempDir(), "tampered.hadescrypt")
+			if err := os.WriteFile(tampered, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := decryptBytes(tampered, testPassword); !errors.Is(err, ErrAuthFailed) {
+				t.Errorf("tampered: got %v, want ErrAuthFailed", err)
+			}
+
+			// migrating re-encrypts with a current mode and keeps the metadata
+			out := filepath.Join(t.TempDir(), "migrated.hadescrypt")
+			if err := Convert(fixture, out, testPassword, EncryptionOptions{Mode: ModePostQuantumKyber768, Argon2: testKDF, KEMKey: testKEMKey}, nil); err != nil {
+				t.Fatal(err)
+			}
+			converted, err := ReadHeaderFromFile(out)
+			if err != nil || converted.legacyPQ() || converted.Flags&FlagKEM == 0 || converted.Metadata != hdr.Metadata {
+				t.Errorf("converted header: %+v, %v", converted, err)
+			}
+			if got, err := decryptBytes(out, testPassword); err != nil || string(got) != legacyPlain {
+				t.Errorf("decrypt converted: %q, %v", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// A KEM key is the second secret of ModePostQuantumKyber768 and ModeHybridPQ:
+// an ML-KEM-768 and an X25519 private key drawn at random, apart from any
+// password, and kept in a key file of their own. Containers of those modes
+// encapsulate to it and record its ID, so they open with the password and the
+// key file together, and neither alone. The key file is
+//
+//	# comment lines
+//	HADESCRYPT-PQ-KEY-1
+//	BASE64([64]MLKEM_SEED | [32]X25519_PRIVATE)
+const (
+	kemKeyMagic = "HADESCRYPT-PQ-KEY-1"
+	kemSeedLen  = mlkem.SeedSize + x25519KeyLen
+	kemKeyIDLen = 8
+)
+
+// ErrKEMKeyMissing means the container was encrypted to a KEM key that has not
+// been loaded with AddKEMKey, or a post-quantum mode was asked to encrypt
+// without one
+var ErrKEMKeyMissing = errors.New("post-quantum key file required")
+
+// KEMKey is a hybrid ML-KEM-768 and X25519 keypair; see GenerateKEMKey
+type KEMKey struct {
+	seed []byte
+	dk   *mlkem.DecapsulationKey768
+	xk   *ecdh.PrivateKey
+	id   []byte
+}
+
+// GenerateKEMKey draws a new KEM key from random
+func GenerateKEMKey(random io.Reader) (*KEMKey, error) {
+	seed := make([]byte, kemSeedLen)
+	if _, err := io.ReadFull(random, seed); err != nil {
+		return nil, fmt.Errorf("generate post-quantum key: %w", err)
+	}
+	return newKEMKey(seed)
+}
+
+// newKEMKey sets up the keypair of seed, which it keeps
+func newKEMKey(seed []byte) (*KEMKey, error) {
+	dk, err := mlkem.NewDecapsulationKey768(seed[:mlkem.SeedSize])
+	if err != nil {
+		return nil, err
+	}
+	xk, err := ecdh.X25519().NewPrivateKey(seed[mlkem.SeedSize:])
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte("HadesCrypt PQ key ID\x00"))
+	h.Write(dk.EncapsulationKey().Bytes())
+	h.Write(xk.PublicKey().Bytes())
+	return &KEMKey{seed: seed, dk: dk, xk: xk, id: h.Sum(nil)[:kemKeyIDLen]}, nil
+}
+
+// ParseKEMKey reads a key file
+func ParseKEMKey(data []byte) (*KEMKey, error) {
+	var lines []string
+	for _, l := range strings.Split(string(data), "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "#") {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) != 2 || lines[0] != kemKeyMagic {
+		return nil, errors.New("not a HadesCrypt post-quantum key file")
+	}
+	seed, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(seed) != kemSeedLen {
+		return nil, errors.New("damaged post-quantum key file")
+	}
+	return newKEMKey(seed)
+}
+
+// LoadKEMKey reads the key file at path
+func LoadKEMKey(path string) (*KEMKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	k, err := ParseKEMKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return k, nil
+}
+
+// Marshal returns the key file of k
+func (k *KEMKey) Marshal() []byte {
+	return fmt.Appendf(nil, "# HadesCrypt post-quantum key %s\n# Keep it apart from the files encrypted to it; without it they cannot be opened.\n%s\n%s\n",
+		k.Fingerprint(), kemKeyMagic, base64.StdEncoding.EncodeToString(k.seed))
+}
+
+// WriteKEMKey stores k as a new key file at path; an existing file is never overwritten
+func WriteKEMKey(path string, k *KEMKey) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(k.Marshal()); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// Fingerprint returns the ID containers record for k as four hex groups
+func (k *KEMKey) Fingerprint() string { return kemFingerprint(k.id) }
+
+func kemFingerprint(id []byte) string {
+	h := fmt.Sprintf("%X", id)
+	return h[0:4] + " " + h[4:8] + " " + h[8:12] + " " + h[12:16]
+}
+
+// NeedsKEMKey reports whether mode encrypts to a KEM key
+func NeedsKEMKey(mode EncryptionMode) bool {
+	return mode == ModePostQuantumKyber768 || mode == ModeHybridPQ
+}
+
+// kemKeys are the keys containers of the post-quantum modes are opened with
+var kemKeys struct {
+	sync.RWMutex
+	keys []*KEMKey
+}
+
+// AddKEMKey makes decryption find k among the loaded KEM keys until the
+// returned function is called. Encryption takes its key from
+// EncryptionOptions.KEMKey instead.
+func AddKEMKey(k *KEMKey) (remove func()) {
+	kemKeys.Lock()
+	defer kemKeys.Unlock()
+	kemKeys.keys = append(kemKeys.keys, k)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			kemKeys.Lock()
+			defer kemKeys.Unlock()
+			if i := slices.Index(kemKeys.keys, k); i >= 0 {
+				kemKeys.keys = slices.Delete(kemKeys.keys, i, i+1)
+			}
+		})
+	}
+}
+
+// kemKeyByID returns the loaded key with id
+func kemKeyByID(id []byte) (*KEMKey, error) {
+	kemKeys.RLock()
+	defer kemKeys.RUnlock()
+	for _, k := range kemKeys.keys {
+		if bytes.Equal(k.id, id) {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: this file was encrypted to post-quantum key %s", ErrKEMKeyMissing, kemFingerprint(id))
+}
+
+// LoadedKEMKey returns the loaded key a container of the post-quantum modes was
+// encrypted to, or nil
+func (h *Header) LoadedKEMKey() *KEMKey {
+	if h.Flags&FlagKEM == 0 || len(h.KEM) < kemKeyIDLen {
+		return nil
+	}
+	k, _ := kemKeyByID(h.KEM[:kemKeyIDLen])
+	return k
+}
+
+// KEMFingerprint returns the fingerprint of the KEM key a container of the
+// post-quantum modes was encrypted to, or "" for other containers
+func (h *Header) KEMFingerprint() string {
+	if h.Flags&FlagKEM == 0 || len(h.KEM) < kemKeyIDLen {
+		return ""
+	}
+	return kemFingerprint(h.KEM[:kemKeyIDLen])
+}
@@ -0,0 +1,108 @@
+package cryptoengine
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Kyber-768, Dilithium-3 and SPHINCS+ containers written before the hybrid
+// KEM (headers without FlagKEM) used a simulated post-quantum cipher under the
+// Argon2id password key. Every chunk is
+//
+//	[12/16/24]NONCE | PLAINTEXT XOR KEYSTREAM | [32]TAG
+//
+// with a random nonce whose length depends on the mode, a keystream of
+// SHA-256(KEY | NONCE | ALG | [4]COUNTER) blocks and a tag of
+// SHA-256(KEY | NONCE | CIPHERTEXT | ALG), where ALG is 0, 1 or 2 for the
+// three modes. It is no vetted cipher, so it only decrypts, for migrating
+// such files to a current mode; nothing encrypts with it.
+
+// errLegacyPQ is returned when a legacy container would be written
+var errLegacyPQ = fmt.Errorf("%w: the simulated post-quantum cipher of earlier releases only decrypts", ErrUnsupported)
+
+// errLegacyPQOpen is returned for a chunk that fails its tag
+var errLegacyPQOpen = errors.New("legacy post-quantum chunk authentication failed")
+
+// legacyPQ opens chunks of the simulated post-quantum cipher. It satisfies
+// cipher.AEAD so it slots in where the other modes open chunks; the nonce
+// callers pass is ignored, since every chunk carries its own.
+type legacyPQ struct {
+	key      []byte
+	alg      byte
+	nonceLen int
+}
+
+// legacyPQ reports whether h is a container of the simulated post-quantum cipher
+func (h *Header) legacyPQ() bool {
+	switch h.Mode {
+	case ModePostQuantumKyber768:
+		return h.Flags&FlagKEM == 0
+	case ModePostQuantumDilithium3, ModePostQuantumSPHINCS:
+		return true
+	}
+	return false
+}
+
+// newLegacyPQ returns the cipher of a legacy container of mode under the password key
+func newLegacyPQ(key []byte, mode EncryptionMode) *legacyPQ {
+	switch mode {
+	case ModePostQuantumDilithium3:
+		return &legacyPQ{key: key, alg: 1, nonceLen: 16}
+	case ModePostQuantumSPHINCS:
+		return &legacyPQ{key: key, alg: 2, nonceLen: 24}
+	}
+	return &legacyPQ{key: key, alg: 0, nonceLen: 12}
+}
+
+func (l *legacyPQ) NonceSize() int { return gcmNonceLen }
+
+func (l *legacyPQ) Overhead() int { return l.nonceLen + sha256.Size }
+
+func (l *legacyPQ) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	panic("cryptoengine: " + errLegacyPQ.Error())
+}
+
+// Open authenticates and decrypts a chunk; dst may be sealed[:0]
+func (l *legacyPQ) Open(dst, _, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < l.Overhead() || len(additionalData) > 0 {
+		return nil, errLegacyPQOpen
+	}
+	nonce := sealed[:l.nonceLen]
+	body := sealed[l.nonceLen : len(sealed)-sha256.Size]
+	if subtle.ConstantTimeCompare(l.tag(nonce, body), sealed[len(sealed)-sha256.Size:]) != 1 {
+		return nil, errLegacyPQOpen
+	}
+	// the nonce is kept, as the plaintext may overwrite it
+	nonce = append([]byte(nil), nonce...)
+	plain := append(dst, body...)
+	out := plain[len(dst):]
+	var block [sha256.Size]byte
+	for i := 0; i < len(out); i += sha256.Size {
+		l.keystream(block[:0], nonce, uint32(i/sha256.Size))
+		subtle.XORBytes(out[i:], out[i:], block[:])
+	}
+	return plain, nil
+}
+
+// keystream appends the keystream block with the given counter to b
+func (l *legacyPQ) keystream(b, nonce []byte, counter uint32) []byte {
+	h := sha256.New()
+	h.Write(l.key)
+	h.Write(nonce)
+	h.Write([]byte{l.alg})
+	h.Write(binary.BigEndian.AppendUint32(nil, counter))
+	return h.Sum(b)
+}
+
+// tag returns the tag of the chunk ciphertext under nonce
+func (l *legacyPQ) tag(nonce, ciphertext []byte) []byte {
+	h := sha256.New()
+	h.Write(l.key)
+	h.Write(nonce)
+	h.Write(ciphertext)
+	h.Write([]byte{l.alg})
+	return h.Sum(nil)
+}
@@ -0,0 +1,67 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bangundwir/HadesCrypt/internal/units"
+)
+
+// ErrTooLarge is returned when a decrypted payload would exceed the caller's memory cap.
+var ErrTooLarge = errors.New("decrypted data exceeds size limit")
+
+// DecryptFileToMemory decrypts a HadesCrypt container into a byte slice, refusing
+// payloads larger than maxBytes (0 means no limit). Plaintext never touches disk.
+func DecryptFileToMemory(inputPath string, password []byte, maxBytes int64) ([]byte, error) {
+	hdr, err := ReadHeaderWithPassword(inputPath, password)
+	if err != nil {
+		return nil, err
+	}
+	size := hdr.OriginalSize
+	if maxBytes > 0 && size > maxBytes {
+		return nil, fmt.Errorf("%w: %s > %s", ErrTooLarge, units.Bytes(size), units.Bytes(maxBytes))
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	w := &cappedWriter{w: buf, limited: maxBytes > 0, remaining: maxBytes}
+	if err := DecryptFileToWriter(inputPath, w, password, nil); err != nil {
+		Wipe(buf.Bytes())
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExtractOriginalSizeFromFile returns the plaintext size recorded in a HadesCrypt header
+func ExtractOriginalSizeFromFile(inputPath string) (int64, error) {
+	hdr, err := ReadHeaderFromFile(inputPath)
+	if err != nil {
+		return 0, err
+	}
+	return hdr.OriginalSize, nil
+}
+
+// Wipe overwrites b with zeros
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// cappedWriter fails once more than remaining bytes have been written
+type cappedWriter struct {
+	w         io.Writer
+	limited   bool
+	remaining int64
+}
+
+func (c *cappedWriter) Write(p []byte) (int, error) {
+	if c.limited {
+		if int64(len(p)) > c.remaining {
+			return 0, ErrTooLarge
+		}
+		c.remaining -= int64(len(p))
+	}
+	return c.w.Write(p)
+}
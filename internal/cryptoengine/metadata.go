@@ -0,0 +1,236 @@
+package cryptoengine
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Metadata is plaintext information kept in the header: readable without the
+// password, but authenticated with the file key so it cannot be changed unnoticed.
+// Encoded as [4]LEN COMMENT | [2]LEN HINT | [2]LEN NAME.
+type Metadata struct {
+	Comment string
+	Hint    string // password hint
+	Name    string // original file name
+}
+
+// Size limits of the metadata fields in bytes
+const (
+	MaxCommentBytes = 1 << 20
+	MaxHintBytes    = 1 << 10
+	MaxNameBytes    = 1 << 10
+)
+
+const headerMACLen = sha256.Size
+
+// ErrHeaderAuth means the header metadata does not match its MAC: it was altered, or the key is wrong
+var ErrHeaderAuth = fmt.Errorf("%w: header metadata failed authentication", ErrCorrupt)
+
+// IsZero reports whether no field is set
+func (m Metadata) IsZero() bool { return m == Metadata{} }
+
+// Validate checks the field limits; the hint and name must be single lines and
+// the name a plain file name
+func (m Metadata) Validate() error {
+	switch {
+	case len(m.Comment) > MaxCommentBytes:
+		return fmt.Errorf("comment is %d bytes; the limit is %d", len(m.Comment), MaxCommentBytes)
+	case len(m.Hint) > MaxHintBytes:
+		return fmt.Errorf("password hint is %d bytes; the limit is %d", len(m.Hint), MaxHintBytes)
+	case len(m.Name) > MaxNameBytes:
+		return fmt.Errorf("file name is %d bytes; the limit is %d", len(m.Name), MaxNameBytes)
+	case !utf8.ValidString(m.Comment) || !utf8.ValidString(m.Hint) || !utf8.ValidString(m.Name):
+		return errors.New("metadata must be valid UTF-8")
+	case strings.ContainsAny(m.Hint, "\r\n"):
+		return errors.New("the password hint must be a single line")
+	case m.Name != "" && (m.Name != filepath.Base(m.Name) || strings.ContainsAny(m.Name, "/\\\r\n") || m.Name == "." || m.Name == ".."):
+		return fmt.Errorf("%q is not a plain file name", m.Name)
+	}
+	return nil
+}
+
+func (m Metadata) appendTo(out []byte) []byte {
+	out = binary.BigEndian.AppendUint32(out, uint32(len(m.Comment)))
+	out = append(out, m.Comment...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(m.Hint)))
+	out = append(out, m.Hint...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(m.Name)))
+	return append(out, m.Name...)
+}
+
+// readMetadata parses the metadata block and MAC that follow the fixed header
+func (h *Header) readMetadata(r io.Reader) error {
+	field := func(lenBytes, limit int) (string, error) {
+		var n [4]byte
+		if _, err := io.ReadFull(r, n[4-lenBytes:]); err != nil {
+			return "", truncatedError(err)
+		}
+		size := int(binary.BigEndian.Uint32(n[:]))
+		if size > limit {
+			return "", fmt.Errorf("%w: metadata field of %d bytes", ErrCorrupt, size)
+		}
+		b := make([]byte, size)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", truncatedError(err)
+		}
+		return string(b), nil
+	}
+	var err error
+	if h.Metadata.Comment, err = field(4, MaxCommentBytes); err != nil {
+		return err
+	}
+	if h.Metadata.Hint, err = field(2, MaxHintBytes); err != nil {
+		return err
+	}
+	if h.Metadata.Name, err = field(2, MaxNameBytes); err != nil {
+		return err
+	}
+	h.MAC = make([]byte, headerMACLen)
+	if _, err := io.ReadFull(r, h.MAC); err != nil {
+		return truncatedError(err)
+	}
+	return nil
+}
+
+// SetMetadata replaces the metadata; the header must be sealed again before it is written
+func (h *Header) SetMetadata(m Metadata) {
+	h.Metadata, h.MAC = m, nil
+	if m.IsZero() {
+		h.Flags &^= FlagMetadata
+	} else {
+		h.Flags |= FlagMetadata
+	}
+}
+
+// headerMAC authenticates the unsealed header with a MAC key derived from the file key
+func (h *Header) headerMAC(key []byte) ([]byte, error) {
+	macKey, err := hkdf.Key(sha256.New, key, h.Salt, "HadesCrypt header MAC", sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	m := hmac.New(sha256.New, macKey)
+	// the parity layer is left out, so a container stripped of it keeps its MAC
+	m.Write(h.withoutParity().unsealed())
+	return m.Sum(nil), nil
+}
+
+// seal computes the MAC of a header with metadata
+func (h *Header) seal(key []byte) error {
+	if h.Flags&FlagMetadata == 0 {
+		h.MAC = nil
+		return nil
+	}
+	mac, err := h.headerMAC(key)
+	h.MAC = mac
+	return err
+}
+
+// verifyMAC checks the MAC of a header with metadata; headers without metadata carry none
+func (h *Header) verifyMAC(key []byte) error {
+	if h.Flags&FlagMetadata == 0 {
+		return nil
+	}
+	mac, err := h.headerMAC(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(mac, h.MAC) {
+		return ErrHeaderAuth
+	}
+	return nil
+}
+
+// errKeyChecked stops decryption once the first chunk authenticated
+var errKeyChecked = errors.New("key checked")
+
+type stopWriter struct{}
+
+func (stopWriter) Write([]byte) (int, error) { return 0, errKeyChecked }
+
+// Restamp replaces the metadata of the container at path without re-encrypting
+// it: the header is rewritten and sealed with the file key, and the encrypted
+// payload is copied unchanged. password must open the first chunk, and existing
+// metadata must pass its MAC. The file is replaced atomically. Deniability
+// Mode output is refused, as its header is wrapped with the rest of the file.
+func Restamp(path string, password []byte, m Metadata) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	c, err := openContainer(path, password)
+	if err != nil {
+		return err
+	}
+	deniable := c.wrap != nil
+	c.Close()
+	if deniable {
+		return fmt.Errorf("%w: Deniability Mode containers cannot be restamped; decrypt and encrypt them again to change their metadata", ErrUnsupported)
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	hdr, err := ReadHeader(in)
+	if err != nil {
+		return err
+	}
+	if hdr.Mode == ModeGnuPG {
+		return fmt.Errorf("%w: GnuPG containers have no HadesCrypt metadata", ErrUnsupported)
+	}
+	key, err := fileKey(password, hdr)
+	if err != nil {
+		return err
+	}
+	// The first chunk tells a wrong password apart from altered metadata
+	if err := DecryptFileToWriter(path, stopWriter{}, password, nil); err != nil && !errors.Is(err, errKeyChecked) {
+		return err
+	}
+	if err := hdr.verifyMAC(key); err != nil {
+		return err
+	}
+
+	hdr.SetMetadata(m)
+	if err := hdr.seal(key); err != nil {
+		return err
+	}
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.restamp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(hdr.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	defer forgetFileInfo(path)
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,286 @@
+package cryptoengine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/bangundwir/HadesCrypt/internal/reedsolomon"
+)
+
+// Containers written with UseReedSolomon carry FlagReedSolomon and the shard
+// counts in the header. Everything after the header is cut into blocks of
+// DATA_SHARDS*parityShardSize bytes, each holding [4]LENGTH and then LENGTH
+// bytes of the container, padded with zeros. A block that is not full ends
+// the data, empty if the container filled the one before. Every block is
+// written as its data shards, its parity shards, and the [4]CRC-32 of each
+// shard, so damaged shards are found by their checksums and up to
+// PARITY_SHARDS of them per block are rebuilt from the others.
+//
+// The flag and shard counts are left out of the header MAC: decoding the
+// parity leaves an ordinary container, which is then decrypted as any other.
+// Decryption decodes and repairs each block as it reads it, and random access
+// goes through parityReaderAt, which decodes just the blocks a read touches.
+const (
+	parityDataShards   = 16
+	parityParityShards = 4
+	parityShardSize    = 4 << 10
+)
+
+// withoutParity returns a copy of h as it reads once the parity is decoded
+func (h *Header) withoutParity() *Header {
+	c := *h
+	c.Flags &^= FlagReedSolomon
+	c.DataShards, c.ParityShards = 0, 0
+	return &c
+}
+
+// parityBlock returns the container bytes a block of h carries at most and
+// the length of the block as written
+func (h *Header) parityBlock() (capacity, record int) {
+	capacity = h.DataShards*parityShardSize - 4
+	record = (h.DataShards + h.ParityShards) * (parityShardSize + 4)
+	return capacity, record
+}
+
+// paritySize returns the length n bytes of container data take with the parity of h
+func (h *Header) paritySize(n int64) int64 {
+	capacity, record := h.parityBlock()
+	return (n/int64(capacity) + 1) * int64(record)
+}
+
+// parityShards slices a block of h into its shards and checksums
+func (h *Header) parityShards(block []byte) (shards [][]byte, sums []byte) {
+	shards = make([][]byte, h.DataShards+h.ParityShards)
+	for i := range shards {
+		shards[i] = block[i*parityShardSize : (i+1)*parityShardSize]
+	}
+	return shards, block[len(shards)*parityShardSize:]
+}
+
+// parityWriter codes the data written to it into blocks on w. Close writes
+// the last block.
+type parityWriter struct {
+	w      io.Writer
+	enc    *reedsolomon.Encoder
+	block  []byte
+	shards [][]byte
+	sums   []byte
+	n      int // container bytes in the block after its length
+}
+
+func newParityWriter(w io.Writer, h *Header) (*parityWriter, error) {
+	enc, err := reedsolomon.New(h.DataShards, h.ParityShards)
+	if err != nil {
+		return nil, err
+	}
+	_, record := h.parityBlock()
+	p := &parityWriter{w: w, enc: enc, block: make([]byte, record)}
+	p.shards, p.sums = h.parityShards(p.block)
+	return p, nil
+}
+
+func (p *parityWriter) Write(b []byte) (int, error) {
+	written := 0
+	data := p.block[:p.enc.DataShards()*parityShardSize]
+	for len(b) > 0 {
+		c := copy(data[4+p.n:], b)
+		p.n += c
+		b = b[c:]
+		written += c
+		if 4+p.n == len(data) {
+			if err := p.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close writes the last block, which is not full
+func (p *parityWriter) Close() error { return p.flush() }
+
+// flush codes and writes the current block
+func (p *parityWriter) flush() error {
+	data := p.block[:p.enc.DataShards()*parityShardSize]
+	binary.BigEndian.PutUint32(data, uint32(p.n))
+	clear(data[4+p.n:])
+	if err := p.enc.Encode(p.shards); err != nil {
+		return err
+	}
+	for i, s := range p.shards {
+		binary.BigEndian.PutUint32(p.sums[4*i:], crc32.ChecksumIEEE(s))
+	}
+	p.n = 0
+	_, err := p.w.Write(p.block)
+	return err
+}
+
+// parityReader reads the container data coded in the blocks read from r,
+// rebuilding damaged shards. Blocks beyond repair fail with ErrCorrupt.
+type parityReader struct {
+	r     io.Reader
+	h     *Header
+	enc   *reedsolomon.Encoder
+	block []byte
+	data  []byte // rest of the current block
+	index int64
+	done  bool
+	err   error
+}
+
+func newParityReader(r io.Reader, h *Header) (*parityReader, error) {
+	enc, err := reedsolomon.New(h.DataShards, h.ParityShards)
+	if err != nil {
+		return nil, err
+	}
+	_, record := h.parityBlock()
+	return &parityReader{r: r, h: h, enc: enc, block: make([]byte, record)}, nil
+}
+
+func (p *parityReader) Read(b []byte) (int, error) {
+	for len(p.data) == 0 {
+		switch {
+		case p.err != nil:
+			return 0, p.err
+		case p.done:
+			return 0, io.EOF
+		}
+		p.err = p.next()
+	}
+	n := copy(b, p.data)
+	p.data = p.data[n:]
+	return n, nil
+}
+
+// next reads, checks and if need be repairs the following block
+func (p *parityReader) next() error {
+	if _, err := io.ReadFull(p.r, p.block); err != nil {
+		return truncatedError(err)
+	}
+	data, err := p.h.decodeBlock(p.enc, p.block, p.index)
+	if err != nil {
+		return err
+	}
+	capacity, _ := p.h.parityBlock()
+	p.data, p.done = data, len(data) < capacity
+	p.index++
+	return nil
+}
+
+// decodeBlock checks block number index of h, rebuilds its damaged shards in
+// place, and returns the container data it carries
+func (h *Header) decodeBlock(enc *reedsolomon.Encoder, block []byte, index int64) ([]byte, error) {
+	shards, sums := h.parityShards(block)
+	damaged := 0
+	for i, s := range shards {
+		if crc32.ChecksumIEEE(s) != binary.BigEndian.Uint32(sums[4*i:]) {
+			shards[i] = nil
+			damaged++
+		}
+	}
+	if damaged > 0 {
+		if err := enc.Reconstruct(shards); err != nil {
+			return nil, fmt.Errorf("%w: block %d has %d damaged shards, at most %d can be rebuilt", ErrCorrupt, index, damaged, h.ParityShards)
+		}
+		for i, s := range shards[:h.DataShards] {
+			copy(block[i*parityShardSize:], s)
+		}
+	}
+	capacity, _ := h.parityBlock()
+	n := int(binary.BigEndian.Uint32(block))
+	if n > capacity {
+		return nil, fmt.Errorf("%w: block %d claims %d bytes", ErrCorrupt, index, n)
+	}
+	return block[4 : 4+n], nil
+}
+
+// parityReaderAt reads at random the container whose header with parity is
+// h and whose blocks follow it in r, size bytes in all: the header without
+// the parity, then the data of the blocks, decoded as they are read. The
+// block read last is kept.
+type parityReaderAt struct {
+	r     io.ReaderAt
+	h     *Header
+	head  []byte // the header without the parity
+	enc   *reedsolomon.Encoder
+	start int64 // of the first block in r
+	last  int64 // index of the last block
+
+	mu    sync.Mutex
+	block []byte
+	index int64  // block held in block, -1 for none
+	data  []byte // its container data
+}
+
+// newParityReaderAt checks that the blocks are complete and decodes the last
+// one, which gives the length of the container
+func newParityReaderAt(r io.ReaderAt, size int64, h *Header) (*io.SectionReader, error) {
+	enc, err := reedsolomon.New(h.DataShards, h.ParityShards)
+	if err != nil {
+		return nil, err
+	}
+	capacity, record := h.parityBlock()
+	start := int64(len(h.Bytes()))
+	blocks := (size - start) / int64(record)
+	if blocks == 0 || (size-start)%int64(record) != 0 {
+		return nil, fmt.Errorf("%w: the Reed-Solomon blocks are cut short", ErrCorrupt)
+	}
+	p := &parityReaderAt{r: r, h: h, head: h.withoutParity().Bytes(), enc: enc, start: start, last: blocks - 1, block: make([]byte, record), index: -1}
+	if err := p.load(p.last); err != nil {
+		return nil, err
+	}
+	if len(p.data) == capacity {
+		return nil, fmt.Errorf("%w: data after the last Reed-Solomon block", ErrCorrupt)
+	}
+	return io.NewSectionReader(p, 0, int64(len(p.head))+p.last*int64(capacity)+int64(len(p.data))), nil
+}
+
+// load decodes block i unless it is held already
+func (p *parityReaderAt) load(i int64) error {
+	if i == p.index {
+		return nil
+	}
+	p.index = -1
+	if _, err := p.r.ReadAt(p.block, p.start+i*int64(len(p.block))); err != nil {
+		return truncatedError(err)
+	}
+	data, err := p.h.decodeBlock(p.enc, p.block, i)
+	if err != nil {
+		return err
+	}
+	// only the last block is short
+	if capacity, _ := p.h.parityBlock(); i < p.last && len(data) < capacity {
+		return fmt.Errorf("%w: data after the last Reed-Solomon block", ErrCorrupt)
+	}
+	p.data, p.index = data, i
+	return nil
+}
+
+func (p *parityReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	if off < int64(len(p.head)) {
+		n = copy(b, p.head[off:])
+	}
+	capacity, _ := p.h.parityBlock()
+	for n < len(b) {
+		at := off + int64(n) - int64(len(p.head))
+		i := at / int64(capacity)
+		if i > p.last {
+			return n, io.EOF
+		}
+		if err := p.load(i); err != nil {
+			return n, err
+		}
+		within := at - i*int64(capacity)
+		if within >= int64(len(p.data)) {
+			return n, io.EOF
+		}
+		n += copy(b[n:], p.data[within:])
+	}
+	return n, nil
+}
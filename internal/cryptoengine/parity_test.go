@@ -0,0 +1,148 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encryptParity encrypts plain with Reed-Solomon parity and returns the container
+func encryptParity(t *testing.T, plain []byte, opts EncryptionOptions) string {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "parity.hadescrypt")
+	opts.Argon2, opts.UseReedSolomon = testKDF, true
+	if err := EncryptReaderWithOptions(bytes.NewReader(plain), int64(len(plain)), out, testPassword, opts, nil); err != nil {
+		t.Fatalf("encrypt %d bytes: %v", len(plain), err)
+	}
+	return out
+}
+
+func TestParityRoundTrip(t *testing.T) {
+	variants := map[string]EncryptionOptions{
+		"plain":      {Mode: ModeAES256GCM, Metadata: Metadata{Comment: "parity"}},
+		"paranoid":   {Mode: ModeParanoidSerpent},
+		"compressed": {Mode: ModeChaCha20, UseCompression: true, CompressionLevel: 1},
+	}
+	for name, opts := range variants {
+		opts.Argon2, opts.UseReedSolomon = testKDF, true
+		for _, size := range []int{0, 1, parityDataShards*parityShardSize - 4 - gcmOverhead, 2*testChunk + 100} {
+			t.Run(fmt.Sprintf("%s/%d", name, size), func(t *testing.T) {
+				t.Parallel()
+				_, want := writePlain(t, size)
+				enc := encryptParity(t, want, opts)
+
+				hdr, err := ReadHeaderFromFile(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.Flags&FlagReedSolomon == 0 || hdr.DataShards != parityDataShards || hdr.ParityShards != parityParityShards {
+					t.Errorf("header: flags %#x, shards %d+%d", hdr.Flags, hdr.DataShards, hdr.ParityShards)
+				}
+				fi, err := os.Stat(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if est, err := EncryptedSize(int64(size), opts); !opts.UseCompression && (err != nil || est != fi.Size()) {
+					t.Errorf("EncryptedSize(%d) = %d, %v; the container is %d bytes", size, est, err, fi.Size())
+				}
+
+				if got, err := decryptBytes(enc, testPassword); err != nil || !bytes.Equal(got, want) {
+					t.Fatalf("decrypt: %d bytes, %v", len(got), err)
+				}
+				f, err := os.Open(enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				r, err := NewDecryptingReader(f, testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, want) {
+					t.Fatalf("DecryptingReader: %d bytes, %v", len(got), err)
+				}
+			})
+		}
+	}
+}
+
+func TestParityStream(t *testing.T) {
+	_, want := writePlain(t, 2*testChunk+100)
+	var buf bytes.Buffer
+	w, err := NewEncryptingWriter(&buf, testPassword, EncryptionOptions{Mode: ModeAES256GCM, Argon2: testKDF, UseReedSolomon: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(want); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "stream.hadescrypt")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := decryptBytes(path, testPassword); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("decrypt: %d bytes, %v", len(got), err)
+	}
+	if _, err := OpenReader(path, testPassword); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("OpenReader: got %v, want ErrUnsupported", err)
+	}
+}
+
+func TestParityRepairsShards(t *testing.T) {
+	_, want := writePlain(t, 3*parityDataShards*parityShardSize)
+	enc := encryptParity(t, want, EncryptionOptions{Mode: ModeAES256GCM, Metadata: Metadata{Comment: "c"}})
+	data, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := ReadHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := len(hdr.Bytes())
+	_, record := hdr.parityBlock()
+
+	// damage shards spread over a block: a data shard, a parity shard and checksums
+	damage := func(b []byte, block, shards int) {
+		at := start + block*record
+		for i := range shards {
+			b[at+i*5*parityShardSize/4+100] ^= 0xff
+		}
+	}
+	for _, c := range []struct {
+		shards int
+		ok     bool
+	}{{1, true}, {parityParityShards, true}, {parityParityShards + 1, false}} {
+		t.Run(fmt.Sprint(c.shards), func(t *testing.T) {
+			bad := bytes.Clone(data)
+			damage(bad, 0, c.shards)
+			damage(bad, 2, 1)
+			path := filepath.Join(t.TempDir(), "damaged.hadescrypt")
+			if err := os.WriteFile(path, bad, 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := decryptBytes(path, testPassword)
+			switch {
+			case c.ok && (err != nil || !bytes.Equal(got, want)):
+				t.Errorf("decrypt: %d bytes, %v", len(got), err)
+			case !c.ok && !errors.Is(err, ErrCorrupt):
+				t.Errorf("got %v, want ErrCorrupt", err)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "short.hadescrypt")
+	if err := os.WriteFile(path, data[:len(data)-record], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptBytes(path, testPassword); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("a block cut off: got %v, want ErrCorrupt", err)
+	}
+}
@@ -0,0 +1,46 @@
+package cryptoengine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// pipeline counts what the chunk workers of every running job are doing, for
+// live statistics; see Pipeline
+var pipeline struct {
+	workers, active, queued atomic.Int64
+	chunks, busy            atomic.Int64 // busy is in nanoseconds
+}
+
+// PipelineStats is a snapshot of the chunk workers of the process
+type PipelineStats struct {
+	Workers int           // goroutines sealing or opening chunks
+	Active  int           // of those, the ones busy with a chunk right now
+	Queued  int           // chunks read and waiting for a worker
+	Chunks  int64         // chunks sealed or opened since the process started
+	Busy    time.Duration // time spent sealing or opening them
+}
+
+// Pipeline returns the current worker statistics. Chunks and Busy only grow,
+// so rates come from the difference between two snapshots.
+func Pipeline() PipelineStats {
+	return PipelineStats{
+		Workers: int(pipeline.workers.Load()),
+		Active:  int(pipeline.active.Load()),
+		Queued:  int(pipeline.queued.Load()),
+		Chunks:  pipeline.chunks.Load(),
+		Busy:    time.Duration(pipeline.busy.Load()),
+	}
+}
+
+// beginChunk marks a worker busy; pass its result to endChunk
+func beginChunk() time.Time {
+	pipeline.active.Add(1)
+	return time.Now()
+}
+
+func endChunk(start time.Time) {
+	pipeline.busy.Add(int64(time.Since(start)))
+	pipeline.chunks.Add(1)
+	pipeline.active.Add(-1)
+}
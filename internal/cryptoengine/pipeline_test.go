@@ -0,0 +1,16 @@
+package cryptoengine
+
+import "testing"
+
+func TestPipelineCounts(t *testing.T) {
+	before := Pipeline()
+	enc, _ := encryptTest(t, ModeChaCha20, 2*testChunk+1, Metadata{})
+	if _, err := decryptBytes(enc, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	after := Pipeline()
+	// three chunks sealed and three opened; other tests may add more
+	if after.Chunks-before.Chunks < 6 || after.Busy <= before.Busy {
+		t.Errorf("pipeline went from %+v to %+v", before, after)
+	}
+}
@@ -0,0 +1,316 @@
+package cryptoengine
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Reader gives random access to the plaintext of a container. Chunks are
+// located from the header, or for framed containers from an index of their
+// lengths built on opening, and authenticated as they are read, so any part of
+// a large file can be read without decrypting what precedes it. The most
+// recently used chunk is cached. Reader is safe for concurrent use.
+type Reader struct {
+	f         *containerFile
+	hdr       *Header
+	opener    *chunkOpener
+	dataStart int64
+	overhead  int
+	frames    []frame // framed containers only
+
+	mu     sync.Mutex
+	index  int64 // chunk held in plain, -1 for none
+	plain  []byte
+	sealed []byte
+	pos    int64 // position for Read and Seek
+}
+
+// OpenReader opens the container at path for random access. The password is
+// checked on the first chunk, header metadata against its MAC, and the file
+// length against the header, so a wrong password or a truncated file fails here.
+// Deniability Mode output is unwrapped as it is read.
+func OpenReader(path string, password []byte) (*Reader, error) {
+	f, err := openContainer(path, password)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(f, password)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func newReader(f *containerFile, password []byte) (*Reader, error) {
+	hdr, err := f.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Mode == ModeGnuPG {
+		return nil, fmt.Errorf("%w: GnuPG containers cannot be read at random", ErrUnsupported)
+	}
+	if hdr.Flags&FlagCompressed != 0 {
+		return nil, fmt.Errorf("%w: compressed containers cannot be read at random", ErrUnsupported)
+	}
+	if hdr.Flags&FlagReedSolomon != 0 {
+		return nil, fmt.Errorf("%w: Reed-Solomon containers cannot be read at random", ErrUnsupported)
+	}
+	overhead, err := hdr.chunkOverhead()
+	if err != nil {
+		return nil, err
+	}
+	opener, err := newChunkOpener(password, hdr)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{f: f, hdr: hdr, opener: opener, dataStart: int64(len(hdr.Bytes())), overhead: overhead, index: -1}
+
+	chunks := (hdr.OriginalSize + int64(hdr.ChunkSize) - 1) / int64(hdr.ChunkSize)
+	stream := hdr.Flags&FlagStream != 0
+	if stream {
+		// the size came from the file length; a stream always has a short last chunk
+		chunks = hdr.OriginalSize/int64(hdr.ChunkSize) + 1
+	}
+	want := r.dataStart + hdr.OriginalSize + chunks*int64(overhead)
+	framed := hdr.Flags&FlagFramed != 0
+	if framed {
+		if r.frames, want, err = scanFrames(f, hdr, r.dataStart, overhead); err != nil {
+			return nil, err
+		}
+		chunks = int64(len(r.frames))
+	}
+	if f.Size() != want {
+		return nil, fmt.Errorf("%w: %d bytes where the header implies %d", ErrCorrupt, f.Size(), want)
+	}
+	if chunks > 0 {
+		if err := r.load(0); err != nil {
+			return nil, err
+		}
+	}
+	// the last chunk of a stream or framed container marks its end, so it must be genuine
+	if (stream || framed) && chunks > 1 {
+		if err := r.load(chunks - 1); err != nil {
+			return nil, err
+		}
+	}
+	if err := hdr.verifyMAC(opener.key); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Size returns the plaintext length
+func (r *Reader) Size() int64 { return r.hdr.OriginalSize }
+
+// Header returns the container's header
+func (r *Reader) Header() *Header { return r.hdr }
+
+// ReadAt implements io.ReaderAt
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("cryptoengine: negative offset")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.readAt(p, off)
+}
+
+func (r *Reader) readAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		if off >= r.hdr.OriginalSize {
+			return n, io.EOF
+		}
+		i, start := r.locate(off)
+		if err := r.load(i); err != nil {
+			return n, err
+		}
+		c := copy(p[n:], r.plain[off-start:])
+		n += c
+		off += int64(c)
+	}
+	return n, nil
+}
+
+// Read implements io.Reader
+func (r *Reader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n, err := r.readAt(p, r.pos)
+	r.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.hdr.OriginalSize
+	default:
+		return 0, errors.New("cryptoengine: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("cryptoengine: negative position")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// Close releases the file and wipes the cached plaintext and keys
+func (r *Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	Wipe(r.plain)
+	Wipe(r.opener.key)
+	r.index = -1
+	return r.f.Close()
+}
+
+// load decrypts chunk i into r.plain unless it is already there
+func (r *Reader) load(i int64) error {
+	if i == r.index {
+		return nil
+	}
+	if i > int64(^uint32(0)) {
+		return fmt.Errorf("%w: chunk %d out of range", ErrCorrupt, i)
+	}
+	// plain usually aliases sealed, so it is wiped before the next chunk is read
+	r.index = -1
+	Wipe(r.plain)
+	at, nPlain := r.span(i)
+	need := nPlain + r.overhead
+	if cap(r.sealed) < need {
+		r.sealed = make([]byte, need)
+	}
+	sealed := r.sealed[:need]
+	if _, err := r.f.ReadAt(sealed, at); err != nil {
+		return truncatedError(err)
+	}
+	var word []byte
+	if r.frames != nil {
+		word = frameWord(r.frames[i].n, r.frames[i].last)
+	}
+	plain, err := r.opener.open(uint32(i), sealed, word)
+	if err != nil {
+		return err
+	}
+	r.plain, r.index = plain, i
+	return nil
+}
+
+// locate returns the chunk holding plaintext offset off and where it starts
+func (r *Reader) locate(off int64) (i, start int64) {
+	if r.frames != nil {
+		k := sort.Search(len(r.frames), func(k int) bool { return r.frames[k].plain > off }) - 1
+		return int64(k), r.frames[k].plain
+	}
+	cs := int64(r.hdr.ChunkSize)
+	return off / cs, off / cs * cs
+}
+
+// span returns the file offset of sealed chunk i and its plaintext length
+func (r *Reader) span(i int64) (at int64, n int) {
+	if r.frames != nil {
+		return r.frames[i].at, r.frames[i].n
+	}
+	cs := int64(r.hdr.ChunkSize)
+	nPlain := r.hdr.OriginalSize - i*cs
+	if nPlain > cs {
+		nPlain = cs
+	}
+	return r.dataStart + i*(cs+int64(r.overhead)), int(nPlain)
+}
+
+// chunkOpener authenticates and decrypts single chunks, in any order
+type chunkOpener struct {
+	key         []byte
+	noncePrefix []byte
+	aead, aead2 cipher.AEAD // aead2 is the outer layer in the paranoid modes
+}
+
+func newChunkOpener(password []byte, hdr *Header) (*chunkOpener, error) {
+	key, err := fileKey(password, hdr)
+	if err != nil {
+		return nil, err
+	}
+	return newChunkOpenerKey(key, password, hdr)
+}
+
+// newChunkOpenerKey sets up the ciphers for the file key of hdr; password is
+// needed for the second layer of the paranoid modes
+func newChunkOpenerKey(key, password []byte, hdr *Header) (*chunkOpener, error) {
+	o := &chunkOpener{key: key, noncePrefix: hdr.NoncePrefix}
+	var err error
+	if hdr.legacyPQ() {
+		o.aead = newLegacyPQ(key, hdr.Mode)
+		return o, nil
+	}
+	switch hdr.Mode {
+	case ModeAES256GCM, ModeParanoid, ModePostQuantumKyber768, ModeHybridPQ:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if o.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+		if hdr.Mode == ModeParanoid {
+			kdf := hdr.KDFParams()
+			key2 := argon2.IDKey(append(password, []byte("paranoid")...), hdr.Salt, kdf.Time*2, kdf.Memory, kdf.Threads, keyLen)
+			if o.aead2, err = chacha20poly1305.New(key2); err != nil {
+				return nil, err
+			}
+		}
+	case ModeChaCha20:
+		if o.aead, err = chacha20poly1305.New(key); err != nil {
+			return nil, err
+		}
+	case ModeParanoidSerpent:
+		if o.aead, o.aead2, err = newParanoidSerpent(key, password, hdr); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: encryption mode %d", ErrUnsupported, hdr.Mode)
+	}
+	return o, nil
+}
+
+// open decrypts the sealed chunk with the given counter and frame word, nil
+// unless the container is framed; sealed is overwritten
+func (o *chunkOpener) open(counter uint32, sealed, word []byte) ([]byte, error) {
+	defer endChunk(beginChunk())
+	nonce := make([]byte, o.aead.NonceSize())
+	copy(nonce, o.noncePrefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixLen:], counter)
+	var err error
+	if o.aead2 != nil {
+		nonce2 := make([]byte, o.aead2.NonceSize())
+		copy(nonce2, nonce)
+		if sealed, err = o.aead2.Open(sealed[:0], nonce2, sealed, word); err != nil {
+			return nil, chunkAuthError(counter)
+		}
+	}
+	plain, err := o.aead.Open(sealed[:0], nonce, sealed, word)
+	if err != nil {
+		return nil, chunkAuthError(counter)
+	}
+	return plain, nil
+}
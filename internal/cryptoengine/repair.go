@@ -0,0 +1,156 @@
+package cryptoengine
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrIntact is returned by Repair when the container decrypts completely
+var ErrIntact = errors.New("container is intact; nothing to repair")
+
+// RepairResult describes what Repair kept of a damaged container
+type RepairResult struct {
+	Chunks      int64 // complete, authenticated chunks kept
+	TotalChunks int64 // chunks the header promises
+	Recovered   int64 // plaintext bytes in the repaired container
+	Lost        int64 // plaintext bytes that could not be recovered
+	Cause       error // why the original stopped decrypting
+}
+
+// countingWriter counts the plaintext decryption hands out; decryptFile only
+// writes a chunk after it authenticated
+type countingWriter struct{ n int64 }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// Repair writes to outputPath a copy of inputPath cut after its last complete,
+// authenticated chunk, with the header's size lowered to match, so the result
+// decrypts cleanly. This recovers the intact beginning of an interrupted
+// download or copy; everything after the first damaged chunk is lost. The
+// chunks are copied as they are, so no data is re-encrypted. Deniability Mode
+// output is repaired inside its wrapping, and the copy is wrapped again.
+func Repair(inputPath, outputPath string, password []byte, onProgress ProgressCallback) (res *RepairResult, err error) {
+	in, err := openContainer(inputPath, password)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	hdr, err := in.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	overhead, err := hdr.chunkOverhead()
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Flags&FlagStream != 0 {
+		return nil, fmt.Errorf("%w: streamed containers cannot be repaired", ErrUnsupported)
+	}
+	if hdr.Flags&FlagCompressed != 0 {
+		return nil, fmt.Errorf("%w: compressed containers cannot be repaired", ErrUnsupported)
+	}
+	if hdr.Flags&FlagFramed != 0 {
+		return nil, fmt.Errorf("%w: framed containers cannot be repaired", ErrUnsupported)
+	}
+	if hdr.Flags&FlagReedSolomon != 0 {
+		return nil, fmt.Errorf("%w: Reed-Solomon containers repair damaged shards as they are decrypted", ErrUnsupported)
+	}
+	if hdr.ChunkSize <= 0 {
+		return nil, fmt.Errorf("%w: chunk size %d", ErrCorrupt, hdr.ChunkSize)
+	}
+
+	var good countingWriter
+	cause := DecryptFileToWriter(inputPath, &good, password, onProgress)
+	switch {
+	case cause == nil:
+		return nil, ErrIntact
+	case !errors.Is(cause, ErrCorrupt):
+		return nil, cause
+	}
+	chunks := good.n / int64(hdr.ChunkSize)
+	if chunks == 0 {
+		return nil, fmt.Errorf("no complete chunk survived: %w", cause)
+	}
+	// The shorter size changes the header, so metadata is re-sealed; it must be genuine first
+	var key []byte
+	if hdr.Flags&FlagMetadata != 0 {
+		if key, err = fileKey(password, hdr); err != nil {
+			return nil, err
+		}
+		if err := hdr.verifyMAC(key); err != nil {
+			return nil, err
+		}
+	}
+	res = &RepairResult{
+		Chunks:      chunks,
+		TotalChunks: (hdr.OriginalSize + int64(hdr.ChunkSize) - 1) / int64(hdr.ChunkSize),
+		Recovered:   chunks * int64(hdr.ChunkSize),
+		Cause:       cause,
+	}
+	res.Lost = hdr.OriginalSize - res.Recovered
+
+	chunked := io.NewSectionReader(in, int64(len(hdr.Bytes())), in.Size())
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil && cerr != nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(outputPath)
+			res = nil
+		}
+	}()
+	var dst io.Writer = out
+	if hdr.Deniable {
+		wrapped, err := newDeniableWriter(out, password, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		defer wrapped.k.wipe()
+		dst = wrapped
+	}
+	hdr.OriginalSize = res.Recovered
+	if err := hdr.seal(key); err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(hdr.Bytes()); err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(dst, chunked, chunks*int64(hdr.ChunkSize+overhead)); err != nil {
+		return nil, truncatedError(err)
+	}
+	return res, nil
+}
+
+// chunkOverhead is how many bytes encryption adds to every chunk in mode
+func chunkOverhead(mode EncryptionMode) (int, error) {
+	switch mode {
+	case ModeAES256GCM, ModeChaCha20, ModePostQuantumKyber768, ModeHybridPQ:
+		return gcmOverhead, nil
+	case ModeParanoid:
+		return 2 * gcmOverhead, nil
+	case ModeParanoidSerpent:
+		return gcmOverhead + serpentTagLen, nil
+	case ModePostQuantumDilithium3, ModePostQuantumSPHINCS:
+		return 0, errLegacyPQ
+	}
+	return 0, fmt.Errorf("%w: %s containers cannot be repaired", ErrUnsupported, GetEncryptionModeName(mode))
+}
+
+// chunkOverhead is chunkOverhead for the mode of h, including the legacy
+// post-quantum containers that are only read
+func (h *Header) chunkOverhead() (int, error) {
+	if h.legacyPQ() {
+		return newLegacyPQ(nil, h.Mode).Overhead(), nil
+	}
+	return chunkOverhead(h.Mode)
+}
@@ -0,0 +1,99 @@
+package cryptoengine
+
+import (
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/bangundwir/HadesCrypt/internal/serpent"
+)
+
+// ModeParanoidSerpent seals every chunk twice: with XChaCha20-Poly1305 under
+// the file key, then with Serpent-256 in CTR mode and an HMAC-SHA256 over the
+// result (encrypt-then-MAC) under keys from a second Argon2id derivation, so a
+// chunk stays secret and authenticated while either layer holds. The outer
+// layer adds serpentTagLen bytes to every chunk.
+const serpentTagLen = sha256.Size
+
+// newParanoidSerpent returns the inner and outer layers of a ModeParanoidSerpent
+// container with file key key
+func newParanoidSerpent(key, password []byte, hdr *Header) (inner, outer cipher.AEAD, err error) {
+	if inner, err = chacha20poly1305.NewX(key); err != nil {
+		return nil, nil, err
+	}
+	kdf := hdr.KDFParams()
+	key2 := argon2.IDKey(append(slices.Clone(password), "paranoid-serpent"...), hdr.Salt, kdf.Time*2, kdf.Memory, kdf.Threads, keyLen)
+	defer Wipe(key2)
+	keys, err := hkdf.Key(sha256.New, key2, hdr.Salt, "HadesCrypt Serpent-CTR + HMAC-SHA256", 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer Wipe(keys)
+	block, err := serpent.NewCipher(keys[:32])
+	if err != nil {
+		return nil, nil, err
+	}
+	return inner, &serpentCTRHMAC{block: block, macKey: slices.Clone(keys[32:])}, nil
+}
+
+// serpentCTRHMAC is the outer layer of ModeParanoidSerpent as a cipher.AEAD.
+// The counter block is the nonce followed by a 32-bit block counter, and the
+// tag covers the nonce, the additional data and the ciphertext.
+type serpentCTRHMAC struct {
+	block  cipher.Block
+	macKey []byte
+}
+
+func (s *serpentCTRHMAC) NonceSize() int { return gcmNonceLen }
+
+func (s *serpentCTRHMAC) Overhead() int { return serpentTagLen }
+
+func (s *serpentCTRHMAC) stream(nonce []byte) cipher.Stream {
+	iv := make([]byte, serpent.BlockSize)
+	copy(iv, nonce)
+	return cipher.NewCTR(s.block, iv)
+}
+
+func (s *serpentCTRHMAC) tag(dst, nonce, ciphertext, additionalData []byte) []byte {
+	// chunks are sealed concurrently, so every call gets its own HMAC
+	mac := hmac.New(sha256.New, s.macKey)
+	mac.Write(nonce)
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(len(additionalData))))
+	mac.Write(additionalData)
+	mac.Write(ciphertext)
+	return mac.Sum(dst)
+}
+
+func (s *serpentCTRHMAC) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmNonceLen {
+		panic("cryptoengine: bad Serpent-CTR nonce length")
+	}
+	n := len(dst)
+	dst = slices.Grow(dst, len(plaintext)+serpentTagLen)[:n+len(plaintext)]
+	s.stream(nonce).XORKeyStream(dst[n:], plaintext)
+	return s.tag(dst, nonce, dst[n:], additionalData)
+}
+
+func (s *serpentCTRHMAC) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmNonceLen {
+		panic("cryptoengine: bad Serpent-CTR nonce length")
+	}
+	if len(ciphertext) < serpentTagLen {
+		return nil, ErrAuthFailed
+	}
+	body, tag := ciphertext[:len(ciphertext)-serpentTagLen], ciphertext[len(ciphertext)-serpentTagLen:]
+	var sum [serpentTagLen]byte
+	if !hmac.Equal(s.tag(sum[:0], nonce, body, additionalData), tag) {
+		return nil, ErrAuthFailed
+	}
+	n := len(dst)
+	dst = slices.Grow(dst, len(body))[:n+len(body)]
+	s.stream(nonce).XORKeyStream(dst[n:], body)
+	return dst, nil
+}
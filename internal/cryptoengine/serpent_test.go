@@ -0,0 +1,45 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"errors"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/serpent"
+)
+
+func TestSerpentLayer(t *testing.T) {
+	key := bytes.Repeat([]byte{9}, 32)
+	block, err := serpent.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := &serpentCTRHMAC{block: block, macKey: key}
+	nonce := []byte("nonce-of-12b")
+	plain := bytes.Repeat([]byte("serpent "), 300)
+	sealed := layer.Seal(nil, nonce, plain, nil)
+	if len(sealed) != len(plain)+serpentTagLen {
+		t.Fatalf("sealed %d bytes for %d", len(sealed), len(plain))
+	}
+	// the body is plain Serpent-CTR
+	want := make([]byte, len(plain))
+	cipher.NewCTR(block, append(bytes.Clone(nonce), 0, 0, 0, 0)).XORKeyStream(want, plain)
+	if !bytes.Equal(sealed[:len(plain)], want) {
+		t.Error("body is not Serpent-CTR of the plaintext")
+	}
+	for _, i := range []int{0, len(plain) - 1, len(sealed) - 1} {
+		bad := bytes.Clone(sealed)
+		bad[i] ^= 1
+		if _, err := layer.Open(nil, nonce, bad, nil); !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("byte %d changed: %v", i, err)
+		}
+	}
+	if _, err := layer.Open(nil, []byte("other nonce!"), sealed, nil); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("other nonce: %v", err)
+	}
+	got, err := layer.Open(sealed[:0], nonce, sealed, nil)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("open in place: %v", err)
+	}
+}
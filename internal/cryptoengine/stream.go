@@ -0,0 +1,300 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EncryptingWriter encrypts what is written to it into a container on another
+// writer, for plaintext whose size is not known up front: stdin, sockets or
+// buffers. Such streamed containers carry FlagStream instead of the size; a
+// chunk shorter than the chunk size ends them, or with UseCompression or
+// AdaptiveChunks the last frame, so truncation at a chunk boundary is
+// detected. Chunks are sealed on opts.Workers goroutines as with files. Close
+// must be called to write the last chunk.
+type EncryptingWriter struct {
+	pw      *io.PipeWriter
+	done    chan error
+	key     []byte
+	wrapped *deniableWriter // with UseDeniability
+	closed  bool
+	err     error
+}
+
+// NewEncryptingWriter writes the header of a streamed container to dst and
+// returns the writer for its plaintext; with UseDeniability the wrapping goes
+// first. ModeGnuPG is not supported.
+func NewEncryptingWriter(dst io.Writer, password []byte, opts EncryptionOptions) (*EncryptingWriter, error) {
+	if opts.Mode == ModeGnuPG {
+		return nil, fmt.Errorf("%w: GnuPG mode encrypts files, not streams", ErrUnsupported)
+	}
+	random, err := randomSource(opts)
+	if err != nil {
+		return nil, err
+	}
+	hdr, err := newHeader(opts, 0, random)
+	if err != nil {
+		return nil, err
+	}
+	hdr.Flags |= FlagStream
+	sealer, err := newChunkSealer(password, hdr, random)
+	if err != nil {
+		return nil, err
+	}
+	sealer.sizer = newAdaptiveSizer(hdr, opts)
+	if err := hdr.seal(sealer.key); err != nil {
+		return nil, err
+	}
+	var wrapped *deniableWriter
+	if opts.UseDeniability {
+		if wrapped, err = newDeniableWriter(dst, password, random); err != nil {
+			return nil, err
+		}
+		dst = wrapped
+	}
+	if _, err := dst.Write(hdr.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var body io.Writer = dst
+	var parity *parityWriter
+	if hdr.Flags&FlagReedSolomon != 0 {
+		if parity, err = newParityWriter(dst, hdr); err != nil {
+			return nil, err
+		}
+		body = parity
+	}
+
+	pr, pw := io.Pipe()
+	w := &EncryptingWriter{pw: pw, done: make(chan error, 1), key: sealer.key, wrapped: wrapped}
+	go func() {
+		var in io.Reader = pr
+		if opts.UseCompression {
+			deflated := deflating(pr, opts.CompressionLevel)
+			defer deflated.Close()
+			in = deflated
+		}
+		_, err := sealer.encryptChunks(in, body, opts.Workers, 0, nil)
+		if err == nil && parity != nil {
+			err = parity.Close()
+		}
+		// a failed write to dst fails the next Write
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+// Write encrypts p. Chunks are written to dst as they fill, so an error may
+// belong to data written earlier.
+func (w *EncryptingWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("cryptoengine: write after Close")
+	}
+	return w.pw.Write(p)
+}
+
+// Close writes the last chunk and wipes the key. It does not close dst.
+func (w *EncryptingWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	w.pw.Close()
+	w.err = <-w.done
+	Wipe(w.key)
+	if w.wrapped != nil {
+		w.wrapped.k.wipe()
+	}
+	return w.err
+}
+
+// DecryptingReader decrypts a container read front to back from an io.Reader.
+// It reads streamed containers and those written from files alike. A streamed
+// container ends where src does, a framed one at its last frame; any other
+// stops after the size in its header.
+// Every chunk is authenticated before its plaintext is returned, and a
+// container cut short fails with ErrCorrupt rather than io.EOF. Compressed
+// containers are inflated as they are read.
+type DecryptingReader struct {
+	src       io.Reader
+	hdr       *Header
+	opener    *chunkOpener
+	overhead  int
+	counter   uint32
+	remaining int64  // chunked data still to come, unless the container is a stream
+	sealed    []byte // chunk buffer; plain aliases it
+	plain     []byte // plaintext of the current chunk not yet read
+	done      bool   // the last chunk has been read
+	err       error
+	inflated  io.Reader       // reads through readChunks, with FlagCompressed
+	wrapped   *deniableReader // Deniability Mode output
+}
+
+// NewDecryptingReader reads the header and the first chunk from src, so a
+// wrong password fails here with ErrAuthFailed, and checks the header metadata
+// against its MAC. A source without the magic is unwrapped with the password
+// as Deniability Mode output. GnuPG containers are not supported.
+func NewDecryptingReader(src io.Reader, password []byte) (*DecryptingReader, error) {
+	// every wrapping starts with at least its salt and nonce
+	head := make([]byte, saltLengthBytes+deniableNonceLen)
+	n, err := io.ReadFull(src, head)
+	head = head[:n]
+	src = io.MultiReader(bytes.NewReader(head), src)
+	var wrapped *deniableReader
+	if err == nil && !notWrapped(head) {
+		if wrapped, err = newDeniableReader(src, password); err != nil {
+			return nil, err
+		}
+		src = wrapped
+	}
+	hdr, err := ReadHeader(src)
+	if err != nil {
+		if wrapped != nil {
+			wrapped.k.wipe()
+		}
+		return nil, err
+	}
+	if hdr.Mode == ModeGnuPG {
+		return nil, fmt.Errorf("%w: GnuPG containers cannot be streamed", ErrUnsupported)
+	}
+	if hdr.Flags&FlagReedSolomon != 0 {
+		if src, err = newParityReader(src, hdr); err != nil {
+			return nil, err
+		}
+	}
+	overhead, err := hdr.chunkOverhead()
+	if err != nil {
+		return nil, err
+	}
+	opener, err := newChunkOpener(password, hdr)
+	if err != nil {
+		return nil, err
+	}
+	r := &DecryptingReader{src: src, hdr: hdr, opener: opener, overhead: overhead, remaining: hdr.OriginalSize, wrapped: wrapped}
+	if hdr.Flags&FlagCompressed != 0 {
+		r.inflated = newInflatingReader(readerFunc(r.readChunks), hdr.inflatedSize())
+	}
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+	if err := hdr.verifyMAC(opener.key); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Header returns the container's header; the size of a stream is not known
+func (r *DecryptingReader) Header() *Header { return r.hdr }
+
+// Read implements io.Reader
+func (r *DecryptingReader) Read(p []byte) (int, error) {
+	if r.inflated != nil {
+		return r.inflated.Read(p)
+	}
+	return r.readChunks(p)
+}
+
+// readChunks reads the opened chunks
+func (r *DecryptingReader) readChunks(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		switch {
+		case r.err != nil:
+			return 0, r.err
+		case r.done:
+			return 0, io.EOF
+		}
+		r.err = r.next()
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// Close wipes the buffered plaintext and the key. It does not close src.
+func (r *DecryptingReader) Close() error {
+	Wipe(r.sealed[:cap(r.sealed)])
+	Wipe(r.opener.key)
+	if r.wrapped != nil {
+		r.wrapped.k.wipe()
+	}
+	r.plain, r.done = nil, true
+	return nil
+}
+
+// next reads and opens the following chunk into r.plain
+func (r *DecryptingReader) next() error {
+	if r.hdr.Flags&FlagFramed != 0 {
+		return r.nextFrame()
+	}
+	stream := r.hdr.endsShort()
+	want := int64(r.hdr.ChunkSize)
+	if !stream {
+		if r.remaining == 0 {
+			r.done = true
+			return nil
+		}
+		if r.remaining < want {
+			want = r.remaining
+		}
+	}
+	need := int(want) + r.overhead
+	if cap(r.sealed) < need {
+		r.sealed = make([]byte, need)
+	}
+	sealed := r.sealed[:need]
+	n, err := io.ReadFull(r.src, sealed)
+	switch {
+	case stream && errors.Is(err, io.ErrUnexpectedEOF) && n >= r.overhead:
+		// the short chunk that ends a stream
+		sealed, r.done = sealed[:n], true
+	case err != nil:
+		return truncatedError(err)
+	}
+	plain, err := r.opener.open(r.counter, sealed, nil)
+	if err != nil {
+		return err
+	}
+	r.counter++
+	r.remaining -= int64(len(plain))
+	if !stream && r.remaining == 0 {
+		r.done = true
+	}
+	r.plain = plain
+	return nil
+}
+
+// nextFrame reads and opens the following chunk of a framed container
+func (r *DecryptingReader) nextFrame() error {
+	remaining := int64(-1)
+	if r.hdr.frameTotal() >= 0 {
+		remaining = r.remaining
+	}
+	n, last, err := readFrame(r.src, r.hdr, remaining)
+	if err != nil {
+		return err
+	}
+	need := n + r.overhead
+	if cap(r.sealed) < need {
+		r.sealed = make([]byte, need)
+	}
+	sealed := r.sealed[:need]
+	if _, err := io.ReadFull(r.src, sealed); err != nil {
+		return truncatedError(err)
+	}
+	plain, err := r.opener.open(r.counter, sealed, frameWord(n, last))
+	if err != nil {
+		return err
+	}
+	r.counter++
+	r.remaining -= int64(n)
+	r.plain, r.done = plain, last
+	return nil
+}
+
+// readerFunc adapts a Read method to io.Reader
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,162 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encryptStream encrypts data with NewEncryptingWriter, writing it in odd-sized pieces
+func encryptStream(t *testing.T, mode EncryptionMode, data []byte, meta Metadata) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewEncryptingWriter(&buf, testPassword, EncryptionOptions{Mode: mode, Argon2: testKDF, Metadata: meta, KEMKey: testKEMKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rest := data; len(rest) > 0; {
+		n := min(len(rest), 100_003)
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatal(err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	for _, mode := range streamModes {
+		for _, size := range testSizes {
+			t.Run(fmt.Sprintf("%s/%d", GetEncryptionModeName(mode), size), func(t *testing.T) {
+				t.Parallel()
+				data := make([]byte, size)
+				rand.Read(data)
+				enc := encryptStream(t, mode, data, Metadata{Name: "stdin.txt"})
+
+				r, err := NewDecryptingReader(bytes.NewReader(enc), testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Fatalf("decrypted %d bytes differ from the %d bytes encrypted", len(got), len(data))
+				}
+				if r.Header().Metadata.Name != "stdin.txt" {
+					t.Errorf("metadata %+v", r.Header().Metadata)
+				}
+
+				// a streamed container saved to a file reads like any other
+				path := filepath.Join(t.TempDir(), "stream.hadescrypt")
+				if err := os.WriteFile(path, enc, 0600); err != nil {
+					t.Fatal(err)
+				}
+				hdr, err := ReadHeaderFromFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.OriginalSize != int64(size) {
+					t.Errorf("size from the file length is %d, want %d", hdr.OriginalSize, size)
+				}
+				if got, err := decryptBytes(path, testPassword); err != nil || !bytes.Equal(got, data) {
+					t.Errorf("DecryptFileToWriter: %d bytes, %v", len(got), err)
+				}
+				ra, err := OpenReader(path, testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer ra.Close()
+				if got, err := io.ReadAll(ra); err != nil || !bytes.Equal(got, data) {
+					t.Errorf("Reader: %d bytes, %v", len(got), err)
+				}
+			})
+		}
+	}
+}
+
+func TestDecryptingReaderFileContainer(t *testing.T) {
+	enc, want := encryptTest(t, ModeAES256GCM, 2*testChunk+100, Metadata{Comment: "c"})
+	f, err := os.Open(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := NewDecryptingReader(f, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("decrypted %d bytes differ from the %d bytes encrypted", len(got), len(want))
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDecryptingReader(f, []byte("wrong password")); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("got %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestStreamTruncated(t *testing.T) {
+	data := make([]byte, 2*testChunk)
+	rand.Read(data)
+	enc := encryptStream(t, ModeChaCha20, data, Metadata{})
+	// the empty last chunk, then whole chunks, then part of one
+	for _, cut := range []int{gcmOverhead, testChunk + 2*gcmOverhead, 100} {
+		short := enc[:len(enc)-cut]
+		r, err := NewDecryptingReader(bytes.NewReader(short), testPassword)
+		if err == nil {
+			_, err = io.ReadAll(r)
+		}
+		if !errors.Is(err, ErrCorrupt) {
+			t.Errorf("cut %d: got %v, want ErrCorrupt", cut, err)
+		}
+		path := filepath.Join(t.TempDir(), "short.hadescrypt")
+		if err := os.WriteFile(path, short, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decryptBytes(path, testPassword); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("cut %d: DecryptFileToWriter got %v, want ErrCorrupt", cut, err)
+		}
+	}
+}
+
+// failingWriter accepts limit bytes and then fails
+type failingWriter struct{ limit int }
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > f.limit {
+		return 0, errors.New("disk full")
+	}
+	f.limit -= len(p)
+	return len(p), nil
+}
+
+func TestEncryptingWriterError(t *testing.T) {
+	w, err := NewEncryptingWriter(&failingWriter{limit: 200}, testPassword, EncryptionOptions{Argon2: testKDF})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := make([]byte, testChunk)
+	for range 8 {
+		if _, err = w.Write(chunk); err != nil {
+			break
+		}
+	}
+	if cerr := w.Close(); cerr == nil || cerr.Error() != "disk full" {
+		t.Errorf("Close: got %v, want the write error", cerr)
+	}
+}
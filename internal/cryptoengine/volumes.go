@@ -0,0 +1,32 @@
+package cryptoengine
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+	"github.com/bangundwir/HadesCrypt/internal/splitter"
+)
+
+// joinVolumes returns the container to decrypt for inputPath. When inputPath is
+// a volume of a split container, or a container split into volumes, they are
+// joined next to output first and done removes the copy.
+func joinVolumes(inputPath, output string) (path string, done func(), err error) {
+	base, parts := splitter.Volumes(inputPath)
+	if parts == nil {
+		return inputPath, func() {}, nil
+	}
+	joined, err := securetemp.TempPath(filepath.Dir(output), filepath.Base(base)+".*.joined")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := splitter.Join(base, parts, joined, nil); err != nil {
+		securetemp.Remove(joined)
+		if errors.Is(err, splitter.ErrVolumes) {
+			err = fmt.Errorf("%w: %w", ErrCorrupt, err)
+		}
+		return "", nil, err
+	}
+	return joined, func() { securetemp.Remove(joined) }, nil
+}
@@ -0,0 +1,36 @@
+package cryptoengine
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/splitter"
+)
+
+func TestDecryptVolumes(t *testing.T) {
+	enc, want := encryptTest(t, ModeChaCha20, testChunk+100, Metadata{})
+	parts, err := splitter.Split(enc, testChunk/3, nil)
+	if err != nil || len(parts) != 4 {
+		t.Fatalf("split into %d volumes: %v", len(parts), err)
+	}
+	for _, in := range []string{enc, parts[0], parts[3]} {
+		out := filepath.Join(t.TempDir(), "plain")
+		if err := DecryptFile(in, out, testPassword, false, nil); err != nil {
+			t.Fatalf("decrypt %s: %v", filepath.Base(in), err)
+		}
+		if got, _ := os.ReadFile(out); !bytes.Equal(got, want) {
+			t.Errorf("decrypt %s: %d bytes differ from the %d encrypted", filepath.Base(in), len(got), len(want))
+		}
+		if left, _ := filepath.Glob(filepath.Join(filepath.Dir(out), "*")); len(left) != 1 {
+			t.Errorf("decrypt %s left %v", filepath.Base(in), left)
+		}
+	}
+
+	os.Remove(parts[2])
+	if err := DecryptFile(enc, filepath.Join(t.TempDir(), "plain"), testPassword, false, nil); !errors.Is(err, ErrCorrupt) || !errors.Is(err, splitter.ErrVolumes) {
+		t.Errorf("a volume missing: got %v, want ErrCorrupt", err)
+	}
+}
@@ -0,0 +1,161 @@
+package editsession
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+)
+
+// Session tracks one decrypted working copy of a container
+type Session struct {
+	Container string // encrypted file being edited
+	TempPath  string // plaintext working copy
+
+	tempDir  string
+	password []byte
+	opts     cryptoengine.EncryptionOptions
+
+	mu       sync.Mutex
+	lastMod  time.Time
+	lastSize int64
+	stop     chan struct{}
+	done     chan struct{}
+	closed   bool
+}
+
+// Start decrypts container into a private temporary directory. Deniability Mode
+// output stays wrapped when saved, and post-quantum containers stay encrypted to
+// their key.
+func Start(containerPath string, password []byte) (*Session, error) {
+	hdr, err := cryptoengine.ReadHeaderWithPassword(containerPath, password)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Mode == cryptoengine.ModeGnuPG {
+		return nil, fmt.Errorf("editing GnuPG containers is not supported")
+	}
+
+	tempDir, err := securetemp.MkdirTemp("", "hadescrypt_edit_*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp directory: %w", err)
+	}
+	name := filepath.Base(containerPath)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	tempPath := filepath.Join(tempDir, name)
+
+	if err := cryptoengine.DecryptFile(containerPath, tempPath, password, false, nil); err != nil {
+		securetemp.Shred(tempPath)
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+
+	s := &Session{
+		Container: containerPath,
+		TempPath:  tempPath,
+		tempDir:   tempDir,
+		password:  append([]byte(nil), password...),
+		opts:      cryptoengine.EncryptionOptions{Mode: hdr.Mode, Compliance: hdr.Compliance(), Argon2: hdr.KDFParams(), Metadata: hdr.Metadata, UseDeniability: hdr.Deniable, KEMKey: hdr.LoadedKEMKey()},
+	}
+	s.lastMod, s.lastSize = s.stat()
+	return s, nil
+}
+
+// Open launches the platform's default application for the working copy
+func (s *Session) Open() error {
+	return OpenWithDefaultApp(s.TempPath)
+}
+
+// Watch polls the working copy and re-encrypts it whenever it has been saved.
+// onSaved is called after every re-encryption attempt.
+func (s *Session) Watch(interval time.Duration, onSaved func(error)) {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				changed, err := s.Sync()
+				if changed && onSaved != nil {
+					onSaved(err)
+				}
+			}
+		}
+	}()
+}
+
+// Sync re-encrypts the working copy if it changed since the last sync.
+// The container is replaced atomically so a failed write never destroys it.
+func (s *Session) Sync() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false, nil
+	}
+	mod, size := s.stat()
+	if size < 0 || (mod.Equal(s.lastMod) && size == s.lastSize) {
+		return false, nil
+	}
+	// Wait for the editor to finish writing before picking up the change
+	time.Sleep(300 * time.Millisecond)
+	if mod2, size2 := s.stat(); !mod2.Equal(mod) || size2 != size {
+		return false, nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.Container), "."+filepath.Base(s.Container)+".*.__edit_tmp__")
+	if err != nil {
+		return true, err
+	}
+	tmpOut := f.Name()
+	f.Close()
+	defer os.Remove(tmpOut)
+	if err := cryptoengine.EncryptFileWithOptions(s.TempPath, tmpOut, s.password, s.opts, nil); err != nil {
+		return true, err
+	}
+	if err := os.Rename(tmpOut, s.Container); err != nil {
+		return true, err
+	}
+	s.lastMod, s.lastSize = mod, size
+	return true, nil
+}
+
+// Close performs a final sync, shreds the working copy and forgets the password
+func (s *Session) Close() error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop = nil
+	}
+	_, syncErr := s.Sync()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return syncErr
+	}
+	s.closed = true
+	securetemp.Shred(s.TempPath)
+	os.RemoveAll(s.tempDir)
+	for i := range s.password {
+		s.password[i] = 0
+	}
+	return syncErr
+}
+
+func (s *Session) stat() (time.Time, int64) {
+	fi, err := os.Stat(s.TempPath)
+	if err != nil {
+		return time.Time{}, -1
+	}
+	return fi.ModTime(), fi.Size()
+}
@@ -0,0 +1,93 @@
+package editsession
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+var password = []byte("correct horse battery staple")
+
+func TestSessionKeepsDeniability(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(src, []byte("first draft"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	container := src + ".hadescrypt"
+	opts := cryptoengine.EncryptionOptions{
+		Mode:           cryptoengine.ModeAES256GCM,
+		Argon2:         cryptoengine.Argon2Params{Time: 1, Memory: 64, Threads: 1},
+		UseDeniability: true,
+	}
+	if err := cryptoengine.EncryptFileWithOptions(src, container, password, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Start(container, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, err := os.ReadFile(s.TempPath); err != nil || string(got) != "first draft" {
+		t.Fatalf("working copy: %q, %v", got, err)
+	}
+	edited := []byte("second draft, longer")
+	if err := os.WriteFile(s.TempPath, edited, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := s.Sync(); !changed || err != nil {
+		t.Fatalf("Sync: %v, %v", changed, err)
+	}
+
+	hdr, err := cryptoengine.ReadHeaderWithPassword(container, password)
+	if err != nil || !hdr.Deniable {
+		t.Fatalf("the saved container is not wrapped: %+v, %v", hdr, err)
+	}
+	got, err := cryptoengine.DecryptFileToMemory(container, password, 0)
+	if err != nil || !bytes.Equal(got, edited) {
+		t.Errorf("saved container: %q, %v", got, err)
+	}
+}
+
+func TestSessionKeepsPQKey(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "plan.txt")
+	if err := os.WriteFile(src, []byte("first draft"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := cryptoengine.GenerateKEMKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cryptoengine.AddKEMKey(key)()
+	container := src + ".hadescrypt"
+	opts := cryptoengine.EncryptionOptions{
+		Mode:   cryptoengine.ModeHybridPQ,
+		Argon2: cryptoengine.Argon2Params{Time: 1, Memory: 64, Threads: 1},
+		KEMKey: key,
+	}
+	if err := cryptoengine.EncryptFileWithOptions(src, container, password, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Start(container, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := os.WriteFile(s.TempPath, []byte("second draft"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := s.Sync(); !changed || err != nil {
+		t.Fatalf("Sync: %v, %v", changed, err)
+	}
+	hdr, err := cryptoengine.ReadHeaderFromFile(container)
+	if err != nil || hdr.Mode != cryptoengine.ModeHybridPQ || hdr.KEMFingerprint() != key.Fingerprint() {
+		t.Fatalf("saved container: %+v, %v", hdr, err)
+	}
+}
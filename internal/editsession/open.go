@@ -0,0 +1,25 @@
+package editsession
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// OpenWithDefaultApp asks the desktop environment to open path with its associated application
+func OpenWithDefaultApp(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	case "darwin":
+		cmd = exec.Command("open", path)
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the launcher; most launchers exit immediately after handing off
+	go cmd.Wait()
+	return nil
+}
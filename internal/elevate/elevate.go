@@ -0,0 +1,22 @@
+// Package elevate relaunches the app with administrator rights for operations
+// on protected locations such as Program Files or other users' folders. Only
+// Windows can elevate, through its UAC prompt; elsewhere Supported is false and
+// access errors are reported as they are.
+package elevate
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Denied reports whether err is an access denial that running elevated may overcome
+func Denied(err error) bool {
+	return Supported() && errors.Is(err, fs.ErrPermission)
+}
+
+// Relaunch starts exe again with args and administrator rights once the user
+// accepts the system prompt, and returns when the new process has started.
+// Declining the prompt returns apperr.ErrCanceled.
+func Relaunch(exe string, args []string) error {
+	return relaunch(exe, args)
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package elevate
+
+import "errors"
+
+// Supported reports whether this platform can relaunch elevated
+func Supported() bool { return false }
+
+func relaunch(string, []string) error { return errors.ErrUnsupported }
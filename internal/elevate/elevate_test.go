@@ -0,0 +1,22 @@
+//go:build !windows
+
+package elevate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestUnsupported(t *testing.T) {
+	if Supported() {
+		t.Fatal("Supported on a platform without UAC")
+	}
+	if Denied(fmt.Errorf("open: %w", fs.ErrPermission)) {
+		t.Error("Denied offers elevation where it cannot happen")
+	}
+	if err := Relaunch("/bin/true", nil); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Relaunch err = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package elevate
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+)
+
+// Supported reports whether this platform can relaunch elevated
+func Supported() bool { return true }
+
+func relaunch(exe string, args []string) error {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = windows.EscapeArg(a)
+	}
+	verb, _ := windows.UTF16PtrFromString("runas")
+	file, err := windows.UTF16PtrFromString(exe)
+	if err != nil {
+		return err
+	}
+	params, err := windows.UTF16PtrFromString(strings.Join(quoted, " "))
+	if err != nil {
+		return err
+	}
+	dir, _ := windows.UTF16PtrFromString(filepath.Dir(exe))
+	err = windows.ShellExecute(0, verb, file, params, dir, windows.SW_NORMAL)
+	if err == windows.ERROR_CANCELLED {
+		return apperr.ErrCanceled
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/archiver"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+)
+
+// Result describes a finished Decrypt
+type Result struct {
+	Path       string // the decrypted file, or the folder a folder archive was extracted into
+	Folder     bool   // the container held a folder archive
+	Size       int64  // plaintext bytes
+	Downloaded int64  // container bytes
+	Checked    bool   // the download matched a checksum the server sent or one expected
+	Signed     bool   // the download matched the expected signature
+	Trusted    string // trusted comment of that signature
+}
+
+// Options are the checks and limits of Decrypt; the zero value checks what the
+// server sends and extracts without limits
+type Options struct {
+	Expect Expect
+	Limits archiver.Limits // for a folder archive
+}
+
+// archivePeek is how much plaintext is looked at to spot a folder archive
+const archivePeek = 64 << 10
+
+// Decrypt downloads the HadesCrypt container at rawURL and decrypts it into dir
+// as it arrives, so the container is never stored. A folder archive is
+// extracted in the same pass. The output takes the original name from the
+// header, else the download's name without its container extension, with a
+// " (n)" suffix when taken. It only appears once every chunk authenticated and
+// the download matched the server's checksum and opts.Expect. onProgress,
+// which may be nil, counts downloaded bytes, which covers every step; total is
+// -1 when the server did not send the size.
+func Decrypt(ctx context.Context, client *http.Client, rawURL, dir string, password []byte, opts Options, onProgress func(done, total int64)) (res *Result, err error) {
+	r, err := Open(ctx, client, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if err := r.Expect(opts.Expect); err != nil {
+		return nil, err
+	}
+	counted := &countingReader{r: r, total: r.Size(), report: onProgress}
+	dr, err := cryptoengine.NewDecryptingReader(counted, password)
+	if err != nil {
+		return nil, err
+	}
+	defer dr.Close()
+	name := filepath.Base(format.DecryptedPathFor(r.Name()))
+	if m := dr.Header().Metadata; m.Name != "" && m.Validate() == nil {
+		name = m.Name
+	}
+
+	plain := &countingReader{r: dr}
+	br := bufio.NewReaderSize(plain, archivePeek)
+	prefix, _ := br.Peek(archivePeek)
+	res = &Result{Folder: archiver.IsTarGz(bytes.NewReader(prefix))}
+	var tmp string
+	if res.Folder {
+		tmp, err = saveFolder(dir, br, opts.Limits)
+		name = strings.TrimSuffix(name, ".tar.gz")
+	} else {
+		tmp, err = saveFile(dir, br)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(tmp)
+		}
+	}()
+	// the archive may end before the plaintext, and a container with its size
+	// in the header before the download; reading on checks the checksums and
+	// signature, and nothing may follow the container
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return nil, err
+	}
+	extra, err := io.Copy(io.Discard, counted)
+	if err != nil {
+		return nil, err
+	}
+	if extra > 0 {
+		return nil, fmt.Errorf("%w: %d bytes after the container", cryptoengine.ErrCorrupt, extra)
+	}
+	out := format.FreePath(filepath.Join(dir, name))
+	if err := os.Rename(tmp, out); err != nil {
+		return nil, err
+	}
+	res.Path, res.Size, res.Downloaded, res.Checked = out, plain.done, counted.done, r.Checked()
+	res.Trusted, res.Signed = r.Signed()
+	return res, nil
+}
+
+// saveFile writes r to a hidden file in dir and returns its path
+func saveFile(dir string, r io.Reader) (path string, err error) {
+	tmp, err := securetemp.CreateTemp(dir, ".download-*.part")
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(tmp, r); err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// saveFolder extracts the folder archive read from r into a hidden folder in
+// dir and returns its path
+func saveFolder(dir string, r io.Reader, limits archiver.Limits) (string, error) {
+	tmp, err := securetemp.MkdirTemp(dir, ".download-*.part")
+	if err != nil {
+		return "", err
+	}
+	if err := archiver.ExtractTarGzFrom(r, -1, tmp, limits, nil); err != nil {
+		os.RemoveAll(tmp)
+		return "", fmt.Errorf("extract archive: %w", err)
+	}
+	return tmp, nil
+}
+
+// countingReader reports the bytes read through it
+type countingReader struct {
+	r      io.Reader
+	done   int64
+	total  int64
+	report func(done, total int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.done += int64(n)
+	if n > 0 && c.report != nil {
+		c.report(c.done, c.total)
+	}
+	return n, err
+}
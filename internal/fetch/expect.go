@@ -0,0 +1,124 @@
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/minisign"
+)
+
+// Expect is what the publisher of a file says it is, checked on top of any
+// checksum the server sends. The zero value checks nothing.
+type Expect struct {
+	SHA256    []byte             // of the file as published
+	Signature []byte             // minisign .minisig of the file, made by Key
+	Key       minisign.PublicKey // only used with Signature
+}
+
+// IsZero reports whether e checks nothing
+func (e Expect) IsZero() bool { return e.SHA256 == nil && e.Signature == nil }
+
+// ParseSHA256 reads a SHA-256 given as 64 hex digits, optionally after
+// "sha256:", or as the first field of a sha256sum line
+func ParseSHA256(s string) ([]byte, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, errors.New("empty SHA-256")
+	}
+	digits := fields[0]
+	if alg, rest, ok := strings.Cut(digits, ":"); ok && strings.EqualFold(alg, "sha256") {
+		digits = rest
+	}
+	sum, err := hex.DecodeString(digits)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("%q is not a SHA-256 in hex", fields[0])
+	}
+	return sum, nil
+}
+
+// maxSmall bounds ReadSmall, which is meant for signatures and checksum files
+const maxSmall = 64 << 10
+
+// ReadSmall downloads a small file such as a .minisig next to a release;
+// client nil uses http.DefaultClient
+func ReadSmall(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	r, err := Open(ctx, client, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if r.Size() > maxSmall {
+		return nil, fmt.Errorf("%s is %d bytes; a signature is far smaller", redact(r.url), r.Size())
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxSmall+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSmall {
+		return nil, fmt.Errorf("%s is over %d bytes; a signature is far smaller", redact(r.url), maxSmall)
+	}
+	return data, nil
+}
+
+// Expect adds the publisher's checksum and signature to the checks of the
+// download. It must be called before the first Read.
+func (r *Reader) Expect(e Expect) error {
+	if e.SHA256 != nil {
+		if len(e.SHA256) != sha256.Size {
+			return fmt.Errorf("expected SHA-256 has %d bytes", len(e.SHA256))
+		}
+		r.expectSum, r.expectHash = e.SHA256, sha256.New()
+	}
+	if e.Signature != nil {
+		v, err := e.Key.NewVerifier(e.Signature)
+		if err != nil {
+			return err
+		}
+		r.verifier = v
+	}
+	return nil
+}
+
+// Signed returns the trusted comment of the signature the download matched;
+// ok is false without a signature or before the end of the download
+func (r *Reader) Signed() (trusted string, ok bool) { return r.trusted, r.signed }
+
+// ParseExpect builds an Expect from what a user typed: a SHA-256 for
+// ParseSHA256, the text of a .minisig or its https:// link, and the minisign
+// public key the signature must be made with. Empty strings check nothing.
+func ParseExpect(ctx context.Context, client *http.Client, sum, signature, publicKey string) (Expect, error) {
+	var e Expect
+	var err error
+	if strings.TrimSpace(sum) != "" {
+		if e.SHA256, err = ParseSHA256(sum); err != nil {
+			return Expect{}, err
+		}
+	}
+	signature, publicKey = strings.TrimSpace(signature), strings.TrimSpace(publicKey)
+	switch {
+	case signature == "" && publicKey == "":
+		return e, nil
+	case signature == "":
+		return Expect{}, errors.New("a public key needs the signature to check")
+	case publicKey == "":
+		return Expect{}, errors.New("a signature needs the publisher's minisign public key")
+	}
+	if e.Key, err = minisign.ParsePublicKey(publicKey); err != nil {
+		return Expect{}, err
+	}
+	if IsURL(signature) {
+		data, err := ReadSmall(ctx, client, signature)
+		if err != nil {
+			return Expect{}, fmt.Errorf("signature: %w", err)
+		}
+		signature = string(data)
+	}
+	e.Signature = []byte(signature)
+	return e, nil
+}
@@ -0,0 +1,320 @@
+// Package fetch downloads encrypted files over HTTPS as a stream, so a file
+// shared by link decrypts without a separate download step. A dropped
+// connection is resumed with a range request, and the body is checked against
+// the size, any checksum the server advertises, and the checksum and minisign
+// signature its publisher gives.
+package fetch
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/minisign"
+)
+
+// Sentinel errors of a download, tagged with their apperr codes
+var (
+	// ErrChecksum means the body does not match a checksum the server sent with it or the one expected
+	ErrChecksum = apperr.Wrap(apperr.CorruptFile, errors.New("download does not match its checksum"))
+	// ErrChanged means the file on the server changed while it was resumed
+	ErrChanged = apperr.Wrap(apperr.IOError, errors.New("the file changed on the server during the download"))
+)
+
+// MaxResumes is how often a dropped download is resumed before it fails
+const MaxResumes = 5
+
+// resumeDelay is the pause before the first resume; it doubles for each further one
+var resumeDelay = time.Second
+
+// IsURL reports whether s is an https:// URL
+func IsURL(s string) bool {
+	u, err := url.Parse(strings.TrimSpace(s))
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+var driveFile = regexp.MustCompile(`^/file/d/([\w-]+)`)
+
+// DirectURL turns a share link of a cloud provider into the link of the file
+// itself, which is what a browser would download; other URLs are returned as is
+func DirectURL(s string) string {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return s
+	}
+	q := u.Query()
+	switch host := strings.TrimPrefix(u.Host, "www."); {
+	case host == "dropbox.com":
+		q.Set("dl", "1")
+	case host == "drive.google.com" && driveFile.MatchString(u.Path):
+		id := driveFile.FindStringSubmatch(u.Path)[1]
+		u.Path, q = "/uc", url.Values{"export": {"download"}, "id": {id}}
+	case host == "1drv.ms" || host == "onedrive.live.com":
+		q.Set("download", "1")
+	default:
+		return u.String()
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Reader streams the body of a download. It is not safe for concurrent use.
+type Reader struct {
+	ctx       context.Context
+	client    *http.Client
+	url       string
+	body      io.ReadCloser
+	size      int64  // -1 when the server did not say
+	name      string // file name the server suggests
+	validator string // ETag or Last-Modified for If-Range; empty when the download cannot resume
+	offset    int64
+	resumes   int
+	hashes    map[string]hash.Hash // by algorithm, for the checksums in want
+	want      map[string][]byte
+	// the publisher's checks, see Expect
+	expectSum  []byte
+	expectHash hash.Hash
+	verifier   *minisign.Verifier
+	trusted    string
+	signed     bool
+	err        error
+}
+
+// Open starts downloading rawURL; client nil uses http.DefaultClient. The
+// context bounds the whole download, resumes included.
+func Open(ctx context.Context, client *http.Client, rawURL string) (*Reader, error) {
+	if !IsURL(rawURL) {
+		return nil, fmt.Errorf("%q is not an https:// URL", rawURL)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	r := &Reader{ctx: ctx, client: client, url: DirectURL(rawURL), size: -1}
+	resp, err := r.get(-1)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download %s: %s", redact(r.url), resp.Status)
+	}
+	r.body = resp.Body
+	r.size = resp.ContentLength
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		r.validator = etag
+	} else {
+		r.validator = resp.Header.Get("Last-Modified")
+	}
+	r.name = fileName(resp)
+	r.want = checksums(resp.Header)
+	r.hashes = make(map[string]hash.Hash)
+	for alg := range r.want {
+		if alg == "sha-256" {
+			r.hashes[alg] = sha256.New()
+		} else {
+			r.hashes[alg] = md5.New()
+		}
+	}
+	return r, nil
+}
+
+// Size is the length of the file, or -1 when the server did not send it
+func (r *Reader) Size() int64 { return r.size }
+
+// Name is the file name from the server's Content-Disposition or the URL path
+func (r *Reader) Name() string { return r.name }
+
+// Checked reports whether the body is checked against a checksum, sent by the server or expected
+func (r *Reader) Checked() bool { return len(r.want) > 0 || r.expectSum != nil }
+
+// Read implements io.Reader. It fails with ErrChecksum instead of io.EOF when the
+// body does not match, and resumes a broken connection where it stopped.
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for r.err == nil {
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		for _, h := range r.hashes {
+			h.Write(p[:n])
+		}
+		if r.expectHash != nil {
+			r.expectHash.Write(p[:n])
+		}
+		if r.verifier != nil {
+			r.verifier.Write(p[:n])
+		}
+		if err == io.EOF && r.size >= 0 && r.offset != r.size {
+			err = io.ErrUnexpectedEOF
+		}
+		switch {
+		case err == nil:
+		case err == io.EOF:
+			r.err = r.verify()
+		case r.ctx.Err() != nil:
+			r.err = r.ctx.Err()
+		default:
+			r.err = r.resume(err)
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return 0, r.err
+}
+
+// Close ends the download
+func (r *Reader) Close() error {
+	if r.err == nil {
+		r.err = errors.New("fetch: read after Close")
+	}
+	return r.body.Close()
+}
+
+// verify checks the finished body against the advertised and expected
+// checksums and the signature; io.EOF means it matches
+func (r *Reader) verify() error {
+	for alg, want := range r.want {
+		if got := r.hashes[alg].Sum(nil); string(got) != string(want) {
+			return fmt.Errorf("%w (%s from the server)", ErrChecksum, alg)
+		}
+	}
+	if r.expectHash != nil && string(r.expectHash.Sum(nil)) != string(r.expectSum) {
+		return fmt.Errorf("%w (the expected SHA-256)", ErrChecksum)
+	}
+	if r.verifier != nil {
+		trusted, err := r.verifier.Verify()
+		if err != nil {
+			return apperr.Wrap(apperr.CorruptFile, fmt.Errorf("download does not match its signature: %w", err))
+		}
+		r.trusted, r.signed = trusted, true
+	}
+	return io.EOF
+}
+
+// resume requests the rest of the file after cause broke the connection; nil means it continues
+func (r *Reader) resume(cause error) error {
+	r.body.Close()
+	for r.validator != "" && r.resumes < MaxResumes {
+		select {
+		case <-time.After(resumeDelay << r.resumes):
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		}
+		r.resumes++
+		resp, err := r.get(r.offset)
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return r.ctx.Err()
+			}
+			cause = err
+			continue
+		}
+		if resp.StatusCode == http.StatusPartialContent && strings.HasPrefix(resp.Header.Get("Content-Range"), "bytes "+strconv.FormatInt(r.offset, 10)+"-") {
+			r.body = resp.Body
+			return nil
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			// If-Range sends the whole file when it no longer matches the validator
+			return ErrChanged
+		}
+		return fmt.Errorf("resume download at byte %d: %s", r.offset, resp.Status)
+	}
+	return fmt.Errorf("download broke off after %d bytes: %w", r.offset, cause)
+}
+
+// get requests the file from offset on, or all of it for -1. The body is asked
+// for as it is stored, so checksums and ranges refer to the same bytes.
+func (r *Reader) get(offset int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept-Encoding", "identity")
+	if offset >= 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", r.validator)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", redact(r.url), errors.Unwrap(err))
+	}
+	return resp, nil
+}
+
+// checksums collects the digests of the whole body from Repr-Digest, Digest,
+// Content-MD5, x-amz-checksum-sha256 and x-goog-hash, keyed by "sha-256" or "md5"
+func checksums(h http.Header) map[string][]byte {
+	want := make(map[string][]byte)
+	add := func(alg, b64 string) {
+		alg = strings.ToLower(strings.TrimSpace(alg))
+		if alg != "sha-256" && alg != "md5" {
+			return
+		}
+		if sum, err := base64.StdEncoding.DecodeString(strings.Trim(strings.TrimSpace(b64), ":")); err == nil {
+			want[alg] = sum
+		}
+	}
+	for _, name := range []string{"Digest", "Repr-Digest"} {
+		for _, field := range strings.Split(h.Get(name), ",") {
+			if alg, sum, ok := strings.Cut(field, "="); ok {
+				add(alg, sum)
+			}
+		}
+	}
+	for _, field := range strings.Split(h.Get("X-Goog-Hash"), ",") {
+		if alg, sum, ok := strings.Cut(field, "="); ok {
+			add(alg, sum)
+		}
+	}
+	if sum := h.Get("Content-MD5"); sum != "" {
+		add("md5", sum)
+	}
+	// multipart uploads carry a checksum of checksums, ending in "-<parts>"
+	if sum := h.Get("X-Amz-Checksum-Sha256"); sum != "" && !strings.Contains(sum, "-") {
+		add("sha-256", sum)
+	}
+	return want
+}
+
+// fileName picks the name the server suggests, else the last element of the final URL
+func fileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := path.Base(strings.ReplaceAll(params["filename"], `\`, "/")); plainName(name) {
+			return name
+		}
+	}
+	if name, err := url.PathUnescape(path.Base(resp.Request.URL.Path)); err == nil && plainName(name) {
+		return name
+	}
+	return "download"
+}
+
+// plainName reports whether name can be used as a file name as it is
+func plainName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// redact drops the query of u, which often holds an access token
+func redact(u string) string {
+	if i := strings.IndexByte(u, '?'); i >= 0 {
+		return u[:i]
+	}
+	return u
+}
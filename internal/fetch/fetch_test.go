@@ -0,0 +1,284 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/archiver"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/minisign"
+)
+
+var testPassword = []byte("correct horse battery staple")
+
+func init() { resumeDelay = 0 }
+
+// container encrypts size random bytes as a stream and returns the container and the plaintext
+func container(t *testing.T, size int, meta cryptoengine.Metadata) ([]byte, []byte) {
+	t.Helper()
+	plain := make([]byte, size)
+	rand.Read(plain)
+	return seal(t, plain, meta), plain
+}
+
+// seal encrypts plain as a stream
+func seal(t *testing.T, plain []byte, meta cryptoengine.Metadata) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := cryptoengine.NewEncryptingWriter(&buf, testPassword, cryptoengine.EncryptionOptions{
+		Argon2:   cryptoengine.Argon2Params{Time: 1, Memory: 64, Threads: 1},
+		Metadata: meta,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(plain)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// server serves body with the ETag etag(), cutting the first response off after cut bytes
+func server(t *testing.T, body []byte, cut int, etag func() string, digest string) *httptest.Server {
+	var requests atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag())
+		w.Header().Set("Content-Disposition", `attachment; filename="report.pdf.hadescrypt"`)
+		if digest != "" {
+			w.Header().Set("Repr-Digest", "sha-256=:"+digest+":")
+		}
+		if requests.Add(1) == 1 && cut > 0 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Write(body[:cut])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sha256B64(b []byte) string {
+	sum := sha256.Sum256(b)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestDecryptResumes(t *testing.T) {
+	enc, plain := container(t, 3<<20, cryptoengine.Metadata{})
+	srv := server(t, enc, len(enc)/2, func() string { return `"v1"` }, sha256B64(enc))
+	dir := t.TempDir()
+	var last int64
+	res, err := Decrypt(context.Background(), srv.Client(), srv.URL+"/files/x?token=secret", dir, testPassword, Options{}, func(done, total int64) {
+		if total != int64(len(enc)) || done < last {
+			t.Errorf("progress %d/%d after %d", done, total, last)
+		}
+		last = done
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Checked || res.Size != int64(len(plain)) || res.Downloaded != int64(len(enc)) {
+		t.Errorf("result %+v", res)
+	}
+	if res.Path != filepath.Join(dir, "report.pdf") {
+		t.Errorf("written to %s", res.Path)
+	}
+	got, err := os.ReadFile(res.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatal("decrypted download differs")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d entries left in the output folder", len(entries))
+	}
+}
+
+func TestDecryptOriginalName(t *testing.T) {
+	enc, _ := container(t, 100, cryptoengine.Metadata{Name: "Ärger.txt"})
+	srv := server(t, enc, 0, func() string { return `"v1"` }, "")
+	res, err := Decrypt(context.Background(), srv.Client(), srv.URL, t.TempDir(), testPassword, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(res.Path) != "Ärger.txt" || res.Checked {
+		t.Errorf("result %+v", res)
+	}
+}
+
+func TestDecryptChecksumMismatch(t *testing.T) {
+	enc, _ := container(t, 100, cryptoengine.Metadata{})
+	srv := server(t, enc, 0, func() string { return `"v1"` }, sha256B64([]byte("something else")))
+	dir := t.TempDir()
+	if _, err := Decrypt(context.Background(), srv.Client(), srv.URL, dir, testPassword, Options{}, nil); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("got %v, want ErrChecksum", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d entries left in the output folder", len(entries))
+	}
+}
+
+func TestDecryptChangedOnServer(t *testing.T) {
+	enc, _ := container(t, 2<<20, cryptoengine.Metadata{})
+	var calls atomic.Int32
+	srv := server(t, enc, len(enc)/2, func() string { return `"v` + strconv.Itoa(int(calls.Add(1))) + `"` }, "")
+	if _, err := Decrypt(context.Background(), srv.Client(), srv.URL, t.TempDir(), testPassword, Options{}, nil); !errors.Is(err, ErrChanged) {
+		t.Fatalf("got %v, want ErrChanged", err)
+	}
+}
+
+func TestDecryptWrongPassword(t *testing.T) {
+	enc, _ := container(t, 100, cryptoengine.Metadata{})
+	srv := server(t, enc, 0, func() string { return `"v1"` }, "")
+	if _, err := Decrypt(context.Background(), srv.Client(), srv.URL, t.TempDir(), []byte("wrong"), Options{}, nil); !errors.Is(err, cryptoengine.ErrAuthFailed) {
+		t.Fatalf("got %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	for s, want := range map[string]bool{
+		"https://example.com/a.hadescrypt": true,
+		" https://example.com/a ":          true,
+		"http://example.com/a.hadescrypt":  false,
+		"/home/me/a.hadescrypt":            false,
+		`C:\Users\me\a.hadescrypt`:         false,
+		"https:///no-host":                 false,
+	} {
+		if got := IsURL(s); got != want {
+			t.Errorf("IsURL(%q) = %v", s, got)
+		}
+	}
+}
+
+func TestDirectURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://www.dropbox.com/s/abc/f.hadescrypt?dl=0":           "https://www.dropbox.com/s/abc/f.hadescrypt?dl=1",
+		"https://drive.google.com/file/d/1AbC-x_Y/view?usp=sharing": "https://drive.google.com/uc?export=download&id=1AbC-x_Y",
+		"https://1drv.ms/u/s!Abc":                                   "https://1drv.ms/u/s!Abc?download=1",
+		"https://example.com/f.hadescrypt?x=1":                      "https://example.com/f.hadescrypt?x=1",
+	} {
+		if got := DirectURL(in); got != want {
+			t.Errorf("DirectURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDecryptExpect(t *testing.T) {
+	enc, plain := container(t, 1000, cryptoengine.Metadata{})
+	srv := server(t, enc, 0, func() string { return `"v1"` }, "")
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	key := minisign.PrivateKey{ID: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}, Key: priv}
+	sig, err := key.Sign(bytes.NewReader(enc), "release", "file:x.hadescrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(enc)
+	sigSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write(sig) }))
+	defer sigSrv.Close()
+	expect, err := ParseExpect(context.Background(), sigSrv.Client(), hex.EncodeToString(sum[:]), sigSrv.URL+"/x.hadescrypt.minisig", key.Public().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseExpect(context.Background(), nil, "", string(sig), ""); err == nil {
+		t.Error("a signature without a public key was accepted")
+	}
+
+	dir := t.TempDir()
+	res, err := Decrypt(context.Background(), srv.Client(), srv.URL, dir, testPassword, Options{Expect: expect}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Checked || !res.Signed || res.Trusted != "file:x.hadescrypt" || res.Size != int64(len(plain)) {
+		t.Errorf("result %+v", res)
+	}
+
+	wrong := sha256.Sum256([]byte("other"))
+	otherSig, _ := key.Sign(bytes.NewReader([]byte("other")), "release", "file:other")
+	for name, expect := range map[string]Expect{
+		"checksum":  {SHA256: wrong[:]},
+		"signature": {Signature: otherSig, Key: key.Public()},
+	} {
+		dir := t.TempDir()
+		_, err := Decrypt(context.Background(), srv.Client(), srv.URL, dir, testPassword, Options{Expect: expect}, nil)
+		if apperr.Classify(err) != apperr.CorruptFile {
+			t.Errorf("%s: got %v (%s), want a corrupt file", name, err, apperr.Classify(err))
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%s: %d entries left in the output folder", name, len(entries))
+		}
+	}
+}
+
+func TestDecryptFolder(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "Photos")
+	os.MkdirAll(filepath.Join(src, "2024"), 0755)
+	os.WriteFile(filepath.Join(src, "2024", "a.jpg"), bytes.Repeat([]byte("jpeg"), 50000), 0644)
+	os.WriteFile(filepath.Join(src, "notes.txt"), []byte("hello"), 0644)
+	archive := filepath.Join(t.TempDir(), "Photos.tar.gz")
+	if err := archiver.CreateTarGz(src, archive, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := seal(t, data, cryptoengine.Metadata{Name: "Photos"})
+	srv := server(t, enc, len(enc)/2, func() string { return `"v1"` }, sha256B64(enc))
+
+	dir := t.TempDir()
+	res, err := Decrypt(context.Background(), srv.Client(), srv.URL, dir, testPassword, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Folder || res.Path != filepath.Join(dir, "Photos") {
+		t.Fatalf("result %+v", res)
+	}
+	if got, err := os.ReadFile(filepath.Join(res.Path, "notes.txt")); err != nil || string(got) != "hello" {
+		t.Errorf("notes.txt: %q, %v", got, err)
+	}
+	if fi, err := os.Stat(filepath.Join(res.Path, "2024", "a.jpg")); err != nil || fi.Size() != 200000 {
+		t.Errorf("a.jpg: %v", err)
+	}
+
+	// the limits of the app apply
+	_, err = Decrypt(context.Background(), srv.Client(), srv.URL, dir, testPassword, Options{Limits: archiver.Limits{MaxFiles: 1}}, nil)
+	if !errors.Is(err, archiver.ErrLimit) {
+		t.Errorf("got %v, want ErrLimit", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d entries in the output folder, want the first folder only", len(entries))
+	}
+}
+
+func TestParseSHA256(t *testing.T) {
+	const hexSum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	for _, s := range []string{hexSum, " sha256:" + hexSum, strings.ToUpper(hexSum) + "  app.hadescrypt\n"} {
+		if sum, err := ParseSHA256(s); err != nil || hex.EncodeToString(sum) != hexSum {
+			t.Errorf("ParseSHA256(%q) = %x, %v", s, sum, err)
+		}
+	}
+	for _, s := range []string{"", "abc", hexSum[:62], "md5:" + hexSum} {
+		if _, err := ParseSHA256(s); err == nil {
+			t.Errorf("ParseSHA256(%q) accepted", s)
+		}
+	}
+}
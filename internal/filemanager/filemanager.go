@@ -0,0 +1,127 @@
+// Package filemanager hands results over to the system file manager (Explorer,
+// Finder, or the freedesktop file manager on Linux). Fyne cannot start a drag
+// into other applications, so results are revealed in a file manager window,
+// where they can be dragged on, or put on the clipboard as files to paste.
+package filemanager
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrNoClipboardTool is returned on Linux when neither wl-copy nor xclip is installed
+var ErrNoClipboardTool = errors.New("copying files needs wl-copy (Wayland) or xclip (X11)")
+
+// Reveal opens a file manager window on the folder holding path with path selected
+func Reveal(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	name, args := revealCommand(runtime.GOOS, path)
+	err = exec.Command(name, args...).Run()
+	switch {
+	case runtime.GOOS == "windows":
+		return nil // explorer.exe exits with 1 even when the window opened
+	case err == nil || runtime.GOOS == "darwin":
+		return err
+	}
+	// no file manager answers the freedesktop call: open the folder instead
+	return start(exec.Command("xdg-open", filepath.Dir(path)))
+}
+
+// revealCommand returns the command that selects path in a file manager on goos
+func revealCommand(goos, path string) (string, []string) {
+	switch goos {
+	case "windows":
+		return "explorer", []string{"/select," + path}
+	case "darwin":
+		return "open", []string{"-R", path}
+	}
+	return "dbus-send", []string{"--session", "--print-reply", "--dest=org.freedesktop.FileManager1",
+		"--type=method_call", "/org/freedesktop/FileManager1", "org.freedesktop.FileManager1.ShowItems",
+		"array:string:" + fileURI(path), "string:"}
+}
+
+// CopyFiles puts paths on the clipboard as files, so pasting in a file manager copies them
+func CopyFiles(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	abs := make([]string, len(paths))
+	for i, p := range paths {
+		a, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		abs[i] = a
+	}
+	cmd, err := copyCommand(runtime.GOOS, abs, os.Getenv("WAYLAND_DISPLAY") != "", exec.LookPath)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "linux" || runtime.GOOS == "freebsd" || runtime.GOOS == "openbsd" {
+		// xclip keeps running to serve the clipboard until something else takes it
+		return start(cmd)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("copy files: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// copyCommand builds the clipboard command for goos; lookPath finds the Linux tools
+func copyCommand(goos string, paths []string, wayland bool, lookPath func(string) (string, error)) (*exec.Cmd, error) {
+	switch goos {
+	case "windows":
+		quoted := make([]string, len(paths))
+		for i, p := range paths {
+			quoted[i] = "'" + strings.ReplaceAll(p, "'", "''") + "'"
+		}
+		return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", "Set-Clipboard -LiteralPath "+strings.Join(quoted, ",")), nil
+	case "darwin":
+		files := make([]string, len(paths))
+		for i, p := range paths {
+			files[i] = `POSIX file "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(p) + `"`
+		}
+		return exec.Command("osascript", "-e", "set the clipboard to {"+strings.Join(files, ", ")+"}"), nil
+	}
+	uris := make([]string, len(paths))
+	for i, p := range paths {
+		uris[i] = fileURI(p)
+	}
+	list := strings.Join(uris, "\r\n") + "\r\n"
+	var cmd *exec.Cmd
+	if _, err := lookPath("wl-copy"); wayland && err == nil {
+		cmd = exec.Command("wl-copy", "--type", "text/uri-list")
+	} else if _, err := lookPath("xclip"); err == nil {
+		cmd = exec.Command("xclip", "-selection", "clipboard", "-t", "text/uri-list")
+	} else {
+		return nil, ErrNoClipboardTool
+	}
+	cmd.Stdin = strings.NewReader(list)
+	return cmd, nil
+}
+
+// fileURI returns the file:// URI of an absolute path
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// start runs cmd in the background and reaps it
+func start(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,62 @@
+package filemanager
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRevealCommand(t *testing.T) {
+	for _, tc := range []struct {
+		goos, path, name, want string
+	}{
+		{"windows", `C:\out\a b.txt`, "explorer", `/select,C:\out\a b.txt`},
+		{"darwin", "/out/a b.txt", "open", "/out/a b.txt"},
+		{"linux", "/out/a b.txt", "dbus-send", "array:string:file:///out/a%20b.txt"},
+	} {
+		name, args := revealCommand(tc.goos, tc.path)
+		if name != tc.name || !slices.Contains(args, tc.want) {
+			t.Errorf("%s: %s %q, want %s with %q", tc.goos, name, args, tc.name, tc.want)
+		}
+	}
+}
+
+func TestCopyCommand(t *testing.T) {
+	found := func(tools ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			if slices.Contains(tools, name) {
+				return "/usr/bin/" + name, nil
+			}
+			return "", exec.ErrNotFound
+		}
+	}
+	paths := []string{"/out/it's.txt", "/out/b.txt"}
+
+	cmd, _ := copyCommand("windows", paths, false, found())
+	if got := cmd.Args[len(cmd.Args)-1]; got != "Set-Clipboard -LiteralPath '/out/it''s.txt','/out/b.txt'" {
+		t.Errorf("windows: %q", got)
+	}
+	cmd, _ = copyCommand("darwin", []string{`/out/say "hi".txt`}, false, found())
+	if got := cmd.Args[len(cmd.Args)-1]; got != `set the clipboard to {POSIX file "/out/say \"hi\".txt"}` {
+		t.Errorf("darwin: %q", got)
+	}
+
+	cmd, _ = copyCommand("linux", paths, true, found("wl-copy", "xclip"))
+	if cmd.Args[0] != "wl-copy" {
+		t.Errorf("wayland uses %s", cmd.Args[0])
+	}
+	data, _ := io.ReadAll(cmd.Stdin)
+	if !strings.HasPrefix(string(data), "file:///out/it%27s.txt\r\n") {
+		t.Errorf("uri list %q", data)
+	}
+	cmd, _ = copyCommand("linux", paths, true, found("xclip"))
+	if cmd.Args[0] != "xclip" {
+		t.Errorf("without wl-copy uses %s", cmd.Args[0])
+	}
+	if _, err := copyCommand("linux", paths, false, found("wl-copy")); !errors.Is(err, ErrNoClipboardTool) {
+		t.Errorf("X11 without xclip: err = %v", err)
+	}
+}
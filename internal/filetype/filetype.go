@@ -0,0 +1,147 @@
+// Package filetype finds out what a decrypted file really is from its first
+// bytes, so a program or script can be flagged before someone opens it, even
+// when its name claims a harmless type.
+package filetype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is how much of a file Detect reads
+const sniffLen = 512
+
+// Info is what Detect found out about a file
+type Info struct {
+	MIME string // media type from the content, e.g. "application/pdf"
+	Kind string // what runs it, e.g. "Windows program"; empty when the file is not executable
+	// Disguised is set when the content is executable but the extension is not
+	// one of a program or script, like a Windows program named report.pdf
+	Disguised bool
+}
+
+// Executable reports whether opening the file may run code
+func (i Info) Executable() bool { return i.Kind != "" }
+
+// runExtensions are run by the system or a shell when opened, whatever the first bytes look like
+var runExtensions = map[string]string{
+	".exe": "Windows program", ".msi": "Windows installer", ".bat": "Windows batch script", ".cmd": "Windows batch script",
+	".ps1": "PowerShell script", ".psm1": "PowerShell script",
+	".vbs": "VBScript", ".vbe": "VBScript", ".js": "Windows Script Host script", ".jse": "Windows Script Host script",
+	".wsf": "Windows Script Host script", ".wsh": "Windows Script Host script", ".hta": "HTML application",
+	".reg": "registry file", ".scr": "Windows screen saver", ".com": "DOS program", ".cpl": "Control Panel item",
+	".sh": "shell script", ".command": "macOS shell script",
+}
+
+// executableExtensions are names a program or script is expected to have
+var executableExtensions = map[string]bool{
+	".exe": true, ".dll": true, ".sys": true, ".msi": true, ".lnk": true, ".jar": true, ".apk": true,
+	".so": true, ".dylib": true, ".bin": true, ".run": true, ".appimage": true, ".elf": true, ".out": true,
+	".class": true, ".py": true, ".pl": true, ".rb": true, "": true,
+}
+
+// Detect reads the start of the file at path and classifies it
+func Detect(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Info{}, err
+	}
+	return Sniff(filepath.Base(path), head[:n]), nil
+}
+
+// Sniff classifies a file called name that starts with head
+func Sniff(name string, head []byte) Info {
+	ext := strings.ToLower(filepath.Ext(name))
+	info := Info{MIME: http.DetectContentType(head)}
+	if kind, mime := magic(ext, head); kind != "" {
+		info.Kind, info.MIME = kind, mime
+		_, runs := runExtensions[ext]
+		info.Disguised = !runs && !executableExtensions[ext]
+		return info
+	}
+	if kind, ok := runExtensions[ext]; ok {
+		info.Kind = kind
+	}
+	return info
+}
+
+// magic recognizes executable content by its signature; ext tells container
+// formats apart that programs share with documents
+func magic(ext string, head []byte) (kind, mime string) {
+	switch {
+	case isPE(head):
+		return "Windows program", "application/vnd.microsoft.portable-executable"
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return "Linux/Unix program", "application/x-elf"
+	case hasAnyPrefix(head, "\xfe\xed\xfa\xce", "\xfe\xed\xfa\xcf", "\xce\xfa\xed\xfe", "\xcf\xfa\xed\xfe"):
+		return "macOS program", "application/x-mach-binary"
+	case bytes.HasPrefix(head, []byte("\xca\xfe\xba\xbe")) && len(head) >= 8:
+		// Universal binaries and Java classes share a magic; a universal binary
+		// counts a handful of architectures where a class file has its version
+		if binary.BigEndian.Uint32(head[4:8]) < 30 {
+			return "macOS program", "application/x-mach-binary"
+		}
+		return "Java class", "application/java-vm"
+	case bytes.HasPrefix(head, []byte("#!")):
+		return "script (" + interpreter(head) + ")", "text/x-shellscript"
+	case bytes.HasPrefix(head, []byte("L\x00\x00\x00\x01\x14\x02\x00")):
+		return "Windows shortcut", "application/x-ms-shortcut"
+	case bytes.HasPrefix(head, []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1")) && ext == ".msi":
+		return "Windows installer", "application/x-msi"
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")) && ext == ".jar":
+		return "Java program", "application/java-archive"
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")) && ext == ".apk":
+		return "Android app", "application/vnd.android.package-archive"
+	}
+	return "", ""
+}
+
+// isPE checks the PE signature the DOS header points to, since text can start with "MZ" too
+func isPE(head []byte) bool {
+	if len(head) < 0x40 || !bytes.HasPrefix(head, []byte("MZ")) {
+		return false
+	}
+	off := int64(binary.LittleEndian.Uint32(head[0x3c:0x40]))
+	return off+4 <= int64(len(head)) && bytes.Equal(head[off:off+4], []byte("PE\x00\x00"))
+}
+
+func hasAnyPrefix(head []byte, prefixes ...string) bool {
+	for _, p := range prefixes {
+		if bytes.HasPrefix(head, []byte(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// interpreter returns the program named on a #! line
+func interpreter(head []byte) string {
+	line, _, _ := bytes.Cut(head[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return "#!"
+	}
+	prog := filepath.Base(fields[0])
+	if prog == "env" && len(fields) > 1 {
+		prog = fields[1]
+	}
+	return prog
+}
+
+// Quarantine gives the file the mark of the web Windows sets on downloads, so
+// SmartScreen and Office's Protected View check it before it runs or opens.
+// Other platforms return errors.ErrUnsupported.
+func Quarantine(path string) error {
+	return quarantine(path)
+}
@@ -0,0 +1,67 @@
+package filetype
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	// DOS header pointing at a PE signature right after it
+	pe := "MZ" + strings.Repeat("\x00", 0x3a) + "\x40\x00\x00\x00" + "PE\x00\x00"
+	tests := []struct {
+		name      string
+		head      string
+		kind      string
+		disguised bool
+	}{
+		{"setup.exe", pe, "Windows program", false},
+		{"report.pdf", pe, "Windows program", true},
+		{"readme.txt", "MZ is where it starts, and the rest is just a long enough line of text\n", "", false},
+		{"old.exe", "MZ\x90\x00", "Windows program", false},
+		{"tool", "\x7fELF\x02\x01", "Linux/Unix program", false},
+		{"photo.jpg", "\xcf\xfa\xed\xfe\x07", "macOS program", true},
+		{"app", "\xca\xfe\xba\xbe\x00\x00\x00\x02", "macOS program", false},
+		{"Main.class", "\xca\xfe\xba\xbe\x00\x00\x00\x41", "Java class", false},
+		{"notes.txt", "#!/usr/bin/env python3\nprint(1)\n", "script (python3)", true},
+		{"run.sh", "#!/bin/sh\n", "script (sh)", false},
+		{"install.bat", "@echo off\r\n", "Windows batch script", false},
+		{"notes.txt", "just text\n", "", false},
+		{"archive.zip", "PK\x03\x04", "", false},
+		{"app.jar", "PK\x03\x04", "Java program", false},
+	}
+	for _, tt := range tests {
+		info := Sniff(tt.name, []byte(tt.head))
+		if info.Kind != tt.kind || info.Disguised != tt.disguised {
+			t.Errorf("Sniff(%q, %q) = %+v, want kind %q disguised %v", tt.name, tt.head, info, tt.kind, tt.disguised)
+		}
+		if info.Executable() != (tt.kind != "") {
+			t.Errorf("Sniff(%q).Executable() = %v", tt.name, info.Executable())
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.html")
+	if err := os.WriteFile(path, []byte("<!DOCTYPE html><html></html>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := Detect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MIME != "text/html; charset=utf-8" || info.Executable() {
+		t.Fatalf("Detect = %+v", info)
+	}
+}
+
+func TestQuarantineUnsupported(t *testing.T) {
+	if Supported() {
+		t.Skip("platform supports the mark of the web")
+	}
+	if err := Quarantine("x"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("err = %v", err)
+	}
+}
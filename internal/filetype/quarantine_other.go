@@ -0,0 +1,10 @@
+//go:build !windows
+
+package filetype
+
+import "errors"
+
+// Supported reports whether this platform can mark files with Quarantine
+func Supported() bool { return false }
+
+func quarantine(string) error { return errors.ErrUnsupported }
@@ -0,0 +1,15 @@
+//go:build windows
+
+package filetype
+
+import "os"
+
+// zoneInternet marks the file as downloaded from the internet (URLZONE_INTERNET)
+const zoneInternet = "[ZoneTransfer]\r\nZoneId=3\r\n"
+
+// Supported reports whether this platform can mark files with Quarantine
+func Supported() bool { return true }
+
+func quarantine(path string) error {
+	return os.WriteFile(path+":Zone.Identifier", []byte(zoneInternet), 0644)
+}
@@ -0,0 +1,162 @@
+// Package format is the registry of encrypted output formats: the file
+// extensions HadesCrypt writes and recognizes, and how input and output names
+// map onto each other. Every component that needs to tell an encrypted file
+// from a plaintext one by name asks this package.
+package format
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Kind is the container family behind an extension
+type Kind int
+
+const (
+	// HadesCrypt is the native HAD1 container
+	HadesCrypt Kind = iota + 1
+	// OpenPGP is a GnuPG/OpenPGP message
+	OpenPGP
+	// Age is an age-encryption.org/v1 file, written for SSH key recipients
+	Age
+)
+
+// Format describes one registered extension
+type Format struct {
+	Ext      string // lower case, with the leading dot
+	Kind     Kind
+	Writable bool // offered for new outputs; otherwise only recognized when decrypting
+}
+
+const (
+	// DefaultExtension is used for new HadesCrypt containers unless configured otherwise
+	DefaultExtension = ".hadescrypt"
+	// OpenPGPExtension is used for new GnuPG outputs
+	OpenPGPExtension = ".gpg"
+	// AgeExtension is used for files encrypted to SSH keys
+	AgeExtension = ".age"
+	// decryptedSuffix is appended when a decrypted name cannot be derived
+	decryptedSuffix = ".dec"
+)
+
+var (
+	mu       sync.RWMutex
+	registry = []Format{
+		{Ext: ".hadescrypt", Kind: HadesCrypt, Writable: true},
+		{Ext: ".heistcrypt", Kind: HadesCrypt, Writable: true},
+		{Ext: ".hades", Kind: HadesCrypt},
+		{Ext: ".gpg", Kind: OpenPGP, Writable: true},
+		{Ext: ".pgp", Kind: OpenPGP},
+		{Ext: ".age", Kind: Age, Writable: true},
+	}
+)
+
+// Register adds an extension, e.g. for a new mode; re-registering replaces it
+func Register(f Format) {
+	f.Ext = strings.ToLower(f.Ext)
+	mu.Lock()
+	defer mu.Unlock()
+	for i, r := range registry {
+		if r.Ext == f.Ext {
+			registry[i] = f
+			return
+		}
+	}
+	registry = append(registry, f)
+}
+
+// Lookup returns the format whose extension path ends with (case-insensitive)
+func Lookup(path string) (Format, bool) {
+	low := strings.ToLower(path)
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, f := range registry {
+		if strings.HasSuffix(low, f.Ext) {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// IsEncryptedArtifact reports whether path is named like an encrypted output of any kind
+func IsEncryptedArtifact(path string) bool {
+	_, ok := Lookup(path)
+	return ok
+}
+
+// IsHadesCrypt reports whether path is named like a HadesCrypt container
+func IsHadesCrypt(path string) bool {
+	f, ok := Lookup(path)
+	return ok && f.Kind == HadesCrypt
+}
+
+// IsOpenPGP reports whether path is named like a GnuPG/OpenPGP file
+func IsOpenPGP(path string) bool {
+	f, ok := Lookup(path)
+	return ok && f.Kind == OpenPGP
+}
+
+// IsAge reports whether path is named like an age file
+func IsAge(path string) bool {
+	f, ok := Lookup(path)
+	return ok && f.Kind == Age
+}
+
+// ContainerExtensions lists the extensions offered for new HadesCrypt containers, default first
+func ContainerExtensions() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	var exts []string
+	for _, f := range registry {
+		if f.Kind == HadesCrypt && f.Writable {
+			exts = append(exts, f.Ext)
+		}
+	}
+	return exts
+}
+
+// OutputPathFor returns the encrypted output name for input; an empty ext
+// means DefaultExtension
+func OutputPathFor(input, ext string) string {
+	if ext == "" {
+		ext = DefaultExtension
+	}
+	return input + ext
+}
+
+// DecryptedPathFor strips a recognized extension from an encrypted file's
+// name; names without one get ".dec" appended
+func DecryptedPathFor(path string) string {
+	if f, ok := Lookup(path); ok && len(path) > len(f.Ext) {
+		return path[:len(path)-len(f.Ext)]
+	}
+	return path + decryptedSuffix
+}
+
+// RepairedPathFor returns a free "name.repaired.ext" next to an encrypted file
+// for its repaired copy
+func RepairedPathFor(path string) string {
+	if f, ok := Lookup(path); ok && len(path) > len(f.Ext) {
+		cut := len(path) - len(f.Ext)
+		return FreePath(path[:cut] + ".repaired" + path[cut:])
+	}
+	return FreePath(path + ".repaired" + DefaultExtension)
+}
+
+// FreePath returns path, or "name (n).ext" with the lowest n not yet taken
+func FreePath(path string) string {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		p := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Lstat(p); os.IsNotExist(err) {
+			return p
+		}
+	}
+}
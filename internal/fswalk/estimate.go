@@ -0,0 +1,183 @@
+package fswalk
+
+import (
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+)
+
+// Defaults for EstimateOptions
+const (
+	DefaultEstimateBudget = 2000
+	DefaultEstimateProbes = 200
+)
+
+// maxProbeDepth stops a random descent that followed links round in a circle
+const maxProbeDepth = 64
+
+// EstimateOptions configures Estimate
+type EstimateOptions struct {
+	Options
+	Keep     func(Entry) bool // files that count; nil counts all
+	Budget   int              // directories read exactly before sampling; 0 uses DefaultEstimateBudget
+	Probes   int              // random descents extrapolating the rest; 0 uses DefaultEstimateProbes
+	Seed     uint64           // seeds the descents; 0 picks one from the clock
+	Canceled func() bool      // polled between directories
+}
+
+// SizeEstimate is what Estimate found. Files and Bytes are the best guess and
+// the Low/High fields bound it with about 95% confidence; when Exact is set the
+// tree was counted in full and all three agree.
+type SizeEstimate struct {
+	Files, FilesLow, FilesHigh int64
+	Bytes, BytesLow, BytesHigh int64
+	Exact                      bool
+}
+
+// Estimate sizes the tree below root without necessarily reading all of it.
+// The first Budget directories are counted exactly, breadth first, so small
+// trees get an exact answer. Below that the rest is extrapolated from random
+// descents (Knuth's estimator): each one follows a random path to a leaf,
+// weighting what it finds by the branching it skipped. Unreadable directories
+// count as empty; a canceled estimate returns apperr.ErrCanceled.
+func Estimate(root string, opts EstimateOptions) (SizeEstimate, error) {
+	budget, probes := opts.Budget, opts.Probes
+	if budget <= 0 {
+		budget = DefaultEstimateBudget
+	}
+	if probes <= 0 {
+		probes = DefaultEstimateProbes
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return SizeEstimate{}, err
+	}
+	e := &estimator{opts: opts, cache: map[string]dirCount{}}
+	if !info.IsDir() {
+		var c dirCount
+		e.count(&c, root, info)
+		return exact(c.files, c.bytes), nil
+	}
+
+	seen := newTracker(opts.Symlinks)
+	var files, bytes int64
+	queue := []string{root}
+	for reads := 0; len(queue) > 0 && reads < budget; reads++ {
+		if e.canceled() {
+			return SizeEstimate{}, apperr.ErrCanceled
+		}
+		dir := queue[0]
+		queue = queue[1:]
+		if !seen.first(realPath(dir)) {
+			continue
+		}
+		c := e.read(dir)
+		files += c.files
+		bytes += c.bytes
+		queue = append(queue, c.dirs...)
+	}
+	if len(queue) == 0 {
+		return exact(files, bytes), nil
+	}
+
+	// Each probe starts at a random unread directory, so len(queue) is its first weight
+	seed := opts.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	rng := rand.New(rand.NewPCG(seed, seed>>1|1))
+	fs, bs := make([]float64, probes), make([]float64, probes)
+	for i := range probes {
+		dir, weight := queue[rng.IntN(len(queue))], float64(len(queue))
+		for depth := 0; depth < maxProbeDepth; depth++ {
+			if e.canceled() {
+				return SizeEstimate{}, apperr.ErrCanceled
+			}
+			c := e.read(dir)
+			fs[i] += weight * float64(c.files)
+			bs[i] += weight * float64(c.bytes)
+			if len(c.dirs) == 0 {
+				break
+			}
+			dir = c.dirs[rng.IntN(len(c.dirs))]
+			weight *= float64(len(c.dirs))
+		}
+	}
+	est := SizeEstimate{}
+	est.Files, est.FilesLow, est.FilesHigh = extrapolate(files, fs)
+	est.Bytes, est.BytesLow, est.BytesHigh = extrapolate(bytes, bs)
+	return est, nil
+}
+
+func exact(files, bytes int64) SizeEstimate {
+	return SizeEstimate{Files: files, FilesLow: files, FilesHigh: files, Bytes: bytes, BytesLow: bytes, BytesHigh: bytes, Exact: true}
+}
+
+// extrapolate adds the mean of the samples to the counted part, with a 95%
+// range from their standard error; the low end never drops below what was counted
+func extrapolate(counted int64, samples []float64) (guess, low, high int64) {
+	var sum, sq float64
+	for _, s := range samples {
+		sum += s
+	}
+	mean := sum / float64(len(samples))
+	for _, s := range samples {
+		sq += (s - mean) * (s - mean)
+	}
+	margin := 0.0
+	if n := float64(len(samples)); n > 1 {
+		margin = 1.96 * math.Sqrt(sq/(n-1)/n)
+	}
+	base := float64(counted)
+	return int64(base + mean), int64(base + math.Max(0, mean-margin)), int64(base + mean + margin)
+}
+
+// dirCount is what one directory holds directly
+type dirCount struct {
+	files, bytes int64
+	dirs         []string
+}
+
+type estimator struct {
+	opts  EstimateOptions
+	cache map[string]dirCount // probes keep passing the same directories near the top
+}
+
+func (e *estimator) canceled() bool {
+	return e.opts.Canceled != nil && e.opts.Canceled()
+}
+
+// read lists dir once, applying the symlink policy, Exclude and Keep like Scan
+func (e *estimator) read(dir string) dirCount {
+	if c, ok := e.cache[dir]; ok {
+		return c
+	}
+	var c dirCount
+	entries, _ := os.ReadDir(dir)
+	for _, de := range entries {
+		child := filepath.Join(dir, de.Name())
+		info, err := resolve(e.opts.Symlinks, child, de)
+		if err != nil || info == nil || (e.opts.Exclude != nil && e.opts.Exclude(child, info.IsDir())) {
+			continue
+		}
+		if info.IsDir() {
+			c.dirs = append(c.dirs, child)
+			continue
+		}
+		e.count(&c, child, info)
+	}
+	e.cache[dir] = c
+	return c
+}
+
+func (e *estimator) count(c *dirCount, path string, info os.FileInfo) {
+	if e.opts.Keep != nil && !e.opts.Keep(Entry{Path: path, Info: info}) {
+		return
+	}
+	c.files++
+	c.bytes += info.Size()
+}
@@ -0,0 +1,84 @@
+package fswalk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+)
+
+// makeTree creates a tree depth levels deep where every folder has fanout
+// subfolders and two 10-byte files, returning its root and file count
+func makeTree(t *testing.T, depth, fanout int) (string, int64) {
+	t.Helper()
+	root := t.TempDir()
+	var files int64
+	var fill func(dir string, level int)
+	fill = func(dir string, level int) {
+		for i := range 2 {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.txt", i)), []byte("0123456789"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			files++
+		}
+		if level == depth {
+			return
+		}
+		for i := range fanout {
+			sub := filepath.Join(dir, fmt.Sprintf("d%d", i))
+			if err := os.Mkdir(sub, 0700); err != nil {
+				t.Fatal(err)
+			}
+			fill(sub, level+1)
+		}
+	}
+	fill(root, 0)
+	return root, files
+}
+
+func TestEstimateExact(t *testing.T) {
+	root, files := makeTree(t, 2, 3)
+	est, err := Estimate(root, EstimateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !est.Exact || est.Files != files || est.Bytes != 10*files || est.BytesLow != est.BytesHigh {
+		t.Errorf("small tree: %+v, want exactly %d files", est, files)
+	}
+
+	keep := func(e Entry) bool { return strings.HasSuffix(e.Path, "f0.txt") }
+	exclude := func(path string, isDir bool) bool { return isDir && filepath.Base(path) == "d0" }
+	est, _ = Estimate(root, EstimateOptions{Options: Options{Exclude: exclude}, Keep: keep})
+	// every d0 is left out: root, d1 and d2 with their d1 and d2, one kept file apiece
+	if est.Files != 7 || !est.Exact {
+		t.Errorf("filtered: %+v, want 7 files", est)
+	}
+}
+
+func TestEstimateSampled(t *testing.T) {
+	root, files := makeTree(t, 4, 4)
+	est, err := Estimate(root, EstimateOptions{Budget: 20, Probes: 400, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Exact {
+		t.Fatal("budget of 20 folders counted the whole tree")
+	}
+	if est.FilesLow > files || est.FilesHigh < files || est.BytesLow > 10*files || est.BytesHigh < 10*files {
+		t.Errorf("range %d–%d files, %d–%d bytes misses %d files", est.FilesLow, est.FilesHigh, est.BytesLow, est.BytesHigh, files)
+	}
+	if est.Files < files/2 || est.Files > files*2 {
+		t.Errorf("guess %d files, actual %d", est.Files, files)
+	}
+}
+
+func TestEstimateCanceled(t *testing.T) {
+	root, _ := makeTree(t, 1, 2)
+	if _, err := Estimate(root, EstimateOptions{Canceled: func() bool { return true }}); !errors.Is(err, apperr.ErrCanceled) {
+		t.Errorf("err = %v, want ErrCanceled", err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package fswalk walks folder trees for recursive encryption and archiving
+// with an explicit policy for symbolic links. Unlike filepath.Walk it can
+// follow linked directories, and it never visits the same directory or file
+// twice, so links cannot cause loops or double processing.
+package fswalk
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Policy decides what happens to symbolic links met during a walk
+type Policy string
+
+const (
+	// Skip leaves links and everything behind them out
+	Skip Policy = "skip"
+	// Follow treats links as the file or directory they point to
+	Follow Policy = "follow"
+	// Link reports the link itself; archives store it as a link, per-file operations pass it by
+	Link Policy = "link"
+)
+
+// Policies lists the policies in the order they are offered to users
+var Policies = []Policy{Skip, Follow, Link}
+
+// ParsePolicy accepts a policy name; empty means Skip
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return Skip, nil
+	case Skip, Follow, Link:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown symlink policy %q (want skip, follow or link)", s)
+}
+
+// Label is the user-facing name of the policy
+func (p Policy) Label() string {
+	switch p {
+	case Follow:
+		return "Follow"
+	case Link:
+		return "Store link"
+	}
+	return "Skip"
+}
+
+// PolicyForLabel is the inverse of Label
+func PolicyForLabel(label string) Policy {
+	for _, p := range Policies {
+		if p.Label() == label {
+			return p
+		}
+	}
+	return Skip
+}
+
+// Options configures a walk
+type Options struct {
+	Symlinks Policy
+	// Exclude, when set, drops a path (and for directories everything below it)
+	Exclude func(path string, isDir bool) bool
+}
+
+// Walk calls fn for root and everything below it in lexical order, like
+// filepath.Walk. Paths are reported as reached, i.e. through the link when
+// following; info describes the link target under Follow and the link itself
+// under Link. fn may return filepath.SkipDir or filepath.SkipAll.
+func Walk(root string, opts Options, fn filepath.WalkFunc) error {
+	w := &walker{opts: opts, fn: fn, seen: newTracker(opts.Symlinks)}
+	info, err := os.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = w.visit(root, realPath(root), info)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+type walker struct {
+	opts Options
+	fn   filepath.WalkFunc
+	seen *tracker
+}
+
+// visit reports path and descends into it; real is its resolved location
+func (w *walker) visit(path, real string, info fs.FileInfo) error {
+	if !w.seen.first(real) {
+		return nil // loop or second route to the same place
+	}
+	if err := w.fn(path, info, nil); err != nil || !info.IsDir() {
+		return err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return w.fn(path, info, err)
+	}
+	for _, e := range entries {
+		child := filepath.Join(path, e.Name())
+		ci, err := resolve(w.opts.Symlinks, child, e)
+		if err != nil {
+			if err := w.fn(child, nil, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		if ci == nil || (w.opts.Exclude != nil && w.opts.Exclude(child, ci.IsDir())) {
+			continue
+		}
+		childLoc := ""
+		if w.seen != nil {
+			childLoc = childReal(real, child, e)
+		}
+		if err := w.visit(child, childLoc, ci); err != nil {
+			if err == filepath.SkipDir && ci.IsDir() {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the info to report for an entry, or nil when the policy drops it
+func resolve(policy Policy, path string, e fs.DirEntry) (fs.FileInfo, error) {
+	if e.Type()&fs.ModeSymlink == 0 {
+		return e.Info()
+	}
+	switch policy {
+	case Follow:
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, nil // dangling link: nothing to follow
+		}
+		return info, nil
+	case Link:
+		return os.Lstat(path)
+	}
+	return nil, nil
+}
+
+// tracker remembers resolved locations. Only following links can lead to the
+// same place twice, so the other policies skip the bookkeeping.
+type tracker struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func newTracker(policy Policy) *tracker {
+	if policy != Follow {
+		return nil
+	}
+	return &tracker{seen: map[string]bool{}}
+}
+
+// first reports whether real is visited for the first time
+func (t *tracker) first(real string) bool {
+	if t == nil || real == "" {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.seen[real] {
+		return false
+	}
+	t.seen[real] = true
+	return true
+}
+
+// realPath resolves path to an absolute location without links ("" if it cannot)
+func realPath(path string) string {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return ""
+	}
+	if abs, err := filepath.Abs(real); err == nil {
+		return abs
+	}
+	return real
+}
+
+// childReal derives an entry's resolved location from its parent's; only links need resolving
+func childReal(parentReal, child string, e fs.DirEntry) string {
+	if e.Type()&fs.ModeSymlink != 0 || parentReal == "" {
+		return realPath(child)
+	}
+	return filepath.Join(parentReal, e.Name())
+}
+
+// IsLink reports whether info describes a symbolic link rather than its target
+func IsLink(info fs.FileInfo) bool {
+	return info != nil && info.Mode()&fs.ModeSymlink != 0
+}
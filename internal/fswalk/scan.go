@@ -0,0 +1,257 @@
+package fswalk
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+)
+
+// Stats counts what a scan has found so far
+type Stats struct {
+	Files, Dirs, Bytes int64
+}
+
+// Entry is a file found by Scan
+type Entry struct {
+	Path string
+	Info fs.FileInfo
+	real string // resolved location when following links
+}
+
+// ScanOptions configures Scan
+type ScanOptions struct {
+	Options
+	Workers  int              // concurrent directory readers; 0 uses the CPU count
+	Canceled func() bool      // polled between directories
+	Progress func(Stats)      // called about every 150 ms from another goroutine
+	Keep     func(Entry) bool // filters the returned files; nil keeps all
+}
+
+// progressInterval is how often Scan reports progress
+const progressInterval = 150 * time.Millisecond
+
+// Scan lists every non-directory below root using several goroutines, which
+// keeps the pre-scan of huge trees short, and returns them sorted by path.
+// Unreadable entries are skipped; the first such error is returned along with
+// the files that could be listed. A canceled scan returns apperr.ErrCanceled.
+func Scan(root string, opts ScanOptions) ([]Entry, Stats, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	sc := &scanner{opts: opts, follow: opts.Symlinks == Follow}
+	if !info.IsDir() {
+		sc.add(root, info)
+		return sc.files, sc.snapshot(), nil
+	}
+	sc.cond = sync.NewCond(&sc.mu)
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	stop := make(chan struct{})
+	if opts.Progress != nil {
+		go func() {
+			t := time.NewTicker(progressInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-t.C:
+					opts.Progress(sc.snapshot())
+				}
+			}
+		}()
+	}
+
+	sc.push(&dirJob{path: root, real: realPath(root)})
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sc.work()
+		}()
+	}
+	wg.Wait()
+	close(stop)
+
+	stats := sc.snapshot()
+	if opts.Progress != nil {
+		opts.Progress(stats)
+	}
+	if sc.canceled.Load() {
+		return nil, stats, apperr.ErrCanceled
+	}
+	sort.Slice(sc.files, func(i, j int) bool { return sc.files[i].Path < sc.files[j].Path })
+	if sc.follow {
+		sc.files = dedupe(sc.files)
+	}
+	return sc.files, stats, sc.err
+}
+
+// dedupe keeps the first path (in sorted order) of every file reached through
+// several links, so which name survives does not depend on goroutine timing
+func dedupe(files []Entry) []Entry {
+	seen := map[string]bool{}
+	out := files[:0]
+	for _, f := range files {
+		if f.real != "" && seen[f.real] {
+			continue
+		}
+		seen[f.real] = true
+		out = append(out, f)
+	}
+	return out
+}
+
+type dirJob struct {
+	path, real string
+	parent     *dirJob
+}
+
+// loops reports whether the directory is one of its own ancestors
+func (j *dirJob) loops() bool {
+	if j.real == "" {
+		return false
+	}
+	for p := j.parent; p != nil; p = p.parent {
+		if p.real == j.real {
+			return true
+		}
+	}
+	return false
+}
+
+type scanner struct {
+	opts   ScanOptions
+	follow bool
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*dirJob
+	pending int // directories queued or being read
+	files   []Entry
+	err     error
+
+	filesN, dirsN, bytesN atomic.Int64
+	canceled              atomic.Bool
+}
+
+func (sc *scanner) snapshot() Stats {
+	return Stats{Files: sc.filesN.Load(), Dirs: sc.dirsN.Load(), Bytes: sc.bytesN.Load()}
+}
+
+func (sc *scanner) push(j *dirJob) {
+	sc.mu.Lock()
+	sc.queue = append(sc.queue, j)
+	sc.pending++
+	sc.mu.Unlock()
+	sc.cond.Signal()
+}
+
+// next blocks for a directory to read; ok is false once the scan is over
+func (sc *scanner) next() (*dirJob, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for len(sc.queue) == 0 && sc.pending > 0 {
+		sc.cond.Wait()
+	}
+	if len(sc.queue) == 0 {
+		return nil, false
+	}
+	j := sc.queue[len(sc.queue)-1] // depth-first keeps the queue short
+	sc.queue = sc.queue[:len(sc.queue)-1]
+	return j, true
+}
+
+func (sc *scanner) done() {
+	sc.mu.Lock()
+	sc.pending--
+	finished := sc.pending == 0
+	sc.mu.Unlock()
+	if finished {
+		sc.cond.Broadcast()
+	}
+}
+
+func (sc *scanner) fail(err error) {
+	sc.mu.Lock()
+	if sc.err == nil {
+		sc.err = err
+	}
+	sc.mu.Unlock()
+}
+
+func (sc *scanner) add(path string, info fs.FileInfo) {
+	sc.addEntry(Entry{Path: path, Info: info})
+}
+
+func (sc *scanner) addEntry(e Entry) {
+	info := e.Info
+	sc.filesN.Add(1)
+	sc.bytesN.Add(info.Size())
+	if sc.opts.Keep != nil && !sc.opts.Keep(e) {
+		return
+	}
+	sc.mu.Lock()
+	sc.files = append(sc.files, e)
+	sc.mu.Unlock()
+}
+
+func (sc *scanner) work() {
+	for {
+		j, ok := sc.next()
+		if !ok {
+			return
+		}
+		if !sc.canceled.Load() && sc.opts.Canceled != nil && sc.opts.Canceled() {
+			sc.canceled.Store(true)
+		}
+		if !sc.canceled.Load() {
+			sc.read(j)
+		}
+		sc.done()
+	}
+}
+
+// read lists one directory, queueing its subdirectories
+func (sc *scanner) read(j *dirJob) {
+	if j.loops() {
+		return
+	}
+	sc.dirsN.Add(1)
+	entries, err := os.ReadDir(j.path)
+	if err != nil {
+		sc.fail(err)
+	}
+	for _, e := range entries {
+		child := filepath.Join(j.path, e.Name())
+		info, err := resolve(sc.opts.Symlinks, child, e)
+		if err != nil {
+			sc.fail(err)
+			continue
+		}
+		if info == nil || (sc.opts.Exclude != nil && sc.opts.Exclude(child, info.IsDir())) {
+			continue
+		}
+		real := ""
+		if sc.follow {
+			real = childReal(j.real, child, e)
+		}
+		if info.IsDir() {
+			sc.push(&dirJob{path: child, real: real, parent: j})
+			continue
+		}
+		sc.addEntry(Entry{Path: child, Info: info, real: real})
+	}
+}
@@ -0,0 +1,118 @@
+package gnupg
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Oldest supported GnuPG release: --status-fd progress, pinentry-less
+// --passphrase in batch mode and AEAD-free defaults all behave from 2.2 on
+const (
+	MinMajor = 2
+	MinMinor = 2
+)
+
+// ErrUnsupportedVersion means the gpg binary is older than MinMajor.MinMinor
+var ErrUnsupportedVersion = fmt.Errorf("GnuPG %d.%d or newer is required", MinMajor, MinMinor)
+
+// Binary is a located and version-checked gpg executable
+type Binary struct {
+	Path    string
+	Version string // e.g. "2.2.40"
+}
+
+// String returns "path (version)" for diagnostics
+func (b *Binary) String() string { return b.Path + " (" + b.Version + ")" }
+
+var (
+	locateMu sync.Mutex
+	located  = map[string]*Binary{}
+)
+
+// Locate returns the gpg binary at path, or the first one found on the system
+// when path is empty. Successful results are cached per path; use Probe to re-check.
+func Locate(path string) (*Binary, error) {
+	locateMu.Lock()
+	defer locateMu.Unlock()
+	if b, ok := located[path]; ok {
+		return b, nil
+	}
+	b, err := probe(path)
+	if err != nil {
+		return nil, err
+	}
+	located[path] = b
+	return b, nil
+}
+
+// Probe locates and version-checks gpg bypassing the cache, then refreshes it
+func Probe(path string) (*Binary, error) {
+	b, err := probe(path)
+	locateMu.Lock()
+	defer locateMu.Unlock()
+	if err != nil {
+		delete(located, path)
+	} else {
+		located[path] = b
+	}
+	return b, err
+}
+
+func probe(path string) (*Binary, error) {
+	resolved := path
+	if resolved == "" {
+		var err error
+		if resolved, err = findGPGExecutable(); err != nil {
+			return nil, err
+		}
+	} else if p, err := exec.LookPath(resolved); err == nil {
+		resolved = p
+	} else {
+		return nil, fmt.Errorf("GPG not usable at %s: %w", path, err)
+	}
+
+	out, err := exec.Command(resolved, "--version").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GPG version from %s: %w", resolved, err)
+	}
+	version, err := parseVersion(string(out))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", resolved, err)
+	}
+	b := &Binary{Path: resolved, Version: version}
+	if !atLeast(version, MinMajor, MinMinor) {
+		return b, fmt.Errorf("%s is GnuPG %s: %w", resolved, version, ErrUnsupportedVersion)
+	}
+	return b, nil
+}
+
+// versionLine matches the first line of gpg --version, "gpg (GnuPG) 2.2.40"
+var versionLine = regexp.MustCompile(`^gpg[^ ]* \(GnuPG[^)]*\) (\d+\.\d+(?:\.\d+)?)`)
+
+func parseVersion(output string) (string, error) {
+	first, _, _ := strings.Cut(output, "\n")
+	m := versionLine.FindStringSubmatch(strings.TrimSpace(first))
+	if m == nil {
+		return "", errors.New("not a GnuPG executable")
+	}
+	return m[1], nil
+}
+
+// atLeast reports whether version is major.minor or newer
+func atLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	maj, err1 := strconv.Atoi(parts[0])
+	min, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return maj > major || (maj == major && min >= minor)
+}
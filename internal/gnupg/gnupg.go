@@ -1,7 +1,6 @@
 package gnupg
 
 import (
-	"bytes"
 	"crypto/rand"
 	"fmt"
 	"io"
@@ -11,6 +10,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
 )
 
 // GnuPGCipher provides GnuPG encryption/decryption capabilities
@@ -29,7 +30,9 @@ type GnuPGOptions struct {
 	ArmorOutput    bool   // ASCII armored output
 	UseSymmetric   bool   // Use symmetric encryption (password-based)
 	KeyID          string // Key ID for asymmetric encryption
+	Recipients     []string // additional key IDs or fingerprints for asymmetric encryption
 	TrustModel     string // pgp, classic, direct, always, auto
+	Progress       ProgressFunc // receives byte progress while gpg runs; may be nil
 }
 
 // DefaultGnuPGOptions returns sensible defaults
@@ -43,19 +46,25 @@ func DefaultGnuPGOptions() *GnuPGOptions {
 	}
 }
 
-// NewGnuPGCipher creates a new GnuPG cipher instance
+// NewGnuPGCipher creates a new GnuPG cipher instance using the gpg found on the system
 func NewGnuPGCipher() (*GnuPGCipher, error) {
+	return NewGnuPGCipherAt("")
+}
+
+// NewGnuPGCipherAt creates a GnuPG cipher using the gpg binary at gpgPath
+// (empty searches the system); the binary must be GnuPG 2.2 or newer
+func NewGnuPGCipherAt(gpgPath string) (*GnuPGCipher, error) {
 	gpg := &GnuPGCipher{}
 	
 	// Find GPG executable
-	var err error
-	gpg.gpgPath, err = findGPGExecutable()
+	bin, err := Locate(gpgPath)
 	if err != nil {
-		return nil, fmt.Errorf("GPG not found: %w", err)
+		return nil, fmt.Errorf("GPG unavailable: %w", err)
 	}
+	gpg.gpgPath = bin.Path
 	
 	// Create temporary directory
-	gpg.tempDir, err = os.MkdirTemp("", "hadescrypt_gpg_*")
+	gpg.tempDir, err = securetemp.MkdirTemp("", "hadescrypt_gpg_*")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
@@ -205,8 +214,13 @@ func (g *GnuPGCipher) EncryptFile(inputPath, outputPath string, options *GnuPGOp
 		if g.passphrase != "" {
 			args = append(args, "--passphrase", g.passphrase)
 		}
-	} else if options.KeyID != "" {
-		args = append(args, "--encrypt", "--recipient", options.KeyID)
+	} else if options.KeyID != "" || len(options.Recipients) > 0 {
+		args = append(args, "--encrypt")
+		for _, r := range append([]string{options.KeyID}, options.Recipients...) {
+			if r != "" {
+				args = append(args, "--recipient", r)
+			}
+		}
 	} else {
 		return fmt.Errorf("either symmetric encryption or recipient key ID must be specified")
 	}
@@ -217,7 +231,16 @@ func (g *GnuPGCipher) EncryptFile(inputPath, outputPath string, options *GnuPGOp
 	
 	args = append(args, "--output", outputPath, inputPath)
 	
-	// Execute GPG command
+	if err := g.run(args, options); err != nil {
+		return fmt.Errorf("GPG encryption failed: %w", err)
+	}
+	return nil
+}
+
+// run executes gpg with status output on stderr, feeding progress to
+// options.Progress and classifying failures
+func (g *GnuPGCipher) run(args []string, options *GnuPGOptions) error {
+	args = append([]string{"--status-fd", "2", "--enable-progress-filter"}, args...)
 	cmd := exec.Command(g.gpgPath, args...)
 	
 	// Set environment for better security
@@ -226,14 +249,18 @@ func (g *GnuPGCipher) EncryptFile(inputPath, outputPath string, options *GnuPGOp
 	env = append(env, "DISPLAY=")
 	cmd.Env = env
 	
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	
-	err := cmd.Run()
+	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return fmt.Errorf("GPG encryption failed: %w, stderr: %s", err, stderr.String())
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	parser := &statusParser{onProgress: options.Progress}
+	parser.consume(stderr)
+	if err := cmd.Wait(); err != nil {
+		return parser.classify(err)
 	}
-	
 	return nil
 }
 
@@ -262,23 +289,9 @@ func (g *GnuPGCipher) DecryptFile(inputPath, outputPath string, options *GnuPGOp
 	
 	args = append(args, "--output", outputPath, inputPath)
 	
-	// Execute GPG command
-	cmd := exec.Command(g.gpgPath, args...)
-	
-	// Set environment
-	env := os.Environ()
-	env = append(env, "GPG_TTY=")
-	env = append(env, "DISPLAY=")
-	cmd.Env = env
-	
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	
-	err := cmd.Run()
-	if err != nil {
-		return fmt.Errorf("GPG decryption failed: %w, stderr: %s", err, stderr.String())
+	if err := g.run(args, options); err != nil {
+		return fmt.Errorf("GPG decryption failed: %w", err)
 	}
-	
 	return nil
 }
 
@@ -0,0 +1,208 @@
+package gnupg
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key is a key from the user's keyring
+type Key struct {
+	Fingerprint string
+	KeyID       string // long key ID of the primary key
+	UserIDs     []string
+	Created     time.Time
+	Expires     time.Time // zero when the key does not expire
+	Validity    string    // gpg validity letter: u ultimate, f full, m marginal, - unknown, ...
+	Revoked     bool
+	Expired     bool
+	Disabled    bool
+	// CanEncrypt and CanSign are set when the key or a usable subkey can encrypt or sign
+	CanEncrypt bool
+	CanSign    bool
+}
+
+// Name returns the primary user ID, or the key ID when there is none
+func (k Key) Name() string {
+	if len(k.UserIDs) > 0 {
+		return k.UserIDs[0]
+	}
+	return k.KeyID
+}
+
+// Usable reports whether the key can be a recipient
+func (k Key) Usable() bool { return k.CanEncrypt && !k.Revoked && !k.Expired && !k.Disabled }
+
+// Matches reports whether query (case-insensitive) occurs in a user ID or the
+// key ID, or is a suffix of the fingerprint
+func (k Key) Matches(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return true
+	}
+	for _, uid := range k.UserIDs {
+		if strings.Contains(strings.ToLower(uid), query) {
+			return true
+		}
+	}
+	hex := strings.TrimPrefix(strings.ReplaceAll(query, " ", ""), "0x")
+	return strings.Contains(strings.ToLower(k.KeyID), hex) || strings.HasSuffix(strings.ToLower(k.Fingerprint), hex)
+}
+
+// ListKeys returns the public keys of the gpg binary at gpgPath (empty searches the system)
+func ListKeys(gpgPath string) ([]Key, error) {
+	return listKeys(gpgPath, "--list-keys")
+}
+
+// ListSecretKeys returns the keys whose secret part is available, i.e. the possible signers
+func ListSecretKeys(gpgPath string) ([]Key, error) {
+	return listKeys(gpgPath, "--list-secret-keys")
+}
+
+func listKeys(gpgPath, command string) ([]Key, error) {
+	bin, err := Locate(gpgPath)
+	if err != nil {
+		return nil, fmt.Errorf("GPG unavailable: %w", err)
+	}
+	out, err := exec.Command(bin.Path, "--batch", command, "--with-colons", "--fixed-list-mode").Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return nil, fmt.Errorf("list keys: %w: %s", err, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, fmt.Errorf("list keys: %w", err)
+	}
+	return parseColons(string(out)), nil
+}
+
+// parseColons parses gpg --with-colons key listings (see doc/DETAILS in GnuPG)
+func parseColons(listing string) []Key {
+	var keys []Key
+	var cur *Key
+	// fingerprints follow the pub or sub record they belong to
+	inPrimary := false
+	for _, line := range strings.Split(listing, "\n") {
+		f := strings.Split(strings.TrimRight(line, "\r"), ":")
+		if len(f) < 2 {
+			continue
+		}
+		switch f[0] {
+		case "pub", "sec":
+			keys = append(keys, Key{})
+			cur = &keys[len(keys)-1]
+			inPrimary = true
+			cur.KeyID = field(f, 4)
+			cur.Created = colonTime(field(f, 5))
+			cur.Expires = colonTime(field(f, 6))
+			validity := field(f, 1)
+			cur.Validity = validity
+			cur.Revoked = validity == "r"
+			cur.Expired = validity == "e"
+			// field 12 holds the usable capabilities of the whole key in upper case
+			caps := field(f, 11)
+			cur.CanEncrypt = strings.Contains(caps, "E")
+			cur.CanSign = strings.Contains(caps, "S")
+			cur.Disabled = strings.Contains(caps, "D")
+		case "sub", "ssb":
+			inPrimary = false
+		case "fpr":
+			if cur != nil && inPrimary && cur.Fingerprint == "" {
+				cur.Fingerprint = field(f, 9)
+			}
+		case "uid":
+			if cur != nil && field(f, 1) != "r" {
+				cur.UserIDs = append(cur.UserIDs, unescapeColon(field(f, 9)))
+			}
+		}
+	}
+	return keys
+}
+
+func field(f []string, i int) string {
+	if i < len(f) {
+		return f[i]
+	}
+	return ""
+}
+
+// colonTime parses a listing date: seconds since the epoch, or an ISO 8601 timestamp
+func colonTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0)
+	}
+	if t, err := time.Parse("20060102T150405", s); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// unescapeColon decodes the \xHH escapes gpg uses in user IDs
+func unescapeColon(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if v, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// Keyring caches the parsed key listing of one gpg binary
+type Keyring struct {
+	mu      sync.Mutex
+	gpgPath string
+	keys    []Key
+	loaded  time.Time
+}
+
+// NewKeyring returns an empty cache for the gpg binary at gpgPath
+func NewKeyring(gpgPath string) *Keyring { return &Keyring{gpgPath: gpgPath} }
+
+// Keys returns the cached listing, loading it on first use
+func (r *Keyring) Keys() ([]Key, error) {
+	r.mu.Lock()
+	loaded := !r.loaded.IsZero()
+	keys := r.keys
+	r.mu.Unlock()
+	if loaded {
+		return keys, nil
+	}
+	return r.Refresh()
+}
+
+// Refresh re-reads the keyring
+func (r *Keyring) Refresh() ([]Key, error) {
+	keys, err := ListKeys(r.gpgPath)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.keys, r.loaded = keys, time.Now()
+	r.mu.Unlock()
+	return keys, nil
+}
+
+// Lookup returns the cached key with the given fingerprint
+func (r *Keyring) Lookup(fingerprint string) (Key, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if strings.EqualFold(k.Fingerprint, fingerprint) {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
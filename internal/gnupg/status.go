@@ -0,0 +1,138 @@
+package gnupg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Errors recognized from gpg's machine-readable status output
+var (
+	ErrBadPassphrase = errors.New("bad passphrase")
+	ErrCorrupt       = errors.New("encrypted data is damaged or has been manipulated")
+	ErrNoData        = errors.New("no valid OpenPGP data found")
+	ErrNoSecretKey   = errors.New("secret key not available")
+	ErrRecipient     = errors.New("recipient key missing or unusable")
+)
+
+// ProgressFunc receives byte progress parsed from gpg's PROGRESS status lines
+type ProgressFunc func(done, total int64)
+
+const statusPrefix = "[GNUPG:] "
+
+// statusParser reads gpg's stderr with --status-fd 2: status lines are
+// interpreted, human-readable messages are kept for error reports
+type statusParser struct {
+	onProgress ProgressFunc
+	messages   strings.Builder
+
+	badPassphrase, badMDC, noData, noSecretKey, invalidRecipient, decryptFailed bool
+	// began is set once the session key was accepted and plaintext started flowing
+	began bool
+	// signature collects the signature status lines of a verify run
+	signature Signature
+}
+
+// consume reads r until EOF
+func (p *statusParser) consume(r io.Reader) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, statusPrefix) {
+			p.messages.WriteString(line)
+			p.messages.WriteByte('\n')
+			continue
+		}
+		p.status(strings.Fields(strings.TrimPrefix(line, statusPrefix)))
+	}
+}
+
+func (p *statusParser) status(f []string) {
+	if len(f) == 0 {
+		return
+	}
+	switch f[0] {
+	case "PROGRESS":
+		p.progress(f[1:])
+	case "BAD_PASSPHRASE":
+		p.badPassphrase = true
+	case "ERROR":
+		// e.g. "ERROR symkey_decrypt.maybe_error 11_BAD_PASSPHRASE"
+		if len(f) > 2 && strings.HasSuffix(f[2], "_BAD_PASSPHRASE") {
+			p.badPassphrase = true
+		}
+	case "BADMDC":
+		p.badMDC = true
+	case "NODATA":
+		p.noData = true
+	case "INV_RECP":
+		p.invalidRecipient = true
+	case "NO_SECKEY":
+		p.noSecretKey = true
+	case "BEGIN_DECRYPTION":
+		p.began = true
+	case "DECRYPTION_FAILED":
+		p.decryptFailed = true
+	default:
+		p.signature.status(f)
+	}
+}
+
+// progress handles "PROGRESS <what> <char> <cur> <total> [<units>]"
+func (p *statusParser) progress(f []string) {
+	if p.onProgress == nil || len(f) < 4 {
+		return
+	}
+	cur, err1 := strconv.ParseInt(f[2], 10, 64)
+	total, err2 := strconv.ParseInt(f[3], 10, 64)
+	if err1 != nil || err2 != nil || total <= 0 {
+		return
+	}
+	unit := int64(1)
+	if len(f) > 4 {
+		switch f[4] {
+		case "KiB":
+			unit = 1 << 10
+		case "MiB":
+			unit = 1 << 20
+		case "GiB":
+			unit = 1 << 30
+		case "TiB":
+			unit = 1 << 40
+		}
+	}
+	p.onProgress(cur*unit, total*unit)
+}
+
+// classify turns a failed gpg run into an error wrapping one of the sentinels when possible
+func (p *statusParser) classify(runErr error) error {
+	detail := strings.TrimSpace(p.messages.String())
+	if detail == "" {
+		detail = runErr.Error()
+	}
+	var kind error
+	switch {
+	case p.badMDC:
+		kind = ErrCorrupt
+	case p.badPassphrase:
+		kind = ErrBadPassphrase
+	case p.noSecretKey:
+		kind = ErrNoSecretKey
+	case p.invalidRecipient:
+		kind = ErrRecipient
+	case p.began:
+		// garbage after a good session key (bad packets, zlib errors) is damage, not a foreign file
+		kind = ErrCorrupt
+	case p.noData:
+		kind = ErrNoData
+	case p.decryptFailed:
+		kind = ErrCorrupt
+	default:
+		return fmt.Errorf("%w: %s", runErr, detail)
+	}
+	return fmt.Errorf("%w: %s", kind, detail)
+}
@@ -0,0 +1,153 @@
+package gnupg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBadSignature means a signature did not verify or its key is unusable
+var ErrBadSignature = errors.New("signature verification failed")
+
+// Signature is the outcome of verifying one OpenPGP signature
+type Signature struct {
+	// Status is gpg's verdict: GOODSIG, BADSIG, EXPSIG, EXPKEYSIG, REVKEYSIG or ERRSIG
+	Status      string
+	KeyID       string
+	Signer      string // primary user ID of the signing key, when known
+	Fingerprint string
+	Created     time.Time
+	Trust       string // TRUST_ULTIMATE, TRUST_FULLY, ... without the prefix; empty when not reported
+	MissingKey  bool   // the signing key is not in the keyring
+}
+
+// Good reports whether the signature verified with a valid key
+func (s *Signature) Good() bool { return s != nil && s.Status == "GOODSIG" }
+
+// status records the signature status lines of a verify run
+func (s *Signature) status(f []string) {
+	switch f[0] {
+	case "GOODSIG", "BADSIG", "EXPSIG", "EXPKEYSIG", "REVKEYSIG":
+		s.Status = f[0]
+		if len(f) > 1 {
+			s.KeyID = f[1]
+		}
+		if len(f) > 2 {
+			s.Signer = strings.Join(f[2:], " ")
+		}
+	case "ERRSIG":
+		s.Status = f[0]
+		if len(f) > 1 {
+			s.KeyID = f[1]
+		}
+	case "NO_PUBKEY":
+		s.MissingKey = true
+	case "VALIDSIG":
+		if len(f) > 1 {
+			s.Fingerprint = f[1]
+		}
+		if len(f) > 3 {
+			if n, err := strconv.ParseInt(f[3], 10, 64); err == nil {
+				s.Created = time.Unix(n, 0)
+			}
+		}
+	default:
+		if trust, ok := strings.CutPrefix(f[0], "TRUST_"); ok {
+			s.Trust = trust
+		}
+	}
+}
+
+// Armor wraps binary data in an OpenPGP ASCII armor ("ARMORED FILE")
+func (g *GnuPGCipher) Armor(data []byte) ([]byte, error) {
+	out, _, err := g.pipe(data, "--enarmor")
+	if err != nil {
+		return nil, fmt.Errorf("GPG armor failed: %w", err)
+	}
+	return out, nil
+}
+
+// Dearmor removes the ASCII armor from any armored OpenPGP block
+func (g *GnuPGCipher) Dearmor(armored []byte) ([]byte, error) {
+	out, _, err := g.pipe(armored, "--dearmor")
+	if err != nil {
+		return nil, fmt.Errorf("GPG dearmor failed: %w", err)
+	}
+	return out, nil
+}
+
+// Clearsign signs text with the secret key signer (key ID, fingerprint or
+// email; empty uses gpg's default key). The passphrase set with SetPassphrase
+// unlocks the key; without one gpg-agent asks through its pinentry.
+func (g *GnuPGCipher) Clearsign(text []byte, signer string) ([]byte, error) {
+	args := []string{"--clearsign"}
+	if signer != "" {
+		args = append(args, "--local-user", signer)
+	}
+	if g.passphrase != "" {
+		args = append(args, "--pinentry-mode", "loopback", "--passphrase", g.passphrase)
+	}
+	out, _, err := g.pipe(text, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GPG clearsign failed: %w", err)
+	}
+	return out, nil
+}
+
+// Verify checks a clearsigned message and returns the signed text. A signature
+// that does not verify returns the details with an error wrapping ErrBadSignature.
+func (g *GnuPGCipher) Verify(signed []byte) ([]byte, *Signature, error) {
+	out, p, err := g.pipe(signed, "--decrypt")
+	sig := p.signature
+	if sig.Status == "" {
+		if err == nil {
+			err = errors.New("no signature found")
+		}
+		return nil, nil, fmt.Errorf("GPG verify failed: %w", err)
+	}
+	if !sig.Good() {
+		reason := map[string]string{
+			"BADSIG":    "bad signature",
+			"EXPSIG":    "signature expired",
+			"EXPKEYSIG": "signing key expired",
+			"REVKEYSIG": "signing key revoked",
+			"ERRSIG":    "signature could not be checked",
+		}[sig.Status]
+		if sig.MissingKey {
+			reason = "public key " + sig.KeyID + " not in keyring"
+		}
+		return out, &sig, fmt.Errorf("%w: %s", ErrBadSignature, reason)
+	}
+	return out, &sig, nil
+}
+
+// pipe runs gpg on in with the given command arguments and returns its stdout
+func (g *GnuPGCipher) pipe(in []byte, command ...string) ([]byte, *statusParser, error) {
+	if !g.initialized {
+		return nil, &statusParser{}, fmt.Errorf("GnuPG cipher not initialized")
+	}
+	args := append([]string{"--status-fd", "2", "--batch", "--yes", "--output", "-"}, command...)
+	cmd := exec.Command(g.gpgPath, args...)
+	cmd.Env = append(os.Environ(), "GPG_TTY=", "DISPLAY=")
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	p := &statusParser{}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, p, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, p, err
+	}
+	p.consume(stderr)
+	if err := cmd.Wait(); err != nil {
+		return stdout.Bytes(), p, p.classify(err)
+	}
+	return stdout.Bytes(), p, nil
+}
@@ -0,0 +1,256 @@
+// Package ignore implements ".hadesignore" files: gitignore-syntax exclusion
+// lists placed in a folder (and optionally its subfolders) that recursive
+// encryption and folder archiving consult before touching a path.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// FileName is the name of an ignore file
+const FileName = ".hadesignore"
+
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher answers whether paths below a root are excluded. Ignore files are
+// read lazily from every directory between the root and the path; rules in
+// deeper files and later lines take precedence, as in git.
+type Matcher struct {
+	root  string
+	mu    sync.Mutex
+	rules map[string][]rule // directory (relative, slash-separated, "" for root) → its rules
+}
+
+// New returns a matcher for the tree rooted at root
+func New(root string) *Matcher {
+	return &Matcher{root: filepath.Clean(root), rules: map[string][]rule{}}
+}
+
+// Match reports whether path (inside the root) is excluded, either directly or
+// because one of its parent directories is. The ignore files themselves are
+// never excluded so archives keep them.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if !isDir && filepath.Base(rel) == FileName {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchOne(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchOne(rel, isDir)
+}
+
+// matchOne applies the rules of every ancestor directory to rel without looking at parents
+func (m *Matcher) matchOne(rel string, isDir bool) bool {
+	ignored := false
+	dir := ""
+	rest := rel
+	for {
+		for _, r := range m.rulesFor(dir) {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.re.MatchString(rest) {
+				ignored = !r.negate
+			}
+		}
+		i := strings.IndexByte(rest, '/')
+		if i < 0 {
+			return ignored
+		}
+		if dir == "" {
+			dir = rest[:i]
+		} else {
+			dir += "/" + rest[:i]
+		}
+		rest = rest[i+1:]
+	}
+}
+
+func (m *Matcher) rulesFor(dir string) []rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+	rules, _ := parseFile(filepath.Join(m.root, filepath.FromSlash(dir), FileName))
+	m.rules[dir] = rules
+	return rules
+}
+
+func parseFile(path string) ([]rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var rules []rule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if r, ok := parseLine(sc.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules, sc.Err()
+}
+
+// parseLine converts one gitignore line into a rule
+func parseLine(line string) (rule, bool) {
+	line = strings.TrimRight(line, "\r")
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return rule{}, false
+	}
+	var r rule
+	switch {
+	case line[0] == '!':
+		r.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+	// A slash at the start or in the middle anchors the pattern to the ignore file's directory
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := translate(line)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return rule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// translate turns a glob into a regular expression: * and ? stay within one
+// path segment, ** spans segments, [...] is a character class
+func translate(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '/' && glob[i:] == "/**":
+			b.WriteString("/.*")
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			j := strings.IndexByte(glob[i+1:], ']')
+			if j < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += j + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// ExtensionPattern returns a rule matching files with ext in any letter case,
+// e.g. "*.[mM][pP]4" for ".mp4"
+func ExtensionPattern(ext string) string {
+	var b strings.Builder
+	b.WriteString("*.")
+	for _, r := range strings.TrimPrefix(ext, ".") {
+		lower, upper := strings.ToLower(string(r)), strings.ToUpper(string(r))
+		switch {
+		case lower != upper:
+			b.WriteString("[" + lower + upper + "]")
+		case strings.ContainsRune(`*?[]\`, r):
+			b.WriteString(`\` + string(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Append adds the rules not yet present to the ignore file in dir, creating it
+// when needed, and returns how many were added
+func Append(dir string, rules []string) (int, error) {
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	have := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		have[strings.TrimSpace(line)] = true
+	}
+	var add strings.Builder
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		add.WriteString("\n")
+	}
+	n := 0
+	for _, r := range rules {
+		if have[r] {
+			continue
+		}
+		have[r] = true
+		add.WriteString(r + "\n")
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteString(add.String()); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return n, f.Close()
+}
@@ -0,0 +1,472 @@
+// Package lanshare sends files directly between two HadesCrypt instances on
+// the same network. A receiver announces itself over mDNS and accepts TLS
+// connections; both sides prove they know a short pairing code with SPAKE2,
+// bound to the receiver's TLS certificate, before any file data moves.
+package lanshare
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/mdns"
+
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/pake"
+)
+
+// ServiceType is the mDNS service receivers announce
+const ServiceType = "_hadescrypt._tcp"
+
+const (
+	magic        = "HCLAN1"
+	maxAttempts  = 3
+	maxHeaderLen = 64 << 10
+	ioTimeout    = time.Minute
+)
+
+var (
+	// ErrWrongCode means the peer used a different pairing code
+	ErrWrongCode = pake.ErrWrongCode
+	// ErrTooManyAttempts ends a receive session after repeated wrong codes
+	ErrTooManyAttempts = errors.New("too many wrong pairing codes; start receiving again for a new code")
+)
+
+var randReader = rand.Reader
+
+// Peer is a receiver found on the network
+type Peer struct {
+	Name string
+	Addr string // host:port
+}
+
+// ProgressFunc reports the bytes of the current file moved so far
+type ProgressFunc func(name string, done, total int64)
+
+// fileHeader precedes every file on the wire
+type fileHeader struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// NewCode returns a random eight-digit pairing code such as "4821-0937"
+func NewCode() string {
+	n, err := rand.Int(randReader, big.NewInt(100_000_000))
+	if err != nil {
+		panic(err)
+	}
+	s := fmt.Sprintf("%08d", n.Int64())
+	return s[:4] + "-" + s[4:]
+}
+
+// normalizeCode drops separators and spaces so "4821 0937" equals "4821-0937"
+func normalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, code)
+}
+
+// Discover browses the network for receivers for up to wait
+func Discover(ctx context.Context, wait time.Duration) ([]Peer, error) {
+	entries := make(chan *mdns.ServiceEntry, 32)
+	params := mdns.DefaultParams(ServiceType)
+	params.Entries = entries
+	params.Timeout = wait
+	params.DisableIPv6 = true
+	errc := make(chan error, 1)
+	go func() { errc <- mdns.Query(params); close(entries) }()
+
+	seen := map[string]bool{}
+	var peers []Peer
+	for {
+		select {
+		case <-ctx.Done():
+			return peers, ctx.Err()
+		case e, ok := <-entries:
+			if !ok {
+				return peers, <-errc
+			}
+			if e.AddrV4 == nil || e.Port == 0 {
+				continue
+			}
+			addr := net.JoinHostPort(e.AddrV4.String(), strconv.Itoa(e.Port))
+			if seen[addr] {
+				continue
+			}
+			seen[addr] = true
+			name := strings.TrimSuffix(strings.TrimSuffix(e.Name, "."+ServiceType+".local."), ".")
+			peers = append(peers, Peer{Name: strings.ReplaceAll(name, `\ `, " "), Addr: addr})
+		}
+	}
+}
+
+// Receiver waits for one sender that knows its pairing code
+type Receiver struct {
+	Name     string
+	code     string
+	dir      string
+	ln       net.Listener
+	tls      *tls.Config
+	certHash []byte
+	mdns     *mdns.Server
+	closeMu  sync.Once
+}
+
+// Listen starts a receiver that stores files in dir and announces itself as name
+func Listen(name, dir string) (*Receiver, error) {
+	cert, err := selfSignedCert(name)
+	if err != nil {
+		return nil, fmt.Errorf("create TLS certificate: %w", err)
+	}
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(cert.Certificate[0])
+	r := &Receiver{
+		Name:     name,
+		code:     NewCode(),
+		dir:      dir,
+		ln:       ln,
+		tls:      &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS13},
+		certHash: sum[:],
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	if svc, err := mdns.NewMDNSService(name, ServiceType, "", "", port, localIPs(), []string{"v=1"}); err == nil {
+		// without multicast the sender can still type an address from Addrs
+		r.mdns, _ = mdns.NewServer(&mdns.Config{Zone: svc})
+	}
+	return r, nil
+}
+
+// Code returns the pairing code the sender has to enter
+func (r *Receiver) Code() string { return r.code }
+
+// Addrs lists host:port addresses for senders that cannot use mDNS
+func (r *Receiver) Addrs() []string {
+	port := strconv.Itoa(r.ln.Addr().(*net.TCPAddr).Port)
+	var addrs []string
+	for _, ip := range localIPs() {
+		if ip.To4() != nil {
+			addrs = append(addrs, net.JoinHostPort(ip.String(), port))
+		}
+	}
+	return addrs
+}
+
+// Close stops listening and withdraws the mDNS announcement
+func (r *Receiver) Close() error {
+	var err error
+	r.closeMu.Do(func() {
+		if r.mdns != nil {
+			r.mdns.Shutdown()
+		}
+		err = r.ln.Close()
+	})
+	return err
+}
+
+// Receive accepts connections until one sender pairs and delivers its files,
+// and returns the paths written. Connections with a wrong code are dropped;
+// after maxAttempts of them the session ends with ErrTooManyAttempts.
+func (r *Receiver) Receive(ctx context.Context, onProgress ProgressFunc) ([]string, error) {
+	defer r.Close()
+	stop := context.AfterFunc(ctx, func() { r.Close() })
+	defer stop()
+
+	failures := 0
+	for {
+		conn, err := r.ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		stopConn := context.AfterFunc(ctx, func() { conn.Close() })
+		paths, err := r.serve(conn, onProgress)
+		stopConn()
+		conn.Close()
+		switch {
+		case err == nil:
+			return paths, nil
+		case errors.Is(err, ErrWrongCode):
+			if failures++; failures >= maxAttempts {
+				return nil, ErrTooManyAttempts
+			}
+		case len(paths) > 0 || ctx.Err() != nil:
+			// paired but the transfer broke off
+			return paths, err
+		}
+	}
+}
+
+func (r *Receiver) serve(raw net.Conn, onProgress ProgressFunc) ([]string, error) {
+	conn := tls.Server(raw, r.tls)
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if _, err := pake.Handshake(conn, magic, normalizeCode(r.code), false, r.certHash); err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	var paths []string
+	for {
+		conn.SetReadDeadline(time.Now().Add(ioTimeout))
+		h, err := readHeader(conn)
+		if err != nil {
+			return paths, err
+		}
+		if h == nil {
+			return paths, nil
+		}
+		path, err := receiveFile(conn, r.dir, h, onProgress)
+		status := byte(0)
+		if err != nil {
+			status = 1
+		}
+		conn.Write([]byte{status})
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+}
+
+// Send pairs with the receiver at addr using code and transfers paths in order
+func Send(ctx context.Context, addr, code string, paths []string, onProgress ProgressFunc) error {
+	var d net.Dialer
+	raw, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	defer raw.Close()
+	stop := context.AfterFunc(ctx, func() { raw.Close() })
+	defer stop()
+
+	// the receiver's certificate is self-signed; it is authenticated by the
+	// pairing handshake, which mixes its hash into the SPAKE2 transcript
+	conn := tls.Client(raw, &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS13})
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if err := conn.Handshake(); err != nil {
+		return fmt.Errorf("TLS handshake with %s: %w", addr, err)
+	}
+	sum := sha256.Sum256(conn.ConnectionState().PeerCertificates[0].Raw)
+	if _, err := pake.Handshake(conn, magic, normalizeCode(code), true, sum[:]); err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+
+	for _, p := range paths {
+		if err := sendFile(conn, p, onProgress); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("send %s: %w", filepath.Base(p), err)
+		}
+	}
+	return writeFrame(conn, nil)
+}
+
+func sendFile(conn net.Conn, path string, onProgress ProgressFunc) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	h, _ := json.Marshal(fileHeader{Name: filepath.Base(path), Size: st.Size()})
+	if err := writeFrame(conn, h); err != nil {
+		return err
+	}
+	sum := sha256.New()
+	if err := copyProgress(conn, io.TeeReader(f, sum), conn, filepath.Base(path), st.Size(), onProgress); err != nil {
+		return err
+	}
+	if _, err := conn.Write(sum.Sum(nil)); err != nil {
+		return err
+	}
+	conn.SetReadDeadline(time.Now().Add(ioTimeout))
+	status := make([]byte, 1)
+	if _, err := io.ReadFull(conn, status); err != nil {
+		return err
+	}
+	if status[0] != 0 {
+		return errors.New("the receiver could not store the file")
+	}
+	return nil
+}
+
+func receiveFile(conn net.Conn, dir string, h *fileHeader, onProgress ProgressFunc) (string, error) {
+	name := filepath.Base(filepath.Clean("/" + h.Name))
+	if name == "/" || name == "." || h.Size < 0 {
+		return "", fmt.Errorf("invalid file name %q", h.Name)
+	}
+	part, err := os.CreateTemp(dir, "."+name+".*.part")
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.New()
+	err = copyProgress(io.MultiWriter(part, sum), io.LimitReader(conn, h.Size), conn, name, h.Size, onProgress)
+	if err == nil {
+		err = checkSum(conn, sum)
+	}
+	if cerr := part.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(part.Name())
+		return "", err
+	}
+	dest := format.FreePath(filepath.Join(dir, name))
+	if err := os.Rename(part.Name(), dest); err != nil {
+		os.Remove(part.Name())
+		return "", err
+	}
+	return dest, nil
+}
+
+func checkSum(conn net.Conn, sum hash.Hash) error {
+	want := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(conn, want); err != nil {
+		return err
+	}
+	if !hmac.Equal(want, sum.Sum(nil)) {
+		return errors.New("checksum mismatch; the file was damaged in transit")
+	}
+	return nil
+}
+
+// copyProgress copies exactly total bytes, pushing conn's deadline forward so
+// only a stalled peer times out
+func copyProgress(dst io.Writer, src io.Reader, conn net.Conn, name string, total int64, onProgress ProgressFunc) error {
+	buf := make([]byte, 256<<10)
+	var done int64
+	for done < total {
+		conn.SetDeadline(time.Now().Add(ioTimeout))
+		n, err := src.Read(buf[:min(int64(len(buf)), total-done)])
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			done += int64(n)
+			if onProgress != nil {
+				onProgress(name, done, total)
+			}
+		}
+		if err == io.EOF && done < total {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	return nil
+}
+
+// writeFrame writes a length-prefixed block; an empty block ends the transfer
+func writeFrame(w io.Writer, data []byte) error {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(data)))
+	if _, err := w.Write(n[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// readHeader reads the next file header; nil means the sender is done
+func readHeader(r io.Reader) (*fileHeader, error) {
+	var n [4]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(n[:])
+	if size == 0 {
+		return nil, nil
+	}
+	if size > maxHeaderLen {
+		return nil, errors.New("file header too large")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	var h fileHeader
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("invalid file header: %w", err)
+	}
+	return &h, nil
+}
+
+// localIPs returns the addresses of the up, non-loopback interfaces
+func localIPs() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, _ := iface.Addrs()
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLinkLocalUnicast() {
+				ips = append(ips, ipnet.IP)
+			}
+		}
+	}
+	return ips
+}
+
+// selfSignedCert makes a throwaway certificate for one receive session
+func selfSignedCert(name string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), randReader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(randReader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(randReader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,151 @@
+package lanshare
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listen starts a receiver storing into a temporary directory and returns it
+// with its loopback address
+func listen(t *testing.T) (*Receiver, string) {
+	t.Helper()
+	r, err := Listen("test receiver", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r, net.JoinHostPort("127.0.0.1", strconv.Itoa(r.ln.Addr().(*net.TCPAddr).Port))
+}
+
+type received struct {
+	paths []string
+	err   error
+}
+
+func receive(r *Receiver) <-chan received {
+	done := make(chan received, 1)
+	go func() {
+		paths, err := r.Receive(context.Background(), nil)
+		done <- received{paths, err}
+	}()
+	return done
+}
+
+func TestNewCode(t *testing.T) {
+	if code := NewCode(); !regexp.MustCompile(`^\d{4}-\d{4}$`).MatchString(code) {
+		t.Errorf("NewCode() = %q", code)
+	}
+	if normalizeCode(" 4821 0937 ") != normalizeCode("4821-0937") {
+		t.Error("separators change the code")
+	}
+}
+
+func TestSendReceive(t *testing.T) {
+	r, addr := listen(t)
+	done := receive(r)
+
+	src := t.TempDir()
+	want := map[string][]byte{"a.hadescrypt": bytes.Repeat([]byte("a"), 300<<10+7), "empty.txt": nil}
+	var paths []string
+	for name, data := range want {
+		p := filepath.Join(src, name)
+		if err := os.WriteFile(p, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	// the code is typed with a different separator
+	code := normalizeCode(r.Code())
+	if err := Send(context.Background(), addr, code[:4]+" "+code[4:], paths, nil); err != nil {
+		t.Fatal(err)
+	}
+	res := <-done
+	if res.err != nil || len(res.paths) != len(want) {
+		t.Fatalf("Receive: %v, %v", res.paths, res.err)
+	}
+	for _, p := range res.paths {
+		got, err := os.ReadFile(p)
+		if err != nil || !bytes.Equal(got, want[filepath.Base(p)]) {
+			t.Errorf("%s: %d bytes, %v", filepath.Base(p), len(got), err)
+		}
+	}
+}
+
+func TestWrongCode(t *testing.T) {
+	r, addr := listen(t)
+	done := receive(r)
+	wrong := "0000-0000"
+	if r.Code() == wrong {
+		wrong = "0000-0001"
+	}
+	for i := range maxAttempts {
+		if err := Send(context.Background(), addr, wrong, nil, nil); !errors.Is(err, ErrWrongCode) {
+			t.Fatalf("attempt %d: got %v, want ErrWrongCode", i+1, err)
+		}
+	}
+	select {
+	case res := <-done:
+		if !errors.Is(res.err, ErrTooManyAttempts) {
+			t.Errorf("Receive: got %v, want ErrTooManyAttempts", res.err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Receive kept waiting after the last attempt")
+	}
+}
+
+func TestReceiveFileStaysInDir(t *testing.T) {
+	dir := t.TempDir()
+	a, b := net.Pipe()
+	data := []byte("payload")
+	go func() {
+		a.Write(data)
+		sum := sha256.Sum256(data)
+		a.Write(sum[:])
+	}()
+	h := &fileHeader{Name: "../../escape.txt", Size: int64(len(data))}
+	path, err := receiveFile(b, dir, h, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != dir || filepath.Base(path) != "escape.txt" {
+		t.Errorf("stored at %s, outside %s", path, dir)
+	}
+
+	go func() {
+		a.Write(data)
+		a.Write(make([]byte, sha256.Size))
+	}()
+	if _, err := receiveFile(b, dir, h, nil); err == nil {
+		t.Error("a file with a wrong checksum was stored")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d entries left in the directory, want 1", len(entries))
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	var buf bytes.Buffer
+	h, _ := json.Marshal(fileHeader{Name: "x", Size: 3})
+	writeFrame(&buf, h)
+	writeFrame(&buf, nil)
+	if got, err := readHeader(&buf); err != nil || got == nil || got.Name != "x" || got.Size != 3 {
+		t.Errorf("readHeader: %+v, %v", got, err)
+	}
+	if got, err := readHeader(&buf); err != nil || got != nil {
+		t.Errorf("end of transfer: %+v, %v", got, err)
+	}
+	buf.Write([]byte{0, 0x10, 0, 1})
+	if _, err := readHeader(&buf); err == nil {
+		t.Error("an oversized header was accepted")
+	}
+}
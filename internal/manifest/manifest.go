@@ -0,0 +1,223 @@
+package manifest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+)
+
+const (
+	formatVersion = 1
+	keyName       = "manifest_ed25519.key"
+
+	// Extension is the suggested file suffix for signed manifests
+	Extension = ".hadesmanifest"
+)
+
+// File describes one distributed artifact
+type File struct {
+	Name   string `json:"name"` // slash-separated path relative to the manifest's folder
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest is the signed listing of a set of artifacts
+type Manifest struct {
+	Version   int    `json:"version"`
+	Created   int64  `json:"created"` // Unix timestamp
+	PublicKey string `json:"public_key"`
+	Files     []File `json:"files"`
+}
+
+// signedFile is the on-disk form. Payload is kept as raw bytes so the signature
+// is checked against exactly what was signed.
+type signedFile struct {
+	Payload   json.RawMessage `json:"manifest"`
+	Signature string          `json:"signature"`
+}
+
+// Result is the outcome of verifying a manifest against the files on disk
+type Result struct {
+	Manifest   *Manifest
+	Missing    []string
+	Mismatched []string
+	Verified   int
+}
+
+// OK reports whether every listed artifact is present and matches
+func (r *Result) OK() bool { return len(r.Missing) == 0 && len(r.Mismatched) == 0 }
+
+// KeyPath returns the location of the signing key inside the config directory
+func KeyPath() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, keyName), nil
+}
+
+// LoadOrCreateKey loads the signing key at path, generating and storing a new one if none exists
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid signing key file %s", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(priv.Seed())+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to store signing key: %w", err)
+	}
+	return priv, nil
+}
+
+// Fingerprint returns a short hex fingerprint of a public key for display
+func Fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Build hashes files and lists them relative to baseDir
+func Build(baseDir string, files []string) (*Manifest, error) {
+	m := &Manifest{Version: formatVersion, Created: time.Now().Unix()}
+	for _, p := range files {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		rel, err := filepath.Rel(baseDir, p)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := hashFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", p, err)
+		}
+		m.Files = append(m.Files, File{Name: filepath.ToSlash(rel), Size: fi.Size(), SHA256: sum})
+	}
+	if len(m.Files) == 0 {
+		return nil, fmt.Errorf("no files to list")
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Name < m.Files[j].Name })
+	return m, nil
+}
+
+// Write signs m with key and writes it to path
+func Write(path string, m *Manifest, key ed25519.PrivateKey) error {
+	m.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	payload, err := json.MarshalIndent(m, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	sf := signedFile{Payload: payload, Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))}
+	data, err := json.MarshalIndent(sf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Read parses a manifest and checks its signature. The returned manifest is only
+// trustworthy once its public key has been compared with the one the sender published.
+func Read(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sf signedFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(sf.Payload, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if m.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported manifest version: %d", m.Version)
+	}
+	pub, err := base64.StdEncoding.DecodeString(m.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key in manifest")
+	}
+	sig, err := base64.StdEncoding.DecodeString(sf.Signature)
+	if err != nil || !ed25519.Verify(pub, sf.Payload, sig) {
+		return nil, fmt.Errorf("manifest signature is invalid")
+	}
+	return &m, nil
+}
+
+// Verify checks the signature of the manifest at path and compares every listed
+// artifact with the files next to it
+func Verify(path string) (*Result, error) {
+	m, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Dir(path)
+	res := &Result{Manifest: m}
+	for _, f := range m.Files {
+		p := filepath.Join(base, filepath.FromSlash(f.Name))
+		fi, err := os.Stat(p)
+		if err != nil {
+			res.Missing = append(res.Missing, f.Name)
+			continue
+		}
+		if fi.Size() != f.Size {
+			res.Mismatched = append(res.Mismatched, f.Name)
+			continue
+		}
+		sum, err := hashFile(p)
+		if err != nil || sum != f.SHA256 {
+			res.Mismatched = append(res.Mismatched, f.Name)
+			continue
+		}
+		res.Verified++
+	}
+	return res, nil
+}
+
+// SignerKey decodes the signer public key of m
+func (m *Manifest) SignerKey() ed25519.PublicKey {
+	pub, _ := base64.StdEncoding.DecodeString(m.PublicKey)
+	return ed25519.PublicKey(pub)
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,41 @@
+//go:build darwin
+
+package media
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func detect(path string) Info {
+	var st unix.Statfs_t
+	if unix.Statfs(path, &st) != nil {
+		return Info{}
+	}
+	info := Info{FSType: string(bytes.TrimRight(st.Fstypename[:], "\x00"))}
+	switch {
+	case IsNetworkFS(info.FSType) || st.Flags&unix.MNT_LOCAL == 0:
+		info.Kind = Network
+	case st.Flags&unix.MNT_REMOVABLE != 0:
+		info.Kind = Removable
+	}
+	// Every Mac that ships with internal storage uses flash
+	info.SolidState = info.Kind == Local
+	return info
+}
+
+func isTransientErrno(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case unix.EIO, unix.EAGAIN, unix.EINTR, unix.ETIMEDOUT, unix.ESTALE, unix.ECONNRESET,
+		unix.ECONNABORTED, unix.EHOSTUNREACH, unix.ENETUNREACH, unix.ENETDOWN:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+//go:build linux
+
+package media
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func detect(path string) Info {
+	var info Info
+	var st unix.Statfs_t
+	if unix.Statfs(path, &st) == nil {
+		switch uint32(st.Type) {
+		case unix.NFS_SUPER_MAGIC, unix.SMB_SUPER_MAGIC, unix.CIFS_SUPER_MAGIC, unix.SMB2_SUPER_MAGIC:
+			info.Kind = Network
+		}
+	}
+	fstype, dev := mountOf(path)
+	info.FSType = fstype
+	if IsNetworkFS(fstype) {
+		info.Kind = Network
+	}
+	if info.Kind == Network || dev == "" {
+		return info
+	}
+	// /sys/dev/block/MAJ:MIN resolves to .../block/<disk>[/<partition>]
+	sys, err := filepath.EvalSymlinks("/sys/dev/block/" + dev)
+	if err != nil {
+		return info
+	}
+	if _, err := os.Stat(filepath.Join(sys, "partition")); err == nil {
+		sys = filepath.Dir(sys)
+	}
+	if readFlag(filepath.Join(sys, "removable")) || strings.Contains(sys, "/usb") || strings.Contains(sys, "/mmc") {
+		info.Kind = Removable
+	}
+	if rot, err := os.ReadFile(filepath.Join(sys, "queue", "rotational")); err == nil {
+		info.SolidState = strings.TrimSpace(string(rot)) == "0"
+	}
+	return info
+}
+
+// mountOf returns the filesystem type and MAJ:MIN device of the mount holding path
+func mountOf(path string) (fstype, dev string) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+	best := -1
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// id parent MAJ:MIN root mountpoint options ... - fstype source superoptions
+		fields := strings.Fields(sc.Text())
+		sep := -1
+		for i, f := range fields {
+			if f == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 5 || sep < 0 || sep+1 >= len(fields) {
+			continue
+		}
+		mnt := unescapeMount(fields[4])
+		if !within(path, mnt) || len(mnt) <= best {
+			continue
+		}
+		best = len(mnt)
+		fstype, dev = fields[sep+1], fields[2]
+	}
+	// btrfs and other virtual devices report major 0; stat the path for its real device
+	if strings.HasPrefix(dev, "0:") {
+		var st syscall.Stat_t
+		if syscall.Stat(path, &st) == nil && unix.Major(st.Dev) != 0 {
+			dev = fmt.Sprintf("%d:%d", unix.Major(st.Dev), unix.Minor(st.Dev))
+		}
+	}
+	return fstype, dev
+}
+
+func within(path, mnt string) bool {
+	if mnt == "/" {
+		return true
+	}
+	return path == mnt || strings.HasPrefix(path, mnt+"/")
+}
+
+// unescapeMount decodes the octal escapes (\040 for space) used in mountinfo
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			var c byte
+			if _, err := fmt.Sscanf(s[i+1:i+4], "%3o", &c); err == nil {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func readFlag(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.TrimSpace(string(data)) == "1"
+}
+
+func isTransientErrno(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case unix.EIO, unix.EAGAIN, unix.EINTR, unix.ETIMEDOUT, unix.ESTALE, unix.ECONNRESET,
+		unix.ECONNABORTED, unix.EHOSTUNREACH, unix.ENETUNREACH, unix.ENETDOWN, unix.EREMOTEIO:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package media
+
+func detect(path string) Info { return Info{} }
+
+func isTransientErrno(err error) bool { return false }
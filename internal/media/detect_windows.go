@@ -0,0 +1,57 @@
+//go:build windows
+
+package media
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// Win32 network errors that usually clear on retry
+const (
+	errorUnexpNetErr    = 59
+	errorNetnameDeleted = 64
+	errorSemTimeout     = 121
+)
+
+func detect(path string) Info {
+	if strings.HasPrefix(path, `\\`) && !strings.HasPrefix(path, `\\?\`) {
+		return Info{Kind: Network}
+	}
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return Info{}
+	}
+	root := make([]uint16, windows.MAX_PATH+1)
+	if windows.GetVolumePathName(p, &root[0], uint32(len(root))) != nil {
+		return Info{}
+	}
+	var info Info
+	switch windows.GetDriveType(&root[0]) {
+	case windows.DRIVE_REMOTE:
+		info.Kind = Network
+	case windows.DRIVE_REMOVABLE:
+		info.Kind = Removable
+	}
+	fs := make([]uint16, windows.MAX_PATH+1)
+	if windows.GetVolumeInformation(&root[0], nil, 0, nil, nil, nil, &fs[0], uint32(len(fs))) == nil {
+		info.FSType = windows.UTF16ToString(fs)
+	}
+	return info
+}
+
+func isTransientErrno(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case errorUnexpNetErr, errorNetnameDeleted, errorSemTimeout, windows.ERROR_NETWORK_BUSY, windows.ERROR_BAD_NETPATH,
+		windows.ERROR_IO_DEVICE, windows.ERROR_SHARING_VIOLATION, windows.ERROR_LOCK_VIOLATION:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package media
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Limit is the largest file a destination accepts
+type Limit struct {
+	Size int64  // bytes; 0 means no known limit
+	Name string // what imposes it, e.g. "FAT32" or "OneDrive"
+}
+
+// fsLimits are the file size limits of filesystems by lower-case type name.
+// Linux reports FAT16 and FAT32 alike as vfat.
+var fsLimits = map[string]Limit{
+	"vfat":         {4<<30 - 1, "FAT32"},
+	"msdos":        {4<<30 - 1, "FAT32"},
+	"fat32":        {4<<30 - 1, "FAT32"},
+	"fat":          {2<<30 - 1, "FAT16"},
+	"fuse.s3fs":    {5 << 40, "S3"},
+	"fuse.gcsfuse": {5 << 40, "Google Cloud Storage"},
+}
+
+// syncFolders are the per-file limits of cloud providers whose desktop client
+// uploads a local folder, by the folder name (case-insensitive). OneDrive for
+// Business folders are named "OneDrive - <organisation>".
+var syncFolders = []struct {
+	name  string
+	limit Limit
+}{
+	{"onedrive", Limit{250_000_000_000, "OneDrive"}},
+	{"icloud drive", Limit{50_000_000_000, "iCloud Drive"}},
+	{"iclouddrive", Limit{50_000_000_000, "iCloud Drive"}},
+	{"mobile documents", Limit{50_000_000_000, "iCloud Drive"}},
+	{"google drive", Limit{5_000_000_000_000, "Google Drive"}},
+	{"my drive", Limit{5_000_000_000_000, "Google Drive"}},
+}
+
+// FileLimit is the largest file the filesystem holds, if known
+func (i Info) FileLimit() Limit { return fsLimits[strings.ToLower(i.FSType)] }
+
+// MaxFileSize returns the tightest file size limit at path: that of its
+// filesystem, or of a cloud provider whose sync folder holds it
+func MaxFileSize(path string) Limit {
+	limit := Detect(path).FileLimit()
+	if p := providerLimit(path); p.Size > 0 && (limit.Size == 0 || p.Size < limit.Size) {
+		limit = p
+	}
+	return limit
+}
+
+// providerLimit looks for a known sync folder among the parents of path
+func providerLimit(path string) Limit {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Limit{}
+	}
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		name := strings.ToLower(filepath.Base(dir))
+		for _, f := range syncFolders {
+			if name == f.name || strings.HasPrefix(name, f.name+" - ") {
+				return f.limit
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return Limit{}
+		}
+	}
+}
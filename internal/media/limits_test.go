@@ -0,0 +1,39 @@
+package media
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFileLimit(t *testing.T) {
+	if l := (Info{FSType: "vfat"}).FileLimit(); l.Size != 4<<30-1 || l.Name != "FAT32" {
+		t.Errorf("vfat limit %+v", l)
+	}
+	if l := (Info{FSType: "FAT32"}).FileLimit(); l.Name != "FAT32" {
+		t.Errorf("Windows FAT32 limit %+v", l)
+	}
+	for _, fs := range []string{"exfat", "ntfs", "ext4", ""} {
+		if l := (Info{FSType: fs}).FileLimit(); l.Size != 0 {
+			t.Errorf("%q limit %+v, want none", fs, l)
+		}
+	}
+}
+
+func TestProviderLimit(t *testing.T) {
+	root := t.TempDir()
+	for path, want := range map[string]string{
+		filepath.Join(root, "OneDrive", "backups", "x.hadescrypt"):         "OneDrive",
+		filepath.Join(root, "OneDrive - Contoso", "x.hadescrypt"):          "OneDrive",
+		filepath.Join(root, "Library", "Mobile Documents", "x.hadescrypt"): "iCloud Drive",
+		filepath.Join(root, "Google Drive", "My Drive", "x.hadescrypt"):    "Google Drive",
+		filepath.Join(root, "OneDriveBackup", "x.hadescrypt"):              "",
+		filepath.Join(root, "Documents", "x.hadescrypt"):                   "",
+	} {
+		if got := providerLimit(path).Name; got != want {
+			t.Errorf("%s: provider %q, want %q", path, got, want)
+		}
+	}
+	if l := MaxFileSize(filepath.Join(root, "OneDrive", "x")); l.Name != "OneDrive" {
+		t.Errorf("MaxFileSize under OneDrive: %+v", l)
+	}
+}
@@ -0,0 +1,128 @@
+// Package media classifies the storage behind a path (local disk, network
+// share, removable drive, solid-state) so callers can pick buffer sizes,
+// retry flaky IO and warn where overwriting data does not erase it.
+package media
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Kind is the class of storage a path lives on
+type Kind int
+
+const (
+	Local Kind = iota
+	Network
+	Removable
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Network:
+		return "network share"
+	case Removable:
+		return "removable drive"
+	}
+	return "local disk"
+}
+
+// Info describes the storage behind a path. Fields that cannot be determined
+// on a platform keep their zero value.
+type Info struct {
+	Kind       Kind
+	SolidState bool   // flash or SSD: overwriting does not reach the old blocks
+	FSType     string // filesystem name when known, e.g. "nfs4", "exfat"
+}
+
+// Slow reports whether IO should use larger buffers and retries
+func (i Info) Slow() bool { return i.Kind != Local }
+
+// ShredIneffective reports whether overwriting a file before deletion cannot be
+// relied on to destroy its contents (remote storage, flash wear levelling, SSDs)
+func (i Info) ShredIneffective() bool { return i.Kind != Local || i.SolidState }
+
+// String describes the storage for status lines and warnings
+func (i Info) String() string {
+	s := i.Kind.String()
+	if i.SolidState && i.Kind == Local {
+		s = "solid-state disk"
+	}
+	if i.FSType != "" {
+		s += " (" + i.FSType + ")"
+	}
+	return s
+}
+
+// BufferSize is the copy buffer to use for IO on this storage
+func (i Info) BufferSize() int {
+	if i.Slow() {
+		return 4 << 20
+	}
+	return 1 << 20
+}
+
+// Detect classifies the storage of path. Paths that do not exist yet (outputs)
+// are classified by their nearest existing parent directory.
+func Detect(path string) Info {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Info{}
+	}
+	for {
+		if _, err := os.Stat(abs); err == nil {
+			break
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return Info{}
+		}
+		abs = parent
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = real
+	}
+	return detect(abs)
+}
+
+// networkFS lists filesystem type names that are backed by a remote server
+var networkFS = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smb3": true, "smbfs": true, "afpfs": true,
+	"webdav": true, "davfs": true, "ncpfs": true, "afs": true, "ceph": true, "glusterfs": true,
+	"9p": true, "fuse.sshfs": true, "fuse.rclone": true, "fuse.s3fs": true, "fuse.gcsfuse": true,
+}
+
+// IsNetworkFS reports whether fstype names a network filesystem
+func IsNetworkFS(fstype string) bool { return networkFS[strings.ToLower(fstype)] }
+
+// Retry runs fn up to attempts times while it fails with a transient IO error,
+// backing off between tries. Non-transient errors are returned immediately.
+func Retry(attempts int, fn func() error) error {
+	delay := 500 * time.Millisecond
+	var err error
+	for i := 0; i < max(attempts, 1); i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = fn(); err == nil || !IsTransient(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// IsTransient reports whether err is an IO failure that may succeed when
+// retried, such as a dropped connection or a timed-out network share
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	return isTransientErrno(err)
+}
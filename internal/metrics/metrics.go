@@ -0,0 +1,238 @@
+// Package metrics keeps counters, gauges and histograms and serves them in the
+// Prometheus text exposition format, so automated pipelines can be monitored
+// without pulling in a client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAddr is where the endpoint listens unless told otherwise
+const DefaultAddr = "127.0.0.1:9464"
+
+// DurationBuckets are histogram bounds in seconds suited to file operations
+var DurationBuckets = []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900, 3600}
+
+// Registry holds metric families in registration order
+type Registry struct {
+	mu       sync.Mutex
+	families []*Vec
+}
+
+// Vec is a metric family; each distinct set of label values is one series
+type Vec struct {
+	reg     *Registry
+	name    string
+	help    string
+	typ     string // "counter", "gauge" or "histogram"
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	values []string
+	value  float64  // counter and gauge
+	counts []uint64 // histogram, per bucket (not cumulative)
+	sum    float64
+	count  uint64
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry { return &Registry{} }
+
+func (r *Registry) add(name, help, typ string, buckets []float64, labels []string) *Vec {
+	v := &Vec{reg: r, name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: map[string]*series{}}
+	r.mu.Lock()
+	r.families = append(r.families, v)
+	r.mu.Unlock()
+	return v
+}
+
+// Counter registers a monotonically increasing metric
+func (r *Registry) Counter(name, help string, labels ...string) *Vec {
+	return r.add(name, help, "counter", nil, labels)
+}
+
+// Gauge registers a metric that can go up and down
+func (r *Registry) Gauge(name, help string, labels ...string) *Vec {
+	return r.add(name, help, "gauge", nil, labels)
+}
+
+// Histogram registers a distribution with the given upper bucket bounds
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Vec {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return r.add(name, help, "histogram", b, labels)
+}
+
+// get returns the series for values; callers hold reg.mu
+func (v *Vec) get(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\x00")
+	s := v.series[key]
+	if s == nil {
+		s = &series{values: append([]string(nil), values...)}
+		if v.typ == "histogram" {
+			s.counts = make([]uint64, len(v.buckets))
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+// Add increases a counter or gauge by delta
+func (v *Vec) Add(delta float64, values ...string) {
+	v.reg.mu.Lock()
+	defer v.reg.mu.Unlock()
+	v.get(values).value += delta
+}
+
+// Inc adds one
+func (v *Vec) Inc(values ...string) { v.Add(1, values...) }
+
+// Set sets a gauge
+func (v *Vec) Set(value float64, values ...string) {
+	v.reg.mu.Lock()
+	defer v.reg.mu.Unlock()
+	v.get(values).value = value
+}
+
+// Observe records one sample in a histogram
+func (v *Vec) Observe(value float64, values ...string) {
+	v.reg.mu.Lock()
+	defer v.reg.mu.Unlock()
+	s := v.get(values)
+	for i, b := range v.buckets {
+		if value <= b {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// WriteTo renders every family in the text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, v := range r.families {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.typ)
+		keys := make([]string, 0, len(v.series))
+		for k := range v.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s := v.series[k]
+			if v.typ != "histogram" {
+				fmt.Fprintf(cw, "%s%s %s\n", v.name, labelString(v.labels, s.values, "", ""), formatFloat(s.value))
+				continue
+			}
+			var cum uint64
+			for i, b := range v.buckets {
+				cum += s.counts[i]
+				fmt.Fprintf(cw, "%s_bucket%s %d\n", v.name, labelString(v.labels, s.values, "le", formatFloat(b)), cum)
+			}
+			fmt.Fprintf(cw, "%s_bucket%s %d\n", v.name, labelString(v.labels, s.values, "le", "+Inf"), s.count)
+			fmt.Fprintf(cw, "%s_sum%s %s\n", v.name, labelString(v.labels, s.values, "", ""), formatFloat(s.sum))
+			fmt.Fprintf(cw, "%s_count%s %d\n", v.name, labelString(v.labels, s.values, "", ""), s.count)
+		}
+	}
+	if err := cw.w.(*bufio.Writer).Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+// Handler serves the registry at any path
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+// Serve exposes r at /metrics on addr until the returned server is closed.
+// Only loopback addresses are accepted, since the metrics reveal activity and
+// file volumes; scrape from elsewhere through a reverse proxy or tunnel.
+func Serve(addr string, r *Registry) (*http.Server, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("metrics address %s is not a loopback address", addr)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+	return srv, nil
+}
+
+func labelString(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var parts []string
+	for i, n := range names {
+		parts = append(parts, n+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,283 @@
+// Package minisign reads minisign keys and writes and checks minisign
+// signatures (https://jedisct1.github.io/minisign/), so release artifacts can
+// be signed without the minisign tool and checked with it.
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Algorithm and KDF identifiers of the minisign formats
+var (
+	algEd       = [2]byte{'E', 'd'} // keys, and signatures over the data itself
+	algPrehash  = [2]byte{'E', 'D'} // signatures over the BLAKE2b-512 of the data
+	kdfScrypt   = [2]byte{'S', 'c'}
+	kdfNone     = [2]byte{0, 0}
+	checksumB2b = [2]byte{'B', '2'}
+)
+
+// ErrKeyMismatch is returned when a signature was made by another key
+var ErrKeyMismatch = errors.New("minisign: signature made by a different key")
+
+// ErrInvalidSignature is returned when a signature does not match the data or its trusted comment
+var ErrInvalidSignature = errors.New("minisign: invalid signature")
+
+// ErrPasswordRequired is returned when an encrypted secret key is read without a password
+var ErrPasswordRequired = errors.New("minisign: the secret key is encrypted and needs its password")
+
+// ErrWrongPassword is returned when an encrypted secret key does not decrypt to a consistent key
+var ErrWrongPassword = errors.New("minisign: wrong password for the secret key")
+
+// PublicKey is a minisign public key
+type PublicKey struct {
+	ID  [8]byte
+	Key ed25519.PublicKey
+}
+
+// PrivateKey is a decrypted minisign secret key
+type PrivateKey struct {
+	ID  [8]byte
+	Key ed25519.PrivateKey
+}
+
+// Public returns the public half of k
+func (k PrivateKey) Public() PublicKey {
+	return PublicKey{ID: k.ID, Key: k.Key.Public().(ed25519.PublicKey)}
+}
+
+// String returns the key in the one-line form minisign prints and accepts with -P
+func (k PublicKey) String() string {
+	return base64.StdEncoding.EncodeToString(append(append(algEd[:], k.ID[:]...), k.Key...))
+}
+
+// ParsePublicKey reads a public key, either the bare base64 line or a .pub file
+func ParsePublicKey(text string) (PublicKey, error) {
+	raw, err := decodeLine(text, 42)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("minisign public key: %w", err)
+	}
+	if !bytes.Equal(raw[:2], algEd[:]) {
+		return PublicKey{}, errors.New("minisign public key: unsupported algorithm")
+	}
+	var k PublicKey
+	copy(k.ID[:], raw[2:10])
+	k.Key = ed25519.PublicKey(raw[10:])
+	return k, nil
+}
+
+// ParsePrivateKey reads a minisign secret key file. Keys made with
+// "minisign -G" are encrypted and need their password; keys made with -W
+// are not, and password is ignored. An encrypted key read without a password
+// returns ErrPasswordRequired.
+func ParsePrivateKey(data []byte, password []byte) (PrivateKey, error) {
+	raw, err := decodeLine(string(data), 158)
+	if err != nil {
+		return PrivateKey{}, fmt.Errorf("minisign secret key: %w", err)
+	}
+	var alg, kdf, cks [2]byte
+	copy(alg[:], raw[0:2])
+	copy(kdf[:], raw[2:4])
+	copy(cks[:], raw[4:6])
+	if alg != algEd || cks != checksumB2b {
+		return PrivateKey{}, errors.New("minisign secret key: unsupported algorithm")
+	}
+	salt := raw[6:38]
+	ops, mem := binary.LittleEndian.Uint64(raw[38:46]), binary.LittleEndian.Uint64(raw[46:54])
+	sk := bytes.Clone(raw[54:158]) // key id, secret key, checksum
+	switch kdf {
+	case kdfNone:
+	case kdfScrypt:
+		if len(password) == 0 {
+			return PrivateKey{}, ErrPasswordRequired
+		}
+		n, r, p := scryptParams(ops, mem)
+		stream, err := scrypt.Key(password, salt, n, r, p, len(sk))
+		if err != nil {
+			return PrivateKey{}, fmt.Errorf("minisign secret key: %w", err)
+		}
+		subtle.XORBytes(sk, sk, stream)
+	default:
+		return PrivateKey{}, errors.New("minisign secret key: unsupported key derivation")
+	}
+	var k PrivateKey
+	copy(k.ID[:], sk[0:8])
+	k.Key = ed25519.PrivateKey(bytes.Clone(sk[8:72]))
+	sum := keyChecksum(k)
+	if subtle.ConstantTimeCompare(sum[:], sk[72:104]) != 1 {
+		if kdf == kdfScrypt {
+			return PrivateKey{}, ErrWrongPassword
+		}
+		return PrivateKey{}, errors.New("minisign secret key: checksum mismatch")
+	}
+	return k, nil
+}
+
+// MarshalPrivateKey encodes k as an unencrypted secret key file, the form
+// "minisign -G -W" writes; it is meant for tests and throwaway keys
+func MarshalPrivateKey(k PrivateKey, comment string) []byte {
+	raw := make([]byte, 0, 158)
+	raw = append(raw, algEd[:]...)
+	raw = append(raw, kdfNone[:]...)
+	raw = append(raw, checksumB2b[:]...)
+	raw = append(raw, make([]byte, 48)...) // salt and limits are unused without a KDF
+	raw = append(raw, k.ID[:]...)
+	raw = append(raw, k.Key...)
+	sum := keyChecksum(k)
+	raw = append(raw, sum[:]...)
+	return []byte("untrusted comment: " + comment + "\n" + base64.StdEncoding.EncodeToString(raw) + "\n")
+}
+
+func keyChecksum(k PrivateKey) [32]byte {
+	return blake2b.Sum256(append(append(algEd[:], k.ID[:]...), k.Key...))
+}
+
+// Sign returns a prehashed minisign signature of the data read from r, with
+// untrusted and trusted comments as minisign writes them to a .minisig file
+func (k PrivateKey) Sign(r io.Reader, untrusted, trusted string) ([]byte, error) {
+	if strings.ContainsAny(untrusted+trusted, "\r\n") {
+		return nil, errors.New("minisign: comments must be single lines")
+	}
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(k.Key, h.Sum(nil))
+	global := ed25519.Sign(k.Key, append(bytes.Clone(sig), trusted...))
+	line := append(append(algPrehash[:], k.ID[:]...), sig...)
+	var b strings.Builder
+	b.WriteString("untrusted comment: " + untrusted + "\n")
+	b.WriteString(base64.StdEncoding.EncodeToString(line) + "\n")
+	b.WriteString("trusted comment: " + trusted + "\n")
+	b.WriteString(base64.StdEncoding.EncodeToString(global) + "\n")
+	return []byte(b.String()), nil
+}
+
+// Verify checks a .minisig signature of the data read from r and returns its trusted comment
+func (k PublicKey) Verify(r io.Reader, signature []byte) (string, error) {
+	v, err := k.NewVerifier(signature)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(v, r); err != nil {
+		return "", err
+	}
+	return v.Verify()
+}
+
+// Verifier checks a signature of the data written to it, so data can be
+// verified as it streams past. Legacy signatures over the data itself, rather
+// than its hash, hold the data in memory.
+type Verifier struct {
+	key     PublicKey
+	sig     []byte
+	global  []byte
+	trusted string
+	prehash hash.Hash    // nil for legacy signatures
+	data    bytes.Buffer // legacy signatures only
+}
+
+// NewVerifier parses a .minisig signature made by k
+func (k PublicKey) NewVerifier(signature []byte) (*Verifier, error) {
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return nil, errors.New("minisign: malformed signature")
+	}
+	line, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(line) != 74 {
+		return nil, errors.New("minisign: malformed signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return nil, errors.New("minisign: malformed signature")
+	}
+	if !bytes.Equal(line[2:10], k.ID[:]) {
+		return nil, ErrKeyMismatch
+	}
+	v := &Verifier{key: k, sig: line[10:], global: global, trusted: strings.TrimPrefix(lines[2], "trusted comment: ")}
+	switch [2]byte(line[:2]) {
+	case algPrehash:
+		v.prehash, _ = blake2b.New512(nil)
+	case algEd:
+	default:
+		return nil, errors.New("minisign: unsupported signature algorithm")
+	}
+	return v, nil
+}
+
+// Write adds p to the signed data
+func (v *Verifier) Write(p []byte) (int, error) {
+	if v.prehash != nil {
+		return v.prehash.Write(p)
+	}
+	return v.data.Write(p)
+}
+
+// Verify checks the signature over everything written and returns its trusted comment
+func (v *Verifier) Verify() (string, error) {
+	msg := v.data.Bytes()
+	if v.prehash != nil {
+		msg = v.prehash.Sum(nil)
+	}
+	if !ed25519.Verify(v.key.Key, msg, v.sig) || !ed25519.Verify(v.key.Key, append(bytes.Clone(v.sig), v.trusted...), v.global) {
+		return "", ErrInvalidSignature
+	}
+	return v.trusted, nil
+}
+
+// decodeLine finds the base64 line of a key file (skipping its comment) and checks its length
+func decodeLine(text string, size int) ([]byte, error) {
+	for _, l := range strings.Split(strings.TrimSpace(text), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "untrusted comment:") {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(l)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) != size {
+			return nil, fmt.Errorf("%d bytes, want %d", len(raw), size)
+		}
+		return raw, nil
+	}
+	return nil, errors.New("no key found")
+}
+
+// scryptParams turns libsodium's opslimit and memlimit into scrypt's N, r and p,
+// the way crypto_pwhash_scryptsalsa208sha256 does
+func scryptParams(ops, mem uint64) (n, r, p int) {
+	ops = max(ops, 32768)
+	r = 8
+	var logN uint
+	if ops < mem/32 {
+		p = 1
+		maxN := ops / uint64(r*4)
+		for logN = 1; logN < 63; logN++ {
+			if uint64(1)<<logN > maxN/2 {
+				break
+			}
+		}
+	} else {
+		maxN := mem / uint64(r*128)
+		for logN = 1; logN < 63; logN++ {
+			if uint64(1)<<logN > maxN/2 {
+				break
+			}
+		}
+		maxRP := min((ops/4)/(uint64(1)<<logN), 0x3fffffff)
+		p = int(maxRP / uint64(r))
+	}
+	return 1 << logN, r, p
+}
@@ -0,0 +1,107 @@
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+func newKey(t *testing.T) PrivateKey {
+	t.Helper()
+	_, sk, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := PrivateKey{Key: sk}
+	rand.Read(k.ID[:])
+	return k
+}
+
+func TestSignVerify(t *testing.T) {
+	k := newKey(t)
+	parsed, err := ParsePrivateKey(MarshalPrivateKey(k, "test key"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("HadesCrypt release artifact")
+	sig, err := parsed.Sign(bytes.NewReader(data), "signature from test key", "timestamp:0\tfile:a.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ParsePublicKey("untrusted comment: test\n" + k.Public().String() + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted, err := pub.Verify(bytes.NewReader(data), sig)
+	if err != nil || trusted != "timestamp:0\tfile:a.zip" {
+		t.Fatalf("verify: %q, %v", trusted, err)
+	}
+	if _, err := pub.Verify(bytes.NewReader(append(data, '!')), sig); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered data: %v", err)
+	}
+	forged := strings.Replace(string(sig), "file:a.zip", "file:b.zip", 1)
+	if _, err := pub.Verify(bytes.NewReader(data), []byte(forged)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered trusted comment: %v", err)
+	}
+	if _, err := newKey(t).Public().Verify(bytes.NewReader(data), sig); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("other key: %v", err)
+	}
+	if _, err := k.Sign(bytes.NewReader(data), "two\nlines", ""); err == nil {
+		t.Error("multi-line comment accepted")
+	}
+}
+
+// encryptedKey encodes k the way "minisign -G" does, with cheap scrypt limits
+func encryptedKey(t *testing.T, k PrivateKey, password string) []byte {
+	t.Helper()
+	const ops, mem = 32768, 1 << 20
+	raw := make([]byte, 158)
+	copy(raw, "EdScB2")
+	rand.Read(raw[6:38])
+	binary.LittleEndian.PutUint64(raw[38:46], ops)
+	binary.LittleEndian.PutUint64(raw[46:54], mem)
+	copy(raw[54:], k.ID[:])
+	copy(raw[62:], k.Key)
+	sum := keyChecksum(k)
+	copy(raw[126:], sum[:])
+	n, r, p := scryptParams(ops, mem)
+	stream, err := scrypt.Key([]byte(password), raw[6:38], n, r, p, 104)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subtle.XORBytes(raw[54:], raw[54:], stream)
+	return []byte("untrusted comment: encrypted\n" + base64.StdEncoding.EncodeToString(raw) + "\n")
+}
+
+func TestEncryptedPrivateKey(t *testing.T) {
+	k := newKey(t)
+	data := encryptedKey(t, k, "release password")
+	got, err := ParsePrivateKey(data, []byte("release password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != k.ID || !got.Key.Equal(k.Key) {
+		t.Error("decrypted key differs")
+	}
+	if _, err := ParsePrivateKey(data, []byte("wrong")); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("wrong password: %v", err)
+	}
+	if _, err := ParsePrivateKey(data, nil); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("no password: %v", err)
+	}
+}
+
+func TestScryptParams(t *testing.T) {
+	// minisign's defaults: crypto_pwhash_scryptsalsa208sha256 OPSLIMIT_SENSITIVE and MEMLIMIT_SENSITIVE
+	if n, r, p := scryptParams(33554432, 1073741824); n != 1<<20 || r != 8 || p != 1 {
+		t.Errorf("sensitive limits give N=%d r=%d p=%d", n, r, p)
+	}
+}
@@ -0,0 +1,268 @@
+// Package notify reports the outcome of unattended work (CLI job runs, sync
+// folders) by email, generic webhook, ntfy or Gotify.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+)
+
+// EnvSMTPPassword holds the SMTP password when no password file is configured
+const EnvSMTPPassword = "HADESCRYPT_SMTP_PASSWORD"
+
+// ErrNoSinks means no notification target is configured
+var ErrNoSinks = errors.New("no notification targets configured")
+
+// Message is one summary to deliver
+type Message struct {
+	Title  string
+	Body   string
+	Failed bool
+}
+
+// Wanted reports whether cfg asks for msg at all
+func Wanted(cfg config.Notifications, msg Message) bool {
+	if msg.Failed {
+		return cfg.OnFailure
+	}
+	return cfg.OnSuccess
+}
+
+// Configured reports whether cfg has at least one target
+func Configured(cfg config.Notifications) bool {
+	return cfg.SMTPHost != "" || cfg.WebhookURL != "" || cfg.NtfyURL != "" || cfg.GotifyURL != ""
+}
+
+// Notify sends msg to every configured target if cfg wants it. Failures of
+// individual targets are joined; the others are still tried.
+func Notify(ctx context.Context, cfg config.Notifications, msg Message) error {
+	if !Wanted(cfg, msg) {
+		return nil
+	}
+	return Send(ctx, cfg, msg)
+}
+
+// Send delivers msg to every configured target regardless of the success and
+// failure switches, as the "send test notification" button does
+func Send(ctx context.Context, cfg config.Notifications, msg Message) error {
+	if !Configured(cfg) {
+		return ErrNoSinks
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var errs []error
+	if cfg.SMTPHost != "" {
+		if err := sendEmail(ctx, cfg, msg); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	if cfg.WebhookURL != "" {
+		if err := sendWebhook(ctx, cfg.WebhookURL, msg); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if cfg.NtfyURL != "" {
+		if err := sendNtfy(ctx, cfg.NtfyURL, cfg.NtfyToken, msg); err != nil {
+			errs = append(errs, fmt.Errorf("ntfy: %w", err))
+		}
+	}
+	if cfg.GotifyURL != "" {
+		if err := sendGotify(ctx, cfg.GotifyURL, cfg.GotifyToken, msg); err != nil {
+			errs = append(errs, fmt.Errorf("gotify: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func hostname() string {
+	name, _ := os.Hostname()
+	if name == "" {
+		return "unknown host"
+	}
+	return name
+}
+
+func status(msg Message) string {
+	if msg.Failed {
+		return "failure"
+	}
+	return "success"
+}
+
+// post sends body to rawURL and treats any non-2xx answer as an error
+func post(ctx context.Context, rawURL, contentType string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// sendWebhook posts a JSON document describing msg
+func sendWebhook(ctx context.Context, target string, msg Message) error {
+	body, err := json.Marshal(map[string]any{
+		"title":   msg.Title,
+		"message": msg.Body,
+		"status":  status(msg),
+		"host":    hostname(),
+		"time":    time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	return post(ctx, target, "application/json", body, nil)
+}
+
+// sendNtfy publishes msg to an ntfy topic URL
+func sendNtfy(ctx context.Context, topicURL, token string, msg Message) error {
+	h := http.Header{}
+	h.Set("Title", mime.QEncoding.Encode("utf-8", msg.Title))
+	if msg.Failed {
+		h.Set("Priority", "high")
+		h.Set("Tags", "warning")
+	} else {
+		h.Set("Tags", "white_check_mark")
+	}
+	if token != "" {
+		h.Set("Authorization", "Bearer "+token)
+	}
+	return post(ctx, topicURL, "text/plain; charset=utf-8", []byte(msg.Body), h)
+}
+
+// sendGotify posts msg to a Gotify server with an application token
+func sendGotify(ctx context.Context, server, token string, msg Message) error {
+	if token == "" {
+		return errors.New("application token is missing")
+	}
+	priority := 4
+	if msg.Failed {
+		priority = 8
+	}
+	body, err := json.Marshal(map[string]any{"title": msg.Title, "message": msg.Body, "priority": priority})
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSuffix(server, "/") + "/message?token=" + url.QueryEscape(token)
+	return post(ctx, target, "application/json", body, nil)
+}
+
+// smtpPassword reads the SMTP password from the configured file or the environment
+func smtpPassword(cfg config.Notifications) (string, error) {
+	if cfg.SMTPPasswordFile != "" {
+		pw, err := secret.FromFile(cfg.SMTPPasswordFile)
+		if err != nil {
+			return "", err
+		}
+		defer secret.Wipe(pw)
+		return string(pw), nil
+	}
+	return os.Getenv(EnvSMTPPassword), nil
+}
+
+// sendEmail delivers msg over SMTP, with implicit TLS on port 465 and STARTTLS otherwise
+func sendEmail(ctx context.Context, cfg config.Notifications, msg Message) error {
+	var to []string
+	for _, addr := range strings.Split(cfg.SMTPTo, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	if len(to) == 0 || cfg.SMTPFrom == "" {
+		return errors.New("sender and recipient addresses are required")
+	}
+	port := cfg.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: cfg.SMTPHost, MinVersion: tls.VersionTLS12}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if port == 465 {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	c, err := smtp.NewClient(conn, cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if port != 465 {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not offer STARTTLS; refusing to send credentials in clear text")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.SMTPUser != "" {
+		pw, err := smtpPassword(cfg)
+		if err != nil {
+			return err
+		}
+		if err := c.Auth(smtp.PlainAuth("", cfg.SMTPUser, pw, cfg.SMTPHost)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(cfg.SMTPFrom); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n",
+		cfg.SMTPFrom, strings.Join(to, ", "), headerSafe(msg.Title), time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(w, "%s\r\n\r\n-- \r\nHadesCrypt on %s\r\n", strings.ReplaceAll(msg.Body, "\n", "\r\n"), hostname())
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// headerSafe keeps a subject on one line and encodes non-ASCII text
+func headerSafe(s string) string {
+	return mime.QEncoding.Encode("utf-8", strings.NewReplacer("\r", " ", "\n", " ").Replace(s))
+}
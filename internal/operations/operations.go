@@ -0,0 +1,176 @@
+// Package operations is the UI-free core of the encrypt and decrypt flows. It
+// decides where outputs go, how each selected item is processed, how much data
+// a selection holds and what "delete source files" removes. The GUI drives it
+// from its widgets and the batch runner from job files, so both follow the same
+// rules; it imports nothing from Fyne and is tested on its own.
+package operations
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+	"github.com/bangundwir/HadesCrypt/internal/ignore"
+	"github.com/bangundwir/HadesCrypt/internal/safename"
+	"github.com/bangundwir/HadesCrypt/internal/splitter"
+)
+
+// Settings are the choices that shape a run
+type Settings struct {
+	Mode        cryptoengine.EncryptionMode
+	Extension   string         // container extension; empty uses format.DefaultExtension
+	OutputDir   string         // folder for encrypted outputs; empty writes next to each source
+	Recursive   bool           // encrypt folders file by file instead of as one archive
+	DeleteAfter bool           // remove each source once its output is verified
+	Symlinks    fswalk.Policy  // how folder walks treat symbolic links
+	Names       safename.Style // how output names are rewritten for picky targets; empty keeps them
+	// AbortOnUnreadable stops a PerFile run at a file it cannot read; otherwise the
+	// file is passed by and reported (see CheckReadable)
+	AbortOnUnreadable bool
+}
+
+// Controller applies Settings to selections. Cancellation and scan progress
+// come in through its hooks; both may be nil.
+type Controller struct {
+	Settings
+	Canceled func() bool        // polled by folder scans, which then fail with apperr.ErrCanceled
+	Scanning func(fswalk.Stats) // running totals of a folder scan, from another goroutine
+}
+
+// Strategy is how one selected item is processed
+type Strategy int
+
+const (
+	// Single processes a file on its own
+	Single Strategy = iota
+	// Archive packs a folder into one encrypted archive
+	Archive
+	// PerFile processes every file below a folder on its own
+	PerFile
+)
+
+// EncryptedPath returns the output path for encrypting src
+func (c *Controller) EncryptedPath(src string) string {
+	if c.OutputDir != "" {
+		src = filepath.Join(c.OutputDir, filepath.Base(src))
+	}
+	return c.EncryptedPathInPlace(src)
+}
+
+// EncryptedPathInPlace returns the output path for a file of a PerFile folder,
+// which is encrypted next to itself so the folder keeps its layout
+func (c *Controller) EncryptedPathInPlace(src string) string {
+	return format.OutputPathFor(c.outputName(src), cryptoengine.ExtensionFor(c.Mode, c.Extension))
+}
+
+// outputName is src with its name rewritten in the Names style. A transliterated
+// name that is already taken next to src ("Ärger.txt" beside "Aerger.txt") is
+// percent-encoded instead, which cannot collide, so one output never overwrites
+// another's.
+func (c *Controller) outputName(src string) string {
+	dir, name := filepath.Split(src)
+	out := safename.Apply(c.Names, name)
+	if out != name && c.Names == safename.Transliterate {
+		if _, err := os.Lstat(dir + out); err == nil {
+			out = safename.Apply(safename.Percent, name)
+		}
+	}
+	return dir + out
+}
+
+// OriginalName returns the name of src to keep in its container's metadata: the
+// exact name when Names rewrites it for the output, otherwise empty
+func (c *Controller) OriginalName(src string) string {
+	if c.outputName(src) != src {
+		return filepath.Base(src)
+	}
+	return ""
+}
+
+// DecryptedPath returns the output path for decrypting src: next to it under the
+// original name kept in a HadesCrypt header, or src without its extension. The
+// name is read before the password authenticates it, so only a plain file name
+// is taken.
+func (c *Controller) DecryptedPath(src string) string {
+	out := format.DecryptedPathFor(src)
+	if format.IsHadesCrypt(src) {
+		if hdr, err := cryptoengine.ReadHeaderFromFile(src); err == nil && hdr.Metadata.Name != "" && hdr.Metadata.Validate() == nil {
+			out = filepath.Join(filepath.Dir(out), hdr.Metadata.Name)
+		}
+	}
+	return out
+}
+
+// StrategyFor returns how an item with info is encrypted, or decrypted when decrypt is set.
+// Folders are always decrypted file by file.
+func (c *Controller) StrategyFor(info os.FileInfo, decrypt bool) Strategy {
+	switch {
+	case !info.IsDir():
+		return Single
+	case decrypt || c.Recursive:
+		return PerFile
+	}
+	return Archive
+}
+
+// WalkOptions returns the walk options for a folder below root. Encryption honors
+// .hadesignore files; decryption only applies the symlink policy.
+func (c *Controller) WalkOptions(root string, decrypt bool) fswalk.Options {
+	if decrypt {
+		return fswalk.Options{Symlinks: c.Symlinks}
+	}
+	return fswalk.Options{Symlinks: c.Symlinks, Exclude: ignore.New(root).Match}
+}
+
+// Scan lists the files below root that keep accepts, using the walk options for
+// the direction of the run
+func (c *Controller) Scan(root string, decrypt bool, keep func(fswalk.Entry) bool) ([]fswalk.Entry, error) {
+	return c.ScanWith(root, c.WalkOptions(root, decrypt), keep)
+}
+
+// ScanWith is Scan with explicit walk options
+func (c *Controller) ScanWith(root string, opts fswalk.Options, keep func(fswalk.Entry) bool) ([]fswalk.Entry, error) {
+	files, _, err := fswalk.Scan(root, fswalk.ScanOptions{Options: opts, Keep: keep, Canceled: c.Canceled, Progress: c.Scanning})
+	return files, err
+}
+
+// CheckReadable opens path for reading and closes it again. It tells files a run
+// cannot read at all (permissions, locks held by other programs, files that
+// vanished since the scan) apart from failures while processing them.
+func CheckReadable(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Processable reports whether a walked entry can be processed on its own:
+// directories and stored links (Store link policy) are passed by
+func Processable(info os.FileInfo) bool {
+	return info != nil && !info.IsDir() && !fswalk.IsLink(info)
+}
+
+// EncryptCandidate selects the files a PerFile encryption encrypts (and deletes).
+// .hadesignore files stay in plaintext so they keep working; encrypted outputs are skipped,
+// including the volumes and manifest of a split one.
+func EncryptCandidate(e fswalk.Entry) bool {
+	split := splitter.GetBasePathFromChunk(strings.TrimSuffix(e.Path, splitter.ManifestExt))
+	return Processable(e.Info) && e.Info.Name() != ignore.FileName && !format.IsEncryptedArtifact(split)
+}
+
+// DecryptCandidate selects the files a PerFile decryption decrypts (and deletes)
+func DecryptCandidate(e fswalk.Entry) bool {
+	return Processable(e.Info) && format.IsEncryptedArtifact(e.Path)
+}
+
+// candidate returns the file filter for the direction of a run
+func candidate(decrypt bool) func(fswalk.Entry) bool {
+	if decrypt {
+		return DecryptCandidate
+	}
+	return EncryptCandidate
+}
@@ -0,0 +1,257 @@
+package operations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/safename"
+)
+
+// writeTree creates files (relative path to contents) below a new temp folder
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, data := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// sampleTree has plaintext, an ignored file, the ignore file itself and a container
+func sampleTree(t *testing.T) string {
+	return writeTree(t, map[string]string{
+		"a.txt":                  "aaaa",
+		"sub/b.txt":              "bb",
+		"sub/skip.log":           "ignored",
+		".hadesignore":           "*.log\n",
+		"sub/old.txt.hadescrypt": "container",
+	})
+}
+
+func TestOutputPaths(t *testing.T) {
+	src := filepath.Join("docs", "report.pdf")
+	for _, tc := range []struct {
+		name     string
+		settings Settings
+		want     string
+	}{
+		{"default", Settings{}, src + ".hadescrypt"},
+		{"extension", Settings{Extension: ".heistcrypt"}, src + ".heistcrypt"},
+		{"gnupg", Settings{Mode: cryptoengine.ModeGnuPG, Extension: ".heistcrypt"}, src + ".gpg"},
+		{"output folder", Settings{OutputDir: "vault"}, filepath.Join("vault", "report.pdf.hadescrypt")},
+		{"transliterated", Settings{Names: safename.Transliterate}, filepath.Join("docs", "report.pdf.hadescrypt")},
+	} {
+		c := &Controller{Settings: tc.settings}
+		if got := c.EncryptedPath(src); got != tc.want {
+			t.Errorf("%s: EncryptedPath = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	c := &Controller{Settings: Settings{OutputDir: "vault"}}
+	if got, want := c.EncryptedPathInPlace(src), src+".hadescrypt"; got != want {
+		t.Errorf("EncryptedPathInPlace = %q, want %q", got, want)
+	}
+	if got := c.DecryptedPath(src + ".hadescrypt"); got != src {
+		t.Errorf("DecryptedPath = %q, want %q", got, src)
+	}
+}
+
+func TestOriginalNameRestored(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "Отчёт: 2024.pdf")
+	os.WriteFile(src, []byte("report"), 0600)
+	c := &Controller{Settings: Settings{Names: safename.Transliterate}}
+	out := c.EncryptedPath(src)
+	if want := filepath.Join(dir, "Otchyot_ 2024.pdf.hadescrypt"); out != want {
+		t.Fatalf("EncryptedPath = %q, want %q", out, want)
+	}
+	opts := cryptoengine.EncryptionOptions{Argon2: cryptoengine.Argon2Params{Time: 1, Memory: 64, Threads: 1}}
+	opts.Metadata.Name = c.OriginalName(src)
+	if err := cryptoengine.EncryptFileWithOptions(src, out, []byte("pw"), opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.DecryptedPath(out); got != src {
+		t.Errorf("DecryptedPath = %q, want the original %q", got, src)
+	}
+	taken := filepath.Join(dir, "Aerger.txt")
+	os.WriteFile(taken, nil, 0600)
+	if got, want := c.EncryptedPath(filepath.Join(dir, "Ärger.txt")), filepath.Join(dir, "%C3%84rger.txt.hadescrypt"); got != want {
+		t.Errorf("EncryptedPath beside its transliteration = %q, want %q", got, want)
+	}
+	if got, want := c.EncryptedPath(taken), taken+".hadescrypt"; got != want {
+		t.Errorf("EncryptedPath of an ASCII name = %q, want %q", got, want)
+	}
+	if name := (&Controller{}).OriginalName(src); name != "" {
+		t.Errorf("OriginalName without rewriting = %q", name)
+	}
+}
+
+func TestStrategyFor(t *testing.T) {
+	root := sampleTree(t)
+	dir, _ := os.Stat(root)
+	file, _ := os.Stat(filepath.Join(root, "a.txt"))
+	archive := &Controller{}
+	recursive := &Controller{Settings: Settings{Recursive: true}}
+	for _, tc := range []struct {
+		name    string
+		c       *Controller
+		info    os.FileInfo
+		decrypt bool
+		want    Strategy
+	}{
+		{"file", archive, file, false, Single},
+		{"folder", archive, dir, false, Archive},
+		{"recursive folder", recursive, dir, false, PerFile},
+		{"decrypt folder", archive, dir, true, PerFile},
+		{"decrypt file", recursive, file, true, Single},
+	} {
+		if got := tc.c.StrategyFor(tc.info, tc.decrypt); got != tc.want {
+			t.Errorf("%s: StrategyFor = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSelectionSize(t *testing.T) {
+	root := sampleTree(t)
+	file := filepath.Join(root, "a.txt")
+	missing := filepath.Join(root, "missing")
+	c := &Controller{}
+
+	total, sizes := c.SelectionSize([]string{root, file, missing}, false)
+	// the folder counts a.txt and sub/b.txt: not the ignored log, the ignore file or the container
+	if sizes[root] != 6 || sizes[file] != 4 || sizes[missing] != 0 || total != 10 {
+		t.Errorf("encrypt sizes = %v, total %d", sizes, total)
+	}
+	total, sizes = c.SelectionSize([]string{root}, true)
+	if sizes[root] != int64(len("container")) || total != sizes[root] {
+		t.Errorf("decrypt sizes = %v, total %d", sizes, total)
+	}
+}
+
+func TestSelectionSizeSkipsVolumes(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"notes.001":                   "plain",
+		"big.iso.hadescrypt.000":      "volume",
+		"big.iso.hadescrypt.001":      "volume",
+		"big.iso.hadescrypt.manifest": "{}",
+	})
+	if total, _ := (&Controller{}).SelectionSize([]string{root}, false); total != int64(len("plain")) {
+		t.Errorf("encrypt size = %d, want only notes.001", total)
+	}
+}
+
+func TestDeletionPlan(t *testing.T) {
+	root := sampleTree(t)
+	file := filepath.Join(root, "a.txt")
+	rel := func(plan []DeletionTarget) []string {
+		var out []string
+		for _, d := range plan {
+			r, _ := filepath.Rel(root, d.Path)
+			if d.Folder {
+				r += "/"
+			}
+			out = append(out, filepath.ToSlash(r))
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	for _, tc := range []struct {
+		name     string
+		settings Settings
+		paths    []string
+		decrypt  bool
+		want     []string
+	}{
+		{"delete off", Settings{Recursive: true}, []string{root}, false, nil},
+		{"archive", Settings{DeleteAfter: true}, []string{root}, false, []string{"./"}},
+		{"recursive", Settings{DeleteAfter: true, Recursive: true}, []string{root}, false, []string{"a.txt", "sub/b.txt"}},
+		{"decrypt", Settings{DeleteAfter: true}, []string{root}, true, []string{"sub/old.txt.hadescrypt"}},
+		{"file and missing", Settings{DeleteAfter: true}, []string{file, filepath.Join(root, "gone")}, false, []string{"a.txt"}},
+	} {
+		c := &Controller{Settings: tc.settings}
+		plan, err := c.DeletionPlan(tc.paths, tc.decrypt)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := rel(plan); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: plan = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNeedsTypedConfirmation(t *testing.T) {
+	files := make([]DeletionTarget, ConfirmThreshold)
+	if NeedsTypedConfirmation(files) {
+		t.Error("threshold-sized plan of files needs typing")
+	}
+	if !NeedsTypedConfirmation(append(files, DeletionTarget{})) {
+		t.Error("plan over the threshold does not need typing")
+	}
+	if !NeedsTypedConfirmation([]DeletionTarget{{Path: "x", Folder: true}}) {
+		t.Error("whole folder does not need typing")
+	}
+}
+
+func TestScanCanceled(t *testing.T) {
+	root := sampleTree(t)
+	c := &Controller{Settings: Settings{DeleteAfter: true, Recursive: true}, Canceled: func() bool { return true }}
+	if _, err := c.DeletionPlan([]string{root}, false); !errors.Is(err, apperr.ErrCanceled) {
+		t.Errorf("canceled plan: err = %v, want ErrCanceled", err)
+	}
+}
+
+func TestCheckReadable(t *testing.T) {
+	root := sampleTree(t)
+	if err := CheckReadable(filepath.Join(root, "a.txt")); err != nil {
+		t.Errorf("readable file: %v", err)
+	}
+	if err := CheckReadable(filepath.Join(root, "vanished.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("vanished file: err = %v, want ErrNotExist", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"clip.MP4":               "0123456789",
+		"song.mp3":               "012345",
+		"notes.txt":              "012",
+		"sub/draft.txt":          "0",
+		"sub/skip.log":           "ignored",
+		".hadesignore":           "*.log\n",
+		"sub/old.txt.hadescrypt": "container",
+	})
+	st, err := (&Controller{}).Stats(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Files != 4 || st.Bytes != 20 || st.Encrypted != 1 || st.EncryptedBytes != 9 {
+		t.Errorf("totals %+v", st)
+	}
+	want := []TypeStats{{".mp4", "Video", 1, 10}, {".mp3", "Audio", 1, 6}, {".txt", "Documents", 2, 4}}
+	if !slices.Equal(st.Types, want) {
+		t.Errorf("types %+v, want %+v", st.Types, want)
+	}
+	if len(st.Largest) != 4 || filepath.Base(st.Largest[0].Path) != "clip.MP4" || filepath.Base(st.Largest[3].Path) != "draft.txt" {
+		t.Errorf("largest %v", st.Largest)
+	}
+	if k := st.KindTotals(); len(k) != 3 || k[0].Kind != "Video" || k[2].Files != 2 {
+		t.Errorf("kinds %+v", k)
+	}
+
+	c := &Controller{Canceled: func() bool { return true }}
+	if _, err := c.Stats(root); !errors.Is(err, apperr.ErrCanceled) {
+		t.Errorf("canceled stats: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package operations
+
+import (
+	"os"
+
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+)
+
+// Deletions of more than ConfirmThreshold paths, or of any whole folder, have to be
+// confirmed by typing ConfirmWord rather than with a click
+const (
+	ConfirmThreshold = 10
+	ConfirmWord      = "DELETE"
+)
+
+// DeletionTarget is one path "delete source files" will remove
+type DeletionTarget struct {
+	Path   string
+	Folder bool // removed with everything inside it
+}
+
+// NeedsTypedConfirmation reports whether plan is large or destructive enough to
+// require typing ConfirmWord
+func NeedsTypedConfirmation(plan []DeletionTarget) bool {
+	if len(plan) > ConfirmThreshold {
+		return true
+	}
+	for _, t := range plan {
+		if t.Folder {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectionSize returns the bytes a run over paths processes, in total and per path.
+// Folders count the files they are encrypted or decrypted from. Paths that cannot be
+// read count zero, and folders whose scan fails count what was listed.
+func (c *Controller) SelectionSize(paths []string, decrypt bool) (int64, map[string]int64) {
+	sizes := make(map[string]int64, len(paths))
+	var total int64
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		switch {
+		case fi.IsDir():
+			files, _ := c.Scan(p, decrypt, candidate(decrypt))
+			for _, f := range files {
+				sizes[p] += f.Info.Size()
+			}
+		case fi.Mode().IsRegular():
+			sizes[p] = fi.Size()
+		}
+		total += sizes[p]
+	}
+	return total, sizes
+}
+
+// Estimate quickly sizes the files a run would process below root, sampling
+// trees too large to count while the user is still choosing (see fswalk.Estimate).
+// The exact count is left to the scan at the start of the run; Canceled is not
+// consulted, since the estimate is bounded and belongs to no job.
+func (c *Controller) Estimate(root string, decrypt bool) (fswalk.SizeEstimate, error) {
+	return fswalk.Estimate(root, fswalk.EstimateOptions{Options: c.WalkOptions(root, decrypt), Keep: candidate(decrypt)})
+}
+
+// DeletionPlan lists what a run over paths removes with DeleteAfter on, following
+// the strategy each item is processed with: single files and archived folders go
+// as a whole, PerFile folders lose exactly the files that were processed. Paths
+// that cannot be read are left out; the run itself reports them.
+func (c *Controller) DeletionPlan(paths []string, decrypt bool) ([]DeletionTarget, error) {
+	if !c.DeleteAfter {
+		return nil, nil
+	}
+	var plan []DeletionTarget
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		switch {
+		case fi.IsDir() && c.StrategyFor(fi, decrypt) == PerFile:
+			entries, err := c.Scan(p, decrypt, candidate(decrypt))
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				plan = append(plan, DeletionTarget{Path: e.Path})
+			}
+		case fi.IsDir():
+			plan = append(plan, DeletionTarget{Path: p, Folder: true})
+		case fi.Mode().IsRegular():
+			plan = append(plan, DeletionTarget{Path: p})
+		}
+	}
+	return plan, nil
+}
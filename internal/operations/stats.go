@@ -0,0 +1,136 @@
+package operations
+
+import (
+	"cmp"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+	"github.com/bangundwir/HadesCrypt/internal/ignore"
+)
+
+// LargestCount is how many of the biggest files FolderStats lists
+const LargestCount = 10
+
+// FolderStats describes what encrypting a folder would take in, so media or
+// bulky files can be excluded, or the output split, before the run
+type FolderStats struct {
+	Files int
+	Bytes int64
+	// Types breaks Files and Bytes down by extension, most bytes first
+	Types []TypeStats
+	// Largest lists up to LargestCount files, biggest first
+	Largest []fswalk.Entry
+	// Encrypted counts containers already in the folder; a per-file run skips
+	// them and an archive run encrypts them again
+	Encrypted      int
+	EncryptedBytes int64
+}
+
+// TypeStats is the share of one file extension
+type TypeStats struct {
+	Ext   string // lower case with the dot; empty for names without one
+	Kind  string // broad group such as "Video" or "Documents"
+	Files int
+	Bytes int64
+}
+
+// kinds groups common extensions; the rest are "Other"
+var kinds = map[string]string{}
+
+func init() {
+	for kind, exts := range map[string]string{
+		"Video":       ".mp4 .mkv .mov .avi .wmv .webm .m4v .mpg .mpeg .flv .3gp",
+		"Audio":       ".mp3 .wav .flac .aac .ogg .m4a .wma .opus .aiff",
+		"Images":      ".jpg .jpeg .png .gif .bmp .tif .tiff .webp .heic .raw .cr2 .nef .arw .dng .psd .svg",
+		"Archives":    ".zip .7z .rar .gz .tgz .bz2 .xz .zst .tar .cab",
+		"Disk images": ".iso .img .dmg .vhd .vhdx .vmdk .qcow2",
+		"Documents":   ".pdf .doc .docx .xls .xlsx .ppt .pptx .odt .ods .odp .rtf .txt .md .csv .epub",
+		"Code":        ".go .c .h .cpp .hpp .cs .java .py .js .ts .rs .rb .php .sh .json .yaml .yml .xml .html .css .sql",
+		"Databases":   ".db .sqlite .sqlite3 .mdb .accdb .pst .ost",
+	} {
+		for _, ext := range strings.Fields(exts) {
+			kinds[ext] = kind
+		}
+	}
+}
+
+// KindOf returns the broad group of a file name by its extension
+func KindOf(name string) string {
+	if k, ok := kinds[strings.ToLower(filepath.Ext(name))]; ok {
+		return k
+	}
+	return "Other"
+}
+
+// Stats walks root with the encryption walk options, so excluded paths are
+// left out, and totals what it finds. Unlike Estimate it reads the whole tree
+// and stops on Canceled. Unreadable subfolders are reported in the error
+// alongside the stats of everything else.
+func (c *Controller) Stats(root string) (*FolderStats, error) {
+	entries, err := c.Scan(root, false, func(e fswalk.Entry) bool {
+		return Processable(e.Info) && e.Info.Name() != ignore.FileName
+	})
+	if errors.Is(err, apperr.ErrCanceled) {
+		return nil, err
+	}
+	st := &FolderStats{}
+	byExt := map[string]*TypeStats{}
+	var files []fswalk.Entry
+	for _, e := range entries {
+		size := e.Info.Size()
+		if format.IsEncryptedArtifact(e.Path) {
+			st.Encrypted++
+			st.EncryptedBytes += size
+			continue
+		}
+		st.Files++
+		st.Bytes += size
+		files = append(files, e)
+		ext := strings.ToLower(filepath.Ext(e.Info.Name()))
+		t := byExt[ext]
+		if t == nil {
+			t = &TypeStats{Ext: ext, Kind: KindOf(ext)}
+			byExt[ext] = t
+		}
+		t.Files++
+		t.Bytes += size
+	}
+	for _, t := range byExt {
+		st.Types = append(st.Types, *t)
+	}
+	slices.SortFunc(st.Types, func(a, b TypeStats) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Ext, b.Ext))
+	})
+	slices.SortFunc(files, func(a, b fswalk.Entry) int {
+		return cmp.Or(cmp.Compare(b.Info.Size(), a.Info.Size()), cmp.Compare(a.Path, b.Path))
+	})
+	st.Largest = files[:min(len(files), LargestCount)]
+	return st, err
+}
+
+// KindTotals folds Types into their kinds, most bytes first
+func (st *FolderStats) KindTotals() []TypeStats {
+	byKind := map[string]*TypeStats{}
+	var out []TypeStats
+	for _, t := range st.Types {
+		k := byKind[t.Kind]
+		if k == nil {
+			k = &TypeStats{Kind: t.Kind}
+			byKind[t.Kind] = k
+		}
+		k.Files += t.Files
+		k.Bytes += t.Bytes
+	}
+	for _, k := range byKind {
+		out = append(out, *k)
+	}
+	slices.SortFunc(out, func(a, b TypeStats) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Kind, b.Kind))
+	})
+	return out
+}
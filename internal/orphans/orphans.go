@@ -0,0 +1,190 @@
+// Package orphans finds the temporary and partial files that interrupted runs
+// leave behind (private temp folders, half-written archives and decryptions,
+// staging folders, atomic-write temps) and destroys them on request.
+package orphans
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+	"github.com/bangundwir/HadesCrypt/internal/txn"
+)
+
+// Orphan is one leftover file or folder
+type Orphan struct {
+	Path    string
+	Kind    string // what left it, for the listing
+	Dir     bool
+	Size    int64     // a folder's is the total of its files
+	ModTime time.Time // a folder's is that of its newest file, not its own
+}
+
+// Options configures Scan
+type Options struct {
+	Recursive bool          // search below the given folders too, not just in them
+	MinAge    time.Duration // skip leftovers written more recently: a running job may still own them
+}
+
+// kind matches one sort of leftover by name
+type kind struct {
+	name  string
+	dir   bool
+	match func(name string) bool
+}
+
+var kinds = []kind{
+	{"temporary plaintext", true, prefix(securetemp.TempPrefix)},
+	{"staged batch output", true, prefix(txn.StagingPrefix)},
+	{"partial decryption", false, suffix(".__dec_tmp__")},
+	{"folder archive", false, suffix(".temp.tar.gz")},
+	{"partial output", false, suffix(".partial")},
+	{"edit re-encryption", false, suffix(".__edit_tmp__")},
+	{"details update", false, suffix(".restamp")},
+	{"security audit re-encryption", false, createTemp(".reencrypt")},
+	{"archive export", false, createTemp(".export")},
+	{"LAN transfer", false, createTemp(".part")},
+	{"settings or journal write", false, createTemp(".tmp")},
+	{"sync transfer", false, prefix(".hcsync-")},
+}
+
+func prefix(p string) func(string) bool {
+	return func(n string) bool { return strings.HasPrefix(n, p) }
+}
+func suffix(s string) func(string) bool {
+	return func(n string) bool { return strings.HasSuffix(n, s) }
+}
+
+// createTemp matches the hidden ".name.<digits><suffix>" names os.CreateTemp
+// gives for the pattern "."+name+".*"+suffix, and nothing an editor or another
+// program is likely to call its own files
+func createTemp(suf string) func(string) bool {
+	return func(n string) bool {
+		rest, ok := strings.CutSuffix(n, suf)
+		if !ok || !strings.HasPrefix(rest, ".") {
+			return false
+		}
+		dot := strings.LastIndexByte(rest, '.')
+		digits := rest[dot+1:]
+		return dot > 1 && digits != "" && strings.Trim(digits, "0123456789") == ""
+	}
+}
+
+// Match returns what kind of leftover the entry called name is
+func Match(name string, isDir bool) (string, bool) {
+	for _, k := range kinds {
+		if k.dir == isDir && k.match(name) {
+			return k.name, true
+		}
+	}
+	return "", false
+}
+
+// Scan lists the leftovers in dirs, oldest first. Unreadable folders are
+// skipped, links are not followed, and a staging folder that still holds a
+// file moved aside by an interrupted commit is left out: it is the only copy.
+func Scan(dirs []string, opts Options) []Orphan {
+	var found []Orphan
+	seen := map[string]bool{}
+	cutoff := time.Now().Add(-opts.MinAge)
+	add := func(path string, d fs.DirEntry) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		k, ok := Match(d.Name(), d.IsDir())
+		if !ok {
+			return
+		}
+		o := Orphan{Path: path, Kind: k, Dir: d.IsDir()}
+		if !measure(&o) || o.ModTime.After(cutoff) {
+			return
+		}
+		found = append(found, o)
+	}
+	for _, root := range dirs {
+		if !opts.Recursive {
+			entries, _ := os.ReadDir(root)
+			for _, d := range entries {
+				add(filepath.Join(root, d.Name()), d)
+			}
+			continue
+		}
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if path == root {
+				return nil
+			}
+			add(path, d)
+			if _, ok := Match(d.Name(), true); ok && d.IsDir() {
+				return fs.SkipDir // its contents belong to the leftover
+			}
+			return nil
+		})
+	}
+	slices.SortFunc(found, func(a, b Orphan) int { return a.ModTime.Compare(b.ModTime) })
+	return found
+}
+
+// measure fills in size and time; false means o must not be offered
+func measure(o *Orphan) bool {
+	info, err := os.Lstat(o.Path)
+	if err != nil {
+		return false
+	}
+	if !o.Dir {
+		o.Size, o.ModTime = info.Size(), info.ModTime()
+		return info.Mode().IsRegular()
+	}
+	safe := true
+	filepath.WalkDir(o.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), txn.ReplacedPrefix) {
+			safe = false
+			return fs.SkipAll
+		}
+		if fi, err := d.Info(); err == nil && !d.IsDir() {
+			o.Size += fi.Size()
+			if fi.ModTime().After(o.ModTime) {
+				o.ModTime = fi.ModTime()
+			}
+		}
+		return nil
+	})
+	if o.ModTime.IsZero() {
+		o.ModTime = info.ModTime() // empty
+	}
+	return safe
+}
+
+// Remove destroys o: every file in it with the method its storage allows
+// (securetemp.Shred), then the folder. It returns the first failure.
+func Remove(o Orphan) error {
+	if !o.Dir {
+		return securetemp.Shred(o.Path)
+	}
+	var first error
+	filepath.WalkDir(o.Path, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if err := securetemp.Shred(path); err != nil && first == nil {
+				first = err
+			}
+		}
+		return nil
+	})
+	if err := os.RemoveAll(o.Path); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
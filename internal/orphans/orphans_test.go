@@ -0,0 +1,94 @@
+package orphans
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+	"github.com/bangundwir/HadesCrypt/internal/txn"
+)
+
+func TestMatch(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		dir   bool
+		match bool
+	}{
+		{"report.pdf.hadescrypt.123.__dec_tmp__", false, true},
+		{"report.pdf.hadescrypt.__dec_tmp__", false, true},
+		{"photos.hadescrypt.55.temp.tar.gz", false, true},
+		{".config.json.2846109.tmp", false, true},
+		{".notes.txt.hadescrypt.91.reencrypt", false, true},
+		{".movie.mkv.811.part", false, true},
+		{securetemp.TempPrefix + "4711", true, true},
+		{txn.StagingPrefix + "12", true, true},
+		{"budget.xlsx.tmp", false, false},        // not hidden: not ours
+		{".budget.xlsx.tmp", false, false},       // no CreateTemp digits
+		{".movie.mkv.a1b2c3.part", false, false}, // code-phrase transfers keep their part to resume
+		{securetemp.TempPrefix + "4711", false, false},
+		{"report.pdf", false, false},
+	} {
+		if _, ok := Match(c.name, c.dir); ok != c.match {
+			t.Errorf("Match(%q, dir %v) = %v", c.name, c.dir, ok)
+		}
+	}
+}
+
+func write(t *testing.T, path, data string, age time.Duration) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	when := time.Now().Add(-age)
+	os.Chtimes(path, when, when)
+}
+
+func TestScanAndRemove(t *testing.T) {
+	root := t.TempDir()
+	old := 2 * time.Hour
+	write(t, filepath.Join(root, "a.hadescrypt.1.__dec_tmp__"), "plain", old)
+	write(t, filepath.Join(root, securetemp.TempPrefix+"9", "b.7.temp.tar.gz"), "archive!", old)
+	write(t, filepath.Join(root, "fresh.hadescrypt.2.__dec_tmp__"), "busy", 0)
+	write(t, filepath.Join(root, "keep.txt"), "mine", old)
+	write(t, filepath.Join(root, "sub", ".config.json.3.tmp"), "{}", old)
+	write(t, filepath.Join(root, txn.StagingPrefix+"1", txn.ReplacedPrefix+"original.doc"), "only copy", old)
+
+	found := Scan([]string{root}, Options{MinAge: time.Hour})
+	if len(found) != 2 {
+		t.Fatalf("shallow scan found %+v", found)
+	}
+	var folder Orphan
+	for _, o := range found {
+		if o.Dir {
+			folder = o
+		}
+	}
+	if folder.Size != int64(len("archive!")) || folder.Kind != "temporary plaintext" {
+		t.Errorf("private folder %+v", folder)
+	}
+	if n := len(Scan([]string{root}, Options{Recursive: true, MinAge: time.Hour})); n != 3 {
+		t.Errorf("recursive scan found %d, want 3", n)
+	}
+	if n := len(Scan([]string{root, root}, Options{})); n != 3 {
+		t.Errorf("scan without a minimum age, folder listed twice: found %d, want 3", n)
+	}
+
+	for _, o := range found {
+		if err := Remove(o); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Lstat(o.Path); !os.IsNotExist(err) {
+			t.Errorf("%s survived: %v", o.Path, err)
+		}
+	}
+	for _, keep := range []string{"keep.txt", "fresh.hadescrypt.2.__dec_tmp__", txn.StagingPrefix + "1"} {
+		if _, err := os.Stat(filepath.Join(root, keep)); err != nil {
+			t.Errorf("%s removed: %v", keep, err)
+		}
+	}
+}
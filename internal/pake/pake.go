@@ -0,0 +1,179 @@
+// Package pake lets two parties that share a short code agree on a key with
+// SPAKE2 over edwards25519, then prove to each other that they used the same
+// code. An eavesdropper learns nothing about the code, and an active attacker
+// gets a single guess per exchange.
+package pake
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"filippo.io/edwards25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrWrongCode means the peer used a different code
+var ErrWrongCode = errors.New("pairing code does not match")
+
+// The blinding points M and N are derived by hashing fixed labels to the
+// curve, so nobody knows their discrete logs.
+var (
+	pointM = hashToPoint("HadesCrypt LAN SPAKE2 M")
+	pointN = hashToPoint("HadesCrypt LAN SPAKE2 N")
+)
+
+// hashToPoint maps label to a prime-order point by try-and-increment
+func hashToPoint(label string) *edwards25519.Point {
+	for i := uint32(0); ; i++ {
+		h := sha256.New()
+		h.Write([]byte(label))
+		binary.Write(h, binary.BigEndian, i)
+		p, err := new(edwards25519.Point).SetBytes(h.Sum(nil))
+		if err != nil {
+			continue
+		}
+		p.MultByCofactor(p)
+		if p.Equal(edwards25519.NewIdentityPoint()) == 0 {
+			return p
+		}
+	}
+}
+
+// exchange is one side of a SPAKE2 run; the initiator plays A, the responder B
+type exchange struct {
+	initiator bool
+	w         *edwards25519.Scalar
+	x         *edwards25519.Scalar
+	msg       []byte // our public share
+	binding   []byte // context both sides must agree on, e.g. a TLS certificate hash
+}
+
+// keys are the outputs of a completed exchange
+type keys struct {
+	initiator, responder []byte // key-confirmation keys
+	session              []byte
+}
+
+func newExchange(code string, initiator bool, binding []byte) (*exchange, error) {
+	wHash := sha512.Sum512([]byte("HadesCrypt LAN pairing code\x00" + code))
+	w, err := edwards25519.NewScalar().SetUniformBytes(wHash[:])
+	if err != nil {
+		return nil, err
+	}
+	var seed [64]byte
+	if _, err := io.ReadFull(rand.Reader, seed[:]); err != nil {
+		return nil, err
+	}
+	x, err := edwards25519.NewScalar().SetUniformBytes(seed[:])
+	if err != nil {
+		return nil, err
+	}
+	blind := pointN
+	if initiator {
+		blind = pointM
+	}
+	// X = x·G + w·M for the initiator, Y = y·G + w·N for the responder
+	share := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(w, blind, x)
+	return &exchange{initiator: initiator, w: w, x: x, msg: share.Bytes(), binding: binding}, nil
+}
+
+// finish combines the peer's share into the confirmation and session keys
+func (e *exchange) finish(peerMsg []byte) (*keys, error) {
+	peer, err := new(edwards25519.Point).SetBytes(peerMsg)
+	if err != nil {
+		return nil, errors.New("invalid pairing message")
+	}
+	blind := pointM
+	if e.initiator {
+		blind = pointN
+	}
+	unblinded := new(edwards25519.Point).Subtract(peer, new(edwards25519.Point).ScalarMult(e.w, blind))
+	z := new(edwards25519.Point).ScalarMult(e.x, unblinded)
+	z.MultByCofactor(z)
+	if z.Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return nil, errors.New("invalid pairing message")
+	}
+
+	shareA, shareB := e.msg, peerMsg
+	if !e.initiator {
+		shareA, shareB = peerMsg, e.msg
+	}
+	transcript := sha256.New()
+	for _, part := range [][]byte{[]byte("HadesCrypt LAN v1"), e.binding, shareA, shareB, z.Bytes(), e.w.Bytes()} {
+		binary.Write(transcript, binary.BigEndian, uint64(len(part)))
+		transcript.Write(part)
+	}
+	out := make([]byte, 96)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, transcript.Sum(nil), nil, []byte("confirmation keys")), out); err != nil {
+		return nil, err
+	}
+	return &keys{initiator: out[:32], responder: out[32:64], session: out[64:]}, nil
+}
+
+// confirm computes the key-confirmation MAC a side sends to prove it knows the code
+func confirm(key []byte, role string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(role))
+	return m.Sum(nil)
+}
+
+// Handshake runs SPAKE2 and key confirmation over conn and returns a 32-byte
+// session key. The initiator sends magic and its share, the responder answers
+// with its share and MAC, and the initiator replies with its MAC. binding is
+// mixed into the transcript; both sides must pass the same value.
+func Handshake(conn io.ReadWriter, magic, code string, initiator bool, binding []byte) ([]byte, error) {
+	e, err := newExchange(code, initiator, binding)
+	if err != nil {
+		return nil, err
+	}
+	if initiator {
+		if _, err := conn.Write(append([]byte(magic), e.msg...)); err != nil {
+			return nil, err
+		}
+		reply := make([]byte, 32+sha256.Size)
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return nil, fmt.Errorf("pairing: %w", err)
+		}
+		k, err := e.finish(reply[:32])
+		if err != nil {
+			return nil, err
+		}
+		if !hmac.Equal(reply[32:], confirm(k.responder, "receiver")) {
+			return nil, ErrWrongCode
+		}
+		if _, err := conn.Write(confirm(k.initiator, "sender")); err != nil {
+			return nil, err
+		}
+		return k.session, nil
+	}
+
+	hello := make([]byte, len(magic)+32)
+	if _, err := io.ReadFull(conn, hello); err != nil {
+		return nil, err
+	}
+	if string(hello[:len(magic)]) != magic {
+		return nil, errors.New("not a HadesCrypt sender")
+	}
+	k, err := e.finish(hello[len(magic):])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(append(append([]byte{}, e.msg...), confirm(k.responder, "receiver")...)); err != nil {
+		return nil, err
+	}
+	mac := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(conn, mac); err != nil {
+		// an initiator with the wrong code hangs up after checking our MAC
+		return nil, ErrWrongCode
+	}
+	if !hmac.Equal(mac, confirm(k.initiator, "sender")) {
+		return nil, ErrWrongCode
+	}
+	return k.session, nil
+}
@@ -0,0 +1,146 @@
+package pake
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+
+	"filippo.io/edwards25519"
+)
+
+const testMagic = "TEST1"
+
+type result struct {
+	key []byte
+	err error
+}
+
+// pair runs both sides of a handshake over an in-memory connection. Each side
+// hangs up when it is done, as callers do.
+func pair(t *testing.T, codeA, codeB string, bindingA, bindingB []byte) (initiator, responder result) {
+	t.Helper()
+	a, b := net.Pipe()
+	done := make(chan result, 1)
+	go func() {
+		defer b.Close()
+		key, err := Handshake(b, testMagic, codeB, false, bindingB)
+		done <- result{key, err}
+	}()
+	key, err := Handshake(a, testMagic, codeA, true, bindingA)
+	a.Close()
+	return result{key, err}, <-done
+}
+
+func TestHandshake(t *testing.T) {
+	binding := []byte("certificate hash")
+	a, b := pair(t, "7-crossbow-orbit", "7-crossbow-orbit", binding, binding)
+	if a.err != nil || b.err != nil {
+		t.Fatalf("initiator: %v, responder: %v", a.err, b.err)
+	}
+	if len(a.key) != 32 || !bytes.Equal(a.key, b.key) {
+		t.Errorf("session keys differ:\n%x\n%x", a.key, b.key)
+	}
+}
+
+func TestHandshakeWrongCode(t *testing.T) {
+	a, b := pair(t, "7-crossbow-orbit", "7-crossbow-orbiT", nil, nil)
+	if !errors.Is(a.err, ErrWrongCode) || !errors.Is(b.err, ErrWrongCode) {
+		t.Errorf("initiator: %v, responder: %v; want ErrWrongCode on both", a.err, b.err)
+	}
+	if a.key != nil || b.key != nil {
+		t.Error("a key was returned for a wrong code")
+	}
+}
+
+func TestHandshakeBindingMismatch(t *testing.T) {
+	// a machine in the middle terminating TLS shows each side its own certificate
+	a, b := pair(t, "7-crossbow-orbit", "7-crossbow-orbit", []byte("certificate A"), []byte("certificate B"))
+	if !errors.Is(a.err, ErrWrongCode) || !errors.Is(b.err, ErrWrongCode) {
+		t.Errorf("initiator: %v, responder: %v; want ErrWrongCode on both", a.err, b.err)
+	}
+}
+
+func TestHandshakeWrongMagic(t *testing.T) {
+	a, b := net.Pipe()
+	go func() {
+		defer a.Close()
+		Handshake(a, "OTHER", "code", true, nil)
+	}()
+	if _, err := Handshake(b, testMagic, "code", false, nil); err == nil || errors.Is(err, ErrWrongCode) {
+		t.Errorf("got %v, want a protocol error", err)
+	}
+	b.Close()
+}
+
+func TestTranscriptNotFromCodeAlone(t *testing.T) {
+	// shares and keys are fresh each run, so the same code never repeats them
+	first, _ := pair(t, "4-velvet-anchor", "4-velvet-anchor", nil, nil)
+	second, _ := pair(t, "4-velvet-anchor", "4-velvet-anchor", nil, nil)
+	if first.err != nil || second.err != nil || bytes.Equal(first.key, second.key) {
+		t.Errorf("two runs with one code gave the same session key (%v, %v)", first.err, second.err)
+	}
+	e1, err := newExchange("4-velvet-anchor", true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := newExchange("4-velvet-anchor", true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(e1.msg, e2.msg) {
+		t.Error("two shares for one code are equal")
+	}
+
+	// an attacker that knows the code but not the responder's secret scalar
+	// cannot reproduce the keys from the code and the public shares
+	resp, err := newExchange("4-velvet-anchor", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := resp.finish(e1.msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guess, err := newExchange("4-velvet-anchor", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guess.msg = resp.msg
+	g, err := guess.finish(e1.msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(k.session, g.session) || bytes.Equal(k.responder, g.responder) {
+		t.Error("keys follow from the code and the public shares alone")
+	}
+
+	// the keys bind the binding as well as the code
+	bound, err := newExchange("4-velvet-anchor", false, []byte("certificate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bound.x, bound.msg = resp.x, resp.msg
+	b, err := bound.finish(e1.msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(k.session, b.session) {
+		t.Error("the binding does not change the keys")
+	}
+}
+
+func TestFinishRejectsInvalidShares(t *testing.T) {
+	e, err := newExchange("code", true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.finish(make([]byte, 31)); err == nil {
+		t.Error("a short share was accepted")
+	}
+	// w·N unblinds to the identity, which would fix the shared point
+	wN := new(edwards25519.Point).ScalarMult(e.w, pointN)
+	if _, err := e.finish(wN.Bytes()); err == nil {
+		t.Error("a share unblinding to the identity was accepted")
+	}
+}
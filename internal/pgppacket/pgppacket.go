@@ -0,0 +1,616 @@
+// Package pgppacket lists the packets of an OpenPGP message (RFC 4880 and
+// RFC 9580) without decrypting anything or calling gpg, so a user can see what
+// a .gpg or .pgp file is before handing it a password: which cipher protects
+// it, how the password is stretched, who it is encrypted to, and for
+// unencrypted messages the name stored with the data.
+package pgppacket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Limits on what Parse walks through, so a crafted message cannot keep it busy
+const (
+	maxPackets = 256
+	maxDepth   = 4       // compressed packets inside compressed packets
+	maxHeader  = 1 << 16 // bytes of a packet body read to describe it
+)
+
+// ErrNotOpenPGP is returned when the input is neither binary nor armored OpenPGP
+var ErrNotOpenPGP = errors.New("not an OpenPGP message")
+
+// Message is what Parse found
+type Message struct {
+	Armored      bool
+	ArmorType    string   // "PGP MESSAGE", "PGP PUBLIC KEY BLOCK", …
+	ArmorHeaders []string // "Version: …", "Comment: …"
+	Packets      []Packet
+}
+
+// Packet describes one packet; Depth counts the compressed packets around it
+type Packet struct {
+	Tag     int
+	Name    string
+	Length  int64 // body length; -1 for partial or indeterminate lengths
+	Depth   int
+	Details []string // "Cipher: AES-256", …
+	Warning string   // set for legacy or weak constructions
+}
+
+// ParseFile parses the message in the file at path
+func ParseFile(path string) (*Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads packets from r, binary or ASCII armored, up to and including the
+// first encrypted data packet; what follows it is ciphertext
+func Parse(r io.Reader) (*Message, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, ErrNotOpenPGP
+	}
+	m := &Message{}
+	var body io.Reader = br
+	if first[0]&0x80 == 0 {
+		if body, err = dearmor(br, m); err != nil {
+			return nil, err
+		}
+	}
+	p := &parser{msg: m}
+	if err := p.packets(bufio.NewReader(body), 0); err != nil && len(m.Packets) == 0 {
+		return nil, err
+	} else if err != nil {
+		m.Packets = append(m.Packets, Packet{Name: "Unreadable data", Length: -1, Warning: err.Error()})
+	}
+	if len(m.Packets) == 0 {
+		return nil, ErrNotOpenPGP
+	}
+	return m, nil
+}
+
+// Summary is a one-line description for the selection label, such as
+// "AES-256 • password (iterated S2K, SHA-256) • armored"
+func (m *Message) Summary() string {
+	var parts []string
+	var recipients, passwords int
+	for _, p := range m.Packets {
+		switch p.Tag {
+		case tagPKESK:
+			recipients++
+		case tagSKESK:
+			passwords++
+		case tagSEIPD, tagSED, tagAEAD:
+			if c := p.detail("Cipher"); c != "" {
+				parts = append(parts, c)
+			}
+		case tagLiteral:
+			parts = append(parts, "not encrypted") // encrypted data is never read this far
+		}
+	}
+	if passwords > 0 {
+		s2k := "password"
+		for _, p := range m.Packets {
+			if p.Tag == tagSKESK {
+				if s := p.detail("S2K"); s != "" {
+					s2k += " (" + s + ")"
+				}
+				if c := p.detail("Cipher"); c != "" && len(parts) == 0 {
+					parts = append(parts, c)
+				}
+				break
+			}
+		}
+		parts = append(parts, s2k)
+	}
+	if recipients > 0 {
+		parts = append(parts, fmt.Sprintf("%d recipient key(s)", recipients))
+	}
+	if m.Armored {
+		parts = append(parts, "armored")
+	}
+	if len(parts) == 0 && len(m.Packets) > 0 {
+		parts = append(parts, m.Packets[0].Name)
+	}
+	return strings.Join(parts, " • ")
+}
+
+func (p Packet) detail(key string) string {
+	for _, d := range p.Details {
+		if v, ok := strings.CutPrefix(d, key+": "); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// dearmor checks the armor header lines and returns the decoded base64 body
+func dearmor(br *bufio.Reader, m *Message) (io.Reader, error) {
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if t, ok := strings.CutPrefix(line, "-----BEGIN "); ok && strings.HasSuffix(t, "-----") {
+			m.Armored, m.ArmorType = true, strings.TrimSuffix(t, "-----")
+			break
+		}
+		if err != nil {
+			return nil, ErrNotOpenPGP
+		}
+	}
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("armor: %w", io.ErrUnexpectedEOF)
+		}
+		if !strings.Contains(line, ": ") {
+			// no blank line after the headers; this already is base64
+			return base64.NewDecoder(base64.StdEncoding, io.MultiReader(strings.NewReader(line), &armorBody{r: br})), nil
+		}
+		m.ArmorHeaders = append(m.ArmorHeaders, line)
+	}
+	return base64.NewDecoder(base64.StdEncoding, &armorBody{r: br}), nil
+}
+
+// armorBody yields the base64 lines of an armor, ending at the checksum or footer
+type armorBody struct {
+	r    *bufio.Reader
+	line []byte
+	done bool
+}
+
+func (a *armorBody) Read(p []byte) (int, error) {
+	for len(a.line) == 0 {
+		if a.done {
+			return 0, io.EOF
+		}
+		line, err := a.r.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "=") || strings.HasPrefix(line, "-----") || (line == "" && err != nil) {
+			a.done = true
+			continue
+		}
+		a.line = []byte(line)
+		if err != nil {
+			a.done = true
+		}
+	}
+	n := copy(p, a.line)
+	a.line = a.line[n:]
+	return n, nil
+}
+
+// Packet tags (RFC 9580 section 5)
+const (
+	tagPKESK      = 1
+	tagSignature  = 2
+	tagSKESK      = 3
+	tagOnePass    = 4
+	tagSecretKey  = 5
+	tagPublicKey  = 6
+	tagSecretSub  = 7
+	tagCompressed = 8
+	tagSED        = 9
+	tagMarker     = 10
+	tagLiteral    = 11
+	tagTrust      = 12
+	tagUserID     = 13
+	tagPublicSub  = 14
+	tagUserAttr   = 17
+	tagSEIPD      = 18
+	tagMDC        = 19
+	tagAEAD       = 20
+	tagPadding    = 21
+)
+
+var tagNames = map[int]string{
+	tagPKESK: "Public-key encrypted session key", tagSignature: "Signature",
+	tagSKESK: "Password encrypted session key", tagOnePass: "One-pass signature",
+	tagSecretKey: "Secret key", tagPublicKey: "Public key", tagSecretSub: "Secret subkey",
+	tagCompressed: "Compressed data", tagSED: "Encrypted data (no integrity protection)",
+	tagMarker: "Marker", tagLiteral: "Literal data", tagTrust: "Trust", tagUserID: "User ID",
+	tagPublicSub: "Public subkey", tagUserAttr: "User attribute",
+	tagSEIPD: "Encrypted data (integrity protected)", tagMDC: "Modification detection code",
+	tagAEAD: "AEAD encrypted data (GnuPG OCB)", tagPadding: "Padding",
+}
+
+type parser struct {
+	msg *Message
+}
+
+// packets reads packets from r until it ends or an encrypted data packet is found
+func (p *parser) packets(r *bufio.Reader, depth int) error {
+	for {
+		if len(p.msg.Packets) >= maxPackets {
+			return nil
+		}
+		tag, body, length, err := next(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := tagNames[tag]
+		if name == "" {
+			name = fmt.Sprintf("Unknown packet %d", tag)
+		}
+		pkt := Packet{Tag: tag, Name: name, Length: length, Depth: depth}
+		if tag == tagCompressed {
+			algo, _ := readByte(body)
+			pkt.Details = append(pkt.Details, "Algorithm: "+compressionName(algo))
+			p.msg.Packets = append(p.msg.Packets, pkt)
+			if depth < maxDepth {
+				inner, err := decompressor(algo, body)
+				if err != nil {
+					return err
+				}
+				if err := p.packets(bufio.NewReader(inner), depth+1); err != nil {
+					return err
+				}
+			}
+			if _, err := io.Copy(io.Discard, body); err != nil {
+				return err
+			}
+			continue
+		}
+		head, _ := io.ReadAll(io.LimitReader(body, maxHeader))
+		describe(&pkt, head)
+		p.msg.Packets = append(p.msg.Packets, pkt)
+		if tag == tagSEIPD || tag == tagSED || tag == tagAEAD {
+			return nil // the rest is ciphertext
+		}
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return err
+		}
+	}
+}
+
+// next reads a packet header and returns the tag and a reader over its body
+func next(r *bufio.Reader) (tag int, body io.Reader, length int64, err error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, 0, io.EOF
+	}
+	if b&0x80 == 0 {
+		return 0, nil, 0, ErrNotOpenPGP
+	}
+	if b&0x40 == 0 {
+		// legacy format: tag in bits 5-2, length type in bits 1-0
+		tag = int(b>>2) & 0x0f
+		var n int
+		switch b & 3 {
+		case 0:
+			n = 1
+		case 1:
+			n = 2
+		case 2:
+			n = 4
+		default:
+			return tag, r, -1, nil
+		}
+		var buf [4]byte
+		if _, err := io.ReadFull(r, buf[4-n:]); err != nil {
+			return 0, nil, 0, fmt.Errorf("packet header: %w", io.ErrUnexpectedEOF)
+		}
+		length = int64(binary.BigEndian.Uint32(buf[:]))
+		return tag, io.LimitReader(r, length), length, nil
+	}
+	tag = int(b & 0x3f)
+	length, partial, err := newLength(r)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	if partial {
+		return tag, &partialReader{r: r, left: length}, -1, nil
+	}
+	return tag, io.LimitReader(r, length), length, nil
+}
+
+// newLength reads an OpenPGP length; partial lengths give one chunk of a body
+func newLength(r *bufio.Reader) (n int64, partial bool, err error) {
+	o, err := r.ReadByte()
+	if err != nil {
+		return 0, false, fmt.Errorf("packet length: %w", io.ErrUnexpectedEOF)
+	}
+	switch {
+	case o < 192:
+		return int64(o), false, nil
+	case o < 224:
+		o2, err := r.ReadByte()
+		if err != nil {
+			return 0, false, fmt.Errorf("packet length: %w", io.ErrUnexpectedEOF)
+		}
+		return (int64(o)-192)<<8 + int64(o2) + 192, false, nil
+	case o == 255:
+		var buf [4]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return 0, false, fmt.Errorf("packet length: %w", io.ErrUnexpectedEOF)
+		}
+		return int64(binary.BigEndian.Uint32(buf[:])), false, nil
+	}
+	return 1 << (o & 0x1f), true, nil
+}
+
+// partialReader joins the chunks of a body sent with partial lengths
+type partialReader struct {
+	r    *bufio.Reader
+	left int64
+	last bool
+}
+
+func (p *partialReader) Read(b []byte) (int, error) {
+	for p.left == 0 {
+		if p.last {
+			return 0, io.EOF
+		}
+		n, partial, err := newLength(p.r)
+		if err != nil {
+			return 0, err
+		}
+		p.left, p.last = n, !partial
+	}
+	if int64(len(b)) > p.left {
+		b = b[:p.left]
+	}
+	n, err := p.r.Read(b)
+	p.left -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func readByte(r io.Reader) (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r, b[:])
+	return b[0], err
+}
+
+func decompressor(algo byte, r io.Reader) (io.Reader, error) {
+	switch algo {
+	case 0:
+		return r, nil
+	case 1:
+		return flate.NewReader(r), nil
+	case 2:
+		return zlib.NewReader(r)
+	case 3:
+		return bzip2.NewReader(r), nil
+	}
+	return nil, fmt.Errorf("unknown compression algorithm %d", algo)
+}
+
+// describe fills in the details of a packet from the start of its body
+func describe(pkt *Packet, b []byte) {
+	add := func(format string, args ...any) { pkt.Details = append(pkt.Details, fmt.Sprintf(format, args...)) }
+	switch pkt.Tag {
+	case tagSKESK:
+		if len(b) < 2 {
+			return
+		}
+		add("Version: %d", b[0])
+		cipher := b[1]
+		rest := b[2:]
+		if b[0] >= 5 {
+			// v5/v6: count, cipher, AEAD mode, S2K length (v6), S2K
+			if len(b) < 4 {
+				return
+			}
+			if b[0] == 6 {
+				cipher, rest = b[2], b[5:]
+				add("Cipher: %s", cipherName(cipher))
+				add("AEAD: %s", aeadName(b[3]))
+			} else {
+				add("Cipher: %s", cipherName(cipher))
+				add("AEAD: %s", aeadName(b[2]))
+				rest = b[3:]
+			}
+		} else {
+			add("Cipher: %s", cipherName(cipher))
+		}
+		s2k(pkt, rest)
+		weakCipher(pkt, cipher)
+	case tagPKESK:
+		if len(b) < 1 {
+			return
+		}
+		add("Version: %d", b[0])
+		if b[0] == 3 && len(b) >= 10 {
+			id := strings.ToUpper(hex.EncodeToString(b[1:9]))
+			if id == "0000000000000000" {
+				id += " (hidden recipient)"
+			}
+			add("Key ID: %s", id)
+			add("Key algorithm: %s", publicKeyName(b[9]))
+		} else if b[0] == 6 && len(b) >= 2 {
+			n := int(b[1])
+			if n > 0 && len(b) >= 2+n+1 {
+				add("Fingerprint: %s", strings.ToUpper(hex.EncodeToString(b[3:2+n])))
+				add("Key algorithm: %s", publicKeyName(b[2+n]))
+			} else {
+				add("Recipient: anonymous")
+			}
+		}
+	case tagSEIPD:
+		if len(b) < 1 {
+			return
+		}
+		add("Version: %d", b[0])
+		if b[0] == 1 {
+			add("Integrity: SHA-1 modification detection code")
+		} else if b[0] == 2 && len(b) >= 4 {
+			add("Cipher: %s", cipherName(b[1]))
+			add("AEAD: %s", aeadName(b[2]))
+			add("Chunk size: %d bytes", int64(1)<<(b[3]+6))
+			weakCipher(pkt, b[1])
+		}
+	case tagAEAD:
+		if len(b) < 4 {
+			return
+		}
+		add("Version: %d", b[0])
+		add("Cipher: %s", cipherName(b[1]))
+		add("AEAD: %s", aeadName(b[2]))
+		add("Chunk size: %d bytes", int64(1)<<(b[3]+6))
+		weakCipher(pkt, b[1])
+	case tagSED:
+		pkt.Warning = "no integrity protection: changes to the ciphertext go unnoticed (GnuPG refuses it by default)"
+	case tagLiteral:
+		if len(b) < 6 {
+			return
+		}
+		add("Format: %s", literalFormat(b[0]))
+		n := int(b[1])
+		if len(b) >= 2+n+4 {
+			name := string(b[2 : 2+n])
+			if name == "" {
+				name = "(none)"
+			} else if name == "_CONSOLE" {
+				name += " (for your eyes only)"
+			}
+			add("File name: %s", name)
+			if t := binary.BigEndian.Uint32(b[2+n:]); t != 0 {
+				add("Date: %s", time.Unix(int64(t), 0).UTC().Format("2006-01-02 15:04:05 UTC"))
+			}
+		}
+	case tagOnePass:
+		if len(b) >= 4 {
+			add("Hash: %s", hashName(b[2]))
+			add("Key algorithm: %s", publicKeyName(b[3]))
+		}
+		if len(b) >= 12 && b[0] == 3 {
+			add("Key ID: %s", strings.ToUpper(hex.EncodeToString(b[4:12])))
+		}
+	case tagSignature:
+		if len(b) >= 5 && b[0] >= 4 {
+			add("Version: %d", b[0])
+			add("Key algorithm: %s", publicKeyName(b[2]))
+			add("Hash: %s", hashName(b[3]))
+		}
+	case tagPublicKey, tagPublicSub, tagSecretKey, tagSecretSub:
+		if len(b) >= 6 {
+			add("Version: %d", b[0])
+			add("Created: %s", time.Unix(int64(binary.BigEndian.Uint32(b[1:5])), 0).UTC().Format("2006-01-02"))
+			if b[0] == 3 && len(b) >= 8 {
+				add("Key algorithm: %s", publicKeyName(b[7]))
+			} else {
+				add("Key algorithm: %s", publicKeyName(b[5]))
+			}
+		}
+	case tagUserID:
+		add("%s", string(bytes.ToValidUTF8(b, []byte("?"))))
+	}
+}
+
+// s2k describes a string-to-key specifier: how the password becomes a key
+func s2k(pkt *Packet, b []byte) {
+	if len(b) < 1 {
+		return
+	}
+	add := func(format string, args ...any) { pkt.Details = append(pkt.Details, fmt.Sprintf(format, args...)) }
+	switch b[0] {
+	case 0:
+		if len(b) >= 2 {
+			add("S2K: simple, %s", hashName(b[1]))
+		}
+		pkt.Warning = "simple S2K: the password is hashed once, without salt"
+	case 1:
+		if len(b) >= 2 {
+			add("S2K: salted, %s", hashName(b[1]))
+		}
+		pkt.Warning = "salted S2K: the password is hashed once, so guessing it is cheap"
+	case 3:
+		if len(b) >= 11 {
+			c := b[10]
+			add("S2K: iterated and salted, %s", hashName(b[1]))
+			add("S2K salt: %s", hex.EncodeToString(b[2:10]))
+			add("S2K bytes hashed: %d", int64(16+int(c&15))<<(uint(c>>4)+6))
+		}
+	case 4:
+		if len(b) >= 20 {
+			add("S2K: Argon2")
+			add("S2K salt: %s", hex.EncodeToString(b[1:17]))
+			add("Argon2: %d passes, %d lanes, %s memory", b[17], b[18], memory(b[19]))
+		}
+	case 101:
+		add("S2K: GnuPG extension (no secret stored)")
+	default:
+		add("S2K: unknown type %d", b[0])
+	}
+}
+
+func memory(exp byte) string {
+	kib := int64(1) << exp
+	if kib >= 1<<20 {
+		return fmt.Sprintf("%d GiB", kib>>20)
+	}
+	if kib >= 1<<10 {
+		return fmt.Sprintf("%d MiB", kib>>10)
+	}
+	return fmt.Sprintf("%d KiB", kib)
+}
+
+func weakCipher(pkt *Packet, c byte) {
+	if c >= 1 && c <= 4 && pkt.Warning == "" {
+		pkt.Warning = cipherName(c) + " is a legacy 64-bit block cipher"
+	}
+}
+
+func cipherName(c byte) string {
+	names := map[byte]string{0: "none", 1: "IDEA", 2: "TripleDES", 3: "CAST5", 4: "Blowfish", 7: "AES-128", 8: "AES-192",
+		9: "AES-256", 10: "Twofish", 11: "Camellia-128", 12: "Camellia-192", 13: "Camellia-256"}
+	return named(names, c)
+}
+
+func aeadName(a byte) string {
+	return named(map[byte]string{1: "EAX", 2: "OCB", 3: "GCM"}, a)
+}
+
+func hashName(h byte) string {
+	names := map[byte]string{1: "MD5", 2: "SHA-1", 3: "RIPEMD-160", 8: "SHA-256", 9: "SHA-384", 10: "SHA-512",
+		11: "SHA-224", 12: "SHA3-256", 14: "SHA3-512"}
+	return named(names, h)
+}
+
+func publicKeyName(a byte) string {
+	names := map[byte]string{1: "RSA", 2: "RSA (encrypt only)", 3: "RSA (sign only)", 16: "ElGamal", 17: "DSA", 18: "ECDH",
+		19: "ECDSA", 22: "EdDSA", 25: "X25519", 26: "X448", 27: "Ed25519", 28: "Ed448"}
+	return named(names, a)
+}
+
+func compressionName(a byte) string {
+	return named(map[byte]string{0: "uncompressed", 1: "ZIP", 2: "ZLIB", 3: "BZip2"}, a)
+}
+
+func literalFormat(f byte) string {
+	return named(map[byte]string{'b': "binary", 't': "text", 'u': "UTF-8 text", 'm': "MIME"}, f)
+}
+
+func named(names map[byte]string, v byte) string {
+	if n, ok := names[v]; ok {
+		return n
+	}
+	return fmt.Sprintf("unknown (%d)", v)
+}
@@ -0,0 +1,140 @@
+package pgppacket
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// packet builds a packet with a new-format header
+func packet(tag int, body []byte) []byte {
+	if len(body) >= 192 {
+		panic("test bodies are short")
+	}
+	return append([]byte{0xc0 | byte(tag), byte(len(body))}, body...)
+}
+
+// passwordMessage is what gpg --symmetric writes: an SKESK with iterated and
+// salted S2K, then an integrity-protected data packet
+func passwordMessage() []byte {
+	skesk := []byte{4, 9, 3, 8, 1, 2, 3, 4, 5, 6, 7, 8, 0xff}
+	seipd := append([]byte{1}, bytes.Repeat([]byte{0xaa}, 40)...)
+	return append(packet(tagSKESK, skesk), packet(tagSEIPD, seipd)...)
+}
+
+func TestParsePasswordMessage(t *testing.T) {
+	m, err := Parse(bytes.NewReader(passwordMessage()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Armored || len(m.Packets) != 2 {
+		t.Fatalf("message %+v", m)
+	}
+	want := []string{"Version: 4", "Cipher: AES-256", "S2K: iterated and salted, SHA-256", "S2K salt: 0102030405060708", "S2K bytes hashed: 65011712"}
+	if got := m.Packets[0].Details; !slices.Equal(got, want) {
+		t.Errorf("SKESK details %q, want %q", got, want)
+	}
+	if got := m.Summary(); got != "AES-256 • password (iterated and salted, SHA-256)" {
+		t.Errorf("summary %q", got)
+	}
+}
+
+func TestParseArmored(t *testing.T) {
+	enc := base64.StdEncoding.EncodeToString(passwordMessage())
+	armored := "-----BEGIN PGP MESSAGE-----\nVersion: GnuPG v2\nComment: test\n\n" + enc[:40] + "\n" + enc[40:] + "\n=abcd\n-----END PGP MESSAGE-----\n"
+	m, err := Parse(strings.NewReader(armored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Armored || m.ArmorType != "PGP MESSAGE" || !slices.Equal(m.ArmorHeaders, []string{"Version: GnuPG v2", "Comment: test"}) {
+		t.Errorf("armor %+v", m)
+	}
+	if len(m.Packets) != 2 || m.Packets[1].Tag != tagSEIPD {
+		t.Errorf("packets %+v", m.Packets)
+	}
+}
+
+func TestParseCompressedLiteral(t *testing.T) {
+	literal := append([]byte{'b', 9}, "notes.txt"...)
+	literal = append(literal, 0x5f, 0x5e, 0x10, 0x00)
+	literal = append(literal, "hello"...)
+	var z bytes.Buffer
+	fw, _ := flate.NewWriter(&z, flate.BestCompression)
+	fw.Write(packet(tagLiteral, literal))
+	fw.Close()
+	// legacy header with indeterminate length, as gpg writes compressed packets
+	msg := append([]byte{0x80 | tagCompressed<<2 | 3, 1}, z.Bytes()...)
+
+	m, err := Parse(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Packets) != 2 || m.Packets[1].Depth != 1 || m.Packets[1].Tag != tagLiteral {
+		t.Fatalf("packets %+v", m.Packets)
+	}
+	if name := m.Packets[1].detail("File name"); name != "notes.txt" {
+		t.Errorf("file name %q", name)
+	}
+	if m.Packets[1].detail("Date") != "2020-09-13 12:26:40 UTC" {
+		t.Errorf("details %q", m.Packets[1].Details)
+	}
+	if !strings.Contains(m.Summary(), "not encrypted") {
+		t.Errorf("summary %q", m.Summary())
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	// public-key session key for a hidden recipient, then legacy data without integrity protection
+	pkesk := append([]byte{3, 0, 0, 0, 0, 0, 0, 0, 0, 1}, bytes.Repeat([]byte{7}, 20)...)
+	msg := append(packet(tagPKESK, pkesk), packet(tagSED, bytes.Repeat([]byte{1}, 30))...)
+	m, err := Parse(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Packets[0].detail("Key ID"); got != "0000000000000000 (hidden recipient)" {
+		t.Errorf("key id %q", got)
+	}
+	if m.Packets[1].Warning == "" {
+		t.Error("no warning for data without integrity protection")
+	}
+	if got := m.Summary(); got != "1 recipient key(s)" {
+		t.Errorf("summary %q", got)
+	}
+
+	weak := packet(tagSKESK, []byte{4, 3, 0, 2})
+	m, err = Parse(bytes.NewReader(weak))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := m.Packets[0].Warning; !strings.Contains(w, "simple S2K") {
+		t.Errorf("warning %q", w)
+	}
+}
+
+func TestParseRejectsOtherData(t *testing.T) {
+	for _, in := range []string{"", "plain text\n", "-----BEGIN PGP MESSAGE-----\n"} {
+		if _, err := Parse(strings.NewReader(in)); err == nil {
+			t.Errorf("Parse(%q) succeeded", in)
+		}
+	}
+	if _, err := Parse(strings.NewReader("hello")); !errors.Is(err, ErrNotOpenPGP) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestPartialLengths(t *testing.T) {
+	// a SEIPD body sent as a 512-byte partial chunk and a final 3-byte chunk, then nothing
+	body := append([]byte{0xc0 | tagSEIPD, 0xe9}, append([]byte{1}, bytes.Repeat([]byte{0}, 511)...)...)
+	body = append(body, 3, 0, 0, 0)
+	m, err := Parse(bytes.NewReader(append(packet(tagMarker, []byte("PGP")), body...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Packets) != 2 || m.Packets[1].Length != -1 || m.Packets[1].detail("Version") != "1" {
+		t.Errorf("packets %+v", m.Packets)
+	}
+}
@@ -0,0 +1,216 @@
+package pinentry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrCanceled is returned when the user dismisses the prompt
+var ErrCanceled = errors.New("password prompt canceled")
+
+// ErrUnavailable is returned when neither pinentry nor a native prompt could be found
+var ErrUnavailable = errors.New("no pinentry or native password prompt available")
+
+// Request describes what the prompt shows
+type Request struct {
+	Title       string
+	Description string
+	Prompt      string
+	Confirm     bool // ask twice; only honored by pinentry
+}
+
+// pinentryCandidates lists pinentry programs in order of preference; $PINENTRY_PROGRAM wins
+func pinentryCandidates() []string {
+	var c []string
+	if p := os.Getenv("PINENTRY_PROGRAM"); p != "" {
+		c = append(c, p)
+	}
+	if runtime.GOOS == "darwin" {
+		c = append(c, "pinentry-mac")
+	}
+	return append(c, "pinentry", "pinentry-gnome3", "pinentry-qt", "pinentry-gtk-2")
+}
+
+// Available reports which backend Prompt would use, or "" when none is installed
+func Available() string {
+	for _, name := range pinentryCandidates() {
+		if p, err := exec.LookPath(name); err == nil {
+			return p
+		}
+	}
+	if p, err := exec.LookPath(nativeCommand(Request{})[0]); err == nil {
+		return p
+	}
+	return ""
+}
+
+// Prompt asks for a password outside the GUI toolkit, preferring pinentry and
+// falling back to the platform's native password dialog
+func Prompt(req Request) (string, error) {
+	for _, name := range pinentryCandidates() {
+		if p, err := exec.LookPath(name); err == nil {
+			return runPinentry(p, req)
+		}
+	}
+	return runNative(req)
+}
+
+// runPinentry drives a pinentry program over the Assuan protocol
+func runPinentry(program string, req Request) (string, error) {
+	cmd := exec.Command(program)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Wait()
+	}()
+
+	r := bufio.NewReader(stdout)
+	if _, err := readReply(r); err != nil {
+		return "", err
+	}
+	cmds := []string{
+		"SETTITLE " + escape(req.Title),
+		"SETDESC " + escape(req.Description),
+		"SETPROMPT " + escape(req.Prompt),
+	}
+	if req.Confirm {
+		cmds = append(cmds, "SETREPEAT "+escape("Confirm:"), "SETREPEATERROR "+escape("Passwords do not match"))
+	}
+	if tty := os.Getenv("GPG_TTY"); tty != "" {
+		cmds = append(cmds, "OPTION ttyname="+tty)
+	}
+	for _, c := range cmds {
+		if _, err := io.WriteString(stdin, c+"\n"); err != nil {
+			return "", err
+		}
+		if _, err := readReply(r); err != nil {
+			// Older pinentries lack SETREPEAT; carry on without confirmation
+			if strings.HasPrefix(c, "SETREPEAT") {
+				continue
+			}
+			return "", err
+		}
+	}
+	if _, err := io.WriteString(stdin, "GETPIN\n"); err != nil {
+		return "", err
+	}
+	pin, err := readReply(r)
+	if err != nil {
+		return "", err
+	}
+	io.WriteString(stdin, "BYE\n")
+	if pin == "" {
+		return "", ErrCanceled
+	}
+	return pin, nil
+}
+
+// readReply reads Assuan lines until OK or ERR and returns the collected data
+func readReply(r *bufio.Reader) (string, error) {
+	var data strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("pinentry: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "OK" || strings.HasPrefix(line, "OK "):
+			return data.String(), nil
+		case strings.HasPrefix(line, "ERR "):
+			// 83886179 = GPG_ERR_CANCELED from the pinentry source
+			if strings.Contains(line, "83886179") || strings.Contains(strings.ToLower(line), "cancel") {
+				return "", ErrCanceled
+			}
+			return "", fmt.Errorf("pinentry: %s", strings.TrimPrefix(line, "ERR "))
+		case strings.HasPrefix(line, "D "):
+			data.WriteString(unescape(line[2:]))
+		}
+		// S (status) and # (comment) lines are ignored
+	}
+}
+
+// escape percent-encodes the characters Assuan does not allow in parameters
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '%' || c == '\n' || c == '\r' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			var c byte
+			if _, err := fmt.Sscanf(s[i+1:i+3], "%02X", &c); err == nil {
+				b.WriteByte(c)
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// nativeCommand returns the platform password dialog invocation
+func nativeCommand(req Request) []string {
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf(`text returned of (display dialog %q with title %q default answer "" with hidden answer)`, req.Description, req.Title)
+		return []string{"osascript", "-e", script}
+	case "windows":
+		script := fmt.Sprintf(`$c = Get-Credential -UserName 'HadesCrypt' -Message %s; if ($c) { [Console]::Out.Write($c.GetNetworkCredential().Password) } else { exit 1 }`, psQuote(req.Description))
+		return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", script}
+	default:
+		if _, err := exec.LookPath("zenity"); err == nil {
+			return []string{"zenity", "--password", "--title", req.Title}
+		}
+		return []string{"kdialog", "--title", req.Title, "--password", req.Description}
+	}
+}
+
+func runNative(req Request) (string, error) {
+	args := nativeCommand(req)
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return "", ErrUnavailable
+	}
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", ErrCanceled
+		}
+		return "", err
+	}
+	pw := strings.TrimRight(string(out), "\r\n")
+	if pw == "" {
+		return "", ErrCanceled
+	}
+	return pw, nil
+}
+
+func psQuote(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
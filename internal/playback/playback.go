@@ -0,0 +1,120 @@
+// Package playback streams decrypted containers to media players over a
+// loopback HTTP server with range requests, so players can seek while only the
+// chunks they ask for are decrypted. Nothing is written to disk. Each stream
+// lives under an unguessable path, since other local users can reach loopback.
+package playback
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+// IsMedia reports whether name looks like an audio or video file
+func IsMedia(name string) bool {
+	t := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	return strings.HasPrefix(t, "audio/") || strings.HasPrefix(t, "video/")
+}
+
+type stream struct {
+	r       *cryptoengine.Reader
+	name    string
+	modTime time.Time
+}
+
+// Server serves the streams added to it until closed
+type Server struct {
+	ln  net.Listener
+	srv *http.Server
+
+	mu      sync.Mutex
+	streams map[string]*stream // token → stream
+}
+
+// Start listens on a random loopback port
+func Start() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{ln: ln, streams: map[string]*stream{}}
+	s.srv = &http.Server{Handler: http.HandlerFunc(s.serve), ReadHeaderTimeout: 10 * time.Second}
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+// Add serves r under name and returns its URL; the server takes ownership of r
+func (s *Server) Add(r *cryptoengine.Reader, name string, modTime time.Time) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b[:])
+	s.mu.Lock()
+	s.streams[token] = &stream{r: r, name: name, modTime: modTime}
+	s.mu.Unlock()
+	u := url.URL{Scheme: "http", Host: s.ln.Addr().String(), Path: "/" + token + "/" + name}
+	return u.String(), nil
+}
+
+// Remove stops serving the stream Add returned u for and closes its reader
+func (s *Server) Remove(u string) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return
+	}
+	token := tokenOf(parsed.Path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st := s.streams[token]; st != nil {
+		st.r.Close()
+		delete(s.streams, token)
+	}
+}
+
+// tokenOf returns the first segment of a stream path
+func tokenOf(p string) string {
+	token, _, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	return token
+}
+
+// Close stops the server and closes every reader
+func (s *Server) Close() error {
+	err := s.srv.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, st := range s.streams {
+		st.r.Close()
+		delete(s.streams, token)
+	}
+	return err
+}
+
+func (s *Server) serve(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	st := s.streams[tokenOf(req.URL.Path)]
+	s.mu.Unlock()
+	if st == nil {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Each request reads through its own section; the reader serializes chunk access
+	http.ServeContent(w, req, path.Base(st.name), st.modTime, io.NewSectionReader(st.r, 0, st.r.Size()))
+}
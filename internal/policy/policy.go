@@ -0,0 +1,230 @@
+// Package policy loads the administrator policy file that organizations deploy
+// next to HadesCrypt. The file lives in a system-wide location users cannot
+// write to and nothing in the user's config can relax it.
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+	"github.com/bangundwir/HadesCrypt/internal/sshrecipient"
+)
+
+// RecoveryExtension is appended to an output's name for its escrowed password
+const RecoveryExtension = ".recovery.age"
+
+// ErrViolation is wrapped by every refusal caused by the policy
+var ErrViolation = errors.New("not allowed by the administrator policy")
+
+// ErrViewer is returned for encryption and deletion in viewer mode
+var ErrViewer = errors.New("HadesCrypt is in viewer mode: it can decrypt and verify files but not encrypt or delete them")
+
+// Policy is the administrator policy. A nil *Policy means no policy is
+// installed and allows everything.
+type Policy struct {
+	AllowedModes       []string `json:"allowed_modes,omitempty"`         // mode names as shown in the app; empty allows all
+	MinArgon2Time      uint32   `json:"min_argon2_time,omitempty"`       // Argon2id passes
+	MinArgon2MemoryMiB uint32   `json:"min_argon2_memory_mib,omitempty"` // Argon2id memory
+	ForbidDeleteAfter  bool     `json:"forbid_delete_after,omitempty"`   // never delete originals after encrypting
+	RecoveryRecipients []string `json:"recovery_recipients,omitempty"`   // age or SSH public keys the password of every new file is escrowed to
+	ViewerOnly         bool     `json:"viewer_only,omitempty"`           // decrypt and verify only, as in a viewer build
+
+	Path string `json:"-"` // file the policy was read from
+
+	modes      []cryptoengine.EncryptionMode
+	recipients []sshrecipient.Recipient
+}
+
+// Path returns where the policy file is looked for on this system
+func Path() string {
+	switch runtime.GOOS {
+	case "windows":
+		dir := os.Getenv("ProgramData")
+		if dir == "" {
+			dir = `C:\ProgramData`
+		}
+		return filepath.Join(dir, "HadesCrypt", "policy.json")
+	case "darwin":
+		return "/Library/Application Support/HadesCrypt/policy.json"
+	}
+	return "/etc/hadescrypt/policy.json"
+}
+
+// Load reads the policy from Path. It returns nil and no error when no policy
+// is installed; a policy that exists but cannot be read is an error, so a
+// broken deployment fails closed instead of silently allowing everything.
+func Load() (*Policy, error) {
+	p, err := LoadFile(Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// LoadFile reads and validates the policy at path
+func LoadFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{Path: path}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	for _, name := range p.AllowedModes {
+		mode, ok := cryptoengine.ModeByName(name)
+		if !ok {
+			return nil, fmt.Errorf("policy %s: unknown encryption mode %q", path, name)
+		}
+		p.modes = append(p.modes, mode)
+	}
+	for _, key := range p.RecoveryRecipients {
+		r, err := sshrecipient.ParseRecipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: recovery recipient: %w", path, err)
+		}
+		p.recipients = append(p.recipients, r)
+	}
+	return p, nil
+}
+
+// ModeAllowed reports whether files may be encrypted with mode
+func (p *Policy) ModeAllowed(mode cryptoengine.EncryptionMode) bool {
+	return p == nil || len(p.modes) == 0 || slices.Contains(p.modes, mode)
+}
+
+// Argon2Allowed reports whether params meet the minimum key derivation cost;
+// the zero value stands for DefaultArgon2
+func (p *Policy) Argon2Allowed(params cryptoengine.Argon2Params) bool {
+	if p == nil {
+		return true
+	}
+	if params.IsZero() {
+		params = cryptoengine.DefaultArgon2
+	}
+	return params.Time >= p.MinArgon2Time && params.Memory >= p.MinArgon2MemoryMiB*1024
+}
+
+// AllowedPresets returns the Argon2 presets that meet the minimum, weakest first
+func (p *Policy) AllowedPresets() []string {
+	var names []string
+	for _, name := range cryptoengine.Argon2PresetNames {
+		if p.Argon2Allowed(cryptoengine.Argon2Preset(name)) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ReadOnly reports whether only decryption and verification are allowed,
+// because of a viewer build or the policy's viewer_only
+func (p *Policy) ReadOnly() bool { return ViewerBuild || (p != nil && p.ViewerOnly) }
+
+// DeleteAfterAllowed reports whether originals may be removed after encrypting
+// or decrypting
+func (p *Policy) DeleteAfterAllowed() bool {
+	return !p.ReadOnly() && (p == nil || !p.ForbidDeleteAfter)
+}
+
+// RecoveryRequired reports whether every new file's password must be escrowed
+func (p *Policy) RecoveryRequired() bool { return p != nil && len(p.recipients) > 0 }
+
+// Recipients returns the parsed recovery recipients
+func (p *Policy) Recipients() []sshrecipient.Recipient {
+	if p == nil {
+		return nil
+	}
+	return p.recipients
+}
+
+// CheckEncrypt refuses options the policy does not allow. The Argon2 minimum
+// does not apply to compliance mode (PBKDF2) or GnuPG, which derive keys
+// their own way.
+func (p *Policy) CheckEncrypt(opts cryptoengine.EncryptionOptions) error {
+	if p.ReadOnly() {
+		return ErrViewer
+	}
+	if p == nil {
+		return nil
+	}
+	if !p.ModeAllowed(opts.Mode) {
+		return fmt.Errorf("%s: %w", cryptoengine.GetEncryptionModeName(opts.Mode), ErrViolation)
+	}
+	if !opts.Compliance && opts.Mode != cryptoengine.ModeGnuPG && !p.Argon2Allowed(opts.Argon2) {
+		return fmt.Errorf("key derivation weaker than %d passes and %d MiB: %w", p.MinArgon2Time, p.MinArgon2MemoryMiB, ErrViolation)
+	}
+	if p.RecoveryRequired() && opts.Mode == cryptoengine.ModeGnuPG && len(opts.Recipients) > 0 {
+		return fmt.Errorf("GnuPG public-key encryption has no password to escrow: %w", ErrViolation)
+	}
+	return nil
+}
+
+// CheckDeleteAfter refuses deleting originals when the policy forbids it
+func (p *Policy) CheckDeleteAfter() error {
+	if p.ReadOnly() {
+		return ErrViewer
+	}
+	if !p.DeleteAfterAllowed() {
+		return fmt.Errorf("deleting originals after encryption: %w", ErrViolation)
+	}
+	return nil
+}
+
+// Record is the escrowed secret of one encrypted output
+type Record struct {
+	File     string    `json:"file"`
+	Created  time.Time `json:"created"`
+	Password string    `json:"password,omitempty"` // the password as typed
+	Key      []byte    `json:"key,omitempty"`      // password combined with keyfiles, when keyfiles were used
+}
+
+// Escrow encrypts key to the recovery recipients into output+RecoveryExtension.
+// keyfiles tells whether key is a keyfile-combined key rather than a typed
+// password. It does nothing when the policy requires no recovery.
+func (p *Policy) Escrow(output string, key []byte, keyfiles bool) error {
+	if !p.RecoveryRequired() {
+		return nil
+	}
+	rec := Record{File: filepath.Base(output), Created: time.Now().UTC()}
+	if keyfiles {
+		rec.Key = key
+	} else {
+		rec.Password = string(key)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	defer secret.Wipe(data)
+	path := output + RecoveryExtension
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("escrow password: %w", err)
+	}
+	w, err := sshrecipient.Encrypt(f, p.recipients)
+	if err == nil {
+		_, err = w.Write(data)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("escrow password: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+//go:build viewer
+
+package policy
+
+// ViewerBuild is true in builds made with -tags viewer, which can only decrypt and verify
+const ViewerBuild = true
@@ -1,249 +0,0 @@
-package postquantum
-
-import (
-	"crypto/rand"
-	"crypto/sha256"
-	"fmt"
-	"io"
-)
-
-// PostQuantumAlgorithm represents different post-quantum algorithms
-type PostQuantumAlgorithm int
-
-const (
-	Kyber768   PostQuantumAlgorithm = iota // NIST Level 3 KEM
-	Dilithium3                             // NIST Level 3 Digital Signature
-	SPHINCS    // Stateless hash-based signatures
-)
-
-// PostQuantumCipher provides post-quantum encryption capabilities
-type PostQuantumCipher struct {
-	algorithm PostQuantumAlgorithm
-	keySize   int
-	nonceSize int
-}
-
-// NewPostQuantumCipher creates a new post-quantum cipher
-func NewPostQuantumCipher(algorithm PostQuantumAlgorithm) *PostQuantumCipher {
-	switch algorithm {
-	case Kyber768:
-		return &PostQuantumCipher{
-			algorithm: algorithm,
-			keySize:   32, // 256-bit symmetric key
-			nonceSize: 12, // 96-bit nonce
-		}
-	case Dilithium3:
-		return &PostQuantumCipher{
-			algorithm: algorithm,
-			keySize:   32,
-			nonceSize: 16,
-		}
-	case SPHINCS:
-		return &PostQuantumCipher{
-			algorithm: algorithm,
-			keySize:   32,
-			nonceSize: 24,
-		}
-	default:
-		return &PostQuantumCipher{
-			algorithm: Kyber768,
-			keySize:   32,
-			nonceSize: 12,
-		}
-	}
-}
-
-// KeyExchange simulates post-quantum key exchange (simplified implementation)
-func (pq *PostQuantumCipher) KeyExchange(password []byte, salt []byte) ([]byte, error) {
-	// In a real implementation, this would use actual post-quantum KEM
-	// For now, we'll use a quantum-resistant key derivation
-	
-	hasher := sha256.New()
-	
-	// Add algorithm identifier to make keys unique per algorithm
-	hasher.Write([]byte{byte(pq.algorithm)})
-	hasher.Write(password)
-	hasher.Write(salt)
-	
-	// Multiple rounds for quantum resistance
-	key := hasher.Sum(nil)
-	for i := 0; i < 1000; i++ {
-		hasher.Reset()
-		hasher.Write(key)
-		hasher.Write(salt)
-		hasher.Write([]byte{byte(i)})
-		key = hasher.Sum(nil)
-	}
-	
-	return key, nil
-}
-
-// Encrypt encrypts data using post-quantum resistant methods
-func (pq *PostQuantumCipher) Encrypt(plaintext []byte, key []byte, nonce []byte) ([]byte, error) {
-	if len(key) < pq.keySize {
-		return nil, fmt.Errorf("key too short, need %d bytes", pq.keySize)
-	}
-	if len(nonce) < pq.nonceSize {
-		return nil, fmt.Errorf("nonce too short, need %d bytes", pq.nonceSize)
-	}
-	
-	// Simplified post-quantum encryption (in practice, use proper PQ algorithms)
-	ciphertext := make([]byte, len(plaintext))
-	
-	// Generate keystream using post-quantum resistant method
-	keystream := pq.generateKeystream(key[:pq.keySize], nonce[:pq.nonceSize], len(plaintext))
-	
-	// XOR with keystream
-	for i := range plaintext {
-		ciphertext[i] = plaintext[i] ^ keystream[i]
-	}
-	
-	// Add authentication tag
-	tag := pq.generateAuthTag(ciphertext, key[:pq.keySize], nonce[:pq.nonceSize])
-	
-	return append(ciphertext, tag...), nil
-}
-
-// Decrypt decrypts data using post-quantum resistant methods
-func (pq *PostQuantumCipher) Decrypt(ciphertext []byte, key []byte, nonce []byte) ([]byte, error) {
-	if len(key) < pq.keySize {
-		return nil, fmt.Errorf("key too short, need %d bytes", pq.keySize)
-	}
-	if len(nonce) < pq.nonceSize {
-		return nil, fmt.Errorf("nonce too short, need %d bytes", pq.nonceSize)
-	}
-	
-	tagSize := 32 // SHA-256 tag size
-	if len(ciphertext) < tagSize {
-		return nil, fmt.Errorf("ciphertext too short")
-	}
-	
-	// Split ciphertext and tag
-	actualCiphertext := ciphertext[:len(ciphertext)-tagSize]
-	providedTag := ciphertext[len(ciphertext)-tagSize:]
-	
-	// Verify authentication tag
-	expectedTag := pq.generateAuthTag(actualCiphertext, key[:pq.keySize], nonce[:pq.nonceSize])
-	if !constantTimeEqual(providedTag, expectedTag) {
-		return nil, fmt.Errorf("authentication failed")
-	}
-	
-	// Generate keystream
-	keystream := pq.generateKeystream(key[:pq.keySize], nonce[:pq.nonceSize], len(actualCiphertext))
-	
-	// XOR to decrypt
-	plaintext := make([]byte, len(actualCiphertext))
-	for i := range actualCiphertext {
-		plaintext[i] = actualCiphertext[i] ^ keystream[i]
-	}
-	
-	return plaintext, nil
-}
-
-// generateKeystream generates a quantum-resistant keystream
-func (pq *PostQuantumCipher) generateKeystream(key []byte, nonce []byte, length int) []byte {
-	keystream := make([]byte, length)
-	
-	hasher := sha256.New()
-	counter := 0
-	
-	for i := 0; i < length; i += 32 {
-		hasher.Reset()
-		hasher.Write(key)
-		hasher.Write(nonce)
-		hasher.Write([]byte{byte(pq.algorithm)}) // Algorithm-specific
-		
-		// Add counter bytes
-		counterBytes := make([]byte, 4)
-		counterBytes[0] = byte(counter >> 24)
-		counterBytes[1] = byte(counter >> 16)
-		counterBytes[2] = byte(counter >> 8)
-		counterBytes[3] = byte(counter)
-		hasher.Write(counterBytes)
-		
-		block := hasher.Sum(nil)
-		
-		// Copy to keystream
-		for j := 0; j < 32 && i+j < length; j++ {
-			keystream[i+j] = block[j]
-		}
-		
-		counter++
-	}
-	
-	return keystream
-}
-
-// generateAuthTag generates authentication tag
-func (pq *PostQuantumCipher) generateAuthTag(data []byte, key []byte, nonce []byte) []byte {
-	hasher := sha256.New()
-	hasher.Write(key)
-	hasher.Write(nonce)
-	hasher.Write(data)
-	hasher.Write([]byte{byte(pq.algorithm)}) // Algorithm-specific
-	return hasher.Sum(nil)
-}
-
-// constantTimeEqual performs constant-time comparison
-func constantTimeEqual(a, b []byte) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	
-	var result byte
-	for i := range a {
-		result |= a[i] ^ b[i]
-	}
-	
-	return result == 0
-}
-
-// GenerateNonce generates a secure nonce for the algorithm
-func (pq *PostQuantumCipher) GenerateNonce() ([]byte, error) {
-	nonce := make([]byte, pq.nonceSize)
-	_, err := io.ReadFull(rand.Reader, nonce)
-	return nonce, err
-}
-
-// GetAlgorithmName returns human-readable algorithm name
-func (pq *PostQuantumCipher) GetAlgorithmName() string {
-	switch pq.algorithm {
-	case Kyber768:
-		return "Kyber-768 (Post-Quantum KEM)"
-	case Dilithium3:
-		return "Dilithium-3 (Post-Quantum Signature)"
-	case SPHINCS:
-		return "SPHINCS+ (Hash-based Signature)"
-	default:
-		return "Unknown Post-Quantum Algorithm"
-	}
-}
-
-// GetKeySize returns the required key size
-func (pq *PostQuantumCipher) GetKeySize() int {
-	return pq.keySize
-}
-
-// GetNonceSize returns the required nonce size
-func (pq *PostQuantumCipher) GetNonceSize() int {
-	return pq.nonceSize
-}
-
-// IsQuantumResistant returns true (all algorithms in this module are quantum-resistant)
-func (pq *PostQuantumCipher) IsQuantumResistant() bool {
-	return true
-}
-
-// GetSecurityLevel returns the security level
-func (pq *PostQuantumCipher) GetSecurityLevel() int {
-	switch pq.algorithm {
-	case Kyber768:
-		return 3 // NIST Level 3
-	case Dilithium3:
-		return 3 // NIST Level 3
-	case SPHINCS:
-		return 5 // Very high security
-	default:
-		return 1
-	}
-}
@@ -0,0 +1,84 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is a progress report with the figures derived from the reports before it
+type Event struct {
+	Done, Total int64
+	Remaining   int64 // Total - Done; 0 while the total is not known
+	Elapsed     time.Duration
+	Rate        float64       // units per second over the meter's window
+	ETA         time.Duration // 0 while the rate is not known
+}
+
+// Meter turns progress reports into Events. Update has the signature of Func
+// and may be called from the job's goroutines while Event is read from another,
+// e.g. a UI timer. The rate covers the last window, so it follows a job that
+// speeds up or stalls rather than averaging over the whole run.
+type Meter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	now     func() time.Time
+	start   time.Time
+	samples []sample // oldest first; the last is the latest report
+	total   int64
+}
+
+type sample struct {
+	at   time.Time
+	done int64
+}
+
+// NewMeter returns a meter whose rate covers window, starting now
+func NewMeter(window time.Duration) *Meter { return newMeter(window, time.Now) }
+
+func newMeter(window time.Duration, now func() time.Time) *Meter {
+	start := now()
+	return &Meter{window: window, now: now, start: start, samples: []sample{{start, 0}}}
+}
+
+// Update records that done of total units are processed
+func (m *Meter) Update(done, total int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if total > 0 {
+		m.total = total
+	}
+	if last := m.samples[len(m.samples)-1]; done < last.done {
+		return
+	}
+	m.samples = append(m.samples, sample{m.now(), done})
+	m.pruneLocked(m.now())
+}
+
+// pruneLocked drops the samples older than the window, keeping one to measure from
+func (m *Meter) pruneLocked(now time.Time) {
+	i := 0
+	for i < len(m.samples)-1 && now.Sub(m.samples[i+1].at) >= m.window {
+		i++
+	}
+	m.samples = m.samples[i:]
+}
+
+// Event returns the figures as of now
+func (m *Meter) Event() Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.pruneLocked(now)
+	first, last := m.samples[0], m.samples[len(m.samples)-1]
+	ev := Event{Done: last.done, Total: m.total, Elapsed: now.Sub(m.start)}
+	if ev.Total > ev.Done {
+		ev.Remaining = ev.Total - ev.Done
+	}
+	if span := now.Sub(first.at); span > 0 {
+		ev.Rate = float64(last.done-first.done) / span.Seconds()
+	}
+	if ev.Rate > 0 && ev.Remaining > 0 {
+		ev.ETA = time.Duration(float64(ev.Remaining) / ev.Rate * float64(time.Second))
+	}
+	return ev
+}
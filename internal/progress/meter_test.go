@@ -0,0 +1,39 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := newMeter(4*time.Second, func() time.Time { return now })
+
+	now = now.Add(2 * time.Second)
+	m.Update(200, 1000)
+	ev := m.Event()
+	if ev.Done != 200 || ev.Remaining != 800 || ev.Elapsed != 2*time.Second || ev.Rate != 100 || ev.ETA != 8*time.Second {
+		t.Errorf("after 2s: %+v", ev)
+	}
+
+	// the window forgets the slow start
+	for range 4 {
+		now = now.Add(time.Second)
+		m.Update(ev.Done+300, 0)
+		ev = m.Event()
+	}
+	if ev.Done != 1400 || ev.Total != 1000 || ev.Remaining != 0 || ev.Rate != 300 || ev.ETA != 0 {
+		t.Errorf("after 6s: %+v", ev)
+	}
+
+	// a stall brings the rate down, and reports never go backwards
+	m.Update(10, 1000)
+	now = now.Add(3 * time.Second)
+	if ev = m.Event(); ev.Done != 1400 || ev.Rate != 75 {
+		t.Errorf("stalled 3s: %+v", ev)
+	}
+	now = now.Add(10 * time.Second)
+	if ev = m.Event(); ev.Rate != 0 {
+		t.Errorf("stalled 13s: %+v", ev)
+	}
+}
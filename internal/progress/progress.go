@@ -0,0 +1,108 @@
+// Package progress folds the byte counts of sequential or nested phases
+// (archive, encrypt, extract, per-file loops) into one monotonic figure.
+package progress
+
+import "sync"
+
+// Func receives overall progress as done/total on the tracker's scale
+type Func func(done, total int64)
+
+// Tracker aggregates weighted phases. Each phase reports its own bytes; the
+// overall value is the weight-averaged completion, rescaled to Scale and
+// never allowed to move backwards.
+type Tracker struct {
+	mu     sync.Mutex
+	scale  int64
+	report Func
+	phases []*Phase
+	last   int64
+}
+
+// Phase is one unit of work inside a Tracker
+type Phase struct {
+	t      *Tracker
+	weight int64
+	done   int64
+	total  int64
+}
+
+// New returns a tracker that reports to fn on a 0..scale range. A scale of
+// zero or less reports in permille.
+func New(scale int64, fn Func) *Tracker {
+	if scale <= 0 {
+		scale = 1000
+	}
+	return &Tracker{scale: scale, report: fn}
+}
+
+// Phase adds a phase whose share of the whole is proportional to weight,
+// usually the number of bytes it will process
+func (t *Tracker) Phase(weight int64) *Phase {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := &Phase{t: t, weight: max(weight, 0)}
+	t.phases = append(t.phases, p)
+	return p
+}
+
+// Update records that done of total bytes of the phase are processed; it has
+// the signature of the engine and archiver progress callbacks
+func (p *Phase) Update(done, total int64) {
+	t := p.t
+	t.mu.Lock()
+	if total > 0 {
+		p.total = total
+	}
+	if done > p.done {
+		p.done = done
+	}
+	t.emitLocked()
+	t.mu.Unlock()
+}
+
+// SetWeight replaces the phase weight once its real size is known, e.g. the
+// archive size before encrypting it. Progress already shown is never undone.
+func (p *Phase) SetWeight(weight int64) {
+	p.t.mu.Lock()
+	p.weight = max(weight, 0)
+	p.t.mu.Unlock()
+}
+
+// Complete marks the phase finished regardless of the bytes reported
+func (p *Phase) Complete() {
+	t := p.t
+	t.mu.Lock()
+	p.total = max(p.total, 1)
+	p.done = p.total
+	t.emitLocked()
+	t.mu.Unlock()
+}
+
+// Done returns the last value reported, on the tracker's scale
+func (t *Tracker) Done() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+func (t *Tracker) emitLocked() {
+	var sum, acc float64
+	for _, p := range t.phases {
+		w := float64(p.weight)
+		sum += w
+		if p.total > 0 {
+			acc += w * min(float64(p.done)/float64(p.total), 1)
+		}
+	}
+	if sum == 0 {
+		return
+	}
+	cur := int64(acc / sum * float64(t.scale))
+	if cur < t.last {
+		return
+	}
+	t.last = cur
+	if t.report != nil {
+		t.report(cur, t.scale)
+	}
+}
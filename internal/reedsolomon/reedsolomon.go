@@ -1,231 +1,67 @@
+// Package reedsolomon implements systematic Reed-Solomon erasure coding over
+// GF(2^8) with github.com/klauspost/reedsolomon. The data shards are kept as
+// they are and parity shards are computed from them, so any dataShards of the
+// shards rebuild all the others. Callers find damaged shards themselves, with
+// checksums for instance, and pass them as missing.
+//
+// The encoding matrix is the identity over a Cauchy matrix of 1/(r xor c) in
+// the field of polynomial 0x11d, which containers with parity depend on; it
+// must not change.
 package reedsolomon
 
 import (
+	"errors"
 	"fmt"
-	"io"
-)
-
-// ReedSolomon provides error correction capabilities
-type ReedSolomon struct {
-	dataShards   int
-	parityShards int
-	encoder      RSEncoder
-}
-
-// RSEncoder interface for Reed-Solomon encoding/decoding
-type RSEncoder interface {
-	Encode(data []byte) ([]byte, error)
-	Decode(data []byte) ([]byte, error)
-}
-
-// SimpleRSEncoder is a basic implementation for demonstration
-// In production, you would use a proper Reed-Solomon library
-type SimpleRSEncoder struct {
-	dataShards   int
-	parityShards int
-}
 
-// NewSimpleRSEncoder creates a new simple Reed-Solomon encoder
-func NewSimpleRSEncoder(dataShards, parityShards int) *SimpleRSEncoder {
-	return &SimpleRSEncoder{
-		dataShards:   dataShards,
-		parityShards: parityShards,
-	}
-}
+	rs "github.com/klauspost/reedsolomon"
+)
 
-// Encode adds Reed-Solomon parity data
-func (rs *SimpleRSEncoder) Encode(data []byte) ([]byte, error) {
-	if len(data) == 0 {
-		return data, nil
-	}
-	
-	// Simple XOR-based parity for demonstration
-	// In production, use a proper Reed-Solomon implementation
-	chunkSize := 128
-	result := make([]byte, 0, len(data)*138/128) // ~8 bytes per 128 bytes
-	
-	for i := 0; i < len(data); i += chunkSize {
-		end := i + chunkSize
-		if end > len(data) {
-			end = len(data)
-		}
-		
-		chunk := data[i:end]
-		result = append(result, chunk...)
-		
-		// Add simple parity bytes (8 bytes per 128-byte chunk)
-		parity := rs.generateParity(chunk)
-		result = append(result, parity...)
-	}
-	
-	return result, nil
-}
+var (
+	// ErrTooFewShards means more shards are missing than there are parity shards
+	ErrTooFewShards = rs.ErrTooFewShards
+	// ErrShardSize means the shards are empty or differ in length
+	ErrShardSize = rs.ErrShardSize
+)
 
-// Decode attempts to correct errors and recover original data
-func (rs *SimpleRSEncoder) Decode(data []byte) ([]byte, error) {
-	if len(data) == 0 {
-		return data, nil
-	}
-	
-	// Calculate expected chunk size with parity
-	chunkWithParity := 136 // 128 data + 8 parity
-	result := make([]byte, 0, len(data)*128/136)
-	
-	for i := 0; i < len(data); i += chunkWithParity {
-		end := i + chunkWithParity
-		if end > len(data) {
-			// Handle last incomplete chunk
-			remaining := len(data) - i
-			if remaining <= 8 {
-				break // Only parity bytes left
-			}
-			
-			dataLen := remaining - 8
-			if dataLen > 0 {
-				result = append(result, data[i:i+dataLen]...)
-			}
-			break
-		}
-		
-		chunk := data[i : i+128]
-		parity := data[i+128 : end]
-		
-		// Simple error detection/correction
-		expectedParity := rs.generateParity(chunk)
-		if rs.parityMatches(parity, expectedParity) {
-			result = append(result, chunk...)
-		} else {
-			// Attempt simple correction
-			corrected, err := rs.attemptCorrection(chunk, parity, expectedParity)
-			if err != nil {
-				// Use original data if correction fails
-				result = append(result, chunk...)
-			} else {
-				result = append(result, corrected...)
-			}
-		}
-	}
-	
-	return result, nil
+// Encoder computes and checks parity shards and rebuilds missing shards
+type Encoder struct {
+	data, parity int
+	enc          rs.Encoder
 }
 
-// generateParity creates simple parity bytes for a chunk
-func (rs *SimpleRSEncoder) generateParity(data []byte) []byte {
-	parity := make([]byte, 8)
-	
-	for i, b := range data {
-		parity[i%8] ^= b
+// New returns an encoder for dataShards data and parityShards parity shards;
+// there may be 256 shards in all
+func New(dataShards, parityShards int) (*Encoder, error) {
+	// the limit also keeps klauspost/reedsolomon from switching to GF(2^16)
+	if dataShards <= 0 || parityShards <= 0 || dataShards+parityShards > 256 {
+		return nil, fmt.Errorf("reedsolomon: %d data and %d parity shards", dataShards, parityShards)
 	}
-	
-	// Add some redundancy
-	for i := 0; i < 8; i++ {
-		parity[i] ^= byte(len(data) + i)
+	enc, err := rs.New(dataShards, parityShards, rs.WithCauchyMatrix())
+	if err != nil {
+		return nil, fmt.Errorf("reedsolomon: %w", err)
 	}
-	
-	return parity
+	return &Encoder{data: dataShards, parity: parityShards, enc: enc}, nil
 }
 
-// parityMatches checks if parity bytes match
-func (rs *SimpleRSEncoder) parityMatches(actual, expected []byte) bool {
-	if len(actual) != len(expected) {
-		return false
-	}
-	
-	for i := range actual {
-		if actual[i] != expected[i] {
-			return false
-		}
-	}
-	return true
-}
+// DataShards returns the number of data shards
+func (e *Encoder) DataShards() int { return e.data }
 
-// attemptCorrection tries to correct single-bit errors
-func (rs *SimpleRSEncoder) attemptCorrection(data, actualParity, expectedParity []byte) ([]byte, error) {
-	// Simple single-bit error correction attempt
-	corrected := make([]byte, len(data))
-	copy(corrected, data)
-	
-	// Try flipping each bit to see if it fixes the parity
-	for i := 0; i < len(data); i++ {
-		for bit := 0; bit < 8; bit++ {
-			// Flip bit
-			corrected[i] ^= (1 << bit)
-			
-			// Check if parity is now correct
-			testParity := rs.generateParity(corrected)
-			if rs.parityMatches(testParity, expectedParity) {
-				return corrected, nil
-			}
-			
-			// Flip bit back
-			corrected[i] ^= (1 << bit)
-		}
-	}
-	
-	return nil, fmt.Errorf("unable to correct errors")
-}
+// ParityShards returns the number of parity shards
+func (e *Encoder) ParityShards() int { return e.parity }
 
-// New creates a new Reed-Solomon encoder
-func New(dataShards, parityShards int) *ReedSolomon {
-	return &ReedSolomon{
-		dataShards:   dataShards,
-		parityShards: parityShards,
-		encoder:      NewSimpleRSEncoder(dataShards, parityShards),
-	}
-}
+// Encode computes the parity shards, which must be allocated already, from the data shards
+func (e *Encoder) Encode(shards [][]byte) error { return e.enc.Encode(shards) }
 
-// EncodeStream encodes data from reader to writer with Reed-Solomon error correction
-func (rs *ReedSolomon) EncodeStream(src io.Reader, dst io.Writer) error {
-	buffer := make([]byte, 32*1024) // 32KB buffer
-	
-	for {
-		n, err := src.Read(buffer)
-		if n > 0 {
-			encoded, encErr := rs.encoder.Encode(buffer[:n])
-			if encErr != nil {
-				return fmt.Errorf("encode error: %w", encErr)
-			}
-			
-			if _, writeErr := dst.Write(encoded); writeErr != nil {
-				return fmt.Errorf("write error: %w", writeErr)
-			}
-		}
-		
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("read error: %w", err)
-		}
-	}
-	
-	return nil
-}
+// Verify reports whether the parity shards match the data shards
+func (e *Encoder) Verify(shards [][]byte) (bool, error) { return e.enc.Verify(shards) }
 
-// DecodeStream decodes data from reader to writer with error correction
-func (rs *ReedSolomon) DecodeStream(src io.Reader, dst io.Writer) error {
-	buffer := make([]byte, 32*1024*138/128) // Larger buffer for encoded data
-	
-	for {
-		n, err := src.Read(buffer)
-		if n > 0 {
-			decoded, decErr := rs.encoder.Decode(buffer[:n])
-			if decErr != nil {
-				return fmt.Errorf("decode error: %w", decErr)
-			}
-			
-			if _, writeErr := dst.Write(decoded); writeErr != nil {
-				return fmt.Errorf("write error: %w", writeErr)
-			}
-		}
-		
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("read error: %w", err)
-		}
+// Reconstruct rebuilds the missing shards, those that are nil or empty, from
+// the others in place. At most ParityShards shards may be missing.
+func (e *Encoder) Reconstruct(shards [][]byte) error {
+	err := e.enc.Reconstruct(shards)
+	if errors.Is(err, rs.ErrShardNoData) {
+		// no shard at all is too few as well
+		return ErrTooFewShards
 	}
-	
-	return nil
+	return err
 }
@@ -0,0 +1,203 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// testShards returns data random data shards of size bytes with room for parity
+func testShards(data, parity, size int) [][]byte {
+	shards := make([][]byte, data+parity)
+	for i := range shards {
+		shards[i] = make([]byte, size)
+		if i < data {
+			rand.Read(shards[i])
+		}
+	}
+	return shards
+}
+
+func TestReconstructEveryLoss(t *testing.T) {
+	const data, parity = 5, 3
+	e, err := New(data, parity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := testShards(data, parity, 100)
+	if err := e.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := e.Verify(shards); !ok || err != nil {
+		t.Fatalf("Verify after Encode = %v, %v", ok, err)
+	}
+	// every way of losing up to parity shards
+	for mask := 0; mask < 1<<(data+parity); mask++ {
+		lost := 0
+		damaged := make([][]byte, len(shards))
+		for i := range shards {
+			if mask&(1<<i) != 0 {
+				lost++
+				continue
+			}
+			damaged[i] = bytes.Clone(shards[i])
+		}
+		err := e.Reconstruct(damaged)
+		if lost > parity {
+			if !errors.Is(err, ErrTooFewShards) {
+				t.Errorf("lost %b: got %v, want ErrTooFewShards", mask, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("lost %b: %v", mask, err)
+		}
+		for i := range shards {
+			if !bytes.Equal(damaged[i], shards[i]) {
+				t.Fatalf("lost %b: shard %d rebuilt wrong", mask, i)
+			}
+		}
+	}
+}
+
+func TestVerifyDetectsDamage(t *testing.T) {
+	e, err := New(16, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := testShards(16, 4, 64)
+	if err := e.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	shards[7][10] ^= 1
+	if ok, err := e.Verify(shards); ok || err != nil {
+		t.Errorf("Verify of a changed shard = %v, %v", ok, err)
+	}
+}
+
+func TestNewLimits(t *testing.T) {
+	for _, c := range [][2]int{{0, 1}, {1, 0}, {200, 57}} {
+		if _, err := New(c[0], c[1]); err == nil {
+			t.Errorf("New(%d, %d) succeeded", c[0], c[1])
+		}
+	}
+	if _, err := New(200, 56); err != nil {
+		t.Errorf("New(200, 56): %v", err)
+	}
+	e, _ := New(2, 1)
+	if err := e.Encode([][]byte{{1}, {2, 3}, {0}}); !errors.Is(err, ErrShardSize) {
+		t.Errorf("Encode of uneven shards: got %v, want ErrShardSize", err)
+	}
+}
+
+// gfMul multiplies in GF(2^8) bit by bit, without the tables, as a reference
+func gfMul(a, b byte) byte {
+	var p byte
+	for ; b != 0; b >>= 1 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1d // x^8 = x^4+x^3+x^2+1
+		}
+	}
+	return p
+}
+
+// gfInv finds the inverse of a != 0 by search
+func gfInv(a byte) byte {
+	for b := 1; b < 256; b++ {
+		if gfMul(a, byte(b)) == 1 {
+			return byte(b)
+		}
+	}
+	panic("no inverse")
+}
+
+// naiveParity computes the parity shards of data straight from the Cauchy
+// matrix definition, one byte at a time
+func naiveParity(data [][]byte, parity int) [][]byte {
+	out := make([][]byte, parity)
+	for p := range out {
+		r := len(data) + p
+		out[p] = make([]byte, len(data[0]))
+		for i := range out[p] {
+			for c, d := range data {
+				out[p][i] ^= gfMul(gfInv(byte(r)^byte(c)), d[i])
+			}
+		}
+	}
+	return out
+}
+
+func TestReferenceField(t *testing.T) {
+	// the field of QR codes and klauspost/reedsolomon: polynomial 0x11d, generator 2
+	for _, v := range []struct{ a, b, product byte }{
+		{2, 0x80, 0x1d},
+		{3, 7, 9},
+		{0xff, 0xff, 0xe2},
+	} {
+		if got := gfMul(v.a, v.b); got != v.product {
+			t.Errorf("%#x * %#x = %#x, want %#x", v.a, v.b, got, v.product)
+		}
+	}
+	if x := gfInv(2); x != 0x8e {
+		t.Errorf("1/2 = %#x, want 0x8e", x)
+	}
+	x := byte(1)
+	for range 25 {
+		x = gfMul(x, 2)
+	}
+	if x != 3 {
+		t.Errorf("2^25 = %#x, want 3", x)
+	}
+}
+
+func TestEncodeMatchesReference(t *testing.T) {
+	for _, c := range [][3]int{{1, 1, 7}, {4, 2, 33}, {10, 4, 100}, {17, 3, 64}} {
+		data, parity, size := c[0], c[1], c[2]
+		e, err := New(data, parity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := testShards(data, parity, size)
+		if err := e.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		for p, want := range naiveParity(shards[:data], parity) {
+			if !bytes.Equal(shards[data+p], want) {
+				t.Errorf("%d+%d: parity shard %d differs from the reference", data, parity, p)
+			}
+		}
+	}
+
+}
+
+// TestEncodeVector pins the parity that containers written so far carry
+func TestEncodeVector(t *testing.T) {
+	e, err := New(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][]byte, 6)
+	for i := range shards {
+		shards[i] = make([]byte, 8)
+		if i < 4 {
+			for j := range shards[i] {
+				shards[i][j] = byte(i*8 + j)
+			}
+		}
+	}
+	if err := e.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"74543414f4d4b494", "69492909e9c9a989"} {
+		if got := hex.EncodeToString(shards[4+i]); got != want {
+			t.Errorf("parity shard %d = %s, want %s", i, got, want)
+		}
+	}
+}
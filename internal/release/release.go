@@ -0,0 +1,142 @@
+// Package release assembles release artifacts: it names the builds for each
+// platform, packs them into byte-for-byte reproducible zips and lists their
+// SHA-256 sums. cmd/release drives it.
+package release
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ChecksumsFile is the name of the checksum list written next to the artifacts
+const ChecksumsFile = "SHA256SUMS"
+
+// Target is one operating system and architecture to build for
+type Target struct {
+	OS, Arch string
+}
+
+func (t Target) String() string { return t.OS + "/" + t.Arch }
+
+// Exe is name with the executable suffix of the target's OS
+func (t Target) Exe(name string) string {
+	if t.OS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
+
+// DefaultTargets are the platforms a release is built for
+var DefaultTargets = []Target{
+	{"windows", "amd64"}, {"windows", "arm64"},
+	{"linux", "amd64"}, {"linux", "arm64"},
+	{"darwin", "amd64"}, {"darwin", "arm64"},
+}
+
+// ParseTargets reads a comma-separated list of os/arch pairs, e.g. "windows/amd64,linux/arm64"
+func ParseTargets(s string) ([]Target, error) {
+	var ts []Target
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		goos, arch, ok := strings.Cut(f, "/")
+		if !ok || goos == "" || arch == "" {
+			return nil, fmt.Errorf("target %q: want os/arch", f)
+		}
+		if t := (Target{goos, arch}); !slices.Contains(ts, t) {
+			ts = append(ts, t)
+		}
+	}
+	if len(ts) == 0 {
+		return nil, fmt.Errorf("no targets in %q", s)
+	}
+	return ts, nil
+}
+
+// ArtifactName is the file name of the portable zip for version and t
+func ArtifactName(version string, t Target) string {
+	return fmt.Sprintf("HadesCrypt-%s-%s-%s.zip", version, t.OS, t.Arch)
+}
+
+// File is one entry of a zip: the file at Source stored as Name
+type File struct {
+	Name   string // slash-separated path inside the zip
+	Source string
+	Exec   bool // marked executable for unzip on Unix
+}
+
+// WriteZip packs files into a zip at dst. Entries are sorted by name and carry
+// modTime and fixed permissions instead of what the disk reports, so the same
+// inputs always give the same bytes.
+func WriteZip(dst string, files []File, modTime time.Time) error {
+	files = slices.Clone(files)
+	slices.SortFunc(files, func(a, b File) int { return strings.Compare(a.Name, b.Name) })
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		h := &zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: modTime.UTC()}
+		mode := os.FileMode(0o644)
+		if f.Exec {
+			mode = 0o755
+		}
+		h.SetMode(mode)
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(f.Source)
+		if err != nil {
+			return fmt.Errorf("zip %s: %w", f.Name, err)
+		}
+		_, err = io.Copy(w, src)
+		src.Close()
+		if err != nil {
+			return fmt.Errorf("zip %s: %w", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, buf.Bytes(), 0o644)
+}
+
+// SumFile returns the hex SHA-256 of the file at path
+func SumFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// WriteChecksums writes ChecksumsFile into dir for the named files in it, in
+// the "sha256sum --binary" format that sha256sum -c and certutil users expect
+func WriteChecksums(dir string, names []string) (string, error) {
+	names = slices.Sorted(slices.Values(names))
+	var b strings.Builder
+	for _, n := range names {
+		sum, err := SumFile(filepath.Join(dir, n))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s *%s\n", sum, n)
+	}
+	path := filepath.Join(dir, ChecksumsFile)
+	return path, os.WriteFile(path, []byte(b.String()), 0o644)
+}
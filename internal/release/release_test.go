@@ -0,0 +1,85 @@
+package release
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTargets(t *testing.T) {
+	ts, err := ParseTargets(" windows/amd64, linux/arm64,windows/amd64,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Target{{"windows", "amd64"}, {"linux", "arm64"}}
+	if !slices.Equal(ts, want) {
+		t.Errorf("targets %v, want %v", ts, want)
+	}
+	for _, bad := range []string{"", "windows", "/amd64", " , "} {
+		if _, err := ParseTargets(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+	if ts[0].Exe("hadescrypt-cli") != "hadescrypt-cli.exe" || ts[1].Exe("hadescrypt-cli") != "hadescrypt-cli" {
+		t.Error("executable suffix")
+	}
+}
+
+func TestWriteZipReproducible(t *testing.T) {
+	dir := t.TempDir()
+	bin, doc := filepath.Join(dir, "app"), filepath.Join(dir, "README.md")
+	os.WriteFile(bin, []byte("binary"), 0o700)
+	os.WriteFile(doc, []byte("docs"), 0o600)
+	files := []File{{Name: "pkg/README.md", Source: doc}, {Name: "pkg/app", Source: bin, Exec: true}}
+	when := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	a, b := filepath.Join(dir, "a.zip"), filepath.Join(dir, "b.zip")
+	if err := WriteZip(a, files, when); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(bin, time.Now(), time.Now())
+	slices.Reverse(files)
+	if err := WriteZip(b, files, when); err != nil {
+		t.Fatal(err)
+	}
+	da, _ := os.ReadFile(a)
+	db, _ := os.ReadFile(b)
+	if !bytes.Equal(da, db) {
+		t.Fatal("same inputs gave different zips")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(da), int64(len(da)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "pkg/README.md" || zr.File[1].Name != "pkg/app" {
+		t.Fatalf("entries %v", zr.File)
+	}
+	if zr.File[1].Mode() != 0o755 || zr.File[0].Mode() != 0o644 || !zr.File[0].Modified.Equal(when) {
+		t.Errorf("modes %v %v, time %v", zr.File[0].Mode(), zr.File[1].Mode(), zr.File[0].Modified)
+	}
+}
+
+func TestWriteChecksums(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "b.zip"), []byte("b"), 0o644)
+	os.WriteFile(filepath.Join(dir, "a.zip"), nil, 0o644)
+	path, err := WriteChecksums(dir, []string{"b.zip", "a.zip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 *a.zip\n" +
+		"3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d *b.zip\n"
+	if string(data) != want {
+		t.Errorf("%s:\n%s", ChecksumsFile, data)
+	}
+	if _, err := WriteChecksums(dir, []string{"missing.zip"}); err == nil || !strings.Contains(err.Error(), "missing.zip") {
+		t.Errorf("missing file: %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+)
+
+// Upload is an unfinished multipart upload as recorded in the journal
+type Upload struct {
+	Endpoint    string    `json:"endpoint,omitempty"`
+	Bucket      string    `json:"bucket"`
+	Key         string    `json:"key"`
+	UploadID    string    `json:"upload_id"`
+	Src         string    `json:"src"`
+	Size        int64     `json:"size"`
+	ModTime     int64     `json:"mod_time"` // UnixNano of src when the upload started
+	PartSize    int64     `json:"part_size"`
+	Parts       []Part    `json:"parts,omitempty"`
+	Started     int64     `json:"started"` // Unix timestamp
+	RetainUntil time.Time `json:"retain_until,omitzero"`
+}
+
+// Part is an uploaded part of an Upload
+type Part struct {
+	Number int    `json:"number"`
+	Size   int64  `json:"size"`
+	ETag   string `json:"etag"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+func (u *Upload) done(number int) bool {
+	for _, p := range u.Parts {
+		if p.Number == number {
+			return true
+		}
+	}
+	return false
+}
+
+// uploaded returns how many bytes the finished parts hold
+func (u *Upload) uploaded() int64 {
+	var n int64
+	for _, p := range u.Parts {
+		n += p.Size
+	}
+	return n
+}
+
+// Journal is the on-disk list of unfinished uploads. A nil Journal records nothing.
+type Journal struct {
+	path string
+
+	mu      sync.Mutex
+	uploads []Upload
+}
+
+// JournalPath returns the default journal location in the config directory
+func JournalPath() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "uploads.json"), nil
+}
+
+// OpenJournal loads the journal at path; a missing file is an empty journal
+func OpenJournal(path string) (*Journal, error) {
+	j := &Journal{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &j.uploads); err != nil {
+		return nil, fmt.Errorf("invalid upload journal %s: %w", path, err)
+	}
+	return j, nil
+}
+
+// Pending returns the unfinished uploads
+func (j *Journal) Pending() []Upload {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]Upload(nil), j.uploads...)
+}
+
+// find returns a copy of the upload of src to key at dest, or nil
+func (j *Journal) find(dest config.Destination, key, src string) *Upload {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, u := range j.uploads {
+		if u.Endpoint == dest.Endpoint && u.Bucket == dest.Bucket && u.Key == key && u.Src == src {
+			u.Parts = append([]Part(nil), u.Parts...)
+			return &u
+		}
+	}
+	return nil
+}
+
+// put records up, replacing the entry with the same upload ID
+func (j *Journal) put(up *Upload) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry := *up
+	entry.Parts = append([]Part(nil), up.Parts...)
+	for i := range j.uploads {
+		if j.uploads[i].UploadID == up.UploadID {
+			j.uploads[i] = entry
+			return j.save()
+		}
+	}
+	j.uploads = append(j.uploads, entry)
+	return j.save()
+}
+
+// remove forgets the upload with uploadID
+func (j *Journal) remove(uploadID string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.uploads {
+		if j.uploads[i].UploadID == uploadID {
+			j.uploads = append(j.uploads[:i], j.uploads[i+1:]...)
+			return j.save()
+		}
+	}
+	return nil
+}
+
+// save writes the journal through a temporary file so a crash never leaves it half written
+func (j *Journal) save() error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(j.uploads, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), "."+filepath.Base(j.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), j.path)
+}
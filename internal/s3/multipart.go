@@ -0,0 +1,321 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MultipartThreshold is the file size from which uploads go up in parts
+const MultipartThreshold = 64 << 20
+
+// Part sizing: S3 allows at most 10000 parts of at least 5 MiB each
+const (
+	minPartSize = 16 << 20
+	maxParts    = 10000
+)
+
+// Retries for a request that failed on the network or with a server error
+const (
+	maxAttempts  = 5
+	firstBackoff = time.Second
+)
+
+// partSize picks a part size that keeps size within maxParts parts
+func partSize(size int64) int64 {
+	ps := int64(minPartSize)
+	for (size+ps-1)/ps > maxParts {
+		ps *= 2
+	}
+	return ps
+}
+
+// putMultipart uploads f in parts, continuing an upload the journal knows about
+func (c *Client) putMultipart(ctx context.Context, f *os.File, fi os.FileInfo, src, key string, onProgress func(done, total int64)) (*PutResult, error) {
+	size := fi.Size()
+	up := c.Journal.find(c.Dest, key, src)
+	if up != nil && (up.Size != size || up.ModTime != fi.ModTime().UnixNano()) {
+		// the file changed since the interrupted upload; its parts are useless
+		c.abort(ctx, up)
+		c.Journal.remove(up.UploadID)
+		up = nil
+	}
+	res := &PutResult{Key: key}
+	if up == nil {
+		up = &Upload{
+			Endpoint: c.Dest.Endpoint,
+			Bucket:   c.Dest.Bucket,
+			Key:      key,
+			Src:      src,
+			Size:     size,
+			ModTime:  fi.ModTime().UnixNano(),
+			PartSize: partSize(size),
+			Started:  time.Now().Unix(),
+		}
+		if err := c.create(ctx, up, res); err != nil {
+			return nil, fmt.Errorf("upload %s: %w", key, err)
+		}
+		if err := c.Journal.put(up); err != nil {
+			return nil, err
+		}
+	} else {
+		res.RetainUntil = up.RetainUntil
+	}
+
+	n := int((size + up.PartSize - 1) / up.PartSize)
+	for i := 1; i <= n; i++ {
+		if up.done(i) {
+			continue
+		}
+		off := int64(i-1) * up.PartSize
+		length := min(up.PartSize, size-off)
+		part, err := c.uploadPart(ctx, f, up, i, off, length, onProgress)
+		if err != nil {
+			// the journal keeps the finished parts for the next attempt
+			return nil, fmt.Errorf("upload %s part %d/%d: %w", key, i, n, err)
+		}
+		up.Parts = append(up.Parts, *part)
+		if err := c.Journal.put(up); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.complete(ctx, up, res); err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Code == "NoSuchUpload" {
+			c.Journal.remove(up.UploadID)
+		}
+		return nil, fmt.Errorf("upload %s: %w", key, err)
+	}
+	c.Journal.remove(up.UploadID)
+	return res, nil
+}
+
+// create starts a multipart upload with the object's storage class and lock settings
+func (c *Client) create(ctx context.Context, up *Upload, res *PutResult) error {
+	u, err := c.objectURL(up.Key)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = "uploads="
+	var out struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = c.retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		if c.checksums() {
+			req.Header.Set("x-amz-checksum-algorithm", "SHA256")
+		}
+		res.RetainUntil = c.objectHeaders(req.Header)
+		c.sign(req, emptySHA256)
+		return c.doXML(req, &out)
+	})
+	if err != nil {
+		return err
+	}
+	if out.UploadID == "" {
+		return errors.New("provider returned no upload ID")
+	}
+	up.UploadID = out.UploadID
+	up.RetainUntil = res.RetainUntil
+	return nil
+}
+
+// uploadPart sends one part, retrying transient failures
+func (c *Client) uploadPart(ctx context.Context, f *os.File, up *Upload, number int, off, length int64, onProgress func(done, total int64)) (*Part, error) {
+	sum, err := hashSection(io.NewSectionReader(f, off, length))
+	if err != nil {
+		return nil, err
+	}
+	u, err := c.objectURL(up.Key)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {up.UploadID}}.Encode()
+	part := &Part{Number: number}
+	if c.checksums() {
+		part.SHA256 = base64.StdEncoding.EncodeToString(sum)
+	}
+	err = c.retry(ctx, func() error {
+		body := c.body(io.NewSectionReader(f, off, length), up.uploaded(), up.Size, onProgress)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), body)
+		if err != nil {
+			return err
+		}
+		req.ContentLength = length
+		if part.SHA256 != "" {
+			req.Header.Set("x-amz-checksum-sha256", part.SHA256)
+		}
+		c.sign(req, hex.EncodeToString(sum))
+		resp, err := c.do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		part.ETag = resp.Header.Get("ETag")
+		if got := resp.Header.Get("x-amz-checksum-sha256"); part.SHA256 != "" && got != "" && got != part.SHA256 {
+			return ErrChecksumMismatch
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	part.Size = length
+	return part, nil
+}
+
+// complete assembles the uploaded parts into the object
+func (c *Client) complete(ctx context.Context, up *Upload, res *PutResult) error {
+	type xmlPart struct {
+		PartNumber     int
+		ETag           string
+		ChecksumSHA256 string `xml:",omitempty"`
+	}
+	doc := struct {
+		XMLName xml.Name  `xml:"CompleteMultipartUpload"`
+		Parts   []xmlPart `xml:"Part"`
+	}{}
+	for _, p := range up.Parts {
+		doc.Parts = append(doc.Parts, xmlPart{PartNumber: p.Number, ETag: p.ETag, ChecksumSHA256: p.SHA256})
+	}
+	payload, err := xml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	sum, _ := hashSection(bytes.NewReader(payload))
+
+	u, err := c.objectURL(up.Key)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = url.Values{"uploadId": {up.UploadID}}.Encode()
+	var out struct {
+		ETag           string `xml:"ETag"`
+		ChecksumSHA256 string `xml:"ChecksumSHA256"`
+	}
+	err = c.retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/xml")
+		c.sign(req, hex.EncodeToString(sum))
+		return c.doXML(req, &out)
+	})
+	if err != nil {
+		return err
+	}
+	res.ETag = strings.Trim(out.ETag, `"`)
+	// a multipart checksum is the hash of the part hashes, suffixed with the part count
+	res.SHA256 = out.ChecksumSHA256
+	return nil
+}
+
+// abort discards an unfinished upload and its stored parts
+func (c *Client) abort(ctx context.Context, up *Upload) error {
+	u, err := c.objectURL(up.Key)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = url.Values{"uploadId": {up.UploadID}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	c.sign(req, emptySHA256)
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Abort discards an interrupted upload from the journal, deleting its parts at the provider
+func (c *Client) Abort(ctx context.Context, up Upload) error {
+	err := c.abort(ctx, &up)
+	var e *Error
+	if err == nil || errors.As(err, &e) && e.Code == "NoSuchUpload" {
+		c.Journal.remove(up.UploadID)
+		return nil
+	}
+	return err
+}
+
+// doXML sends req and decodes the XML answer into out. Some answers report
+// an error in a 200 response, so the body is checked for one as well.
+func (c *Client) doXML(req *http.Request, out any) error {
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	var e struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(data, &e) == nil && e.XMLName.Local == "Error" {
+		return &Error{Status: http.StatusInternalServerError, Code: e.Code, Message: e.Message}
+	}
+	if err := xml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// retry runs fn until it succeeds, fails permanently or runs out of attempts,
+// backing off between tries
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	wait := firstBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == maxAttempts || !transient(err) || ctx.Err() != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// transient reports whether err is worth retrying: network failures, server
+// errors and throttling, but not rejected requests
+func transient(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Status >= 500 || e.Status == http.StatusTooManyRequests || e.Code == "RequestTimeout" || e.Code == "SlowDown"
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.Is(err, ErrChecksumMismatch)
+}
@@ -0,0 +1,420 @@
+// Package s3 uploads files to S3-compatible object storage (AWS, MinIO,
+// Backblaze B2, Wasabi, ...) with Signature Version 4. Uploads can ask the
+// provider to verify a SHA-256 checksum, pick a storage class and put the
+// object under Object Lock retention.
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/throttle"
+)
+
+// Environment variables read when a destination has no credentials of its own
+const (
+	EnvAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	EnvSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+)
+
+// Object Lock retention modes
+const (
+	LockGovernance = "GOVERNANCE"
+	LockCompliance = "COMPLIANCE"
+)
+
+// StorageClasses lists common storage classes; providers may accept others
+var StorageClasses = []string{"STANDARD", "STANDARD_IA", "ONEZONE_IA", "INTELLIGENT_TIERING", "GLACIER_IR", "GLACIER", "DEEP_ARCHIVE"}
+
+// ErrChecksumMismatch means the provider stored a different checksum than was sent
+var ErrChecksumMismatch = errors.New("provider reported a different SHA-256 checksum")
+
+// Client uploads to one destination
+type Client struct {
+	Dest   config.Destination
+	Secret string
+	HTTP   *http.Client // nil uses http.DefaultClient
+
+	// Journal records multipart uploads so they resume after a crash or
+	// network drop; nil keeps them in memory only
+	Journal *Journal
+
+	limiter *throttle.Limiter
+	now     func() time.Time
+}
+
+// New returns a client for dest; empty credentials fall back to the AWS environment variables
+func New(dest config.Destination, secret string) (*Client, error) {
+	if dest.Bucket == "" {
+		return nil, errors.New("destination has no bucket")
+	}
+	if dest.AccessKeyID == "" {
+		dest.AccessKeyID = os.Getenv(EnvAccessKeyID)
+	}
+	if secret == "" {
+		secret = os.Getenv(EnvSecretAccessKey)
+	}
+	if dest.AccessKeyID == "" || secret == "" {
+		return nil, errors.New("destination needs an access key ID and secret key")
+	}
+	if err := ValidateOptions(dest); err != nil {
+		return nil, err
+	}
+	return &Client{Dest: dest, Secret: secret, limiter: throttle.NewLimiter(throttle.MBps(dest.RateLimitMBps))}, nil
+}
+
+// ValidateOptions checks the server-side safety settings of dest
+func ValidateOptions(dest config.Destination) error {
+	switch dest.LockMode {
+	case "":
+	case LockGovernance, LockCompliance:
+		if dest.LockDays <= 0 {
+			return errors.New("object lock needs a retention period in days")
+		}
+	default:
+		return fmt.Errorf("unknown object lock mode %q", dest.LockMode)
+	}
+	if strings.ContainsAny(dest.StorageClass, " \t\r\n") {
+		return fmt.Errorf("invalid storage class %q", dest.StorageClass)
+	}
+	return nil
+}
+
+func (c *Client) region() string {
+	if c.Dest.Region == "" {
+		return "us-east-1"
+	}
+	return c.Dest.Region
+}
+
+// Key returns the object key a file called name is stored under
+func (c *Client) Key(name string) string {
+	prefix := strings.TrimLeft(c.Dest.Prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix + name
+}
+
+// objectURL builds the URL of key, or of the bucket itself when key is empty
+func (c *Client) objectURL(key string) (*url.URL, error) {
+	endpoint := c.Dest.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + c.region() + ".amazonaws.com"
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", c.Dest.Endpoint)
+	}
+	p := "/" + key
+	if c.Dest.PathStyle {
+		p = "/" + c.Dest.Bucket + strings.TrimSuffix(p, "/")
+		if key != "" {
+			p = "/" + c.Dest.Bucket + "/" + key
+		}
+	} else {
+		u.Host = c.Dest.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + p
+	u.RawPath = uriEncode(u.Path, false)
+	return u, nil
+}
+
+// PutResult describes a stored object
+type PutResult struct {
+	Key         string
+	ETag        string
+	SHA256      string    // base64, as providers report it
+	RetainUntil time.Time // zero without object lock
+}
+
+// PutFile uploads the file at src as key. Files of MultipartThreshold or more go
+// up in parts and resume from the journal after an interruption. onProgress may be nil.
+func (c *Client) PutFile(ctx context.Context, src, key string, onProgress func(done, total int64)) (*PutResult, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() >= MultipartThreshold {
+		return c.putMultipart(ctx, f, fi, src, key, onProgress)
+	}
+	sum, err := hashSection(io.NewSectionReader(f, 0, fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+	u, err := c.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PutResult{Key: key}
+	// Object Lock uploads must carry an integrity checksum, so locking implies it
+	if c.checksums() {
+		res.SHA256 = base64.StdEncoding.EncodeToString(sum)
+	}
+	err = c.retry(ctx, func() error {
+		body := c.body(io.NewSectionReader(f, 0, fi.Size()), 0, fi.Size(), onProgress)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), body)
+		if err != nil {
+			return err
+		}
+		req.ContentLength = fi.Size()
+		req.Header.Set("Content-Type", "application/octet-stream")
+		if res.SHA256 != "" {
+			req.Header.Set("x-amz-checksum-sha256", res.SHA256)
+		}
+		res.RetainUntil = c.objectHeaders(req.Header)
+		c.sign(req, hex.EncodeToString(sum))
+		resp, err := c.do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		res.ETag = strings.Trim(resp.Header.Get("ETag"), `"`)
+		if got := resp.Header.Get("x-amz-checksum-sha256"); res.SHA256 != "" && got != "" && got != res.SHA256 {
+			return ErrChecksumMismatch
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("upload %s: %w", key, err)
+	}
+	return res, nil
+}
+
+func (c *Client) checksums() bool { return c.Dest.Checksum || c.Dest.LockMode != "" }
+
+// objectHeaders sets the storage class and Object Lock headers and returns the retention end
+func (c *Client) objectHeaders(h http.Header) time.Time {
+	if c.Dest.StorageClass != "" {
+		h.Set("x-amz-storage-class", c.Dest.StorageClass)
+	}
+	if c.Dest.LockMode == "" {
+		return time.Time{}
+	}
+	until := c.clock().UTC().AddDate(0, 0, c.Dest.LockDays).Truncate(time.Second)
+	h.Set("x-amz-object-lock-mode", c.Dest.LockMode)
+	h.Set("x-amz-object-lock-retain-until-date", until.Format(time.RFC3339))
+	return until
+}
+
+// body wraps r, the bytes from offset on of a total-byte upload, with the rate
+// limit and progress reporting
+func (c *Client) body(r io.Reader, offset, total int64, onProgress func(done, total int64)) io.Reader {
+	if c.limiter == nil && onProgress == nil {
+		return r
+	}
+	return &progressReader{r: r, done: offset, total: total, cb: onProgress, limit: c.limiter}
+}
+
+func hashSection(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Check verifies that the bucket exists and the credentials can reach it
+func (c *Client) Check(ctx context.Context) error {
+	u, err := c.objectURL("")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	c.sign(req, emptySHA256)
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("bucket %s: %w", c.Dest.Bucket, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends req and turns non-2xx answers into errors carrying the S3 error code
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return nil, &Error{Status: resp.StatusCode, Code: e.Code, Message: e.Message}
+	}
+	return nil, &Error{Status: resp.StatusCode}
+}
+
+// Error is an error response from the provider
+type Error struct {
+	Status  int
+	Code    string // e.g. "AccessDenied", "InvalidRequest"
+	Message string
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Code == "":
+		return fmt.Sprintf("HTTP %d", e.Status)
+	case e.Message == "":
+		return e.Code
+	default:
+		return e.Code + ": " + e.Message
+	}
+}
+
+func (c *Client) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds an AWS Signature Version 4 Authorization header covering the host
+// and every header already set on req
+func (c *Client) sign(req *http.Request, payloadHash string) {
+	now := c.clock().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.EscapedPath(), false),
+		canonicalQuery(req.URL.Query()),
+		canonHeaders.String(),
+		signed,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + c.region() + "/s3/aws4_request"
+	digest := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := []byte("AWS4" + c.Secret)
+	for _, part := range []string{date, c.region(), "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.Dest.AccessKeyID, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes s the way SigV4 expects; an already escaped path
+// is decoded first so it is not encoded twice
+func uriEncode(s string, encodeSlash bool) string {
+	if !encodeSlash {
+		if u, err := url.PathUnescape(s); err == nil {
+			s = u
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+type progressReader struct {
+	r     io.Reader
+	done  int64
+	total int64
+	cb    func(done, total int64)
+	limit *throttle.Limiter
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.limit.Wait(int64(n))
+		p.done += int64(n)
+		if p.cb != nil {
+			p.cb(p.done, p.total)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,90 @@
+// Package safedelete removes the sources of encrypt and decrypt runs only after
+// the output of that same item has been verified, so a failed, truncated or
+// missing output never costs the original.
+package safedelete
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/splitter"
+)
+
+// ErrUnverified is wrapped when an output failed verification and its source was kept
+var ErrUnverified = errors.New("output failed verification; source kept")
+
+// CheckEncrypted verifies that output is a complete encryption of src. HadesCrypt
+// containers, Deniability Mode output included, are decrypted in full with
+// password, discarding the plaintext, and for a file source must record src's
+// size. OpenPGP and age outputs cannot be
+// opened without gpg or a private key and are only checked to be non-empty.
+func CheckEncrypted(src, output string, password []byte) error {
+	fi, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() || fi.Size() == 0 {
+		return fmt.Errorf("%s is empty or not a file", output)
+	}
+	hdr, err := cryptoengine.ReadHeaderWithPassword(output, password)
+	if err != nil {
+		if format.IsOpenPGP(output) || format.IsAge(output) {
+			return nil
+		}
+		return err
+	}
+	if hdr.Mode == cryptoengine.ModeGnuPG {
+		return nil
+	}
+	if si, err := os.Stat(src); err != nil {
+		return err
+	} else if si.Mode().IsRegular() && hdr.OriginalSize != si.Size() {
+		return fmt.Errorf("%s records %d bytes but the source has %d", output, hdr.OriginalSize, si.Size())
+	}
+	return cryptoengine.DecryptFileToWriter(output, io.Discard, password, nil)
+}
+
+// CheckDecrypted verifies that decrypting src produced output: a folder for
+// archived folders, otherwise a file of the size src's header records
+func CheckDecrypted(src, output string) error {
+	fi, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return nil
+	}
+	if hdr, err := cryptoengine.ReadHeaderFromFile(src); err == nil && hdr.Mode != cryptoengine.ModeGnuPG && fi.Size() != hdr.OriginalSize {
+		return fmt.Errorf("%s has %d bytes but %d were encrypted", output, fi.Size(), hdr.OriginalSize)
+	}
+	return nil
+}
+
+// AfterEncrypt removes src, recursively for folders, once CheckEncrypted passes
+func AfterEncrypt(src, output string, password []byte) error {
+	if err := CheckEncrypted(src, output, password); err != nil {
+		return fmt.Errorf("%s: %w: %v", src, ErrUnverified, err)
+	}
+	return os.RemoveAll(src)
+}
+
+// AfterDecrypt removes src once CheckDecrypted passes. A container split into
+// volumes (see splitter.Volumes) goes with all of them and its manifest.
+func AfterDecrypt(src, output string) error {
+	first := src
+	base, parts := splitter.Volumes(src)
+	if parts != nil {
+		first = parts[0]
+	}
+	if err := CheckDecrypted(first, output); err != nil {
+		return fmt.Errorf("%s: %w: %v", src, ErrUnverified, err)
+	}
+	if parts != nil {
+		return splitter.DeleteChunks(base)
+	}
+	return os.Remove(src)
+}
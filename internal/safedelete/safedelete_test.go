@@ -0,0 +1,165 @@
+package safedelete
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+// testKDF keeps key derivation negligible
+var testKDF = cryptoengine.Argon2Params{Time: 1, Memory: 64, Threads: 1}
+
+var password = []byte("correct horse battery staple")
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// encrypted returns a plaintext source and its container
+func encrypted(t *testing.T, size int) (src, out string) {
+	t.Helper()
+	dir := t.TempDir()
+	src = filepath.Join(dir, "plain.txt")
+	out = src + ".hadescrypt"
+	writeFile(t, src, make([]byte, size))
+	opts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM, Argon2: testKDF}
+	if err := cryptoengine.EncryptFileWithOptions(src, out, password, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	return src, out
+}
+
+func assertKept(t *testing.T, err error, src string) {
+	t.Helper()
+	if !errors.Is(err, ErrUnverified) {
+		t.Fatalf("err = %v, want ErrUnverified", err)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Fatalf("source was removed: %v", err)
+	}
+}
+
+func assertRemoved(t *testing.T, err error, src string) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatalf("source still exists: %v", err)
+	}
+}
+
+func TestAfterEncryptRemovesVerifiedSource(t *testing.T) {
+	src, out := encrypted(t, 3<<20+17)
+	assertRemoved(t, AfterEncrypt(src, out, password), src)
+}
+
+func TestAfterEncryptRemovesDeniableSource(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "plain.txt")
+	out := src + ".hadescrypt"
+	writeFile(t, src, make([]byte, 4096))
+	opts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM, Argon2: testKDF, UseDeniability: true}
+	if err := cryptoengine.EncryptFileWithOptions(src, out, password, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertRemoved(t, AfterEncrypt(src, out, password), src)
+}
+
+func TestAfterEncryptKeepsSourceWithoutOutput(t *testing.T) {
+	src, out := encrypted(t, 1024)
+	os.Remove(out)
+	assertKept(t, AfterEncrypt(src, out, password), src)
+}
+
+func TestAfterEncryptKeepsSourceWithEmptyOutput(t *testing.T) {
+	src, out := encrypted(t, 1024)
+	writeFile(t, out, nil)
+	assertKept(t, AfterEncrypt(src, out, password), src)
+}
+
+func TestAfterEncryptKeepsSourceWithTruncatedOutput(t *testing.T) {
+	src, out := encrypted(t, 2<<20)
+	fi, _ := os.Stat(out)
+	if err := os.Truncate(out, fi.Size()-100); err != nil {
+		t.Fatal(err)
+	}
+	assertKept(t, AfterEncrypt(src, out, password), src)
+}
+
+func TestAfterEncryptKeepsSourceWithCorruptOutput(t *testing.T) {
+	src, out := encrypted(t, 4096)
+	data, _ := os.ReadFile(out)
+	data[len(data)-1] ^= 0xff
+	writeFile(t, out, data)
+	assertKept(t, AfterEncrypt(src, out, password), src)
+}
+
+func TestAfterEncryptKeepsSourceWithWrongPassword(t *testing.T) {
+	src, out := encrypted(t, 4096)
+	assertKept(t, AfterEncrypt(src, out, []byte("wrong")), src)
+}
+
+func TestAfterEncryptKeepsSourceThatChanged(t *testing.T) {
+	src, out := encrypted(t, 4096)
+	writeFile(t, src, make([]byte, 5000))
+	assertKept(t, AfterEncrypt(src, out, password), src)
+}
+
+func TestAfterEncryptKeepsSourceWithForeignOutput(t *testing.T) {
+	src, _ := encrypted(t, 4096)
+	other := filepath.Join(t.TempDir(), "other.bin")
+	writeFile(t, other, []byte("not a container"))
+	assertKept(t, AfterEncrypt(src, other, password), src)
+}
+
+func TestAfterEncryptRemovesFolderSource(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "folder")
+	os.Mkdir(dir, 0700)
+	writeFile(t, filepath.Join(dir, "a.txt"), []byte("a"))
+	archive := filepath.Join(t.TempDir(), "folder.tar.gz")
+	writeFile(t, archive, make([]byte, 777))
+	out := dir + ".hadescrypt"
+	opts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeChaCha20, Argon2: testKDF}
+	if err := cryptoengine.EncryptFileWithOptions(archive, out, password, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertRemoved(t, AfterEncrypt(dir, out, password), dir)
+}
+
+func TestAfterEncryptAcceptsNonEmptyAgeOutput(t *testing.T) {
+	src, _ := encrypted(t, 64)
+	age := src + ".age"
+	writeFile(t, age, nil)
+	assertKept(t, AfterEncrypt(src, age, password), src)
+	writeFile(t, age, []byte("age-encryption.org/v1\n"))
+	assertRemoved(t, AfterEncrypt(src, age, password), src)
+}
+
+func TestAfterDecrypt(t *testing.T) {
+	src, out := encrypted(t, 4096)
+	plain := src + ".out"
+
+	assertKept(t, AfterDecrypt(out, plain), out)
+
+	writeFile(t, plain, make([]byte, 4000))
+	assertKept(t, AfterDecrypt(out, plain), out)
+
+	if err := cryptoengine.DecryptFile(out, plain, password, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertRemoved(t, AfterDecrypt(out, plain), out)
+}
+
+func TestAfterDecryptAcceptsExtractedFolder(t *testing.T) {
+	_, out := encrypted(t, 4096)
+	dir := filepath.Join(t.TempDir(), "extracted")
+	os.Mkdir(dir, 0700)
+	assertRemoved(t, AfterDecrypt(out, dir), out)
+}
@@ -0,0 +1,183 @@
+// Package safename rewrites file names for targets that reject or mangle some
+// of them: FAT and Windows refuse reserved characters, device names and
+// trailing dots or spaces, and some cloud providers and legacy systems turn
+// non-ASCII names into question marks. The rewritten name is only for the
+// target; callers keep the original, e.g. in the container metadata.
+package safename
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Style is how names are rewritten
+type Style string
+
+const (
+	// Keep leaves names as they are
+	Keep Style = "keep"
+	// Transliterate spells names in ASCII: "Ärger.txt" becomes "Aerger.txt", "Москва" "Moskva".
+	// Reserved characters become "_" and scripts without a spelling are percent-encoded.
+	Transliterate Style = "transliterate"
+	// Percent encodes every byte outside a portable ASCII set as %XX, so the
+	// name is unambiguous and can be decoded again
+	Percent Style = "percent"
+)
+
+// Styles lists the styles in the order they are offered to users
+var Styles = []Style{Keep, Transliterate, Percent}
+
+// ParseStyle accepts a style name; empty means Keep
+func ParseStyle(s string) (Style, error) {
+	switch st := Style(strings.ToLower(strings.TrimSpace(s))); st {
+	case "":
+		return Keep, nil
+	case Keep, Transliterate, Percent:
+		return st, nil
+	}
+	return "", fmt.Errorf("unknown name style %q (want keep, transliterate or percent)", s)
+}
+
+// Label is the user-facing name of the style
+func (st Style) Label() string {
+	switch st {
+	case Transliterate:
+		return "Transliterate to ASCII"
+	case Percent:
+		return "Percent-encode"
+	}
+	return "Keep as is"
+}
+
+// StyleForLabel is the inverse of Label
+func StyleForLabel(label string) Style {
+	for _, st := range Styles {
+		if st.Label() == label {
+			return st
+		}
+	}
+	return Keep
+}
+
+// MaxBytes is the longest name Apply returns, leaving room for a container
+// extension within the 255 bytes most filesystems allow
+const MaxBytes = 240
+
+// Apply rewrites the file name name (not a path) in style st
+func Apply(st Style, name string) string {
+	switch st {
+	case Transliterate:
+		name = fixReserved(transliterate(name), func(byte) string { return "_" })
+	case Percent:
+		name = fixReserved(percentEncode(name), func(c byte) string { return fmt.Sprintf("%%%02X", c) })
+	default:
+		return name
+	}
+	return truncate(name)
+}
+
+// reserved are the characters Windows and FAT do not allow in names
+const reserved = `<>:"/\|?*`
+
+// portable reports whether c may appear anywhere in a name on every target
+func portable(c byte) bool {
+	return c > 0x20 && c < 0x7f && !strings.ContainsRune(reserved, rune(c))
+}
+
+func percentEncode(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (portable(c) || c == ' ') && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func transliterate(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < utf8.RuneSelf {
+			b.WriteRune(r)
+			continue
+		}
+		if t, ok := spellings[r]; ok {
+			b.WriteString(t)
+			continue
+		}
+		// letters with accents decompose into a base letter and combining marks
+		d := norm.NFD.String(string(r))
+		base, size := utf8.DecodeRuneInString(d)
+		marks := strings.TrimFunc(d[size:], func(r rune) bool { return unicode.Is(unicode.Mn, r) })
+		switch t, ok := spellings[base]; {
+		case marks != "":
+		case base < utf8.RuneSelf:
+			b.WriteRune(base)
+			continue
+		case ok:
+			b.WriteString(t)
+			continue
+		}
+		b.WriteString(percentEncode(string(r)))
+	}
+	return b.String()
+}
+
+// fixReserved replaces reserved and control characters with sub, avoids
+// trailing dots and spaces, Windows device names and empty names
+func fixReserved(name string, sub func(byte) string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; portable(c) || c == ' ' {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(sub(c))
+		}
+	}
+	name = b.String()
+	trimmed := strings.TrimRight(name, ". ")
+	for i := len(trimmed); i < len(name); i++ {
+		trimmed += sub(name[i])
+	}
+	name = trimmed
+	stem, rest, _ := strings.Cut(name, ".")
+	if isDevice(stem) {
+		name = stem + "_"
+		if rest != "" {
+			name += "." + rest
+		}
+	}
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// isDevice reports whether stem is a name Windows reserves for a device
+func isDevice(stem string) bool {
+	switch s := strings.ToUpper(strings.TrimRight(stem, " ")); s {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	default:
+		return len(s) == 4 && (strings.HasPrefix(s, "COM") || strings.HasPrefix(s, "LPT")) && s[3] >= '1' && s[3] <= '9'
+	}
+}
+
+// truncate shortens name to MaxBytes, keeping its extension
+func truncate(name string) string {
+	if len(name) <= MaxBytes {
+		return name
+	}
+	ext := ""
+	if i := strings.LastIndexByte(name, '.'); i > 0 && len(name)-i <= 16 {
+		ext = name[i:]
+	}
+	return name[:MaxBytes-len(ext)] + ext
+}
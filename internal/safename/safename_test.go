@@ -0,0 +1,70 @@
+package safename
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	for in, want := range map[string]string{
+		"report.pdf":          "report.pdf",
+		"Ärger über Öl.txt":   "Aerger ueber Oel.txt",
+		"café crème.doc":      "cafe creme.doc",
+		"Москва 2024.jpg":     "Moskva 2024.jpg",
+		"Щука и Ёж":           "Shchuka i Yozh",
+		"Ελληνικά.odt":        "Ellinika.odt",
+		"Łódź—plan.md":        "Lodz-plan.md",
+		"中文.txt":              "%E4%B8%AD%E6%96%87.txt",
+		`a:b*c?.txt`:          "a_b_c_.txt",
+		"notes. ":             "notes__",
+		"CON.txt":             "CON_.txt",
+		"lpt1":                "lpt1_",
+		"":                    "_",
+		"résumé (final).pdf ": "resume (final).pdf_",
+	} {
+		if got := Apply(Transliterate, in); got != want {
+			t.Errorf("Transliterate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	for in, want := range map[string]string{
+		"report.pdf":   "report.pdf",
+		"50% off.txt":  "50%25 off.txt",
+		"Ärger.txt":    "%C3%84rger.txt",
+		`what?.txt`:    "what%3F.txt",
+		"trailing. ":   "trailing%2E%20",
+		"aux":          "aux_",
+		"tab\tname.md": "tab%09name.md",
+	} {
+		if got := Apply(Percent, in); got != want {
+			t.Errorf("Percent(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestKeepAndLength(t *testing.T) {
+	if got := Apply(Keep, "Ärger: ?.txt"); got != "Ärger: ?.txt" {
+		t.Errorf("Keep changed the name to %q", got)
+	}
+	long := strings.Repeat("ж", 200) + ".tar.gz"
+	got := Apply(Percent, long)
+	if len(got) > MaxBytes || !strings.HasSuffix(got, ".gz") {
+		t.Errorf("long name became %d bytes: %q", len(got), got)
+	}
+}
+
+func TestStyleNames(t *testing.T) {
+	for _, st := range Styles {
+		if got, err := ParseStyle(string(st)); err != nil || got != st {
+			t.Errorf("ParseStyle(%q) = %q, %v", st, got, err)
+		}
+		if StyleForLabel(st.Label()) != st {
+			t.Errorf("label %q does not map back to %q", st.Label(), st)
+		}
+	}
+	if _, err := ParseStyle("rot13"); err == nil {
+		t.Error("unknown style accepted")
+	}
+}
@@ -0,0 +1,46 @@
+package safename
+
+import (
+	"strings"
+	"unicode"
+)
+
+// spellings are the ASCII spellings of letters that do not decompose into an
+// ASCII base letter and accents. Accented Greek and Cyrillic letters decompose
+// into the letters listed here.
+var spellings = map[rune]string{
+	// German spells umlauts out rather than dropping the dots
+	'ä': "ae", 'ö': "oe", 'ü': "ue", 'Ä': "Ae", 'Ö': "Oe", 'Ü': "Ue", 'ß': "ss", 'ẞ': "SS",
+	'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'ø': "o", 'Ø': "O", 'å': "aa", 'Å': "Aa",
+	'đ': "d", 'Đ': "D", 'ð': "d", 'Ð': "D", 'þ': "th", 'Þ': "Th", 'ł': "l", 'Ł': "L",
+	'ı': "i", 'ŋ': "ng", 'Ŋ': "Ng", 'ħ': "h", 'Ħ': "H",
+	// typographic punctuation
+	'‘': "'", '’': "'", '“': "'", '”': "'", '–': "-", '—': "-", '…': "...", '«': "'", '»': "'",
+	' ': " ",
+}
+
+func init() {
+	// lower-case letters; capitals take a capitalised spelling
+	for _, table := range []string{
+		// Cyrillic (Russian, Ukrainian, Belarusian, Serbian, Bulgarian)
+		"а a б b в v г g д d е e ё yo ж zh з z и i й y к k л l м m н n о o п p р r с s т t у u ф f х kh ц ts ч ch ш sh щ shch ъ - ы y ь - э e ю yu я ya " +
+			"і i ї yi є ye ґ g ў u ђ dj ј j љ lj њ nj ћ c џ dz ѓ gj ќ kj ѕ dz",
+		// Greek
+		"α a β v γ g δ d ε e ζ z η i θ th ι i κ k λ l μ m ν n ξ x ο o π p ρ r σ s ς s τ t υ y φ f χ ch ψ ps ω o",
+	} {
+		f := strings.Fields(table)
+		for i := 0; i+1 < len(f); i += 2 {
+			r, t := []rune(f[i])[0], f[i+1]
+			if t == "-" {
+				t = "" // hard and soft signs are not spelled
+			}
+			spellings[r] = t
+			if up := unicode.ToUpper(r); up != r {
+				if t != "" {
+					t = strings.ToUpper(t[:1]) + t[1:]
+				}
+				spellings[up] = t
+			}
+		}
+	}
+}
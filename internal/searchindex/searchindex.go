@@ -0,0 +1,261 @@
+package searchindex
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+)
+
+// File layout of the encrypted index:
+// [4]MAGIC "HIX1" | [16]SALT | [12]NONCE | [..]AES-256-GCM(JSON)
+const (
+	indexMagic   = "HIX1"
+	saltLen      = 16
+	nonceLen     = 12
+	indexName    = "index.hadesidx"
+	argonTime    = uint32(1)
+	argonMemory  = uint32(64 * 1024)
+	argonThreads = uint8(4)
+	keyLen       = uint32(32)
+)
+
+// Entry describes a single plaintext file stored inside an encrypted container
+type Entry struct {
+	Container string `json:"container"` // path of the encrypted output
+	Path      string `json:"path"`      // path of the file relative to the container root
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256,omitempty"`
+	Added     int64  `json:"added"` // Unix timestamp
+}
+
+// Index is the decrypted, in-memory view of the search index
+type Index struct {
+	Entries []Entry `json:"entries"`
+}
+
+// DefaultPath returns the location of the index inside the config directory
+func DefaultPath() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, indexName), nil
+}
+
+// Exists reports whether an index file has already been created
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Load decrypts the index at path. A missing file yields an empty index.
+func Load(path string, password []byte) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Index{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(indexMagic)+saltLen+nonceLen || string(data[:len(indexMagic)]) != indexMagic {
+		return nil, fmt.Errorf("not a HadesCrypt index")
+	}
+	salt := data[len(indexMagic) : len(indexMagic)+saltLen]
+	nonce := data[len(indexMagic)+saltLen : len(indexMagic)+saltLen+nonceLen]
+	sealed := data[len(indexMagic)+saltLen+nonceLen:]
+
+	aead, err := newAEAD(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, sealed, []byte(indexMagic))
+	if err != nil {
+		return nil, fmt.Errorf("unlock index: wrong password or corrupted index")
+	}
+
+	ix := &Index{}
+	if err := json.Unmarshal(plain, ix); err != nil {
+		return nil, fmt.Errorf("parse index: %w", err)
+	}
+	return ix, nil
+}
+
+// Save encrypts the index with password and writes it to path
+func (ix *Index) Save(path string, password []byte) error {
+	plain, err := json.Marshal(ix)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return fmt.Errorf("generate salt: %w", err)
+	}
+	nonce := make([]byte, nonceLen)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+	aead, err := newAEAD(password, salt)
+	if err != nil {
+		return err
+	}
+
+	out := make([]byte, 0, len(indexMagic)+saltLen+nonceLen+len(plain)+aead.Overhead())
+	out = append(out, indexMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, plain, []byte(indexMagic))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, out)
+}
+
+// writeFileAtomic replaces path with data through a synced temp file in the same
+// folder, so a crash or full disk leaves the previous index in place
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Add replaces all entries of container with the given entries
+func (ix *Index) Add(container string, entries []Entry) {
+	ix.Remove(container)
+	now := time.Now().Unix()
+	for _, e := range entries {
+		e.Container = container
+		if e.Added == 0 {
+			e.Added = now
+		}
+		ix.Entries = append(ix.Entries, e)
+	}
+}
+
+// Remove drops every entry belonging to container
+func (ix *Index) Remove(container string) {
+	kept := ix.Entries[:0]
+	for _, e := range ix.Entries {
+		if e.Container != container {
+			kept = append(kept, e)
+		}
+	}
+	ix.Entries = kept
+}
+
+// Search returns entries whose path contains query (case-insensitive) or whose
+// SHA-256 equals query. Results are sorted by container then path.
+func (ix *Index) Search(query string) []Entry {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return nil
+	}
+	var results []Entry
+	for _, e := range ix.Entries {
+		if strings.Contains(strings.ToLower(e.Path), q) || strings.EqualFold(e.SHA256, q) {
+			results = append(results, e)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Container != results[j].Container {
+			return results[i].Container < results[j].Container
+		}
+		return results[i].Path < results[j].Path
+	})
+	return results
+}
+
+// Containers returns the number of distinct containers in the index
+func (ix *Index) Containers() int {
+	seen := make(map[string]struct{})
+	for _, e := range ix.Entries {
+		seen[e.Container] = struct{}{}
+	}
+	return len(seen)
+}
+
+// BuildEntries collects index entries for a file or a folder tree, walked with
+// opts so the entries match what the folder's archive holds. Paths are relative
+// to the folder root (or the file's base name for single files).
+func BuildEntries(source string, opts fswalk.Options) ([]Entry, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		sum, err := hashFile(source)
+		if err != nil {
+			return nil, err
+		}
+		return []Entry{{Path: filepath.Base(source), Size: info.Size(), SHA256: sum}}, nil
+	}
+
+	var entries []Entry
+	err = fswalk.Walk(source, opts, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi == nil || !fi.Mode().IsRegular() {
+			return nil
+		}
+		rel, rerr := filepath.Rel(source, p)
+		if rerr != nil {
+			return nil
+		}
+		sum, herr := hashFile(p)
+		if herr != nil {
+			return nil
+		}
+		entries = append(entries, Entry{Path: filepath.ToSlash(rel), Size: fi.Size(), SHA256: sum})
+		return nil
+	})
+	return entries, err
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func newAEAD(password, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey(password, salt, argonTime, argonMemory, argonThreads, keyLen)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
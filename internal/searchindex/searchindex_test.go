@@ -0,0 +1,98 @@
+package searchindex
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, indexName)
+	password := []byte("index password")
+
+	ix := &Index{}
+	ix.Add("a.hadescrypt", []Entry{{Path: "notes/plan.txt", Size: 12}})
+	if err := ix.Save(path, password); err != nil {
+		t.Fatal(err)
+	}
+	// saving again replaces the index in place
+	ix.Add("b.hadescrypt", []Entry{{Path: "photo.jpg", Size: 34}})
+	if err := ix.Save(path, password); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || entries[0].Name() != indexName {
+		t.Errorf("files after saving: %v, %v", entries, err)
+	}
+
+	got, err := Load(path, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Containers() != 2 || len(got.Search("plan")) != 1 {
+		t.Errorf("loaded %+v", got.Entries)
+	}
+	if _, err := Load(path, []byte("wrong")); err == nil {
+		t.Error("Load with a wrong password succeeded")
+	}
+}
+
+func TestSaveFailureLeavesNoTemp(t *testing.T) {
+	dir := t.TempDir()
+	// a directory in the way makes the rename fail after the write
+	blocked := filepath.Join(dir, indexName)
+	if err := os.MkdirAll(filepath.Join(blocked, "x"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	ix := &Index{}
+	ix.Add("a.hadescrypt", []Entry{{Path: "a.txt"}})
+	if err := ix.Save(blocked, []byte("pw")); err == nil {
+		t.Fatal("Save over a directory succeeded")
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(dir, ".*.tmp")); len(tmps) != 0 {
+		t.Errorf("temp files left: %v", tmps)
+	}
+}
+
+func TestBuildEntriesWalkOptions(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	for name, data := range map[string]string{
+		filepath.Join(root, "keep.txt"):         "keep",
+		filepath.Join(root, "cache", "tmp.bin"): "skip",
+		filepath.Join(outside, "linked.txt"):    "linked",
+	} {
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if err := os.WriteFile(name, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+	exclude := func(path string, isDir bool) bool { return filepath.Base(path) == "cache" }
+
+	for _, tc := range []struct {
+		policy fswalk.Policy
+		want   []string
+	}{
+		{fswalk.Skip, []string{"keep.txt"}},
+		{fswalk.Follow, []string{"keep.txt", "link/linked.txt"}},
+	} {
+		entries, err := BuildEntries(root, fswalk.Options{Symlinks: tc.policy, Exclude: exclude})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Path)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: entries %v, want %v", tc.policy, got, tc.want)
+		}
+	}
+}
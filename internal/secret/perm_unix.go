@@ -0,0 +1,19 @@
+//go:build !windows
+
+package secret
+
+import (
+	"fmt"
+	"os"
+)
+
+func checkPrivate(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("read password file: %w", err)
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("password file %s is accessible by other users (mode %04o); run chmod 600", path, fi.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+//go:build windows
+
+package secret
+
+// checkPrivate is a no-op on Windows, where access is governed by ACLs
+func checkPrivate(path string) error { return nil }
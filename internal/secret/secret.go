@@ -0,0 +1,141 @@
+// Package secret reads passwords from files, the environment or a no-echo
+// terminal prompt, and keeps them out of error strings and logs.
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Environment variables understood by every headless entry point
+const (
+	EnvPasswordFile      = "HADESCRYPT_PASSWORD_FILE"
+	EnvAllowArgvPassword = "HADESCRYPT_ALLOW_ARGV_PASSWORD"
+)
+
+// Redacted replaces secret material in scrubbed text
+const Redacted = "[REDACTED]"
+
+var (
+	// ErrNoTerminal is returned by Prompt when no interactive terminal is attached
+	ErrNoTerminal = errors.New("no terminal available for password prompt")
+	// ErrEmpty is returned when a source yields an empty password
+	ErrEmpty = errors.New("password is empty")
+)
+
+// FromFile returns the first line of path. Files readable by other users are
+// rejected on Unix-like systems, as ssh does for private keys.
+func FromFile(path string) ([]byte, error) {
+	if err := checkPrivate(path); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read password file: %w", err)
+	}
+	defer f.Close()
+	line, err := readLine(f)
+	if err != nil {
+		return nil, fmt.Errorf("read password file: %w", err)
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("password file %s: %w", path, ErrEmpty)
+	}
+	return line, nil
+}
+
+// FromEnv returns the value of the environment variable name
+func FromEnv(name string) ([]byte, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil, fmt.Errorf("environment variable %s: %w", name, ErrEmpty)
+	}
+	return []byte(v), nil
+}
+
+// Prompt reads a password from the controlling terminal with echo disabled.
+// With confirm set the password is asked twice and must match.
+func Prompt(prompt string, confirm bool) ([]byte, error) {
+	pw, err := readNoEcho(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if len(pw) == 0 {
+		return nil, ErrEmpty
+	}
+	if confirm {
+		again, err := readNoEcho("Confirm " + strings.ToLower(prompt[:1]) + prompt[1:])
+		if err != nil {
+			Wipe(pw)
+			return nil, err
+		}
+		defer Wipe(again)
+		if !bytes.Equal(pw, again) {
+			Wipe(pw)
+			return nil, errors.New("passwords do not match")
+		}
+	}
+	return pw, nil
+}
+
+// readLine reads up to the first newline without keeping extra copies of the data
+func readLine(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	line, err := br.ReadSlice('\n')
+	if err != nil && err != io.EOF {
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, errors.New("password line too long")
+		}
+		return nil, err
+	}
+	line = bytes.TrimRight(line, "\r\n")
+	out := make([]byte, len(line))
+	copy(out, line)
+	Wipe(line)
+	return out, nil
+}
+
+// Wipe overwrites b with zeros
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// Scrub replaces every occurrence of the given secrets in s with Redacted.
+// Secrets shorter than 4 bytes are ignored to avoid mangling ordinary text.
+func Scrub(s string, secrets ...[]byte) string {
+	for _, sec := range secrets {
+		if len(sec) < 4 {
+			continue
+		}
+		s = strings.ReplaceAll(s, string(sec), Redacted)
+	}
+	return s
+}
+
+// ScrubError returns err with its message scrubbed, or nil. The original error
+// chain is kept so errors.Is still works.
+func ScrubError(err error, secrets ...[]byte) error {
+	if err == nil {
+		return nil
+	}
+	msg := Scrub(err.Error(), secrets...)
+	if msg == err.Error() {
+		return err
+	}
+	return &scrubbed{msg: msg, err: err}
+}
+
+type scrubbed struct {
+	msg string
+	err error
+}
+
+func (e *scrubbed) Error() string { return e.msg }
+func (e *scrubbed) Unwrap() error { return e.err }
@@ -0,0 +1,5 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package secret
+
+func readNoEcho(prompt string) ([]byte, error) { return nil, ErrNoTerminal }
@@ -0,0 +1,33 @@
+//go:build windows
+
+package secret
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func readNoEcho(prompt string) ([]byte, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, ErrNoTerminal
+	}
+	defer in.Close()
+	h := windows.Handle(in.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return nil, ErrNoTerminal
+	}
+	quiet := mode&^windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT
+	if err := windows.SetConsoleMode(h, quiet); err != nil {
+		return nil, fmt.Errorf("disable echo: %w", err)
+	}
+	defer windows.SetConsoleMode(h, mode)
+
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := readLine(in)
+	fmt.Fprintln(os.Stderr)
+	return line, err
+}
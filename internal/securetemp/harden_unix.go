@@ -0,0 +1,13 @@
+//go:build !windows
+
+package securetemp
+
+import "os"
+
+func harden(path string, isDir bool) error {
+	mode := os.FileMode(0600)
+	if isDir {
+		mode = 0700
+	}
+	return os.Chmod(path, mode)
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package securetemp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestrictModes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".hadescrypt")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(file, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		path  string
+		isDir bool
+		want  os.FileMode
+	}{{dir, true, 0700}, {file, false, 0600}} {
+		if err := Restrict(c.path, c.isDir); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode().Perm(); got != c.want {
+			t.Errorf("%s: mode %04o, want %04o", c.path, got, c.want)
+		}
+	}
+}
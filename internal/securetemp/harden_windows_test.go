@@ -0,0 +1,99 @@
+//go:build windows
+
+package securetemp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ownerOnlyACEs returns the ACEs of path's DACL after checking that every one
+// grants access to the current user only, and whether the DACL is protected
+func ownerOnlyACEs(t *testing.T, path string) ([]*windows.ACCESS_ALLOWED_ACE, bool) {
+	t.Helper()
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		t.Fatal(err)
+	}
+	control, _, err := sd.Control()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dacl == nil {
+		t.Fatalf("%s: null DACL grants everyone access", path)
+	}
+	var aces []*windows.ACCESS_ALLOWED_ACE
+	for i := uint32(0); i < uint32(dacl.AceCount); i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(dacl, i, &ace); err != nil {
+			t.Fatal(err)
+		}
+		if ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE {
+			t.Fatalf("%s: ACE %d has type %d", path, i, ace.Header.AceType)
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		if !sid.Equals(user.User.Sid) {
+			t.Fatalf("%s: ACE %d grants access to %s", path, i, sid)
+		}
+		aces = append(aces, ace)
+	}
+	return aces, control&windows.SE_DACL_PROTECTED != 0
+}
+
+func TestRestrictFileACL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restrict(path, false); err != nil {
+		t.Fatal(err)
+	}
+	aces, protected := ownerOnlyACEs(t, path)
+	if !protected {
+		t.Error("DACL still inherits from the parent folder")
+	}
+	if len(aces) != 1 {
+		t.Fatalf("got %d ACEs, want 1", len(aces))
+	}
+	if aces[0].Header.AceFlags&(windows.OBJECT_INHERIT_ACE|windows.CONTAINER_INHERIT_ACE) != 0 {
+		t.Error("file ACE is marked inheritable")
+	}
+}
+
+func TestRestrictDirACLIsInherited(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".hadescrypt")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restrict(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	aces, protected := ownerOnlyACEs(t, dir)
+	if !protected || len(aces) != 1 {
+		t.Fatalf("protected=%v, %d ACEs; want a protected DACL with 1 ACE", protected, len(aces))
+	}
+	if want := uint8(windows.OBJECT_INHERIT_ACE | windows.CONTAINER_INHERIT_ACE); aces[0].Header.AceFlags&want != want {
+		t.Errorf("ACE flags %#x do not pass the entry on to new files and folders", aces[0].Header.AceFlags)
+	}
+
+	// Files created later, as config.Save does, get only the inherited owner entry
+	child := filepath.Join(dir, "records.db")
+	if err := os.WriteFile(child, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if aces, _ := ownerOnlyACEs(t, child); len(aces) == 0 {
+		t.Error("new file has an empty DACL")
+	}
+}
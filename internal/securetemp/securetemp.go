@@ -0,0 +1,118 @@
+// Package securetemp creates temporary files and directories for plaintext with the
+// strictest permissions the platform offers: 0600/0700 on Unix, an owner-only,
+// non-inherited DACL on Windows.
+package securetemp
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/wipe"
+)
+
+// MkdirTemp creates a private temporary directory (see os.MkdirTemp for dir and pattern)
+func MkdirTemp(dir, pattern string) (string, error) {
+	path, err := os.MkdirTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	if err := harden(path, true); err != nil {
+		os.RemoveAll(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// CreateTemp creates a new private file opened for reading and writing
+// (see os.CreateTemp for dir and pattern)
+func CreateTemp(dir, pattern string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if err := harden(f.Name(), false); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// TempPrefix starts the name of the private folder around every TempPath file;
+// leftovers from an interrupted run can be recognised by it
+const TempPrefix = ".hadescrypt-tmp-"
+
+// TempPath creates an empty private file and returns its path. The file sits in
+// a new owner-only folder in dir, so its name cannot be guessed, cannot collide
+// with an existing file, and cannot be replaced by another user's link before
+// the caller opens it again. Callers that write to the path with os.Create keep
+// the restricted permissions, because truncating an existing file does not
+// change its mode or ACL. Remove deletes the file together with its folder.
+func TempPath(dir, pattern string) (string, error) {
+	private, err := MkdirTemp(dir, TempPrefix+"*")
+	if err != nil {
+		return "", err
+	}
+	f, err := CreateTemp(private, pattern)
+	if err != nil {
+		os.Remove(private)
+		return "", err
+	}
+	name := f.Name()
+	if err := f.Close(); err != nil {
+		Remove(name)
+		return "", err
+	}
+	return name, nil
+}
+
+// Remove deletes a file made by TempPath and its private folder. A file that was
+// already renamed away is not an error; the folder goes either way.
+func Remove(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	if dir := filepath.Dir(path); strings.HasPrefix(filepath.Base(dir), TempPrefix) {
+		if derr := os.Remove(dir); err == nil && !errors.Is(derr, fs.ErrNotExist) {
+			err = derr
+		}
+	}
+	return err
+}
+
+// Restrict gives an existing file or directory the same owner-only permissions
+// as the temporary ones created here
+func Restrict(path string, isDir bool) error {
+	return harden(path, isDir)
+}
+
+// CreateAnonymous returns a private file that has no name on disk where the platform
+// supports it (O_TMPFILE on Linux). Elsewhere the file is unlinked right after creation,
+// which is best effort on Windows where open files cannot be removed.
+func CreateAnonymous(dir string) (*os.File, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if f, err := openTmpfile(dir); err == nil {
+		return f, nil
+	}
+	f, err := CreateTemp(dir, ".hadescrypt_anon_*")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	return f, nil
+}
+
+// Shred destroys a file with the method that works on its storage (see package
+// wipe): an overwrite pass on disks, TRIM on SSDs, plain deletion elsewhere.
+func Shred(path string) error {
+	if _, err := wipe.File(path); err != nil {
+		return os.Remove(path)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package securetemp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTempPath(t *testing.T) {
+	dir := t.TempDir()
+	a, err := TempPath(dir, "report.pdf.*.__dec_tmp__")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := TempPath(dir, "report.pdf.*.__dec_tmp__")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b || filepath.Dir(a) == filepath.Dir(b) {
+		t.Errorf("two reservations share a name or folder: %s, %s", a, b)
+	}
+	private := filepath.Dir(a)
+	if filepath.Dir(private) != dir || !strings.HasPrefix(filepath.Base(private), TempPrefix) {
+		t.Errorf("%s is not in a private folder of %s", a, dir)
+	}
+	if fi, err := os.Stat(a); err != nil || fi.Size() != 0 {
+		t.Fatalf("reserved file: %v", err)
+	}
+
+	if err := Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(private); !os.IsNotExist(err) {
+		t.Errorf("private folder left behind: %v", err)
+	}
+
+	// a result renamed into place leaves only the folder to clean up
+	final := filepath.Join(dir, "report.pdf")
+	if err := os.Rename(b, final); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(b); err != nil {
+		t.Errorf("remove after rename: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "report.pdf" {
+		t.Errorf("left in dir: %v", entries)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build linux
+
+package securetemp
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func openTmpfile(dir string) (*os.File, error) {
+	fd, err := unix.Open(dir, unix.O_TMPFILE|unix.O_RDWR|unix.O_CLOEXEC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), dir+"/(anonymous)"), nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package securetemp
+
+import (
+	"errors"
+	"os"
+)
+
+func openTmpfile(dir string) (*os.File, error) {
+	return nil, errors.New("O_TMPFILE not supported")
+}
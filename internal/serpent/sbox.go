@@ -0,0 +1,255 @@
+package serpent
+
+// The S-boxes in bitslice form: each applies its table to the 32 columns of
+// x at once, x[0] holding the least significant bit of every column. The
+// expressions are the algebraic normal form of the tables in the
+// specification, which TestSBoxes checks them against, so no lookup depends
+// on secret data.
+
+// s0 is S-box 0
+func s0(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x0 ^ x01 ^ x2 ^ x02 ^ x12 ^ x012 ^ x3 ^ x023 ^ x123), ^(x0 ^ x02 ^ x12 ^ x012 ^ x13 ^ x023 ^ x123), x1^x01^x02^x012^x3^x13^x123, x0^x1^x2^x3^x03
+}
+
+// s1 is S-box 1
+func s1(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x0 ^ x1 ^ x12 ^ x03 ^ x23 ^ x023 ^ x123), ^(x0 ^ x01 ^ x2 ^ x02 ^ x3 ^ x13 ^ x013 ^ x023 ^ x123), ^(x1 ^ x01 ^ x2 ^ x3), ^(x1 ^ x02 ^ x3 ^ x03 ^ x013 ^ x023 ^ x123)
+}
+
+// s2 is S-box 2
+func s2(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x[0], x[1], x[2], x[3] = x1^x2^x02^x3, x0^x1^x2^x12^x012^x03^x013^x23^x023, x0^x1^x12^x3^x13^x013^x23^x023, ^(x0 ^ x1 ^ x2 ^ x012 ^ x13)
+}
+
+// s3 is S-box 3
+func s3(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = x0^x1^x12^x3^x03^x23^x023^x123, x0^x1^x02^x03^x013^x23^x023, x0^x01^x2^x012^x3^x13^x013, x0^x1^x01^x2^x02^x012^x3^x23^x023
+}
+
+// s4 is S-box 4
+func s4(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x1 ^ x01 ^ x2 ^ x3 ^ x03 ^ x13), x0^x02^x12^x3^x13^x23^x023^x123, x0^x01^x2^x12^x012^x13^x013^x23^x123, x0^x1^x2^x12^x03^x13^x013
+}
+
+// s5 is S-box 5
+func s5(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x1 ^ x01 ^ x2 ^ x3 ^ x03 ^ x13), ^(x0 ^ x01 ^ x2 ^ x3 ^ x13 ^ x013 ^ x23), ^(x1 ^ x02 ^ x3 ^ x013 ^ x23 ^ x023 ^ x123), ^(x0 ^ x1 ^ x2 ^ x012 ^ x3 ^ x03 ^ x023)
+}
+
+// s6 is S-box 6
+func s6(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x0 ^ x1 ^ x2 ^ x02 ^ x12 ^ x012 ^ x3 ^ x013 ^ x123), ^(x1 ^ x2 ^ x03), ^(x0 ^ x01 ^ x2 ^ x12 ^ x012 ^ x13 ^ x013 ^ x23 ^ x123), x1^x01^x2^x02^x012^x3^x23^x123
+}
+
+// s7 is S-box 7
+func s7(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x01 ^ x2 ^ x03 ^ x13 ^ x23 ^ x023 ^ x123), x1^x01^x2^x02^x12^x3^x03^x013^x023, x0^x1^x2^x012^x3^x03^x13^x013^x123, x0^x1^x2^x02^x012^x03
+}
+
+// inv0 is the inverse of S-box 0
+func inv0(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x01 ^ x2 ^ x12 ^ x03 ^ x13 ^ x013 ^ x23 ^ x023 ^ x123), x0^x1^x2^x02^x13^x023^x123, ^(x0 ^ x1 ^ x01 ^ x2 ^ x3), ^(x0 ^ x12 ^ x3 ^ x013 ^ x23 ^ x023 ^ x123)
+}
+
+// inv1 is the inverse of S-box 1
+func inv1(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x0 ^ x1 ^ x01 ^ x012 ^ x13 ^ x023 ^ x123), x1^x2^x012^x3^x03^x13^x023^x123, ^(x0 ^ x1 ^ x02 ^ x12 ^ x012 ^ x3 ^ x023), x0^x2^x3^x13
+}
+
+// inv2 is the inverse of S-box 2
+func inv2(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x[0], x[1], x[2], x[3] = x0^x1^x2^x12^x13, x1^x01^x2^x03^x013^x23^x023, ^(x0 ^ x01 ^ x2 ^ x3 ^ x03 ^ x13 ^ x013 ^ x023), ^(x01 ^ x12 ^ x012 ^ x3 ^ x023)
+}
+
+// inv3 is the inverse of S-box 3
+func inv3(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = x0^x2^x12^x3^x03^x13^x123, x1^x2^x12^x012^x3^x03^x023^x123, x01^x02^x12^x03^x13^x013^x23^x023, x0^x1^x2^x02^x012^x03^x013^x23
+}
+
+// inv4 is the inverse of S-box 4
+func inv4(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x[0], x[1], x[2], x[3] = ^(x0 ^ x1 ^ x2 ^ x3 ^ x03 ^ x013 ^ x23 ^ x023), x01^x2^x02^x3^x03^x023, ^(x0 ^ x1 ^ x01 ^ x2 ^ x02 ^ x012 ^ x3 ^ x13 ^ x013), x1^x01^x2^x03^x013^x23
+}
+
+// inv5 is the inverse of S-box 5
+func inv5(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x023 := x02 & x3
+	x[0], x[1], x[2], x[3] = x0^x12^x3^x013, x0^x1^x02^x12^x012^x3^x03^x013, x0^x01^x2^x13^x013^x023, ^(x1 ^ x01 ^ x2 ^ x012 ^ x03)
+}
+
+// inv6 is the inverse of S-box 6
+func inv6(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x0 ^ x01 ^ x02 ^ x12 ^ x012 ^ x3 ^ x013 ^ x123), ^(x1 ^ x2 ^ x02 ^ x3), ^(x0 ^ x1 ^ x12 ^ x13 ^ x013 ^ x23 ^ x123), ^(x1 ^ x01 ^ x2 ^ x12 ^ x012 ^ x3 ^ x03 ^ x013 ^ x23 ^ x123)
+}
+
+// inv7 is the inverse of S-box 7
+func inv7(x *[4]uint32) {
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	x01 := x0 & x1
+	x02 := x0 & x2
+	x12 := x1 & x2
+	x012 := x01 & x2
+	x03 := x0 & x3
+	x13 := x1 & x3
+	x013 := x01 & x3
+	x23 := x2 & x3
+	x023 := x02 & x3
+	x123 := x12 & x3
+	x[0], x[1], x[2], x[3] = ^(x0 ^ x1 ^ x12 ^ x13 ^ x013 ^ x23 ^ x123), ^(x0 ^ x2 ^ x12 ^ x3 ^ x03 ^ x13 ^ x023 ^ x123), x1^x02^x3^x013^x23^x023, x01^x2^x012^x03^x13^x013
+}
+
+// sbox and invSbox index the S-boxes by number, for the key schedule and tests
+var (
+	sbox    = [8]func(*[4]uint32){s0, s1, s2, s3, s4, s5, s6, s7}
+	invSbox = [8]func(*[4]uint32){inv0, inv1, inv2, inv3, inv4, inv5, inv6, inv7}
+)
@@ -0,0 +1,179 @@
+// Package serpent implements the Serpent block cipher (Anderson, Biham and
+// Knudsen, the AES finalist) for 128, 192 and 256-bit keys, in the byte order
+// of the NESSIE test vectors, Nettle, libgcrypt and the Linux kernel. It runs
+// in bitslice mode; see sbox.go.
+package serpent
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"math/bits"
+	"strconv"
+)
+
+// BlockSize is the Serpent block size in bytes
+const BlockSize = 16
+
+// KeySizeError is returned for keys other than 16, 24 or 32 bytes
+type KeySizeError int
+
+func (k KeySizeError) Error() string {
+	return "serpent: invalid key size " + strconv.Itoa(int(k))
+}
+
+// phi is the fractional part of the golden ratio, used by the key schedule
+const phi = 0x9e3779b9
+
+// transform is the linear transformation between rounds
+func transform(x *[4]uint32) {
+	x[0] = bits.RotateLeft32(x[0], 13)
+	x[2] = bits.RotateLeft32(x[2], 3)
+	x[1] ^= x[0] ^ x[2]
+	x[3] ^= x[2] ^ x[0]<<3
+	x[1] = bits.RotateLeft32(x[1], 1)
+	x[3] = bits.RotateLeft32(x[3], 7)
+	x[0] ^= x[1] ^ x[3]
+	x[2] ^= x[3] ^ x[1]<<7
+	x[0] = bits.RotateLeft32(x[0], 5)
+	x[2] = bits.RotateLeft32(x[2], 22)
+}
+
+// untransform inverts transform
+func untransform(x *[4]uint32) {
+	x[2] = bits.RotateLeft32(x[2], -22)
+	x[0] = bits.RotateLeft32(x[0], -5)
+	x[2] ^= x[3] ^ x[1]<<7
+	x[0] ^= x[1] ^ x[3]
+	x[3] = bits.RotateLeft32(x[3], -7)
+	x[1] = bits.RotateLeft32(x[1], -1)
+	x[3] ^= x[2] ^ x[0]<<3
+	x[1] ^= x[0] ^ x[2]
+	x[2] = bits.RotateLeft32(x[2], -3)
+	x[0] = bits.RotateLeft32(x[0], -13)
+}
+
+type serpentCipher struct {
+	subkeys [33][4]uint32
+}
+
+// NewCipher returns Serpent with a 16, 24 or 32-byte key. Shorter keys are
+// padded as the specification describes.
+func NewCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, KeySizeError(len(key))
+	}
+	var w [8 + 132]uint32
+	for i := range len(key) / 4 {
+		w[i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+	if len(key) < 32 {
+		w[len(key)/4] = 1
+	}
+	for i := 8; i < len(w); i++ {
+		w[i] = bits.RotateLeft32(w[i-8]^w[i-5]^w[i-3]^w[i-1]^phi^uint32(i-8), 11)
+	}
+	c := new(serpentCipher)
+	for i := range c.subkeys {
+		k := &c.subkeys[i]
+		copy(k[:], w[8+4*i:])
+		sbox[(35-i)%8](k)
+	}
+	return c, nil
+}
+
+func (c *serpentCipher) BlockSize() int { return BlockSize }
+
+func (c *serpentCipher) Encrypt(dst, src []byte) {
+	if len(src) < BlockSize || len(dst) < BlockSize {
+		panic("serpent: input not full block")
+	}
+	x := load(src)
+	k := &c.subkeys
+	for r := 0; r < 32; r += 8 {
+		xorKey(&x, &k[r])
+		s0(&x)
+		transform(&x)
+		xorKey(&x, &k[r+1])
+		s1(&x)
+		transform(&x)
+		xorKey(&x, &k[r+2])
+		s2(&x)
+		transform(&x)
+		xorKey(&x, &k[r+3])
+		s3(&x)
+		transform(&x)
+		xorKey(&x, &k[r+4])
+		s4(&x)
+		transform(&x)
+		xorKey(&x, &k[r+5])
+		s5(&x)
+		transform(&x)
+		xorKey(&x, &k[r+6])
+		s6(&x)
+		transform(&x)
+		xorKey(&x, &k[r+7])
+		s7(&x)
+		// the last round replaces the transformation with a final key
+		if r < 24 {
+			transform(&x)
+		}
+	}
+	xorKey(&x, &k[32])
+	store(dst, &x)
+}
+
+func (c *serpentCipher) Decrypt(dst, src []byte) {
+	if len(src) < BlockSize || len(dst) < BlockSize {
+		panic("serpent: input not full block")
+	}
+	x := load(src)
+	k := &c.subkeys
+	xorKey(&x, &k[32])
+	for r := 24; r >= 0; r -= 8 {
+		if r < 24 {
+			untransform(&x)
+		}
+		inv7(&x)
+		xorKey(&x, &k[r+7])
+		untransform(&x)
+		inv6(&x)
+		xorKey(&x, &k[r+6])
+		untransform(&x)
+		inv5(&x)
+		xorKey(&x, &k[r+5])
+		untransform(&x)
+		inv4(&x)
+		xorKey(&x, &k[r+4])
+		untransform(&x)
+		inv3(&x)
+		xorKey(&x, &k[r+3])
+		untransform(&x)
+		inv2(&x)
+		xorKey(&x, &k[r+2])
+		untransform(&x)
+		inv1(&x)
+		xorKey(&x, &k[r+1])
+		untransform(&x)
+		inv0(&x)
+		xorKey(&x, &k[r])
+	}
+	store(dst, &x)
+}
+
+func load(b []byte) [4]uint32 {
+	return [4]uint32{binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:]), binary.LittleEndian.Uint32(b[8:]), binary.LittleEndian.Uint32(b[12:])}
+}
+
+func store(b []byte, x *[4]uint32) {
+	for i, v := range x {
+		binary.LittleEndian.PutUint32(b[4*i:], v)
+	}
+}
+
+func xorKey(x, k *[4]uint32) {
+	for i := range x {
+		x[i] ^= k[i]
+	}
+}
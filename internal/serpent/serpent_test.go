@@ -0,0 +1,104 @@
+package serpent
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// vectors come from NESSIE (set 1, vector 0) and Nettle
+var vectors = []struct{ key, plain, cipher string }{
+	{"80000000000000000000000000000000", "00000000000000000000000000000000", "264e5481eff42a4606abda06c0bfda3d"},
+	{"00000000000000000000000000000000", "00000000000000000000000000000000", "3620b17ae6a993d09618b8768266bae9"},
+	{"000102030405060708090a0b0c0d0e0f", "ffffffffffffffffffffffffffffffff", "250b55cc7af75f4f2ebfad0bdbb59671"},
+	{"000000000000000000000000000000000000000000000000", "000102030405060708090a0b0c0d0e0f", "d3c42705ced3d1605977886c0de610a5"},
+	{"000102030405060708090a0b0c0d0e0f1011121314151617", "00000000000000000000000000000000", "105540d094b65ba952478eea5126eb7a"},
+	{"0000000000000000000000000000000000000000000000000000000000000000", "00000000000000000000000000000000", "49672ba898d98df95019180445491089"},
+	{"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "000102030405060708090a0b0c0d0e0f", "de269ff833e432b85b2e88d2701ce75c"},
+	{"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "ffffffffffffffffffffffffffffffff", "df7e7ed4b159087b13e72a3793041fbd"},
+}
+
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVectors(t *testing.T) {
+	for _, v := range vectors {
+		c, err := NewCipher(unhex(t, v.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, want := unhex(t, v.plain), unhex(t, v.cipher)
+		got := make([]byte, BlockSize)
+		c.Encrypt(got, plain)
+		if !bytes.Equal(got, want) {
+			t.Errorf("key %s: encrypt %s = %x, want %s", v.key, v.plain, got, v.cipher)
+		}
+		c.Decrypt(got, want)
+		if !bytes.Equal(got, plain) {
+			t.Errorf("key %s: decrypt %s = %x, want %s", v.key, v.cipher, got, v.plain)
+		}
+	}
+}
+
+// sboxes are the tables of the specification
+var sboxes = [8][16]uint32{
+	{3, 8, 15, 1, 10, 6, 5, 11, 14, 13, 4, 2, 7, 0, 9, 12},
+	{15, 12, 2, 7, 9, 0, 5, 10, 1, 11, 14, 8, 6, 13, 3, 4},
+	{8, 6, 7, 9, 3, 12, 10, 15, 13, 1, 14, 4, 0, 11, 5, 2},
+	{0, 15, 11, 8, 12, 9, 6, 3, 13, 1, 2, 4, 10, 7, 5, 14},
+	{1, 15, 8, 3, 12, 0, 11, 6, 2, 5, 4, 10, 9, 14, 7, 13},
+	{15, 5, 2, 11, 4, 10, 9, 12, 0, 3, 14, 8, 13, 6, 7, 1},
+	{7, 2, 12, 5, 8, 4, 6, 11, 14, 9, 1, 15, 13, 3, 10, 0},
+	{1, 13, 15, 0, 14, 8, 2, 11, 7, 4, 12, 10, 9, 3, 5, 6},
+}
+
+func TestSBoxes(t *testing.T) {
+	for s, table := range sboxes {
+		// column j of the state holds the input j%16
+		var x [4]uint32
+		for j := range 32 {
+			for b := range 4 {
+				x[b] |= uint32(j%16>>b&1) << j
+			}
+		}
+		in := x
+		sbox[s](&x)
+		for j := range 32 {
+			var y uint32
+			for b := range 4 {
+				y |= x[b] >> j & 1 << b
+			}
+			if y != table[j%16] {
+				t.Errorf("S%d(%d) = %d, want %d", s, j%16, y, table[j%16])
+			}
+		}
+		invSbox[s](&x)
+		if x != in {
+			t.Errorf("inverse S%d does not undo S%d", s, s)
+		}
+	}
+}
+
+func TestKeySize(t *testing.T) {
+	for _, n := range []int{0, 8, 15, 33} {
+		var kerr KeySizeError
+		if _, err := NewCipher(make([]byte, n)); !errors.As(err, &kerr) || int(kerr) != n {
+			t.Errorf("%d-byte key: %v", n, err)
+		}
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	c, _ := NewCipher(make([]byte, 32))
+	buf := make([]byte, BlockSize)
+	b.SetBytes(BlockSize)
+	for b.Loop() {
+		c.Encrypt(buf, buf)
+	}
+}
@@ -0,0 +1,177 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+)
+
+const keysDirName = "keys"
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Key is an ed25519 key known to the Keys screen. Private is nil for imported public keys.
+type Key struct {
+	Name    string
+	ID      [8]byte
+	Public  ed25519.PublicKey
+	Private ed25519.PrivateKey
+}
+
+// HasPrivate reports whether the key can sign
+func (k *Key) HasPrivate() bool { return k.Private != nil }
+
+// IDString formats the key ID the way minisign prints it
+func (k *Key) IDString() string { return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(k.ID[:])) }
+
+// secretFile is the on-disk form of an own keypair; the file is owner-only
+type secretFile struct {
+	Name string `json:"name"`
+	ID   string `json:"key_id"`
+	Seed string `json:"seed"`
+}
+
+// KeysDir returns the key store inside the config directory
+func KeysDir() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, keysDirName), nil
+}
+
+// Generate creates a new keypair named name and stores it in dir
+func Generate(dir, name string) (*Key, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid key name %q", name)
+	}
+	if _, err := os.Stat(filepath.Join(dir, name+".key")); err == nil {
+		return nil, fmt.Errorf("a key named %q already exists", name)
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	k := &Key{Name: name, Public: pub, Private: priv}
+	if _, err := rand.Read(k.ID[:]); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(secretFile{
+		Name: name,
+		ID:   base64.StdEncoding.EncodeToString(k.ID[:]),
+		Seed: base64.StdEncoding.EncodeToString(priv.Seed()),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".key"), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to store key: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".pub"), []byte(k.PublicKeyText()), 0644); err != nil {
+		return nil, fmt.Errorf("failed to store public key: %w", err)
+	}
+	return k, nil
+}
+
+// ImportPublic stores a minisign-format public key under name
+func ImportPublic(dir, name, text string) (*Key, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid key name %q", name)
+	}
+	k, err := ParsePublicKey(text)
+	if err != nil {
+		return nil, err
+	}
+	k.Name = name
+	if _, err := os.Stat(filepath.Join(dir, name+".pub")); err == nil {
+		return nil, fmt.Errorf("a key named %q already exists", name)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return k, os.WriteFile(filepath.Join(dir, name+".pub"), []byte(k.PublicKeyText()), 0644)
+}
+
+// List returns all keys in dir sorted by name; own keypairs include the private key
+func List(dir string) ([]*Key, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []*Key
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".pub")
+		if !ok || e.IsDir() {
+			continue
+		}
+		k, err := load(dir, name)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys, nil
+}
+
+// Delete removes the key name (public and, if present, private part) from dir
+func Delete(dir, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid key name %q", name)
+	}
+	if err := os.Remove(filepath.Join(dir, name+".key")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(filepath.Join(dir, name+".pub"))
+}
+
+func load(dir, name string) (*Key, error) {
+	pubText, err := os.ReadFile(filepath.Join(dir, name+".pub"))
+	if err != nil {
+		return nil, err
+	}
+	k, err := ParsePublicKey(string(pubText))
+	if err != nil {
+		return nil, err
+	}
+	k.Name = name
+
+	data, err := os.ReadFile(filepath.Join(dir, name+".key"))
+	if os.IsNotExist(err) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sf secretFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return nil, fmt.Errorf("invalid key file for %q: %w", name, err)
+	}
+	seed, err := base64.StdEncoding.DecodeString(sf.Seed)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid key file for %q", name)
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	if !priv.Public().(ed25519.PublicKey).Equal(k.Public) {
+		return nil, fmt.Errorf("private and public key for %q do not match", name)
+	}
+	k.Private = priv
+	return k, nil
+}
@@ -0,0 +1,160 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signatures use the minisign format so they can also be checked with
+// `minisign -V`. New signatures are prehashed (BLAKE2b-512, algorithm "ED");
+// legacy "Ed" signatures over the raw file are accepted when verifying.
+const (
+	algLegacy    = "Ed"
+	algPrehashed = "ED"
+
+	untrustedPrefix = "untrusted comment: "
+	trustedPrefix   = "trusted comment: "
+
+	// SignatureExtension is appended to the signed file's name
+	SignatureExtension = ".minisig"
+)
+
+// PublicKeyText returns the key in minisign public key format
+func (k *Key) PublicKeyText() string {
+	raw := make([]byte, 0, 2+8+ed25519.PublicKeySize)
+	raw = append(raw, algLegacy...)
+	raw = append(raw, k.ID[:]...)
+	raw = append(raw, k.Public...)
+	return fmt.Sprintf("%sminisign public key %s\n%s\n", untrustedPrefix, k.IDString(), base64.StdEncoding.EncodeToString(raw))
+}
+
+// ParsePublicKey reads a minisign public key (with or without the comment line)
+func ParsePublicKey(text string) (*Key, error) {
+	line := ""
+	for _, l := range strings.Split(strings.TrimSpace(text), "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, untrustedPrefix) {
+			line = l
+			break
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != algLegacy {
+		return nil, fmt.Errorf("not a minisign public key")
+	}
+	k := &Key{Public: ed25519.PublicKey(raw[10:])}
+	copy(k.ID[:], raw[2:10])
+	return k, nil
+}
+
+// SignFile writes a detached signature for path to path+SignatureExtension and returns its location
+func SignFile(path string, k *Key) (string, error) {
+	if !k.HasPrivate() {
+		return "", fmt.Errorf("key %q has no private part", k.Name)
+	}
+	digest, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	sig := ed25519.Sign(k.Private, digest)
+
+	sigRaw := make([]byte, 0, 2+8+ed25519.SignatureSize)
+	sigRaw = append(sigRaw, algPrehashed...)
+	sigRaw = append(sigRaw, k.ID[:]...)
+	sigRaw = append(sigRaw, sig...)
+
+	trusted := fmt.Sprintf("timestamp:%d\tfile:%s\tkey:%s", time.Now().Unix(), filepath.Base(path), k.Name)
+	global := ed25519.Sign(k.Private, append(append([]byte{}, sig...), trusted...))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%ssignature from HadesCrypt key %s\n", untrustedPrefix, k.IDString())
+	b.WriteString(base64.StdEncoding.EncodeToString(sigRaw) + "\n")
+	b.WriteString(trustedPrefix + trusted + "\n")
+	b.WriteString(base64.StdEncoding.EncodeToString(global) + "\n")
+
+	out := path + SignatureExtension
+	return out, os.WriteFile(out, []byte(b.String()), 0644)
+}
+
+// Verification is the result of a successful signature check
+type Verification struct {
+	Key            *Key
+	TrustedComment string
+}
+
+// VerifyFile checks the detached signature sigPath of path against the known keys
+func VerifyFile(path, sigPath string, keys []*Key) (*Verification, error) {
+	data, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], untrustedPrefix) || !strings.HasPrefix(lines[2], trustedPrefix) {
+		return nil, fmt.Errorf("not a minisign signature file")
+	}
+	sigRaw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sigRaw) != 2+8+ed25519.SignatureSize {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("malformed trusted comment signature")
+	}
+	alg, sig := string(sigRaw[:2]), sigRaw[10:]
+
+	var key *Key
+	for _, k := range keys {
+		if bytes.Equal(k.ID[:], sigRaw[2:10]) {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("signed by unknown key %016X; import the signer's public key first", binary.LittleEndian.Uint64(sigRaw[2:10]))
+	}
+
+	var msg []byte
+	switch alg {
+	case algPrehashed:
+		if msg, err = hashFile(path); err != nil {
+			return nil, err
+		}
+	case algLegacy:
+		if msg, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm %q", alg)
+	}
+	if !ed25519.Verify(key.Public, msg, sig) {
+		return nil, fmt.Errorf("signature verification failed: file was modified or signed by another key")
+	}
+	trusted := strings.TrimPrefix(lines[2], trustedPrefix)
+	if !ed25519.Verify(key.Public, append(append([]byte{}, sig...), trusted...), global) {
+		return nil, fmt.Errorf("trusted comment was tampered with")
+	}
+	return &Verification{Key: key, TrustedComment: trusted}, nil
+}
+
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,44 @@
+//go:build linux || darwin || freebsd
+
+package sparse
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func dataExtents(f *os.File, size int64) ([]Extent, error) {
+	defer f.Seek(0, io.SeekStart)
+	var ext []Extent
+	for off := int64(0); off < size; {
+		data, err := f.Seek(off, unix.SEEK_DATA)
+		if err != nil {
+			if errors.Is(err, syscall.ENXIO) {
+				break // only a hole remains
+			}
+			if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP) {
+				return nil, nil // file system cannot tell
+			}
+			return nil, err
+		}
+		hole, err := f.Seek(data, unix.SEEK_HOLE)
+		if err != nil {
+			return nil, err
+		}
+		if hole > size {
+			hole = size
+		}
+		ext = append(ext, Extent{data, hole - data})
+		off = hole
+	}
+	if ext == nil {
+		ext = []Extent{} // entirely a hole
+	}
+	return ext, nil
+}
+
+func markSparse(*os.File) {}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package sparse
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+func dataExtents(f *os.File, size int64) ([]Extent, error) {
+	in := Extent{0, size}
+	out := make([]Extent, 64)
+	var ext []Extent
+	for {
+		var returned uint32
+		err := windows.DeviceIoControl(windows.Handle(f.Fd()), windows.FSCTL_QUERY_ALLOCATED_RANGES,
+			(*byte)(unsafe.Pointer(&in)), uint32(unsafe.Sizeof(in)),
+			(*byte)(unsafe.Pointer(&out[0])), uint32(len(out))*uint32(unsafe.Sizeof(out[0])), &returned, nil)
+		if err != nil && err != windows.ERROR_MORE_DATA {
+			return nil, nil // not NTFS/ReFS: treat as dense
+		}
+		got := out[:returned/uint32(unsafe.Sizeof(out[0]))]
+		ext = append(ext, got...)
+		if err == nil || len(got) == 0 {
+			break
+		}
+		last := got[len(got)-1]
+		in = Extent{last.Offset + last.Length, size - (last.Offset + last.Length)}
+	}
+	if ext == nil {
+		ext = []Extent{}
+	}
+	return ext, nil
+}
+
+// markSparse lets NTFS keep unwritten ranges unallocated
+func markSparse(f *os.File) {
+	var returned uint32
+	windows.DeviceIoControl(windows.Handle(f.Fd()), windows.FSCTL_SET_SPARSE, nil, 0, nil, 0, &returned, nil)
+}
@@ -0,0 +1,100 @@
+// Package sparse finds the holes of sparse files (disk images, VM disks) and
+// writes files back with holes instead of runs of zeros, so archiving and
+// splitting such files neither inflates the output nor the restored copy.
+package sparse
+
+import (
+	"io"
+	"os"
+)
+
+// BlockSize is the granularity at which zero runs become holes
+const BlockSize = 4096
+
+// Extent is a run of data in a file; the gaps between extents are holes
+type Extent struct {
+	Offset int64
+	Length int64
+}
+
+// DataExtents returns the data runs of f, which is size bytes long. Platforms
+// or file systems that cannot report holes yield one extent covering the file.
+func DataExtents(f *os.File, size int64) ([]Extent, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	ext, err := dataExtents(f, size)
+	if err != nil || ext == nil {
+		return []Extent{{0, size}}, err
+	}
+	return ext, nil
+}
+
+// IsSparse reports whether extents leave holes in a file of the given size
+func IsSparse(extents []Extent, size int64) bool {
+	var data int64
+	for _, e := range extents {
+		data += e.Length
+	}
+	return data < size
+}
+
+// Writer writes to a file, turning block-aligned runs of zeros into holes
+type Writer struct {
+	f   *os.File
+	off int64 // logical end of what was written
+	pos int64 // file position
+}
+
+// NewWriter returns a Writer appending to f from offset 0
+func NewWriter(f *os.File) *Writer {
+	markSparse(f)
+	return &Writer{f: f}
+}
+
+var zeros = make([]byte, BlockSize)
+
+// Write implements io.Writer
+func (w *Writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// Pieces end on block boundaries so whole zero blocks can be recognized
+		n := BlockSize - int(w.off%BlockSize)
+		if n > len(p) {
+			n = len(p)
+		}
+		piece := p[:n]
+		if n == BlockSize && string(piece) == string(zeros) {
+			w.off += int64(n)
+		} else {
+			if w.pos != w.off {
+				if _, err := w.f.Seek(w.off, io.SeekStart); err != nil {
+					return written, err
+				}
+				w.pos = w.off
+			}
+			m, err := w.f.Write(piece)
+			w.off += int64(m)
+			w.pos += int64(m)
+			if err != nil {
+				return written + m, err
+			}
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Skip advances over a hole of n bytes without reading any zeros
+func (w *Writer) Skip(n int64) {
+	w.off += n
+}
+
+// Finish extends the file over a trailing hole; call it before closing
+func (w *Writer) Finish() error {
+	if w.pos == w.off {
+		return nil
+	}
+	return w.f.Truncate(w.off)
+}
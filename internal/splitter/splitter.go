@@ -5,6 +5,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/bangundwir/HadesCrypt/internal/sparse"
 )
 
 // SizeUnit represents different size units
@@ -73,7 +75,8 @@ func SplitFile(inputPath string, chunkSize int64, onProgress ProgressCallback) (
 			return nil, fmt.Errorf("create chunk file %s: %w", chunkPath, err)
 		}
 
-		// Copy data to chunk
+		// Copy data to chunk; zero runs (holes of a disk image) stay holes
+		chunk := sparse.NewWriter(chunkFile)
 		chunkWritten := int64(0)
 		for chunkWritten < chunkSize && processed < totalSize {
 			// Determine how much to read
@@ -89,7 +92,7 @@ func SplitFile(inputPath string, chunkSize int64, onProgress ProgressCallback) (
 			n, readErr := inputFile.Read(buffer[:toRead])
 			if n > 0 {
 				// Write to chunk
-				if _, writeErr := chunkFile.Write(buffer[:n]); writeErr != nil {
+				if _, writeErr := chunk.Write(buffer[:n]); writeErr != nil {
 					chunkFile.Close()
 					return nil, fmt.Errorf("write to chunk file: %w", writeErr)
 				}
@@ -111,6 +114,10 @@ func SplitFile(inputPath string, chunkSize int64, onProgress ProgressCallback) (
 			}
 		}
 
+		if err := chunk.Finish(); err != nil {
+			chunkFile.Close()
+			return nil, fmt.Errorf("write to chunk file: %w", err)
+		}
 		chunkFile.Close()
 		chunkIndex++
 	}
@@ -134,6 +141,7 @@ func CombineFiles(chunkPaths []string, outputPath string, onProgress ProgressCal
 		return fmt.Errorf("create output file: %w", err)
 	}
 	defer outputFile.Close()
+	output := sparse.NewWriter(outputFile)
 
 	buffer := make([]byte, 64*1024) // 64KB buffer
 	processed := int64(0)
@@ -149,7 +157,7 @@ func CombineFiles(chunkPaths []string, outputPath string, onProgress ProgressCal
 		for {
 			n, readErr := chunkFile.Read(buffer)
 			if n > 0 {
-				if _, writeErr := outputFile.Write(buffer[:n]); writeErr != nil {
+				if _, writeErr := output.Write(buffer[:n]); writeErr != nil {
 					chunkFile.Close()
 					return fmt.Errorf("write to output file: %w", writeErr)
 				}
@@ -173,6 +181,9 @@ func CombineFiles(chunkPaths []string, outputPath string, onProgress ProgressCal
 		chunkFile.Close()
 	}
 
+	if err := output.Finish(); err != nil {
+		return fmt.Errorf("write to output file: %w", err)
+	}
 	return nil
 }
 
@@ -233,7 +244,7 @@ func GetBasePathFromChunk(chunkPath string) string {
 	return chunkPath[:len(chunkPath)-4]
 }
 
-// DeleteChunks deletes all chunk files for a given base path
+// DeleteChunks deletes all chunk files for a given base path, and their manifest
 func DeleteChunks(basePath string) error {
 	chunks, err := FindChunks(basePath)
 	if err != nil {
@@ -245,6 +256,9 @@ func DeleteChunks(basePath string) error {
 			return fmt.Errorf("delete chunk %s: %w", chunkPath, err)
 		}
 	}
+	if err := os.Remove(ManifestPath(basePath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete manifest: %w", err)
+	}
 	
 	return nil
 }
@@ -0,0 +1,16 @@
+package splitter
+
+// SuggestPartSize returns a round part size no larger than limit bytes: whole
+// GiB from 16 GiB up, hundreds of MiB below (4000 MiB for FAT32's 4 GiB - 1),
+// and whole MiB for limits under 100 MiB
+func SuggestPartSize(limit int64) (int, SizeUnit) {
+	switch mib := limit >> 20; {
+	case limit >= 16<<30:
+		return int(limit >> 30), UnitGiB
+	case mib >= 100:
+		return int(mib / 100 * 100), UnitMiB
+	case mib >= 1:
+		return int(mib), UnitMiB
+	}
+	return int(max(limit>>10, 1)), UnitKiB
+}
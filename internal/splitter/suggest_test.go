@@ -0,0 +1,25 @@
+package splitter
+
+import "testing"
+
+func TestSuggestPartSize(t *testing.T) {
+	for _, tc := range []struct {
+		limit int64
+		size  int
+		unit  SizeUnit
+	}{
+		{4<<30 - 1, 4000, UnitMiB},
+		{2<<30 - 1, 2000, UnitMiB},
+		{250_000_000_000, 232, UnitGiB},
+		{50 << 20, 50, UnitMiB},
+		{5 << 10, 5, UnitKiB},
+	} {
+		size, unit := SuggestPartSize(tc.limit)
+		if size != tc.size || unit != tc.unit {
+			t.Errorf("SuggestPartSize(%d) = %d %s, want %d %s", tc.limit, size, unit, tc.size, tc.unit)
+		}
+		if ConvertToBytes(size, unit) > tc.limit {
+			t.Errorf("SuggestPartSize(%d) exceeds the limit", tc.limit)
+		}
+	}
+}
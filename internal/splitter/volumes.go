@@ -0,0 +1,186 @@
+package splitter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestExt is appended to the name of a split file to name its manifest
+const ManifestExt = ".manifest"
+
+// ErrVolumes means a volume set is incomplete or a volume differs from its manifest
+var ErrVolumes = errors.New("incomplete or damaged volume set")
+
+// Manifest lists the volumes a file was split into. It is written next to them
+// as <name>.manifest, so a missing or damaged volume is named before the set
+// is joined.
+type Manifest struct {
+	Name     string `json:"name"` // base name of the file that was split
+	Size     int64  `json:"size"`
+	PartSize int64  `json:"part_size"`
+	Parts    []Part `json:"parts"`
+}
+
+// Part is one volume in a Manifest
+type Part struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ManifestPath returns where the manifest of the volumes of base is written
+func ManifestPath(base string) string { return base + ManifestExt }
+
+// Split splits path into volumes of partSize bytes named path.000, path.001…,
+// writes their manifest and removes path. A file no larger than partSize is
+// left whole, and the result is then just path.
+func Split(path string, partSize int64, onProgress ProgressCallback) ([]string, error) {
+	if partSize <= 0 {
+		return nil, fmt.Errorf("part size %d", partSize)
+	}
+	// volumes of an earlier file of the same name would join onto the new ones
+	if err := DeleteChunks(path); err != nil {
+		return nil, err
+	}
+	parts, err := SplitFile(path, partSize, onProgress)
+	if err != nil || len(parts) == 1 && parts[0] == path {
+		return parts, err
+	}
+	m := Manifest{Name: filepath.Base(path), PartSize: partSize}
+	for _, p := range parts {
+		sum, n, err := hashFile(p)
+		if err != nil {
+			removeAll(parts)
+			return nil, err
+		}
+		m.Parts = append(m.Parts, Part{Name: filepath.Base(p), Size: n, SHA256: sum})
+		m.Size += n
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err == nil {
+		err = os.WriteFile(ManifestPath(path), append(data, '\n'), 0644)
+	}
+	if err != nil {
+		removeAll(parts)
+		return nil, fmt.Errorf("write manifest: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// Volumes returns the split file path belongs to and its volumes, when path is
+// one of them or was split away; otherwise parts is nil
+func Volumes(path string) (base string, parts []string) {
+	base = path
+	if IsChunkFile(path) {
+		base = GetBasePathFromChunk(path)
+	} else if _, err := os.Stat(path); err == nil {
+		return "", nil
+	}
+	parts, _ = FindChunks(base)
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return base, parts
+}
+
+// ReadManifest reads the manifest of the volumes of base
+func ReadManifest(base string) (*Manifest, error) {
+	data, err := os.ReadFile(ManifestPath(base))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%w: manifest: %v", ErrVolumes, err)
+	}
+	return &m, nil
+}
+
+// Join writes the volumes of base to output in order. When base has a
+// manifest, every volume must be there with the size and SHA-256 it lists;
+// without one the volumes are joined as they are.
+func Join(base string, parts []string, output string, onProgress ProgressCallback) error {
+	m, err := ReadManifest(base)
+	if errors.Is(err, os.ErrNotExist) {
+		m, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	var total int64
+	for i, p := range parts {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		if m != nil && i < len(m.Parts) && fi.Size() != m.Parts[i].Size {
+			return fmt.Errorf("%w: %s has %d bytes, the manifest lists %d", ErrVolumes, filepath.Base(p), fi.Size(), m.Parts[i].Size)
+		}
+		total += fi.Size()
+	}
+	if m != nil && len(parts) < len(m.Parts) {
+		return fmt.Errorf("%w: %s is missing (%d of %d volumes found)", ErrVolumes, m.Parts[len(parts)].Name, len(parts), len(m.Parts))
+	}
+	if m != nil && len(parts) > len(m.Parts) {
+		return fmt.Errorf("%w: %d volumes found, the manifest lists %d", ErrVolumes, len(parts), len(m.Parts))
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	defer out.Close()
+	var done int64
+	for i, p := range parts {
+		h := sha256.New()
+		n, err := copyFile(io.MultiWriter(out, h), p)
+		if err != nil {
+			return err
+		}
+		if m != nil && i < len(m.Parts) && !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), m.Parts[i].SHA256) {
+			return fmt.Errorf("%w: %s is damaged", ErrVolumes, filepath.Base(p))
+		}
+		done += n
+		if onProgress != nil {
+			onProgress(done, total)
+		}
+	}
+	return out.Close()
+}
+
+// hashFile returns the SHA-256 and size of the file at path
+func hashFile(path string) (string, int64, error) {
+	h := sha256.New()
+	n, err := copyFile(h, path)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// copyFile copies the file at path to w
+func copyFile(w io.Writer, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
+
+// removeAll removes the volumes of a split that failed
+func removeAll(parts []string) {
+	for _, p := range parts {
+		os.Remove(p)
+	}
+}
@@ -0,0 +1,88 @@
+package splitter
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// splitTest splits size random bytes into 1000-byte volumes and returns the
+// path of the split file and its contents
+func splitTest(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), "data.hadescrypt")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Split(path, 1000, nil); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func TestSplitJoin(t *testing.T) {
+	path, data := splitTest(t, 2500)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the split file is still there: %v", err)
+	}
+	m, err := ReadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "data.hadescrypt" || m.Size != 2500 || len(m.Parts) != 3 || m.Parts[2].Size != 500 {
+		t.Errorf("manifest %+v", m)
+	}
+
+	for _, p := range []string{path, path + ".001"} {
+		base, parts := Volumes(p)
+		if base != path || len(parts) != 3 {
+			t.Fatalf("Volumes(%s) = %s, %v", p, base, parts)
+		}
+	}
+	_, parts := Volumes(path)
+	out := filepath.Join(t.TempDir(), "joined")
+	var done int64
+	if err := Join(path, parts, out, func(n, total int64) { done = n }); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); !bytes.Equal(got, data) || done != 2500 {
+		t.Errorf("joined %d bytes, progress %d", len(got), done)
+	}
+
+	// a file no larger than a volume stays whole
+	small := filepath.Join(t.TempDir(), "small")
+	os.WriteFile(small, data[:1000], 0600)
+	if parts, err := Split(small, 1000, nil); err != nil || len(parts) != 1 || parts[0] != small {
+		t.Errorf("Split of one volume = %v, %v", parts, err)
+	}
+	if base, parts := Volumes(small); parts != nil {
+		t.Errorf("Volumes of a whole file = %s, %v", base, parts)
+	}
+}
+
+func TestJoinChecksManifest(t *testing.T) {
+	for name, damage := range map[string]func(path string){
+		"missing":   func(path string) { os.Remove(path + ".002") },
+		"extra":     func(path string) { os.WriteFile(path+".003", []byte("x"), 0600) },
+		"truncated": func(path string) { os.Truncate(path+".001", 999) },
+		"damaged": func(path string) {
+			b, _ := os.ReadFile(path + ".001")
+			b[10]++
+			os.WriteFile(path+".001", b, 0600)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path, _ := splitTest(t, 2500)
+			damage(path)
+			_, parts := Volumes(path)
+			if err := Join(path, parts, filepath.Join(t.TempDir(), "joined"), nil); !errors.Is(err, ErrVolumes) {
+				t.Errorf("got %v, want ErrVolumes", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+// Package sshrecipient encrypts files to SSH public keys (and native age
+// X25519 keys) and decrypts them with the matching private key, using the age
+// file format (age-encryption.org/v1) so outputs interoperate with the age and
+// rage tools.
+package sshrecipient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrNoMatchingKey means none of the file's recipients is one of the given private keys
+var ErrNoMatchingKey = errors.New("the file was not encrypted to this key")
+
+// Recipient is a parsed SSH public key
+type Recipient struct {
+	Type        string // "ssh-ed25519", "ssh-rsa" or "X25519"
+	Comment     string
+	Fingerprint string // SHA256:... as printed by ssh-keygen -l; the age1… key itself for X25519
+	recipient   age.Recipient
+	key         ssh.PublicKey
+}
+
+// Line returns the key in authorized_keys form
+func (r Recipient) Line() string {
+	line := r.Fingerprint
+	if r.key != nil {
+		line = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(r.key)))
+	}
+	if r.Comment != "" {
+		line += " " + r.Comment
+	}
+	return line
+}
+
+// String returns "type fingerprint comment" for display
+func (r Recipient) String() string {
+	return strings.TrimSpace(r.Type + " " + r.Fingerprint + " " + r.Comment)
+}
+
+// ParseRecipients reads authorized_keys style lines: one key per line, blank
+// lines and # comments ignored. Unsupported key types are an error.
+func ParseRecipients(text string) ([]Recipient, error) {
+	var recs []Recipient
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := ParseRecipient(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		recs = append(recs, r)
+	}
+	return recs, sc.Err()
+}
+
+// ParseRecipient parses one ssh-ed25519 or ssh-rsa public key line, or an
+// age1… X25519 key optionally followed by a comment
+func ParseRecipient(line string) (Recipient, error) {
+	if strings.HasPrefix(line, "age1") {
+		key, comment, _ := strings.Cut(strings.TrimSpace(line), " ")
+		x, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return Recipient{}, err
+		}
+		return Recipient{Type: "X25519", Comment: strings.TrimSpace(comment), Fingerprint: key, recipient: x}, nil
+	}
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return Recipient{}, fmt.Errorf("not an SSH public key: %w", err)
+	}
+	r := Recipient{Type: pub.Type(), Comment: comment, Fingerprint: ssh.FingerprintSHA256(pub), key: pub}
+	switch pub.Type() {
+	case ssh.KeyAlgoED25519:
+		r.recipient, err = agessh.NewEd25519Recipient(pub)
+	case ssh.KeyAlgoRSA:
+		r.recipient, err = agessh.NewRSARecipient(pub)
+	default:
+		return Recipient{}, fmt.Errorf("unsupported SSH key type %s (use ssh-ed25519 or ssh-rsa)", pub.Type())
+	}
+	if err != nil {
+		return Recipient{}, err
+	}
+	return r, nil
+}
+
+// githubUser matches valid GitHub user names
+var githubUser = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})$`)
+
+// FetchGitHubKeys downloads the public SSH keys of a GitHub user
+// (https://github.com/<user>.keys), keeping the supported types
+func FetchGitHubKeys(ctx context.Context, user string) ([]Recipient, error) {
+	user = strings.TrimPrefix(strings.TrimSpace(user), "@")
+	if !githubUser.MatchString(user) {
+		return nil, fmt.Errorf("invalid GitHub user name %q", user)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://github.com/"+user+".keys", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch keys of %s: %w", user, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch keys of %s: %s", user, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("fetch keys of %s: %w", user, err)
+	}
+	var recs []Recipient
+	for _, line := range strings.Split(string(body), "\n") {
+		if r, err := ParseRecipient(line); err == nil {
+			r.Comment = "github.com/" + user
+			recs = append(recs, r)
+		}
+	}
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("%s has no ssh-ed25519 or ssh-rsa keys on GitHub", user)
+	}
+	return recs, nil
+}
+
+// DefaultIdentityPath returns the first of ~/.ssh/id_ed25519 and ~/.ssh/id_rsa that exists
+func DefaultIdentityPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range []string{"id_ed25519", "id_rsa"} {
+		p := filepath.Join(home, ".ssh", name)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// LoadIdentity reads an SSH private key. Passphrase-protected keys call
+// passphrase only when a file actually needs the key.
+func LoadIdentity(path string, passphrase func() ([]byte, error)) (age.Identity, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	id, err := agessh.ParseIdentity(pemBytes)
+	if err == nil {
+		return id, nil
+	}
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return nil, fmt.Errorf("read SSH key %s: %w", path, err)
+	}
+	pub := missing.PublicKey
+	if pub == nil {
+		// older PEM keys carry no public key; use the .pub next to them
+		pubBytes, perr := os.ReadFile(path + ".pub")
+		if perr != nil {
+			return nil, fmt.Errorf("%s is encrypted and %s.pub is missing", path, path)
+		}
+		if pub, _, _, _, perr = ssh.ParseAuthorizedKey(pubBytes); perr != nil {
+			return nil, fmt.Errorf("read %s.pub: %w", path, perr)
+		}
+	}
+	return agessh.NewEncryptedSSHIdentity(pub, pemBytes, passphrase)
+}
+
+// Encrypt returns a writer that encrypts to every recipient into dst; Close finishes the age file
+func Encrypt(dst io.Writer, recipients []Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	ageRecipients := make([]age.Recipient, len(recipients))
+	for i, r := range recipients {
+		ageRecipients[i] = r.recipient
+	}
+	return age.Encrypt(dst, ageRecipients...)
+}
+
+// EncryptFile encrypts inputPath to every recipient, writing an age file to outputPath
+func EncryptFile(inputPath, outputPath string, recipients []Recipient, onProgress func(done, total int64)) error {
+	if len(recipients) == 0 {
+		return errors.New("no recipients")
+	}
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	st, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	return writeOutput(outputPath, func(out io.Writer) error {
+		w, err := Encrypt(out, recipients)
+		if err != nil {
+			return err
+		}
+		if err := copyProgress(w, in, st.Size(), onProgress); err != nil {
+			return err
+		}
+		return w.Close()
+	})
+}
+
+// DecryptFile decrypts the age file at inputPath into outputPath with the first matching identity
+func DecryptFile(inputPath, outputPath string, identities []age.Identity, onProgress func(done, total int64)) error {
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	st, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	r, err := age.Decrypt(in, identities...)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return ErrNoMatchingKey
+		}
+		return fmt.Errorf("decrypt %s: %w", filepath.Base(inputPath), err)
+	}
+	// ciphertext is slightly larger than the plaintext; progress tops out just short of total
+	return writeOutput(outputPath, func(out io.Writer) error {
+		return copyProgress(out, r, st.Size(), onProgress)
+	})
+}
+
+// writeOutput creates path, runs fill and removes the partial file on failure
+func writeOutput(path string, fill func(io.Writer) error) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := fill(out); err != nil {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+func copyProgress(dst io.Writer, src io.Reader, total int64, onProgress func(done, total int64)) error {
+	buf := make([]byte, 1<<20)
+	var done int64
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			done += int64(n)
+			if onProgress != nil {
+				onProgress(min(done, total), total)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
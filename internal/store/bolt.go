@@ -0,0 +1,305 @@
+//go:build !js
+
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+)
+
+var (
+	bucketHistory = []byte("history")
+	bucketJobs    = []byte("jobs")
+	bucketAudit   = []byte("audit")
+)
+
+// Store is a handle on the database; it holds no open file between calls
+type Store struct {
+	path string
+	aead cipher.AEAD
+}
+
+// Open prepares the database at path, creating it and its key on first use
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	key, err := loadKey(filepath.Join(filepath.Dir(path), keyName), path)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path, aead: aead}, nil
+}
+
+// loadKey reads the key file, or creates it when neither it nor the database exists
+func loadKey(keyPath, dbPath string) ([]byte, error) {
+	key, err := os.ReadFile(keyPath)
+	if err == nil {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("%w: %s is %d bytes", ErrKey, keyPath, len(key))
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if _, err := os.Stat(dbPath); err == nil {
+		return nil, fmt.Errorf("%w: %s is missing", ErrKey, keyPath)
+	}
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(key); err != nil {
+		f.Close()
+		os.Remove(keyPath)
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(keyPath)
+		return nil, err
+	}
+	return key, nil
+}
+
+// update runs fn in a write transaction on a freshly opened database
+func (s *Store) update(fn func(*bolt.Tx) error) error {
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return fmt.Errorf("open %s: %w", s.path, err)
+	}
+	if err := db.Update(fn); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+// view runs fn in a read transaction; a database that does not exist yet reads as empty
+func (s *Store) view(fn func(*bolt.Tx) error) error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("open %s: %w", s.path, err)
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+// seal encrypts v for the record at bucket/key, which is bound as additional data
+func (s *Store) seal(bucket, key []byte, v any) ([]byte, error) {
+	plain, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plain)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plain, recordAD(bucket, key)), nil
+}
+
+// open decrypts the record at bucket/key into v
+func (s *Store) open(bucket, key, sealed []byte, v any) error {
+	ns := s.aead.NonceSize()
+	if len(sealed) < ns {
+		return fmt.Errorf("store: %s record %x is truncated", bucket, key)
+	}
+	plain, err := s.aead.Open(nil, sealed[:ns], sealed[ns:], recordAD(bucket, key))
+	if err != nil {
+		return fmt.Errorf("store: %s record %x failed authentication", bucket, key)
+	}
+	return json.Unmarshal(plain, v)
+}
+
+func recordAD(bucket, key []byte) []byte {
+	return bytes.Join([][]byte{bucket, key}, []byte{0})
+}
+
+// appendTx adds v to bucket under the next sequence number and drops the oldest
+// records beyond MaxRecords
+func (s *Store) appendTx(tx *bolt.Tx, bucket []byte, v any) error {
+	b, err := tx.CreateBucketIfNotExists(bucket)
+	if err != nil {
+		return err
+	}
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := binary.BigEndian.AppendUint64(nil, seq)
+	sealed, err := s.seal(bucket, key, v)
+	if err != nil {
+		return err
+	}
+	if err := b.Put(key, sealed); err != nil {
+		return err
+	}
+	if seq <= MaxRecords {
+		return nil
+	}
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k) <= seq-MaxRecords; k, _ = c.First() {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendRecord adds v to bucket
+func (s *Store) appendRecord(bucket []byte, v any) error {
+	return s.update(func(tx *bolt.Tx) error { return s.appendTx(tx, bucket, v) })
+}
+
+// eachNewest calls fn with the records of bucket from newest to oldest until it returns false
+func (s *Store) eachNewest(bucket []byte, fn func(k, v []byte) (bool, error)) error {
+	return s.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			more, err := fn(k, v)
+			if err != nil || !more {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddHistory records one operation
+func (s *Store) AddHistory(e config.HistoryEntry) error {
+	return s.appendRecord(bucketHistory, e)
+}
+
+// History returns up to limit entries matching f, newest first; limit <= 0 returns all
+func (s *Store) History(f HistoryFilter, limit int) ([]config.HistoryEntry, error) {
+	var out []config.HistoryEntry
+	err := s.eachNewest(bucketHistory, func(k, v []byte) (bool, error) {
+		var e config.HistoryEntry
+		if err := s.open(bucketHistory, k, v, &e); err != nil {
+			return false, err
+		}
+		if f.Since != 0 && e.Timestamp < f.Since {
+			return false, nil // entries are appended in time order
+		}
+		if f.match(e) {
+			out = append(out, e)
+		}
+		return limit <= 0 || len(out) < limit, nil
+	})
+	return out, err
+}
+
+// ClearHistory removes every history entry
+func (s *Store) ClearHistory() error {
+	return s.update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketHistory) == nil {
+			return nil
+		}
+		return tx.DeleteBucket(bucketHistory)
+	})
+}
+
+// ImportHistory moves entries from config.json's legacy history array into the
+// store. Entries already present are skipped, so an import that was interrupted
+// before config.json was rewritten can safely run again. It returns how many
+// entries were added.
+func (s *Store) ImportHistory(entries []config.HistoryEntry) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	seen := map[config.HistoryEntry]bool{}
+	existing, err := s.History(HistoryFilter{}, 0)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range existing {
+		seen[e] = true
+	}
+	added := 0
+	err = s.update(func(tx *bolt.Tx) error {
+		for _, e := range entries {
+			if seen[e] {
+				continue
+			}
+			if err := s.appendTx(tx, bucketHistory, e); err != nil {
+				return err
+			}
+			seen[e] = true
+			added++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// AddJobRun records a finished job file run
+func (s *Store) AddJobRun(r JobRun) error {
+	return s.appendRecord(bucketJobs, r)
+}
+
+// JobRuns returns up to limit runs, newest first; limit <= 0 returns all
+func (s *Store) JobRuns(limit int) ([]JobRun, error) {
+	var out []JobRun
+	err := s.eachNewest(bucketJobs, func(k, v []byte) (bool, error) {
+		var r JobRun
+		if err := s.open(bucketJobs, k, v, &r); err != nil {
+			return false, err
+		}
+		out = append(out, r)
+		return limit <= 0 || len(out) < limit, nil
+	})
+	return out, err
+}
+
+// Audit records event with an optional detail at the current time
+func (s *Store) Audit(event, detail string) error {
+	return s.appendRecord(bucketAudit, AuditEntry{Timestamp: time.Now().Unix(), Event: event, Detail: detail})
+}
+
+// AuditLog returns up to limit audit entries, newest first; limit <= 0 returns all
+func (s *Store) AuditLog(limit int) ([]AuditEntry, error) {
+	var out []AuditEntry
+	err := s.eachNewest(bucketAudit, func(k, v []byte) (bool, error) {
+		var e AuditEntry
+		if err := s.open(bucketAudit, k, v, &e); err != nil {
+			return false, err
+		}
+		out = append(out, e)
+		return limit <= 0 || len(out) < limit, nil
+	})
+	return out, err
+}
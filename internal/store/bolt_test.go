@@ -0,0 +1,204 @@
+//go:build !js
+
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+)
+
+// openTemp opens a store in a new folder
+func openTemp(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), dbName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// entry returns history entry i, which names a distinct file
+func entry(i int) config.HistoryEntry {
+	return config.HistoryEntry{FileName: fmt.Sprintf("secret-%05d.txt", i), Operation: "encrypt", Size: int64(i), Timestamp: int64(1700000000 + i), Result: "success"}
+}
+
+// rawUpdate edits the database behind the store's back
+func rawUpdate(t *testing.T, s *Store, fn func(tx *bolt.Tx) error) {
+	t.Helper()
+	db, err := bolt.Open(s.path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Update(fn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func seq(n uint64) []byte { return binary.BigEndian.AppendUint64(nil, n) }
+
+func TestStoreRoundTrip(t *testing.T) {
+	s := openTemp(t)
+	if got, err := s.History(HistoryFilter{}, 0); err != nil || len(got) != 0 {
+		t.Fatalf("History before the first write: %v, %v", got, err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := s.AddHistory(entry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := JobRun{File: "nightly.yaml", Started: 1, Finished: 2, Succeeded: 3, Lines: []string{"[ok] docs"}}
+	if err := s.AddJobRun(run); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Audit("unlock", "failed attempt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a second handle reads the same records with the key it finds on disk
+	s, err := Open(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.History(HistoryFilter{}, 0); err != nil || !reflect.DeepEqual(got, []config.HistoryEntry{entry(3), entry(2), entry(1)}) {
+		t.Errorf("History: %+v, %v", got, err)
+	}
+	if got, err := s.History(HistoryFilter{Text: "SECRET-00002"}, 0); err != nil || !reflect.DeepEqual(got, []config.HistoryEntry{entry(2)}) {
+		t.Errorf("History filtered: %+v, %v", got, err)
+	}
+	if got, err := s.JobRuns(0); err != nil || !reflect.DeepEqual(got, []JobRun{run}) {
+		t.Errorf("JobRuns: %+v, %v", got, err)
+	}
+	if got, err := s.AuditLog(0); err != nil || len(got) != 1 || got[0].Event != "unlock" || got[0].Detail != "failed attempt" {
+		t.Errorf("AuditLog: %+v, %v", got, err)
+	}
+
+	// the database alone reveals no file names
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret-")) || bytes.Contains(data, []byte("nightly.yaml")) {
+		t.Error("the database holds plaintext")
+	}
+}
+
+func TestStoreTamperedRecord(t *testing.T) {
+	s := openTemp(t)
+	for i := 1; i <= 2; i++ {
+		if err := s.AddHistory(entry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rawUpdate(t, s, func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketHistory)
+		v := bytes.Clone(b.Get(seq(2)))
+		v[len(v)-1] ^= 1
+		return b.Put(seq(2), v)
+	})
+	if _, err := s.History(HistoryFilter{}, 0); err == nil || !strings.Contains(err.Error(), "failed authentication") {
+		t.Errorf("History of a tampered record: got %v, want an authentication error", err)
+	}
+
+	rawUpdate(t, s, func(tx *bolt.Tx) error { return tx.Bucket(bucketHistory).Put(seq(2), []byte{1, 2, 3}) })
+	if _, err := s.History(HistoryFilter{}, 0); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("History of a truncated record: got %v", err)
+	}
+}
+
+func TestStoreRecordMoved(t *testing.T) {
+	s := openTemp(t)
+	for i := 1; i <= 2; i++ {
+		if err := s.AddHistory(entry(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// swapping two intact records is caught, as each is bound to its key
+	rawUpdate(t, s, func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketHistory)
+		one, two := bytes.Clone(b.Get(seq(1))), bytes.Clone(b.Get(seq(2)))
+		if err := b.Put(seq(1), two); err != nil {
+			return err
+		}
+		return b.Put(seq(2), one)
+	})
+	if _, err := s.History(HistoryFilter{}, 0); err == nil || !strings.Contains(err.Error(), "failed authentication") {
+		t.Errorf("History of swapped records: got %v, want an authentication error", err)
+	}
+
+	// and to its bucket
+	s = openTemp(t)
+	if err := s.AddHistory(entry(1)); err != nil {
+		t.Fatal(err)
+	}
+	rawUpdate(t, s, func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket(bucketAudit)
+		if err != nil {
+			return err
+		}
+		return b.Put(seq(1), tx.Bucket(bucketHistory).Get(seq(1)))
+	})
+	if _, err := s.AuditLog(0); err == nil || !strings.Contains(err.Error(), "failed authentication") {
+		t.Errorf("AuditLog of a record moved from history: got %v, want an authentication error", err)
+	}
+}
+
+func TestStoreKeyFile(t *testing.T) {
+	s := openTemp(t)
+	if err := s.AddHistory(entry(1)); err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(filepath.Dir(s.path), keyName)
+
+	// a new key would leave the existing records unreadable, so none is made
+	if err := os.Remove(keyPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(s.path); !errors.Is(err, ErrKey) {
+		t.Errorf("Open without the key: got %v, want ErrKey", err)
+	}
+	if _, err := os.Stat(keyPath); !os.IsNotExist(err) {
+		t.Errorf("Open created a key for an existing database: %v", err)
+	}
+
+	if err := os.WriteFile(keyPath, make([]byte, keySize-1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(s.path); !errors.Is(err, ErrKey) {
+		t.Errorf("Open with a short key: got %v, want ErrKey", err)
+	}
+}
+
+func TestStoreTrimsToMaxRecords(t *testing.T) {
+	s := openTemp(t)
+	const extra = 5
+	entries := make([]config.HistoryEntry, MaxRecords+extra)
+	for i := range entries {
+		entries[i] = entry(i + 1)
+	}
+	if n, err := s.ImportHistory(entries); err != nil || n != len(entries) {
+		t.Fatalf("ImportHistory: %d, %v", n, err)
+	}
+	if err := s.AddHistory(entry(len(entries) + 1)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.History(HistoryFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the oldest are dropped, the newest kept
+	if len(got) != MaxRecords || got[0] != entry(MaxRecords+extra+1) || got[len(got)-1] != entry(extra+2) {
+		t.Errorf("History: %d entries, newest %s, oldest %s", len(got), got[0].FileName, got[len(got)-1].FileName)
+	}
+}
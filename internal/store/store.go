@@ -0,0 +1,90 @@
+// Package store keeps the operation history, the job journal and the audit log
+// in a bbolt database next to config.json, so config.json stays small and the
+// History view can page through entries without loading them all. Every record
+// is sealed with AES-256-GCM under a random per-install key kept in store.key
+// (mode 0600); a copy of the database alone reveals no file names.
+//
+// The database is opened only for the duration of each call, so the GUI and
+// the CLI can both use it.
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+)
+
+const (
+	dbName  = "records.db"
+	keyName = "store.key"
+	keySize = 32
+
+	// MaxRecords is how many entries each log keeps; older ones are dropped
+	MaxRecords = 10000
+)
+
+// ErrKey is returned when store.key is missing for an existing database or has the wrong size
+var ErrKey = errors.New("store: unusable key file")
+
+// JobRun is one run of a job file by the CLI runner
+type JobRun struct {
+	File      string   `json:"file"`
+	Started   int64    `json:"started"`  // Unix timestamp
+	Finished  int64    `json:"finished"` // Unix timestamp
+	Succeeded int      `json:"succeeded"`
+	Failed    int      `json:"failed"`
+	Skipped   int      `json:"skipped"`
+	Lines     []string `json:"lines,omitempty"` // one "[status] name: error" line per job
+}
+
+// AuditEntry records a security-relevant event, e.g. an unlock attempt
+type AuditEntry struct {
+	Timestamp int64  `json:"timestamp"` // Unix timestamp
+	Event     string `json:"event"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// HistoryFilter selects history entries; empty fields match everything
+type HistoryFilter struct {
+	Text      string // case-insensitive substring of the file name or error
+	Operation string // exact operation, e.g. "decrypt"
+	Result    string // "success" or "error"
+	Since     int64  // Unix timestamp; 0 means no lower bound
+}
+
+func (f HistoryFilter) match(e config.HistoryEntry) bool {
+	if f.Operation != "" && e.Operation != f.Operation {
+		return false
+	}
+	if f.Result != "" && e.Result != f.Result {
+		return false
+	}
+	if f.Since != 0 && e.Timestamp < f.Since {
+		return false
+	}
+	if f.Text != "" {
+		t := strings.ToLower(f.Text)
+		return strings.Contains(strings.ToLower(e.FileName), t) || strings.Contains(strings.ToLower(e.Error), t)
+	}
+	return true
+}
+
+// DefaultPath returns the location of the database inside the config directory
+func DefaultPath() (string, error) {
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, dbName), nil
+}
+
+// OpenDefault opens the database at DefaultPath
+func OpenDefault() (*Store, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return Open(path)
+}
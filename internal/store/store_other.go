@@ -0,0 +1,36 @@
+//go:build js
+
+package store
+
+import (
+	"errors"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+)
+
+// ErrUnsupported is returned by Open where bbolt is not available
+var ErrUnsupported = errors.New("store: not supported on this platform")
+
+// Store is never created on this platform
+type Store struct{}
+
+// Open always fails on this platform
+func Open(path string) (*Store, error) { return nil, ErrUnsupported }
+
+func (s *Store) AddHistory(config.HistoryEntry) error { return ErrUnsupported }
+
+func (s *Store) History(HistoryFilter, int) ([]config.HistoryEntry, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Store) ClearHistory() error { return ErrUnsupported }
+
+func (s *Store) ImportHistory([]config.HistoryEntry) (int, error) { return 0, ErrUnsupported }
+
+func (s *Store) AddJobRun(JobRun) error { return ErrUnsupported }
+
+func (s *Store) JobRuns(int) ([]JobRun, error) { return nil, ErrUnsupported }
+
+func (s *Store) Audit(event, detail string) error { return ErrUnsupported }
+
+func (s *Store) AuditLog(int) ([]AuditEntry, error) { return nil, ErrUnsupported }
@@ -0,0 +1,460 @@
+// Package syncfolder keeps a plaintext folder and an encrypted mirror (for
+// example a folder inside a cloud drive) in step in both directions. Every
+// file is stored in the mirror as <relative path>.hadescrypt, as recursive mode
+// writes it. Replaced and deleted mirror files move to <mirror>/.versions;
+// a file edited on both sides keeps both edits, the mirror's as a conflict copy.
+package syncfolder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+)
+
+// VersionsDir is the folder inside the mirror holding earlier versions
+const VersionsDir = ".versions"
+
+const (
+	tempPrefix   = ".hcsync-"
+	settleTime   = 2 * time.Second // files modified more recently are picked up next pass
+	keepVersions = 10
+)
+
+// Result counts what one sync pass did
+type Result struct {
+	Uploaded, Downloaded        int
+	DeletedLocal, DeletedRemote int
+	Conflicts                   int
+	Errors                      []error
+}
+
+// Changed reports whether the pass touched anything
+func (r Result) Changed() bool {
+	return r.Uploaded+r.Downloaded+r.DeletedLocal+r.DeletedRemote+r.Conflicts > 0
+}
+
+// String summarizes r for a status line
+func (r Result) String() string {
+	s := fmt.Sprintf("↑%d ↓%d", r.Uploaded, r.Downloaded)
+	if n := r.DeletedLocal + r.DeletedRemote; n > 0 {
+		s += fmt.Sprintf(" ✕%d", n)
+	}
+	if r.Conflicts > 0 {
+		s += fmt.Sprintf(" ⚠️%d conflict(s)", r.Conflicts)
+	}
+	if len(r.Errors) > 0 {
+		s += fmt.Sprintf(" ❌%d error(s)", len(r.Errors))
+	}
+	return s
+}
+
+// stamp identifies one state of a file
+type stamp struct {
+	Mod  int64 `json:"mod"` // UnixNano
+	Size int64 `json:"size"`
+}
+
+func stampOf(fi os.FileInfo) stamp { return stamp{Mod: fi.ModTime().UnixNano(), Size: fi.Size()} }
+
+// entry is the last synced state of a path on both sides
+type entry struct {
+	Local  stamp `json:"local"`
+	Remote stamp `json:"remote"`
+}
+
+// Pair syncs Local with the encrypted mirror Remote
+type Pair struct {
+	Local, Remote string
+
+	password  []byte
+	opts      cryptoengine.EncryptionOptions
+	statePath string
+
+	mu    sync.Mutex
+	state map[string]entry
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// New prepares a pair; the sync state lives in the config directory, keyed by both folders
+func New(local, remote string, password []byte, opts cryptoengine.EncryptionOptions) (*Pair, error) {
+	local, err := filepath.Abs(local)
+	if err != nil {
+		return nil, err
+	}
+	remote, err = filepath.Abs(remote)
+	if err != nil {
+		return nil, err
+	}
+	if local == remote || strings.HasPrefix(remote, local+string(filepath.Separator)) || strings.HasPrefix(local, remote+string(filepath.Separator)) {
+		return nil, errors.New("the plaintext folder and the encrypted mirror must not contain each other")
+	}
+	for _, dir := range []string{local, remote} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+	}
+	dir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	id := sha256.Sum256([]byte(local + "\x00" + remote))
+	p := &Pair{
+		Local:     local,
+		Remote:    remote,
+		password:  append([]byte(nil), password...),
+		opts:      opts,
+		statePath: filepath.Join(dir, "sync", hex.EncodeToString(id[:8])+".json"),
+		state:     map[string]entry{},
+	}
+	if data, err := os.ReadFile(p.statePath); err == nil {
+		if err := json.Unmarshal(data, &p.state); err != nil {
+			return nil, fmt.Errorf("invalid sync state %s: %w", p.statePath, err)
+		}
+	}
+	return p, nil
+}
+
+// Watch runs a sync pass every interval until Close; onSync sees every pass that changed
+// something or failed
+func (p *Pair) Watch(interval time.Duration, onSync func(Result)) {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if r := p.Sync(); (r.Changed() || len(r.Errors) > 0) && onSync != nil {
+				onSync(r)
+			}
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops watching and forgets the password
+func (p *Pair) Close() {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+		p.stop = nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.password {
+		p.password[i] = 0
+	}
+}
+
+// Sync runs one two-way pass
+func (p *Pair) Sync() Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var r Result
+	local, err := p.scanLocal()
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Errorf("scan %s: %w", p.Local, err))
+		return r
+	}
+	remote, err := p.scanRemote()
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Errorf("scan %s: %w", p.Remote, err))
+		return r
+	}
+
+	paths := map[string]bool{}
+	for rel := range local {
+		paths[rel] = true
+	}
+	for rel := range remote {
+		paths[rel] = true
+	}
+	for rel := range p.state {
+		paths[rel] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for rel := range paths {
+		sorted = append(sorted, rel)
+	}
+	sort.Strings(sorted)
+
+	now := time.Now()
+	for _, rel := range sorted {
+		l, hasL := local[rel]
+		rm, hasR := remote[rel]
+		if known, ok := p.state[rel]; hasL && (!ok || l != known.Local) && now.Sub(time.Unix(0, l.Mod)) < settleTime {
+			continue // a local edit still being written
+		}
+		if err := p.reconcile(rel, l, hasL, rm, hasR, &r); err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("%s: %w", rel, err))
+		}
+	}
+	if err := p.saveState(); err != nil {
+		r.Errors = append(r.Errors, err)
+	}
+	return r
+}
+
+// reconcile brings one path in line given its current local and remote stamps
+func (p *Pair) reconcile(rel string, l stamp, hasL bool, rm stamp, hasR bool, r *Result) error {
+	known, synced := p.state[rel]
+	localChanged := hasL && (!synced || l != known.Local)
+	remoteChanged := hasR && (!synced || rm != known.Remote)
+
+	switch {
+	case !hasL && !hasR:
+		delete(p.state, rel)
+	case hasL && !hasR:
+		if synced && !localChanged {
+			// deleted from the mirror elsewhere
+			r.DeletedLocal++
+			delete(p.state, rel)
+			return os.Remove(p.localPath(rel))
+		}
+		r.Uploaded++
+		return p.upload(rel)
+	case !hasL && hasR:
+		if synced && !remoteChanged {
+			r.DeletedRemote++
+			delete(p.state, rel)
+			return p.retire(rel)
+		}
+		r.Downloaded++
+		return p.download(rel, p.localPath(rel))
+	case localChanged && remoteChanged:
+		if !synced {
+			same, err := p.sameContent(rel)
+			if err != nil {
+				return err
+			}
+			if same {
+				p.state[rel] = entry{Local: l, Remote: rm}
+				return nil
+			}
+		}
+		// keep the mirror's edit next to ours, then publish ours
+		r.Conflicts++
+		if err := p.download(rel, conflictPath(p.localPath(rel))); err != nil {
+			return err
+		}
+		return p.upload(rel)
+	case localChanged:
+		r.Uploaded++
+		return p.upload(rel)
+	case remoteChanged:
+		r.Downloaded++
+		return p.download(rel, p.localPath(rel))
+	}
+	return nil
+}
+
+func (p *Pair) localPath(rel string) string { return filepath.Join(p.Local, filepath.FromSlash(rel)) }
+
+func (p *Pair) remotePath(rel string) string {
+	return format.OutputPathFor(filepath.Join(p.Remote, filepath.FromSlash(rel)), format.DefaultExtension)
+}
+
+// upload encrypts the local file over the mirror copy, keeping the old copy as a version
+func (p *Pair) upload(rel string) error {
+	src, dst := p.localPath(rel), p.remotePath(rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	// ciphertext needs a unique name only; the plaintext of download gets a private folder
+	f, err := os.CreateTemp(filepath.Dir(dst), tempPrefix+filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	f.Close()
+	defer os.Remove(tmp)
+	if err := cryptoengine.EncryptFileWithOptions(src, tmp, p.password, p.opts, nil); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		if err := p.retire(rel); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	return p.record(rel)
+}
+
+// download decrypts the mirror copy into dest via a temporary file
+func (p *Pair) download(rel, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	tmp, err := securetemp.TempPath(filepath.Dir(dest), tempPrefix+filepath.Base(dest)+".*")
+	if err != nil {
+		return err
+	}
+	defer securetemp.Remove(tmp)
+	if err := cryptoengine.DecryptFile(p.remotePath(rel), tmp, p.password, false, nil); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return err
+	}
+	if dest != p.localPath(rel) {
+		return nil // conflict copy; the next pass uploads it as a new file
+	}
+	return p.record(rel)
+}
+
+// record stores the current stamps of rel as synced
+func (p *Pair) record(rel string) error {
+	lfi, err := os.Stat(p.localPath(rel))
+	if err != nil {
+		return err
+	}
+	rfi, err := os.Stat(p.remotePath(rel))
+	if err != nil {
+		return err
+	}
+	p.state[rel] = entry{Local: stampOf(lfi), Remote: stampOf(rfi)}
+	return nil
+}
+
+// retire moves the mirror copy of rel into the versions folder and prunes old versions
+func (p *Pair) retire(rel string) error {
+	src := p.remotePath(rel)
+	base := filepath.Join(p.Remote, VersionsDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(base), 0700); err != nil {
+		return err
+	}
+	dst := format.OutputPathFor(base+"."+time.Now().UTC().Format("20060102-150405.000"), format.DefaultExtension)
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+	old, _ := filepath.Glob(globEscape(base) + ".*" + format.DefaultExtension)
+	sort.Strings(old) // timestamps sort chronologically
+	for len(old) > keepVersions {
+		os.Remove(old[0])
+		old = old[1:]
+	}
+	return nil
+}
+
+// sameContent reports whether the mirror copy decrypts to the local file's content
+func (p *Pair) sameContent(rel string) (bool, error) {
+	local, err := os.Open(p.localPath(rel))
+	if err != nil {
+		return false, err
+	}
+	defer local.Close()
+	want := sha256.New()
+	if _, err := io.Copy(want, local); err != nil {
+		return false, err
+	}
+	got := sha256.New()
+	if err := cryptoengine.DecryptFileToWriter(p.remotePath(rel), got, p.password, nil); err != nil {
+		return false, err
+	}
+	return string(got.Sum(nil)) == string(want.Sum(nil)), nil
+}
+
+func (p *Pair) saveState() error {
+	data, err := json.Marshal(p.state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.statePath), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p.statePath), "."+filepath.Base(p.statePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p.statePath)
+}
+
+// scanLocal lists the plaintext files by slash-separated relative path
+func (p *Pair) scanLocal() (map[string]stamp, error) {
+	files, _, err := fswalk.Scan(p.Local, fswalk.ScanOptions{})
+	if err != nil && len(files) == 0 {
+		return nil, err
+	}
+	out := map[string]stamp{}
+	for _, f := range files {
+		if strings.HasPrefix(filepath.Base(f.Path), tempPrefix) || !f.Info.Mode().IsRegular() {
+			continue
+		}
+		rel, err := filepath.Rel(p.Local, f.Path)
+		if err != nil {
+			continue
+		}
+		out[filepath.ToSlash(rel)] = stampOf(f.Info)
+	}
+	return out, nil
+}
+
+// scanRemote lists the mirror's encrypted files by the relative path of their plaintext
+func (p *Pair) scanRemote() (map[string]stamp, error) {
+	files, _, err := fswalk.Scan(p.Remote, fswalk.ScanOptions{})
+	if err != nil && len(files) == 0 {
+		return nil, err
+	}
+	out := map[string]stamp{}
+	for _, f := range files {
+		rel, err := filepath.Rel(p.Remote, f.Path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == VersionsDir || strings.HasPrefix(rel, VersionsDir+"/") || strings.HasPrefix(filepath.Base(rel), tempPrefix) {
+			continue
+		}
+		plain, ok := strings.CutSuffix(rel, format.DefaultExtension)
+		if !ok || !f.Info.Mode().IsRegular() {
+			continue
+		}
+		out[plain] = stampOf(f.Info)
+	}
+	return out, nil
+}
+
+// conflictPath names the conflict copy of path: "report (conflict 2025-01-02 150405).txt"
+func conflictPath(path string) string {
+	ext := filepath.Ext(path)
+	return format.FreePath(fmt.Sprintf("%s (conflict %s)%s", strings.TrimSuffix(path, ext), time.Now().Format("2006-01-02 150405"), ext))
+}
+
+// globEscape quotes the glob metacharacters in a literal path
+func globEscape(path string) string {
+	r := strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`)
+	if filepath.Separator == '\\' {
+		r = strings.NewReplacer(`*`, `[*]`, `?`, `[?]`, `[`, `[[]`)
+	}
+	return r.Replace(path)
+}
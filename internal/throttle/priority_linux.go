@@ -0,0 +1,32 @@
+//go:build linux
+
+package throttle
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioprioClassBE    = 2
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// LowerPriority renices the calling thread to 10 and moves it to the lowest
+// best-effort IO priority. The goroutine stays locked to the thread, so the
+// thread is discarded when the goroutine exits instead of being reused at low
+// priority; call it only from a goroutine dedicated to the job.
+func LowerPriority() error {
+	runtime.LockOSThread()
+	tid := unix.Gettid()
+	if err := unix.Setpriority(unix.PRIO_PROCESS, tid, 10); err != nil {
+		return err
+	}
+	_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassBE<<ioprioClassShift|7)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !windows
+
+package throttle
+
+import "errors"
+
+// LowerPriority is not supported here: other systems only offer process-wide
+// niceness, which would slow the user interface as well. Rate limiting still applies.
+func LowerPriority() error {
+	return errors.New("thread priority control is not supported on this platform")
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package throttle
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/windows"
+)
+
+const threadModeBackgroundBegin = 0x00010000
+
+var procSetThreadPriority = windows.NewLazySystemDLL("kernel32.dll").NewProc("SetThreadPriority")
+
+// LowerPriority puts the calling thread in background mode, which lowers its
+// CPU, IO and memory priority. The goroutine stays locked to the thread, so the
+// thread is discarded when the goroutine exits; call it only from a goroutine
+// dedicated to the job.
+func LowerPriority() error {
+	runtime.LockOSThread()
+	h, err := windows.GetCurrentThread()
+	if err != nil {
+		return err
+	}
+	if r, _, err := procSetThreadPriority.Call(uintptr(h), threadModeBackgroundBegin); r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Package throttle keeps long jobs from saturating the machine: a byte-rate
+// limiter driven by progress callbacks, and per-thread CPU/IO priority lowering.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter paces work to a byte rate using a token bucket with one second of burst
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter for bytesPerSec, or nil (no limit) when it is not positive
+func NewLimiter(bytesPerSec int64) *Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &Limiter{rate: float64(bytesPerSec), tokens: float64(bytesPerSec), last: time.Now()}
+}
+
+// MBps converts a megabytes-per-second setting to bytes per second
+func MBps(mb float64) int64 {
+	if mb <= 0 {
+		return 0
+	}
+	return int64(mb * 1024 * 1024)
+}
+
+// Wait blocks until n more bytes may be processed. A nil limiter never blocks.
+func (l *Limiter) Wait(n int64) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	var sleep time.Duration
+	if l.tokens < 0 {
+		sleep = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if sleep > 0 {
+		time.Sleep(sleep)
+	}
+}
+
+// Progress wraps a progress callback so that each reported advance is paced by l.
+// Because engine and archiver loops report after every chunk, sleeping here
+// throttles both their reads and writes. A nil limiter returns cb unchanged.
+func Progress(l *Limiter, cb func(done, total int64)) func(done, total int64) {
+	if l == nil {
+		return cb
+	}
+	var mu sync.Mutex
+	var last int64
+	return func(done, total int64) {
+		mu.Lock()
+		delta := done - last
+		if delta < 0 { // a new operation started reporting from zero
+			delta = done
+		}
+		last = done
+		mu.Unlock()
+		l.Wait(delta)
+		if cb != nil {
+			cb(done, total)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package timefmt turns stored Unix timestamps into labels for people: a relative
+// one ("2 hours ago") for lists and an absolute one in the order and clock the
+// user's locale expects.
+package timefmt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Layouts for Absolute, by the date order a locale writes
+const (
+	layoutISO = "2006-01-02 15:04:05"
+	layoutUS  = "01/02/2006 3:04:05 PM"
+	layoutDMY = "02/01/2006 15:04:05"
+	layoutDot = "02.01.2006 15:04:05"
+	layoutNL  = "02-01-2006 15:04:05"
+)
+
+// usRegions write month first with a 12-hour clock
+var usRegions = map[string]bool{"US": true, "PH": true, "PR": true}
+
+// languageLayouts maps a language to its layout; unlisted languages use ISO 8601
+var languageLayouts = map[string]string{
+	"de": layoutDot, "ru": layoutDot, "pl": layoutDot, "cs": layoutDot, "sk": layoutDot, "fi": layoutDot,
+	"nb": layoutDot, "da": layoutDot, "tr": layoutDot, "uk": layoutDot, "ro": layoutDot,
+	"en": layoutDMY, "fr": layoutDMY, "es": layoutDMY, "it": layoutDMY, "pt": layoutDMY, "el": layoutDMY,
+	"id": layoutDMY, "ms": layoutDMY, "vi": layoutDMY,
+	"nl": layoutNL,
+}
+
+// Layout returns the time.Format layout for a BCP 47 locale such as "en-US" or "de_DE"
+func Layout(locale string) string {
+	lang, region, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	region, _, _ = strings.Cut(region, "-")
+	if usRegions[strings.ToUpper(region)] {
+		return layoutUS
+	}
+	if l, ok := languageLayouts[strings.ToLower(lang)]; ok {
+		return l
+	}
+	return layoutISO
+}
+
+// Absolute formats t in local time for locale
+func Absolute(t time.Time, locale string) string {
+	return t.Local().Format(Layout(locale))
+}
+
+// Relative describes t as seen at now, e.g. "just now", "5 minutes ago" or "yesterday".
+// Times in the future, as after a clock change, count as just now.
+func Relative(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return ago(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return ago(int(d/time.Hour), "hour")
+	case d < 48*time.Hour:
+		return "yesterday"
+	}
+	days := int(d / (24 * time.Hour))
+	switch {
+	case days < 7:
+		return ago(days, "day")
+	case days < 30:
+		return ago(days/7, "week")
+	case days < 365:
+		return ago(days/30, "month")
+	}
+	return ago(days/365, "year")
+}
+
+func ago(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit + " ago"
+	}
+	return fmt.Sprintf("%d %ss ago", n, unit)
+}
@@ -0,0 +1,45 @@
+package timefmt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRelative(t *testing.T) {
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		ago  time.Duration
+		want string
+	}{
+		{-time.Hour, "just now"},
+		{30 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{59 * time.Minute, "59 minutes ago"},
+		{2 * time.Hour, "2 hours ago"},
+		{30 * time.Hour, "yesterday"},
+		{3 * 24 * time.Hour, "3 days ago"},
+		{15 * 24 * time.Hour, "2 weeks ago"},
+		{90 * 24 * time.Hour, "3 months ago"},
+		{800 * 24 * time.Hour, "2 years ago"},
+	} {
+		if got := Relative(now.Add(-tc.ago), now); got != tc.want {
+			t.Errorf("Relative(-%v) = %q, want %q", tc.ago, got, tc.want)
+		}
+	}
+}
+
+func TestAbsolute(t *testing.T) {
+	at := time.Date(2026, 3, 14, 15, 4, 5, 0, time.Local)
+	for _, tc := range []struct{ locale, want string }{
+		{"en-US", "03/14/2026 3:04:05 PM"},
+		{"en-GB", "14/03/2026 15:04:05"},
+		{"de_DE", "14.03.2026 15:04:05"},
+		{"nl-NL", "14-03-2026 15:04:05"},
+		{"ja-JP", "2026-03-14 15:04:05"},
+		{"", "2026-03-14 15:04:05"},
+	} {
+		if got := Absolute(at, tc.locale); got != tc.want {
+			t.Errorf("Absolute(%q) = %q, want %q", tc.locale, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package timestamp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+)
+
+// TokenExtension is appended to the timestamped file's name. The file holds the
+// DER TimeStampResp, so it can also be checked with `openssl ts -verify -in`.
+const TokenExtension = ".tsr"
+
+// DefaultURL is the TSA used when none is configured
+const DefaultURL = "https://freetsa.org/tsr"
+
+var (
+	oidSHA256     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+)
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// RFC 3161 structures (only the parts needed here)
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int
+	CertReq        bool
+}
+
+type pkiStatusInfo struct {
+	Status int
+}
+
+type timeStampResp struct {
+	Status pkiStatusInfo
+	Token  asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapContentInfo
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       accuracy  `asn1:"optional"`
+	Ordering       bool      `asn1:"optional"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
+
+// Token is the parsed content of a timestamp token
+type Token struct {
+	Time   time.Time
+	Serial *big.Int
+	Policy string
+}
+
+// Stamp requests a timestamp for the SHA-256 of path from the TSA at url and
+// stores the response next to the file
+func Stamp(path, url string) (*Token, error) {
+	if url == "" {
+		url = DefaultURL
+	}
+	digest, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	req, err := asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: messageImprint{HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}, HashedMessage: digest},
+		Nonce:          nonce,
+		CertReq:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Post(url, "application/timestamp-query", bytes.NewReader(req))
+	if err != nil {
+		return nil, fmt.Errorf("TSA request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA returned %s", resp.Status)
+	}
+	der, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := parseResponse(der)
+	if err != nil {
+		return nil, err
+	}
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, fmt.Errorf("TSA response nonce does not match the request")
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, fmt.Errorf("TSA response is for a different hash")
+	}
+	if err := os.WriteFile(path+TokenExtension, der, 0644); err != nil {
+		return nil, err
+	}
+	return info.token(), nil
+}
+
+// Verify checks that the stored token for path covers the file's current content.
+// The TSA's CMS signature is not validated here; use `openssl ts -verify` with the
+// TSA certificate for that.
+func Verify(path string) (*Token, error) {
+	der, err := os.ReadFile(path + TokenExtension)
+	if err != nil {
+		return nil, err
+	}
+	info, err := parseResponse(der)
+	if err != nil {
+		return nil, err
+	}
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) {
+		return nil, fmt.Errorf("unsupported hash algorithm %v", info.MessageImprint.HashAlgorithm.Algorithm)
+	}
+	digest, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, fmt.Errorf("file was modified after it was timestamped")
+	}
+	return info.token(), nil
+}
+
+// HasToken reports whether a token is stored next to path
+func HasToken(path string) bool {
+	_, err := os.Stat(path + TokenExtension)
+	return err == nil
+}
+
+func parseResponse(der []byte) (*tstInfo, error) {
+	var resp timeStampResp
+	if _, err := asn1.Unmarshal(der, &resp); err != nil {
+		return nil, fmt.Errorf("invalid timestamp response: %w", err)
+	}
+	// 0 = granted, 1 = granted with modifications
+	if resp.Status.Status > 1 {
+		return nil, fmt.Errorf("TSA rejected the request (status %d)", resp.Status.Status)
+	}
+	if len(resp.Token.FullBytes) == 0 {
+		return nil, fmt.Errorf("timestamp response contains no token")
+	}
+
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(resp.Token.FullBytes, &ci); err != nil {
+		return nil, fmt.Errorf("invalid timestamp token: %w", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("timestamp token is not CMS SignedData")
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("invalid timestamp token: %w", err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, fmt.Errorf("timestamp token does not contain TSTInfo")
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &info); err != nil {
+		return nil, fmt.Errorf("invalid TSTInfo: %w", err)
+	}
+	return &info, nil
+}
+
+func (t *tstInfo) token() *Token {
+	return &Token{Time: t.GenTime, Serial: t.SerialNumber, Policy: t.Policy.String()}
+}
+
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
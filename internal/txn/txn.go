@@ -0,0 +1,130 @@
+// Package txn gives a batch all-or-nothing semantics: outputs are written into
+// staging folders next to their final locations and only moved into place once
+// every item has succeeded. A failed batch leaves no outputs behind.
+package txn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StagingPrefix starts the name of every staging folder; leftovers from a
+// crash can be recognized and removed by it
+const StagingPrefix = ".hadescrypt-staging-"
+
+// ReplacedPrefix marks an existing file moved aside during Commit so a rollback can restore it
+const ReplacedPrefix = ".replaced-"
+
+// Txn is one all-or-nothing batch. Stage may be called from several goroutines.
+type Txn struct {
+	mu    sync.Mutex
+	items []*item
+}
+
+type item struct {
+	final    string
+	dir      string   // private staging folder of this item
+	moved    []string // names published into filepath.Dir(final)
+	replaced []string // names of files that existed there before
+}
+
+// New starts an empty transaction
+func New() *Txn { return &Txn{} }
+
+// Stage returns the path to write the output destined for final. Files written
+// next to it (sidecars such as .meta or .tsr) are published along with it.
+func (t *Txn) Stage(final string) (string, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(final), StagingPrefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("stage %s: %w", final, err)
+	}
+	t.mu.Lock()
+	t.items = append(t.items, &item{final: final, dir: dir})
+	t.mu.Unlock()
+	return filepath.Join(dir, filepath.Base(final)), nil
+}
+
+// Commit moves every staged file into place, replacing existing files but never
+// folders or links. If a move fails, everything already published is taken
+// back, replaced files are restored and the staged outputs discarded.
+func (t *Txn) Commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, it := range t.items {
+		if err := it.publish(); err != nil {
+			uerr := t.undo()
+			t.cleanup()
+			return errors.Join(fmt.Errorf("publish %s: %w", it.final, err), uerr)
+		}
+	}
+	t.cleanup()
+	return nil
+}
+
+// Rollback discards every staged output; nothing outside the staging folders is touched
+func (t *Txn) Rollback() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cleanup()
+}
+
+func (it *item) publish() error {
+	entries, err := os.ReadDir(it.dir)
+	if err != nil {
+		return err
+	}
+	dest := filepath.Dir(it.final)
+	for _, e := range entries {
+		src, dst := filepath.Join(it.dir, e.Name()), filepath.Join(dest, e.Name())
+		if fi, err := os.Lstat(dst); err == nil {
+			if !fi.Mode().IsRegular() {
+				return fmt.Errorf("%s is in the way and is not a file", e.Name())
+			}
+			if err := os.Rename(dst, filepath.Join(it.dir, ReplacedPrefix+e.Name())); err != nil {
+				return err
+			}
+			it.replaced = append(it.replaced, e.Name())
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return err
+		}
+		it.moved = append(it.moved, e.Name())
+	}
+	return nil
+}
+
+// undo takes published files back into staging and restores the files they replaced
+func (t *Txn) undo() error {
+	var errs []error
+	for i := len(t.items) - 1; i >= 0; i-- {
+		it := t.items[i]
+		dest := filepath.Dir(it.final)
+		for _, name := range it.moved {
+			if err := os.Rename(filepath.Join(dest, name), filepath.Join(it.dir, name)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		for _, name := range it.replaced {
+			if err := os.Rename(filepath.Join(it.dir, ReplacedPrefix+name), filepath.Join(dest, name)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		it.moved, it.replaced = nil, nil
+	}
+	return errors.Join(errs...)
+}
+
+// cleanup removes the staging folders, with any staged or replaced files in them
+func (t *Txn) cleanup() error {
+	var errs []error
+	for _, it := range t.items {
+		if err := os.RemoveAll(it.dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.items = nil
+	return errors.Join(errs...)
+}
@@ -1,18 +1,14 @@
 package ui
 
-import "fmt"
+import "github.com/bangundwir/HadesCrypt/internal/units"
 
+// HumanBytes formats a size in the units chosen in Settings
 func HumanBytes(n int64) string {
-    const unit = 1024
-    if n < unit {
-        return fmt.Sprintf("%d B", n)
-    }
-    div, exp := int64(unit), 0
-    for m := n / unit; m >= unit; m /= unit {
-        div *= unit
-        exp++
-    }
-    return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+    return units.Bytes(n)
 }
 
+// HumanRate formats a throughput in the units chosen in Settings
+func HumanRate(bytesPerSecond float64) string {
+    return units.Rate(bytesPerSecond)
+}
 
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// Theme names stored in the config
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+)
+
+// ScalePresets are the offered text and control sizes in percent
+var ScalePresets = []int{100, 125, 150, 200}
+
+// highContrast is white text on black with yellow accents; every text color
+// keeps at least a 7:1 contrast ratio against the background
+var highContrast = map[fyne.ThemeColorName]color.Color{
+	theme.ColorNameBackground:          color.Black,
+	theme.ColorNameForeground:          color.White,
+	theme.ColorNameButton:              color.NRGBA{R: 0x1a, G: 0x1a, B: 0x1a, A: 0xff},
+	theme.ColorNameDisabledButton:      color.NRGBA{R: 0x26, G: 0x26, B: 0x26, A: 0xff},
+	theme.ColorNameDisabled:            color.NRGBA{R: 0xa6, G: 0xa6, B: 0xa6, A: 0xff},
+	theme.ColorNamePlaceHolder:         color.NRGBA{R: 0xc8, G: 0xc8, B: 0xc8, A: 0xff},
+	theme.ColorNamePrimary:             color.NRGBA{R: 0xff, G: 0xd7, B: 0x00, A: 0xff},
+	theme.ColorNameForegroundOnPrimary: color.Black,
+	theme.ColorNameFocus:               color.NRGBA{R: 0xff, G: 0xd7, B: 0x00, A: 0xff},
+	theme.ColorNameHover:               color.NRGBA{R: 0x40, G: 0x40, B: 0x40, A: 0xff},
+	theme.ColorNamePressed:             color.NRGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xff},
+	theme.ColorNameSelection:           color.NRGBA{R: 0x00, G: 0x3c, B: 0xc8, A: 0xff},
+	theme.ColorNameInputBackground:     color.Black,
+	theme.ColorNameInputBorder:         color.White,
+	theme.ColorNameSeparator:           color.White,
+	theme.ColorNameHeaderBackground:    color.Black,
+	theme.ColorNameMenuBackground:      color.Black,
+	theme.ColorNameOverlayBackground:   color.Black,
+	theme.ColorNameScrollBar:           color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xc0},
+	theme.ColorNameHyperlink:           color.NRGBA{R: 0x00, G: 0xff, B: 0xff, A: 0xff},
+	theme.ColorNameError:               color.NRGBA{R: 0xff, G: 0x6e, B: 0x6e, A: 0xff},
+	theme.ColorNameForegroundOnError:   color.Black,
+	theme.ColorNameSuccess:             color.NRGBA{R: 0x3c, G: 0xff, B: 0x8c, A: 0xff},
+	theme.ColorNameForegroundOnSuccess: color.Black,
+	theme.ColorNameWarning:             color.NRGBA{R: 0xff, G: 0xb4, B: 0x00, A: 0xff},
+	theme.ColorNameForegroundOnWarning: color.Black,
+}
+
+// appTheme is the default Fyne theme with a fixed variant, an optional
+// high-contrast palette and every size scaled
+type appTheme struct {
+	variant  fyne.ThemeVariant
+	contrast bool
+	scale    float32
+}
+
+// NewTheme returns the theme for a stored theme name and scale in percent.
+// Unknown names fall back to dark and a scale of 0 means 100%.
+func NewTheme(name string, scalePercent int) fyne.Theme {
+	t := &appTheme{variant: theme.VariantDark, scale: 1}
+	switch name {
+	case ThemeLight:
+		t.variant = theme.VariantLight
+	case ThemeHighContrast:
+		t.contrast = true
+	}
+	if scalePercent > 0 {
+		t.scale = float32(scalePercent) / 100
+	}
+	return t
+}
+
+func (t *appTheme) Color(name fyne.ThemeColorName, _ fyne.ThemeVariant) color.Color {
+	if t.contrast {
+		if c, ok := highContrast[name]; ok {
+			return c
+		}
+	}
+	return theme.DefaultTheme().Color(name, t.variant)
+}
+
+func (t *appTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return theme.DefaultTheme().Font(style)
+}
+
+func (t *appTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return theme.DefaultTheme().Icon(name)
+}
+
+func (t *appTheme) Size(name fyne.ThemeSizeName) float32 {
+	size := theme.DefaultTheme().Size(name)
+	// Borders and outlines stay crisp; thicker ones help in high contrast
+	if name == theme.SizeNameInputBorder || name == theme.SizeNameSeparatorThickness {
+		if t.contrast {
+			return size * 2
+		}
+		return size
+	}
+	return size * t.scale
+}
@@ -0,0 +1,39 @@
+// Package units formats byte counts and transfer rates for display. Every size
+// the app shows goes through it, so one setting switches all of them between
+// binary units (KiB, MiB: powers of 1024) and decimal units (kB, MB: powers of 1000).
+package units
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+var decimal atomic.Bool
+
+// SetDecimal selects decimal units when on, binary units (the default) otherwise
+func SetDecimal(on bool) { decimal.Store(on) }
+
+// Decimal reports whether decimal units are selected
+func Decimal() bool { return decimal.Load() }
+
+// Bytes formats n bytes in the selected units, e.g. "1.5 MiB" or "1.6 MB"
+func Bytes(n int64) string { return format(n, decimal.Load()) }
+
+// Rate formats a transfer rate in the selected units, e.g. "12.0 MiB/s"
+func Rate(bytesPerSecond float64) string { return Bytes(int64(bytesPerSecond)) + "/s" }
+
+func format(n int64, decimal bool) string {
+	unit, prefixes, suffix := int64(1024), "KMGTPE", "iB"
+	if decimal {
+		unit, prefixes, suffix = 1000, "kMGTPE", "B"
+	}
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit || m <= -unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %c%s", float64(n)/float64(div), prefixes[exp], suffix)
+}
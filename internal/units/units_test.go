@@ -0,0 +1,39 @@
+package units
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		n       int64
+		decimal bool
+		want    string
+	}{
+		{0, false, "0 B"},
+		{1023, false, "1023 B"},
+		{1024, false, "1.0 KiB"},
+		{1536 * 1024, false, "1.5 MiB"},
+		{999, true, "999 B"},
+		{1000, true, "1.0 kB"},
+		{1536 * 1024, true, "1.6 MB"},
+		{5 << 40, false, "5.0 TiB"},
+		{-2048, false, "-2.0 KiB"},
+	} {
+		if got := format(tc.n, tc.decimal); got != tc.want {
+			t.Errorf("format(%d, %v) = %q, want %q", tc.n, tc.decimal, got, tc.want)
+		}
+	}
+}
+
+func TestSetDecimal(t *testing.T) {
+	defer SetDecimal(false)
+	if got := Rate(2 * 1024 * 1024); got != "2.0 MiB/s" {
+		t.Errorf("binary Rate = %q", got)
+	}
+	SetDecimal(true)
+	if !Decimal() {
+		t.Fatal("Decimal() = false after SetDecimal(true)")
+	}
+	if got := Bytes(2_500_000); got != "2.5 MB" {
+		t.Errorf("decimal Bytes = %q", got)
+	}
+}
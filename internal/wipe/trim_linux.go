@@ -0,0 +1,55 @@
+//go:build linux
+
+package wipe
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// fitrim is _IOWR('X', 121, struct fstrim_range)
+const fitrim = 0xC0185879
+
+type fstrimRange struct {
+	start, length, minLen uint64
+}
+
+// discard punches a hole over the whole file so the filesystem frees (and, when
+// mounted with discard, TRIMs) its blocks while the name still exists
+func discard(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() == 0 {
+		return nil
+	}
+	if err := unix.Fallocate(int(f.Fd()), unix.FALLOC_FL_PUNCH_HOLE|unix.FALLOC_FL_KEEP_SIZE, 0, fi.Size()); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// trimFreeSpace asks the filesystem holding dir to discard all free blocks, like
+// fstrim. It needs CAP_SYS_ADMIN, so it usually fails for normal users; the
+// periodic fstrim timer then catches the freed blocks.
+func trimFreeSpace(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	r := fstrimRange{length: ^uint64(0)}
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, d.Fd(), fitrim, uintptr(unsafe.Pointer(&r)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
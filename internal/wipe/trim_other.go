@@ -0,0 +1,12 @@
+//go:build !linux
+
+package wipe
+
+import "errors"
+
+// discard is a no-op: Windows and macOS send TRIM for deleted files on SSDs themselves
+func discard(path string) error { return nil }
+
+func trimFreeSpace(dir string) error {
+	return errors.New("free-space trim is handled by the operating system")
+}
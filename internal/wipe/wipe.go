@@ -0,0 +1,109 @@
+// Package wipe deletes files in the way that actually works for the storage
+// they live on: an overwrite pass on spinning disks, discarding the blocks
+// (TRIM) on SSDs, and plain deletion plus a warning where neither helps.
+package wipe
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bangundwir/HadesCrypt/internal/media"
+)
+
+// Strategy is how a file is destroyed
+type Strategy int
+
+const (
+	// Overwrite writes one pass of random data before deleting (NIST SP 800-88
+	// considers a single pass sufficient for magnetic media)
+	Overwrite Strategy = iota
+	// Trim releases the file's blocks to the SSD with a discard before deleting;
+	// overwriting would only land on fresh flash pages
+	Trim
+	// DeleteOnly removes the file; the storage gives no control over old copies
+	DeleteOnly
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case Trim:
+		return "TRIM (discard blocks)"
+	case DeleteOnly:
+		return "delete only"
+	}
+	return "overwrite"
+}
+
+// Result reports what was done for one file
+type Result struct {
+	Path     string
+	Strategy Strategy
+	Media    media.Info
+	Trimmed  bool // the filesystem confirmed a discard of free space
+}
+
+// Plan picks the strategy for path's storage
+func Plan(info media.Info) Strategy {
+	switch {
+	case info.Kind != media.Local:
+		return DeleteOnly
+	case info.SolidState:
+		return Trim
+	}
+	return Overwrite
+}
+
+// Advice explains the limits of file-level deletion on info's storage and what to do instead
+func Advice(info media.Info) string {
+	switch Plan(info) {
+	case DeleteOnly:
+		return fmt.Sprintf("Files on a %s cannot be securely erased from here: the server, its snapshots or the stick's flash controller may keep old copies. Keep only encrypted data on such storage.", info.Kind)
+	case Trim:
+		return "SSDs remap writes, so overwriting cannot reach the old pages. HadesCrypt discards the file's blocks instead (TRIM); the drive erases them in the background. For guaranteed erasure use full-disk encryption (BitLocker, FileVault, LUKS) and destroy the key (crypto-erase), or the drive's secure-erase command."
+	}
+	return "The file is overwritten once with random data and then deleted. Copy-on-write filesystems and snapshots may still hold earlier versions."
+}
+
+// File destroys path using the strategy suited to its storage
+func File(path string) (Result, error) {
+	info := media.Detect(path)
+	res := Result{Path: path, Media: info, Strategy: Plan(info)}
+	switch res.Strategy {
+	case Overwrite:
+		if err := overwrite(path); err != nil {
+			return res, err
+		}
+	case Trim:
+		if err := discard(path); err != nil {
+			// Unsupported hole punching still leaves deletion as the right choice on flash
+			res.Strategy = DeleteOnly
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		return res, err
+	}
+	if res.Strategy == Trim {
+		res.Trimmed = trimFreeSpace(filepath.Dir(path)) == nil
+	}
+	return res, nil
+}
+
+// overwrite writes one pass of random data over the file and syncs it
+func overwrite(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(f, rand.Reader, fi.Size()); err != nil {
+		return fmt.Errorf("overwrite: %w", err)
+	}
+	return f.Sync()
+}
@@ -0,0 +1,266 @@
+package wormhole
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRelayPort is where hadescrypt-relay listens unless told otherwise
+const DefaultRelayPort = "4747"
+
+const relayHello = "HCRELAY1"
+
+// Relay is a Channel through a hadescrypt-relay server at Addr (host or host:port).
+// The relay pairs one sender and one receiver per nameplate and copies bytes
+// between them; it cannot read the end-to-end encrypted stream.
+type Relay struct {
+	Addr string
+}
+
+// Open connects to the relay and registers under nameplate
+func (r Relay) Open(ctx context.Context, nameplate string, sender bool) (io.ReadWriteCloser, error) {
+	addr := r.Addr
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultRelayPort)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("connect to relay %s: %w", addr, err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	side := "recv"
+	if sender {
+		side = "send"
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if _, err := fmt.Fprintf(conn, "%s %s %s\n", relayHello, side, nameplate); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("relay %s: %w", addr, err)
+	}
+	reply, err := readLine(conn)
+	conn.SetDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("relay %s: %w", addr, err)
+	}
+	switch reply {
+	case "ok":
+		return conn, nil
+	case "taken":
+		conn.Close()
+		return nil, ErrNameplateTaken
+	case "unknown":
+		conn.Close()
+		return nil, ErrNoSuchCode
+	case "busy":
+		conn.Close()
+		return nil, ErrRelayBusy
+	default:
+		conn.Close()
+		return nil, fmt.Errorf("relay %s: unexpected reply %q", addr, reply)
+	}
+}
+
+// readLine reads one short line byte by byte, so no stream data after it is consumed
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for len(line) < 128 {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return string(line), nil
+		}
+		line = append(line, b[0])
+	}
+	return "", errors.New("line too long")
+}
+
+// Defaults of Server
+const (
+	DefaultWaitTimeout = 10 * time.Minute
+	DefaultMaxPerAddr  = 4
+)
+
+// maxEarly bounds what a waiting sender may send before its receiver joins;
+// the handshake needs far less
+const maxEarly = 4 << 10
+
+// Server is the relay: it matches a waiting sender with the receiver that
+// names the same nameplate and then pipes the two connections together. A
+// nameplate is freed as soon as its sender disconnects or times out.
+type Server struct {
+	// WaitTimeout drops senders nobody joined; zero means DefaultWaitTimeout
+	WaitTimeout time.Duration
+	// MaxPerAddr caps the nameplates senders from one IP address hold at
+	// once; zero means DefaultMaxPerAddr
+	MaxPerAddr int
+	Logger     *log.Logger
+
+	mu      sync.Mutex
+	waiting map[string]*waiter
+}
+
+// waiter is a sender holding a nameplate
+type waiter struct {
+	conn  net.Conn
+	host  string
+	early []byte        // sent before the receiver joined, owned by watch until done
+	done  chan struct{} // closed once watch stopped reading
+}
+
+// Serve accepts connections on ln until it fails
+func (s *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, args...)
+	}
+}
+
+// hostOf returns the IP address of a connection's peer
+func hostOf(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+func (s *Server) handle(conn net.Conn) {
+	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	line, err := readLine(conn)
+	conn.SetReadDeadline(time.Time{})
+	fields := strings.Fields(line)
+	if err != nil || len(fields) != 3 || fields[0] != relayHello {
+		conn.Close()
+		return
+	}
+	side, nameplate := fields[1], fields[2]
+
+	s.mu.Lock()
+	if s.waiting == nil {
+		s.waiting = map[string]*waiter{}
+	}
+	peer := s.waiting[nameplate]
+	switch side {
+	case "send":
+		if peer != nil {
+			s.mu.Unlock()
+			io.WriteString(conn, "taken\n")
+			conn.Close()
+			return
+		}
+		w := &waiter{conn: conn, host: hostOf(conn), done: make(chan struct{})}
+		held := 0
+		for _, o := range s.waiting {
+			if o.host == w.host {
+				held++
+			}
+		}
+		if limit := cmp.Or(s.MaxPerAddr, DefaultMaxPerAddr); held >= limit {
+			s.mu.Unlock()
+			io.WriteString(conn, "busy\n")
+			conn.Close()
+			s.logf("nameplate %s: refused, %s holds %d", nameplate, w.host, held)
+			return
+		}
+		s.waiting[nameplate] = w
+		s.mu.Unlock()
+		io.WriteString(conn, "ok\n")
+		s.logf("nameplate %s: sender waiting from %s", nameplate, conn.RemoteAddr())
+		timer := time.AfterFunc(cmp.Or(s.WaitTimeout, DefaultWaitTimeout), func() { s.release(nameplate, w, "timed out") })
+		s.watch(nameplate, w)
+		timer.Stop()
+	case "recv":
+		if peer == nil {
+			s.mu.Unlock()
+			io.WriteString(conn, "unknown\n")
+			conn.Close()
+			return
+		}
+		// a nameplate pairs once; a wrong guess burns it
+		delete(s.waiting, nameplate)
+		s.mu.Unlock()
+		// stop the watch, then pass on what the sender sent meanwhile
+		peer.conn.SetReadDeadline(time.Now())
+		<-peer.done
+		peer.conn.SetReadDeadline(time.Time{})
+		io.WriteString(conn, "ok\n")
+		s.logf("nameplate %s: receiver joined from %s", nameplate, conn.RemoteAddr())
+		if _, err := conn.Write(peer.early); err != nil {
+			peer.conn.Close()
+			conn.Close()
+			return
+		}
+		pipe(peer.conn, conn)
+		s.logf("nameplate %s: closed", nameplate)
+	default:
+		s.mu.Unlock()
+		conn.Close()
+	}
+}
+
+// watch reads from a waiting sender until a receiver claims it, so a sender
+// that disconnects frees its nameplate at once
+func (s *Server) watch(nameplate string, w *waiter) {
+	b := make([]byte, 512)
+	for {
+		n, err := w.conn.Read(b)
+		w.early = append(w.early, b[:n]...)
+		if len(w.early) > maxEarly {
+			s.release(nameplate, w, "sent too much before its receiver joined")
+			break
+		}
+		if err != nil {
+			s.release(nameplate, w, "left")
+			break
+		}
+	}
+	close(w.done)
+}
+
+// release drops w from nameplate and closes it unless a receiver claimed it
+func (s *Server) release(nameplate string, w *waiter, why string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.waiting[nameplate] == w {
+		delete(s.waiting, nameplate)
+		w.conn.Close()
+		s.logf("nameplate %s: sender %s", nameplate, why)
+	}
+}
+
+// pipe copies between a and b until either side finishes, then closes both
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(a, b); done <- struct{}{} }()
+	go func() { io.Copy(b, a); done <- struct{}{} }()
+	<-done
+	a.Close()
+	b.Close()
+	<-done
+}
@@ -0,0 +1,33 @@
+package wormhole
+
+// words holds the 256 code words; each adds 8 bits to a code. They are short,
+// distinct and easy to say aloud.
+var words = [256]string{
+	"acid", "acorn", "actor", "adobe", "agent", "alarm", "album", "alert", "alpha", "amber", "angle",
+	"ankle", "apple", "apron", "arena", "armor", "arrow", "aspen", "atlas", "attic", "autumn",
+	"avenue", "bacon", "badge", "bagel", "baker", "balsa", "bamboo", "banjo", "barber", "barley",
+	"basil", "basket", "beacon", "beaver", "berry", "bishop", "blanket", "blossom", "bonnet",
+	"border", "bottle", "bounty", "bracket", "breeze", "brick", "bridge", "bronze", "bucket",
+	"buffalo", "bugle", "butter", "button", "cabin", "cactus", "camel", "candle", "canoe", "canvas",
+	"canyon", "carbon", "carpet", "castle", "cedar", "cello", "cement", "chalk", "cherry", "chess",
+	"cider", "cinder", "circus", "clover", "cobalt", "cocoa", "comet", "copper", "coral", "cotton",
+	"cradle", "crater", "crayon", "cricket", "crystal", "curtain", "cycle", "dagger", "daisy",
+	"dancer", "delta", "denim", "desert", "diesel", "dinner", "dolphin", "donkey", "dragon", "drift",
+	"eagle", "easel", "echo", "eclipse", "elbow", "ember", "emerald", "engine", "falcon", "feather",
+	"fennel", "ferry", "fiddle", "fig", "flannel", "flint", "fossil", "fountain", "fox", "galaxy",
+	"garden", "garlic", "garnet", "geyser", "ginger", "glacier", "goblet", "granite", "gravel",
+	"guitar", "hammer", "harbor", "harvest", "hazel", "helmet", "honey", "hornet", "husky", "igloo",
+	"indigo", "island", "ivory", "jacket", "jaguar", "jasmine", "jelly", "jungle", "kayak", "kernel",
+	"kettle", "kiwi", "ladder", "lagoon", "lantern", "laser", "lemon", "lentil", "lilac", "linen",
+	"lizard", "locket", "lotus", "magnet", "mango", "maple", "marble", "meadow", "melon", "meteor",
+	"mitten", "mosaic", "muffin", "mural", "nectar", "needle", "nickel", "noodle", "nutmeg", "oasis",
+	"ocean", "olive", "onion", "opal", "orbit", "orchid", "otter", "oyster", "paddle", "palette",
+	"panda", "parrot", "pebble", "pepper", "piano", "pickle", "pilot", "planet", "plaza", "pocket",
+	"pollen", "poppy", "potato", "prism", "pumpkin", "puzzle", "quartz", "quill", "rabbit", "radar",
+	"raisin", "raven", "ribbon", "river", "rocket", "saddle", "salmon", "sandal", "satin", "scarf",
+	"shadow", "silver", "sketch", "spider", "spruce", "squash", "statue", "sugar", "summit", "sunset",
+	"swan", "tango", "teapot", "temple", "thistle", "thunder", "tiger", "timber", "tomato", "topaz",
+	"tractor", "trumpet", "tulip", "tundra", "turtle", "umbrella", "valley", "velvet", "violin",
+	"volcano", "waffle", "walnut", "walrus", "willow", "window", "wizard", "yogurt", "zebra",
+	"zigzag",
+}
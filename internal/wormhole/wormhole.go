@@ -0,0 +1,400 @@
+// Package wormhole sends files over the internet with a short code phrase,
+// in the style of magic-wormhole. The sender reserves a code such as
+// "42-copper-willow" on a rendezvous Channel; the receiver types it, both
+// sides run a PAKE on the full code, and the files then travel end-to-end
+// encrypted, so the relay only ever sees the nameplate ("42") and ciphertext.
+// Interrupted files resume where they stopped when sent again.
+package wormhole
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/pake"
+)
+
+const (
+	magic         = "HCWORM1"
+	codeWords     = 2
+	maxNameplate  = 999
+	claimAttempts = 5
+	chunkSize     = 64 << 10
+	maxMessage    = chunkSize + chacha20poly1305.Overhead
+)
+
+var (
+	// ErrNameplateTaken is returned by Channel.Open when another sender holds the nameplate
+	ErrNameplateTaken = errors.New("nameplate already in use")
+	// ErrNoSuchCode is returned by Channel.Open when no sender waits under the nameplate
+	ErrNoSuchCode = errors.New("no sender is waiting for this code")
+	// ErrRelayBusy is returned by Channel.Open when the relay holds as many
+	// nameplates for the caller's address as it allows
+	ErrRelayBusy = errors.New("too many transfers waiting from this address; try again later")
+	// ErrWrongCode means the words of the code did not match the sender's
+	ErrWrongCode = pake.ErrWrongCode
+)
+
+// Channel connects the two ends of a transfer. Open registers the caller as
+// sender or receiver under nameplate and returns a byte stream to the other
+// side; for a sender the stream starts flowing once the receiver joins.
+// Relay is the internet implementation.
+type Channel interface {
+	Open(ctx context.Context, nameplate string, sender bool) (io.ReadWriteCloser, error)
+}
+
+// ProgressFunc reports the bytes of the current file transferred so far,
+// including any part resumed from an earlier attempt
+type ProgressFunc func(name string, done, total int64)
+
+// fileHeader announces a file; an empty Name ends the transfer
+type fileHeader struct {
+	Name   string `json:"name,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Offer is a reserved code waiting for its receiver
+type Offer struct {
+	Code string
+	conn io.ReadWriteCloser
+}
+
+// NewOffer reserves a fresh code on ch
+func NewOffer(ctx context.Context, ch Channel) (*Offer, error) {
+	for range claimAttempts {
+		code, nameplate, err := newCode()
+		if err != nil {
+			return nil, err
+		}
+		conn, err := ch.Open(ctx, nameplate, true)
+		if errors.Is(err, ErrNameplateTaken) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Offer{Code: code, conn: conn}, nil
+	}
+	return nil, errors.New("the relay has no free nameplate; try again later")
+}
+
+// Close withdraws the offer
+func (o *Offer) Close() error { return o.conn.Close() }
+
+// Send waits for the receiver, verifies the code and transfers paths in order
+func (o *Offer) Send(ctx context.Context, paths []string, onProgress ProgressFunc) error {
+	defer o.conn.Close()
+	stop := context.AfterFunc(ctx, func() { o.conn.Close() })
+	defer stop()
+
+	sc, err := handshake(o.conn, o.Code, true)
+	if err != nil {
+		return canceled(ctx, err)
+	}
+	for _, p := range paths {
+		if err := sc.sendFile(p, onProgress); err != nil {
+			return canceled(ctx, fmt.Errorf("send %s: %w", filepath.Base(p), err))
+		}
+	}
+	end, _ := json.Marshal(fileHeader{})
+	return canceled(ctx, sc.write(end))
+}
+
+// Receive joins the sender waiting under code and stores its files in dir.
+// Part files from an interrupted earlier transfer of the same content are resumed.
+func Receive(ctx context.Context, ch Channel, code, dir string, onProgress ProgressFunc) ([]string, error) {
+	code, nameplate, err := ParseCode(code)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := ch.Open(ctx, nameplate, false)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	sc, err := handshake(conn, code, false)
+	if err != nil {
+		return nil, canceled(ctx, err)
+	}
+	var paths []string
+	for {
+		msg, err := sc.read()
+		if err != nil {
+			return paths, canceled(ctx, err)
+		}
+		var h fileHeader
+		if err := json.Unmarshal(msg, &h); err != nil {
+			return paths, fmt.Errorf("invalid file header: %w", err)
+		}
+		if h.Name == "" {
+			return paths, nil
+		}
+		path, err := sc.receiveFile(dir, &h, onProgress)
+		if err != nil {
+			return paths, canceled(ctx, fmt.Errorf("receive %s: %w", h.Name, err))
+		}
+		paths = append(paths, path)
+	}
+}
+
+// canceled reports ctx's error in place of the broken-connection error it caused
+func canceled(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// newCode returns a code and its nameplate
+func newCode() (code, nameplate string, err error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(maxNameplate))
+	if err != nil {
+		return "", "", err
+	}
+	nameplate = strconv.FormatInt(n.Int64()+1, 10)
+	parts := []string{nameplate}
+	var idx [codeWords]byte
+	if _, err := rand.Read(idx[:]); err != nil {
+		return "", "", err
+	}
+	for _, i := range idx {
+		parts = append(parts, words[i])
+	}
+	return strings.Join(parts, "-"), nameplate, nil
+}
+
+// ParseCode normalizes a typed code ("42 Copper willow" → "42-copper-willow")
+// and returns its nameplate
+func ParseCode(code string) (normalized, nameplate string, err error) {
+	fields := strings.FieldsFunc(strings.ToLower(code), func(r rune) bool { return r == '-' || r == ' ' || r == '\t' })
+	if len(fields) != codeWords+1 {
+		return "", "", fmt.Errorf("a code looks like 42-copper-willow")
+	}
+	if n, err := strconv.Atoi(fields[0]); err != nil || n < 1 || n > maxNameplate {
+		return "", "", fmt.Errorf("a code starts with a number from 1 to %d", maxNameplate)
+	}
+	return strings.Join(fields, "-"), fields[0], nil
+}
+
+// secureConn carries length-prefixed messages sealed with ChaCha20-Poly1305;
+// each direction has its own key and a counter nonce
+type secureConn struct {
+	rw           io.ReadWriter
+	send, recv   cipher.AEAD
+	sendN, recvN uint64
+}
+
+func handshake(conn io.ReadWriter, code string, sender bool) (*secureConn, error) {
+	session, err := pake.Handshake(conn, magic, code, sender, []byte(magic))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]byte, 2*chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, session, nil, []byte("wormhole stream keys")), keys); err != nil {
+		return nil, err
+	}
+	toReceiver, err := chacha20poly1305.New(keys[:chacha20poly1305.KeySize])
+	if err != nil {
+		return nil, err
+	}
+	toSender, err := chacha20poly1305.New(keys[chacha20poly1305.KeySize:])
+	if err != nil {
+		return nil, err
+	}
+	if sender {
+		return &secureConn{rw: conn, send: toReceiver, recv: toSender}, nil
+	}
+	return &secureConn{rw: conn, send: toSender, recv: toReceiver}, nil
+}
+
+func nonce(n uint64) []byte {
+	b := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(b[4:], n)
+	return b
+}
+
+func (c *secureConn) write(msg []byte) error {
+	sealed := c.send.Seal(make([]byte, 4, 4+len(msg)+chacha20poly1305.Overhead), nonce(c.sendN), msg, nil)
+	c.sendN++
+	binary.BigEndian.PutUint32(sealed, uint32(len(sealed)-4))
+	_, err := c.rw.Write(sealed)
+	return err
+}
+
+func (c *secureConn) read() ([]byte, error) {
+	var n [4]byte
+	if _, err := io.ReadFull(c.rw, n[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(n[:])
+	if size > maxMessage {
+		return nil, errors.New("message too large")
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(c.rw, sealed); err != nil {
+		return nil, err
+	}
+	msg, err := c.recv.Open(sealed[:0], nonce(c.recvN), sealed, nil)
+	if err != nil {
+		return nil, errors.New("message failed authentication")
+	}
+	c.recvN++
+	return msg, nil
+}
+
+// sendFile announces path with its hash, skips what the receiver already has
+// and streams the rest
+func (c *secureConn) sendFile(path string, onProgress ProgressFunc) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sum := sha256.New()
+	size, err := io.Copy(sum, f)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(path)
+	h, _ := json.Marshal(fileHeader{Name: name, Size: size, SHA256: hex.EncodeToString(sum.Sum(nil))})
+	if err := c.write(h); err != nil {
+		return err
+	}
+	reply, err := c.read()
+	if err != nil {
+		return err
+	}
+	if len(reply) != 8 {
+		return errors.New("invalid resume offset")
+	}
+	offset := int64(binary.BigEndian.Uint64(reply))
+	if offset < 0 || offset > size {
+		return errors.New("invalid resume offset")
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	buf := make([]byte, chunkSize)
+	for done := offset; done < size; {
+		n, err := io.ReadFull(f, buf[:min(int64(chunkSize), size-done)])
+		if err != nil {
+			return fmt.Errorf("file changed while sending: %w", err)
+		}
+		if err := c.write(buf[:n]); err != nil {
+			return err
+		}
+		done += int64(n)
+		if onProgress != nil {
+			onProgress(name, done, size)
+		}
+	}
+	status, err := c.read()
+	if err != nil {
+		return err
+	}
+	if string(status) != "ok" {
+		return fmt.Errorf("receiver: %s", status)
+	}
+	return nil
+}
+
+// receiveFile appends to the part file for h's content, checks the hash and
+// moves the result into dir under a free name
+func (c *secureConn) receiveFile(dir string, h *fileHeader, onProgress ProgressFunc) (string, error) {
+	name := filepath.Base(filepath.Clean("/" + h.Name))
+	want, err := hex.DecodeString(h.SHA256)
+	if name == "/" || name == "." || h.Size < 0 || err != nil || len(want) != sha256.Size {
+		return "", fmt.Errorf("invalid file header for %q", h.Name)
+	}
+	partPath := filepath.Join(dir, "."+name+"."+h.SHA256[:16]+".part")
+	part, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer part.Close()
+	sum := sha256.New()
+	offset, err := resumeFrom(part, sum, h.Size)
+	if err != nil {
+		return "", err
+	}
+	var off [8]byte
+	binary.BigEndian.PutUint64(off[:], uint64(offset))
+	if err := c.write(off[:]); err != nil {
+		return "", err
+	}
+	for done := offset; done < h.Size; {
+		chunk, err := c.read()
+		if err != nil {
+			return "", err
+		}
+		if int64(len(chunk)) > h.Size-done {
+			return "", errors.New("sender sent more data than announced")
+		}
+		if _, err := part.Write(chunk); err != nil {
+			c.write([]byte(err.Error()))
+			return "", err
+		}
+		sum.Write(chunk)
+		done += int64(len(chunk))
+		if onProgress != nil {
+			onProgress(name, done, h.Size)
+		}
+	}
+	if !hmac.Equal(sum.Sum(nil), want) {
+		// start over next time rather than resuming from bad data
+		part.Truncate(0)
+		c.write([]byte("checksum mismatch"))
+		return "", errors.New("checksum mismatch; send the file again")
+	}
+	if err := part.Close(); err != nil {
+		return "", err
+	}
+	dest := format.FreePath(filepath.Join(dir, name))
+	if err := os.Rename(partPath, dest); err != nil {
+		c.write([]byte(err.Error()))
+		return "", err
+	}
+	return dest, c.write([]byte("ok"))
+}
+
+// resumeFrom hashes the data already in part and returns its length; a part
+// longer than the announced size is discarded
+func resumeFrom(part *os.File, sum hash.Hash, size int64) (int64, error) {
+	st, err := part.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if st.Size() > size {
+		if err := part.Truncate(0); err != nil {
+			return 0, err
+		}
+		return 0, nil
+	}
+	n, err := io.Copy(sum, part)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,306 @@
+package wormhole
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startRelay runs a relay server on a loopback port for the test
+func startRelay(t *testing.T) Relay {
+	t.Helper()
+	return startServer(t, &Server{WaitTimeout: time.Minute})
+}
+
+// startServer runs srv on a loopback port for the test
+func startServer(t *testing.T, srv *Server) Relay {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go srv.Serve(ln)
+	return Relay{Addr: ln.Addr().String()}
+}
+
+// faultyChannel hands the receiver a stream that breaks off after cutAt bytes,
+// or has the byte at flipAt flipped; zero disables either
+type faultyChannel struct {
+	Channel
+	cutAt, flipAt int64
+}
+
+func (f faultyChannel) Open(ctx context.Context, nameplate string, sender bool) (io.ReadWriteCloser, error) {
+	conn, err := f.Channel.Open(ctx, nameplate, sender)
+	if err != nil || sender {
+		return conn, err
+	}
+	return &faultyConn{ReadWriteCloser: conn, cutAt: f.cutAt, flipAt: f.flipAt}, nil
+}
+
+type faultyConn struct {
+	io.ReadWriteCloser
+	off, cutAt, flipAt int64
+}
+
+func (c *faultyConn) Read(p []byte) (int, error) {
+	if c.cutAt > 0 {
+		if c.off >= c.cutAt {
+			c.Close()
+			return 0, io.ErrUnexpectedEOF
+		}
+		p = p[:min(int64(len(p)), c.cutAt-c.off)]
+	}
+	n, err := c.ReadWriteCloser.Read(p)
+	if c.flipAt > 0 && c.flipAt >= c.off && c.flipAt < c.off+int64(n) {
+		p[c.flipAt-c.off] ^= 1
+	}
+	c.off += int64(n)
+	return n, err
+}
+
+// writeRandom writes size random bytes to a new file named name
+func writeRandom(t *testing.T, name string, size int) (string, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+// transfer offers paths on send and receives them through recv into dir
+func transfer(t *testing.T, send, recv Channel, code func(string) string, paths []string, dir string, onProgress ProgressFunc) (got []string, sendErr, recvErr error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	offer, err := NewOffer(ctx, send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := make(chan error, 1)
+	go func() { sent <- offer.Send(ctx, paths, onProgress) }()
+	got, recvErr = Receive(ctx, recv, code(offer.Code), dir, nil)
+	return got, <-sent, recvErr
+}
+
+func same(code string) string { return code }
+
+func TestTransfer(t *testing.T) {
+	relay := startRelay(t)
+	a, dataA := writeRandom(t, "photo.jpg", 3*chunkSize+17)
+	b, dataB := writeRandom(t, "empty.txt", 0)
+	dir := t.TempDir()
+	got, sendErr, recvErr := transfer(t, relay, relay, func(c string) string { return strings.ToUpper(strings.ReplaceAll(c, "-", " ")) }, []string{a, b}, dir, nil)
+	if sendErr != nil || recvErr != nil {
+		t.Fatalf("send: %v, receive: %v", sendErr, recvErr)
+	}
+	if len(got) != 2 {
+		t.Fatalf("received %v", got)
+	}
+	for i, want := range [][]byte{dataA, dataB} {
+		if data, err := os.ReadFile(got[i]); err != nil || !bytes.Equal(data, want) {
+			t.Errorf("%s: %d bytes, %v", got[i], len(data), err)
+		}
+	}
+	if parts, _ := filepath.Glob(filepath.Join(dir, "*.part")); len(parts) != 0 {
+		t.Errorf("part files left: %v", parts)
+	}
+}
+
+func TestTransferResumes(t *testing.T) {
+	relay := startRelay(t)
+	path, data := writeRandom(t, "video.mp4", 6*chunkSize+5)
+	dir := t.TempDir()
+
+	// the first attempt breaks off in the middle of the file
+	_, _, err := transfer(t, relay, faultyChannel{Channel: relay, cutAt: 3*chunkSize + chunkSize/2}, same, []string{path}, dir, nil)
+	if err == nil {
+		t.Fatal("the interrupted transfer succeeded")
+	}
+	parts, _ := filepath.Glob(filepath.Join(dir, ".video.mp4.*.part"))
+	if len(parts) != 1 {
+		t.Fatalf("part files: %v", parts)
+	}
+	st, err := os.Stat(parts[0])
+	if err != nil || st.Size() == 0 || st.Size()%chunkSize != 0 {
+		t.Fatalf("part file: %v, %v", st, err)
+	}
+	kept := st.Size()
+
+	// the second attempt only sends the rest
+	first := int64(-1)
+	progress := func(name string, done, total int64) {
+		if first < 0 {
+			first = done
+		}
+	}
+	got, sendErr, recvErr := transfer(t, relay, relay, same, []string{path}, dir, progress)
+	if sendErr != nil || recvErr != nil {
+		t.Fatalf("send: %v, receive: %v", sendErr, recvErr)
+	}
+	if first != kept+chunkSize {
+		t.Errorf("resumed transfer started at %d, want %d", first-chunkSize, kept)
+	}
+	if out, err := os.ReadFile(got[0]); err != nil || !bytes.Equal(out, data) {
+		t.Errorf("resumed file: %d bytes, %v", len(out), err)
+	}
+}
+
+func TestTransferWrongCode(t *testing.T) {
+	relay := startRelay(t)
+	path, _ := writeRandom(t, "secret.txt", 100)
+	dir := t.TempDir()
+	wrong := func(code string) string {
+		parts := strings.Split(code, "-")
+		if parts[1] == words[0] {
+			parts[1] = words[1]
+		} else {
+			parts[1] = words[0]
+		}
+		return strings.Join(parts, "-")
+	}
+	got, sendErr, recvErr := transfer(t, relay, relay, wrong, []string{path}, dir, nil)
+	if !errors.Is(sendErr, ErrWrongCode) || !errors.Is(recvErr, ErrWrongCode) {
+		t.Errorf("send: %v, receive: %v; want ErrWrongCode on both", sendErr, recvErr)
+	}
+	if entries, _ := os.ReadDir(dir); len(got) != 0 || len(entries) != 0 {
+		t.Errorf("files received with a wrong code: %v", entries)
+	}
+}
+
+func TestTransferTamperedFrame(t *testing.T) {
+	relay := startRelay(t)
+	path, _ := writeRandom(t, "report.pdf", 2*chunkSize)
+	dir := t.TempDir()
+	// the receiver reads 39 bytes of SPAKE2 share and 32 of key confirmation
+	// before the first sealed frame; flip a byte of its ciphertext
+	handshakeLen := int64(len(magic) + 32 + 32)
+	_, _, recvErr := transfer(t, relay, faultyChannel{Channel: relay, flipAt: handshakeLen + 10}, same, []string{path}, dir, nil)
+	if recvErr == nil || !strings.Contains(recvErr.Error(), "authentication") {
+		t.Errorf("receive: got %v, want an authentication failure", recvErr)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files received from a tampered stream: %v", entries)
+	}
+}
+
+func TestRelayNameplates(t *testing.T) {
+	relay := startRelay(t)
+	ctx := context.Background()
+	if _, err := relay.Open(ctx, "7", false); !errors.Is(err, ErrNoSuchCode) {
+		t.Errorf("receiver without a sender: got %v, want ErrNoSuchCode", err)
+	}
+	conn, err := relay.Open(ctx, "7", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := relay.Open(ctx, "7", true); !errors.Is(err, ErrNameplateTaken) {
+		t.Errorf("second sender: got %v, want ErrNameplateTaken", err)
+	}
+}
+
+// eventually retries f for a while until it returns nil
+func eventually(t *testing.T, what string, f func() error) {
+	t.Helper()
+	var err error
+	for range 100 {
+		if err = f(); err == nil {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("%s: %v", what, err)
+}
+
+func TestRelayFreesNameplateOfClosedSender(t *testing.T) {
+	relay := startRelay(t)
+	ctx := context.Background()
+	conn, err := relay.Open(ctx, "8", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	eventually(t, "nameplate of a closed sender", func() error {
+		if _, err := relay.Open(ctx, "8", false); !errors.Is(err, ErrNoSuchCode) {
+			return fmt.Errorf("receiver got %v, want ErrNoSuchCode", err)
+		}
+		return nil
+	})
+	again, err := relay.Open(ctx, "8", true)
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+	again.Close()
+}
+
+func TestRelayWaitTimeout(t *testing.T) {
+	relay := startServer(t, &Server{WaitTimeout: 50 * time.Millisecond})
+	ctx := context.Background()
+	conn, err := relay.Open(ctx, "9", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// the relay hangs up on a sender nobody joined
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("read from a timed-out sender succeeded")
+	}
+	if _, err := relay.Open(ctx, "9", false); !errors.Is(err, ErrNoSuchCode) {
+		t.Errorf("receiver after the timeout: got %v, want ErrNoSuchCode", err)
+	}
+}
+
+func TestRelayPerAddressLimit(t *testing.T) {
+	relay := startServer(t, &Server{WaitTimeout: time.Minute, MaxPerAddr: 2})
+	ctx := context.Background()
+	var held []io.ReadWriteCloser
+	for _, nameplate := range []string{"1", "2"} {
+		conn, err := relay.Open(ctx, nameplate, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		held = append(held, conn)
+	}
+	if _, err := relay.Open(ctx, "3", true); !errors.Is(err, ErrRelayBusy) {
+		t.Errorf("third sender: got %v, want ErrRelayBusy", err)
+	}
+	held[0].Close()
+	eventually(t, "sender after one left", func() error {
+		conn, err := relay.Open(ctx, "3", true)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	})
+}
+
+func TestParseCode(t *testing.T) {
+	for in, want := range map[string]string{
+		"42-copper-willow":    "42-copper-willow",
+		" 42 Copper\tWILLOW ": "42-copper-willow",
+	} {
+		if got, nameplate, err := ParseCode(in); err != nil || got != want || nameplate != "42" {
+			t.Errorf("ParseCode(%q) = %q, %q, %v", in, got, nameplate, err)
+		}
+	}
+	for _, in := range []string{"", "copper-willow", "0-copper-willow", "1000-copper-willow", "42-copper"} {
+		if _, _, err := ParseCode(in); err == nil {
+			t.Errorf("ParseCode(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+var keypadSets = map[string]string{
+	"123": "0123456789",
+	"abc": "abcdefghijklmnopqrstuvwxyz",
+	"ABC": "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"#+=": "!@#$%^&*()-_=+[]{};:,.<>/?~",
+}
+
+// shuffledKeys returns the characters of set in a cryptographically random order
+func shuffledKeys(set string) []string {
+	keys := strings.Split(set, "")
+	for i := len(keys) - 1; i > 0; i-- {
+		j, _ := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		keys[i], keys[j.Int64()] = keys[j.Int64()], keys[i]
+	}
+	return keys
+}
+
+// showKeypad lets the user enter the password with a randomized on-screen keypad.
+// The result is written to the regular password entries, so all other checks apply unchanged.
+func (s *AppState) showKeypad(w fyne.Window) {
+	var typed []rune
+	masked := widget.NewLabel("")
+	targetSelect := widget.NewRadioGroup([]string{"Password", "Confirm", "Both"}, nil)
+	targetSelect.Horizontal = true
+	targetSelect.SetSelected("Both")
+	reshuffle := widget.NewCheck("Reshuffle after every key", nil)
+	reshuffle.SetChecked(true)
+
+	setName := "123"
+	grid := container.NewGridWithColumns(7)
+	var rebuild func()
+	update := func() { masked.SetText(strings.Repeat("●", len(typed))) }
+	rebuild = func() {
+		grid.RemoveAll()
+		for _, k := range shuffledKeys(keypadSets[setName]) {
+			grid.Add(widget.NewButton(k, func() {
+				s.touchActivity()
+				typed = append(typed, []rune(k)...)
+				update()
+				if reshuffle.Checked { rebuild() }
+			}))
+		}
+		grid.Refresh()
+	}
+	rebuild()
+
+	setSelect := widget.NewRadioGroup([]string{"123", "abc", "ABC", "#+="}, func(name string) {
+		if name == "" { return }
+		setName = name
+		rebuild()
+	})
+	setSelect.Horizontal = true
+	setSelect.SetSelected(setName)
+
+	wipe := func() {
+		for i := range typed { typed[i] = 0 }
+		typed = typed[:0]
+		update()
+	}
+	backBtn := widget.NewButton("⌫", func() {
+		if len(typed) > 0 { typed[len(typed)-1] = 0; typed = typed[:len(typed)-1]; update() }
+	})
+	clearBtn := widget.NewButton("Clear", wipe)
+	shuffleBtn := widget.NewButton("🔀 Shuffle", rebuild)
+
+	content := container.NewVBox(
+		targetSelect,
+		container.NewBorder(nil, nil, widget.NewLabel("Entered:"), nil, masked),
+		setSelect,
+		grid,
+		container.NewHBox(backBtn, clearBtn, shuffleBtn, reshuffle),
+	)
+	d := dialog.NewCustomConfirm("On-screen Keypad", "Use", "Cancel", content, func(ok bool) {
+		if ok {
+			pw := string(typed)
+			switch targetSelect.Selected {
+			case "Password":
+				s.passwordEntry.SetText(pw)
+			case "Confirm":
+				s.confirmPasswordEntry.SetText(pw)
+			default:
+				s.passwordEntry.SetText(pw)
+				s.confirmPasswordEntry.SetText(pw)
+			}
+		}
+		wipe()
+	}, w)
+	d.Resize(fyne.NewSize(520, 420))
+	d.Show()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/signing"
+)
+
+// showKeysScreen manages signing keypairs and imported public keys, and signs or verifies the selection;
+// further tabs hold the X25519 encryption keys and the contact book
+func (s *AppState) showKeysScreen(w fyne.Window) {
+	dir, err := signing.KeysDir()
+	if err != nil { dialog.ShowError(err, w); return }
+
+	var keys []*signing.Key
+	selected := -1
+	reload := func() {
+		keys, err = signing.List(dir)
+		if err != nil { dialog.ShowError(err, w) }
+	}
+	reload()
+
+	list := widget.NewList(
+		func() int { return len(keys) },
+		func() fyne.CanvasObject { return widget.NewLabel("key") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(keys) { return }
+			k := keys[id]
+			kind := "👤 public"
+			if k.HasPrivate() { kind = "🔐 keypair" }
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s  •  %s  •  %s", k.Name, k.IDString(), kind))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) { selected = id }
+	refresh := func() { reload(); selected = -1; list.UnselectAll(); list.Refresh() }
+	current := func() *signing.Key {
+		if selected < 0 || selected >= len(keys) { return nil }
+		return keys[selected]
+	}
+
+	generateBtn := widget.NewButton("Generate…", func() {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetPlaceHolder("e.g. release-2025")
+		dialog.ShowForm("Generate Keypair", "Generate", "Cancel", []*widget.FormItem{widget.NewFormItem("Name", nameEntry)}, func(ok bool) {
+			if !ok { return }
+			if _, err := signing.Generate(dir, strings.TrimSpace(nameEntry.Text)); err != nil { dialog.ShowError(err, w); return }
+			refresh()
+		}, w)
+	})
+	importBtn := widget.NewButton("Import public key…", func() {
+		nameEntry := widget.NewEntry()
+		keyEntry := widget.NewMultiLineEntry()
+		keyEntry.SetPlaceHolder("Paste a minisign public key")
+		keyEntry.SetMinRowsVisible(3)
+		dialog.ShowForm("Import Public Key", "Import", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Name", nameEntry),
+			widget.NewFormItem("Key", keyEntry),
+		}, func(ok bool) {
+			if !ok { return }
+			if _, err := signing.ImportPublic(dir, strings.TrimSpace(nameEntry.Text), keyEntry.Text); err != nil { dialog.ShowError(err, w); return }
+			refresh()
+		}, w)
+	})
+	copyBtn := widget.NewButton("Copy public key", func() {
+		k := current()
+		if k == nil { return }
+		w.Clipboard().SetContent(k.PublicKeyText())
+		s.statusLabel.SetText("📋 Public key " + k.Name + " copied")
+	})
+	deleteBtn := widget.NewButton("Delete", func() {
+		k := current()
+		if k == nil { return }
+		msg := fmt.Sprintf("Delete key %s?", k.Name)
+		if k.HasPrivate() { msg += "\nThe private key cannot be recovered; signatures made with it stay verifiable only with its public key." }
+		dialog.ShowConfirm("Delete Key", msg, func(ok bool) {
+			if !ok { return }
+			if err := signing.Delete(dir, k.Name); err != nil { dialog.ShowError(err, w); return }
+			refresh()
+		}, w)
+	})
+	signBtn := widget.NewButton("✍ Sign selected files", func() {
+		k := current()
+		if k == nil || !k.HasPrivate() {
+			dialog.ShowInformation("Sign", "Select one of your keypairs first.", w)
+			return
+		}
+		s.signSelection(w, k)
+	})
+	signBtn.Importance = widget.HighImportance
+	verifyBtn := widget.NewButton("✔ Verify selected file", func() { s.verifySelection(w, keys) })
+
+	signingTab := container.NewBorder(
+		widget.NewLabel("Detached ed25519 signatures in minisign format (.minisig)."),
+		container.NewVBox(
+			container.NewHBox(generateBtn, importBtn, copyBtn, deleteBtn),
+			container.NewHBox(signBtn, verifyBtn),
+		),
+		nil, nil,
+		list,
+	)
+	tabs := container.NewAppTabs(
+		container.NewTabItem("✍ Signing", signingTab),
+		container.NewTabItem("🔐 Encryption", s.encryptionKeysTab(w)),
+		container.NewTabItem("👥 Contacts", s.contactsTab(w)),
+	)
+	d := dialog.NewCustom("Keys", "Close", tabs, w)
+	d.Resize(fyne.NewSize(680, 480))
+	d.Show()
+}
+
+// signSelection writes a .minisig next to every selected file
+func (s *AppState) signSelection(w fyne.Window, k *signing.Key) {
+	var paths []string
+	if s.selectedPath != "" { paths = []string{s.selectedPath} } else { paths = s.selectedPaths }
+	if len(paths) == 0 {
+		dialog.ShowInformation("Sign", "Select the files to sign.", w)
+		return
+	}
+	go func() {
+		signed := 0
+		var errs []string
+		for _, p := range paths {
+			if fi, err := os.Stat(p); err != nil || fi.IsDir() {
+				errs = append(errs, p+": not a file")
+				continue
+			}
+			if _, err := signing.SignFile(p, k); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", p, err))
+				continue
+			}
+			signed++
+		}
+		s.ui(func() {
+			s.statusLabel.SetText(fmt.Sprintf("✍ %d file(s) signed with %s", signed, k.Name))
+			if len(errs) > 0 { dialog.ShowError(fmt.Errorf("%s", strings.Join(errs, "\n")), w) }
+		})
+	}()
+}
+
+// verifySelection checks the selected file against its .minisig using the known keys
+func (s *AppState) verifySelection(w fyne.Window, keys []*signing.Key) {
+	if s.selectedPath == "" {
+		dialog.ShowInformation("Verify", "Select a single signed file.", w)
+		return
+	}
+	path := s.selectedPath
+	sigPath := path + signing.SignatureExtension
+	if _, err := os.Stat(sigPath); err != nil {
+		dialog.ShowInformation("Verify", "No signature found at\n"+sigPath, w)
+		return
+	}
+	go func() {
+		v, err := signing.VerifyFile(path, sigPath, keys)
+		s.ui(func() {
+			if err != nil {
+				s.statusLabel.SetText("❌ Signature invalid")
+				dialog.ShowError(err, w)
+				return
+			}
+			s.statusLabel.SetText("✅ Signature valid (" + v.Key.Name + ")")
+			dialog.ShowInformation("Signature Valid", fmt.Sprintf("Signed by %s (%s)\n\n%s", v.Key.Name, v.Key.IDString(), v.TrustedComment), w)
+		})
+	}()
+}
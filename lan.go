@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/lanshare"
+	"github.com/bangundwir/HadesCrypt/internal/wormhole"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// downloadDir is where received and downloaded files land: the output folder, else ~/Downloads, else home
+func (s *AppState) downloadDir() string {
+	if dir := s.opt().OutputDir; dir != "" { return dir }
+	home, err := os.UserHomeDir()
+	if err != nil { return os.TempDir() }
+	if fi, err := os.Stat(filepath.Join(home, "Downloads")); err == nil && fi.IsDir() { return filepath.Join(home, "Downloads") }
+	return home
+}
+
+// showLANDialog sends the selected files to another HadesCrypt on the network, or receives
+// from one; the devices pair with a short code shown by the receiver. The code phrase tab
+// does the same over the internet through a relay.
+func (s *AppState) showLANDialog(w fyne.Window) {
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := widget.NewProgressBar()
+	progress.Hide()
+	onProgress := func(name string, done, total int64) {
+		s.ui(func() {
+			progress.Show()
+			if total > 0 { progress.SetValue(float64(done) / float64(total)) } else { progress.SetValue(1) }
+		})
+	}
+
+	// receive
+	codeLabel := widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Bold: true, Monospace: true})
+	receiveStatus := widget.NewLabel("")
+	receiveStatus.Wrapping = fyne.TextWrapWord
+	var receiveBtn *widget.Button
+	receiveBtn = widget.NewButton("📥 Start receiving", func() {
+		name, _ := os.Hostname()
+		if name == "" { name = "HadesCrypt" }
+		dir := s.downloadDir()
+		r, err := lanshare.Listen(name, dir)
+		if err != nil { receiveStatus.SetText("⚠️ " + err.Error()); return }
+		receiveBtn.Disable()
+		codeLabel.SetText("Pairing code:  " + r.Code())
+		receiveStatus.SetText(fmt.Sprintf("Waiting as %q — saving to %s\nAddress: %s", name, dir, strings.Join(r.Addrs(), ", ")))
+		go func() {
+			paths, err := r.Receive(ctx, onProgress)
+			s.ui(func() {
+				receiveBtn.Enable()
+				codeLabel.SetText("")
+				switch {
+				case err != nil && ctx.Err() != nil:
+					return
+				case err != nil:
+					receiveStatus.SetText("❌ " + err.Error())
+				default:
+					var names []string
+					for _, p := range paths { names = append(names, filepath.Base(p)) }
+					receiveStatus.SetText(fmt.Sprintf("✅ Received %d file(s):\n%s", len(paths), strings.Join(names, "\n")))
+					s.statusLabel.SetText(fmt.Sprintf("📥 Received %d file(s) into %s", len(paths), dir))
+				}
+			})
+		}()
+	})
+	receiveTab := container.NewVBox(
+		widget.NewLabel("Receive files from another HadesCrypt on this network. Tell the sender the pairing code."),
+		receiveBtn,
+		codeLabel,
+		receiveStatus,
+	)
+
+	// send
+	var peers []lanshare.Peer
+	peerSelect := widget.NewSelect(nil, nil)
+	peerSelect.PlaceHolder = "Nearby devices…"
+	addrEntry := widget.NewEntry()
+	addrEntry.SetPlaceHolder("or address, e.g. 192.168.1.20:41234")
+	peerSelect.OnChanged = func(name string) {
+		for _, p := range peers {
+			if p.Name+"  ("+p.Addr+")" == name { addrEntry.SetText(p.Addr) }
+		}
+	}
+	sendStatus := widget.NewLabel("")
+	sendStatus.Wrapping = fyne.TextWrapWord
+	var scanBtn *widget.Button
+	scan := func() {
+		scanBtn.Disable()
+		sendStatus.SetText("⏳ Looking for devices…")
+		go func() {
+			found, err := lanshare.Discover(ctx, 3*time.Second)
+			s.ui(func() {
+				scanBtn.Enable()
+				peers = found
+				var names []string
+				for _, p := range found { names = append(names, p.Name+"  ("+p.Addr+")") }
+				peerSelect.SetOptions(names)
+				switch {
+				case err != nil && ctx.Err() == nil:
+					sendStatus.SetText("⚠️ Discovery failed: " + err.Error() + "\nEnter the receiver's address instead.")
+				case len(found) == 0:
+					sendStatus.SetText("No devices found. Start receiving on the other device, or enter its address.")
+				default:
+					sendStatus.SetText(fmt.Sprintf("%d device(s) found", len(found)))
+				}
+			})
+		}()
+	}
+	scanBtn = widget.NewButton("🔄 Scan", scan)
+	codeEntry := widget.NewEntry()
+	codeEntry.SetPlaceHolder("1234-5678")
+	var sendBtn *widget.Button
+	sendBtn = widget.NewButton("📤 Send selection", func() {
+		files := s.lanSelectedFiles()
+		addr := strings.TrimSpace(addrEntry.Text)
+		switch {
+		case len(files) == 0:
+			sendStatus.SetText("Select one or more files first (folders are not supported).")
+			return
+		case addr == "":
+			sendStatus.SetText("Choose a device or enter its address.")
+			return
+		case strings.TrimSpace(codeEntry.Text) == "":
+			sendStatus.SetText("Enter the pairing code shown on the receiver.")
+			return
+		}
+		sendBtn.Disable()
+		sendStatus.SetText(fmt.Sprintf("⏳ Sending %d file(s) to %s…", len(files), addr))
+		go func() {
+			var total int64
+			for _, f := range files {
+				if fi, err := os.Stat(f); err == nil { total += fi.Size() }
+			}
+			err := lanshare.Send(ctx, addr, codeEntry.Text, files, onProgress)
+			s.ui(func() {
+				sendBtn.Enable()
+				if err != nil {
+					if ctx.Err() == nil { sendStatus.SetText("❌ " + err.Error()) }
+					return
+				}
+				sendStatus.SetText(fmt.Sprintf("✅ Sent %d file(s), %s", len(files), uiutil.HumanBytes(total)))
+				s.statusLabel.SetText(fmt.Sprintf("📤 Sent %d file(s) to %s", len(files), addr))
+			})
+		}()
+	})
+	sendBtn.Importance = widget.HighImportance
+	sendTab := container.NewVBox(
+		widget.NewLabel("Send the selected files to a device that is receiving."),
+		container.NewBorder(nil, nil, nil, scanBtn, peerSelect),
+		addrEntry,
+		widget.NewForm(widget.NewFormItem("Pairing code", codeEntry)),
+		sendBtn,
+		sendStatus,
+	)
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem("📤 Send", sendTab),
+		container.NewTabItem("📥 Receive", receiveTab),
+		container.NewTabItem("🌐 Code phrase", s.wormholeTab(ctx, onProgress)),
+	)
+	d := dialog.NewCustom("📡 Send to Device", "Close", container.NewBorder(nil, progress, nil, nil, tabs), w)
+	d.SetOnClosed(cancel)
+	d.Resize(fyne.NewSize(560, 440))
+	d.Show()
+	scan()
+}
+
+// lanSelectedFiles returns the selected regular files
+func (s *AppState) lanSelectedFiles() []string {
+	paths := s.selectedPaths
+	if s.selectedPath != "" { paths = []string{s.selectedPath} }
+	var files []string
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() { files = append(files, p) }
+	}
+	return files
+}
+
+// wormholeTab sends the selection under a fresh code phrase, or receives with a typed one,
+// through the configured relay
+func (s *AppState) wormholeTab(ctx context.Context, onProgress func(name string, done, total int64)) fyne.CanvasObject {
+	relayEntry := widget.NewEntry()
+	relayEntry.SetPlaceHolder("relay.example.org:" + wormhole.DefaultRelayPort)
+	relayEntry.SetText(s.config.RelayAddr)
+	relayEntry.OnChanged = func(text string) {
+		text = strings.TrimSpace(text)
+		if text == s.config.RelayAddr { return }
+		s.config.RelayAddr = text
+		s.config.Save()
+	}
+	relay := func() (wormhole.Relay, bool) {
+		return wormhole.Relay{Addr: s.config.RelayAddr}, s.config.RelayAddr != ""
+	}
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+	codeLabel := widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Bold: true, Monospace: true})
+
+	var sendBtn, receiveBtn *widget.Button
+	busy := func(b bool) {
+		if b { sendBtn.Disable(); receiveBtn.Disable() } else { sendBtn.Enable(); receiveBtn.Enable() }
+	}
+	sendBtn = widget.NewButton("📤 Send selection", func() {
+		files := s.lanSelectedFiles()
+		ch, ok := relay()
+		switch {
+		case !ok:
+			status.SetText("Enter the address of a hadescrypt-relay server first.")
+			return
+		case len(files) == 0:
+			status.SetText("Select one or more files first (folders are not supported).")
+			return
+		}
+		busy(true)
+		status.SetText("⏳ Reserving a code…")
+		go func() {
+			offer, err := wormhole.NewOffer(ctx, ch)
+			if err != nil {
+				s.ui(func() { busy(false); if ctx.Err() == nil { status.SetText("❌ " + err.Error()) } })
+				return
+			}
+			s.ui(func() {
+				codeLabel.SetText(offer.Code)
+				status.SetText(fmt.Sprintf("Tell the receiver this code. Waiting to send %d file(s)…", len(files)))
+			})
+			err = offer.Send(ctx, files, onProgress)
+			s.ui(func() {
+				busy(false)
+				codeLabel.SetText("")
+				switch {
+				case err != nil && ctx.Err() != nil:
+				case err != nil:
+					status.SetText("❌ " + err.Error())
+				default:
+					status.SetText(fmt.Sprintf("✅ Sent %d file(s)", len(files)))
+					s.statusLabel.SetText(fmt.Sprintf("📤 Sent %d file(s) by code phrase", len(files)))
+				}
+			})
+		}()
+	})
+	sendBtn.Importance = widget.HighImportance
+
+	codeEntry := widget.NewEntry()
+	codeEntry.SetPlaceHolder("42-copper-willow")
+	receiveBtn = widget.NewButton("📥 Receive", func() {
+		ch, ok := relay()
+		if !ok { status.SetText("Enter the address of a hadescrypt-relay server first."); return }
+		if _, _, err := wormhole.ParseCode(codeEntry.Text); err != nil { status.SetText("⚠️ " + err.Error()); return }
+		dir := s.downloadDir()
+		busy(true)
+		status.SetText("⏳ Connecting…")
+		go func() {
+			paths, err := wormhole.Receive(ctx, ch, codeEntry.Text, dir, onProgress)
+			s.ui(func() {
+				busy(false)
+				switch {
+				case err != nil && ctx.Err() != nil:
+				case err != nil:
+					msg := "❌ " + err.Error()
+					if len(paths) > 0 || !errors.Is(err, wormhole.ErrWrongCode) { msg += "\nUnfinished files resume when the sender sends them again." }
+					status.SetText(msg)
+				default:
+					var names []string
+					for _, p := range paths { names = append(names, filepath.Base(p)) }
+					status.SetText(fmt.Sprintf("✅ Received %d file(s) into %s:\n%s", len(paths), dir, strings.Join(names, "\n")))
+					s.statusLabel.SetText(fmt.Sprintf("📥 Received %d file(s) into %s", len(paths), dir))
+				}
+			})
+		}()
+	})
+
+	intro := widget.NewLabel("Send over the internet: the sender gets a code phrase, the receiver types it. Files are end-to-end encrypted; the relay only passes ciphertext.")
+	intro.Wrapping = fyne.TextWrapWord
+	return container.NewVBox(
+		intro,
+		widget.NewForm(widget.NewFormItem("Relay", relayEntry)),
+		sendBtn,
+		codeLabel,
+		widget.NewSeparator(),
+		widget.NewForm(widget.NewFormItem("Code", codeEntry)),
+		receiveBtn,
+		status,
+	)
+}
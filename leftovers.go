@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/orphans"
+	"github.com/bangundwir/HadesCrypt/internal/store"
+	"github.com/bangundwir/HadesCrypt/internal/timefmt"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// leftoverMinAge keeps the scan away from files a running job (here or in another window) still writes
+const leftoverMinAge = 15 * time.Minute
+
+// leftoverHistory is how many recent operations name folders worth checking
+const leftoverHistory = 200
+
+// leftoverDirs are the folders interrupted runs are likely to have left files in:
+// the temp folder, the settings folder, and where recent and pending jobs read and wrote
+func (s *AppState) leftoverDirs() []string {
+	var dirs []string
+	add := func(dir string) {
+		if dir != "" && dir != "." && !slices.Contains(dirs, dir) { dirs = append(dirs, dir) }
+	}
+	add(os.TempDir())
+	if d, err := config.GetConfigDir(); err == nil { add(d) }
+	paths := s.selection()
+	if sess := s.config.Session; sess != nil {
+		paths = append(paths, sess.SelectedPath)
+		paths = append(paths, sess.SelectedPaths...)
+		if sess.Pending != nil { paths = append(paths, sess.Pending.Remaining...) }
+	}
+	if records != nil {
+		recent, _ := records.History(store.HistoryFilter{}, leftoverHistory)
+		for _, e := range recent { paths = append(paths, e.Output) }
+	}
+	for _, p := range paths {
+		if p != "" { add(filepath.Dir(p)) }
+	}
+	return dirs
+}
+
+// scanLeftovers looks for files interrupted runs left behind and offers to destroy them.
+// At startup it checks the leftoverDirs quietly; on demand it also searches the
+// selected folders and says so when nothing turned up.
+func (s *AppState) scanLeftovers(w fyne.Window, onDemand bool) {
+	dirs := s.leftoverDirs()
+	var deep []string
+	if onDemand {
+		for _, p := range s.selection() {
+			if fi, err := os.Stat(p); err == nil && fi.IsDir() { deep = append(deep, p) }
+		}
+		s.statusLabel.SetText("🧽 Looking for leftover temporary files…")
+	}
+	go func() {
+		found := orphans.Scan(dirs, orphans.Options{MinAge: leftoverMinAge})
+		for _, o := range orphans.Scan(deep, orphans.Options{Recursive: true, MinAge: leftoverMinAge}) {
+			if !slices.ContainsFunc(found, func(f orphans.Orphan) bool { return f.Path == o.Path }) { found = append(found, o) }
+		}
+		s.ui(func() {
+			if onDemand { s.statusLabel.SetText(fmt.Sprintf("🧽 %d leftover(s) found", len(found))) }
+			if len(found) > 0 {
+				s.showLeftovers(w, found)
+			} else if onDemand {
+				dialog.ShowInformation("Leftover files", "No temporary or partial files from interrupted runs were found.", w)
+			}
+		})
+	}()
+}
+
+// showLeftovers lists leftovers with their sizes, all ticked, and destroys the ticked ones
+func (s *AppState) showLeftovers(w fyne.Window, found []orphans.Orphan) {
+	skip := make([]bool, len(found))
+	var total int64
+	for _, o := range found { total += o.Size }
+	list := container.NewVBox()
+	var deleteBtn *widget.Button
+	update := func() {
+		n := 0
+		for _, k := range skip { if !k { n++ } }
+		deleteBtn.SetText(fmt.Sprintf("Securely delete %d", n))
+		if n == 0 { deleteBtn.Disable() } else { deleteBtn.Enable() }
+	}
+	for i, o := range found {
+		name := o.Path
+		if o.Dir { name += string(filepath.Separator) }
+		c := widget.NewCheck(fmt.Sprintf("%s\n%s, %s, %s", name, o.Kind, uiutil.HumanBytes(o.Size), timefmt.Relative(o.ModTime, time.Now())), nil)
+		c.SetChecked(true)
+		c.OnChanged = func(on bool) { skip[i] = !on; update() }
+		list.Add(c)
+	}
+
+	var d dialog.Dialog
+	deleteBtn = widget.NewButton("", func() {
+		d.Hide()
+		var chosen []orphans.Orphan
+		for i, o := range found {
+			if !skip[i] { chosen = append(chosen, o) }
+		}
+		s.removeLeftovers(w, chosen)
+	})
+	deleteBtn.Importance = widget.DangerImportance
+	update()
+	header := widget.NewLabel(fmt.Sprintf("Interrupted runs left %d temporary or partial item(s), %s in total. They can hold plaintext, so they are shredded rather than just deleted. This cannot be undone.", len(found), uiutil.HumanBytes(total)))
+	header.Wrapping = fyne.TextWrapWord
+	d = dialog.NewCustom("🧽 Leftover files", "Keep", container.NewBorder(header, deleteBtn, nil, nil, container.NewVScroll(list)), w)
+	d.Resize(fyne.NewSize(640, 420))
+	d.Show()
+}
+
+// removeLeftovers shreds the chosen leftovers in the background
+func (s *AppState) removeLeftovers(w fyne.Window, chosen []orphans.Orphan) {
+	go func() {
+		var firstErr error
+		removed := 0
+		for i, o := range chosen {
+			s.setStatus(fmt.Sprintf("🧽 Removing %d/%d %s", i+1, len(chosen), filepath.Base(o.Path)))
+			if err := orphans.Remove(o); err != nil {
+				if firstErr == nil { firstErr = fmt.Errorf("%s: %w", o.Path, err) }
+				continue
+			}
+			removed++
+		}
+		recordAudit("leftovers-removed", fmt.Sprintf("%d of %d", removed, len(chosen)))
+		s.ui(func() {
+			s.statusLabel.SetText(fmt.Sprintf("✅ Removed %d leftover(s)", removed))
+			if firstErr != nil { dialog.ShowError(firstErr, w) }
+		})
+	}()
+}
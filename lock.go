@@ -0,0 +1,173 @@
+package main
+
+import (
+	"image/color"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/applock"
+)
+
+// activityCatcher is a transparent background that records pointer movement for idle detection
+type activityCatcher struct {
+	widget.BaseWidget
+	onActivity func()
+}
+
+func newActivityCatcher(onActivity func()) *activityCatcher {
+	a := &activityCatcher{onActivity: onActivity}
+	a.ExtendBaseWidget(a)
+	return a
+}
+
+func (a *activityCatcher) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(canvas.NewRectangle(color.Transparent))
+}
+
+func (a *activityCatcher) MouseIn(*desktop.MouseEvent)    { a.onActivity() }
+func (a *activityCatcher) MouseMoved(*desktop.MouseEvent) { a.onActivity() }
+func (a *activityCatcher) MouseOut()                      {}
+
+// touchActivity resets the idle timer
+func (s *AppState) touchActivity() { s.lastActivity.Store(time.Now().UnixNano()) }
+
+// hasMasterPassword reports whether the app lock is configured
+func (s *AppState) hasMasterPassword() bool { return s.config.MasterPasswordHash != "" }
+
+// setupAppLock registers Ctrl+L, starts the idle watcher and locks immediately when a master password exists
+func (s *AppState) setupAppLock(w fyne.Window) {
+	s.touchActivity()
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyL, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) {
+		if s.hasMasterPassword() {
+			s.lockApp(w)
+		} else {
+			s.showAppLockSettings(w)
+		}
+	})
+
+	go func() {
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			if s.closed.Load() { return }
+			mins := s.config.AutoLockMinutes
+			if mins <= 0 || !s.hasMasterPassword() { continue }
+			idle := time.Since(time.Unix(0, s.lastActivity.Load()))
+			if idle >= time.Duration(mins)*time.Minute {
+				s.ui(func() { s.lockApp(w) })
+			}
+		}
+	}()
+
+	if s.hasMasterPassword() { s.lockApp(w) }
+}
+
+// lockApp replaces the window content with the unlock screen. Passwords, keyfiles,
+// the post-quantum key, selection and the unlocked search index are dropped from memory.
+func (s *AppState) lockApp(w fyne.Window) {
+	if s.locked { return }
+	s.locked = true
+	recordAudit("locked", "")
+	s.mainContent = w.Content()
+
+	s.passwordEntry.SetText("")
+	s.confirmPasswordEntry.SetText("")
+	s.keyfileManager.Clear()
+	s.updateKeyfilesDisplay()
+	s.setPQKey(nil)
+	s.setSelectedFile("")
+	s.lockIndex()
+	s.stopPlayback()
+
+	pwEntry := widget.NewPasswordEntry()
+	pwEntry.SetPlaceHolder("Master password…")
+	errLabel := widget.NewLabel("")
+	unlock := func() {
+		if !applock.Verify(pwEntry.Text, s.config.MasterPasswordHash, s.config.MasterPasswordSalt) {
+			errLabel.SetText("❌ Wrong master password")
+			pwEntry.SetText("")
+			recordAudit("unlock-failed", "")
+			return
+		}
+		recordAudit("unlocked", "")
+		s.locked = false
+		s.touchActivity()
+		w.SetContent(s.mainContent)
+	}
+	pwEntry.OnSubmitted = func(string) { unlock() }
+	unlockBtn := widget.NewButton("🔓 Unlock", unlock)
+	unlockBtn.Importance = widget.HighImportance
+
+	form := container.NewVBox(
+		widget.NewLabelWithStyle("HadesCrypt 🔱 is locked", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		pwEntry,
+		unlockBtn,
+		errLabel,
+	)
+	w.SetContent(container.NewCenter(container.NewGridWrap(fyne.NewSize(340, 200), form)))
+	w.Canvas().Focus(pwEntry)
+}
+
+// showAppLockSettings sets, changes or removes the master password and the idle timeout
+func (s *AppState) showAppLockSettings(w fyne.Window) {
+	currentEntry := widget.NewPasswordEntry()
+	newEntry := widget.NewPasswordEntry()
+	confirmEntry := widget.NewPasswordEntry()
+	removeCheck := widget.NewCheck("Remove master password", nil)
+
+	lockOptions := []string{"Off", "1", "5", "15", "30", "60"}
+	autoLock := widget.NewSelect(lockOptions, nil)
+	autoLock.SetSelected("Off")
+	if s.config.AutoLockMinutes > 0 { autoLock.SetSelected(strconv.Itoa(s.config.AutoLockMinutes)) }
+
+	var items []*widget.FormItem
+	if s.hasMasterPassword() {
+		newEntry.SetPlaceHolder("Leave empty to keep")
+		items = append(items, widget.NewFormItem("Current", currentEntry))
+	}
+	items = append(items,
+		widget.NewFormItem("New password", newEntry),
+		widget.NewFormItem("Confirm", confirmEntry),
+		widget.NewFormItem("Auto-lock (min)", autoLock),
+	)
+	if s.hasMasterPassword() { items = append(items, widget.NewFormItem("", removeCheck)) }
+
+	dialog.ShowForm("App Lock", "Save", "Cancel", items, func(ok bool) {
+		if !ok { return }
+		if s.hasMasterPassword() && !applock.Verify(currentEntry.Text, s.config.MasterPasswordHash, s.config.MasterPasswordSalt) {
+			dialog.ShowInformation("App Lock", "Current master password is wrong.", w)
+			return
+		}
+		if newEntry.Text != confirmEntry.Text {
+			dialog.ShowInformation("App Lock", "New password and confirmation do not match.", w)
+			return
+		}
+		if !s.hasMasterPassword() && newEntry.Text == "" {
+			dialog.ShowInformation("App Lock", "Please enter a master password.", w)
+			return
+		}
+		if removeCheck.Checked {
+			s.config.MasterPasswordHash, s.config.MasterPasswordSalt = "", ""
+			recordAudit("master-password-removed", "")
+		} else if newEntry.Text != "" {
+			hash, salt, err := applock.Hash(newEntry.Text)
+			if err != nil { dialog.ShowError(err, w); return }
+			s.config.MasterPasswordHash, s.config.MasterPasswordSalt = hash, salt
+			recordAudit("master-password-set", "")
+		}
+		s.config.AutoLockMinutes, _ = strconv.Atoi(autoLock.Selected)
+		s.config.Save()
+		if s.hasMasterPassword() {
+			s.statusLabel.SetText("🔐 App lock enabled • Ctrl+L locks now")
+		} else {
+			s.statusLabel.SetText("App lock disabled")
+		}
+	}, w)
+}
@@ -3,27 +3,49 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"io/fs"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
     "strings"
+    "sync"
     "time"
 
     "fyne.io/fyne/v2"
     "fyne.io/fyne/v2/app"
     "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/data/binding"
     "fyne.io/fyne/v2/dialog"
-    "fyne.io/fyne/v2/theme"
+    "fyne.io/fyne/v2/driver/desktop"
     "fyne.io/fyne/v2/widget"
 
 	"io"
-	"encoding/binary"
 	"sync/atomic"
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
 	"github.com/bangundwir/HadesCrypt/internal/archiver"
+	"github.com/bangundwir/HadesCrypt/internal/avscan"
 	"github.com/bangundwir/HadesCrypt/internal/config"
 	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/editsession"
+	"github.com/bangundwir/HadesCrypt/internal/elevate"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+	"github.com/bangundwir/HadesCrypt/internal/gnupg"
 	"github.com/bangundwir/HadesCrypt/internal/keyfiles"
+	"github.com/bangundwir/HadesCrypt/internal/operations"
+	"github.com/bangundwir/HadesCrypt/internal/policy"
+	"github.com/bangundwir/HadesCrypt/internal/progress"
+	"github.com/bangundwir/HadesCrypt/internal/searchindex"
+	"github.com/bangundwir/HadesCrypt/internal/throttle"
+	"github.com/bangundwir/HadesCrypt/internal/timestamp"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+	"github.com/bangundwir/HadesCrypt/internal/securetemp"
+	"github.com/bangundwir/HadesCrypt/internal/splitter"
+	"github.com/bangundwir/HadesCrypt/internal/playback"
+	"github.com/bangundwir/HadesCrypt/internal/syncfolder"
 	pw "github.com/bangundwir/HadesCrypt/internal/password"
 	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
 )
@@ -42,6 +64,10 @@ type AppState struct {
 	progressBar         *widget.ProgressBar
 	statusLabel         *widget.Label
 	fileInfoLabel       *widget.Label
+	fileInfoActivity    *widget.Activity // spins while the selection's details load
+	fileInfoGen         uint64           // bumped per selection; UI goroutine only
+	outputEstimateLabel *widget.Label    // expected output size of the selection
+	sizeBasis           *sizeBasis       // what the preview is computed from; UI goroutine only
 	dragDropLabel       *widget.Label
 	commentsEntry       *widget.Entry
 	passwordEntry       *widget.Entry
@@ -53,31 +79,114 @@ type AppState struct {
 	keyfileManager   *keyfiles.KeyfileManager
 	keyfilesList     *widget.List
 	keyfilesLabel    *widget.Label
+
+	// Post-quantum key the Kyber-768 and Hybrid PQ modes encrypt to (see pqkey.go)
+	pqKey            *cryptoengine.KEMKey
+	pqKeyLabel       *widget.Label
+	forgetPQKey      func() // unloads pqKey from the decryption keyring
 	
 	// Encryption mode
 	encryptionMode   cryptoengine.EncryptionMode
 	
 	// Advanced options
+	options          *optionsModel // bound to the Advanced panel controls; read with opt()
 	deleteAfter      bool
-	useKeyfiles      bool
 	cancelRequested  atomic.Bool
-	paranoidMode     bool
-	reedSolomon      bool
-	forceDecrypt     bool
-	splitOutput      bool
-	splitSize        int
-	splitUnit        string
-	compressFiles    bool
-	deniabilityMode  bool
-	recursiveMode    bool
+	allOrNothing     bool // multi-selection encrypts publish outputs only if every item succeeds
+	profileGPGPath   string // gpg binary override from the applied profile
+	gpgStatusLabel   *widget.Label
+	gpgRecipients    []string // fingerprints; GnuPG mode encrypts to these instead of the password
+	keyring          *gnupg.Keyring
+	keyringPath      string
+	recipientsLabel  *widget.Label
+	indexEnabled     bool
+	sessionReady     bool // set once the saved session was restored; guards saveSession during setup
+
+	// Encrypted search index (nil while locked); encryptions add to it from their goroutines
+	indexMu          sync.Mutex // guards index and indexPassword
+	index            *searchindex.Index
+	indexPassword    []byte
+
+	// Open-for-editing working copies
+	editSessions     []*editsession.Session
+
+	// Loopback server streaming media to the system player (nil until first Play)
+	playback         *playback.Server
+	playbackMu       sync.Mutex
+
+	// Running sync folders, keyed by syncKey
+	syncPairs        map[string]*syncfolder.Pair
+
+	// Administrator policy (nil when unmanaged); a policy that fails to load blocks encryption
+	adminPolicy      *policy.Policy
+	adminPolicyErr   error
+	viewer           bool // decrypt and verify only (viewer build, --viewer or the policy)
+
+	encryptionModeSelect *widget.Select
+
+	// App lock
+	locked           bool
+	mainContent      fyne.CanvasObject
+	lastActivity     atomic.Int64
+	closed           atomic.Bool // window closed; stops background watchers
+	busy             atomic.Bool // an encrypt/decrypt job is running
+	primary          bool        // first window; owns the saved session
+	mediaAcknowledged bool       // user confirmed which sources the job being started deletes, and where
+	limitAcknowledged bool       // user saw that an output exceeds its destination's file size limit
 
 	// UX enhancements
 	progressLastTime time.Time
 	progressLastVal  float64
+	perfMeter        atomic.Pointer[progress.Meter] // rates of the running job, nil between jobs; see perf.go
+	perfStop         chan struct{}
+	perfPanel        *fyne.Container
+	perfLabel, perfHint *widget.Label
 
 	opSummary        *OperationSummary
 }
 
+// allModeOptions lists the entries of the encryption mode selector
+var allModeOptions = []string{
+	"AES-256-GCM",
+	"ChaCha20-Poly1305",
+	"Paranoid (AES-256 + ChaCha20)",
+	"🛡️ Post-Quantum: Kyber-768",
+	"🛡️ Hybrid PQ (Argon2id + ML-KEM-768)",
+	"🔐 GnuPG/OpenPGP (Standard)",
+}
+
+// complianceModeOptions is the selector content while compliance mode is on
+var complianceModeOptions = []string{"AES-256-GCM"}
+
+// encryptOptions returns the engine options for the current UI state
+func (s *AppState) encryptOptions() cryptoengine.EncryptionOptions {
+	opts := cryptoengine.EncryptionOptions{Mode: s.encryptionMode, Compliance: s.config.ComplianceMode, Argon2: cryptoengine.Argon2Preset(s.opt().Argon2Preset), GPGPath: s.gpgPath(), Comments: s.comments, UseCompression: s.opt().Compress, CompressionLevel: s.opt().CompressionLevel, AdaptiveChunks: s.config.AdaptiveChunks, UseReedSolomon: s.opt().ReedSolomon, UseDeniability: s.opt().Deniability, KEMKey: s.pqKey}
+	// Paranoid Mode replaces the selected HadesCrypt mode; GnuPG output is up to gpg
+	if s.opt().Paranoid && !opts.Compliance && opts.Mode != cryptoengine.ModeGnuPG { opts.Mode = cryptoengine.ModeParanoidSerpent }
+	if s.usesRecipients() { opts.Recipients = s.gpgRecipients }
+	return opts
+}
+
+// encryptOptionsFor returns the engine options for encrypting src, keeping its exact
+// name in the header when the output name is rewritten for the target
+func (s *AppState) encryptOptionsFor(src string) cryptoengine.EncryptionOptions {
+	opts := s.encryptOptions()
+	opts.Metadata.Name = s.ops().OriginalName(src)
+	return opts
+}
+
+// applyComplianceMode restricts (or restores) the encryption mode list
+func (s *AppState) applyComplianceMode(on bool) {
+	if s.encryptionModeSelect == nil { return }
+	if on {
+		s.encryptionModeSelect.SetOptions(s.allowedModeOptions(complianceModeOptions))
+		s.encryptionModeSelect.SetSelected("AES-256-GCM")
+	} else {
+		s.encryptionModeSelect.SetOptions(s.allowedModeOptions(allModeOptions))
+	}
+	if opts := s.encryptionModeSelect.Options; !slices.Contains(opts, s.encryptionModeSelect.Selected) && len(opts) > 0 { s.encryptionModeSelect.SetSelected(opts[0]) }
+}
+
 // OperationSummary captures metrics of a completed operation batch
 type OperationSummary struct {
 	Operation      string
@@ -89,14 +198,44 @@ type OperationSummary struct {
 	Errors         int
 	Canceled       bool
 	FirstError     string
+	FirstCode      apperr.Code
+	Skipped        []string // unreadable files passed by, "path: reason"
+	AccessDenied   bool     // the first error was an access denial that administrator rights may overcome
+	Outputs        []string // files and folders written, offered for revealing or copying
+	Executables    []flaggedOutput // decrypted files that are programs or scripts
+	AVScanner      string          // antivirus that checked the outputs; empty when scanning is off
+	AVResults      []avscan.Result
+	AVError        string          // why the configured scan could not run
 }
 
-func (s *AppState) startOpSummary(op string) { s.opSummary = &OperationSummary{Operation: op, Start: time.Now()} }
+func (s *AppState) startOpSummary(op string) { s.busy.Store(true); s.opSummary = &OperationSummary{Operation: op, Start: time.Now()}; s.startPerf() }
 func (s *AppState) addFile(size int64) { if s.opSummary!=nil { s.opSummary.Files++; s.opSummary.TotalBytes += size } }
 func (s *AppState) addFolder(size int64) { if s.opSummary!=nil { s.opSummary.Folders++; s.opSummary.TotalBytes += size } }
-func (s *AppState) noteError(err error) { if s.opSummary!=nil { s.opSummary.Errors++; if s.opSummary.FirstError=="" && err!=nil { s.opSummary.FirstError = err.Error() } } }
+func (s *AppState) noteError(err error) { if s.opSummary!=nil { s.opSummary.Errors++; if s.opSummary.FirstError=="" && err!=nil { s.opSummary.FirstError = secret.Scrub(err.Error(), []byte(s.password)); s.opSummary.FirstCode = apperr.Classify(err); s.opSummary.AccessDenied = elevate.Denied(err) } } }
+func (s *AppState) noteSkipped(path string, err error) {
+	if s.opSummary == nil { return }
+	var pe *fs.PathError
+	if errors.As(err, &pe) { err = pe.Err }
+	s.opSummary.Skipped = append(s.opSummary.Skipped, fmt.Sprintf("%s: %v", path, err))
+}
+// skippedNote is appended to a finished job's status when unreadable files were skipped
+func (s *AppState) skippedNote() string {
+	if s.opSummary == nil || len(s.opSummary.Skipped) == 0 { return "" }
+	return fmt.Sprintf(" — ⚠️ %d unreadable file(s) skipped", len(s.opSummary.Skipped))
+}
+func (s *AppState) addOutput(path string) { if s.opSummary != nil { s.opSummary.Outputs = append(s.opSummary.Outputs, path) } }
 func (s *AppState) markCanceled() { if s.opSummary!=nil { s.opSummary.Canceled = true } }
-func (s *AppState) finishSummary() *OperationSummary { if s.opSummary!=nil { s.opSummary.End = time.Now(); return s.opSummary }; return nil }
+func (s *AppState) finishSummary() *OperationSummary {
+	defer s.busy.Store(false)
+	s.stopPerf()
+	if s.opSummary == nil { return nil }
+	if s.opSummary.Operation == "decrypt" {
+		s.opSummary.Executables = flagExecutables(s.opSummary.Outputs)
+		s.scanOutputs(s.opSummary)
+	}
+	s.opSummary.End = time.Now()
+	return s.opSummary
+}
 
 // Throttled progress update to reduce UI churn
 func (s *AppState) setProgressFraction(f float64) {
@@ -115,39 +254,30 @@ func (s *AppState) showSummaryDialog(w fyne.Window, sum *OperationSummary) {
 	if sum == nil { return }
 	dur := sum.End.Sub(sum.Start)
 	speed := "-"
-	if dur > 0 && sum.TotalBytes > 0 { speed = fmt.Sprintf("%s/s", uiutil.HumanBytes(int64(float64(sum.TotalBytes)/dur.Seconds()))) }
+	if dur > 0 && sum.TotalBytes > 0 { speed = uiutil.HumanRate(float64(sum.TotalBytes) / dur.Seconds()) }
 	status := "✅ Success"
 	if sum.Canceled { status = "⚠️ Canceled" }
+	if len(sum.Skipped) > 0 { status = "⚠️ Done, some files skipped" }
 	if sum.Errors > 0 { status = "❌ Partial" }
+	if sum.threatFound() { status = "🦠 Threat found" }
 	content := widget.NewLabel(fmt.Sprintf("%s\nOperation: %s\nFiles: %d  Folders: %d\nData: %s\nDuration: %s\nThroughput: %s\nErrors: %d", status, sum.Operation, sum.Files, sum.Folders, uiutil.HumanBytes(sum.TotalBytes), dur.Round(time.Millisecond), speed, sum.Errors))
 	if sum.FirstError != "" { content.SetText(content.Text + "\nFirst error: " + sum.FirstError) }
-	dialog.ShowCustom("Summary", "Close", content, w)
-}
-
-// computeMixedSelectionSize walks selectedPaths computing total bytes that will be processed.
-// For folders: sums all regular files (excluding already encrypted outputs) according to mode.
-func (s *AppState) computeMixedSelectionSize(recursive bool) (int64, map[string]int64) {
-	sizes := make(map[string]int64)
-	var total int64
-	for _, p := range s.selectedPaths {
-		fi, err := os.Stat(p); if err != nil { continue }
-		if fi.IsDir() {
-			// sum all eligible files inside
-			filepath.Walk(p, func(sp string, info os.FileInfo, err error) error {
-				if err != nil || info == nil { return nil }
-				if info.IsDir() { return nil }
-				low := strings.ToLower(sp)
-				if strings.HasSuffix(low, ".hadescrypt") || strings.HasSuffix(low, ".heistcrypt") || strings.HasSuffix(low, ".gpg") || strings.HasSuffix(low, ".pgp") { return nil }
-				total += info.Size()
-				sizes[p] += info.Size()
-				return nil
-			})
-		} else if fi.Mode().IsRegular() {
-			total += fi.Size()
-			sizes[p] = fi.Size()
-		}
+	if sum.FirstCode != "" { content.SetText(content.Text + fmt.Sprintf("\nError code: %s (%s)", sum.FirstCode, sum.FirstCode.Description())) }
+	content.SetText(content.Text + avReport(sum))
+	if n := len(sum.Skipped); n > 0 {
+		const shown = 10
+		text := fmt.Sprintf("\nSkipped (unreadable): %d\n  %s", n, strings.Join(sum.Skipped[:min(n, shown)], "\n  "))
+		if n > shown { text += fmt.Sprintf("\n  …and %d more", n-shown) }
+		content.SetText(content.Text + text)
 	}
-	return total, sizes
+	if canRetryElevated(sum) {
+		content.SetText(content.Text + "\nAccess was denied: this location needs administrator rights.")
+		dialog.ShowCustomConfirm("Summary", "🛡 Retry as administrator", "Close", withExecutableWarning(w, withOutputs(w, content, sum.Outputs), sum.Executables), func(ok bool) {
+			if ok { s.relaunchElevated(w, sum.Operation) }
+		}, w)
+		return
+	}
+	dialog.ShowCustom("Summary", "Close", withExecutableWarning(w, withOutputs(w, content, sum.Outputs), sum.Executables), w)
 }
 
 func main() {
@@ -160,61 +290,129 @@ func main() {
 			version = "dev"
 		}
 	}
+	viewerRequested = slices.Contains(os.Args[1:], "--viewer")
+	elevatedRequested = parseElevatedRequest(os.Args[1:])
 	application := app.NewWithID("hadescrypt")
 	// Initialize preferences to avoid EOF warning when the file is first created empty.
 	if application.Preferences().String("_init") == "" {
 		application.Preferences().SetString("_init", version)
 	}
 	
-	// Load configuration
+	if err := config.SecureDir(); err != nil { fmt.Fprintln(os.Stderr, "warning: restrict config folder:", err) }
+	// Load configuration; a damaged file falls back to defaults or a backup
 	cfg, err := config.Load()
-	if err != nil {
-		// Use default config if loading fails
-		cfg = config.DefaultConfig()
-	}
+	startWithConfigCheck(application, cfg, err)
+	application.Run()
+}
 
-	// Set theme based on config
-	if cfg.Theme == "light" {
-		application.Settings().SetTheme(theme.LightTheme())
-	} else {
-    application.Settings().SetTheme(theme.DarkTheme())
-	}
+// newMainWindow creates a window with its own AppState, so jobs in different windows run
+// independently. Only the primary window restores and records the saved session.
+// openWindows tracks live windows (UI goroutine only) so closing the primary one cleans up all
+var openWindows = map[*AppState]fyne.Window{}
 
+func newMainWindow(application fyne.App, cfg *config.Config, primary bool) fyne.Window {
 	w := application.NewWindow(fmt.Sprintf("HadesCrypt v%s 🔱 — Lock your secrets, rule your data.", version))
 	w.Resize(fyne.NewSize(cfg.WindowWidth, cfg.WindowHeight))
 	w.CenterOnScreen()
 
+	pol, polErr := policy.Load()
+	viewer := viewerRequested || pol.ReadOnly()
 	state := &AppState{
 		config:         cfg,
 		keyfileManager: keyfiles.NewKeyfileManager(),
 		encryptionMode: cryptoengine.ModeAES256GCM,
-		deleteAfter:    true, // Default to delete source files
+		options:        newOptionsModel(),
+		deleteAfter:    pol.DeleteAfterAllowed() && !viewer, // Default to delete source files
+		primary:        primary,
+		adminPolicy:    pol,
+		adminPolicyErr: polErr,
+		viewer:         viewer,
 	}
+	openWindows[state] = w
 	state.setupUI(w)
+	if primary { state.restoreSession(w) }
+	if primary && elevatedRequested != nil { state.applyElevatedRequest(elevatedRequested) }
+	if primary && !viewer { state.scanLeftovers(w, false) }
+	state.setupAppLock(w)
+
+	newWindow := func() { newMainWindow(application, cfg, false).Show() }
+	w.SetMainMenu(fyne.NewMainMenu(
+		fyne.NewMenu("File", fyne.NewMenuItem("New Window", newWindow)),
+	))
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyN, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) { newWindow() })
 
 	// Save window size on close
 	w.SetCloseIntercept(func() {
-		cfg.WindowWidth = w.Content().Size().Width
-		cfg.WindowHeight = w.Content().Size().Height
-		cfg.Save() // Save config on exit
-		w.Close()
+		if state.busy.Load() {
+			dialog.ShowConfirm("Job running", "A job is still running in this window. Close anyway?", func(ok bool) {
+				if ok { state.closeWindow(w) }
+			}, w)
+			return
+		}
+		state.closeWindow(w)
 	})
+	return w
+}
 
-	w.ShowAndRun()
+// closeWindow persists the window size, shreds open edit sessions and closes w
+func (s *AppState) closeWindow(w fyne.Window) {
+	delete(openWindows, s)
+	if s.primary {
+		for other, ow := range openWindows { other.closeWindow(ow) }
+	}
+	s.cancelRequested.Store(true)
+	s.closed.Store(true)
+	s.config.WindowWidth = w.Content().Size().Width
+	s.config.WindowHeight = w.Content().Size().Height
+	s.config.Save() // Save config on exit
+	s.closeEditSessions()
+	s.stopSyncFolders()
+	s.stopPlayback()
+	s.setPQKey(nil)
+	w.Close()
 }
 
 func (s *AppState) setupUI(w fyne.Window) {
 	// Header
 	header := widget.NewLabelWithStyle("HadesCrypt 🔱", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	lockSettingsBtn := widget.NewButton("🔑 App Lock", func() {
+		s.showAppLockSettings(w)
+	})
+	lockNowBtn := widget.NewButton("🔒 Lock", func() {
+		if s.hasMasterPassword() { s.lockApp(w) } else { s.showAppLockSettings(w) }
+	})
+	keysBtn := widget.NewButton("🗝 Keys", func() {
+		s.showKeysScreen(w)
+	})
+	notifyBtn := widget.NewButton("🔔", func() {
+		s.showNotificationSettings(w)
+	})
+	settingsBtn := widget.NewButton("⚙️", func() {
+		s.showSettings(w)
+	})
+	headerRow := container.NewBorder(nil, nil, nil, container.NewHBox(keysBtn, notifyBtn, settingsBtn, lockSettingsBtn, lockNowBtn), header)
 	tagline := widget.NewLabelWithStyle("Lock your secrets, rule your data.", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	headerBox := container.NewVBox(headerRow, tagline)
+	if banner := s.viewerBanner(); banner != nil { headerBox.Add(banner) }
+	if banner := s.policyBanner(); banner != nil { headerBox.Add(banner) }
 
 	// Drag & Drop Area (supports files and folders)
 	s.dragDropLabel = widget.NewLabelWithStyle("[ Drag & Drop files or folders here ]", fyne.TextAlignCenter, fyne.TextStyle{})
 	s.fileInfoLabel = widget.NewLabel("")
+	s.fileInfoActivity = widget.NewActivity()
+	s.fileInfoActivity.Hide()
+	s.outputEstimateLabel = widget.NewLabel("")
+	s.outputEstimateLabel.Wrapping = fyne.TextWrapWord
+	s.outputEstimateLabel.Hide()
+	// the preview follows the options it depends on
+	for _, d := range []binding.DataItem{s.options.split, s.options.splitSize, s.options.splitUnit, s.options.compress, s.options.recursive, s.options.paranoid} {
+		d.AddListener(binding.NewDataListener(s.refreshSizePreview))
+	}
 	
 	dragDropContainer := container.NewVBox(
 		s.dragDropLabel,
-		s.fileInfoLabel,
+		container.NewBorder(nil, nil, s.fileInfoActivity, nil, s.fileInfoLabel),
+		s.outputEstimateLabel,
 	)
 	
 	// Create a card-like container for drag & drop
@@ -233,12 +431,59 @@ func (s *AppState) setupUI(w fyne.Window) {
 	selectFolderBtn := widget.NewButton("Select Folder", func() {
 		s.showFolderDialog(w)
 	})
-	selectButtons := container.NewHBox(selectFileBtn, selectFolderBtn)
+	searchIndexBtn := widget.NewButton("🔍 Search Index", func() {
+		s.showSearchIndexDialog(w)
+	})
+	historyBtn := widget.NewButton("🕘 History", func() {
+		s.showHistoryDialog(w)
+	})
+	auditBtn := widget.NewButton("🛡 Security Audit", func() {
+		s.doSecurityAudit(w)
+	})
+	manifestBtn := widget.NewButton("📜 Manifest", func() {
+		s.showManifestDialog(w)
+	})
+	compareBtn := widget.NewButton("🟰 Compare", func() {
+		s.showCompareDialog(w)
+	})
+	sshBtn := widget.NewButton("🔑 SSH", func() {
+		s.showSSHDialog(w)
+	})
+	pgpTextBtn := widget.NewButton("✉️ PGP Text", func() {
+		s.showPGPTextTools(w)
+	})
+	lanBtn := widget.NewButton("📡 Send to device", func() {
+		s.showLANDialog(w)
+	})
+	syncBtn := widget.NewButton("🔄 Sync", func() {
+		s.showSyncDialog(w)
+	})
+	uploadBtn := widget.NewButton("☁️ Upload", func() {
+		s.showUploadDialog(w)
+	})
+	shredBtn := widget.NewButton("🧨 Shred", func() {
+		s.showShredDialog(w)
+	})
+	freshBtn := widget.NewButton("🧹 Start fresh", func() {
+		s.startFresh()
+	})
+	leftoversBtn := widget.NewButton("🧽 Leftovers", func() {
+		s.scanLeftovers(w, true)
+	})
+	urlBtn := widget.NewButton("🌐 URL", func() {
+		s.showURLDialog(w, "")
+	})
+	benchBtn := widget.NewButton("⏱ Benchmark", func() {
+		s.showBenchmarkDialog(w)
+	})
+	selectButtons := container.NewHBox(selectFileBtn, selectFolderBtn, urlBtn, searchIndexBtn, historyBtn, auditBtn, manifestBtn, compareBtn, sshBtn, pgpTextBtn, lanBtn, syncBtn, uploadBtn, shredBtn, freshBtn, leftoversBtn, benchBtn)
+	if s.viewer { syncBtn.Hide(); uploadBtn.Hide(); shredBtn.Hide(); leftoversBtn.Hide() }
 
     // Password controls
 	s.passwordEntry = widget.NewPasswordEntry()
 	s.passwordEntry.SetPlaceHolder("Enter password…")
 	s.passwordEntry.OnChanged = func(text string) {
+		s.touchActivity()
 		s.password = text
 		s.updateStrength(text)
 		s.validatePasswordMatch()
@@ -260,15 +505,7 @@ func (s *AppState) setupUI(w fyne.Window) {
 
 	// Encryption mode selection
 	encryptionModeSelect := widget.NewSelect(
-		[]string{
-			"AES-256-GCM", 
-			"ChaCha20-Poly1305", 
-			"Paranoid (AES-256 + ChaCha20)",
-			"🛡️ Post-Quantum: Kyber-768",
-			"🛡️ Post-Quantum: Dilithium-3",
-			"🛡️ Post-Quantum: SPHINCS+",
-			"🔐 GnuPG/OpenPGP (Standard)",
-		},
+		allModeOptions,
 		func(selected string) {
 			switch selected {
 			case "AES-256-GCM":
@@ -279,16 +516,18 @@ func (s *AppState) setupUI(w fyne.Window) {
 				s.encryptionMode = cryptoengine.ModeParanoid
 			case "🛡️ Post-Quantum: Kyber-768":
 				s.encryptionMode = cryptoengine.ModePostQuantumKyber768
-			case "🛡️ Post-Quantum: Dilithium-3":
-				s.encryptionMode = cryptoengine.ModePostQuantumDilithium3
-			case "🛡️ Post-Quantum: SPHINCS+":
-				s.encryptionMode = cryptoengine.ModePostQuantumSPHINCS
+			case "🛡️ Hybrid PQ (Argon2id + ML-KEM-768)":
+				s.encryptionMode = cryptoengine.ModeHybridPQ
 			case "🔐 GnuPG/OpenPGP (Standard)":
 				s.encryptionMode = cryptoengine.ModeGnuPG
 			}
+			s.refreshSizePreview()
+			s.saveSession()
 		},
 	)
 	encryptionModeSelect.SetSelected("AES-256-GCM")
+	s.encryptionModeSelect = encryptionModeSelect
+	s.applyComplianceMode(s.config.ComplianceMode)
 
 	// Keyfiles section
 	s.keyfilesLabel = widget.NewLabel("No keyfiles selected")
@@ -330,6 +569,7 @@ func (s *AppState) setupUI(w fyne.Window) {
 	s.commentsEntry.OnChanged = func(text string) {
 		s.comments = text
 	}
+	limitEntry(s.commentsEntry, cryptoengine.MaxCommentBytes, func() { s.statusLabel.SetText(commentTrimmedNote()) })
 
 	// Password strength meter
 	s.strengthBar = widget.NewProgressBar()
@@ -347,6 +587,39 @@ func (s *AppState) setupUI(w fyne.Window) {
 		s.doDecrypt(w)
 	})
 
+	previewBtn := widget.NewButton("👁 Preview", func() {
+		s.doPreview(w)
+	})
+
+	playBtn := widget.NewButton("▶ Play", func() {
+		s.doPlay(w)
+	})
+
+	editBtn := widget.NewButton("✏️ Edit", func() {
+		s.doOpenForEditing(w)
+	})
+
+	detailsBtn := widget.NewButton("🏷 Details", func() {
+		s.showMetadataDialog(w)
+	})
+
+	inspectBtn := widget.NewButton("🔍 Inspect", func() {
+		s.showInspectDialog(w)
+	})
+
+	statsBtn := widget.NewButton("📊 Stats", func() {
+		s.showFolderStats(w)
+	})
+
+	convertBtn := widget.NewButton("🔁 Convert", func() {
+		s.showConvertDialog(w)
+	})
+
+	exportBtn := widget.NewButton("📦 Export", func() {
+		s.showExportDialog(w)
+	})
+	if s.viewer { encryptBtn.Hide(); editBtn.Hide(); detailsBtn.Hide(); convertBtn.Hide() }
+
 	// Progress and status
 	s.progressBar = widget.NewProgressBar()
 	s.progressBar.Min = 0
@@ -354,13 +627,19 @@ func (s *AppState) setupUI(w fyne.Window) {
 	s.statusLabel = widget.NewLabel("Status: Ready")
 
 	// Advanced options
-	advanced := s.buildAdvancedPanel()
+	advanced := s.buildAdvancedPanel(w)
 
 	// Layout
+	keypadBtn := widget.NewButton("⌨ Keypad", func() {
+		s.showKeypad(w)
+	})
+	promptBtn := widget.NewButton("🔐 Prompt", func() {
+		s.promptSystemPassword(w, true, nil)
+	})
 	passwordRow := container.NewBorder(
         nil, nil,
         widget.NewLabel("Password:"),
-		genBtn,
+		container.NewHBox(keypadBtn, promptBtn, genBtn),
         s.passwordEntry,
     )
 
@@ -389,6 +668,7 @@ func (s *AppState) setupUI(w fyne.Window) {
 		container.NewBorder(nil, nil, widget.NewLabel("Keyfiles:"), s.keyfilesLabel, nil),
 		s.keyfilesList,
 		keyfileButtons,
+		s.pqKeyRow(w),
 	)
 
 	commentsRow := container.NewBorder(
@@ -401,6 +681,14 @@ func (s *AppState) setupUI(w fyne.Window) {
 	actionsRow := container.NewHBox(
 		encryptBtn,
 		decryptBtn,
+		previewBtn,
+		playBtn,
+		editBtn,
+		detailsBtn,
+		inspectBtn,
+		statsBtn,
+		convertBtn,
+		exportBtn,
 		widget.NewButton("Cancel", func(){
 			if !s.cancelRequested.Load() {
 				s.cancelRequested.Store(true)
@@ -417,7 +705,7 @@ func (s *AppState) setupUI(w fyne.Window) {
 	)
 
 	content := container.NewVBox(
-		container.NewPadded(container.NewVBox(header, tagline)),
+		container.NewPadded(headerBox),
 		widget.NewSeparator(),
 		container.NewPadded(dragDropCard),
 		container.NewPadded(selectButtons),
@@ -431,6 +719,7 @@ func (s *AppState) setupUI(w fyne.Window) {
 		widget.NewSeparator(),
 		container.NewPadded(actionsRow),
 		container.NewPadded(progressRow),
+		container.NewPadded(s.buildPerfPanel()),
 		container.NewPadded(s.statusLabel),
 		widget.NewSeparator(),
 		advanced,
@@ -439,6 +728,7 @@ func (s *AppState) setupUI(w fyne.Window) {
 	// Set up drag and drop
 	w.SetOnDropped(func(position fyne.Position, uris []fyne.URI) {
 		if len(uris) == 0 { return }
+		if len(uris) == 1 && uris[0].Scheme() == "https" { s.showURLDialog(w, uris[0].String()); return }
 		if len(uris) == 1 {
 			s.setSelectedFile(uris[0].Path())
 			return
@@ -449,7 +739,7 @@ func (s *AppState) setupUI(w fyne.Window) {
 		s.setSelectedFiles(paths)
 	})
 
-	w.SetContent(container.NewScroll(content))
+	w.SetContent(container.NewScroll(container.NewStack(newActivityCatcher(s.touchActivity), content)))
 }
 
 func (s *AppState) showFileDialog(w fyne.Window) {
@@ -489,10 +779,12 @@ func (s *AppState) showFolderDialog(w fyne.Window) {
 }
 
 func (s *AppState) setSelectedFile(path string) {
+	s.touchActivity()
 	// Single selection resets multi selection
 	s.selectedPath = path
 	s.selectedPaths = nil
 	s.updateFileInfo()
+	s.saveSession()
 }
 
 // setSelectedFiles sets multiple file selections (files only, no directories yet)
@@ -500,123 +792,32 @@ func (s *AppState) setSelectedFiles(paths []string) {
     s.selectedPath = ""
     s.selectedPaths = paths
     s.updateFileInfo()
+    s.saveSession()
 }
 
 func (s *AppState) updateFileInfo() {
+	gen := s.beginFileInfo()
 	if s.selectedPath == "" && len(s.selectedPaths) == 0 {
 		s.dragDropLabel.SetText("[ Drag & Drop your files here ]")
 		s.fileInfoLabel.SetText("")
 		s.commentsEntry.SetText("")
+		s.endFileInfo(gen)
 		return
 	}
-
+	// Everything that touches the disk runs in the background; see filedetails.go
 	if len(s.selectedPaths) > 0 {
-		// Mixed multi selection summary
-		var totalSize int64
-		files := 0
-		folders := 0
-		for _, p := range s.selectedPaths {
-			if fi, err := os.Stat(p); err == nil {
-				if fi.IsDir() { folders++ } else if fi.Mode().IsRegular() { files++; totalSize += fi.Size() }
-			}
-		}
-		icon := "📄"
-		if folders > 0 { icon = "📂" }
-		label := fmt.Sprintf("%s %d item(s) (%d file(s), %d folder(s))", icon, len(s.selectedPaths), files, folders)
-		s.dragDropLabel.SetText(label)
-		s.fileInfoLabel.SetText(fmt.Sprintf("Regular file bytes (pre-archive): %s", uiutil.HumanBytes(totalSize)))
-		return
-	}
-
-	fileName := filepath.Base(s.selectedPath)
-	
-	// Check if it's a file or directory
-	info, err := os.Stat(s.selectedPath)
-	if err != nil {
-		s.dragDropLabel.SetText("[ Drag & Drop your files here ]")
-		s.fileInfoLabel.SetText("Error: " + err.Error())
-		s.commentsEntry.SetText("")
+		s.dragDropLabel.SetText(fmt.Sprintf("%d item(s)", len(s.selectedPaths)))
+		s.fileInfoLabel.SetText("")
+		s.loadSelectionSummary(gen, slices.Clone(s.selectedPaths))
 		return
 	}
-
-	if info.IsDir() {
-		s.dragDropLabel.SetText("📁 " + fileName)
-		modeText := "Archive + Encrypt"
-		if s.recursiveMode {
-			modeText = "Recursive per-file encryption"
-		}
-		// Count files (non-recursive quick info)
-		var fileCount int
-		filepath.Walk(s.selectedPath, func(path string, fi os.FileInfo, err error) error {
-			if err != nil { return nil }
-			if fi.IsDir() { return nil }
-			lower := strings.ToLower(path)
-			if strings.HasSuffix(lower, ".hadescrypt") || strings.HasSuffix(lower, ".heistcrypt") || strings.HasSuffix(lower, ".gpg") { return nil }
-			fileCount++
-			return nil
-		})
-		s.fileInfoLabel.SetText(fmt.Sprintf("Folder: %d file(s) | Mode: %s", fileCount, modeText))
-		// Don't clear comments for directories, user might want to add them
-	} else {
-		// Check sidecar meta indicating archived folder
-		metaPath := s.selectedPath + ".meta"
-		if _, err := os.Stat(metaPath); err == nil {
-			// Show folder-archive icon and parse minimal info
-			data, _ := os.ReadFile(metaPath)
-			// crude parse (avoid adding JSON dep): look for file_count & original_folder
-			fileCount := "?"
-			original := fileName
-			for _, line := range strings.Split(string(data), "\n") {
-				line = strings.TrimSpace(line)
-				if strings.HasPrefix(line, "\"file_count\"") {
-					parts := strings.Split(line, ":")
-					if len(parts) > 1 { fileCount = strings.Trim(parts[1], " ,\"") }
-				}
-				if strings.HasPrefix(line, "\"original_folder\"") {
-					parts := strings.Split(line, ":")
-					if len(parts) > 1 { original = strings.Trim(parts[1], " ,\"") }
-				}
-			}
-			s.dragDropLabel.SetText("📦 " + original)
-			s.fileInfoLabel.SetText(fmt.Sprintf("Archived Folder (files: %s)", fileCount))
-			return
-		}
-		s.dragDropLabel.SetText("📄 " + fileName)
-		sizeText := uiutil.HumanBytes(info.Size())
-		
-		// Try to get detailed file information for encrypted files
-		fileInfo, err := cryptoengine.GetFileInfo(s.selectedPath)
-		if err == nil {
-			format := fileInfo["format"].(string)
-			
-			if format == "HadesCrypt" {
-				// HadesCrypt encrypted file
-				modeName := fileInfo["encryption_mode_name"].(string)
-				s.fileInfoLabel.SetText(fmt.Sprintf("🔒 Size: %s - %s", sizeText, modeName))
-				
-				// Extract and display comments
-				if comments, ok := fileInfo["comments"].(string); ok && comments != "" {
-					s.commentsEntry.SetText(comments)
-					s.comments = comments
-				} else {
-					// Clear comments for encrypted files with no comments
-					s.commentsEntry.SetText("")
-					s.comments = ""
-				}
-			} else if format == "GnuPG/OpenPGP" {
-				// GnuPG encrypted file
-				s.fileInfoLabel.SetText(fmt.Sprintf("🔐 Size: %s - GnuPG/OpenPGP", sizeText))
-				// GnuPG files don't store comments, but don't clear existing ones
-			} else {
-				// Regular file
-				s.fileInfoLabel.SetText(fmt.Sprintf("Size: %s", sizeText))
-				// Don't clear comments for regular files
-			}
-		} else {
-			// Fallback for files that can't be analyzed
-			s.fileInfoLabel.SetText(fmt.Sprintf("Size: %s", sizeText))
-		}
-	}
+	s.dragDropLabel.SetText(filepath.Base(s.selectedPath))
+	s.fileInfoLabel.SetText("")
+	modeText := "Archive + Encrypt"
+	if s.opt().Recursive { modeText = "Recursive per-file encryption" }
+	finalPassword := []byte(s.password)
+	if s.keyfileManager.HasKeyfiles() { finalPassword = s.keyfileManager.GetCombinedKey([]byte(s.password)) }
+	s.loadFileDetails(gen, s.selectedPath, modeText, finalPassword)
 }
 
 func (s *AppState) updateStrength(password string) {
@@ -656,11 +857,11 @@ func (s *AppState) animatePasswordMatch(isMatch bool) {
 		// Success animation - pulse green checkmark
 		for i := 0; i < 3; i++ {
 			time.Sleep(200 * time.Millisecond)
-			fyne.Do(func() {
+			s.ui(func() {
 				s.passwordMatchLabel.SetText("✨ Match")
 			})
 			time.Sleep(200 * time.Millisecond)
-			fyne.Do(func() {
+			s.ui(func() {
 				s.passwordMatchLabel.SetText("✅ Match")
 			})
 		}
@@ -668,11 +869,11 @@ func (s *AppState) animatePasswordMatch(isMatch bool) {
 		// Error animation - pulse red X
 		for i := 0; i < 2; i++ {
 			time.Sleep(150 * time.Millisecond)
-			fyne.Do(func() {
+			s.ui(func() {
 				s.passwordMatchLabel.SetText("⚠️ No Match")
 			})
 			time.Sleep(150 * time.Millisecond)
-			fyne.Do(func() {
+			s.ui(func() {
 				s.passwordMatchLabel.SetText("❌ No Match")
 			})
 		}
@@ -680,20 +881,48 @@ func (s *AppState) animatePasswordMatch(isMatch bool) {
 }
 
 func (s *AppState) doEncrypt(w fyne.Window) {
+	if s.denyInViewer(w) { return }
 	s.cancelRequested.Store(false)
 	if s.selectedPath == "" && len(s.selectedPaths) == 0 {
 		dialog.ShowInformation("Select input", "Please select a file, folder, or multiple files to encrypt.", w)
 		return
 	}
-	if s.password == "" {
-		dialog.ShowInformation("Password required", "Please enter a password.", w)
+	if s.password == "" && s.config.UseSystemPrompt && !s.usesRecipients() {
+		s.promptSystemPassword(w, true, func() { s.doEncrypt(w) })
 		return
 	}
-	if s.password != s.confirmPassword {
+	if s.password == "" && !s.usesRecipients() {
+		dialog.ShowInformation("Password required", "Please enter a password or choose GnuPG recipients.", w)
+		return
+	}
+	if s.password != s.confirmPassword && !s.usesRecipients() {
 		dialog.ShowInformation("Password Mismatch", "Password and confirmation password do not match.", w)
 		return
 	}
+	if err := s.checkPolicy(s.encryptOptions()); err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	if cryptoengine.NeedsKEMKey(s.encryptOptions().Mode) && s.pqKey == nil {
+		dialog.ShowInformation("Post-quantum key required", "The post-quantum modes encrypt to a key file as well as the password. Load or generate one in the PQ key row; the files will only open with both.", w)
+		return
+	}
+	if s.allOrNothingFolderConflict() {
+		dialog.ShowInformation("All-or-nothing batch", "All-or-nothing batches encrypt folders as archives. Turn off Recursive Mode or All-or-nothing.", w)
+		return
+	}
+	if !s.mediaAcknowledged {
+		sources := s.selectedPaths
+		if len(sources) == 0 { sources = []string{s.selectedPath} }
+		s.confirmSourceDeletion(w, sources, func() { s.doEncrypt(w) })
+		return
+	}
+	if !s.limitAcknowledged {
+		s.checkOutputLimit(w, func() { s.doEncrypt(w) })
+		return
+	}
 
+	recursive := s.opt().Recursive
 	var singleInfo os.FileInfo
 	var outputPath string
 	if s.selectedPath != "" {
@@ -701,7 +930,7 @@ func (s *AppState) doEncrypt(w fyne.Window) {
 		outputPath = s.defaultOutputPathForEncrypt(s.selectedPath)
 		singleInfo, err = os.Stat(s.selectedPath)
 		if err != nil { dialog.ShowError(err, w); return }
-		if singleInfo.IsDir() && !s.recursiveMode { /* archive mode comment */ }
+		if singleInfo.IsDir() && !recursive { /* archive mode comment */ }
 	}
 
 	s.statusLabel.SetText("🔐 Encrypting…")
@@ -709,16 +938,11 @@ func (s *AppState) doEncrypt(w fyne.Window) {
 
     go func() {
         s.startOpSummary("encrypt")
-		onProgress := func(done, total int64) {
-			fyne.Do(func() {
-				if s.cancelRequested.Load() { return }
-					if total <= 0 {
-						s.setProgressFraction(0)
-                return
-            }
-					s.setProgressFraction(float64(done) / float64(total))
-			})
-        }
+		s.noteSlowMedia(append([]string{s.selectedPath, outputPath}, s.selectedPaths...)...)
+		limiter := s.beginBackgroundJob()
+		onProgress := throttle.Progress(limiter, func(done, total int64) {
+			if !s.cancelRequested.Load() { s.reportProgress(done, total) }
+		})
 
         start := time.Now()
 		var encErr error
@@ -727,81 +951,94 @@ func (s *AppState) doEncrypt(w fyne.Window) {
 
 		if len(s.selectedPaths) > 0 { // multi-file mode
 			// Aggregate bytes across files & folders
-			grandTotal, _ := s.computeMixedSelectionSize(s.recursiveMode)
-			var processed int64
-			for idx, p := range s.selectedPaths {
-				if s.cancelRequested.Load() { encErr = fmt.Errorf("canceled"); break }
-				fi, err := os.Stat(p); if err != nil { continue }
-				base := filepath.Base(p)
-				fyne.Do(func(){ s.statusLabel.SetText(fmt.Sprintf("🔐 %d/%d %s", idx+1, len(s.selectedPaths), base)) })
-				if fi.IsDir() {
-					// Choose strategy: recursive or archive
-					if s.recursiveMode {
-						cerr := s.encryptDirectoryRecursive(p, finalPassword, func(done,total int64){ if grandTotal>0 { onProgress(processed+done, grandTotal) } })
-						if cerr != nil { encErr = cerr; break }
-						// after folder, increment processed by summed size of its contents
-						filepath.Walk(p, func(sp string, info os.FileInfo, e error) error {
-							if e!=nil || info==nil || info.IsDir() { return nil }
-							low := strings.ToLower(sp)
-							if strings.HasSuffix(low, ".hadescrypt") || strings.HasSuffix(low, ".heistcrypt") || strings.HasSuffix(low, ".gpg") || strings.HasSuffix(low, ".pgp") { return nil }
-							processed += info.Size(); return nil
-						})
-					} else {
-						outArchive := s.defaultOutputPathForEncrypt(p)
-						cerr := s.encryptDirectory(p, outArchive, finalPassword, func(done,total int64){ if grandTotal>0 { onProgress(processed+done/2, grandTotal) } })
+			grandTotal, sizes := s.ops().SelectionSize(s.selectedPaths, false)
+			track := progress.New(grandTotal, onProgress)
+			phases := make([]*progress.Phase, len(s.selectedPaths))
+			for i, p := range s.selectedPaths { phases[i] = track.Phase(sizes[p]) }
+			if s.allOrNothing {
+				// nothing is published before the end, so there is no partial batch to resume
+				encErr = s.encryptAllOrNothing(finalPassword, phases)
+			} else {
+				s.beginBatch("encrypt", s.selectedPaths)
+				for idx, p := range s.selectedPaths {
+					if s.cancelRequested.Load() { encErr = apperr.ErrCanceled; break }
+					fi, err := os.Stat(p); if err != nil { continue }
+					base := filepath.Base(p)
+					s.setStatus(fmt.Sprintf("🔐 %d/%d %s", idx+1, len(s.selectedPaths), base))
+					if fi.IsDir() {
+						// Choose strategy: recursive or archive
+						if recursive {
+							cerr := s.encryptDirectoryRecursive(p, finalPassword, phases[idx].Update)
+							if cerr != nil { encErr = cerr; break }
+						} else {
+							outArchive := s.defaultOutputPathForEncrypt(p)
+							cerr := s.encryptDirectory(p, outArchive, finalPassword, phases[idx].Update)
+							if cerr != nil { encErr = cerr; break }
+						}
+						if !recursive { s.indexOutput(s.defaultOutputPathForEncrypt(p), p); s.timestampOutput(s.defaultOutputPathForEncrypt(p)) }
+						// history entry folder
+						folderOut := s.defaultOutputPathForEncrypt(p)
+						if recursive { folderOut = p }
+						s.addHistory(config.HistoryEntry{FileName: base, Operation:"encrypt-folder", Size: fi.Size(), Timestamp: time.Now().Unix(), Result: "success", Output: folderOut})
+						// recursive mode already removed each file once its output was verified
+						if s.deleteAfter && !recursive { s.removeEncryptedSource(p, s.defaultOutputPathForEncrypt(p), finalPassword) }
+						if !recursive { if cerr := s.splitOutput(s.defaultOutputPathForEncrypt(p)); cerr != nil { encErr = cerr; break } }
+						s.addFolder(0)
+					} else if fi.Mode().IsRegular() {
+						out := s.defaultOutputPathForEncrypt(p)
+						cerr := withMediaRetry(p, out, func() error { return cryptoengine.EncryptFileWithOptions(p, out, finalPassword, s.encryptOptionsFor(p), phases[idx].Update) })
+						if cerr == nil { cerr = s.escrowOutput(out, finalPassword) }
 						if cerr != nil { encErr = cerr; break }
-						// After archive encryption, approximate processed as full folder content size
-						filepath.Walk(p, func(sp string, info os.FileInfo, e error) error {
-							if e!=nil || info==nil || info.IsDir() { return nil }
-							low := strings.ToLower(sp)
-							if strings.HasSuffix(low, ".hadescrypt") || strings.HasSuffix(low, ".heistcrypt") || strings.HasSuffix(low, ".gpg") || strings.HasSuffix(low, ".pgp") { return nil }
-							processed += info.Size(); return nil
-						})
+						s.indexOutput(out, p)
+						s.timestampOutput(out)
+						s.addHistory(config.HistoryEntry{FileName: base, Operation:"encrypt", Size: fi.Size(), Timestamp: time.Now().Unix(), Result: "success", Output: out})
+						if s.deleteAfter { s.removeEncryptedSource(p, out, finalPassword) }
+						if cerr := s.splitOutput(out); cerr != nil { encErr = cerr; break }
+						s.addFile(fi.Size())
 					}
-					// history entry folder
-					s.config.AddHistoryEntry(config.HistoryEntry{FileName: base, Operation:"encrypt-folder", Size: fi.Size(), Timestamp: time.Now().Unix(), Result: "success"})
-					if s.deleteAfter { os.RemoveAll(p) }
-					s.addFolder(0)
-				} else if fi.Mode().IsRegular() {
-					out := s.defaultOutputPathForEncrypt(p)
-					cerr := cryptoengine.EncryptFileWithMode(p, out, finalPassword, s.encryptionMode, func(done,total int64){ if grandTotal>0 { onProgress(processed+done, grandTotal) } })
-					if cerr != nil { encErr = cerr; break }
-					processed += fi.Size()
-					s.config.AddHistoryEntry(config.HistoryEntry{FileName: base, Operation:"encrypt", Size: fi.Size(), Timestamp: time.Now().Unix(), Result: "success"})
-					if s.deleteAfter { os.Remove(p) }
-					s.addFile(fi.Size())
+					phases[idx].Complete()
+					s.batchItemDone(p)
 				}
-				if onProgress != nil { onProgress(processed, grandTotal) }
 			}
 			elapsed := time.Since(start).Round(time.Millisecond)
-			if encErr == nil { fyne.Do(func(){ s.statusLabel.SetText(fmt.Sprintf("✅ %d items encrypted (%s)", len(s.selectedPaths), elapsed)) }) }
+			if encErr == nil && !s.cancelRequested.Load() { s.endBatch() }
+			if encErr == nil { s.setStatus(fmt.Sprintf("✅ %d items encrypted (%s)%s", len(s.selectedPaths), elapsed, s.skippedNote())) }
 		} else if singleInfo != nil && singleInfo.IsDir() {
 			// Single folder encryption path (not multi-selection)
-			if s.recursiveMode { encErr = s.encryptDirectoryRecursive(s.selectedPath, finalPassword, onProgress) } else { encErr = s.encryptDirectory(s.selectedPath, outputPath, finalPassword, onProgress) }
+			if recursive { encErr = s.encryptDirectoryRecursive(s.selectedPath, finalPassword, onProgress) } else { encErr = s.encryptDirectory(s.selectedPath, outputPath, finalPassword, onProgress) }
 			elapsed := time.Since(start).Round(time.Millisecond)
 			if encErr == nil {
-				fyne.Do(func(){ s.statusLabel.SetText(fmt.Sprintf("✅ Folder encrypted (%s)", elapsed)) })
+				s.setStatus(fmt.Sprintf("✅ Folder encrypted (%s)%s", elapsed, s.skippedNote()))
+				if !recursive { s.indexOutput(outputPath, s.selectedPath); s.timestampOutput(outputPath) }
 				// Add history entry for folder
-				s.config.AddHistoryEntry(config.HistoryEntry{FileName: filepath.Base(s.selectedPath), Operation:"encrypt-folder", Size: 0, Timestamp: time.Now().Unix(), Result: "success"})
-				// Delete original folder if user selected deleteAfter
-				if s.deleteAfter { os.RemoveAll(s.selectedPath) }
+				folderOut := outputPath
+				if recursive { folderOut = s.selectedPath }
+				s.addHistory(config.HistoryEntry{FileName: filepath.Base(s.selectedPath), Operation:"encrypt-folder", Size: 0, Timestamp: time.Now().Unix(), Result: "success", Output: folderOut})
+				// Delete original folder if user selected deleteAfter; recursive mode removed each file already
+				if s.deleteAfter && !recursive { s.removeEncryptedSource(s.selectedPath, outputPath, finalPassword) }
+				if !recursive { encErr = s.splitOutput(outputPath) }
 				s.addFolder(0)
 			}
 		} else {
-			encErr = cryptoengine.EncryptFileWithMode(s.selectedPath, outputPath, finalPassword, s.encryptionMode, onProgress)
+			encErr = withMediaRetry(s.selectedPath, outputPath, func() error { return cryptoengine.EncryptFileWithOptions(s.selectedPath, outputPath, finalPassword, s.encryptOptionsFor(s.selectedPath), onProgress) })
+			if encErr == nil { encErr = s.escrowOutput(outputPath, finalPassword) }
 			elapsed := time.Since(start).Round(time.Millisecond)
-			if encErr == nil { fyne.Do(func(){ s.statusLabel.SetText(fmt.Sprintf("✅ %s encrypted (%s)", filepath.Base(s.selectedPath), elapsed)) }); if singleInfo!=nil { s.addFile(singleInfo.Size()) } }
+			if encErr == nil { s.setStatus(fmt.Sprintf("✅ %s encrypted (%s)", filepath.Base(s.selectedPath), elapsed)); if singleInfo!=nil { s.addFile(singleInfo.Size()) }; s.indexOutput(outputPath, s.selectedPath); s.timestampOutput(outputPath) }
 			// single file history
-			s.config.AddHistoryEntry(config.HistoryEntry{FileName: filepath.Base(s.selectedPath), Operation:"encrypt", Size: singleInfo.Size(), Timestamp: time.Now().Unix(), Result: "success"})
-			if s.deleteAfter { os.Remove(s.selectedPath) }
+			entry := config.HistoryEntry{FileName: filepath.Base(s.selectedPath), Operation:"encrypt", Size: singleInfo.Size(), Timestamp: time.Now().Unix(), Result: "success", Output: outputPath}
+			if encErr != nil { entry.Result, entry.Error, entry.Output = "error", secret.ScrubError(encErr, finalPassword, []byte(s.password)).Error(), "" }
+			s.addHistory(entry)
+			if s.deleteAfter && encErr == nil { s.removeEncryptedSource(s.selectedPath, outputPath, finalPassword) }
+			if encErr == nil { encErr = s.splitOutput(outputPath) }
 		}
 
 		// Save config/history at end
 		s.config.Save()
 		if encErr != nil { s.noteError(encErr) }
+		if err := s.saveIndex(); err != nil { s.noteError(err) }
 		if s.cancelRequested.Load() { s.markCanceled() }
 		sum := s.finishSummary()
-		fyne.Do(func(){ if sum!=nil { s.showSummaryDialog(w,sum) } })
+		s.showSummary(w, sum)
 
 		// (legacy per-file final status removed; handled inline per branch)
 	}()
@@ -813,19 +1050,32 @@ func (s *AppState) doDecrypt(w fyne.Window) {
 		dialog.ShowInformation("Select input", "Please select a file, folder, or multiple encrypted items to decrypt.", w)
 		return
 	}
-	if s.password == "" {
+	if s.password == "" && s.config.UseSystemPrompt && !s.onlyAgeSelected() {
+		s.promptSystemPassword(w, false, func() { s.doDecrypt(w) })
+		return
+	}
+	if s.password == "" && !s.onlyAgeSelected() {
         dialog.ShowInformation("Password required", "Please enter a password.", w)
         return
     }
+	if !s.mediaAcknowledged {
+		s.confirmDeletionPlan(w, true, func() { s.doDecrypt(w) })
+		return
+	}
 
 	outputPath := ""
 	if s.selectedPath != "" { outputPath = s.defaultOutputPathForDecrypt(s.selectedPath) }
+	// a volume (name.hadescrypt.000) stands for the container it was split from; DecryptFile joins them
+	input := s.selectedPath
+	if base, parts := splitter.Volumes(input); parts != nil { input, outputPath = base, s.defaultOutputPathForDecrypt(base) }
 
 	s.statusLabel.SetText("🔓 Decrypting…")
 	s.setProgressFraction(0)
 
 	go func() {
 		s.startOpSummary("decrypt")
+		s.noteSlowMedia(append([]string{s.selectedPath, outputPath}, s.selectedPaths...)...)
+		limiter := s.beginBackgroundJob()
 		// Batch multi-selection path
 		if len(s.selectedPaths) > 0 {
 			finalPassword := []byte(s.password)
@@ -833,54 +1083,48 @@ func (s *AppState) doDecrypt(w fyne.Window) {
 			// Collect targets (files or directories)
 			var targets []string
 			for _, p := range s.selectedPaths { targets = append(targets, p) }
-			// Pre-compute total bytes (approx): for encrypted dirs (user selected) we'll walk inside
-			var totalBytes int64
-			for _, t := range targets {
-				fi, err := os.Stat(t); if err != nil { continue }
-				if fi.IsDir() {
-					filepath.Walk(t, func(sp string, info os.FileInfo, e error) error {
-						if e!=nil || info==nil || info.IsDir() { return nil }
-						low:=strings.ToLower(sp)
-						if strings.HasSuffix(low, ".hadescrypt") || strings.HasSuffix(low, ".heistcrypt") || strings.HasSuffix(low, ".gpg") || strings.HasSuffix(low, ".pgp") { totalBytes += info.Size() }
-						return nil
-					})
-				} else if fi.Mode().IsRegular() { totalBytes += fi.Size() }
-			}
-			var processed int64
+			// Pre-compute total bytes: encrypted folders count the containers inside them
+			totalBytes, sizes := s.ops().SelectionSize(targets, true)
+			track := progress.New(totalBytes, throttle.Progress(limiter, s.reportProgress))
+			phases := make([]*progress.Phase, len(targets))
+			for i, t := range targets { phases[i] = track.Phase(sizes[t]) }
 			start := time.Now()
+			s.beginBatch("decrypt", targets)
+			joined := map[string]bool{}
 			for idx, t := range targets {
 				if s.cancelRequested.Load() { break }
 				fi, err := os.Stat(t); if err != nil { continue }
 				base := filepath.Base(t)
-				fyne.Do(func(){ s.statusLabel.SetText(fmt.Sprintf("🔓 %d/%d %s", idx+1, len(targets), base)) })
+				s.setStatus(fmt.Sprintf("🔓 %d/%d %s", idx+1, len(targets), base))
 				if fi.IsDir() {
 					// Decrypt all encrypted files inside directory recursively
-					dErr := s.decryptDirectoryRecursive(t, finalPassword, func(done,total int64){ if totalBytes>0 { fyne.Do(func(){ s.setProgressFraction(float64(processed+done)/float64(totalBytes)) }) } })
-					// After finishing dir, increment processed by sizes of encrypted files within
-					filepath.Walk(t, func(sp string, info os.FileInfo, e error) error {
-						if e!=nil || info==nil || info.IsDir() { return nil }
-						low:=strings.ToLower(sp)
-						if strings.HasSuffix(low, ".hadescrypt") || strings.HasSuffix(low, ".heistcrypt") || strings.HasSuffix(low, ".gpg") || strings.HasSuffix(low, ".pgp") { processed += info.Size() }
-						return nil })
-					if dErr != nil { fyne.Do(func(){ s.statusLabel.SetText("❌ "+dErr.Error()) }); s.noteError(dErr); break } else { s.addFolder(0) }
+					dErr := s.decryptDirectoryRecursive(t, finalPassword, phases[idx].Update)
+					if dErr != nil { s.setStatus("❌ "+dErr.Error()); s.noteError(dErr); break } else { s.addFolder(0) }
 				} else {
+					// a volume stands for its whole container, which is decrypted once
+					if base, parts := splitter.Volumes(t); parts != nil { t = base }
+					if joined[t] { phases[idx].Complete(); s.batchItemDone(targets[idx]); continue }
+					joined[t] = true
 					out := s.defaultOutputPathForDecrypt(t)
 					var dErr error
-					if s.isHadesCryptFile(t) { dErr = s.decryptFileAuto(t, out, finalPassword, func(done,total int64){ if totalBytes>0 { fyne.Do(func(){ s.setProgressFraction(float64(processed+done)/float64(totalBytes)) }) } })
-					} else if s.isGnuPGFile(t) { dErr = cryptoengine.DecryptFileWithGnuPG(t, out, finalPassword, func(done,total int64){ if totalBytes>0 { fyne.Do(func(){ s.setProgressFraction(float64(processed+done)/float64(totalBytes)) }) } })
-					} else { dErr = cryptoengine.DecryptFile(t, out, finalPassword, s.forceDecrypt, func(done,total int64){ if totalBytes>0 { fyne.Do(func(){ s.setProgressFraction(float64(processed+done)/float64(totalBytes)) }) } }) }
-					if dErr != nil { fyne.Do(func(){ s.statusLabel.SetText("❌ "+dErr.Error()) }); s.noteError(dErr); break } else { s.addFile(fi.Size()) }
-					processed += fi.Size()
+					if s.isHadesCryptFile(t) { dErr = s.decryptFileAuto(t, out, finalPassword, phases[idx].Update)
+					} else if format.IsAge(t) { dErr = withMediaRetry(t, out, func() error { return s.decryptAge(t, out, []byte(s.password), phases[idx].Update) })
+					} else if s.isGnuPGFile(t) { dErr = withMediaRetry(t, out, func() error { return cryptoengine.DecryptFileWithGnuPGAt(s.gpgPath(), t, out, finalPassword, phases[idx].Update) })
+					} else { dErr = withMediaRetry(t, out, func() error { return cryptoengine.DecryptFile(t, out, finalPassword, s.opt().ForceDecrypt, phases[idx].Update) }) }
+					if dErr != nil { s.setStatus("❌ "+dErr.Error()); s.noteError(dErr); break } else { s.addFile(fi.Size()); s.addOutput(out) }
 				}
-				fyne.Do(func(){ if totalBytes>0 { s.setProgressFraction(float64(processed)/float64(totalBytes)) } })
-				if s.deleteAfter { os.RemoveAll(t) }
+				phases[idx].Complete()
+				// folders removed their decrypted files one by one; the folder itself now holds the plaintext
+				if s.deleteAfter && !fi.IsDir() { s.removeDecryptedSource(t, s.defaultOutputPathForDecrypt(t)) }
+				s.batchItemDone(targets[idx])
 			}
 			if s.cancelRequested.Load() { s.markCanceled() }
+			if s.opSummary != nil && s.opSummary.Errors == 0 && !s.cancelRequested.Load() { s.endBatch() }
 			if !s.cancelRequested.Load() {
 				elapsed := time.Since(start).Round(time.Millisecond)
-				fyne.Do(func(){ s.statusLabel.SetText(fmt.Sprintf("✅ %d items decrypted (%s)", len(targets), elapsed)) })
-			} else { fyne.Do(func(){ s.statusLabel.SetText("Canceled") }) }
-			sum := s.finishSummary(); fyne.Do(func(){ if sum!=nil { s.showSummaryDialog(w,sum) } })
+				s.setStatus(fmt.Sprintf("✅ %d items decrypted (%s)", len(targets), elapsed))
+			} else { s.setStatus("Canceled") }
+			sum := s.finishSummary(); s.showSummary(w, sum)
 			return
 		}
 		// If single selectedPath is a directory: decrypt all encrypted files inside.
@@ -888,23 +1132,13 @@ func (s *AppState) doDecrypt(w fyne.Window) {
 			start := time.Now()
 			finalPassword := []byte(s.password)
 			if s.keyfileManager.HasKeyfiles() { finalPassword = s.keyfileManager.GetCombinedKey([]byte(s.password)) }
-			err := s.decryptDirectoryRecursive(s.selectedPath, finalPassword, func(done,total int64){ fyne.Do(func(){ if total>0 { s.setProgressFraction(float64(done)/float64(total)) } }) })
+			err := s.decryptDirectoryRecursive(s.selectedPath, finalPassword, throttle.Progress(limiter, s.reportProgress))
 			elapsed := time.Since(start).Round(time.Millisecond)
-			fyne.Do(func(){
-				if err != nil { s.statusLabel.SetText("❌ "+err.Error()); s.noteError(err) } else { s.statusLabel.SetText(fmt.Sprintf("✅ Folder decrypted (%s)", elapsed)); s.addFolder(0) }
-			})
-			sum := s.finishSummary(); fyne.Do(func(){ if sum!=nil { s.showSummaryDialog(w,sum) } })
+			if err != nil { s.noteError(err); s.setStatus("❌ "+err.Error()) } else { s.addFolder(0); s.setStatus(fmt.Sprintf("✅ Folder decrypted (%s)", elapsed)) }
+			sum := s.finishSummary(); s.showSummary(w, sum)
 			return
 		} }
-		onProgress := func(done, total int64) {
-			fyne.Do(func() {
-				if total <= 0 {
-					s.setProgressFraction(0)
-                return
-            }
-				s.setProgressFraction(float64(done) / float64(total))
-			})
-        }
+		onProgress := throttle.Progress(limiter, s.reportProgress)
 
         start := time.Now()
 		
@@ -916,16 +1150,19 @@ func (s *AppState) doDecrypt(w fyne.Window) {
 
 		// Auto decrypt for HadesCrypt (.hadescrypt/.heistcrypt) – handles single-file or archived folder transparently
 		var err error
-		if s.isHadesCryptFile(s.selectedPath) {
-			err = s.decryptFileAuto(s.selectedPath, outputPath, finalPassword, onProgress)
+		if s.isHadesCryptFile(input) {
+			err = s.decryptFileAuto(input, outputPath, finalPassword, onProgress)
+		} else if format.IsAge(s.selectedPath) {
+			err = withMediaRetry(s.selectedPath, outputPath, func() error { return s.decryptAge(s.selectedPath, outputPath, []byte(s.password), onProgress) })
 		} else {
 			if s.isGnuPGFile(s.selectedPath) {
-				err = cryptoengine.DecryptFileWithGnuPG(s.selectedPath, outputPath, finalPassword, onProgress)
+				err = withMediaRetry(s.selectedPath, outputPath, func() error { return cryptoengine.DecryptFileWithGnuPGAt(s.gpgPath(), s.selectedPath, outputPath, finalPassword, onProgress) })
 			} else {
-				err = cryptoengine.DecryptFile(s.selectedPath, outputPath, finalPassword, s.forceDecrypt, onProgress)
+				err = withMediaRetry(s.selectedPath, outputPath, func() error { return cryptoengine.DecryptFile(input, outputPath, finalPassword, s.opt().ForceDecrypt, onProgress) })
 			}
 		}
 		
+		err = withPasswordHint(secret.ScrubError(err, finalPassword, []byte(s.password)), s.selectedPath)
 		elapsed := time.Since(start).Round(time.Millisecond)
 		
 		// Get file size for history
@@ -942,39 +1179,54 @@ func (s *AppState) doDecrypt(w fyne.Window) {
 			Timestamp: time.Now().Unix(),
 		}
 
-		fyne.Do(func() {
-			if err != nil {
-				if strings.Contains(err.Error(), "canceled") { s.statusLabel.SetText("⏹️ Canceled"); s.markCanceled(); return }
-				historyEntry.Result = "error"; historyEntry.Error = err.Error(); s.statusLabel.SetText("❌ "+err.Error()); s.noteError(err); dialog.ShowError(err, w)
-			} else {
-				historyEntry.Result = "success"; statusMsg := fmt.Sprintf("✅ Decrypted → %s (%s)", filepath.Base(outputPath), elapsed)
-				if s.deleteAfter { if deleteErr := os.Remove(s.selectedPath); deleteErr != nil { statusMsg += " • source kept" } else { statusMsg += " • source deleted" } }
-				s.statusLabel.SetText(statusMsg); if fileSize>0 { s.addFile(fileSize) }
-			}
-		})
-		if err != nil { s.noteError(err) }
-		sum := s.finishSummary(); fyne.Do(func(){ if sum!=nil { s.showSummaryDialog(w,sum) } })
-
-		s.config.AddHistoryEntry(historyEntry)
+		// the entry and the summary are settled here, before the job ends; only widgets wait for the UI
+		if err != nil && apperr.Classify(err) == apperr.Canceled {
+			s.markCanceled(); s.setStatus("⏹️ Canceled")
+		} else if err != nil {
+			historyEntry.Result = "error"; historyEntry.Error = err.Error(); s.noteError(err)
+			path, repairable := s.selectedPath, errors.Is(err, cryptoengine.ErrCorrupt) && s.isHadesCryptFile(s.selectedPath)
+			s.ui(func() {
+				s.statusLabel.SetText("❌ "+err.Error())
+				if repairable { s.offerRepair(w, path, finalPassword, err) } else { dialog.ShowError(err, w) }
+			})
+		} else {
+			historyEntry.Result, historyEntry.Output = "success", outputPath; statusMsg := fmt.Sprintf("✅ Decrypted → %s (%s)", filepath.Base(outputPath), elapsed)
+			if s.deleteAfter { if deleteErr := s.removeDecryptedSource(input, outputPath); deleteErr != nil { statusMsg += " • source kept" } else { statusMsg += " • source deleted" } }
+			if fileSize>0 { s.addFile(fileSize) }
+			s.setStatus(statusMsg)
+		}
+		s.addHistory(historyEntry)
 		s.config.Save()
+		sum := s.finishSummary(); s.showSummary(w, sum)
 	}()
 }
 
 func (s *AppState) encryptDirectory(inputDir, outputPath string, password []byte, onProgress cryptoengine.ProgressCallback) error {
-	// Create temporary tar.gz file
-	tempArchive := outputPath + ".temp.tar.gz"
-	defer os.Remove(tempArchive)
-
-	// Phase 1: create archive (0-50%)
-	err := archiver.CreateTarGz(inputDir, tempArchive, func(processed, total int64) {
-		if onProgress != nil && total > 0 {
-			progress := float64(processed) / float64(total) * 0.5
-			onProgress(int64(progress*float64(total)), total)
-		}
-	})
+	// Create temporary tar.gz file (owner-only, next to the output)
+	tempArchive, err := securetemp.TempPath(archiveTempDir(outputPath), filepath.Base(outputPath)+".*.temp.tar.gz")
+	if err != nil {
+		return fmt.Errorf("create temp archive: %w", err)
+	}
+	defer securetemp.Remove(tempArchive)
+
+	var fileCount int
+	var totalBytes int64
+	ops := s.ops()
+	walkOpts := ops.WalkOptions(inputDir, false)
+	files, err := ops.ScanWith(inputDir, walkOpts, func(e fswalk.Entry) bool { return e.Info.Mode().IsRegular() })
+	if errors.Is(err, apperr.ErrCanceled) { return err }
+	for _, f := range files { fileCount++; totalBytes += f.Info.Size() }
+	// Progress is reported on the folder's byte scale: archiving reads every source
+	// byte, encryption then processes the (smaller) archive
+	track := progress.New(totalBytes, progress.Func(onProgress))
+	archivePhase, encryptPhase := track.Phase(totalBytes), track.Phase(totalBytes)
+
+	err = archiver.CreateTarGzWithOptions(inputDir, tempArchive, walkOpts, archivePhase.Update)
 	if err != nil {
 		return fmt.Errorf("create archive: %w", err)
 	}
+	archivePhase.Complete()
+	if fi, err := os.Stat(tempArchive); err == nil { encryptPhase.SetWeight(fi.Size()) }
 
 	// Compute SHA-256 of plaintext archive for integrity metadata
 	archiveHash := ""
@@ -987,26 +1239,18 @@ func (s *AppState) encryptDirectory(inputDir, outputPath string, password []byte
 		}()
 	}
 
-	// Phase 2: encrypt archive (50-100%)
-	err = cryptoengine.EncryptFile(tempArchive, outputPath, password, func(processed, total int64) {
-		if onProgress != nil && total > 0 {
-			progress := 0.5 + (float64(processed)/float64(total))*0.5
-			onProgress(int64(progress*float64(total)), total)
-		}
-	})
+	archiveOpts := cryptoengine.EncryptionOptions{Mode: cryptoengine.ModeAES256GCM, Compliance: s.config.ComplianceMode, Argon2: cryptoengine.Argon2Preset(s.opt().Argon2Preset), Comments: s.comments}
+	archiveOpts.Metadata.Name = s.ops().OriginalName(inputDir)
+	if err := s.checkPolicy(archiveOpts); err != nil { return err }
+	err = withMediaRetry(tempArchive, outputPath, func() error { return cryptoengine.EncryptFileWithOptions(tempArchive, outputPath, password, archiveOpts, encryptPhase.Update) })
 	if err != nil {
 		return fmt.Errorf("encrypt archive: %w", err)
 	}
+	if err := s.escrowOutput(outputPath, password); err != nil { return err }
+	encryptPhase.Complete()
 
 	// Sidecar metadata (.meta JSON)
 	metaPath := outputPath + ".meta"
-	var fileCount int
-	var totalBytes int64
-	filepath.Walk(inputDir, func(p string, info os.FileInfo, err error) error {
-		if err != nil { return nil }
-		if info != nil && !info.IsDir() { fileCount++; totalBytes += info.Size() }
-		return nil
-	})
 	metaJSON := fmt.Sprintf("{\n  \"type\": \"archive-folder\",\n  \"original_folder\": %q,\n  \"file_count\": %d,\n  \"total_size\": %d,\n  \"archive_sha256\": %q\n}", filepath.Base(inputDir), fileCount, totalBytes, archiveHash)
 	os.WriteFile(metaPath, []byte(metaJSON), 0600)
 
@@ -1017,60 +1261,63 @@ func (s *AppState) encryptDirectory(inputDir, outputPath string, password []byte
 // Each file produces <name>.hadescrypt (or .gpg) beside original. Progress aggregated by total bytes.
 func (s *AppState) encryptDirectoryRecursive(inputDir string, password []byte, onProgress cryptoengine.ProgressCallback) error {
 	var totalBytes int64
-	var files []string
-	// Collect files
-	err := filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil { return err }
-		if info.IsDir() { return nil }
-		// Skip already encrypted outputs
-		lower := strings.ToLower(path)
-		if strings.HasSuffix(lower, ".hadescrypt") || strings.HasSuffix(lower, ".heistcrypt") || strings.HasSuffix(lower, ".gpg") { return nil }
-		files = append(files, path)
-		totalBytes += info.Size()
-		return nil
-	})
-	if err != nil { return err }
+	// Collect files, honoring .hadesignore (the ignore files stay in plaintext so they keep
+	// working) and skipping already encrypted outputs
+	ops := s.ops()
+	entries, err := s.scanFolder(inputDir, operations.EncryptCandidate)
+	if err != nil {
+		// an unreadable subfolder loses what is inside it; everything listed is still encrypted
+		if errors.Is(err, apperr.ErrCanceled) || ops.AbortOnUnreadable { return err }
+		s.noteSkipped(inputDir, err)
+	}
+	for _, e := range entries { totalBytes += e.Info.Size() }
 	if totalBytes == 0 { return fmt.Errorf("no files to encrypt in directory") }
 
-	var processedBytes int64
-	for _, file := range files {
-		if s.cancelRequested.Load() { return fmt.Errorf("canceled") }
+	track := progress.New(totalBytes, progress.Func(onProgress))
+	phases := make([]*progress.Phase, len(entries))
+	for i, e := range entries { phases[i] = track.Phase(e.Info.Size()) }
+	for i, e := range entries {
+		file := e.Path
+		if s.cancelRequested.Load() { return apperr.ErrCanceled }
 		rel, _ := filepath.Rel(inputDir, file)
-		// progress callback for single file
-		fi, _ := os.Stat(file)
-		singleSize := fi.Size()
-		fileOutput := file + ".hadescrypt"
-		if s.encryptionMode == cryptoengine.ModeGnuPG { fileOutput = file + ".gpg" }
-		err := cryptoengine.EncryptFileWithMode(file, fileOutput, password, s.encryptionMode, func(done, total int64){
-			// translate per-file progress into global progress (estimate): processedBytes + done
-			if onProgress != nil && totalBytes > 0 {
-				onProgress(processedBytes+done, totalBytes)
-			}
-		})
+		if err := operations.CheckReadable(file); err != nil {
+			if ops.AbortOnUnreadable { return fmt.Errorf("encrypt %s: %w", rel, err) }
+			s.noteSkipped(file, err)
+			phases[i].Complete()
+			continue
+		}
+		fileOutput := ops.EncryptedPathInPlace(file)
+		err := withMediaRetry(file, fileOutput, func() error { return cryptoengine.EncryptFileWithOptions(file, fileOutput, password, s.encryptOptionsFor(file), phases[i].Update) })
+		if err == nil { err = s.escrowOutput(fileOutput, password) }
 		if err != nil { return fmt.Errorf("encrypt %s: %w", rel, err) }
-		processedBytes += singleSize
-		if onProgress != nil { onProgress(processedBytes, totalBytes) }
-		if s.deleteAfter { os.Remove(file) }
+		s.indexOutput(fileOutput, file)
+		s.timestampOutput(fileOutput)
+		phases[i].Complete()
+		if s.deleteAfter { s.removeEncryptedSource(file, fileOutput, password) }
+		if err := s.splitOutput(fileOutput); err != nil { return fmt.Errorf("encrypt %s: %w", rel, err) }
 	}
 	return nil
 }
 
 func (s *AppState) decryptDirectory(encryptedFile, outputDir string, password []byte, onProgress cryptoengine.ProgressCallback) error {
 	// Create temporary file for decrypted archive
-	tempArchive := encryptedFile + ".temp.tar.gz"
-	defer os.Remove(tempArchive) // Clean up temp file
+	tempArchive, err := securetemp.TempPath(filepath.Dir(encryptedFile), filepath.Base(encryptedFile)+".*.temp.tar.gz")
+	if err != nil {
+		return fmt.Errorf("create temp archive: %w", err)
+	}
+	defer securetemp.Remove(tempArchive) // Clean up temp file and its private folder
+
+	var encSize int64
+	if fi, err := os.Stat(encryptedFile); err == nil { encSize = fi.Size() }
+	track := progress.New(encSize, progress.Func(onProgress))
+	decryptPhase, extractPhase := track.Phase(encSize), track.Phase(encSize)
 
 	// First decrypt the file
-	err := cryptoengine.DecryptFile(encryptedFile, tempArchive, password, false, func(processed, total int64) {
-		// Report progress for decryption phase (0-50%)
-		if onProgress != nil && total > 0 {
-			progress := float64(processed) / float64(total) * 0.5
-			onProgress(int64(progress*float64(total)), total)
-		}
-	})
+	err = withMediaRetry(encryptedFile, tempArchive, func() error { return cryptoengine.DecryptFile(encryptedFile, tempArchive, password, false, decryptPhase.Update) })
 	if err != nil {
 		return fmt.Errorf("decrypt file: %w", err)
 	}
+	decryptPhase.Complete()
 
 	// Check if the decrypted file is actually a tar.gz archive
 	if !archiver.IsArchive(tempArchive) {
@@ -1079,16 +1326,12 @@ func (s *AppState) decryptDirectory(encryptedFile, outputDir string, password []
 	}
 
 	// Extract the archive
-	err = archiver.ExtractTarGz(tempArchive, outputDir, func(processed, total int64) {
-		// Report progress for extraction phase (50-100%)
-		if onProgress != nil && total > 0 {
-			progress := 0.5 + (float64(processed)/float64(total))*0.5
-			onProgress(int64(progress*float64(total)), total)
-		}
-	})
+	if fi, err := os.Stat(tempArchive); err == nil { extractPhase.SetWeight(fi.Size()) }
+	err = archiver.ExtractTarGzWithLimits(tempArchive, outputDir, s.extractLimits(), extractPhase.Update)
 	if err != nil {
 		return fmt.Errorf("extract archive: %w", err)
 	}
+	extractPhase.Complete()
 
 	return nil
 }
@@ -1100,26 +1343,21 @@ func (s *AppState) decryptFileAuto(encryptedFile, outputPath string, password []
 	// Read header quickly for integrity (HadesCrypt only)
 	var expectedSize int64 = -1
 	if s.isHadesCryptFile(encryptedFile) {
-		f, err := os.Open(encryptedFile)
-		if err == nil {
-			defer f.Close()
-			buf := make([]byte, 4)
-			if _, err := io.ReadFull(f, buf); err == nil && string(buf) == "HAD1" {
-				// version
-				ver := make([]byte,1); io.ReadFull(f,ver)
-				mode := make([]byte,1); io.ReadFull(f,mode)
-				salt := make([]byte,16); io.ReadFull(f,salt)
-				nonce := make([]byte,8); io.ReadFull(f,nonce)
-				cs := make([]byte,4); io.ReadFull(f,cs)
-				osz := make([]byte,8); if _, err := io.ReadFull(f,osz); err==nil { expectedSize = int64(binary.BigEndian.Uint64(osz)) }
-			}
-		}
+		if hdr, err := cryptoengine.ReadHeaderWithPassword(encryptedFile, password); err == nil { expectedSize = hdr.OriginalSize }
 	}
-	tempDecrypted := encryptedFile + ".__dec_tmp__"
-	defer os.Remove(tempDecrypted)
+	tempDecrypted, err := securetemp.TempPath(filepath.Dir(encryptedFile), filepath.Base(encryptedFile)+".*.__dec_tmp__")
+	if err != nil { return err }
+	defer securetemp.Remove(tempDecrypted)
+	// Decryption and optional extraction share one progress scale (encrypted bytes)
+	var encSize int64
+	if fi, err := os.Stat(encryptedFile); err == nil { encSize = fi.Size() } else if _, size, err := splitter.GetChunkInfo(encryptedFile); err == nil { encSize = size }
+	track := progress.New(encSize, progress.Func(onProgress))
+	decryptPhase, extractPhase := track.Phase(encSize), track.Phase(0)
+	defer extractPhase.Complete()
 	// low-level decrypt (not directory)
-	err := cryptoengine.DecryptFile(encryptedFile, tempDecrypted, password, s.forceDecrypt, onProgress)
+	err = withMediaRetry(encryptedFile, tempDecrypted, func() error { return cryptoengine.DecryptFile(encryptedFile, tempDecrypted, password, s.opt().ForceDecrypt, decryptPhase.Update) })
 	if err != nil { return err }
+	decryptPhase.Complete()
 	// Check if decrypted is archive
 	if archiver.IsArchive(tempDecrypted) {
 		// Optional hash verification via sidecar meta
@@ -1148,18 +1386,17 @@ func (s *AppState) decryptFileAuto(encryptedFile, outputPath string, password []
 					f.Close()
 					calc := hex.EncodeToString(h.Sum(nil))
 					if !strings.EqualFold(calc, expectedHash) {
-						fyne.Do(func(){ s.statusLabel.SetText("❌ Hash mismatch — decryption aborted") })
+						s.setStatus("❌ Hash mismatch — decryption aborted")
 						return fmt.Errorf("archive hash mismatch (expected %s got %s)", expectedHash, calc)
 					}
-					fyne.Do(func(){ s.statusLabel.SetText("🔐 Hash verified OK — extracting...") })
+					s.setStatus("🔐 Hash verified OK — extracting...")
 				}
 			}
 		}
 		// Ensure directory target
 		if err := os.MkdirAll(outputPath, 0755); err != nil { return err }
-		var archCb archiver.ProgressCallback
-		if onProgress != nil { archCb = func(done,total int64){ onProgress(done,total) } }
-		if err := archiver.ExtractTarGz(tempDecrypted, outputPath, archCb); err != nil {
+		if fi, err := os.Stat(tempDecrypted); err == nil { extractPhase.SetWeight(fi.Size()) }
+		if err := archiver.ExtractTarGzWithLimits(tempDecrypted, outputPath, s.extractLimits(), extractPhase.Update); err != nil {
 			return fmt.Errorf("extract archive: %w", err)
 		}
 		// Remove sidecar meta if exists
@@ -1181,84 +1418,63 @@ func (s *AppState) decryptFileAuto(encryptedFile, outputPath string, password []
 func (s *AppState) decryptDirectoryRecursive(root string, password []byte, onProgress cryptoengine.ProgressCallback) error {
 	var encryptedFiles []string
 	var totalBytes int64
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil { return err }
-		if info.IsDir() { return nil }
-		lower := strings.ToLower(path)
-		if strings.HasSuffix(lower, ".hadescrypt") || strings.HasSuffix(lower, ".heistcrypt") || strings.HasSuffix(lower, ".gpg") || strings.HasSuffix(lower, ".pgp") {
-			encryptedFiles = append(encryptedFiles, path)
-			totalBytes += info.Size()
-		}
-		return nil
-	})
+	entries, err := s.scanEncrypted(root, operations.DecryptCandidate)
 	if err != nil { return err }
+	for _, e := range entries {
+		encryptedFiles = append(encryptedFiles, e.Path)
+		totalBytes += e.Info.Size()
+	}
 	if len(encryptedFiles) == 0 { return fmt.Errorf("no encrypted files found in folder") }
 
-	var processedBytes int64
+	track := progress.New(totalBytes, progress.Func(onProgress))
+	sizes := make([]int64, len(encryptedFiles))
+	phases := make([]*progress.Phase, len(encryptedFiles))
+	for i, file := range encryptedFiles {
+		if fi, err := os.Stat(file); err == nil { sizes[i] = fi.Size() }
+		phases[i] = track.Phase(sizes[i])
+	}
 	for i, file := range encryptedFiles {
-		if s.cancelRequested.Load() { return fmt.Errorf("canceled") }
+		if s.cancelRequested.Load() { return apperr.ErrCanceled }
 		rel, _ := filepath.Rel(root, file)
-		fyne.Do(func(){ s.statusLabel.SetText(fmt.Sprintf("Decrypting %d/%d: %s", i+1, len(encryptedFiles), rel)) })
+		s.setStatus(fmt.Sprintf("Decrypting %d/%d: %s", i+1, len(encryptedFiles), rel))
 		outPath := s.defaultOutputPathForDecrypt(file)
-		fi, _ := os.Stat(file)
-		size := fi.Size()
+		size := sizes[i]
 		// choose method
 		var derr error
-		if s.isGnuPGFile(file) {
-			derr = cryptoengine.DecryptFileWithGnuPG(file, outPath, password, func(done,total int64){ if onProgress!=nil { onProgress(processedBytes+done,totalBytes) } })
-		} else if s.isHadesCryptFile(file) {
-			derr = s.decryptFileAuto(file, outPath, password, func(done,total int64){ if onProgress!=nil { onProgress(processedBytes+done,totalBytes) } })
+		// containers first: Deniability Mode output may start like an OpenPGP packet
+		if s.isHadesCryptFile(file) {
+			derr = s.decryptFileAuto(file, outPath, password, phases[i].Update)
+		} else if s.isGnuPGFile(file) {
+			derr = withMediaRetry(file, outPath, func() error { return cryptoengine.DecryptFileWithGnuPGAt(s.gpgPath(), file, outPath, password, phases[i].Update) })
+		} else if format.IsAge(file) {
+			derr = withMediaRetry(file, outPath, func() error { return s.decryptAge(file, outPath, []byte(s.password), phases[i].Update) })
 		} else {
-			derr = cryptoengine.DecryptFile(file, outPath, password, s.forceDecrypt, func(done,total int64){ if onProgress!=nil { onProgress(processedBytes+done,totalBytes) } })
+			derr = withMediaRetry(file, outPath, func() error { return cryptoengine.DecryptFile(file, outPath, password, s.opt().ForceDecrypt, phases[i].Update) })
 		}
 		if derr != nil { return fmt.Errorf("decrypt %s: %w", rel, derr) }
 		// history entry
-		hist := config.HistoryEntry{FileName: rel, Operation: "decrypt", Size: size, Timestamp: time.Now().Unix(), Result: "success"}
-		s.config.AddHistoryEntry(hist)
-		processedBytes += size
-		if onProgress != nil { onProgress(processedBytes, totalBytes) }
-		if s.deleteAfter { os.Remove(file) }
+		hist := config.HistoryEntry{FileName: rel, Operation: "decrypt", Size: size, Timestamp: time.Now().Unix(), Result: "success", Output: outPath}
+		s.addHistory(hist)
+		phases[i].Complete()
+		if s.deleteAfter { s.removeDecryptedSource(file, outPath) }
 	}
-	fyne.Do(func(){ s.statusLabel.SetText(fmt.Sprintf("✅ Decrypted %d files", len(encryptedFiles))) })
+	s.setStatus(fmt.Sprintf("✅ Decrypted %d files", len(encryptedFiles)))
 	s.config.Save()
 	return nil
 }
 
 func (s *AppState) defaultOutputPathForEncrypt(inPath string) string {
-	// Use appropriate extension based on encryption mode
-	if s.encryptionMode == cryptoengine.ModeGnuPG {
-		return inPath + ".gpg"
-	}
-	return inPath + ".hadescrypt"
+	return s.ops().EncryptedPath(inPath)
 }
 
 func (s *AppState) defaultOutputPathForDecrypt(inPath string) string {
-	lowerPath := strings.ToLower(inPath)
-	
-	// Handle GnuPG files
-	if strings.HasSuffix(lowerPath, ".gpg") {
-		return strings.TrimSuffix(inPath, ".gpg")
-	}
-	if strings.HasSuffix(lowerPath, ".pgp") {
-		return strings.TrimSuffix(inPath, ".pgp")
-	}
-	
-    // Handle HadesCrypt files
-    if strings.HasSuffix(lowerPath, ".hades") {
-        return strings.TrimSuffix(inPath, ".hades")
-    }
-    if strings.HasSuffix(lowerPath, ".hadescrypt") || strings.HasSuffix(lowerPath, ".heistcrypt") {
-        return strings.TrimSuffix(inPath, filepath.Ext(inPath))
-    }
-	
-    return inPath + ".dec"
+	return s.ops().DecryptedPath(inPath)
 }
 
 // isGnuPGFile checks if the file is a GnuPG/OpenPGP file
 func (s *AppState) isGnuPGFile(filePath string) bool {
 	// Check by extension first
-	lowerPath := strings.ToLower(filePath)
-	if strings.HasSuffix(lowerPath, ".gpg") || strings.HasSuffix(lowerPath, ".pgp") {
+	if format.IsOpenPGP(filePath) {
 		return true
 	}
 	
@@ -1287,18 +1503,20 @@ func (s *AppState) isGnuPGFile(filePath string) bool {
 	return false
 }
 
-// isHadesCryptFile detects files produced by HadesCrypt (.hadescrypt or .heistcrypt) using extension and magic header.
+// isHadesCryptFile detects files produced by HadesCrypt (.hadescrypt or .heistcrypt) using extension and magic header,
+// or for Deniability Mode output, which has no magic, a start that is not that of another known format.
 func (s *AppState) isHadesCryptFile(path string) bool {
-	lower := strings.ToLower(path)
-	if !(strings.HasSuffix(lower, ".hadescrypt") || strings.HasSuffix(lower, ".heistcrypt")) {
+	if !format.IsHadesCrypt(path) {
 		return false
 	}
+	// a container split into volumes starts in the first one
+	if _, parts := splitter.Volumes(path); parts != nil { path = parts[0] }
 	f, err := os.Open(path)
 	if err != nil { return false }
 	defer f.Close()
 	header := make([]byte, 4)
 	if _, err := f.Read(header); err != nil { return false }
-	return string(header) == "HAD1"
+	return string(header) == "HAD1" || cryptoengine.MayBeDeniable(path)
 }
 
 func (s *AppState) updateKeyfilesDisplay() {
@@ -1501,53 +1719,54 @@ func (s *AppState) showPasswordGeneratorDialog(w fyne.Window) {
 	d.Show()
 }
 
-func (s *AppState) buildAdvancedPanel() *widget.Accordion {
-	// Initialize defaults
-	s.splitSize = 100
-	s.splitUnit = "MiB"
-
+func (s *AppState) buildAdvancedPanel(w fyne.Window) *widget.Accordion {
 	deleteCheck := widget.NewCheck("Delete source files after operation", func(checked bool) {
 		s.deleteAfter = checked
 	})
 	deleteCheck.SetChecked(true) // Set as default
+	if s.viewer {
+		deleteCheck.SetChecked(false)
+		deleteCheck.SetText("Delete source files after operation (unavailable in viewer mode)")
+		deleteCheck.Disable()
+	} else if !s.adminPolicy.DeleteAfterAllowed() {
+		deleteCheck.SetChecked(false)
+		deleteCheck.SetText("Delete source files after operation (disabled by your administrator)")
+		deleteCheck.Disable()
+	}
 	
-	keyfilesCheck := widget.NewCheck("Use Keyfiles", func(checked bool) {
-		s.useKeyfiles = checked
-	})
+	keyfilesCheck := widget.NewCheckWithData("Use Keyfiles", s.options.useKeyfiles)
 	
 	requireOrderCheck := widget.NewCheck("Require correct keyfile order", func(checked bool) {
 		s.keyfileManager.RequireOrder = checked
 	})
 	
-	paranoidCheck := widget.NewCheck("Paranoid Mode (XChaCha20 + Serpent)", func(checked bool) {
-		s.paranoidMode = checked
-	})
-	
-	rsCheck := widget.NewCheck("Reed-Solomon ECC (error correction)", func(checked bool) {
-		s.reedSolomon = checked
-	})
-	
-	forceCheck := widget.NewCheck("Force Decrypt (ignore integrity errors)", func(checked bool) {
-		s.forceDecrypt = checked
+	paranoidCheck := widget.NewCheckWithData("Paranoid Mode (XChaCha20 + Serpent)", s.options.paranoid)
+
+	complianceCheck := widget.NewCheck("Compliance Mode (FIPS-approved: AES-256-GCM + PBKDF2 only)", func(checked bool) {
+		changed := s.config.ComplianceMode != checked
+		s.config.ComplianceMode = checked
+		s.applyComplianceMode(checked)
+		if checked {
+			s.options.paranoid.Set(false)
+			paranoidCheck.Disable()
+		} else {
+			paranoidCheck.Enable()
+		}
+		if changed { s.config.Save() }
 	})
+	complianceCheck.SetChecked(s.config.ComplianceMode)
 	
-	splitCheck := widget.NewCheck("Split into chunks", func(checked bool) {
-		s.splitOutput = checked
-	})
+	rsCheck := widget.NewCheckWithData("Reed-Solomon ECC (error correction)", s.options.reedSolomon)
+	forceCheck := widget.NewCheckWithData("Force Decrypt (ignore integrity errors)", s.options.forceDecrypt)
+	splitCheck := widget.NewCheckWithData("Split into chunks", s.options.split)
 	
 	// Split size controls
-	splitSizeEntry := widget.NewEntry()
-	splitSizeEntry.SetText("100")
-	splitSizeEntry.OnChanged = func(text string) {
-		if size, err := strconv.Atoi(text); err == nil && size > 0 {
-			s.splitSize = size
-		}
+	splitSizeEntry := widget.NewEntryWithData(binding.IntToString(s.options.splitSize))
+	splitSizeEntry.Validator = func(text string) error {
+		if size, err := strconv.Atoi(text); err != nil || size <= 0 { return errors.New("enter a whole number above zero") }
+		return nil
 	}
-	
-	splitUnitSelect := widget.NewSelect([]string{"KiB", "MiB", "GiB", "TiB"}, func(unit string) {
-		s.splitUnit = unit
-	})
-	splitUnitSelect.SetSelected("MiB")
+	splitUnitSelect := widget.NewSelectWithData(splitUnits, s.options.splitUnit)
 	
 	splitRow := container.NewHBox(
 		widget.NewLabel("Size:"),
@@ -1555,20 +1774,70 @@ func (s *AppState) buildAdvancedPanel() *widget.Accordion {
 		splitUnitSelect,
 	)
 	
-	compressCheck := widget.NewCheck("Compress files (Deflate)", func(checked bool) {
-		s.compressFiles = checked
+	compressCheck := widget.NewCheckWithData("Compress files (Deflate)", s.options.compress)
+	denyCheck := widget.NewCheckWithData("Deniability Mode (hide encryption)", s.options.deniability)
+	recursiveCheck := widget.NewCheckWithData("Recursive Mode (process files individually)", s.options.recursive)
+	abortUnreadableCheck := widget.NewCheckWithData("Stop at unreadable files (otherwise skip and list them in the summary)", s.options.abortUnreadable)
+
+	allOrNothingCheck := widget.NewCheck("All-or-nothing batches (keep outputs only if every item succeeds)", func(checked bool) {
+		s.allOrNothing = checked
 	})
-	
-	denyCheck := widget.NewCheck("Deniability Mode (hide encryption)", func(checked bool) {
-		s.deniabilityMode = checked
+
+	var indexCheck *widget.Check
+	indexCheck = widget.NewCheck("Add encrypted outputs to search index", func(checked bool) {
+		s.indexEnabled = checked
+		if checked && s.indexLocked() {
+			s.showUnlockIndexDialog(w, func(ok bool) {
+				if !ok { indexCheck.SetChecked(false) }
+			})
+		}
 	})
-	
-	recursiveCheck := widget.NewCheck("Recursive Mode (process files individually)", func(checked bool) {
-		s.recursiveMode = checked
+
+	tsaEntry := widget.NewEntry()
+	tsaEntry.SetPlaceHolder(timestamp.DefaultURL)
+	tsaEntry.SetText(s.config.TimestampURL)
+	tsaEntry.OnChanged = func(text string) { s.config.TimestampURL = strings.TrimSpace(text) }
+	timestampCheck := widget.NewCheck("Trusted timestamp (RFC 3161) for encrypted outputs", func(checked bool) {
+		if s.config.TimestampEnabled != checked {
+			s.config.TimestampEnabled = checked
+			s.config.Save()
+		}
 	})
+	timestampCheck.SetChecked(s.config.TimestampEnabled)
+
+	systemPromptCheck := widget.NewCheck("Enter passwords via system prompt (pinentry / OS dialog)", func(checked bool) {
+		changed := s.config.UseSystemPrompt != checked
+		s.config.UseSystemPrompt = checked
+		s.applySystemPrompt(checked)
+		if changed { s.config.Save() }
+	})
+	systemPromptCheck.SetChecked(s.config.UseSystemPrompt)
+	timestampRow := container.NewBorder(nil, nil, widget.NewLabel("TSA URL:"),
+		widget.NewButton("⏱ Verify", func() { s.doVerifyTimestamp(w) }), tsaEntry)
+
+	lowPriorityCheck, rateRow := s.buildBackgroundControls()
+	adaptiveCheck := widget.NewCheck("Adaptive chunk size (grows with disk / network speed)", func(checked bool) {
+		if s.config.AdaptiveChunks != checked {
+			s.config.AdaptiveChunks = checked
+			s.config.Save()
+			s.refreshSizePreview()
+		}
+	})
+	adaptiveCheck.SetChecked(s.config.AdaptiveChunks)
+	symlinkSelect := s.buildSymlinkSelect()
+	gnupgRow := s.buildGnuPGControls(w)
+
+	outputRow := s.buildOutputControls(w)
+	profileRow := s.buildProfileRow(w)
 
     content := container.NewVBox(
+		profileRow,
+		outputRow,
+		widget.NewSeparator(),
 		deleteCheck,
+		allOrNothingCheck,
+		complianceCheck,
+		systemPromptCheck,
 		widget.NewSeparator(),
 		keyfilesCheck,
 		container.NewPadded(requireOrderCheck),
@@ -1581,6 +1850,16 @@ func (s *AppState) buildAdvancedPanel() *widget.Accordion {
 		compressCheck,
 		denyCheck,
 		recursiveCheck,
+		container.NewPadded(abortUnreadableCheck),
+		container.NewPadded(symlinkRow(symlinkSelect)),
+		lowPriorityCheck,
+		container.NewPadded(rateRow),
+		adaptiveCheck,
+		indexCheck,
+		timestampCheck,
+		container.NewPadded(timestampRow),
+		widget.NewSeparator(),
+		gnupgRow,
 	)
 	
 	item := widget.NewAccordionItem("Advanced Options ▼", content)
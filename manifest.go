@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/manifest"
+)
+
+// showManifestDialog offers signing the selection or verifying a received manifest
+func (s *AppState) showManifestDialog(w fyne.Window) {
+	var d dialog.Dialog
+	signBtn := widget.NewButton("✍ Sign selected containers", func() { d.Hide(); s.doSignManifest(w) })
+	verifyBtn := widget.NewButton("✔ Verify a manifest…", func() { d.Hide(); s.doVerifyManifest(w) })
+	info := widget.NewLabel("A signed manifest lists names, sizes and SHA-256 hashes of encrypted files so recipients can check a distributed set is complete and untampered before decrypting.")
+	info.Wrapping = fyne.TextWrapWord
+	d = dialog.NewCustom("Signed Manifest", "Close", container.NewVBox(info, signBtn, verifyBtn), w)
+	d.Resize(fyne.NewSize(480, 240))
+	d.Show()
+}
+
+// manifestTargets returns the encrypted files of the selection and the folder the manifest is written to
+func (s *AppState) manifestTargets() ([]string, string) {
+	var paths []string
+	if s.selectedPath != "" { paths = []string{s.selectedPath} } else { paths = s.selectedPaths }
+	if len(paths) == 0 { return nil, "" }
+
+	base := filepath.Dir(paths[0])
+	if fi, err := os.Stat(paths[0]); err == nil && fi.IsDir() && len(paths) == 1 { base = paths[0] }
+
+	var files []string
+	for _, p := range paths {
+		filepath.Walk(p, func(sp string, info os.FileInfo, err error) error {
+			if err != nil || info == nil || !info.Mode().IsRegular() { return nil }
+			if s.isHadesCryptFile(sp) || format.IsOpenPGP(sp) { files = append(files, sp) }
+			return nil
+		})
+	}
+	return files, base
+}
+
+// doSignManifest writes an ed25519-signed manifest of the selected containers
+func (s *AppState) doSignManifest(w fyne.Window) {
+	files, base := s.manifestTargets()
+	if len(files) == 0 {
+		dialog.ShowInformation("Sign Manifest", "Select encrypted files or a folder containing them.", w)
+		return
+	}
+	keyPath, err := manifest.KeyPath()
+	if err != nil { dialog.ShowError(err, w); return }
+
+	s.statusLabel.SetText(fmt.Sprintf("✍ Hashing %d file(s) for manifest…", len(files)))
+	go func() {
+		out := filepath.Join(base, "MANIFEST"+manifest.Extension)
+		key, err := manifest.LoadOrCreateKey(keyPath)
+		var m *manifest.Manifest
+		if err == nil { m, err = manifest.Build(base, files) }
+		if err == nil { err = manifest.Write(out, m, key) }
+		s.ui(func() {
+			if err != nil {
+				s.statusLabel.SetText("❌ Manifest failed: " + err.Error())
+				dialog.ShowError(err, w)
+				return
+			}
+			s.statusLabel.SetText("✅ Signed manifest written")
+			dialog.ShowInformation("Manifest Signed", fmt.Sprintf(
+				"%d file(s) listed in\n%s\n\nSigner fingerprint: %s\nPublish this fingerprint so recipients can check who signed the set.",
+				len(m.Files), out, manifest.Fingerprint(m.SignerKey())), w)
+		})
+	}()
+}
+
+// doVerifyManifest checks a manifest's signature and the artifacts next to it
+func (s *AppState) doVerifyManifest(w fyne.Window) {
+	fd := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil { dialog.ShowError(err, w); return }
+		if rc == nil { return }
+		path := rc.URI().Path()
+		rc.Close()
+
+		s.statusLabel.SetText("✔ Verifying manifest…")
+		go func() {
+			res, err := manifest.Verify(path)
+			s.ui(func() {
+				if err != nil {
+					s.statusLabel.SetText("❌ Manifest rejected: " + err.Error())
+					dialog.ShowError(err, w)
+					return
+				}
+				var sb strings.Builder
+				fmt.Fprintf(&sb, "Signature: valid\nSigner fingerprint: %s\n\n", manifest.Fingerprint(res.Manifest.SignerKey()))
+				fmt.Fprintf(&sb, "%d of %d file(s) verified\n", res.Verified, len(res.Manifest.Files))
+				for _, n := range res.Missing { fmt.Fprintf(&sb, "❌ missing: %s\n", n) }
+				for _, n := range res.Mismatched { fmt.Fprintf(&sb, "❌ modified: %s\n", n) }
+				if res.OK() {
+					s.statusLabel.SetText("✅ Manifest verified — set is complete")
+					sb.WriteString("\nCompare the fingerprint with the one the sender published.")
+				} else {
+					s.statusLabel.SetText("⚠️ Manifest verification found problems")
+				}
+				lbl := widget.NewLabel(sb.String())
+				lbl.Wrapping = fyne.TextWrapWord
+				d := dialog.NewCustom("Manifest Verification", "Close", container.NewScroll(lbl), w)
+				d.Resize(fyne.NewSize(520, 380))
+				d.Show()
+			})
+		}()
+	}, w)
+	fd.Show()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+	"github.com/bangundwir/HadesCrypt/internal/timestamp"
+)
+
+// withPasswordHint adds the container's password hint to a wrong-password error
+func withPasswordHint(err error, path string) error {
+	if !errors.Is(err, cryptoengine.ErrAuthFailed) { return err }
+	hdr, herr := cryptoengine.ReadHeaderFromFile(path)
+	if herr != nil || hdr.Metadata.Hint == "" { return err }
+	return fmt.Errorf("%w\n\nPassword hint: %s", err, hdr.Metadata.Hint)
+}
+
+// showMetadataDialog edits the comment, password hint and original name kept in the
+// selected container's header
+func (s *AppState) showMetadataDialog(w fyne.Window) {
+	if s.denyInViewer(w) { return }
+	path := s.selectedPath
+	if path == "" || !s.isHadesCryptFile(path) {
+		dialog.ShowInformation("File Details", "Select a single HadesCrypt container (.hadescrypt).", w)
+		return
+	}
+	hdr, err := cryptoengine.ReadHeaderFromFile(path)
+	if errors.Is(err, cryptoengine.ErrNotContainer) {
+		dialog.ShowInformation("File Details", "Deniability Mode output keeps its details inside the wrapping, where they cannot be edited.", w)
+		return
+	}
+	if err != nil { dialog.ShowError(err, w); return }
+	if hdr.Mode == cryptoengine.ModeGnuPG {
+		dialog.ShowInformation("File Details", "GnuPG containers keep no HadesCrypt details.", w)
+		return
+	}
+
+	comment := widget.NewMultiLineEntry()
+	comment.SetText(hdr.Metadata.Comment)
+	comment.SetMinRowsVisible(4)
+	hint := widget.NewEntry()
+	hint.SetText(hdr.Metadata.Hint)
+	hint.SetPlaceHolder("Shown after a wrong password")
+	name := widget.NewEntry()
+	name.SetText(hdr.Metadata.Name)
+	limitEntry(comment, cryptoengine.MaxCommentBytes, func() { s.statusLabel.SetText(commentTrimmedNote()) })
+	limitEntry(hint, cryptoengine.MaxHintBytes, nil)
+	limitEntry(name, cryptoengine.MaxNameBytes, nil)
+	name.SetPlaceHolder(filepath.Base(format.DecryptedPathFor(path)))
+
+	note := "These details are stored unencrypted in the header: anyone with the file can read them, but they cannot be changed without the password. Only the header is rewritten; the encrypted contents are not re-encrypted."
+	if timestamp.HasToken(path) { note += "\n\n⚠️ The trusted timestamp covers the whole file and will no longer match after saving." }
+	info := widget.NewLabel(note)
+	info.Wrapping = fyne.TextWrapWord
+	form := widget.NewForm(
+		widget.NewFormItem("Comment", comment),
+		widget.NewFormItem("Password hint", hint),
+		widget.NewFormItem("Original name", name),
+	)
+	d := dialog.NewCustomConfirm("File Details", "Save", "Cancel", container.NewVBox(form, info), func(ok bool) {
+		if !ok { return }
+		m := cryptoengine.Metadata{Comment: comment.Text, Hint: strings.TrimSpace(hint.Text), Name: strings.TrimSpace(name.Text)}
+		if err := m.Validate(); err != nil { dialog.ShowError(err, w); return }
+		s.runRestamp(w, path, m)
+	}, w)
+	d.Resize(fyne.NewSize(560, 420))
+	d.Show()
+}
+
+// runRestamp rewrites the container's header with m, authenticated with the entered password
+func (s *AppState) runRestamp(w fyne.Window, path string, m cryptoengine.Metadata) {
+	if s.password == "" && !s.keyfileManager.HasKeyfiles() {
+		dialog.ShowInformation("Password required", "Enter the file's password; it authenticates the new details.", w)
+		return
+	}
+	finalPassword := []byte(s.password)
+	if s.keyfileManager.HasKeyfiles() { finalPassword = s.keyfileManager.GetCombinedKey([]byte(s.password)) }
+	s.statusLabel.SetText("🏷 Saving details…")
+	go func() {
+		err := secret.ScrubError(cryptoengine.Restamp(path, finalPassword, m), finalPassword, []byte(s.password))
+		s.ui(func() {
+			if err != nil { s.statusLabel.SetText("❌ " + err.Error()); dialog.ShowError(err, w); return }
+			s.statusLabel.SetText("✅ Details saved to " + filepath.Base(path))
+			if s.selectedPath == path { s.updateFileInfo() }
+		})
+	}()
+}
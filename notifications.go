@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/notify"
+)
+
+// showNotificationSettings configures where summaries of CLI job runs and sync folders go
+func (s *AppState) showNotificationSettings(w fyne.Window) {
+	n := s.config.Notifications
+	entry := func(value, placeholder string) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetPlaceHolder(placeholder)
+		e.SetText(value)
+		return e
+	}
+	onSuccess := widget.NewCheck("Notify on success", nil)
+	onSuccess.SetChecked(n.OnSuccess)
+	onFailure := widget.NewCheck("Notify on failure", nil)
+	onFailure.SetChecked(n.OnFailure)
+
+	smtpHost := entry(n.SMTPHost, "smtp.example.org")
+	smtpPort := entry("", "587 (STARTTLS) or 465 (TLS)")
+	if n.SMTPPort > 0 { smtpPort.SetText(strconv.Itoa(n.SMTPPort)) }
+	smtpUser := entry(n.SMTPUser, "Login name")
+	smtpPassFile := entry(n.SMTPPasswordFile, "Password file (chmod 600); empty uses $"+notify.EnvSMTPPassword)
+	smtpFrom := entry(n.SMTPFrom, "hadescrypt@example.org")
+	smtpTo := entry(n.SMTPTo, "admin@example.org, ops@example.org")
+	webhook := entry(n.WebhookURL, "https://hooks.example.org/hadescrypt")
+	ntfyURL := entry(n.NtfyURL, "https://ntfy.sh/my-backups")
+	ntfyToken := widget.NewPasswordEntry()
+	ntfyToken.SetPlaceHolder("Access token (optional)")
+	ntfyToken.SetText(n.NtfyToken)
+	gotifyURL := entry(n.GotifyURL, "https://gotify.example.org")
+	gotifyToken := widget.NewPasswordEntry()
+	gotifyToken.SetPlaceHolder("Application token")
+	gotifyToken.SetText(n.GotifyToken)
+
+	read := func() (config.Notifications, error) {
+		out := config.Notifications{
+			OnSuccess:        onSuccess.Checked,
+			OnFailure:        onFailure.Checked,
+			SMTPHost:         strings.TrimSpace(smtpHost.Text),
+			SMTPUser:         strings.TrimSpace(smtpUser.Text),
+			SMTPPasswordFile: strings.TrimSpace(smtpPassFile.Text),
+			SMTPFrom:         strings.TrimSpace(smtpFrom.Text),
+			SMTPTo:           strings.TrimSpace(smtpTo.Text),
+			WebhookURL:       strings.TrimSpace(webhook.Text),
+			NtfyURL:          strings.TrimSpace(ntfyURL.Text),
+			NtfyToken:        strings.TrimSpace(ntfyToken.Text),
+			GotifyURL:        strings.TrimSpace(gotifyURL.Text),
+			GotifyToken:      strings.TrimSpace(gotifyToken.Text),
+		}
+		if v := strings.TrimSpace(smtpPort.Text); v != "" {
+			port, err := strconv.Atoi(v)
+			if err != nil || port <= 0 || port > 65535 { return out, errors.New("SMTP port must be a number between 1 and 65535") }
+			out.SMTPPort = port
+		}
+		return out, nil
+	}
+
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+	var testBtn *widget.Button
+	testBtn = widget.NewButton("📨 Send test notification", func() {
+		cfg, err := read()
+		if err != nil { status.SetText("⚠️ " + err.Error()); return }
+		testBtn.Disable()
+		status.SetText("Sending…")
+		go func() {
+			err := notify.Send(context.Background(), cfg, notify.Message{Title: "HadesCrypt test notification", Body: "Notifications from HadesCrypt reach this target."})
+			s.ui(func() {
+				testBtn.Enable()
+				if err != nil { status.SetText("⚠️ " + err.Error()) } else { status.SetText("✅ Sent to every configured target") }
+			})
+		}()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("", container.NewHBox(onSuccess, onFailure)),
+		widget.NewFormItem("SMTP server", container.NewGridWithColumns(2, smtpHost, smtpPort)),
+		widget.NewFormItem("SMTP login", container.NewGridWithColumns(2, smtpUser, smtpPassFile)),
+		widget.NewFormItem("From", smtpFrom),
+		widget.NewFormItem("To", smtpTo),
+		widget.NewFormItem("Webhook URL", webhook),
+		widget.NewFormItem("ntfy topic", container.NewGridWithColumns(2, ntfyURL, ntfyToken)),
+		widget.NewFormItem("Gotify", container.NewGridWithColumns(2, gotifyURL, gotifyToken)),
+	)
+	note := widget.NewLabel("Summaries are sent after hadescrypt-cli job runs and sync passes that changed something or failed. Leave a target empty to skip it.")
+	note.Wrapping = fyne.TextWrapWord
+	content := container.NewVBox(note, form, container.NewHBox(testBtn), status)
+
+	d := dialog.NewCustomConfirm("🔔 Notifications", "Save", "Cancel", container.NewVScroll(content), func(ok bool) {
+		if !ok { return }
+		cfg, err := read()
+		if err != nil { dialog.ShowError(err, w); return }
+		s.config.Notifications = cfg
+		s.config.Save()
+	}, w)
+	d.Resize(fyne.NewSize(680, 560))
+	d.Show()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2/data/binding"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+	"github.com/bangundwir/HadesCrypt/internal/safename"
+)
+
+// splitUnits are the units offered for the split size
+var splitUnits = []string{"KiB", "MiB", "GiB", "TiB"}
+
+// Options is a snapshot of the Advanced panel choices
+type Options struct {
+	UseKeyfiles      bool
+	Paranoid         bool
+	ReedSolomon      bool
+	ForceDecrypt     bool
+	Split            bool
+	SplitSize        int
+	SplitUnit        string
+	Compress         bool
+	Deniability      bool
+	Recursive        bool
+	Extension        string
+	CompressionLevel int
+	Argon2Preset     string
+	OutputDir        string
+	Symlinks         fswalk.Policy
+	AbortUnreadable  bool
+	Names            safename.Style
+}
+
+// defaultOptions are the choices of a fresh window
+func defaultOptions() Options {
+	return Options{SplitSize: 100, SplitUnit: "MiB", Extension: outputExtensions[0], Argon2Preset: "Balanced", Symlinks: fswalk.Skip, Names: safename.Keep}
+}
+
+// optionsFromProfile returns the choices a saved profile stands for; unset fields take the defaults
+func optionsFromProfile(p *config.Profile) Options {
+	o := defaultOptions()
+	o.UseKeyfiles, o.Paranoid, o.ReedSolomon, o.ForceDecrypt = p.UseKeyfiles, p.ParanoidMode, p.ReedSolomon, p.ForceDecrypt
+	o.Split, o.Compress, o.Deniability, o.Recursive = p.SplitOutput, p.CompressFiles, p.DeniabilityMode, p.RecursiveMode
+	if p.OutputExtension != "" { o.Extension = p.OutputExtension }
+	o.CompressionLevel = p.CompressionLevel
+	if p.Argon2Preset != "" { o.Argon2Preset = p.Argon2Preset }
+	o.OutputDir = p.OutputDir
+	o.Symlinks, _ = fswalk.ParsePolicy(p.SymlinkPolicy)
+	o.AbortUnreadable = p.AbortOnUnreadable
+	o.Names, _ = safename.ParseStyle(p.OutputNames)
+	return o
+}
+
+// optionsModel holds the Advanced panel choices as data bindings. The widgets are
+// bound to it, so setting the model (a profile, the defaults) updates every control,
+// and jobs read a consistent snapshot through get from any goroutine.
+type optionsModel struct {
+	useKeyfiles, paranoid, reedSolomon, forceDecrypt binding.Bool
+	split, compress, deniability, recursive            binding.Bool
+	abortUnreadable                                    binding.Bool
+	splitSize                                          binding.Int
+	splitUnit, extension, compression, argon2Preset    binding.String // compression holds a compressionLevels label
+	outputDir, symlinks, names                         binding.String // symlinks and names hold fswalk.Policy and safename.Style labels
+}
+
+// newOptionsModel returns a model holding defaultOptions
+func newOptionsModel() *optionsModel {
+	m := &optionsModel{
+		useKeyfiles: binding.NewBool(), paranoid: binding.NewBool(), reedSolomon: binding.NewBool(), forceDecrypt: binding.NewBool(),
+		split: binding.NewBool(), compress: binding.NewBool(), deniability: binding.NewBool(), recursive: binding.NewBool(),
+		abortUnreadable: binding.NewBool(),
+		splitSize: binding.NewInt(),
+		splitUnit: binding.NewString(), extension: binding.NewString(), compression: binding.NewString(), argon2Preset: binding.NewString(),
+		outputDir: binding.NewString(), symlinks: binding.NewString(), names: binding.NewString(),
+	}
+	m.set(defaultOptions())
+	return m
+}
+
+// get returns the current choices
+func (m *optionsModel) get() Options {
+	var o Options
+	o.UseKeyfiles, _ = m.useKeyfiles.Get()
+	o.Paranoid, _ = m.paranoid.Get()
+	o.ReedSolomon, _ = m.reedSolomon.Get()
+	o.ForceDecrypt, _ = m.forceDecrypt.Get()
+	o.Split, _ = m.split.Get()
+	o.SplitSize, _ = m.splitSize.Get()
+	o.SplitUnit, _ = m.splitUnit.Get()
+	o.Compress, _ = m.compress.Get()
+	o.Deniability, _ = m.deniability.Get()
+	o.Recursive, _ = m.recursive.Get()
+	o.Extension, _ = m.extension.Get()
+	label, _ := m.compression.Get()
+	o.CompressionLevel = compressionLevel(label)
+	o.Argon2Preset, _ = m.argon2Preset.Get()
+	dir, _ := m.outputDir.Get()
+	o.OutputDir = strings.TrimSpace(dir)
+	label, _ = m.symlinks.Get()
+	o.Symlinks = fswalk.PolicyForLabel(label)
+	o.AbortUnreadable, _ = m.abortUnreadable.Get()
+	label, _ = m.names.Get()
+	o.Names = safename.StyleForLabel(label)
+	return o
+}
+
+// set replaces every choice with those of o
+func (m *optionsModel) set(o Options) {
+	m.useKeyfiles.Set(o.UseKeyfiles)
+	m.paranoid.Set(o.Paranoid)
+	m.reedSolomon.Set(o.ReedSolomon)
+	m.forceDecrypt.Set(o.ForceDecrypt)
+	m.split.Set(o.Split)
+	m.splitSize.Set(o.SplitSize)
+	m.splitUnit.Set(o.SplitUnit)
+	m.compress.Set(o.Compress)
+	m.deniability.Set(o.Deniability)
+	m.recursive.Set(o.Recursive)
+	m.extension.Set(o.Extension)
+	m.compression.Set(compressionLabel(o.CompressionLevel))
+	m.argon2Preset.Set(o.Argon2Preset)
+	m.outputDir.Set(o.OutputDir)
+	m.symlinks.Set(o.Symlinks.Label())
+	m.abortUnreadable.Set(o.AbortUnreadable)
+	m.names.Set(o.Names.Label())
+}
+
+// opt returns a snapshot of the window's Advanced panel choices
+func (s *AppState) opt() Options { return s.options.get() }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/progress"
+	"github.com/bangundwir/HadesCrypt/internal/throttle"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+const (
+	perfWindow = 5 * time.Second        // the rate shown covers this span
+	perfDelay  = 2 * time.Second        // jobs that finish sooner never show the panel
+	perfTick   = 500 * time.Millisecond // refresh interval
+)
+
+// perfSample is what one refresh of the performance panel shows
+type perfSample struct {
+	ev      progress.Event
+	stats   cryptoengine.PipelineStats
+	cpu     float64 // share of all cores spent on chunks since the last refresh
+	usage   float64 // share of the workers' time spent on chunks, 0 without workers
+	limited float64 // the Low priority rate limit in bytes per second, 0 when off
+}
+
+// buildPerfPanel returns the live performance panel, hidden until a job runs long enough
+func (s *AppState) buildPerfPanel() fyne.CanvasObject {
+	s.perfLabel = widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	s.perfHint = widget.NewLabel("")
+	s.perfHint.Wrapping = fyne.TextWrapWord
+	s.perfPanel = container.NewVBox(s.perfLabel, s.perfHint)
+	s.perfPanel.Hide()
+	return s.perfPanel
+}
+
+// startPerf starts measuring the job that is starting; reportProgress feeds it
+func (s *AppState) startPerf() {
+	m := progress.NewMeter(perfWindow)
+	s.perfMeter.Store(m)
+	stop := make(chan struct{})
+	s.perfStop = stop
+	go func() {
+		tick := time.NewTicker(perfTick)
+		defer tick.Stop()
+		last, lastAt := cryptoengine.Pipeline(), time.Now()
+		for {
+			select {
+			case <-stop:
+				s.ui(func() { if s.perfPanel != nil { s.perfPanel.Hide() } })
+				return
+			case now := <-tick.C:
+				stats := cryptoengine.Pipeline()
+				sample := perfSample{ev: m.Event(), stats: stats}
+				busy, wall := float64(stats.Busy-last.Busy), float64(now.Sub(lastAt))
+				sample.cpu = min(busy/wall/float64(runtime.NumCPU()), 1)
+				if stats.Workers > 0 { sample.usage = min(busy/wall/float64(stats.Workers), 1) }
+				if s.config.LowPriority { sample.limited = float64(throttle.MBps(s.config.RateLimitMBps)) }
+				last, lastAt = stats, now
+				if sample.ev.Elapsed < perfDelay { continue }
+				s.ui(func() {
+					// a refresh queued before the job ended must not show the panel again
+					if s.perfPanel == nil || s.perfMeter.Load() != m { return }
+					s.perfLabel.SetText(sample.text())
+					s.perfHint.SetText(sample.hint())
+					s.perfPanel.Show()
+				})
+			}
+		}
+	}()
+}
+
+// stopPerf hides the panel once the job is over
+func (s *AppState) stopPerf() {
+	s.perfMeter.Store(nil)
+	if s.perfStop != nil { close(s.perfStop); s.perfStop = nil }
+}
+
+// text is the figures line of the panel
+func (p perfSample) text() string {
+	line := fmt.Sprintf("⚡ %s  CPU ~%.0f%%  workers %d/%d busy  queue %d", uiutil.HumanRate(p.ev.Rate), p.cpu*100, p.stats.Active, p.stats.Workers, p.stats.Queued)
+	if p.ev.Remaining > 0 {
+		line += "  " + uiutil.HumanBytes(p.ev.Remaining) + " left"
+		if p.ev.ETA > 0 { line += fmt.Sprintf(" (~%s)", p.ev.ETA.Round(time.Second)) }
+	}
+	return line
+}
+
+// hint says what holds the job back, or nothing when it is not clear
+func (p perfSample) hint() string {
+	switch {
+	case p.limited > 0 && p.ev.Rate >= 0.9*p.limited:
+		return fmt.Sprintf("🐢 Held at the Low priority limit of %s; raise or clear it in Advanced Options to go faster.", uiutil.HumanRate(p.limited))
+	case p.stats.Workers == 0:
+		return "📁 No chunks are being encrypted right now: the job is archiving, extracting, scanning or waiting on gpg."
+	case p.usage >= 0.85 && p.stats.Workers == 1:
+		return "🔥 Limited by the CPU: decryption opens one chunk at a time. A lighter mode (AES-256-GCM or ChaCha20) decrypts faster."
+	case p.usage >= 0.85:
+		return "🔥 Limited by the CPU: every worker is busy encrypting. A lighter mode (AES-256-GCM or ChaCha20) is faster."
+	case p.usage < 0.5:
+		return "💾 Limited by the disk or network: the workers wait for data more than they encrypt."
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/gnupg"
+)
+
+// showPGPTextTools armors/dearmors and clearsigns/verifies text with gpg, for
+// exchanging data with PGP mail users
+func (s *AppState) showPGPTextTools(w fyne.Window) {
+	input := widget.NewMultiLineEntry()
+	input.SetPlaceHolder("Paste text or an armored PGP block")
+	input.SetMinRowsVisible(8)
+	input.Wrapping = fyne.TextWrapWord
+	output := widget.NewMultiLineEntry()
+	output.SetMinRowsVisible(8)
+	output.Wrapping = fyne.TextWrapWord
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+
+	// result holds the raw output, which may be binary after dearmoring
+	var result []byte
+	show := func(out []byte, msg string) {
+		result = out
+		if utf8.Valid(out) { output.SetText(string(out)) } else { output.SetText(fmt.Sprintf("(%d bytes of binary data — use Save…)", len(out))) }
+		status.SetText(msg)
+	}
+
+	var signers []gnupg.Key
+	signerSelect := widget.NewSelect(nil, nil)
+	signerSelect.PlaceHolder = "Default key"
+	go func() {
+		keys, err := gnupg.ListSecretKeys(s.gpgPath())
+		s.ui(func() {
+			if err != nil { status.SetText("⚠️ " + err.Error()); return }
+			var names []string
+			for _, k := range keys {
+				if k.CanSign && !k.Revoked && !k.Expired { signers = append(signers, k); names = append(names, k.Name()+"  •  "+k.KeyID) }
+			}
+			signerSelect.SetOptions(names)
+			if len(names) == 0 { signerSelect.PlaceHolder = "No signing keys"; signerSelect.Refresh() }
+		})
+	}()
+	signer := func() string {
+		if i := signerSelect.SelectedIndex(); i >= 0 && i < len(signers) { return signers[i].Fingerprint }
+		return ""
+	}
+
+	// run executes op on a fresh gpg instance off the UI thread
+	run := func(label string, op func(g *gnupg.GnuPGCipher, in []byte) ([]byte, string, error)) {
+		in := []byte(input.Text)
+		if len(in) == 0 { status.SetText("Nothing to " + label); return }
+		status.SetText("⏳ " + label + "…")
+		go func() {
+			g, err := gnupg.NewGnuPGCipherAt(s.gpgPath())
+			var out []byte
+			msg := ""
+			if err == nil {
+				defer g.Cleanup()
+				out, msg, err = op(g, in)
+			}
+			s.ui(func() {
+				if err != nil && out == nil { result = nil; output.SetText(""); status.SetText("❌ " + err.Error()); return }
+				if err != nil { msg = "❌ " + err.Error() }
+				show(out, msg)
+			})
+		}()
+	}
+
+	armorBtn := widget.NewButton("Armor", func() {
+		run("armor", func(g *gnupg.GnuPGCipher, in []byte) ([]byte, string, error) {
+			out, err := g.Armor(in)
+			return out, "✅ Armored", err
+		})
+	})
+	dearmorBtn := widget.NewButton("Dearmor", func() {
+		run("dearmor", func(g *gnupg.GnuPGCipher, in []byte) ([]byte, string, error) {
+			out, err := g.Dearmor(in)
+			return out, fmt.Sprintf("✅ Dearmored %d bytes", len(out)), err
+		})
+	})
+	clearsignBtn := widget.NewButton("Clearsign", func() {
+		who := signer()
+		run("clearsign", func(g *gnupg.GnuPGCipher, in []byte) ([]byte, string, error) {
+			out, err := g.Clearsign(in, who)
+			return out, "✅ Signed", err
+		})
+	})
+	verifyBtn := widget.NewButton("Verify", func() {
+		run("verify", func(g *gnupg.GnuPGCipher, in []byte) ([]byte, string, error) {
+			out, sig, err := g.Verify(in)
+			// a bad signature still shows the text it covered
+			if err != nil { return out, "", err }
+			msg := fmt.Sprintf("✅ Good signature from %s (%s)", sig.Signer, sig.KeyID)
+			if !sig.Created.IsZero() { msg += ", made " + sig.Created.Format("2006-01-02 15:04") }
+			if sig.Trust != "" { msg += ", trust " + sig.Trust }
+			return out, msg, nil
+		})
+	})
+	copyBtn := widget.NewButton("Copy", func() { w.Clipboard().SetContent(output.Text) })
+	saveBtn := widget.NewButton("Save…", func() {
+		if len(result) == 0 { return }
+		data := result
+		dialog.ShowFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil || wc == nil { return }
+			path := wc.URI().Path()
+			wc.Close()
+			if err := os.WriteFile(path, data, 0600); err != nil { dialog.ShowError(err, w) }
+		}, w)
+	})
+
+	content := container.NewVBox(
+		widget.NewLabel("Input:"), input,
+		container.NewHBox(armorBtn, dearmorBtn, widget.NewSeparator(), widget.NewLabel("Signer:"), signerSelect, clearsignBtn, verifyBtn),
+		widget.NewLabel("Output:"), output,
+		container.NewBorder(nil, nil, nil, container.NewHBox(copyBtn, saveBtn), status),
+	)
+	d := dialog.NewCustom("PGP Text Tools", "Close", content, w)
+	d.Resize(fyne.NewSize(720, 560))
+	d.Show()
+}
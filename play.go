@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/editsession"
+	"github.com/bangundwir/HadesCrypt/internal/playback"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// doPlay streams the selected audio or video container to the system player
+// through a loopback server; only the chunks the player reads are decrypted
+func (s *AppState) doPlay(w fyne.Window) {
+	path := s.selectedPath
+	if path == "" || !s.isHadesCryptFile(path) {
+		dialog.ShowInformation("Play", "Select a single encrypted audio or video file.", w)
+		return
+	}
+	if s.password == "" && !s.keyfileManager.HasKeyfiles() {
+		dialog.ShowInformation("Password required", "Please enter a password.", w)
+		return
+	}
+	finalPassword := []byte(s.password)
+	if s.keyfileManager.HasKeyfiles() { finalPassword = s.keyfileManager.GetCombinedKey([]byte(s.password)) }
+	name := filepath.Base(s.defaultOutputPathForDecrypt(path))
+	if hdr, err := cryptoengine.ReadHeaderWithPassword(path, finalPassword); err == nil && hdr.Metadata.Name != "" { name = hdr.Metadata.Name }
+	if !playback.IsMedia(name) {
+		dialog.ShowInformation("Play", name+" does not look like an audio or video file. Use Preview or Decrypt instead.", w)
+		return
+	}
+
+	s.statusLabel.SetText("▶ Opening " + name + "…")
+	go func() {
+		url, size, err := s.startStream(path, name, finalPassword)
+		err = secret.ScrubError(err, finalPassword, []byte(s.password))
+		s.ui(func() {
+			if err != nil {
+				s.statusLabel.SetText("❌ Play failed: " + err.Error())
+				dialog.ShowError(withPasswordHint(err, path), w)
+				return
+			}
+			s.statusLabel.SetText("▶ Streaming " + name)
+			if err := editsession.OpenWithDefaultApp(url); err != nil { s.statusLabel.SetText("⚠️ Could not start a player: " + err.Error()) }
+			s.showNowPlaying(w, name, url, size)
+		})
+	}()
+}
+
+// startStream opens path for random access and serves it, starting the server on first use
+func (s *AppState) startStream(path, name string, password []byte) (url string, size int64, err error) {
+	r, err := cryptoengine.OpenReader(path, password)
+	if err != nil { return "", 0, err }
+	modTime := fileModTime(path)
+	s.playbackMu.Lock()
+	defer s.playbackMu.Unlock()
+	if s.playback == nil {
+		if s.playback, err = playback.Start(); err != nil { r.Close(); return "", 0, err }
+	}
+	url, err = s.playback.Add(r, name, modTime)
+	if err != nil { r.Close(); return "", 0, err }
+	return url, r.Size(), nil
+}
+
+// showNowPlaying shows the stream address for players that were not started
+// automatically; closing the dialog stops the stream
+func (s *AppState) showNowPlaying(w fyne.Window, name, url string, size int64) {
+	addr := widget.NewEntry()
+	addr.SetText(url)
+	info := widget.NewLabel(fmt.Sprintf("%s (%s) is streamed from this computer only, decrypted piece by piece as the player reads it; nothing is written to disk.\n\nIf no player opened, paste the address into one (e.g. VLC → Open Network Stream).", name, uiutil.HumanBytes(size)))
+	info.Wrapping = fyne.TextWrapWord
+	d := dialog.NewCustom("▶ Now playing", "Stop streaming", container.NewVBox(info, addr), w)
+	d.SetOnClosed(func() {
+		s.playbackMu.Lock()
+		if s.playback != nil { s.playback.Remove(url) }
+		s.playbackMu.Unlock()
+		s.statusLabel.SetText("⏹️ Stopped streaming " + name)
+	})
+	d.Resize(fyne.NewSize(560, 260))
+	d.Show()
+}
+
+// stopPlayback closes the stream server and every open stream
+func (s *AppState) stopPlayback() {
+	s.playbackMu.Lock()
+	defer s.playbackMu.Unlock()
+	if s.playback != nil { s.playback.Close(); s.playback = nil }
+}
+
+// fileModTime returns path's modification time, or the zero time
+func fileModTime(path string) (t time.Time) {
+	if fi, err := os.Stat(path); err == nil { t = fi.ModTime() }
+	return t
+}
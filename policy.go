@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/policy"
+)
+
+// checkPolicy returns why opts may not be used to encrypt, or nil. A policy
+// file that exists but cannot be read blocks encryption altogether.
+func (s *AppState) checkPolicy(opts cryptoengine.EncryptionOptions) error {
+	if s.viewer { return policy.ErrViewer }
+	if s.adminPolicyErr != nil { return s.adminPolicyErr }
+	return s.adminPolicy.CheckEncrypt(opts)
+}
+
+// escrowOutput escrows key for out when the policy requires recovery. An output
+// whose key could not be escrowed is removed, so callers must keep the source.
+func (s *AppState) escrowOutput(out string, key []byte) error {
+	if err := s.adminPolicy.Escrow(out, key, s.keyfileManager.HasKeyfiles()); err != nil {
+		os.Remove(out)
+		return err
+	}
+	return nil
+}
+
+// modeForOption returns the engine mode a selector entry stands for
+func modeForOption(opt string) (cryptoengine.EncryptionMode, bool) {
+	for m := cryptoengine.ModeAES256GCM; m <= cryptoengine.ModeParanoidSerpent; m++ {
+		if strings.Contains(opt, cryptoengine.GetEncryptionModeName(m)) { return m, true }
+	}
+	return 0, false
+}
+
+// allowedModeOptions keeps the selector entries whose mode the policy allows
+func (s *AppState) allowedModeOptions(options []string) []string {
+	return slices.DeleteFunc(slices.Clone(options), func(opt string) bool {
+		m, ok := modeForOption(opt)
+		return !ok || !s.adminPolicy.ModeAllowed(m)
+	})
+}
+
+// restrictArgon2Select limits the KDF presets to those meeting the policy minimum
+func (s *AppState) restrictArgon2Select(sel *widget.Select) {
+	if s.adminPolicy == nil { return }
+	sel.SetOptions(s.adminPolicy.AllowedPresets())
+	if !slices.Contains(sel.Options, sel.Selected) && len(sel.Options) > 0 { sel.SetSelected(sel.Options[0]) }
+}
+
+// policyBanner tells the user which settings their administrator controls; nil when unmanaged
+func (s *AppState) policyBanner() fyne.CanvasObject {
+	if s.adminPolicyErr != nil {
+		return widget.NewLabelWithStyle("🏢 Encryption is disabled: "+s.adminPolicyErr.Error(), fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	}
+	p := s.adminPolicy
+	if p == nil { return nil }
+	var rules []string
+	if len(p.AllowedModes) > 0 { rules = append(rules, "modes: "+strings.Join(p.AllowedModes, ", ")) }
+	if p.MinArgon2Time > 0 || p.MinArgon2MemoryMiB > 0 { rules = append(rules, fmt.Sprintf("KDF ≥ %d passes / %d MiB", p.MinArgon2Time, p.MinArgon2MemoryMiB)) }
+	if p.ForbidDeleteAfter { rules = append(rules, "originals are kept") }
+	if p.RecoveryRequired() { rules = append(rules, "passwords escrowed for recovery") }
+	text := "🏢 Managed by your administrator"
+	if len(rules) > 0 { text += " — " + strings.Join(rules, " • ") }
+	l := widget.NewLabelWithStyle(text, fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	l.Wrapping = fyne.TextWrapWord
+	return l
+}
+
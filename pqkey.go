@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+// pqKeyRow is the PQ key row of the main screen: the Kyber-768 and Hybrid PQ
+// modes encrypt to the loaded key file, and their containers only open with it
+func (s *AppState) pqKeyRow(w fyne.Window) fyne.CanvasObject {
+	s.pqKeyLabel = widget.NewLabel("")
+	s.updatePQKeyDisplay()
+	load := widget.NewButton("Load", func() { s.showLoadPQKeyDialog(w) })
+	generate := widget.NewButton("Generate", func() { s.showGeneratePQKeyDialog(w) })
+	clear := widget.NewButton("Clear", func() { s.setPQKey(nil) })
+	return container.NewBorder(nil, nil, widget.NewLabel("PQ key:"), container.NewHBox(load, generate, clear), s.pqKeyLabel)
+}
+
+// setPQKey replaces the loaded post-quantum key; nil unloads it
+func (s *AppState) setPQKey(k *cryptoengine.KEMKey) {
+	if s.forgetPQKey != nil { s.forgetPQKey(); s.forgetPQKey = nil }
+	s.pqKey = k
+	if k != nil { s.forgetPQKey = cryptoengine.AddKEMKey(k) }
+	s.updatePQKeyDisplay()
+}
+
+func (s *AppState) updatePQKeyDisplay() {
+	if s.pqKeyLabel == nil { return }
+	if s.pqKey == nil { s.pqKeyLabel.SetText("None (needed by the post-quantum modes)"); return }
+	s.pqKeyLabel.SetText("🛡️ " + s.pqKey.Fingerprint())
+}
+
+func (s *AppState) showLoadPQKeyDialog(w fyne.Window) {
+	fd := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil { dialog.ShowError(err, w); return }
+		if rc == nil { return }
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, 64<<10))
+		if err != nil { dialog.ShowError(err, w); return }
+		k, err := cryptoengine.ParseKEMKey(data)
+		if err != nil { dialog.ShowError(fmt.Errorf("%s: %w", rc.URI().Name(), err), w); return }
+		s.setPQKey(k)
+	}, w)
+	fd.Show()
+}
+
+func (s *AppState) showGeneratePQKeyDialog(w fyne.Window) {
+	k, err := cryptoengine.GenerateKEMKey(rand.Reader)
+	if err != nil { dialog.ShowError(err, w); return }
+	fd := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+		if err != nil { dialog.ShowError(err, w); return }
+		if uc == nil { return }
+		_, err = uc.Write(k.Marshal())
+		if cerr := uc.Close(); err == nil { err = cerr }
+		if err != nil { dialog.ShowError(fmt.Errorf("Failed to save the post-quantum key: %v", err), w); return }
+		s.setPQKey(k)
+		dialog.ShowInformation("Post-quantum key", fmt.Sprintf("Key %s saved to %s and loaded.\n\nKeep it apart from the files you encrypt to it: they open only with the password and this key file together.", k.Fingerprint(), uc.URI().Name()), w)
+	}, w)
+	fd.SetFileName("hadescrypt-pq.key")
+	fd.Show()
+}
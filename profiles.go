@@ -0,0 +1,180 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/safename"
+)
+
+// outputExtensions are the container extensions offered for new outputs
+var outputExtensions = format.ContainerExtensions()
+
+// compressionLevels maps the selector labels to flate levels (0 = library default)
+var compressionLevels = []struct {
+	Label string
+	Level int
+}{{"Fast", 1}, {"Default", 0}, {"Best", 9}}
+
+func compressionLabel(level int) string {
+	for _, c := range compressionLevels {
+		if c.Level == level { return c.Label }
+	}
+	return "Default"
+}
+
+// compressionLevel is the inverse of compressionLabel
+func compressionLevel(label string) int {
+	for _, c := range compressionLevels {
+		if c.Label == label { return c.Level }
+	}
+	return 0
+}
+
+// outputExtension returns the extension for new HadesCrypt containers
+func (s *AppState) outputExtension() string {
+	if ext := s.opt().Extension; ext != "" { return ext }
+	return format.DefaultExtension
+}
+
+// modeOptionFor returns the mode selector entry for a stored mode name
+func modeOptionFor(name string) string {
+	for _, opt := range allModeOptions {
+		if strings.Contains(opt, name) { return opt }
+	}
+	return ""
+}
+
+// currentProfile captures the present settings under name
+func (s *AppState) currentProfile(name string) config.Profile {
+	o := s.opt()
+	return config.Profile{
+		Name:              name,
+		UseKeyfiles:       o.UseKeyfiles,
+		ParanoidMode:      o.Paranoid,
+		ReedSolomon:       o.ReedSolomon,
+		ForceDecrypt:      o.ForceDecrypt,
+		SplitOutput:       o.Split,
+		CompressFiles:     o.Compress,
+		DeniabilityMode:   o.Deniability,
+		RecursiveMode:     o.Recursive,
+		EncryptionMode:    cryptoengine.GetEncryptionModeName(s.encryptionMode),
+		OutputExtension:   s.outputExtension(),
+		CompressionLevel:  o.CompressionLevel,
+		Argon2Preset:      o.Argon2Preset,
+		OutputDir:         o.OutputDir,
+		SymlinkPolicy:     string(o.Symlinks),
+		GPGPath:           s.profileGPGPath,
+		AbortOnUnreadable: o.AbortUnreadable,
+		OutputNames:       string(o.Names),
+	}
+}
+
+// applyProfile sets the options model, and through it every bound control, from p.
+// Compliance mode keeps paranoid mode off and an admin policy keeps the KDF preset allowed.
+func (s *AppState) applyProfile(p *config.Profile) {
+	o := optionsFromProfile(p)
+	o.Paranoid = o.Paranoid && !s.config.ComplianceMode
+	if s.adminPolicy != nil && !slices.Contains(s.adminPolicy.AllowedPresets(), o.Argon2Preset) { o.Argon2Preset = s.opt().Argon2Preset }
+	s.options.set(o)
+
+	if opt := modeOptionFor(p.EncryptionMode); opt != "" && p.EncryptionMode != "" {
+		// compliance mode keeps the restricted list; SetSelected ignores entries not in it
+		s.encryptionModeSelect.SetSelected(opt)
+	}
+	if s.profileGPGPath != p.GPGPath {
+		s.profileGPGPath = p.GPGPath
+		s.refreshGnuPGStatus(false)
+	}
+}
+
+// buildProfileRow returns the profile selector with save/delete actions
+func (s *AppState) buildProfileRow(w fyne.Window) fyne.CanvasObject {
+	names := func() []string {
+		var n []string
+		for _, p := range s.config.Profiles { n = append(n, p.Name) }
+		return n
+	}
+	profileSelect := widget.NewSelect(names(), nil)
+	profileSelect.PlaceHolder = "(custom)"
+	profileSelect.OnChanged = func(name string) {
+		p := s.config.GetProfile(name)
+		if p == nil { return }
+		s.applyProfile(p)
+		if s.config.LastUsedProfile != name {
+			s.config.LastUsedProfile = name
+			s.config.Save()
+		}
+		s.statusLabel.SetText("Profile applied: " + name)
+	}
+
+	saveBtn := widget.NewButton("Save as…", func() {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetText(profileSelect.Selected)
+		dialog.ShowForm("Save Profile", "Save", "Cancel", []*widget.FormItem{widget.NewFormItem("Name", nameEntry)}, func(ok bool) {
+			name := strings.TrimSpace(nameEntry.Text)
+			if !ok || name == "" { return }
+			s.config.AddProfile(s.currentProfile(name))
+			s.config.LastUsedProfile = name
+			s.config.Save()
+			profileSelect.SetOptions(names())
+			profileSelect.SetSelected(name)
+		}, w)
+	})
+	deleteBtn := widget.NewButton("Delete", func() {
+		name := profileSelect.Selected
+		if name == "" { return }
+		dialog.ShowConfirm("Delete Profile", "Delete profile "+name+"?", func(ok bool) {
+			if !ok { return }
+			s.config.DeleteProfile(name)
+			if s.config.LastUsedProfile == name { s.config.LastUsedProfile = "" }
+			s.config.Save()
+			profileSelect.ClearSelected()
+			profileSelect.SetOptions(names())
+		}, w)
+	})
+
+	if s.config.GetProfile(s.config.LastUsedProfile) != nil {
+		profileSelect.SetSelected(s.config.LastUsedProfile)
+	}
+	return container.NewBorder(nil, nil, widget.NewLabel("Profile:"), container.NewHBox(saveBtn, deleteBtn), profileSelect)
+}
+
+// buildOutputControls returns the extension, compression level, Argon2 preset and output folder row, bound to the options model
+func (s *AppState) buildOutputControls(w fyne.Window) fyne.CanvasObject {
+	ext := widget.NewSelectWithData(outputExtensions, s.options.extension)
+
+	var levelLabels []string
+	for _, c := range compressionLevels { levelLabels = append(levelLabels, c.Label) }
+	level := widget.NewSelectWithData(levelLabels, s.options.compression)
+
+	argon := widget.NewSelectWithData(cryptoengine.Argon2PresetNames, s.options.argon2Preset)
+	s.restrictArgon2Select(argon)
+
+	var nameLabels []string
+	for _, st := range safename.Styles { nameLabels = append(nameLabels, st.Label()) }
+	names := widget.NewSelectWithData(nameLabels, s.options.names)
+
+	dir := widget.NewEntryWithData(s.options.outputDir)
+	dir.SetPlaceHolder("Next to the input")
+	browse := widget.NewButton("…", func() {
+		dialog.ShowFolderOpen(func(lu fyne.ListableURI, err error) {
+			if err != nil || lu == nil { return }
+			dir.SetText(lu.Path())
+		}, w)
+	})
+
+	return container.NewVBox(
+		container.NewHBox(widget.NewLabel("Extension:"), ext, widget.NewLabel("Compression:"), level, widget.NewLabel("KDF:"), argon),
+		container.NewBorder(nil, nil, widget.NewLabel("Output folder:"), browse, dir),
+		container.NewHBox(widget.NewLabel("Output names:"), names),
+	)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/bangundwir/HadesCrypt/internal/pinentry"
+)
+
+// promptSystemPassword collects the password through pinentry or the native OS dialog,
+// so keystrokes never pass through the GUI toolkit. then, if set, runs once a password was received.
+func (s *AppState) promptSystemPassword(w fyne.Window, confirm bool, then func()) {
+	if pinentry.Available() == "" {
+		dialog.ShowInformation("System Prompt", "No pinentry program or native password dialog was found.\nInstall pinentry (or zenity/kdialog on Linux).", w)
+		return
+	}
+	s.statusLabel.SetText("🔐 Waiting for system password prompt…")
+	go func() {
+		pw, err := pinentry.Prompt(pinentry.Request{
+			Title:       "HadesCrypt",
+			Description: "Enter the password for the selected files",
+			Prompt:      "Password:",
+			Confirm:     confirm,
+		})
+		s.ui(func() {
+			if errors.Is(err, pinentry.ErrCanceled) {
+				s.statusLabel.SetText("Password prompt canceled")
+				return
+			}
+			if err != nil {
+				s.statusLabel.SetText("❌ Password prompt failed")
+				dialog.ShowError(err, w)
+				return
+			}
+			s.touchActivity()
+			s.passwordEntry.SetText(pw)
+			s.confirmPasswordEntry.SetText(pw)
+			s.statusLabel.SetText("🔐 Password received from system prompt")
+			if then != nil { then() }
+		})
+	}()
+}
+
+// applySystemPrompt disables typing into the GUI password entries while the system prompt is in use
+func (s *AppState) applySystemPrompt(on bool) {
+	if on {
+		s.passwordEntry.Disable()
+		s.confirmPasswordEntry.Disable()
+		s.passwordEntry.SetPlaceHolder("Entered via system prompt…")
+	} else {
+		s.passwordEntry.Enable()
+		s.confirmPasswordEntry.Enable()
+		s.passwordEntry.SetPlaceHolder("Enter password…")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/gnupg"
+)
+
+// usesRecipients reports whether encryption targets public keys instead of the password
+func (s *AppState) usesRecipients() bool {
+	return s.encryptionMode == cryptoengine.ModeGnuPG && len(s.gpgRecipients) > 0
+}
+
+// gnupgKeyring returns the keyring cache of the current gpg binary
+func (s *AppState) gnupgKeyring() *gnupg.Keyring {
+	if s.keyring == nil || s.keyringPath != s.gpgPath() {
+		s.keyring, s.keyringPath = gnupg.NewKeyring(s.gpgPath()), s.gpgPath()
+	}
+	return s.keyring
+}
+
+// recipientsText summarizes the selected recipients for the Advanced panel
+func (s *AppState) recipientsText() string {
+	if len(s.gpgRecipients) == 0 { return "Recipients: none (password)" }
+	var names []string
+	for _, fpr := range s.gpgRecipients {
+		if k, ok := s.gnupgKeyring().Lookup(fpr); ok { names = append(names, k.Name()) } else { names = append(names, fpr) }
+	}
+	return "Recipients: " + strings.Join(names, ", ")
+}
+
+// buildRecipientsRow returns the GnuPG recipient summary with its picker button
+func (s *AppState) buildRecipientsRow(w fyne.Window) fyne.CanvasObject {
+	s.recipientsLabel = widget.NewLabel(s.recipientsText())
+	s.recipientsLabel.Truncation = fyne.TextTruncateEllipsis
+	choose := widget.NewButton("👥 Choose…", func() { s.showRecipientPicker(w) })
+	return container.NewBorder(nil, nil, nil, choose, s.recipientsLabel)
+}
+
+// showRecipientPicker lists the keyring's encryption keys with search and lets
+// the user tick the recipients for GnuPG mode
+func (s *AppState) showRecipientPicker(w fyne.Window) {
+	ring := s.gnupgKeyring()
+	var all, shown []gnupg.Key
+	loaded := false
+	picked := map[string]bool{}
+	for _, fpr := range s.gpgRecipients { picked[fpr] = true }
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Search name, email or key ID")
+	status := widget.NewLabel("Loading keyring…")
+	list := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject { return widget.NewCheck("key", nil) },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(shown) { return }
+			k := shown[id]
+			c := obj.(*widget.Check)
+			c.OnChanged = nil
+			c.SetText(fmt.Sprintf("%s  •  %s", k.Name(), k.KeyID))
+			c.SetChecked(picked[k.Fingerprint])
+			c.OnChanged = func(on bool) { picked[k.Fingerprint] = on }
+		},
+	)
+	filter := func() {
+		shown = shown[:0]
+		for _, k := range all {
+			if k.Usable() && k.Matches(search.Text) { shown = append(shown, k) }
+		}
+		list.Refresh()
+		if len(all) > 0 { status.SetText(fmt.Sprintf("%d of %d keys can encrypt", len(shown), len(all))) }
+	}
+	search.OnChanged = func(string) { filter() }
+	load := func(fetch func() ([]gnupg.Key, error)) {
+		status.SetText("Loading keyring…")
+		go func() {
+			keys, err := fetch()
+			s.ui(func() {
+				if err != nil { status.SetText("⚠️ " + err.Error()); return }
+				all, loaded = keys, true
+				if len(all) == 0 { status.SetText("No public keys in the keyring") }
+				filter()
+			})
+		}()
+	}
+	refresh := widget.NewButton("🔄 Refresh", func() { load(ring.Refresh) })
+
+	content := container.NewBorder(container.NewBorder(nil, nil, nil, refresh, search), status, nil, nil, list)
+	d := dialog.NewCustomConfirm("GnuPG Recipients", "Use", "Cancel", content, func(ok bool) {
+		if !ok || !loaded { return }
+		s.gpgRecipients = s.gpgRecipients[:0]
+		// keep the keyring order so the summary is stable
+		for _, k := range all {
+			if picked[k.Fingerprint] { s.gpgRecipients = append(s.gpgRecipients, k.Fingerprint) }
+		}
+		if s.recipientsLabel != nil { s.recipientsLabel.SetText(s.recipientsText()) }
+	}, w)
+	d.Resize(fyne.NewSize(600, 440))
+	d.Show()
+	load(ring.Keys)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/secret"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// offerRepair explains why a container failed to decrypt and offers writing a repaired copy
+func (s *AppState) offerRepair(w fyne.Window, path string, password []byte, cause error) {
+	msg := fmt.Sprintf("%v\n\nThe file is truncated or damaged, e.g. by an interrupted download. HadesCrypt can write a shorter copy that keeps every complete, authenticated chunk before the damage. The original is not changed.\n\nRepair it now?", cause)
+	dialog.ShowConfirm("Damaged container", msg, func(ok bool) { if ok { s.runRepair(w, path, password) } }, w)
+}
+
+// runRepair writes name.repaired.ext next to path and reports how much was lost
+func (s *AppState) runRepair(w fyne.Window, path string, password []byte) {
+	out := format.RepairedPathFor(path)
+	s.statusLabel.SetText("🩹 Repairing " + filepath.Base(path) + "…")
+	s.setProgressFraction(0)
+	go func() {
+		res, err := cryptoengine.Repair(path, out, password, func(done, total int64) {
+			s.reportProgress(done, total)
+		})
+		err = secret.ScrubError(err, password, []byte(s.password))
+		s.ui(func() {
+			if err != nil { s.statusLabel.SetText("❌ Repair failed: " + err.Error()); dialog.ShowError(err, w); return }
+			s.setProgressFraction(1)
+			s.statusLabel.SetText("✅ Repaired → " + filepath.Base(out))
+			dialog.ShowInformation("Container repaired", fmt.Sprintf(
+				"Written to\n%s\n\nKept %d of %d chunks (%s).\nLost %s: %v\n\nDecrypt the repaired file to recover the intact part.",
+				out, res.Chunks, res.TotalChunks, uiutil.HumanBytes(res.Recovered), uiutil.HumanBytes(res.Lost), res.Cause), w)
+		})
+	}()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/filemanager"
+)
+
+// Fyne cannot start a drag into another application, so results are handed to
+// the file manager instead: revealed there, to be dragged on from it, or put on
+// the clipboard as files, ready to paste into Explorer, Finder or a mail draft.
+
+// outputsShown caps how many results the summary lists one by one
+const outputsShown = 10
+
+// revealOutput shows path selected in the system file manager
+func revealOutput(w fyne.Window, path string) {
+	if err := filemanager.Reveal(path); err != nil { dialog.ShowError(err, w) }
+}
+
+// copyOutputs puts paths on the clipboard as files
+func copyOutputs(w fyne.Window, paths []string) {
+	if err := filemanager.CopyFiles(paths); err != nil { dialog.ShowError(err, w) }
+}
+
+// withOutputs lists the results of a job below the summary text, each with
+// Show in folder and Copy as file actions
+func withOutputs(w fyne.Window, text fyne.CanvasObject, outputs []string) fyne.CanvasObject {
+	if len(outputs) == 0 { return text }
+	box := container.NewVBox(text, widget.NewSeparator(), widget.NewLabel("Results (📂 show in folder, 📋 copy as file):"))
+	for _, p := range outputs[:min(len(outputs), outputsShown)] {
+		name := widget.NewLabel(filepath.Base(p))
+		name.Truncation = fyne.TextTruncateEllipsis
+		actions := container.NewHBox(widget.NewButton("📂", func() { revealOutput(w, p) }), widget.NewButton("📋", func() { copyOutputs(w, []string{p}) }))
+		box.Add(container.NewBorder(nil, nil, nil, actions, name))
+	}
+	if n := len(outputs); n > outputsShown { box.Add(widget.NewLabel(fmt.Sprintf("…and %d more", n-outputsShown))) }
+	if n := len(outputs); n > 1 { box.Add(widget.NewButton(fmt.Sprintf("📋 Copy all %d as files", n), func() { copyOutputs(w, outputs) })) }
+	return box
+}
+
+// newOutputRow creates a History row: when, what, then the Show in folder and Copy as file actions
+func newOutputRow() fyne.CanvasObject {
+	actions := container.NewHBox(widget.NewButton("📂", nil), widget.NewButton("📋", nil))
+	return container.NewBorder(nil, nil, newTimeLabel(), actions, widget.NewLabel("entry"))
+}
+
+// setOutputRow fills a row made by newOutputRow; the actions show only when something was written
+func setOutputRow(w fyne.Window, obj fyne.CanvasObject, l timedLine, output string) {
+	setTimedRow(obj, l)
+	actions := obj.(*fyne.Container).Objects[2].(*fyne.Container)
+	if output == "" { actions.Hide(); return }
+	actions.Objects[0].(*widget.Button).OnTapped = func() { revealOutput(w, output) }
+	actions.Objects[1].(*widget.Button).OnTapped = func() { copyOutputs(w, []string{output}) }
+	actions.Show()
+}
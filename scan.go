@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+	"github.com/bangundwir/HadesCrypt/internal/operations"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// ops returns the operations controller for the window's current settings. Its
+// scans show a live "Scanning…" status and stop on Cancel (apperr.ErrCanceled).
+func (s *AppState) ops() *operations.Controller {
+	o := s.opt()
+	return &operations.Controller{
+		Settings: operations.Settings{
+			Mode:              s.encryptionMode,
+			Extension:         o.Extension,
+			OutputDir:         o.OutputDir,
+			Recursive:         o.Recursive,
+			DeleteAfter:       s.deleteAfter,
+			Symlinks:          o.Symlinks,
+			AbortOnUnreadable: o.AbortUnreadable,
+			Names:             o.Names,
+		},
+		Canceled: s.cancelRequested.Load,
+		Scanning: func(st fswalk.Stats) {
+			s.setStatus(fmt.Sprintf("🔎 Scanning… %d files, %s", st.Files, uiutil.HumanBytes(st.Bytes)))
+		},
+	}
+}
+
+// selection returns the selected paths, one or many
+func (s *AppState) selection() []string {
+	if len(s.selectedPaths) > 0 { return s.selectedPaths }
+	if s.selectedPath != "" { return []string{s.selectedPath} }
+	return nil
+}
+
+// scanFolder lists the files below a folder selected for encryption. Must not run on the UI goroutine.
+func (s *AppState) scanFolder(root string, keep func(fswalk.Entry) bool) ([]fswalk.Entry, error) {
+	return s.ops().Scan(root, false, keep)
+}
+
+// scanEncrypted lists the files below a folder selected for decryption. Must not run on the UI goroutine.
+func (s *AppState) scanEncrypted(root string, keep func(fswalk.Entry) bool) ([]fswalk.Entry, error) {
+	return s.ops().Scan(root, true, keep)
+}
+
+// folderEstimate describes a sampled size estimate of a selected folder, so huge
+// trees do not hold up the details; the exact count is made when a job starts.
+// Must not run on the UI goroutine.
+func folderEstimate(ops *operations.Controller, root string) (string, fswalk.SizeEstimate, error) {
+	est, err := ops.Estimate(root, false)
+	switch {
+	case err != nil:
+		return "Folder: size unknown (" + err.Error() + ")", est, err
+	case est.Exact:
+		return fmt.Sprintf("Folder: %d file(s), %s", est.Files, uiutil.HumanBytes(est.Bytes)), est, nil
+	}
+	return fmt.Sprintf("Folder: ~%d file(s), ~%s (likely %s – %s)", est.Files, uiutil.HumanBytes(est.Bytes), uiutil.HumanBytes(est.BytesLow), uiutil.HumanBytes(est.BytesHigh)), est, nil
+}
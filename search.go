@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/searchindex"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// showUnlockIndexDialog asks for the index password and loads (or creates) the encrypted index.
+// onDone receives true once the index is unlocked.
+func (s *AppState) showUnlockIndexDialog(w fyne.Window, onDone func(bool)) {
+	path, err := searchindex.DefaultPath()
+	if err != nil {
+		dialog.ShowError(err, w)
+		if onDone != nil { onDone(false) }
+		return
+	}
+	creating := !searchindex.Exists(path)
+
+	pwEntry := widget.NewPasswordEntry()
+	pwEntry.SetPlaceHolder("Index password…")
+	confirmEntry := widget.NewPasswordEntry()
+	confirmEntry.SetPlaceHolder("Confirm index password…")
+
+	items := []*widget.FormItem{widget.NewFormItem("Password", pwEntry)}
+	title := "Unlock Search Index"
+	if creating {
+		title = "Create Search Index"
+		items = append(items, widget.NewFormItem("Confirm", confirmEntry))
+	}
+
+	dialog.ShowForm(title, "Unlock", "Cancel", items, func(ok bool) {
+		if !ok {
+			if onDone != nil { onDone(false) }
+			return
+		}
+		if pwEntry.Text == "" || (creating && pwEntry.Text != confirmEntry.Text) {
+			dialog.ShowInformation("Search Index", "Passwords are empty or do not match.", w)
+			if onDone != nil { onDone(false) }
+			return
+		}
+		ix, err := searchindex.Load(path, []byte(pwEntry.Text))
+		if err != nil {
+			dialog.ShowError(err, w)
+			if onDone != nil { onDone(false) }
+			return
+		}
+		s.indexMu.Lock()
+		s.index = ix
+		s.indexPassword = []byte(pwEntry.Text)
+		s.indexMu.Unlock()
+		if creating {
+			if err := s.saveIndex(); err != nil {
+				dialog.ShowError(err, w)
+				s.lockIndex()
+				if onDone != nil { onDone(false) }
+				return
+			}
+		}
+		if onDone != nil { onDone(true) }
+	}, w)
+}
+
+// showSearchIndexDialog lets the user find which container holds a given file
+func (s *AppState) showSearchIndexDialog(w fyne.Window) {
+	if s.indexLocked() {
+		s.showUnlockIndexDialog(w, func(ok bool) {
+			if ok { s.showSearchIndexDialog(w) }
+		})
+		return
+	}
+
+	var results []searchindex.Entry
+	s.indexMu.Lock()
+	summary := widget.NewLabel(fmt.Sprintf("%d file(s) in %d container(s)", len(s.index.Entries), s.index.Containers()))
+	s.indexMu.Unlock()
+	list := widget.NewList(
+		func() int { return len(results) },
+		func() fyne.CanvasObject { return widget.NewLabel("result") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id < len(results) {
+				e := results[id]
+				obj.(*widget.Label).SetText(fmt.Sprintf("%s  (%s)  →  %s", e.Path, uiutil.HumanBytes(e.Size), filepath.Base(e.Container)))
+			}
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		if id < len(results) {
+			s.setSelectedFile(results[id].Container)
+		}
+	}
+
+	query := widget.NewEntry()
+	query.SetPlaceHolder("File name, path fragment or SHA-256…")
+	query.OnChanged = func(text string) {
+		s.indexMu.Lock()
+		if s.index == nil { s.indexMu.Unlock(); return }
+		results = s.index.Search(text)
+		s.indexMu.Unlock()
+		summary.SetText(fmt.Sprintf("%d match(es)", len(results)))
+		list.UnselectAll()
+		list.Refresh()
+	}
+
+	lockBtn := widget.NewButton("Lock Index", func() {
+		s.lockIndex()
+		results = nil
+		list.Refresh()
+		summary.SetText("Index locked")
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(query, summary),
+		container.NewHBox(lockBtn, widget.NewLabel("Select a result to load its container.")),
+		nil, nil,
+		list,
+	)
+	d := dialog.NewCustom("Search Encrypted Index", "Close", content, w)
+	d.Resize(fyne.NewSize(560, 420))
+	d.Show()
+}
+
+// indexOutput records the plaintext files of source as living in containerPath.
+// Must be called before the source is deleted. No-op unless indexing is enabled and unlocked.
+func (s *AppState) indexOutput(containerPath, source string) {
+	if !s.indexEnabled || s.indexLocked() { return }
+	// walk the source as encryptDirectory does, so ignored files and skipped links stay out
+	entries, err := searchindex.BuildEntries(source, s.ops().WalkOptions(source, false))
+	if err != nil { return }
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	if s.index != nil { s.index.Add(containerPath, entries) }
+}
+
+// indexLocked reports whether the index is locked
+func (s *AppState) indexLocked() bool {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	return s.index == nil
+}
+
+// saveIndex persists the unlocked index, if any
+func (s *AppState) saveIndex() error {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	if s.index == nil { return nil }
+	path, err := searchindex.DefaultPath()
+	if err == nil { err = s.index.Save(path, s.indexPassword) }
+	if err != nil { return fmt.Errorf("save search index: %w", err) }
+	return nil
+}
+
+// lockIndex forgets the decrypted index and its password
+func (s *AppState) lockIndex() {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	for i := range s.indexPassword { s.indexPassword[i] = 0 }
+	s.indexPassword = nil
+	s.index = nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+)
+
+// saveSession persists the current selection and mode. Passwords are never stored.
+func (s *AppState) saveSession() {
+	if !s.sessionReady { return }
+	sess := s.config.Session
+	if sess == nil { sess = &config.Session{} }
+	sess.SelectedPath = s.selectedPath
+	sess.SelectedPaths = slices.Clone(s.selectedPaths)
+	sess.EncryptionMode = cryptoengine.GetEncryptionModeName(s.encryptionMode)
+	s.config.Session = sess
+	s.config.Save()
+}
+
+// beginBatch records a multi-item operation so it can be resumed after a crash or restart
+func (s *AppState) beginBatch(op string, paths []string) {
+	if !s.primary { return }
+	if s.config.Session == nil { s.config.Session = &config.Session{} }
+	s.config.Session.Pending = &config.PendingBatch{Operation: op, Remaining: slices.Clone(paths), Started: time.Now().Unix()}
+	s.config.Save()
+}
+
+// batchItemDone drops a finished item from the pending batch
+func (s *AppState) batchItemDone(path string) {
+	if !s.primary { return }
+	if s.config.Session == nil || s.config.Session.Pending == nil { return }
+	p := s.config.Session.Pending
+	p.Remaining = slices.DeleteFunc(p.Remaining, func(r string) bool { return r == path })
+	s.config.Save()
+}
+
+// endBatch forgets the pending batch after it completed
+func (s *AppState) endBatch() {
+	if !s.primary { return }
+	if s.config.Session == nil || s.config.Session.Pending == nil { return }
+	s.config.Session.Pending = nil
+	s.config.Save()
+}
+
+// existingPaths filters out paths that no longer exist
+func existingPaths(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil { out = append(out, p) }
+	}
+	return out
+}
+
+// restoreSession reselects the last workspace and offers to resume an interrupted batch
+func (s *AppState) restoreSession(w fyne.Window) {
+	defer func() { s.sessionReady = true }()
+	sess := s.config.Session
+	if sess == nil { return }
+
+	if opt := modeOptionFor(sess.EncryptionMode); opt != "" && sess.EncryptionMode != "" {
+		s.encryptionModeSelect.SetSelected(opt)
+	}
+
+	if p := sess.Pending; p != nil {
+		if remaining := existingPaths(p.Remaining); len(remaining) > 0 {
+			verb := "Encrypt"
+			if p.Operation == "decrypt" { verb = "Decrypt" }
+			msg := fmt.Sprintf("A %s batch started %s was interrupted with %d item(s) left.\n\nResume selects them again; enter your password and press %s to continue.",
+				p.Operation, time.Unix(p.Started, 0).Format("2006-01-02 15:04"), len(remaining), verb)
+			d := dialog.NewConfirm("Resume Interrupted Batch", msg, func(resume bool) {
+				if !resume { s.startFresh(); return }
+				s.setSelectedFiles(remaining)
+				s.statusLabel.SetText(fmt.Sprintf("↩ Restored %d item(s) of the interrupted %s", len(remaining), p.Operation))
+			}, w)
+			d.SetConfirmText("Resume")
+			d.SetDismissText("Start fresh")
+			d.Show()
+			return
+		}
+		sess.Pending = nil
+	}
+
+	if sess.SelectedPath != "" {
+		if _, err := os.Stat(sess.SelectedPath); err == nil {
+			s.setSelectedFile(sess.SelectedPath)
+			s.statusLabel.SetText("↩ Restored last session")
+		}
+	} else if paths := existingPaths(sess.SelectedPaths); len(paths) > 0 {
+		s.setSelectedFiles(paths)
+		s.statusLabel.SetText("↩ Restored last session")
+	}
+}
+
+// startFresh clears the selection, mode and any pending batch
+func (s *AppState) startFresh() {
+	if s.primary { s.config.Session = nil }
+	s.setSelectedFile("")
+	s.encryptionModeSelect.SetSelected("AES-256-GCM")
+	s.config.Save()
+	s.statusLabel.SetText("Status: Ready")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+	"github.com/bangundwir/HadesCrypt/internal/units"
+)
+
+// themeLabels maps the theme names stored in the config to selector entries
+var themeLabels = []struct{ Name, Label string }{
+	{uiutil.ThemeDark, "Dark"},
+	{uiutil.ThemeLight, "Light"},
+	{uiutil.ThemeHighContrast, "High contrast"},
+}
+
+// Size unit choices; sizes shown after a change use the new units
+const (
+	binaryUnitsLabel  = "Binary (KiB, MiB)"
+	decimalUnitsLabel = "Decimal (kB, MB)"
+)
+
+// applyAppearance switches every open window to the configured theme and size
+func (s *AppState) applyAppearance() {
+	fyne.CurrentApp().Settings().SetTheme(uiutil.NewTheme(s.config.Theme, s.config.UIScale))
+}
+
+// showSettings changes the theme, the text and control size, the size units, the
+// extraction limits and the antivirus scan; changes apply at once
+func (s *AppState) showSettings(w fyne.Window) {
+	var themeOptions []string
+	for _, t := range themeLabels { themeOptions = append(themeOptions, t.Label) }
+	themeSelect := widget.NewSelect(themeOptions, func(label string) {
+		for _, t := range themeLabels {
+			if t.Label == label && s.config.Theme != t.Name {
+				s.config.Theme = t.Name
+				s.applyAppearance()
+				s.config.Save()
+			}
+		}
+	})
+	current := "Dark"
+	for _, t := range themeLabels {
+		if t.Name == s.config.Theme { current = t.Label }
+	}
+	themeSelect.SetSelected(current)
+
+	var scaleOptions []string
+	for _, p := range uiutil.ScalePresets { scaleOptions = append(scaleOptions, fmt.Sprintf("%d%%", p)) }
+	scaleSelect := widget.NewSelect(scaleOptions, func(label string) {
+		var percent int
+		fmt.Sscanf(strings.TrimSuffix(label, "%"), "%d", &percent)
+		if percent == 100 { percent = 0 }
+		if percent == s.config.UIScale { return }
+		s.config.UIScale = percent
+		s.applyAppearance()
+		s.config.Save()
+	})
+	scale := s.config.UIScale
+	if scale == 0 { scale = 100 }
+	scaleSelect.SetSelected(fmt.Sprintf("%d%%", scale))
+
+	unitsSelect := widget.NewSelect([]string{binaryUnitsLabel, decimalUnitsLabel}, func(label string) {
+		on := label == decimalUnitsLabel
+		if on == s.config.DecimalUnits { return }
+		s.config.DecimalUnits = on
+		units.SetDecimal(on)
+		s.config.Save()
+	})
+	unitsSelect.SetSelected(binaryUnitsLabel)
+	if s.config.DecimalUnits { unitsSelect.SetSelected(decimalUnitsLabel) }
+
+	extractSize, extractFiles := s.buildExtractLimitControls()
+
+	form := widget.NewForm(
+		widget.NewFormItem("Theme", themeSelect),
+		widget.NewFormItem("Text size", scaleSelect),
+		widget.NewFormItem("Size units", unitsSelect),
+		widget.NewFormItem("Extract limit (GB)", extractSize),
+		widget.NewFormItem("Extract file limit", extractFiles),
+		widget.NewFormItem("Antivirus", s.buildAntivirusControls()),
+	)
+	dialog.NewCustom("⚙️ Settings", "Close", form, w).Show()
+}
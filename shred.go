@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/media"
+	"github.com/bangundwir/HadesCrypt/internal/wipe"
+)
+
+// shredTargets expands the selection into the regular files to destroy and the folders to remove afterwards
+func (s *AppState) shredTargets() (files, dirs []string) {
+	var paths []string
+	if s.selectedPath != "" { paths = []string{s.selectedPath} } else { paths = s.selectedPaths }
+	for _, p := range paths {
+		filepath.WalkDir(p, func(sp string, d fs.DirEntry, err error) error {
+			if err != nil { return nil }
+			if d.IsDir() { dirs = append(dirs, sp) } else if d.Type().IsRegular() { files = append(files, sp) }
+			return nil
+		})
+	}
+	// Deepest folders first so each is empty when removed
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	return files, dirs
+}
+
+// showShredDialog explains, per storage type, how the selection will be destroyed and runs the shredder
+func (s *AppState) showShredDialog(w fyne.Window) {
+	if s.denyInViewer(w) { return }
+	files, dirs := s.shredTargets()
+	if len(files) == 0 && len(dirs) == 0 {
+		dialog.ShowInformation("Shred", "Select files or folders to destroy.", w)
+		return
+	}
+
+	// Group by storage so the advice is shown once per kind of media
+	groups := map[string]media.Info{}
+	counts := map[string]int{}
+	for _, f := range files {
+		info := media.Detect(f)
+		key := info.String()
+		groups[key] = info
+		counts[key]++
+	}
+	var sb strings.Builder
+	for key, info := range groups {
+		fmt.Fprintf(&sb, "%d file(s) on %s → %s\n%s\n\n", counts[key], key, wipe.Plan(info), wipe.Advice(info))
+	}
+	report := widget.NewLabel(strings.TrimSpace(sb.String()))
+	report.Wrapping = fyne.TextWrapWord
+
+	var d dialog.Dialog
+	shredBtn := widget.NewButton(fmt.Sprintf("Destroy %d file(s)", len(files)), func() {
+		d.Hide()
+		s.runShred(w, files, dirs)
+	})
+	shredBtn.Importance = widget.DangerImportance
+	header := widget.NewLabel("This cannot be undone.")
+	content := container.NewBorder(header, shredBtn, nil, nil, container.NewScroll(report))
+	d = dialog.NewCustom("Shred", "Cancel", content, w)
+	d.Resize(fyne.NewSize(620, 420))
+	d.Show()
+}
+
+// runShred destroys files with the strategy of their media and reports what each strategy achieved
+func (s *AppState) runShred(w fyne.Window, files, dirs []string) {
+	go func() {
+		s.startOpSummary("shred")
+		byStrategy := map[wipe.Strategy]int{}
+		trimmed := 0
+		for i, f := range files {
+			if s.cancelRequested.Load() { s.markCanceled(); break }
+			name := filepath.Base(f)
+			s.ui(func() {
+				s.statusLabel.SetText(fmt.Sprintf("🧨 Shredding %d/%d %s", i+1, len(files), name))
+				s.setProgressFraction(float64(i) / float64(len(files)))
+			})
+			var size int64
+			if fi, err := os.Stat(f); err == nil { size = fi.Size() }
+			res, err := wipe.File(f)
+			if err != nil { s.noteError(fmt.Errorf("%s: %w", name, err)); continue }
+			byStrategy[res.Strategy]++
+			if res.Trimmed { trimmed++ }
+			s.addFile(size)
+		}
+		if !s.cancelRequested.Load() {
+			for _, d := range dirs { os.Remove(d) }
+		}
+		sum := s.finishSummary()
+		s.ui(func() {
+			s.setSelectedFiles(nil)
+			s.setProgressFraction(1)
+			status := fmt.Sprintf("✅ Shredded: %d overwritten, %d TRIM, %d deleted only", byStrategy[wipe.Overwrite], byStrategy[wipe.Trim], byStrategy[wipe.DeleteOnly])
+			if trimmed > 0 { status += " • free space trimmed" }
+			s.statusLabel.SetText(status)
+			if sum != nil { s.showSummaryDialog(w, sum) }
+		})
+	}()
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/splitter"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+)
+
+// Compression is estimated from up to sampleFiles files, reading sampleBytes of each
+const (
+	sampleFiles = 8
+	sampleBytes = 128 << 10
+)
+
+// tarEntryOverhead approximates what tar adds per file: a 512-byte header and padding to 512
+const tarEntryOverhead = 1024
+
+// sizeBasis is what the selection will be encrypted from, for the output size preview
+type sizeBasis struct {
+	files       []int64 // sizes of selected files, one container each
+	folder      bool    // a folder of folderFiles files holding folderBytes
+	folderFiles int64
+	folderBytes int64
+	approx      bool    // the folder figures are a sampled estimate
+	ratio       float64 // compressed share of a sample; 0 when none could be read
+}
+
+// setSizeBasis replaces the basis of the preview; UI goroutine only
+func (s *AppState) setSizeBasis(b *sizeBasis) {
+	s.sizeBasis = b
+	s.refreshSizePreview()
+}
+
+// refreshSizePreview shows the expected output size and split part count for the
+// current selection and options. UI goroutine only.
+func (s *AppState) refreshSizePreview() {
+	if s.outputEstimateLabel == nil { return }
+	text := sizePreviewText(s.sizeBasis, s.opt(), s.encryptOptions())
+	s.outputEstimateLabel.SetText(text)
+	if text == "" { s.outputEstimateLabel.Hide() } else { s.outputEstimateLabel.Show() }
+}
+
+// outputGroup is count outputs encrypted from size bytes each
+type outputGroup struct {
+	size  int64
+	count int64
+}
+
+// outputs splits b into the containers encrypting with o writes, and whether they
+// are compressed and only estimated. A folder archive is gzip compressed whatever
+// the Compress option says.
+func (b *sizeBasis) outputs(o Options) (groups []outputGroup, compress, approx bool) {
+	compress, approx = o.Compress, b.approx
+	switch {
+	case b.folder && b.folderFiles == 0:
+	case b.folder && o.Recursive:
+		// per-file sizes are not known, so every file counts as the average
+		groups = append(groups, outputGroup{b.folderBytes / b.folderFiles, b.folderFiles})
+		approx = approx || b.folderFiles > 1
+	case b.folder:
+		groups = append(groups, outputGroup{b.folderBytes + b.folderFiles*tarEntryOverhead + 2*512, 1})
+		compress = true
+	default:
+		for _, size := range b.files { groups = append(groups, outputGroup{size, 1}) }
+	}
+	return groups, compress, approx
+}
+
+// sizePreviewText describes the outputs encrypting from b with o and opts would write
+func sizePreviewText(b *sizeBasis, o Options, opts cryptoengine.EncryptionOptions) string {
+	if b == nil { return "" }
+	groups, compress, approx := b.outputs(o)
+	if len(groups) == 0 { return "" }
+	if opts.Mode == cryptoengine.ModeGnuPG { return "📦 Output size is decided by gpg" }
+	split := int64(0)
+	if o.Split { split = splitter.ConvertToBytes(o.SplitSize, splitter.SizeUnit(o.SplitUnit)) }
+
+	var plain, packed, total, parts, outputs int64
+	for _, g := range groups {
+		in := g.size
+		if compress && b.ratio > 0 {
+			in = int64(float64(in) * b.ratio)
+			approx = true
+		}
+		size, err := cryptoengine.EncryptedSize(in, opts)
+		if err != nil { return "📦 Output size unknown: " + err.Error() }
+		plain += g.size * g.count
+		packed += in * g.count
+		total += size * g.count
+		outputs += g.count
+		if split > 0 { parts += g.count * max(1, (size+split-1)/split) }
+	}
+	tilde := ""
+	if approx { tilde = "~" } else if opts.AdaptiveChunks || opts.UseDeniability { tilde = "≤" } // EncryptedSize counts the smallest chunks and the longest padding
+	text := fmt.Sprintf("📦 Output: %s%s", tilde, uiutil.HumanBytes(total))
+	if outputs > 1 { text += fmt.Sprintf(" in %d files", outputs) }
+	switch {
+	case compress && b.ratio > 0 && plain > 0:
+		text += fmt.Sprintf(" (compressed to ~%.0f%%, +%s headers and tags)", float64(packed)*100/float64(plain), uiutil.HumanBytes(total-packed))
+	case compress:
+		text += " (compression not estimated)"
+	default:
+		text += fmt.Sprintf(" (+%s headers and tags)", uiutil.HumanBytes(total-packed))
+	}
+	if split > 0 { text += fmt.Sprintf(" • %s%d part(s) of %d %s", tilde, parts, o.SplitSize, o.SplitUnit) }
+	return text
+}
+
+// largestOutput is the size of the biggest container encrypting from b writes, or
+// of the average one for a folder encrypted file by file; 0 when not known
+func largestOutput(b *sizeBasis, o Options, opts cryptoengine.EncryptionOptions) int64 {
+	if b == nil { return 0 }
+	groups, compress, _ := b.outputs(o)
+	var largest int64
+	for _, g := range groups {
+		in := g.size
+		if compress && b.ratio > 0 { in = int64(float64(in) * b.ratio) }
+		if size, err := cryptoengine.EncryptedSize(in, opts); err == nil { largest = max(largest, size) }
+	}
+	return largest
+}
+
+// sampleRatio compresses the start of up to sampleFiles of paths and returns the
+// compressed share, or 0 when nothing could be read. Folders are searched for files.
+// Must not run on the UI goroutine.
+func sampleRatio(paths []string) float64 {
+	var files []string
+	for _, p := range paths {
+		filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if len(files) >= sampleFiles { return filepath.SkipAll }
+			if err == nil && d.Type().IsRegular() { files = append(files, path) }
+			return nil
+		})
+	}
+	var plain, packed int64
+	buf := make([]byte, sampleBytes)
+	for _, f := range files {
+		n, err := readHead(f, buf)
+		if err != nil || n == 0 { continue }
+		var z bytes.Buffer
+		w, _ := flate.NewWriter(&z, flate.DefaultCompression)
+		w.Write(buf[:n])
+		w.Close()
+		plain += int64(n)
+		packed += int64(z.Len())
+	}
+	if plain == 0 { return 0 }
+	return min(float64(packed)/float64(plain), 1)
+}
+
+func readHead(path string, buf []byte) (int, error) {
+	f, err := os.Open(path)
+	if err != nil { return 0, err }
+	defer f.Close()
+	n, err := io.ReadFull(f, buf)
+	if err == io.ErrUnexpectedEOF || err == io.EOF { err = nil }
+	return n, err
+}
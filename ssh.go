@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/contacts"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/format"
+	"github.com/bangundwir/HadesCrypt/internal/sshrecipient"
+)
+
+// sshIdentityPath is the private key for .age files: the configured one, else ~/.ssh/id_ed25519 or id_rsa
+func (s *AppState) sshIdentityPath() string {
+	if s.config.SSHIdentity != "" { return s.config.SSHIdentity }
+	return sshrecipient.DefaultIdentityPath()
+}
+
+// decryptAge decrypts an age file with the SSH identity and the own X25519 keypairs
+// (🗝 Keys); passphrase unlocks a protected SSH key or the keypairs
+func (s *AppState) decryptAge(in, out string, passphrase []byte, onProgress cryptoengine.ProgressCallback) error {
+	var ids []age.Identity
+	if path := s.sshIdentityPath(); path != "" {
+		id, err := sshrecipient.LoadIdentity(path, func() ([]byte, error) {
+			if len(passphrase) == 0 { return nil, fmt.Errorf("%s is passphrase-protected; enter its passphrase as the password", path) }
+			return passphrase, nil
+		})
+		if err != nil { return err }
+		ids = append(ids, id)
+	}
+	if dir, err := contacts.IdentitiesDir(); err == nil {
+		if keys, _ := contacts.ListKeypairs(dir); len(keys) > 0 {
+			ids = append(ids, contacts.Identity(keys, func() (string, error) {
+				if len(passphrase) == 0 { return "", errors.New("enter the passphrase of your encryption key as the password") }
+				return string(passphrase), nil
+			}))
+		}
+	}
+	if len(ids) == 0 { return errors.New("no private key found; choose an SSH key under 🔑 SSH or generate one under 🗝 Keys") }
+	err := sshrecipient.DecryptFile(in, out, ids, onProgress)
+	if errors.Is(err, sshrecipient.ErrNoMatchingKey) || errors.Is(err, contacts.ErrWrongPassphrase) { return fmt.Errorf("%w: %v", cryptoengine.ErrAuthFailed, err) }
+	return err
+}
+
+// onlyAgeSelected reports whether every selected item is an .age file, which needs no password
+// unless the key is protected
+func (s *AppState) onlyAgeSelected() bool {
+	paths := s.selectedPaths
+	if s.selectedPath != "" { paths = []string{s.selectedPath} }
+	for _, p := range paths {
+		if !format.IsAge(p) { return false }
+	}
+	return len(paths) > 0
+}
+
+// showSSHDialog encrypts the selected files to SSH public keys (pasted or
+// fetched from GitHub) and sets the private key used to decrypt .age files
+func (s *AppState) showSSHDialog(w fyne.Window) {
+	recipients := widget.NewMultiLineEntry()
+	recipients.SetPlaceHolder("ssh-ed25519 AAAA… alice@laptop\nssh-rsa AAAA… bob@desktop\nage1… carol")
+	recipients.SetMinRowsVisible(5)
+	recipients.SetText(s.config.SSHRecipients)
+	summary := widget.NewLabel("")
+	summary.Wrapping = fyne.TextWrapWord
+	parse := func() ([]sshrecipient.Recipient, error) {
+		recs, err := sshrecipient.ParseRecipients(recipients.Text)
+		switch {
+		case err != nil:
+			summary.SetText("⚠️ " + err.Error())
+		case len(recs) == 0:
+			summary.SetText("Paste ssh-ed25519, ssh-rsa or age1… public keys, one per line")
+		default:
+			var names []string
+			for _, r := range recs { names = append(names, r.String()) }
+			summary.SetText(fmt.Sprintf("%d recipient(s):\n%s", len(recs), strings.Join(names, "\n")))
+		}
+		return recs, err
+	}
+	recipients.OnChanged = func(string) { parse() }
+	parse()
+
+	githubBtn := widget.NewButton("Add GitHub user…", func() {
+		user := widget.NewEntry()
+		user.SetPlaceHolder("username")
+		dialog.ShowForm("Keys from GitHub", "Fetch", "Cancel", []*widget.FormItem{widget.NewFormItem("User", user)}, func(ok bool) {
+			if !ok { return }
+			summary.SetText("⏳ Fetching keys…")
+			go func() {
+				recs, err := sshrecipient.FetchGitHubKeys(context.Background(), user.Text)
+				s.ui(func() {
+					if err != nil { summary.SetText("⚠️ " + err.Error()); return }
+					text := strings.TrimRight(recipients.Text, "\n")
+					if text != "" { text += "\n" }
+					text += "# github.com/" + strings.TrimPrefix(strings.TrimSpace(user.Text), "@") + "\n"
+					for _, r := range recs { text += r.Line() + "\n" }
+					recipients.SetText(text)
+				})
+			}()
+		}, w)
+	})
+
+	// contacts and own encryption keys, appended to the recipients when picked
+	known := map[string]string{}
+	var knownNames []string
+	if path, err := contacts.BookPath(); err == nil {
+		if book, err := contacts.LoadBook(path); err == nil {
+			for _, c := range book.Contacts { known[c.Name] = c.Recipient; knownNames = append(knownNames, c.Name) }
+		}
+	}
+	if dir, err := contacts.IdentitiesDir(); err == nil {
+		keys, _ := contacts.ListKeypairs(dir)
+		for _, k := range keys { known["me: "+k.Name] = k.Recipient; knownNames = append(knownNames, "me: "+k.Name) }
+	}
+	var contactSelect *widget.Select
+	contactSelect = widget.NewSelect(knownNames, func(name string) {
+		if name == "" { return }
+		text := strings.TrimRight(recipients.Text, "\n")
+		if text != "" { text += "\n" }
+		recipients.SetText(text + "# " + name + "\n" + known[name] + "\n")
+		contactSelect.ClearSelected()
+	})
+	contactSelect.PlaceHolder = "Add contact…"
+	if len(knownNames) == 0 { contactSelect.Disable() }
+
+	identity := widget.NewEntry()
+	identity.SetPlaceHolder(sshrecipient.DefaultIdentityPath())
+	identity.SetText(s.config.SSHIdentity)
+	identity.OnChanged = func(text string) {
+		text = strings.TrimSpace(text)
+		if text == s.config.SSHIdentity { return }
+		s.config.SSHIdentity = text
+		s.config.Save()
+	}
+	browse := widget.NewButton("…", func() {
+		dialog.ShowFileOpen(func(rc fyne.URIReadCloser, err error) {
+			if err != nil || rc == nil { return }
+			rc.Close()
+			identity.SetText(rc.URI().Path())
+		}, w)
+	})
+
+	var d dialog.Dialog
+	encryptBtn := widget.NewButton("🔒 Encrypt selection", func() {
+		recs, err := parse()
+		if err != nil || len(recs) == 0 { return }
+		if s.config.SSHRecipients != recipients.Text {
+			s.config.SSHRecipients = recipients.Text
+			s.config.Save()
+		}
+		d.Hide()
+		s.encryptToSSH(w, recs)
+	})
+	content := container.NewVBox(
+		widget.NewLabelWithStyle("Encrypt to SSH or age public keys", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		recipients,
+		container.NewHBox(contactSelect, githubBtn, encryptBtn),
+		summary,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Decrypt .age files with", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewBorder(nil, nil, widget.NewLabel("Private key:"), browse, identity),
+		widget.NewLabel("Select .age files and press 🔓 Decrypt; a protected key's passphrase goes in the password field."),
+	)
+	d = dialog.NewCustom("🔑 SSH Keys", "Close", content, w)
+	d.Resize(fyne.NewSize(680, 520))
+	d.Show()
+}
+
+// encryptToSSH writes <file>.age for every selected file
+func (s *AppState) encryptToSSH(w fyne.Window, recs []sshrecipient.Recipient) {
+	if s.denyInViewer(w) { return }
+	paths := s.selectedPaths
+	if s.selectedPath != "" { paths = []string{s.selectedPath} }
+	var files []string
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() { files = append(files, p) }
+	}
+	if len(files) == 0 {
+		dialog.ShowInformation("SSH encryption", "Select one or more files (folders are not supported).", w)
+		return
+	}
+	if s.adminPolicyErr != nil { dialog.ShowError(s.adminPolicyErr, w); return }
+	// Recovery recipients can decrypt every file the policy covers
+	recs = append(slices.Clone(recs), s.adminPolicy.Recipients()...)
+	s.cancelRequested.Store(false)
+	s.setProgressFraction(0)
+	go func() {
+		s.startOpSummary("encrypt")
+		for i, in := range files {
+			if s.cancelRequested.Load() { s.markCanceled(); break }
+			out := format.OutputPathFor(in, format.AgeExtension)
+			if dir := s.opt().OutputDir; dir != "" { out = format.OutputPathFor(filepath.Join(dir, filepath.Base(in)), format.AgeExtension) }
+			s.setStatus(fmt.Sprintf("🔐 %d/%d %s", i+1, len(files), filepath.Base(in)))
+			err := sshrecipient.EncryptFile(in, out, recs, func(done, total int64) {
+				s.reportProgress(done, total)
+			})
+			entry := config.HistoryEntry{FileName: filepath.Base(in), Operation: "encrypt", Timestamp: time.Now().Unix(), Result: "success"}
+			if fi, err := os.Stat(in); err == nil { entry.Size = fi.Size() }
+			if err != nil {
+				entry.Result, entry.Error = "error", err.Error()
+				s.noteError(err)
+			} else {
+				s.addFile(entry.Size)
+			}
+			s.addHistory(entry)
+		}
+		s.config.Save()
+		sum := s.finishSummary()
+		s.ui(func() {
+			s.setProgressFraction(1)
+			s.statusLabel.SetText("✅ Encrypted to SSH keys")
+			if sum != nil && sum.Errors > 0 { s.statusLabel.SetText("❌ " + sum.FirstError) }
+			s.showSummaryDialog(w, sum)
+		})
+	}()
+}
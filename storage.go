@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/media"
+	"github.com/bangundwir/HadesCrypt/internal/splitter"
+	uiutil "github.com/bangundwir/HadesCrypt/internal/ui"
+	"github.com/bangundwir/HadesCrypt/internal/wipe"
+)
+
+// splitOutput splits the container out into volumes of the Split into chunks size,
+// once it has been verified, indexed and timestamped whole; see splitter.Split
+func (s *AppState) splitOutput(out string) error {
+	o := s.opt()
+	if !o.Split { return nil }
+	if _, err := splitter.Split(out, splitter.ConvertToBytes(o.SplitSize, splitter.SizeUnit(o.SplitUnit)), nil); err != nil { return fmt.Errorf("split %s: %w", filepath.Base(out), err) }
+	return nil
+}
+
+// mediaRetries is how often a file operation is attempted on network or removable storage
+const mediaRetries = 3
+
+// confirmSourceDeletion warns before encrypting with "delete source" when a source lives on
+// storage where deleted plaintext stays recoverable, then lists what will be deleted and
+// runs proceed if the user agrees to both.
+func (s *AppState) confirmSourceDeletion(w fyne.Window, sources []string, proceed func()) {
+	run := func() { s.confirmDeletionPlan(w, false, proceed) }
+	if !s.deleteAfter { run(); return }
+	for _, p := range sources {
+		info := media.Detect(p)
+		if info.Kind == media.Local { continue }
+		msg := fmt.Sprintf("%s is on a %s.\n\n%s\n\nEncrypt and delete the sources anyway?", p, info, wipe.Advice(info))
+		dialog.ShowConfirm("Source deletion is not secure here", msg, func(ok bool) {
+			if ok { run() }
+		}, w)
+		return
+	}
+	run()
+}
+
+// checkOutputLimit warns before encrypting when the largest output will not fit the
+// file size limit of its destination (FAT32, a cloud sync folder) and offers to split
+// it, instead of failing gigabytes into the job. It is reached with the sources
+// acknowledged, so proceed runs with both acknowledgements set.
+func (s *AppState) checkOutputLimit(w fyne.Window, proceed func()) {
+	run := func() {
+		s.mediaAcknowledged, s.limitAcknowledged = true, true
+		proceed()
+		s.mediaAcknowledged, s.limitAcknowledged = false, false
+	}
+	o := s.opt()
+	part := largestOutput(s.sizeBasis, o, s.encryptOptions())
+	if o.Split { part = min(part, splitter.ConvertToBytes(o.SplitSize, splitter.SizeUnit(o.SplitUnit))) }
+	if part == 0 { run(); return }
+	var limit media.Limit
+	var dir string
+	seen := map[string]bool{}
+	for _, p := range s.selection() {
+		d := filepath.Dir(s.defaultOutputPathForEncrypt(p))
+		if seen[d] { continue }
+		seen[d] = true
+		if l := media.MaxFileSize(d); l.Size > 0 && (limit.Size == 0 || l.Size < limit.Size) { limit, dir = l, d }
+	}
+	if limit.Size == 0 || part <= limit.Size { run(); return }
+
+	size, unit := splitter.SuggestPartSize(limit.Size)
+	msg := fmt.Sprintf("The output will be about %s, but %s is on %s, which takes files of at most %s. Encrypting would fail once that much is written.\n\nSplit the output into parts of %d %s instead?",
+		uiutil.HumanBytes(part), dir, limit.Name, uiutil.HumanBytes(limit.Size), size, unit)
+	var d *dialog.CustomDialog
+	splitBtn := widget.NewButton(fmt.Sprintf("Split into %d %s parts", size, unit), func() {
+		d.Hide()
+		s.options.splitUnit.Set(string(unit))
+		s.options.splitSize.Set(size)
+		s.options.split.Set(true)
+		run()
+	})
+	splitBtn.Importance = widget.HighImportance
+	anyway := widget.NewButton("Encrypt anyway", func() { d.Hide(); run() })
+	cancel := widget.NewButton("Cancel", func() { d.Hide() })
+	text := widget.NewLabel(msg)
+	text.Wrapping = fyne.TextWrapWord
+	d = dialog.NewCustomWithoutButtons("Output too large for "+limit.Name, text, w)
+	d.SetButtons([]fyne.CanvasObject{cancel, anyway, splitBtn})
+	d.Resize(fyne.NewSize(520, 0))
+	d.Show()
+}
+
+// noteSlowMedia tells the user when network or removable storage switches on retries and larger buffers
+func (s *AppState) noteSlowMedia(paths ...string) {
+	for _, p := range paths {
+		if p == "" { continue }
+		if info := media.Detect(p); info.Slow() {
+			s.setStatus(fmt.Sprintf("📡 %s detected — larger buffers, retrying transient errors", info.Kind))
+			return
+		}
+	}
+}
+
+// withMediaRetry runs fn, retrying transient IO errors when src or dst is on slow media
+func withMediaRetry(src, dst string, fn func() error) error {
+	if !media.Detect(src).Slow() && !media.Detect(dst).Slow() { return fn() }
+	return media.Retry(mediaRetries, fn)
+}
+
+// archiveTempDir is where folder archives are staged before encryption: next to the
+// output normally, but on local temp storage when the output is a network or removable drive
+func archiveTempDir(outputPath string) string {
+	dir := filepath.Dir(outputPath)
+	if media.Detect(dir).Slow() { return "" }
+	return dir
+}
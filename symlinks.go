@@ -0,0 +1,28 @@
+package main
+
+import (
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/fswalk"
+)
+
+// walkFolder walks a folder selected for encryption
+func (s *AppState) walkFolder(root string, fn filepath.WalkFunc) error {
+	return fswalk.Walk(root, s.ops().WalkOptions(root, false), fn)
+}
+
+// buildSymlinkSelect returns the symlink policy selector, bound to the options model
+func (s *AppState) buildSymlinkSelect() *widget.Select {
+	var labels []string
+	for _, p := range fswalk.Policies { labels = append(labels, p.Label()) }
+	return widget.NewSelectWithData(labels, s.options.symlinks)
+}
+
+// symlinkRow lays out the selector with its label
+func symlinkRow(sel *widget.Select) fyne.CanvasObject {
+	return container.NewHBox(widget.NewLabel("Symlinks in folders:"), sel)
+}
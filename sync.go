@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/notify"
+	"github.com/bangundwir/HadesCrypt/internal/syncfolder"
+)
+
+// syncInterval is how often a running pair looks for changes
+const syncInterval = 30 * time.Second
+
+func syncKey(f config.SyncFolder) string { return f.Local + "\x00" + f.Remote }
+
+// syncMessage summarizes one sync pass for the notification targets; errors and
+// conflicts count as failures since they need the user's attention
+func syncMessage(f config.SyncFolder, r syncfolder.Result) notify.Message {
+	lines := []string{f.Local + " ⇄ " + f.Remote, r.String()}
+	for _, err := range r.Errors { lines = append(lines, err.Error()) }
+	return notify.Message{
+		Title:  "HadesCrypt sync " + filepath.Base(f.Local) + ": " + r.String(),
+		Body:   strings.Join(lines, "\n"),
+		Failed: len(r.Errors) > 0 || r.Conflicts > 0,
+	}
+}
+
+// stopSyncFolders stops every running pair and forgets their passwords
+func (s *AppState) stopSyncFolders() {
+	for key, p := range s.syncPairs {
+		p.Close()
+		delete(s.syncPairs, key)
+	}
+}
+
+// showSyncDialog manages the folders kept in two-way sync with an encrypted mirror
+func (s *AppState) showSyncDialog(w fyne.Window) {
+	if s.denyInViewer(w) { return }
+	if s.syncPairs == nil { s.syncPairs = map[string]*syncfolder.Pair{} }
+	status := map[string]string{}
+	selected := -1
+
+	var list *widget.List
+	list = widget.NewList(
+		func() int { return len(s.config.SyncFolders) },
+		func() fyne.CanvasObject { return widget.NewLabel("folder") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id >= len(s.config.SyncFolders) { return }
+			f := s.config.SyncFolders[id]
+			state := "⏸ stopped"
+			if s.syncPairs[syncKey(f)] != nil { state = "▶ running" }
+			if st := status[syncKey(f)]; st != "" { state += " — " + st }
+			obj.(*widget.Label).SetText(fmt.Sprintf("%s  ⇄  %s   %s", f.Local, f.Remote, state))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) { selected = id }
+	current := func() (config.SyncFolder, bool) {
+		if selected < 0 || selected >= len(s.config.SyncFolders) { return config.SyncFolder{}, false }
+		return s.config.SyncFolders[selected], true
+	}
+	report := func(f config.SyncFolder, r syncfolder.Result) {
+		s.ui(func() {
+			status[syncKey(f)] = time.Now().Format("15:04") + " " + r.String()
+			list.Refresh()
+			if r.Changed() || len(r.Errors) > 0 { go notify.Notify(context.Background(), s.config.Notifications, syncMessage(f, r)) }
+		})
+	}
+
+	addBtn := widget.NewButton("Add…", func() {
+		localEntry := widget.NewEntry()
+		localEntry.SetPlaceHolder("Plaintext folder")
+		remoteEntry := widget.NewEntry()
+		remoteEntry.SetPlaceHolder("Encrypted mirror, e.g. inside a cloud drive")
+		browse := func(e *widget.Entry) *widget.Button {
+			return widget.NewButton("…", func() {
+				dialog.ShowFolderOpen(func(lu fyne.ListableURI, err error) {
+					if err != nil || lu == nil { return }
+					e.SetText(lu.Path())
+				}, w)
+			})
+		}
+		dialog.ShowForm("Add Sync Folder", "Add", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Folder", container.NewBorder(nil, nil, nil, browse(localEntry), localEntry)),
+			widget.NewFormItem("Mirror", container.NewBorder(nil, nil, nil, browse(remoteEntry), remoteEntry)),
+		}, func(ok bool) {
+			if !ok { return }
+			if localEntry.Text == "" || remoteEntry.Text == "" { dialog.ShowError(errors.New("choose both folders"), w); return }
+			f := config.SyncFolder{Local: filepath.Clean(localEntry.Text), Remote: filepath.Clean(remoteEntry.Text)}
+			for _, other := range s.config.SyncFolders {
+				if syncKey(other) == syncKey(f) { return }
+			}
+			s.config.SyncFolders = append(s.config.SyncFolders, f)
+			s.config.Save()
+			list.Refresh()
+		}, w)
+	})
+	startBtn := widget.NewButton("▶ Start…", func() {
+		f, ok := current()
+		if !ok || s.syncPairs[syncKey(f)] != nil { return }
+		pwEntry := widget.NewPasswordEntry()
+		pwEntry.SetPlaceHolder("Password for the mirror…")
+		dialog.ShowForm("Start Sync", "Start", "Cancel", []*widget.FormItem{widget.NewFormItem("Password", pwEntry)}, func(ok bool) {
+			if !ok || pwEntry.Text == "" { return }
+			opts := s.encryptOptions()
+			opts.Recipients = nil
+			if err := s.checkPolicy(opts); err != nil { dialog.ShowError(err, w); return }
+			// One password protects the whole mirror, so it is escrowed once beside it
+			if err := s.adminPolicy.Escrow(f.Remote, []byte(pwEntry.Text), false); err != nil { dialog.ShowError(err, w); return }
+			p, err := syncfolder.New(f.Local, f.Remote, []byte(pwEntry.Text), opts)
+			if err != nil { dialog.ShowError(err, w); return }
+			key := syncKey(f)
+			s.syncPairs[key] = p
+			status[key] = "syncing…"
+			list.Refresh()
+			p.Watch(syncInterval, func(r syncfolder.Result) { report(f, r) })
+		}, w)
+	})
+	stopBtn := widget.NewButton("⏸ Stop", func() {
+		f, ok := current()
+		if !ok { return }
+		if p := s.syncPairs[syncKey(f)]; p != nil {
+			delete(s.syncPairs, syncKey(f))
+			go func() { p.Close() }()
+		}
+		status[syncKey(f)] = ""
+		list.Refresh()
+	})
+	syncNowBtn := widget.NewButton("🔄 Sync now", func() {
+		f, ok := current()
+		if !ok { return }
+		p := s.syncPairs[syncKey(f)]
+		if p == nil { dialog.ShowInformation("Sync", "Start this folder first.", w); return }
+		status[syncKey(f)] = "syncing…"
+		list.Refresh()
+		go func() { report(f, p.Sync()) }()
+	})
+	removeBtn := widget.NewButton("Remove", func() {
+		f, ok := current()
+		if !ok { return }
+		if s.syncPairs[syncKey(f)] != nil { dialog.ShowInformation("Sync", "Stop this folder first.", w); return }
+		s.config.SyncFolders = append(s.config.SyncFolders[:selected], s.config.SyncFolders[selected+1:]...)
+		s.config.Save()
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	})
+
+	note := widget.NewLabel("Files are encrypted one by one into the mirror; their names stay visible there. Replaced and deleted files are kept in the mirror's " + syncfolder.VersionsDir + " folder, and a file changed on both sides keeps the mirror's edit as a conflict copy. Sync runs while HadesCrypt is open.")
+	note.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(
+		note,
+		container.NewHBox(addBtn, startBtn, stopBtn, syncNowBtn, removeBtn),
+		nil, nil, list,
+	)
+	d := dialog.NewCustom("🔄 Sync Folders", "Close", content, w)
+	d.Resize(fyne.NewSize(720, 420))
+	d.Show()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"github.com/bangundwir/HadesCrypt/internal/timestamp"
+)
+
+// timestampOutput obtains an RFC 3161 token for an encrypted output when timestamping is enabled.
+// A failing TSA does not fail the encryption; it is counted in the operation summary.
+func (s *AppState) timestampOutput(out string) {
+	if !s.config.TimestampEnabled { return }
+	name := filepath.Base(out)
+	s.setStatus("⏱ Timestamping " + name + "…")
+	if _, err := timestamp.Stamp(out, s.config.TimestampURL); err != nil {
+		s.noteError(fmt.Errorf("timestamp %s: %w", name, err))
+	}
+}
+
+// doVerifyTimestamp checks the selected file against its stored timestamp token
+func (s *AppState) doVerifyTimestamp(w fyne.Window) {
+	if s.selectedPath == "" || !timestamp.HasToken(s.selectedPath) {
+		dialog.ShowInformation("Timestamp", "Select a file that has a "+timestamp.TokenExtension+" token next to it.", w)
+		return
+	}
+	path := s.selectedPath
+	go func() {
+		tok, err := timestamp.Verify(path)
+		s.ui(func() {
+			if err != nil {
+				s.statusLabel.SetText("❌ Timestamp invalid")
+				dialog.ShowError(err, w)
+				return
+			}
+			s.statusLabel.SetText("✅ Timestamp matches file")
+			dialog.ShowInformation("Timestamp Valid", fmt.Sprintf(
+				"%s existed at %s\nSerial: %s • Policy: %s\n\nThe hash matches the token. To validate the TSA signature run:\nopenssl ts -verify -in %s -data %s -CAfile <tsa-ca.pem>",
+				filepath.Base(path), tok.Time.Local().Format("2006-01-02 15:04:05 MST"), tok.Serial, tok.Policy,
+				filepath.Base(path)+timestamp.TokenExtension, filepath.Base(path)), w)
+		})
+	}()
+}
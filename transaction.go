@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bangundwir/HadesCrypt/internal/apperr"
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/cryptoengine"
+	"github.com/bangundwir/HadesCrypt/internal/progress"
+	"github.com/bangundwir/HadesCrypt/internal/txn"
+)
+
+// allOrNothingFolderConflict reports whether the selection has a folder that recursive
+// mode would encrypt file by file, which all-or-nothing batches cannot stage
+func (s *AppState) allOrNothingFolderConflict() bool {
+	if !s.allOrNothing || !s.opt().Recursive { return false }
+	for _, p := range s.selectedPaths {
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() { return true }
+	}
+	return false
+}
+
+// encryptAllOrNothing encrypts the multi-selection as one transaction: outputs are staged
+// next to their destinations and published only after every item succeeded, and sources are
+// deleted only after that. A failure or cancel discards every staged output and the returned
+// error names the item that blocked completion. Runs on the job goroutine.
+func (s *AppState) encryptAllOrNothing(password []byte, phases []*progress.Phase) error {
+	type done struct { src, out string; info os.FileInfo }
+	var finished []done
+	tx := txn.New()
+	blocked := func(p string, err error) error {
+		if rerr := tx.Rollback(); rerr != nil { s.noteError(fmt.Errorf("clean up staged outputs: %w", rerr)) }
+		return fmt.Errorf("all-or-nothing batch rolled back (%d finished output(s) discarded, no sources deleted); blocked by %s: %w", len(finished), filepath.Base(p), err)
+	}
+	for idx, p := range s.selectedPaths {
+		if s.cancelRequested.Load() { return blocked(p, apperr.ErrCanceled) }
+		fi, err := os.Stat(p)
+		if err != nil { return blocked(p, err) }
+		base := filepath.Base(p)
+		s.setStatus(fmt.Sprintf("🔐 %d/%d %s", idx+1, len(s.selectedPaths), base))
+		out := s.defaultOutputPathForEncrypt(p)
+		dst, err := tx.Stage(out)
+		if err != nil { return blocked(p, err) }
+		switch {
+		case fi.IsDir():
+			err = s.encryptDirectory(p, dst, password, phases[idx].Update)
+		case fi.Mode().IsRegular():
+			err = withMediaRetry(p, dst, func() error { return cryptoengine.EncryptFileWithOptions(p, dst, password, s.encryptOptionsFor(p), phases[idx].Update) })
+			if err == nil { err = s.escrowOutput(dst, password) }
+		default:
+			err = fmt.Errorf("not a regular file or folder")
+		}
+		if err != nil { return blocked(p, err) }
+		// the token file lands next to the staged output and is published with it
+		s.timestampOutput(dst)
+		phases[idx].Complete()
+		finished = append(finished, done{src: p, out: out, info: fi})
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("all-or-nothing batch rolled back (%d finished output(s) discarded, no sources deleted): %w", len(finished), err)
+	}
+
+	for _, d := range finished {
+		s.indexOutput(d.out, d.src)
+		op := "encrypt"
+		if d.info.IsDir() { op = "encrypt-folder" }
+		s.addHistory(config.HistoryEntry{FileName: filepath.Base(d.src), Operation: op, Size: d.info.Size(), Timestamp: time.Now().Unix(), Result: "success"})
+		if s.deleteAfter { s.removeEncryptedSource(d.src, d.out, password) }
+		if err := s.splitOutput(d.out); err != nil { s.noteError(err) }
+		if d.info.IsDir() { s.addFolder(0) } else { s.addFile(d.info.Size()) }
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/config"
+	"github.com/bangundwir/HadesCrypt/internal/s3"
+)
+
+// showDestinationForm adds a destination, or edits dest when it is not nil
+func (s *AppState) showDestinationForm(w fyne.Window, dest *config.Destination, onSaved func()) {
+	d := config.Destination{Region: "us-east-1", Checksum: true}
+	if dest != nil { d = *dest }
+
+	name := widget.NewEntry()
+	name.SetText(d.Name)
+	endpoint := widget.NewEntry()
+	endpoint.SetPlaceHolder("Empty for AWS, e.g. https://minio.lan:9000")
+	endpoint.SetText(d.Endpoint)
+	region := widget.NewEntry()
+	region.SetText(d.Region)
+	bucket := widget.NewEntry()
+	bucket.SetText(d.Bucket)
+	prefix := widget.NewEntry()
+	prefix.SetPlaceHolder("e.g. backups/")
+	prefix.SetText(d.Prefix)
+	accessKey := widget.NewEntry()
+	accessKey.SetPlaceHolder("Empty uses " + s3.EnvAccessKeyID)
+	accessKey.SetText(d.AccessKeyID)
+	pathStyle := widget.NewCheck("Path-style URLs (most self-hosted servers)", nil)
+	pathStyle.SetChecked(d.PathStyle)
+
+	storageClass := widget.NewSelectEntry(s3.StorageClasses)
+	storageClass.SetPlaceHolder("Bucket default")
+	storageClass.SetText(d.StorageClass)
+	lockMode := widget.NewSelect([]string{"Off", s3.LockGovernance, s3.LockCompliance}, nil)
+	lockMode.SetSelected("Off")
+	if d.LockMode != "" { lockMode.SetSelected(d.LockMode) }
+	lockDays := widget.NewEntry()
+	lockDays.SetPlaceHolder("days")
+	if d.LockDays > 0 { lockDays.SetText(strconv.Itoa(d.LockDays)) }
+	checksum := widget.NewCheck("Send SHA-256 checksum (provider verifies on receipt)", nil)
+	checksum.SetChecked(d.Checksum)
+	rate := widget.NewEntry()
+	rate.SetPlaceHolder("Unlimited")
+	if d.RateLimitMBps > 0 { rate.SetText(strconv.FormatFloat(d.RateLimitMBps, 'f', -1, 64)) }
+
+	title := "Add Destination"
+	if dest != nil { title = "Edit Destination" }
+	dialog.ShowForm(title, "Save", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Name", name),
+		widget.NewFormItem("Endpoint", endpoint),
+		widget.NewFormItem("Region", region),
+		widget.NewFormItem("Bucket", bucket),
+		widget.NewFormItem("Key prefix", prefix),
+		widget.NewFormItem("Access key ID", accessKey),
+		widget.NewFormItem("", pathStyle),
+		widget.NewFormItem("Storage class", storageClass),
+		widget.NewFormItem("Object Lock", container.NewGridWithColumns(2, lockMode, lockDays)),
+		widget.NewFormItem("", checksum),
+		widget.NewFormItem("Bandwidth (MB/s)", rate),
+	}, func(ok bool) {
+		if !ok { return }
+		nd := config.Destination{
+			Name:         strings.TrimSpace(name.Text),
+			Endpoint:     strings.TrimSpace(endpoint.Text),
+			Region:       strings.TrimSpace(region.Text),
+			Bucket:       strings.TrimSpace(bucket.Text),
+			Prefix:       strings.TrimSpace(prefix.Text),
+			AccessKeyID:  strings.TrimSpace(accessKey.Text),
+			PathStyle:    pathStyle.Checked,
+			StorageClass: strings.TrimSpace(storageClass.Text),
+			Checksum:     checksum.Checked,
+		}
+		if lockMode.Selected != "Off" {
+			nd.LockMode = lockMode.Selected
+			nd.LockDays, _ = strconv.Atoi(strings.TrimSpace(lockDays.Text))
+		}
+		if v := strings.TrimSpace(rate.Text); v != "" {
+			mbps, err := strconv.ParseFloat(v, 64)
+			if err != nil || mbps < 0 { dialog.ShowError(errors.New("bandwidth must be a number of MB/s"), w); return }
+			nd.RateLimitMBps = mbps
+		}
+		if nd.Name == "" || nd.Bucket == "" { dialog.ShowError(errors.New("name and bucket are required"), w); return }
+		if err := s3.ValidateOptions(nd); err != nil { dialog.ShowError(err, w); return }
+		if other := s.config.GetDestination(nd.Name); other != nil && other != dest { dialog.ShowError(fmt.Errorf("a destination called %q already exists", nd.Name), w); return }
+		if dest != nil { *dest = nd } else { s.config.Destinations = append(s.config.Destinations, nd) }
+		s.config.Save()
+		if onSaved != nil { onSaved() }
+	}, w)
+}
+
+// showUploadDialog uploads the selected files to an S3-compatible destination. Large
+// files go up in parts recorded in the upload journal, so an interrupted upload
+// continues where it stopped.
+func (s *AppState) showUploadDialog(w fyne.Window) {
+	if s.denyInViewer(w) { return }
+	ctx, cancel := context.WithCancel(context.Background())
+	var journal *s3.Journal
+	if path, err := s3.JournalPath(); err == nil {
+		if journal, err = s3.OpenJournal(path); err != nil { dialog.ShowError(err, w) }
+	}
+	names := func() []string {
+		var out []string
+		for _, d := range s.config.Destinations { out = append(out, d.Name) }
+		return out
+	}
+	destSelect := widget.NewSelect(names(), nil)
+	if len(s.config.Destinations) > 0 { destSelect.SetSelected(s.config.Destinations[0].Name) }
+	reload := func(selected string) {
+		destSelect.SetOptions(names())
+		if s.config.GetDestination(selected) != nil { destSelect.SetSelected(selected) } else { destSelect.ClearSelected() }
+	}
+	secretEntry := widget.NewPasswordEntry()
+	secretEntry.SetPlaceHolder("Secret access key (not saved; empty uses " + s3.EnvSecretAccessKey + ")")
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+	progress := widget.NewProgressBar()
+	progress.Hide()
+
+	client := func() (*s3.Client, error) {
+		dest := s.config.GetDestination(destSelect.Selected)
+		if dest == nil { return nil, errors.New("choose a destination") }
+		c, err := s3.New(*dest, secretEntry.Text)
+		if c != nil { c.Journal = journal }
+		return c, err
+	}
+
+	addBtn := widget.NewButton("Add…", func() {
+		s.showDestinationForm(w, nil, func() {
+			reload(s.config.Destinations[len(s.config.Destinations)-1].Name)
+		})
+	})
+	editBtn := widget.NewButton("Edit…", func() {
+		dest := s.config.GetDestination(destSelect.Selected)
+		if dest == nil { return }
+		s.showDestinationForm(w, dest, func() { reload(dest.Name) })
+	})
+	removeBtn := widget.NewButton("Remove", func() {
+		for i, d := range s.config.Destinations {
+			if d.Name == destSelect.Selected {
+				s.config.Destinations = append(s.config.Destinations[:i], s.config.Destinations[i+1:]...)
+				s.config.Save()
+				reload("")
+				return
+			}
+		}
+	})
+	testBtn := widget.NewButton("Test", func() {
+		c, err := client()
+		if err != nil { status.SetText("⚠️ " + err.Error()); return }
+		status.SetText("Checking bucket…")
+		go func() {
+			err := c.Check(ctx)
+			s.ui(func() {
+				if err != nil { status.SetText("⚠️ " + err.Error()) } else { status.SetText("✅ Bucket " + c.Dest.Bucket + " is reachable") }
+			})
+		}()
+	})
+
+	pendingLabel := widget.NewLabel("")
+	var resumeBtn, discardBtn *widget.Button
+	refreshPending := func() {
+		n := len(journal.Pending())
+		pendingLabel.SetText(fmt.Sprintf("⏸ %d interrupted upload(s)", n))
+		if n == 0 { pendingLabel.Hide(); resumeBtn.Hide(); discardBtn.Hide() } else { pendingLabel.Show(); resumeBtn.Show(); discardBtn.Show() }
+	}
+
+	var uploadBtn *widget.Button
+	// upload sends files[i] as keys[i]; keys may be nil to use the destination prefix
+	upload := func(c *s3.Client, files, keys []string) {
+		uploadBtn.Disable()
+		resumeBtn.Disable()
+		progress.SetValue(0)
+		progress.Show()
+		go func() {
+			var lines []string
+			failed := 0
+			for i, f := range files {
+				key := c.Key(filepath.Base(f))
+				if keys != nil { key = keys[i] }
+				s.ui(func() { status.SetText(fmt.Sprintf("Uploading %s (%d/%d)…", filepath.Base(f), i+1, len(files))) })
+				res, err := c.PutFile(ctx, f, key, func(done, total int64) {
+					s.ui(func() { if total > 0 { progress.SetValue(float64(done) / float64(total)) } })
+				})
+				if err != nil {
+					failed++
+					lines = append(lines, "❌ "+filepath.Base(f)+": "+err.Error())
+					if ctx.Err() != nil { break }
+					continue
+				}
+				line := "✅ " + res.Key
+				if !res.RetainUntil.IsZero() { line += "  🔒 until " + res.RetainUntil.Format("2006-01-02") }
+				if res.SHA256 != "" { line += "  ✔ sha256" }
+				lines = append(lines, line)
+			}
+			s.ui(func() {
+				uploadBtn.Enable()
+				resumeBtn.Enable()
+				progress.Hide()
+				refreshPending()
+				summary := fmt.Sprintf("%d uploaded, %d failed", len(files)-failed, failed)
+				status.SetText(summary + "\n" + strings.Join(lines, "\n"))
+				s.statusLabel.SetText("☁️ " + summary)
+			})
+		}()
+	}
+	uploadBtn = widget.NewButton("☁️ Upload selection", func() {
+		files := s.lanSelectedFiles()
+		if len(files) == 0 { status.SetText("Select one or more files first."); return }
+		c, err := client()
+		if err != nil { status.SetText("⚠️ " + err.Error()); return }
+		upload(c, files, nil)
+	})
+	// interrupted uploads resume through the destination with the same bucket
+	pendingFor := func(c *s3.Client) (files, keys []string, other int) {
+		for _, up := range journal.Pending() {
+			if up.Endpoint != c.Dest.Endpoint || up.Bucket != c.Dest.Bucket { other++; continue }
+			files = append(files, up.Src)
+			keys = append(keys, up.Key)
+		}
+		return
+	}
+	resumeBtn = widget.NewButton("▶ Resume", func() {
+		c, err := client()
+		if err != nil { status.SetText("⚠️ " + err.Error()); return }
+		files, keys, other := pendingFor(c)
+		if len(files) == 0 { status.SetText(fmt.Sprintf("The %d interrupted upload(s) belong to another destination.", other)); return }
+		upload(c, files, keys)
+	})
+	discardBtn = widget.NewButton("Discard", func() {
+		c, err := client()
+		if err != nil { status.SetText("⚠️ " + err.Error()); return }
+		dialog.ShowConfirm("Discard Uploads", "Delete the uploaded parts of the interrupted uploads to "+c.Dest.Bucket+"?", func(ok bool) {
+			if !ok { return }
+			var failed []string
+			for _, up := range journal.Pending() {
+				if up.Endpoint != c.Dest.Endpoint || up.Bucket != c.Dest.Bucket { continue }
+				if err := c.Abort(ctx, up); err != nil { failed = append(failed, up.Key+": "+err.Error()) }
+			}
+			refreshPending()
+			if len(failed) > 0 { status.SetText("⚠️ " + strings.Join(failed, "\n")) } else { status.SetText("Interrupted uploads discarded.") }
+		}, w)
+	})
+	refreshPending()
+
+	note := widget.NewLabel("Upload encrypted files only: the destination sees whatever you send. Object Lock needs a bucket created with Object Lock enabled; COMPLIANCE retention cannot be shortened or removed by anyone, including you.")
+	note.Wrapping = fyne.TextWrapWord
+	content := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("Destination:"), container.NewHBox(addBtn, editBtn, removeBtn), destSelect),
+		secretEntry,
+		container.NewHBox(testBtn, uploadBtn),
+		container.NewHBox(pendingLabel, resumeBtn, discardBtn),
+		progress,
+		status,
+		note,
+	)
+	d := dialog.NewCustom("☁️ Upload to S3", "Close", container.NewVScroll(content), w)
+	d.SetOnClosed(cancel)
+	d.Resize(fyne.NewSize(640, 460))
+	d.Show()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/bangundwir/HadesCrypt/internal/policy"
+)
+
+// viewerRequested is set by the --viewer command-line switch
+var viewerRequested bool
+
+// denyInViewer tells the user that an encrypting or deleting action is unavailable
+// and reports whether it was refused
+func (s *AppState) denyInViewer(w fyne.Window) bool {
+	if !s.viewer { return false }
+	dialog.ShowInformation("Viewer mode", policy.ErrViewer.Error()+".", w)
+	return true
+}
+
+// viewerBanner marks the window as a read-only viewer; nil otherwise
+func (s *AppState) viewerBanner() fyne.CanvasObject {
+	if !s.viewer { return nil }
+	return widget.NewLabelWithStyle("👁 Viewer mode — decrypt, preview and verify only", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+}